@@ -0,0 +1,66 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// adminMiddleware 只允许 admin_emails 中配置的用户访问（需在 authMiddleware 之后使用）
+func (s *Server) adminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !s.isAdmin(c.GetString("email")) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "需要管理员权限"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// isAdmin 邮箱是否在系统配置 admin_emails 中（不区分大小写）
+func (s *Server) isAdmin(email string) bool {
+	if email == "" {
+		return false
+	}
+	adminEmailsStr, err := s.database.GetSystemConfig("admin_emails")
+	if err != nil || adminEmailsStr == "" {
+		return false
+	}
+	var adminEmails []string
+	if err := json.Unmarshal([]byte(adminEmailsStr), &adminEmails); err != nil {
+		log.Printf("⚠️  解析 admin_emails 配置失败: %v", err)
+		return false
+	}
+	for _, adminEmail := range adminEmails {
+		if strings.EqualFold(strings.TrimSpace(adminEmail), email) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleEncryptionStatus 检查所有敏感字段的加密状态（只读）
+func (s *Server) handleEncryptionStatus(c *gin.Context) {
+	report, err := s.database.VerifySensitiveData()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("检查加密状态失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"report": report, "complete": report.Complete()})
+}
+
+// handleRotateEncryption 用当前数据加密密钥重新加密所有敏感字段（单个事务，失败则全部回滚）
+func (s *Server) handleRotateEncryption(c *gin.Context) {
+	report, err := s.database.ReEncryptSensitiveData()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("重新加密失败: %v", err)})
+		return
+	}
+	log.Printf("🔐 管理员 %s 触发了敏感数据重新加密: %d 条", c.GetString("email"), report.ReEncrypted)
+	c.JSON(http.StatusOK, gin.H{"report": report, "complete": report.Complete()})
+}
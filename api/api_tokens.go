@@ -0,0 +1,180 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"nofx/auth"
+	"nofx/config"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultAPITokenTTL 未指定有效期时的默认有效期
+	defaultAPITokenTTL = 90 * 24 * time.Hour
+	// apiTokenTouchInterval 最近使用时间的更新间隔（避免每个请求都写库）
+	apiTokenTouchInterval = time.Minute
+)
+
+// adminScopeRoutes 需要 admin 范围的接口前缀（令牌、组织权限、Webhook、Telegram 绑定和管理接口）
+var adminScopeRoutes = []string{
+	"/api/tokens",
+	"/api/admin",
+	"/api/orgs",
+	"/api/invitations",
+	"/api/webhooks",
+	"/api/telegram",
+	"/api/traders/:id/org",
+	"/api/traders/:id/permissions",
+}
+
+// adminScopeMethods 需要 admin 范围的单个接口（删除交易员、修改交易所和模型密钥）
+var adminScopeMethods = map[string]bool{
+	"DELETE /api/traders/:id": true,
+	"PUT /api/models":         true,
+	"PUT /api/exchanges":      true,
+}
+
+// requiredTokenScope API 令牌访问接口所需的权限范围（route 为 gin 路由模板）
+func requiredTokenScope(method, route string) string {
+	for _, prefix := range adminScopeRoutes {
+		if route == prefix || strings.HasPrefix(route, prefix+"/") {
+			return auth.TokenScopeAdmin
+		}
+	}
+	if adminScopeMethods[method+" "+route] {
+		return auth.TokenScopeAdmin
+	}
+	if method == http.MethodGet || method == http.MethodHead {
+		return auth.TokenScopeRead
+	}
+	return auth.TokenScopeTrade
+}
+
+// authenticateAPIToken 校验 API 令牌并写入用户信息，失败时直接写入响应
+func (s *Server) authenticateAPIToken(c *gin.Context, tokenString string) bool {
+	token, err := s.database.GetAPITokenByHash(auth.HashAPIToken(tokenString))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("校验API令牌失败: %v", err)})
+		return false
+	}
+	now := time.Now()
+	if token == nil || !token.Active(now) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "API令牌无效、已撤销或已过期"})
+		return false
+	}
+
+	required := requiredTokenScope(c.Request.Method, c.FullPath())
+	if !auth.TokenScopeAllows(token.Scope, required) {
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("API令牌权限不足：需要 %s 范围", required)})
+		return false
+	}
+
+	user, err := s.database.GetUserByID(token.UserID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "API令牌所属用户不存在"})
+		return false
+	}
+
+	if now.Sub(token.LastUsedAt) >= apiTokenTouchInterval {
+		if err := s.database.TouchAPIToken(token.ID, c.ClientIP(), now); err != nil {
+			log.Printf("⚠️  更新API令牌使用记录失败: %v", err)
+		}
+	}
+
+	c.Set("user_id", user.ID)
+	c.Set("email", user.Email)
+	c.Set("token_scope", token.Scope)
+	return true
+}
+
+// handleListAPITokens 获取当前用户的 API 令牌（不返回明文）
+func (s *Server) handleListAPITokens(c *gin.Context) {
+	tokens, err := s.database.GetAPITokens(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取API令牌失败: %v", err)})
+		return
+	}
+	if tokens == nil {
+		tokens = []*config.APIToken{}
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// handleCreateAPIToken 创建 API 令牌（明文只在响应中返回一次）
+//
+// 请求体：{"name": "bot", "scope": "read|trade|admin", "expires_in_days": 30}，未指定有效期默认 90 天，0 表示不过期
+func (s *Server) handleCreateAPIToken(c *gin.Context) {
+	var req struct {
+		Name          string `json:"name" binding:"required"`
+		Scope         string `json:"scope" binding:"required"`
+		ExpiresInDays *int   `json:"expires_in_days"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !auth.ValidTokenScope(req.Scope) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("无效的权限范围: %s（可选: read, trade, admin）", req.Scope)})
+		return
+	}
+	if req.ExpiresInDays != nil && *req.ExpiresInDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "有效期不能为负数"})
+		return
+	}
+
+	plaintext, hash, err := auth.GenerateAPIToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	token := &config.APIToken{
+		ID:        uuid.New().String(),
+		UserID:    c.GetString("user_id"),
+		Name:      strings.TrimSpace(req.Name),
+		TokenHash: hash,
+		Prefix:    plaintext[:len(auth.APITokenPrefix)+8],
+		Scope:     req.Scope,
+		CreatedAt: time.Now(),
+	}
+	switch {
+	case req.ExpiresInDays == nil:
+		token.ExpiresAt = time.Now().Add(defaultAPITokenTTL)
+	case *req.ExpiresInDays > 0:
+		token.ExpiresAt = time.Now().AddDate(0, 0, *req.ExpiresInDays)
+	}
+	if err := s.database.CreateAPIToken(token); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("保存API令牌失败: %v", err)})
+		return
+	}
+
+	log.Printf("🔑 用户 %s 创建API令牌: %s (%s, 范围: %s)", token.UserID, token.Name, token.Prefix, token.Scope)
+	c.JSON(http.StatusCreated, gin.H{
+		"token":   plaintext,
+		"info":    token,
+		"message": "请立即保存令牌，之后将无法再次查看",
+	})
+}
+
+// handleRevokeAPIToken 撤销 API 令牌
+func (s *Server) handleRevokeAPIToken(c *gin.Context) {
+	userID := c.GetString("user_id")
+	err := s.database.RevokeAPIToken(userID, c.Param("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API令牌不存在或已撤销"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("撤销API令牌失败: %v", err)})
+		return
+	}
+
+	log.Printf("🔑 用户 %s 已撤销API令牌 %s", userID, c.Param("id"))
+	c.JSON(http.StatusOK, gin.H{"message": "API令牌已撤销"})
+}
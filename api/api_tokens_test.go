@@ -0,0 +1,56 @@
+package api
+
+import (
+	"testing"
+
+	"nofx/auth"
+)
+
+// TestRequiredTokenScope 测试 API 令牌访问各接口所需的权限范围
+func TestRequiredTokenScope(t *testing.T) {
+	tests := []struct {
+		method string
+		route  string
+		want   string
+	}{
+		{"GET", "/api/decisions", auth.TokenScopeRead},
+		{"GET", "/api/traders/:id/events", auth.TokenScopeRead},
+		{"POST", "/api/traders/:id/start", auth.TokenScopeTrade},
+		{"POST", "/api/traders/:id/orders", auth.TokenScopeTrade},
+		{"PUT", "/api/traders/:id", auth.TokenScopeTrade},
+		{"DELETE", "/api/traders/:id", auth.TokenScopeAdmin},
+		{"GET", "/api/models", auth.TokenScopeRead},
+		{"PUT", "/api/exchanges", auth.TokenScopeAdmin},
+		{"GET", "/api/tokens", auth.TokenScopeAdmin},
+		{"POST", "/api/orgs/:org_id/invitations", auth.TokenScopeAdmin},
+		{"GET", "/api/traders/:id/permissions", auth.TokenScopeAdmin},
+		{"POST", "/api/webhooks", auth.TokenScopeAdmin},
+		{"POST", "/api/admin/encryption/rotate", auth.TokenScopeAdmin},
+	}
+	for _, tt := range tests {
+		if got := requiredTokenScope(tt.method, tt.route); got != tt.want {
+			t.Errorf("requiredTokenScope(%s %s) = %s, want %s", tt.method, tt.route, got, tt.want)
+		}
+	}
+}
+
+// TestTokenScopeAllows 测试权限范围包含关系和令牌生成
+func TestTokenScopeAllows(t *testing.T) {
+	if !auth.TokenScopeAllows(auth.TokenScopeAdmin, auth.TokenScopeTrade) {
+		t.Error("admin 范围应包含 trade")
+	}
+	if auth.TokenScopeAllows(auth.TokenScopeRead, auth.TokenScopeTrade) {
+		t.Error("read 范围不应允许 trade 接口")
+	}
+	if auth.TokenScopeAllows("", auth.TokenScopeRead) {
+		t.Error("无效范围不应允许任何接口")
+	}
+
+	token, hash, err := auth.GenerateAPIToken()
+	if err != nil {
+		t.Fatalf("生成令牌失败: %v", err)
+	}
+	if !auth.IsAPIToken(token) || auth.HashAPIToken(token) != hash || hash == token {
+		t.Errorf("令牌格式或哈希不正确: %s %s", token, hash)
+	}
+}
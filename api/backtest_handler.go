@@ -0,0 +1,150 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"nofx/backtest"
+	"nofx/mcp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// backtestCacheDir 回测K线缓存目录（与CLI默认值一致）
+const backtestCacheDir = "backtest_cache"
+
+// maxBacktestDuration 单次回测最长区间，防止占用过多资源
+const maxBacktestDuration = 31 * 24 * time.Hour
+
+// maxBacktestSymbols 单次回测最多币种数
+const maxBacktestSymbols = 10
+
+// BacktestRequest 创建回测任务请求
+type BacktestRequest struct {
+	Symbols              []string  `json:"symbols" binding:"required"`
+	StartTime            time.Time `json:"start_time" binding:"required"`
+	EndTime              time.Time `json:"end_time" binding:"required"`
+	InitialBalance       float64   `json:"initial_balance"`
+	ScanIntervalMinutes  int       `json:"scan_interval_minutes"`
+	AIModelID            string    `json:"ai_model_id"`        // 使用用户已配置的AI模型
+	RecordedResponses    []string  `json:"recorded_responses"` // 或使用录制的AI响应（不调用真实AI）
+	BTCETHLeverage       int       `json:"btc_eth_leverage"`
+	AltcoinLeverage      int       `json:"altcoin_leverage"`
+	TakerFeeRate         float64   `json:"taker_fee_rate"`
+	FundingRate          float64   `json:"funding_rate"`
+	CustomPrompt         string    `json:"custom_prompt"`
+	OverrideBasePrompt   bool      `json:"override_base_prompt"`
+	SystemPromptTemplate string    `json:"system_prompt_template"`
+}
+
+// handleCreateBacktest 提交异步回测任务
+func (s *Server) handleCreateBacktest(c *gin.Context) {
+	userID := c.GetString("user_id")
+	var req BacktestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !req.EndTime.After(req.StartTime) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "结束时间必须晚于开始时间"})
+		return
+	}
+	if req.EndTime.Sub(req.StartTime) > maxBacktestDuration {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "回测区间不能超过31天"})
+		return
+	}
+	if len(req.Symbols) == 0 || len(req.Symbols) > maxBacktestSymbols {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("回测币种数量必须在1-%d个之间", maxBacktestSymbols)})
+		return
+	}
+	for i, symbol := range req.Symbols {
+		req.Symbols[i] = strings.ToUpper(strings.TrimSpace(symbol))
+		if !backtest.ValidSymbol(req.Symbols[i]) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("无效的币种格式: %s，必须为字母数字且以USDT结尾", symbol)})
+			return
+		}
+	}
+	if req.InitialBalance <= 0 {
+		req.InitialBalance = 1000
+	}
+
+	client, err := s.backtestAIClient(userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cfg := backtest.Config{
+		Symbols:              req.Symbols,
+		StartTime:            req.StartTime,
+		EndTime:              req.EndTime,
+		InitialBalance:       req.InitialBalance,
+		ScanInterval:         time.Duration(req.ScanIntervalMinutes) * time.Minute,
+		BTCETHLeverage:       req.BTCETHLeverage,
+		AltcoinLeverage:      req.AltcoinLeverage,
+		TakerFeeRate:         req.TakerFeeRate,
+		FundingRate:          req.FundingRate,
+		CustomPrompt:         req.CustomPrompt,
+		OverrideBasePrompt:   req.OverrideBasePrompt,
+		SystemPromptTemplate: req.SystemPromptTemplate,
+	}
+	dataCfg := backtest.DataConfig{
+		Symbols:   req.Symbols,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+		CacheDir:  backtestCacheDir,
+	}
+
+	job, err := s.backtestJobs.Submit(userID, cfg, dataCfg, client)
+	if err != nil {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("🧪 用户 %s 提交回测任务 %s", userID, job.ID)
+	c.JSON(http.StatusAccepted, job)
+}
+
+// backtestAIClient 根据请求选择AI客户端：录制响应或用户配置的AI模型
+func (s *Server) backtestAIClient(userID string, req *BacktestRequest) (mcp.AIClient, error) {
+	if len(req.RecordedResponses) > 0 {
+		return backtest.NewRecordedClient(req.RecordedResponses), nil
+	}
+	if req.AIModelID == "" {
+		return nil, fmt.Errorf("必须指定 ai_model_id 或 recorded_responses")
+	}
+
+	models, err := s.database.GetAIModels(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取AI模型配置失败: %w", err)
+	}
+	for _, model := range models {
+		if model.ID != req.AIModelID {
+			continue
+		}
+		if !model.Enabled || model.APIKey == "" {
+			return nil, fmt.Errorf("AI模型 %s 未启用或未配置API Key", req.AIModelID)
+		}
+		return mcp.NewClientForProvider(model.Provider, model.APIKey, model.CustomAPIURL, model.CustomModelName)
+	}
+	return nil, fmt.Errorf("AI模型 %s 不存在", req.AIModelID)
+}
+
+// handleListBacktests 列出当前用户的回测任务
+func (s *Server) handleListBacktests(c *gin.Context) {
+	userID := c.GetString("user_id")
+	c.JSON(http.StatusOK, s.backtestJobs.List(userID))
+}
+
+// handleGetBacktest 获取回测任务详情（完成后包含权益曲线和绩效分析）
+func (s *Server) handleGetBacktest(c *gin.Context) {
+	userID := c.GetString("user_id")
+	job, ok := s.backtestJobs.Get(userID, c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "回测任务不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
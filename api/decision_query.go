@@ -0,0 +1,121 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"nofx/config"
+	"nofx/logger"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultDecisionPageSize = 50
+	maxDecisionPageSize     = 500
+)
+
+// handleQueryDecisions 决策日志条件查询（分页）
+//
+// 参数（均可选）：start / end（RFC3339 或毫秒时间戳）、min_cycle / max_cycle、
+// symbol、action、limit（默认50，最大500）、offset、order（asc/desc，默认desc）、summary（true 时不返回 prompt 与思维链）
+func (s *Server) handleQueryDecisions(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, ok := s.requireTraderRole(c, traderID, config.OrgRoleViewer); !ok {
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	query, err := parseDecisionQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	querier, ok := trader.GetDecisionLogger().(logger.DecisionQuerier)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "当前决策日志存储不支持条件查询"})
+		return
+	}
+
+	page, err := querier.QueryDecisions(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("查询决策日志失败: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// parseDecisionQuery 解析决策日志查询参数
+func parseDecisionQuery(c *gin.Context) (logger.DecisionQuery, error) {
+	query := logger.DecisionQuery{
+		Symbol: c.Query("symbol"),
+		Action: c.Query("action"),
+		Limit:  defaultDecisionPageSize,
+		Desc:   c.Query("order") != "asc",
+	}
+
+	var err error
+	if query.Start, err = parseQueryTime(c.Query("start")); err != nil {
+		return query, fmt.Errorf("无效的 start: %w", err)
+	}
+	if query.End, err = parseQueryTime(c.Query("end")); err != nil {
+		return query, fmt.Errorf("无效的 end: %w", err)
+	}
+
+	intParams := []struct {
+		name   string
+		target *int
+	}{
+		{"min_cycle", &query.MinCycle},
+		{"max_cycle", &query.MaxCycle},
+		{"limit", &query.Limit},
+		{"offset", &query.Offset},
+	}
+	for _, p := range intParams {
+		value := c.Query(p.name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return query, fmt.Errorf("无效的 %s: %s", p.name, value)
+		}
+		*p.target = n
+	}
+	if query.Limit <= 0 || query.Limit > maxDecisionPageSize {
+		query.Limit = maxDecisionPageSize
+	}
+
+	if summary := c.Query("summary"); summary != "" {
+		query.Summary, err = strconv.ParseBool(summary)
+		if err != nil {
+			return query, fmt.Errorf("无效的 summary: %s", summary)
+		}
+	}
+	return query, nil
+}
+
+// parseQueryTime 解析 RFC3339 或毫秒时间戳，空字符串返回零值
+func parseQueryTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
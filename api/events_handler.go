@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"nofx/config"
+	"nofx/events"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sseHeartbeatInterval 心跳间隔（防止代理因空闲断开连接）
+const sseHeartbeatInterval = 15 * time.Second
+
+// queryTokenMiddleware 请求未携带 Authorization 头时，使用 ?token= 参数作为 Bearer token
+func queryTokenMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			if token := c.Query("token"); token != "" {
+				c.Request.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+		c.Next()
+	}
+}
+
+// handleTraderEvents 交易员实时事件流（Server-Sent Events）
+//
+// 参数：types（逗号分隔的事件类型，为空表示全部）、since（补发该ID之后的事件，也可使用 Last-Event-ID 头）
+func (s *Server) handleTraderEvents(c *gin.Context) {
+	traderID := c.Param("id")
+
+	if _, ok := s.requireTraderRole(c, traderID, config.OrgRoleViewer); !ok {
+		return
+	}
+
+	var types []string
+	if typesParam := c.Query("types"); typesParam != "" {
+		for _, t := range strings.Split(typesParam, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				types = append(types, t)
+			}
+		}
+	}
+
+	since := c.GetHeader("Last-Event-ID")
+	if since == "" {
+		since = c.Query("since")
+	}
+	var sinceID uint64
+	if since != "" {
+		id, err := strconv.ParseUint(since, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 since: " + since})
+			return
+		}
+		sinceID = id
+	}
+
+	sub, backlog := s.eventBus.Subscribe(sinceID, events.ForTrader(traderID, types...))
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 关闭 nginx 缓冲
+	c.Status(http.StatusOK)
+
+	for _, event := range backlog {
+		if err := writeSSEEvent(c, event); err != nil {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			if err := writeSSEEvent(c, event); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-c.Request.Context().Done():
+			return
+		case <-s.closing:
+			return
+		}
+	}
+}
+
+// writeSSEEvent 按 SSE 格式写出事件（id / event / data）
+func writeSSEEvent(c *gin.Context, event events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
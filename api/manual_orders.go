@@ -0,0 +1,130 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"nofx/config"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/trader"
+
+	"github.com/gin-gonic/gin"
+)
+
+// manualOrderRequest 手动操作请求
+type manualOrderRequest struct {
+	Action          string  `json:"action" binding:"required"` // open_long, open_short, close_long, close_short, partial_close, update_stop_loss, update_take_profit
+	Symbol          string  `json:"symbol" binding:"required"`
+	Leverage        int     `json:"leverage"`
+	PositionSizeUSD float64 `json:"position_size_usd"`
+	StopLoss        float64 `json:"stop_loss"`
+	TakeProfit      float64 `json:"take_profit"`
+	NewStopLoss     float64 `json:"new_stop_loss"`
+	NewTakeProfit   float64 `json:"new_take_profit"`
+	ClosePercentage float64 `json:"close_percentage"`
+	Note            string  `json:"note"` // 备注（会在下一周期告知AI）
+}
+
+// ownedTrader 获取当前用户以指定角色可访问的运行中交易员，失败时直接写入响应
+func (s *Server) ownedTrader(c *gin.Context, required string) (*trader.AutoTrader, bool) {
+	traderID := c.Param("id")
+
+	if _, ok := s.requireTraderRole(c, traderID, required); !ok {
+		return nil, false
+	}
+
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在"})
+		return nil, false
+	}
+	return at, true
+}
+
+// handleManualOrder 手动执行一个交易操作（不等待AI周期）
+//
+// 操作与AI决策走相同的执行路径并写入决策日志（source=manual），AI会在下一周期看到该操作
+func (s *Server) handleManualOrder(c *gin.Context) {
+	var req manualOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	at, ok := s.ownedTrader(c, config.OrgRoleOperator)
+	if !ok {
+		return
+	}
+
+	d := decision.Decision{
+		Symbol:          req.Symbol,
+		Action:          req.Action,
+		Leverage:        req.Leverage,
+		PositionSizeUSD: req.PositionSizeUSD,
+		StopLoss:        req.StopLoss,
+		TakeProfit:      req.TakeProfit,
+		NewStopLoss:     req.NewStopLoss,
+		NewTakeProfit:   req.NewTakeProfit,
+		ClosePercentage: req.ClosePercentage,
+		Reasoning:       "手动操作",
+	}
+	record, err := at.ExecuteManualDecisions([]decision.Decision{d}, req.Note)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("🖐️ 交易员 %s 手动操作: %s %s", at.GetName(), req.Action, d.Symbol)
+	respondManualRecord(c, record)
+}
+
+// handleFlattenTrader 手动平掉交易员所有持仓
+func (s *Server) handleFlattenTrader(c *gin.Context) {
+	var req struct {
+		Note string `json:"note"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	at, ok := s.ownedTrader(c, config.OrgRoleOperator)
+	if !ok {
+		return
+	}
+
+	record, err := at.FlattenAll(req.Note)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("🖐️ 交易员 %s 手动全部平仓: %d 个持仓", at.GetName(), len(record.Decisions))
+	respondManualRecord(c, record)
+}
+
+// handleListPendingOrders 获取交易员跟踪中的限价开仓挂单
+func (s *Server) handleListPendingOrders(c *gin.Context) {
+	at, ok := s.ownedTrader(c, config.OrgRoleViewer)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, at.GetPendingOrders())
+}
+
+// respondManualRecord 返回手动操作的执行记录（有操作失败时返回 422）
+func respondManualRecord(c *gin.Context, record *logger.DecisionRecord) {
+	status := http.StatusOK
+	if !record.Success {
+		status = http.StatusUnprocessableEntity
+	}
+	c.JSON(status, gin.H{
+		"success":       record.Success,
+		"cycle_number":  record.CycleNumber,
+		"decisions":     record.Decisions,
+		"execution_log": record.ExecutionLog,
+		"error":         record.ErrorMessage,
+	})
+}
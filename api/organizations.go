@@ -0,0 +1,494 @@
+package api
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"nofx/config"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// orgIDHeader 选择组织工作区的请求头（也可使用 org_id 查询参数，均未指定时为个人工作区）
+const orgIDHeader = "X-Org-ID"
+
+// orgInvitationTTL 组织邀请有效期
+const orgInvitationTTL = 7 * 24 * time.Hour
+
+// requestOrgID 请求指定的组织ID
+func requestOrgID(c *gin.Context) string {
+	if orgID := strings.TrimSpace(c.GetHeader(orgIDHeader)); orgID != "" {
+		return orgID
+	}
+	return strings.TrimSpace(c.Query("org_id"))
+}
+
+// workspace 解析请求的工作区，返回资源所属账户和组织ID，失败时直接写入响应
+//
+// 个人工作区的资源属于当前用户；组织工作区的交易员、交易所和模型属于组织所有者账户，按成员角色授权
+func (s *Server) workspace(c *gin.Context, required string) (ownerID, orgID string, ok bool) {
+	userID := c.GetString("user_id")
+	orgID = requestOrgID(c)
+	if orgID == "" {
+		return userID, "", true
+	}
+
+	org, role, err := s.orgRole(orgID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取组织失败: %v", err)})
+		return "", "", false
+	}
+	if org == nil || role == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "组织不存在或无访问权限"})
+		return "", "", false
+	}
+	if !config.OrgRoleAtLeast(role, required) {
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("权限不足：需要 %s 角色", required)})
+		return "", "", false
+	}
+	return org.OwnerID, org.ID, true
+}
+
+// credentialOwner 解析可修改交易所和AI模型密钥的账户，失败时直接写入响应
+//
+// 组织工作区的密钥就是组织创建者的个人密钥（同时驱动其组织外的个人交易员），
+// 因此只允许创建者本人修改，被授予 owner 角色的其他成员也不能覆盖
+func (s *Server) credentialOwner(c *gin.Context) (string, bool) {
+	ownerID, orgID, ok := s.workspace(c, config.OrgRoleOwner)
+	if !ok {
+		return "", false
+	}
+	if orgID != "" && ownerID != c.GetString("user_id") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有组织创建者可以修改交易所和AI模型密钥"})
+		return "", false
+	}
+	return ownerID, true
+}
+
+// orgRole 获取组织及用户在组织中的角色（组织不存在时返回 nil）
+func (s *Server) orgRole(orgID, userID string) (*config.Organization, string, error) {
+	org, err := s.database.GetOrganization(orgID)
+	if err != nil || org == nil {
+		return nil, "", err
+	}
+	role, err := s.database.GetOrgMemberRole(orgID, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	return org, role, nil
+}
+
+// requireOrgRole 校验当前用户在路径参数 org_id 对应组织中的角色，失败时直接写入响应
+func (s *Server) requireOrgRole(c *gin.Context, required string) (*config.Organization, bool) {
+	org, role, err := s.orgRole(c.Param("org_id"), c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取组织失败: %v", err)})
+		return nil, false
+	}
+	if org == nil || role == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "组织不存在或无访问权限"})
+		return nil, false
+	}
+	if !config.OrgRoleAtLeast(role, required) {
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("权限不足：需要 %s 角色", required)})
+		return nil, false
+	}
+	return org, true
+}
+
+// requireTraderRole 校验当前用户对交易员的角色，失败时直接写入响应
+//
+// 交易员不存在或不可见时返回 404，可见但角色不足时返回 403
+func (s *Server) requireTraderRole(c *gin.Context, traderID, required string) (*config.TraderAccess, bool) {
+	access, err := s.database.GetTraderAccess(c.GetString("user_id"), traderID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !config.OrgRoleAtLeast(access.Role, config.OrgRoleViewer)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在或无访问权限"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取交易员权限失败: %v", err)})
+		return nil, false
+	}
+	if !config.OrgRoleAtLeast(access.Role, required) {
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("权限不足：需要 %s 角色", required)})
+		return nil, false
+	}
+	return access, true
+}
+
+// handleCreateOrganization 创建组织，当前用户成为所有者
+func (s *Server) handleCreateOrganization(c *gin.Context) {
+	var req struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	org := &config.Organization{
+		ID:      uuid.New().String(),
+		Name:    strings.TrimSpace(req.Name),
+		OwnerID: c.GetString("user_id"),
+		Role:    config.OrgRoleOwner,
+	}
+	if err := s.database.CreateOrganization(org); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("创建组织失败: %v", err)})
+		return
+	}
+
+	log.Printf("🏢 用户 %s 创建组织: %s (%s)", org.OwnerID, org.Name, org.ID)
+	c.JSON(http.StatusCreated, org)
+}
+
+// handleListOrganizations 获取当前用户加入的组织
+func (s *Server) handleListOrganizations(c *gin.Context) {
+	orgs, err := s.database.GetUserOrganizations(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取组织列表失败: %v", err)})
+		return
+	}
+	if orgs == nil {
+		orgs = []*config.Organization{}
+	}
+	c.JSON(http.StatusOK, orgs)
+}
+
+// handleDeleteOrganization 删除组织（仅组织资源所属账户可操作，组织内交易员回到其个人工作区）
+func (s *Server) handleDeleteOrganization(c *gin.Context) {
+	org, ok := s.requireOrgRole(c, config.OrgRoleOwner)
+	if !ok {
+		return
+	}
+	if org.OwnerID != c.GetString("user_id") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有组织创建者可以删除组织"})
+		return
+	}
+	if err := s.database.DeleteOrganization(org.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("删除组织失败: %v", err)})
+		return
+	}
+
+	log.Printf("🏢 组织已删除: %s (%s)", org.Name, org.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "组织已删除"})
+}
+
+// handleListOrgMembers 获取组织成员
+func (s *Server) handleListOrgMembers(c *gin.Context) {
+	org, ok := s.requireOrgRole(c, config.OrgRoleViewer)
+	if !ok {
+		return
+	}
+	members, err := s.database.GetOrgMembers(org.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取成员列表失败: %v", err)})
+		return
+	}
+	if members == nil {
+		members = []*config.OrgMember{}
+	}
+	c.JSON(http.StatusOK, members)
+}
+
+// handleUpdateOrgMember 修改成员角色（组织创建者的角色不可修改）
+func (s *Server) handleUpdateOrgMember(c *gin.Context) {
+	var req struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !config.ValidOrgRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("无效的角色: %s（可选: owner, operator, viewer）", req.Role)})
+		return
+	}
+
+	org, ok := s.requireOrgRole(c, config.OrgRoleOwner)
+	if !ok {
+		return
+	}
+	memberID := c.Param("user_id")
+	if memberID == org.OwnerID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不能修改组织创建者的角色"})
+		return
+	}
+
+	err := s.database.SetOrgMemberRole(org.ID, memberID, req.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "成员不存在"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("修改成员角色失败: %v", err)})
+		return
+	}
+
+	log.Printf("👥 组织 %s 成员 %s 角色修改为 %s", org.Name, memberID, req.Role)
+	c.JSON(http.StatusOK, gin.H{"message": "成员角色已更新"})
+}
+
+// handleRemoveOrgMember 移除成员（所有者可移除任意成员，成员可移除自己以退出组织）
+func (s *Server) handleRemoveOrgMember(c *gin.Context) {
+	userID := c.GetString("user_id")
+	memberID := c.Param("user_id")
+	required := config.OrgRoleOwner
+	if memberID == userID {
+		required = config.OrgRoleViewer
+	}
+
+	org, ok := s.requireOrgRole(c, required)
+	if !ok {
+		return
+	}
+	if memberID == org.OwnerID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不能移除组织创建者"})
+		return
+	}
+
+	err := s.database.RemoveOrgMember(org.ID, memberID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "成员不存在"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("移除成员失败: %v", err)})
+		return
+	}
+
+	log.Printf("👥 组织 %s 已移除成员 %s", org.Name, memberID)
+	c.JSON(http.StatusOK, gin.H{"message": "成员已移除"})
+}
+
+// handleCreateOrgInvitation 邀请成员（被邀请人使用同一邮箱登录后接受邀请）
+func (s *Server) handleCreateOrgInvitation(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required"`
+		Role  string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !config.ValidOrgRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("无效的角色: %s（可选: owner, operator, viewer）", req.Role)})
+		return
+	}
+	email := strings.TrimSpace(req.Email)
+	if !strings.Contains(email, "@") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的邮箱地址"})
+		return
+	}
+
+	org, ok := s.requireOrgRole(c, config.OrgRoleOwner)
+	if !ok {
+		return
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("生成邀请码失败: %v", err)})
+		return
+	}
+	invitation := &config.OrgInvitation{
+		Token:     hex.EncodeToString(buf),
+		OrgID:     org.ID,
+		Email:     strings.ToLower(email),
+		Role:      req.Role,
+		InvitedBy: c.GetString("user_id"),
+		ExpiresAt: time.Now().Add(orgInvitationTTL),
+	}
+	if err := s.database.CreateOrgInvitation(invitation); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("保存邀请失败: %v", err)})
+		return
+	}
+
+	log.Printf("✉️  组织 %s 邀请 %s 加入（角色: %s）", org.Name, MaskEmail(invitation.Email), invitation.Role)
+	c.JSON(http.StatusCreated, invitation)
+}
+
+// handleListOrgInvitations 获取组织未过期的邀请
+func (s *Server) handleListOrgInvitations(c *gin.Context) {
+	org, ok := s.requireOrgRole(c, config.OrgRoleOwner)
+	if !ok {
+		return
+	}
+	invitations, err := s.database.GetOrgInvitations(org.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取邀请列表失败: %v", err)})
+		return
+	}
+	if invitations == nil {
+		invitations = []*config.OrgInvitation{}
+	}
+	c.JSON(http.StatusOK, invitations)
+}
+
+// handleDeleteOrgInvitation 撤销邀请
+func (s *Server) handleDeleteOrgInvitation(c *gin.Context) {
+	org, ok := s.requireOrgRole(c, config.OrgRoleOwner)
+	if !ok {
+		return
+	}
+	err := s.database.DeleteOrgInvitation(org.ID, c.Param("token"))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "邀请不存在"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("撤销邀请失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "邀请已撤销"})
+}
+
+// handleAcceptOrgInvitation 接受组织邀请（邀请邮箱需与当前账户一致）
+func (s *Server) handleAcceptOrgInvitation(c *gin.Context) {
+	userID := c.GetString("user_id")
+	invitation, err := s.database.AcceptOrgInvitation(c.Param("token"), userID, c.GetString("email"))
+	if errors.Is(err, config.ErrInvitationEmailMismatch) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("接受邀请失败: %v", err)})
+		return
+	}
+	if invitation == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "邀请不存在或已过期"})
+		return
+	}
+
+	role, _ := s.database.GetOrgMemberRole(invitation.OrgID, userID)
+	log.Printf("👥 用户 %s 已加入组织 %s（角色: %s）", userID, invitation.OrgID, role)
+	c.JSON(http.StatusOK, gin.H{"org_id": invitation.OrgID, "role": role})
+}
+
+// handleSetTraderOrg 将交易员移入组织或移回个人工作区
+//
+// 交易员需属于组织创建者账户（组织内交易员使用该账户的交易所和模型配置），当前用户需为交易员和目标组织的所有者
+func (s *Server) handleSetTraderOrg(c *gin.Context) {
+	var req struct {
+		OrgID string `json:"org_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	access, ok := s.requireTraderRole(c, c.Param("id"), config.OrgRoleOwner)
+	if !ok {
+		return
+	}
+	if req.OrgID != "" {
+		org, role, err := s.orgRole(req.OrgID, c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取组织失败: %v", err)})
+			return
+		}
+		if org == nil || role != config.OrgRoleOwner {
+			c.JSON(http.StatusForbidden, gin.H{"error": "需要目标组织的所有者角色"})
+			return
+		}
+		if org.OwnerID != access.OwnerID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "只能移入交易员所属账户创建的组织"})
+			return
+		}
+	}
+
+	if err := s.database.SetTraderOrg(access.OwnerID, access.TraderID, req.OrgID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("更新交易员所属组织失败: %v", err)})
+		return
+	}
+
+	log.Printf("🏢 交易员 %s 所属组织更新为 %q", access.TraderID, req.OrgID)
+	c.JSON(http.StatusOK, gin.H{"trader_id": access.TraderID, "org_id": req.OrgID})
+}
+
+// handleListTraderPermissions 获取交易员的成员权限覆盖
+func (s *Server) handleListTraderPermissions(c *gin.Context) {
+	access, ok := s.requireTraderRole(c, c.Param("id"), config.OrgRoleOwner)
+	if !ok {
+		return
+	}
+	permissions, err := s.database.GetTraderPermissions(access.TraderID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取交易员权限失败: %v", err)})
+		return
+	}
+	if permissions == nil {
+		permissions = []*config.TraderPermission{}
+	}
+	c.JSON(http.StatusOK, permissions)
+}
+
+// handleSetTraderPermission 覆盖组织成员对单个交易员的角色（operator / viewer / none）
+func (s *Server) handleSetTraderPermission(c *gin.Context) {
+	var req struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !config.ValidTraderPermissionRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("无效的角色: %s（可选: operator, viewer, none）", req.Role)})
+		return
+	}
+
+	access, ok := s.requireTraderRole(c, c.Param("id"), config.OrgRoleOwner)
+	if !ok {
+		return
+	}
+	if access.OrgID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "交易员不属于任何组织"})
+		return
+	}
+	memberID := c.Param("user_id")
+	role, err := s.database.GetOrgMemberRole(access.OrgID, memberID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取成员角色失败: %v", err)})
+		return
+	}
+	if role == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "成员不存在"})
+		return
+	}
+	if role == config.OrgRoleOwner {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "所有者的权限不能被单个交易员覆盖"})
+		return
+	}
+
+	if err := s.database.SetTraderPermission(access.TraderID, memberID, req.Role); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("设置交易员权限失败: %v", err)})
+		return
+	}
+
+	log.Printf("🔐 交易员 %s 成员 %s 权限覆盖为 %s", access.TraderID, memberID, req.Role)
+	c.JSON(http.StatusOK, gin.H{"message": "交易员权限已更新"})
+}
+
+// handleDeleteTraderPermission 删除交易员权限覆盖（恢复为成员在组织中的角色）
+func (s *Server) handleDeleteTraderPermission(c *gin.Context) {
+	access, ok := s.requireTraderRole(c, c.Param("id"), config.OrgRoleOwner)
+	if !ok {
+		return
+	}
+	err := s.database.DeleteTraderPermission(access.TraderID, c.Param("user_id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "权限覆盖不存在"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("删除交易员权限失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "交易员权限已恢复为组织角色"})
+}
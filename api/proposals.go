@@ -0,0 +1,74 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"nofx/config"
+	"nofx/trader"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleListProposals 获取交易员的AI决策提案（审批模式）
+func (s *Server) handleListProposals(c *gin.Context) {
+	at, ok := s.ownedTrader(c, config.OrgRoleViewer)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, at.GetProposals())
+}
+
+// handleApproveProposal 批准提案并立即执行
+func (s *Server) handleApproveProposal(c *gin.Context) {
+	at, ok := s.ownedTrader(c, config.OrgRoleOperator)
+	if !ok {
+		return
+	}
+
+	proposal, err := at.ApproveProposal(c.Param("proposal_id"))
+	if err != nil {
+		c.JSON(proposalErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("✓ 交易员 %s 的提案 %s 已批准: %s", at.GetName(), proposal.ID, proposal.Status)
+	status := http.StatusOK
+	if proposal.Result != nil && !proposal.Result.Success {
+		status = http.StatusUnprocessableEntity
+	}
+	c.JSON(status, proposal)
+}
+
+// handleRejectProposal 拒绝提案
+func (s *Server) handleRejectProposal(c *gin.Context) {
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	at, ok := s.ownedTrader(c, config.OrgRoleOperator)
+	if !ok {
+		return
+	}
+
+	proposal, err := at.RejectProposal(c.Param("proposal_id"), req.Reason)
+	if err != nil {
+		c.JSON(proposalErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, proposal)
+}
+
+// proposalErrorStatus 提案不存在返回 404，已处理（包括已过期）返回 409
+func proposalErrorStatus(err error) int {
+	if errors.Is(err, trader.ErrProposalNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusConflict
+}
@@ -3,17 +3,24 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math"
 	"net"
 	"net/http"
 	"nofx/auth"
+	"nofx/backtest"
 	"nofx/config"
 	"nofx/crypto"
 	"nofx/decision"
+	"nofx/events"
+	"nofx/exit"
 	"nofx/hook"
+	"nofx/logger"
 	"nofx/manager"
+	"nofx/market"
+	"nofx/risk"
 	"nofx/trader"
 	"strconv"
 	"strings"
@@ -30,6 +37,9 @@ type Server struct {
 	traderManager *manager.TraderManager
 	database      *config.Database
 	cryptoHandler *CryptoHandler
+	backtestJobs  *backtest.JobManager
+	eventBus      *events.Bus   // 交易员实时事件
+	closing       chan struct{} // 关闭时通知事件流断开
 	port          int
 }
 
@@ -51,6 +61,9 @@ func NewServer(traderManager *manager.TraderManager, database *config.Database,
 		traderManager: traderManager,
 		database:      database,
 		cryptoHandler: cryptoHandler,
+		backtestJobs:  backtest.NewJobManager(),
+		eventBus:      events.Default(),
+		closing:       make(chan struct{}),
 		port:          port,
 	}
 
@@ -65,7 +78,7 @@ func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Last-Event-ID, X-Org-ID")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(http.StatusOK)
@@ -115,6 +128,9 @@ func (s *Server) setupRoutes() {
 		api.POST("/verify-otp", s.handleVerifyOTP)
 		api.POST("/complete-registration", s.handleCompleteRegistration)
 
+		// 交易员实时事件流（SSE）：EventSource 无法设置请求头，允许通过 ?token= 传递JWT
+		api.GET("/traders/:id/events", queryTokenMiddleware(), s.authMiddleware(), s.handleTraderEvents)
+
 		// 需要认证的路由
 		protected := api.Group("/", s.authMiddleware())
 		{
@@ -133,6 +149,14 @@ func (s *Server) setupRoutes() {
 			protected.POST("/traders/:id/start", s.handleStartTrader)
 			protected.POST("/traders/:id/stop", s.handleStopTrader)
 			protected.PUT("/traders/:id/prompt", s.handleUpdateTraderPrompt)
+			protected.GET("/traders/:id/circuit-breaker", s.handleGetCircuitBreaker)
+			protected.POST("/traders/:id/circuit-breaker/reset", s.handleResetCircuitBreaker)
+			protected.POST("/traders/:id/orders", s.handleManualOrder)
+			protected.POST("/traders/:id/flatten", s.handleFlattenTrader)
+			protected.GET("/traders/:id/proposals", s.handleListProposals)
+			protected.GET("/traders/:id/orders/pending", s.handleListPendingOrders)
+			protected.POST("/traders/:id/proposals/:proposal_id/approve", s.handleApproveProposal)
+			protected.POST("/traders/:id/proposals/:proposal_id/reject", s.handleRejectProposal)
 
 			// AI模型配置
 			protected.GET("/models", s.handleGetModelConfigs)
@@ -152,8 +176,55 @@ func (s *Server) setupRoutes() {
 			protected.GET("/positions", s.handlePositions)
 			protected.GET("/decisions", s.handleDecisions)
 			protected.GET("/decisions/latest", s.handleLatestDecisions)
+			protected.GET("/decisions/query", s.handleQueryDecisions)
 			protected.GET("/statistics", s.handleStatistics)
 			protected.GET("/performance", s.handlePerformance)
+
+			// 历史回测（异步任务）
+			protected.POST("/backtests", s.handleCreateBacktest)
+			protected.GET("/backtests", s.handleListBacktests)
+			protected.GET("/backtests/:id", s.handleGetBacktest)
+
+			// Webhook 订阅（向外部系统推送签名的交易事件）
+			protected.GET("/webhooks", s.handleListWebhooks)
+			protected.POST("/webhooks", s.handleCreateWebhook)
+			protected.PUT("/webhooks/:id", s.handleUpdateWebhook)
+			protected.DELETE("/webhooks/:id", s.handleDeleteWebhook)
+			protected.GET("/webhooks/:id/deliveries", s.handleWebhookDeliveries)
+			protected.POST("/webhooks/:id/test", s.handleTestWebhook)
+
+			// Telegram 机器人绑定
+			protected.POST("/telegram/bind-code", s.handleCreateTelegramBindCode)
+			protected.GET("/telegram/bindings", s.handleListTelegramBindings)
+			protected.DELETE("/telegram/bindings/:chat_id", s.handleDeleteTelegramBinding)
+
+			// API 令牌（个人访问令牌，可在 Authorization: Bearer 中替代 JWT）
+			protected.GET("/tokens", s.handleListAPITokens)
+			protected.POST("/tokens", s.handleCreateAPIToken)
+			protected.DELETE("/tokens/:id", s.handleRevokeAPIToken)
+
+			// 组织与团队权限（交易员/交易所/模型接口通过 X-Org-ID 头或 org_id 参数选择组织工作区）
+			protected.GET("/orgs", s.handleListOrganizations)
+			protected.POST("/orgs", s.handleCreateOrganization)
+			protected.DELETE("/orgs/:org_id", s.handleDeleteOrganization)
+			protected.GET("/orgs/:org_id/members", s.handleListOrgMembers)
+			protected.PUT("/orgs/:org_id/members/:user_id", s.handleUpdateOrgMember)
+			protected.DELETE("/orgs/:org_id/members/:user_id", s.handleRemoveOrgMember)
+			protected.GET("/orgs/:org_id/invitations", s.handleListOrgInvitations)
+			protected.POST("/orgs/:org_id/invitations", s.handleCreateOrgInvitation)
+			protected.DELETE("/orgs/:org_id/invitations/:token", s.handleDeleteOrgInvitation)
+			protected.POST("/invitations/:token/accept", s.handleAcceptOrgInvitation)
+			protected.PUT("/traders/:id/org", s.handleSetTraderOrg)
+			protected.GET("/traders/:id/permissions", s.handleListTraderPermissions)
+			protected.PUT("/traders/:id/permissions/:user_id", s.handleSetTraderPermission)
+			protected.DELETE("/traders/:id/permissions/:user_id", s.handleDeleteTraderPermission)
+
+			// 管理接口（仅 admin_emails 中的用户）
+			admin := protected.Group("/admin", s.adminMiddleware())
+			{
+				admin.GET("/encryption/status", s.handleEncryptionStatus)
+				admin.POST("/encryption/rotate", s.handleRotateEncryption)
+			}
 		}
 	}
 }
@@ -382,20 +453,29 @@ func (s *Server) getTraderFromQuery(c *gin.Context) (*manager.TraderManager, str
 
 // AI交易员管理相关结构体
 type CreateTraderRequest struct {
-	Name                 string  `json:"name" binding:"required"`
-	AIModelID            string  `json:"ai_model_id" binding:"required"`
-	ExchangeID           string  `json:"exchange_id" binding:"required"`
-	InitialBalance       float64 `json:"initial_balance"`
-	ScanIntervalMinutes  int     `json:"scan_interval_minutes"`
-	BTCETHLeverage       int     `json:"btc_eth_leverage"`
-	AltcoinLeverage      int     `json:"altcoin_leverage"`
-	TradingSymbols       string  `json:"trading_symbols"`
-	CustomPrompt         string  `json:"custom_prompt"`
-	OverrideBasePrompt   bool    `json:"override_base_prompt"`
-	SystemPromptTemplate string  `json:"system_prompt_template"` // 系统提示词模板名称
-	IsCrossMargin        *bool   `json:"is_cross_margin"`        // 指针类型，nil表示使用默认值true
-	UseCoinPool          bool    `json:"use_coin_pool"`
-	UseOITop             bool    `json:"use_oi_top"`
+	Name                 string          `json:"name" binding:"required"`
+	AIModelID            string          `json:"ai_model_id" binding:"required"`
+	ExchangeID           string          `json:"exchange_id" binding:"required"`
+	InitialBalance       float64         `json:"initial_balance"`
+	ScanIntervalMinutes  int             `json:"scan_interval_minutes"`
+	BTCETHLeverage       int             `json:"btc_eth_leverage"`
+	AltcoinLeverage      int             `json:"altcoin_leverage"`
+	TradingSymbols       string          `json:"trading_symbols"`
+	CustomPrompt         string          `json:"custom_prompt"`
+	OverrideBasePrompt   bool            `json:"override_base_prompt"`
+	SystemPromptTemplate string          `json:"system_prompt_template"` // 系统提示词模板名称
+	IsCrossMargin        *bool           `json:"is_cross_margin"`        // 指针类型，nil表示使用默认值true
+	UseCoinPool          bool            `json:"use_coin_pool"`
+	UseOITop             bool            `json:"use_oi_top"`
+	RiskConfig           json.RawMessage `json:"risk_config"`           // 组合风控规则（JSON对象，未提供的字段使用系统默认值）
+	ExitPolicy           json.RawMessage `json:"exit_policy"`           // 持仓退出策略（JSON对象，未提供的字段使用默认策略）
+	DecisionMode         string          `json:"decision_mode"`         // 决策输出模式: text / tool_call / agent（默认 text）
+	ExecutionMode        string          `json:"execution_mode"`        // 执行模式: auto / approval（默认 auto）
+	AutoApproveNotional  float64         `json:"auto_approve_notional"` // 审批模式下名义价值低于该值（USDT）的决策自动执行
+	LimitOrderTimeout    int             `json:"limit_order_timeout"`   // 限价开仓单超时秒数（0=一个扫描周期）
+	LimitOrderFallback   string          `json:"limit_order_fallback"`  // 限价单超时处理: cancel / market（默认 cancel）
+	MarketTimeframes     string          `json:"market_timeframes"`     // 行情K线周期，逗号分隔，如 "15m,1h,1d"（默认 3m,4h）
+	MarketIndicators     string          `json:"market_indicators"`     // 行情指标，逗号分隔，如 "ema,bollinger,adx"（默认 ema,macd,rsi,atr）
 }
 
 type ModelConfig struct {
@@ -463,13 +543,18 @@ type UpdateExchangeConfigRequest struct {
 
 // handleCreateTrader 创建新的AI交易员
 func (s *Server) handleCreateTrader(c *gin.Context) {
-	userID := c.GetString("user_id")
 	var req CreateTraderRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	// 组织工作区中创建需要 operator 角色，交易员归属组织创建者账户并使用其交易所和模型配置
+	ownerID, orgID, ok := s.workspace(c, config.OrgRoleOperator)
+	if !ok {
+		return
+	}
+
 	// 校验杠杆值
 	if req.BTCETHLeverage < 0 || req.BTCETHLeverage > 50 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "BTC/ETH杠杆必须在1-50倍之间"})
@@ -492,6 +577,47 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 		}
 	}
 
+	// 校验风控配置
+	riskConfig, err := parseRiskConfig(req.RiskConfig)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	exitPolicy, err := parseExitPolicy(req.ExitPolicy)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !decision.ValidDecisionMode(req.DecisionMode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("无效的决策模式: %s", req.DecisionMode)})
+		return
+	}
+	decisionMode := req.DecisionMode
+	if decisionMode == "" {
+		decisionMode = decision.DecisionModeText
+	}
+	if !trader.ValidExecutionMode(req.ExecutionMode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("无效的执行模式: %s", req.ExecutionMode)})
+		return
+	}
+	if req.AutoApproveNotional < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "自动批准阈值不能为负数"})
+		return
+	}
+	if req.LimitOrderTimeout < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "限价单超时时间不能为负数"})
+		return
+	}
+	if !trader.ValidLimitOrderFallback(req.LimitOrderFallback) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("无效的限价单超时处理方式: %s", req.LimitOrderFallback)})
+		return
+	}
+	marketTimeframes, marketIndicators, err := parseMarketDataConfig(req.MarketTimeframes, req.MarketIndicators)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// 生成交易员ID (使用 UUID 确保唯一性，解决 Issue #893)
 	// 保留前缀以便调试和日志追踪
 	traderID := fmt.Sprintf("%s_%s_%s", req.ExchangeID, req.AIModelID, uuid.New().String())
@@ -540,7 +666,7 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 
 	// ✨ 查询交易所实际余额，覆盖用户输入
 	actualBalance := req.InitialBalance // 默认使用用户输入
-	exchanges, err := s.database.GetExchanges(userID)
+	exchanges, err := s.database.GetExchanges(ownerID)
 	if err != nil {
 		log.Printf("⚠️ 获取交易所配置失败，使用用户输入的初始资金: %v", err)
 	}
@@ -565,7 +691,7 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 
 		switch req.ExchangeID {
 		case "binance":
-			tempTrader = trader.NewFuturesTrader(exchangeCfg.APIKey, exchangeCfg.SecretKey, userID)
+			tempTrader = trader.NewFuturesTrader(exchangeCfg.APIKey, exchangeCfg.SecretKey, ownerID)
 		case "hyperliquid":
 			tempTrader, createErr = trader.NewHyperliquidTrader(
 				exchangeCfg.APIKey, // private key
@@ -578,6 +704,9 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 				exchangeCfg.AsterSigner,
 				exchangeCfg.AsterPrivateKey,
 			)
+		case "paper":
+			// 模拟盘没有真实账户，直接使用用户输入的初始资金
+			log.Printf("🧪 模拟盘交易员，使用用户输入的初始资金: %.2f USDT", req.InitialBalance)
 		default:
 			log.Printf("⚠️ 不支持的交易所类型: %s，使用用户输入的初始资金", req.ExchangeID)
 		}
@@ -628,7 +757,8 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 	// 创建交易员配置（数据库实体）
 	trader := &config.TraderRecord{
 		ID:                   traderID,
-		UserID:               userID,
+		UserID:               ownerID,
+		OrgID:                orgID,
 		Name:                 req.Name,
 		AIModelID:            req.AIModelID,
 		ExchangeID:           req.ExchangeID,
@@ -643,6 +773,15 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 		SystemPromptTemplate: systemPromptTemplate,
 		IsCrossMargin:        isCrossMargin,
 		ScanIntervalMinutes:  scanIntervalMinutes,
+		RiskConfig:           riskConfig,
+		ExitPolicy:           exitPolicy,
+		DecisionMode:         decisionMode,
+		ExecutionMode:        req.ExecutionMode,
+		AutoApproveNotional:  req.AutoApproveNotional,
+		LimitOrderTimeout:    req.LimitOrderTimeout,
+		LimitOrderFallback:   req.LimitOrderFallback,
+		MarketTimeframes:     marketTimeframes,
+		MarketIndicators:     marketIndicators,
 		IsRunning:            false,
 	}
 
@@ -654,7 +793,7 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 	}
 
 	// 立即将新交易员加载到TraderManager中
-	err = s.traderManager.LoadTraderByID(s.database, userID, traderID)
+	err = s.traderManager.LoadTraderByID(s.database, ownerID, traderID)
 	if err != nil {
 		log.Printf("⚠️ 加载交易员到内存失败: %v", err)
 		// 这里不返回错误，因为交易员已经成功创建到数据库
@@ -670,25 +809,74 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 	})
 }
 
+// parseRiskConfig 校验请求中的风控配置，返回规范化后的JSON字符串（null或空表示使用默认值）
+func parseRiskConfig(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+	if _, err := risk.ParseConfig(string(raw), risk.DefaultConfig()); err != nil {
+		return "", fmt.Errorf("无效的风控配置: %w", err)
+	}
+	var compact map[string]interface{}
+	if err := json.Unmarshal(raw, &compact); err != nil {
+		return "", fmt.Errorf("无效的风控配置: %w", err)
+	}
+	normalized, _ := json.Marshal(compact)
+	return string(normalized), nil
+}
+
+// parseExitPolicy 校验请求中的退出策略配置，返回规范化后的JSON字符串（null或空表示使用默认策略）
+func parseExitPolicy(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+	if _, err := exit.ParseConfig(string(raw), exit.DefaultConfig()); err != nil {
+		return "", fmt.Errorf("无效的退出策略配置: %w", err)
+	}
+	var compact map[string]interface{}
+	if err := json.Unmarshal(raw, &compact); err != nil {
+		return "", fmt.Errorf("无效的退出策略配置: %w", err)
+	}
+	normalized, _ := json.Marshal(compact)
+	return string(normalized), nil
+}
+
+// parseMarketDataConfig 校验请求中的行情K线周期和指标，返回规范化后的逗号分隔列表（空表示使用默认值）
+func parseMarketDataConfig(timeframes, indicators string) (string, string, error) {
+	cfg, err := market.ParseDataConfig(timeframes, indicators)
+	if err != nil {
+		return "", "", fmt.Errorf("无效的行情数据配置: %w", err)
+	}
+	return strings.Join(cfg.Timeframes, ","), strings.Join(cfg.Indicators, ","), nil
+}
+
 // UpdateTraderRequest 更新交易员请求
 type UpdateTraderRequest struct {
-	Name                 string  `json:"name" binding:"required"`
-	AIModelID            string  `json:"ai_model_id" binding:"required"`
-	ExchangeID           string  `json:"exchange_id" binding:"required"`
-	InitialBalance       float64 `json:"initial_balance"`
-	ScanIntervalMinutes  int     `json:"scan_interval_minutes"`
-	BTCETHLeverage       int     `json:"btc_eth_leverage"`
-	AltcoinLeverage      int     `json:"altcoin_leverage"`
-	TradingSymbols       string  `json:"trading_symbols"`
-	CustomPrompt         string  `json:"custom_prompt"`
-	OverrideBasePrompt   bool    `json:"override_base_prompt"`
-	SystemPromptTemplate string  `json:"system_prompt_template"`
-	IsCrossMargin        *bool   `json:"is_cross_margin"`
+	Name                 string          `json:"name" binding:"required"`
+	AIModelID            string          `json:"ai_model_id" binding:"required"`
+	ExchangeID           string          `json:"exchange_id" binding:"required"`
+	InitialBalance       float64         `json:"initial_balance"`
+	ScanIntervalMinutes  int             `json:"scan_interval_minutes"`
+	BTCETHLeverage       int             `json:"btc_eth_leverage"`
+	AltcoinLeverage      int             `json:"altcoin_leverage"`
+	TradingSymbols       string          `json:"trading_symbols"`
+	CustomPrompt         string          `json:"custom_prompt"`
+	OverrideBasePrompt   bool            `json:"override_base_prompt"`
+	SystemPromptTemplate string          `json:"system_prompt_template"`
+	IsCrossMargin        *bool           `json:"is_cross_margin"`
+	RiskConfig           json.RawMessage `json:"risk_config"`           // 未提供时保持原值，null 表示恢复默认
+	ExitPolicy           json.RawMessage `json:"exit_policy"`           // 未提供时保持原值，null 表示恢复默认
+	DecisionMode         string          `json:"decision_mode"`         // 未提供时保持原值
+	ExecutionMode        string          `json:"execution_mode"`        // 未提供时保持原值
+	AutoApproveNotional  *float64        `json:"auto_approve_notional"` // 未提供时保持原值
+	LimitOrderTimeout    *int            `json:"limit_order_timeout"`   // 未提供时保持原值
+	LimitOrderFallback   string          `json:"limit_order_fallback"`  // 未提供时保持原值
+	MarketTimeframes     *string         `json:"market_timeframes"`     // 未提供时保持原值，空字符串表示恢复默认
+	MarketIndicators     *string         `json:"market_indicators"`     // 未提供时保持原值，空字符串表示恢复默认
 }
 
 // handleUpdateTrader 更新交易员配置
 func (s *Server) handleUpdateTrader(c *gin.Context) {
-	userID := c.GetString("user_id")
 	traderID := c.Param("id")
 
 	var req UpdateTraderRequest
@@ -697,8 +885,12 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 		return
 	}
 
-	// 检查交易员是否存在且属于当前用户
-	traders, err := s.database.GetTraders(userID)
+	// 检查交易员是否存在且当前用户有操作权限
+	access, ok := s.requireTraderRole(c, traderID, config.OrgRoleOperator)
+	if !ok {
+		return
+	}
+	traders, err := s.database.GetTraders(access.OwnerID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取交易员列表失败"})
 		return
@@ -747,10 +939,88 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 		systemPromptTemplate = existingTrader.SystemPromptTemplate // 如果请求中没有提供，保持原值
 	}
 
+	// 设置风控配置，允许更新
+	riskConfig := existingTrader.RiskConfig
+	if len(req.RiskConfig) > 0 {
+		riskConfig, err = parseRiskConfig(req.RiskConfig)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// 设置退出策略，允许更新
+	exitPolicy := existingTrader.ExitPolicy
+	if len(req.ExitPolicy) > 0 {
+		exitPolicy, err = parseExitPolicy(req.ExitPolicy)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// 设置决策模式，允许更新
+	decisionMode := req.DecisionMode
+	if decisionMode == "" {
+		decisionMode = existingTrader.DecisionMode // 如果请求中没有提供，保持原值
+	} else if !decision.ValidDecisionMode(decisionMode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("无效的决策模式: %s", decisionMode)})
+		return
+	}
+
+	// 设置执行模式和自动批准阈值，未提供时保持原值
+	executionMode := req.ExecutionMode
+	if executionMode == "" {
+		executionMode = existingTrader.ExecutionMode
+	} else if !trader.ValidExecutionMode(executionMode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("无效的执行模式: %s", executionMode)})
+		return
+	}
+	autoApproveNotional := existingTrader.AutoApproveNotional
+	if req.AutoApproveNotional != nil {
+		if *req.AutoApproveNotional < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "自动批准阈值不能为负数"})
+			return
+		}
+		autoApproveNotional = *req.AutoApproveNotional
+	}
+
+	// 设置限价单超时时间和处理方式，未提供时保持原值
+	limitOrderTimeout := existingTrader.LimitOrderTimeout
+	if req.LimitOrderTimeout != nil {
+		if *req.LimitOrderTimeout < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "限价单超时时间不能为负数"})
+			return
+		}
+		limitOrderTimeout = *req.LimitOrderTimeout
+	}
+	limitOrderFallback := req.LimitOrderFallback
+	if limitOrderFallback == "" {
+		limitOrderFallback = existingTrader.LimitOrderFallback
+	} else if !trader.ValidLimitOrderFallback(limitOrderFallback) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("无效的限价单超时处理方式: %s", limitOrderFallback)})
+		return
+	}
+
+	// 设置行情K线周期和指标，未提供时保持原值
+	marketTimeframes, marketIndicators := existingTrader.MarketTimeframes, existingTrader.MarketIndicators
+	if req.MarketTimeframes != nil {
+		marketTimeframes = *req.MarketTimeframes
+	}
+	if req.MarketIndicators != nil {
+		marketIndicators = *req.MarketIndicators
+	}
+	marketTimeframes, marketIndicators, err = parseMarketDataConfig(marketTimeframes, marketIndicators)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// 更新交易员配置
 	trader := &config.TraderRecord{
 		ID:                   traderID,
-		UserID:               userID,
+		UserID:               access.OwnerID,
+		OrgID:                existingTrader.OrgID,
 		Name:                 req.Name,
 		AIModelID:            req.AIModelID,
 		ExchangeID:           req.ExchangeID,
@@ -763,6 +1033,15 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 		SystemPromptTemplate: systemPromptTemplate,
 		IsCrossMargin:        isCrossMargin,
 		ScanIntervalMinutes:  scanIntervalMinutes,
+		RiskConfig:           riskConfig,
+		ExitPolicy:           exitPolicy,
+		DecisionMode:         decisionMode,
+		ExecutionMode:        executionMode,
+		AutoApproveNotional:  autoApproveNotional,
+		LimitOrderTimeout:    limitOrderTimeout,
+		LimitOrderFallback:   limitOrderFallback,
+		MarketTimeframes:     marketTimeframes,
+		MarketIndicators:     marketIndicators,
 		IsRunning:            existingTrader.IsRunning, // 保持原值
 	}
 
@@ -776,7 +1055,7 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 	// 如果请求中包含initial_balance且与现有值不同，单独更新它
 	// UpdateTrader不会更新initial_balance，需要使用专门的方法
 	if req.InitialBalance > 0 && math.Abs(req.InitialBalance-existingTrader.InitialBalance) > 0.1 {
-		err = s.database.UpdateTraderInitialBalance(userID, traderID, req.InitialBalance)
+		err = s.database.UpdateTraderInitialBalance(access.OwnerID, traderID, req.InitialBalance)
 		if err != nil {
 			log.Printf("⚠️ 更新初始余额失败: %v", err)
 			// 不返回错误，因为主要配置已更新成功
@@ -789,7 +1068,7 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 	s.traderManager.RemoveTrader(traderID)
 
 	// 重新加载交易员到内存
-	err = s.traderManager.LoadTraderByID(s.database, userID, traderID)
+	err = s.traderManager.LoadTraderByID(s.database, access.OwnerID, traderID)
 	if err != nil {
 		log.Printf("⚠️ 重新加载交易员到内存失败: %v", err)
 	}
@@ -806,11 +1085,15 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 
 // handleDeleteTrader 删除交易员
 func (s *Server) handleDeleteTrader(c *gin.Context) {
-	userID := c.GetString("user_id")
 	traderID := c.Param("id")
 
+	access, ok := s.requireTraderRole(c, traderID, config.OrgRoleOwner)
+	if !ok {
+		return
+	}
+
 	// 从数据库删除
-	err := s.database.DeleteTrader(userID, traderID)
+	err := s.database.DeleteTrader(access.OwnerID, traderID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("删除交易员失败: %v", err)})
 		return
@@ -831,11 +1114,14 @@ func (s *Server) handleDeleteTrader(c *gin.Context) {
 
 // handleStartTrader 启动交易员
 func (s *Server) handleStartTrader(c *gin.Context) {
-	userID := c.GetString("user_id")
 	traderID := c.Param("id")
 
-	// 校验交易员是否属于当前用户
-	traderRecord, _, _, err := s.database.GetTraderConfig(userID, traderID)
+	// 校验当前用户对交易员的操作权限
+	access, ok := s.requireTraderRole(c, traderID, config.OrgRoleOperator)
+	if !ok {
+		return
+	}
+	traderRecord, _, _, err := s.database.GetTraderConfig(access.OwnerID, traderID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在或无访问权限"})
 		return
@@ -869,7 +1155,7 @@ func (s *Server) handleStartTrader(c *gin.Context) {
 	}()
 
 	// 更新数据库中的运行状态
-	err = s.database.UpdateTraderStatus(userID, traderID, true)
+	err = s.database.UpdateTraderStatus(access.OwnerID, traderID, true)
 	if err != nil {
 		log.Printf("⚠️  更新交易员状态失败: %v", err)
 	}
@@ -880,13 +1166,11 @@ func (s *Server) handleStartTrader(c *gin.Context) {
 
 // handleStopTrader 停止交易员
 func (s *Server) handleStopTrader(c *gin.Context) {
-	userID := c.GetString("user_id")
 	traderID := c.Param("id")
 
-	// 校验交易员是否属于当前用户
-	_, _, _, err := s.database.GetTraderConfig(userID, traderID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在或无访问权限"})
+	// 校验当前用户对交易员的操作权限
+	access, ok := s.requireTraderRole(c, traderID, config.OrgRoleOperator)
+	if !ok {
 		return
 	}
 
@@ -907,7 +1191,7 @@ func (s *Server) handleStopTrader(c *gin.Context) {
 	trader.Stop()
 
 	// 更新数据库中的运行状态
-	err = s.database.UpdateTraderStatus(userID, traderID, false)
+	err = s.database.UpdateTraderStatus(access.OwnerID, traderID, false)
 	if err != nil {
 		log.Printf("⚠️  更新交易员状态失败: %v", err)
 	}
@@ -916,10 +1200,49 @@ func (s *Server) handleStopTrader(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "交易员已停止"})
 }
 
+// handleGetCircuitBreaker 获取交易员熔断状态
+func (s *Server) handleGetCircuitBreaker(c *gin.Context) {
+	traderID := c.Param("id")
+
+	if _, ok := s.requireTraderRole(c, traderID, config.OrgRoleViewer); !ok {
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, trader.GetCircuitBreakerStatus())
+}
+
+// handleResetCircuitBreaker 手动解除交易员熔断
+func (s *Server) handleResetCircuitBreaker(c *gin.Context) {
+	traderID := c.Param("id")
+
+	if _, ok := s.requireTraderRole(c, traderID, config.OrgRoleOperator); !ok {
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在"})
+		return
+	}
+
+	if err := trader.ResetCircuitBreaker(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("解除熔断失败: %v", err)})
+		return
+	}
+
+	log.Printf("🔄 交易员 %s 的熔断已手动解除", trader.GetName())
+	c.JSON(http.StatusOK, trader.GetCircuitBreakerStatus())
+}
+
 // handleUpdateTraderPrompt 更新交易员自定义Prompt
 func (s *Server) handleUpdateTraderPrompt(c *gin.Context) {
 	traderID := c.Param("id")
-	userID := c.GetString("user_id")
 
 	var req struct {
 		CustomPrompt       string `json:"custom_prompt"`
@@ -931,8 +1254,13 @@ func (s *Server) handleUpdateTraderPrompt(c *gin.Context) {
 		return
 	}
 
+	access, ok := s.requireTraderRole(c, traderID, config.OrgRoleOperator)
+	if !ok {
+		return
+	}
+
 	// 更新数据库
-	err := s.database.UpdateTraderCustomPrompt(userID, traderID, req.CustomPrompt, req.OverrideBasePrompt)
+	err := s.database.UpdateTraderCustomPrompt(access.OwnerID, traderID, req.CustomPrompt, req.OverrideBasePrompt)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("更新自定义prompt失败: %v", err)})
 		return
@@ -951,7 +1279,10 @@ func (s *Server) handleUpdateTraderPrompt(c *gin.Context) {
 
 // handleGetModelConfigs 获取AI模型配置
 func (s *Server) handleGetModelConfigs(c *gin.Context) {
-	userID := c.GetString("user_id")
+	userID, _, ok := s.workspace(c, config.OrgRoleViewer)
+	if !ok {
+		return
+	}
 	log.Printf("🔍 查询用户 %s 的AI模型配置", userID)
 	models, err := s.database.GetAIModels(userID)
 	if err != nil {
@@ -979,7 +1310,11 @@ func (s *Server) handleGetModelConfigs(c *gin.Context) {
 
 // handleUpdateModelConfigs 更新AI模型配置（仅支持加密数据）
 func (s *Server) handleUpdateModelConfigs(c *gin.Context) {
-	userID := c.GetString("user_id")
+	// 组织工作区中只有组织创建者可以修改交易所和模型密钥
+	userID, ok := s.credentialOwner(c)
+	if !ok {
+		return
+	}
 
 	// 读取原始请求体
 	bodyBytes, err := c.GetRawData()
@@ -1027,6 +1362,10 @@ func (s *Server) handleUpdateModelConfigs(c *gin.Context) {
 	// 更新每个模型的配置
 	for modelID, modelData := range req.Models {
 		err := s.database.UpdateAIModel(userID, modelID, modelData.Enabled, modelData.APIKey, modelData.CustomAPIURL, modelData.CustomModelName)
+		if errors.Is(err, config.ErrUnsupportedAIProvider) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("更新模型 %s 失败: %v", modelID, err)})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("更新模型 %s 失败: %v", modelID, err)})
 			return
@@ -1046,7 +1385,10 @@ func (s *Server) handleUpdateModelConfigs(c *gin.Context) {
 
 // handleGetExchangeConfigs 获取交易所配置
 func (s *Server) handleGetExchangeConfigs(c *gin.Context) {
-	userID := c.GetString("user_id")
+	userID, _, ok := s.workspace(c, config.OrgRoleViewer)
+	if !ok {
+		return
+	}
 	log.Printf("🔍 查询用户 %s 的交易所配置", userID)
 	exchanges, err := s.database.GetExchanges(userID)
 	if err != nil {
@@ -1076,7 +1418,11 @@ func (s *Server) handleGetExchangeConfigs(c *gin.Context) {
 
 // handleUpdateExchangeConfigs 更新交易所配置（仅支持加密数据）
 func (s *Server) handleUpdateExchangeConfigs(c *gin.Context) {
-	userID := c.GetString("user_id")
+	// 组织工作区中只有组织创建者可以修改交易所和模型密钥
+	userID, ok := s.credentialOwner(c)
+	if !ok {
+		return
+	}
 
 	// 读取原始请求体
 	bodyBytes, err := c.GetRawData()
@@ -1185,8 +1531,11 @@ func (s *Server) handleSaveUserSignalSource(c *gin.Context) {
 
 // handleTraderList trader列表
 func (s *Server) handleTraderList(c *gin.Context) {
-	userID := c.GetString("user_id")
-	traders, err := s.database.GetTraders(userID)
+	ownerID, orgID, ok := s.workspace(c, config.OrgRoleViewer)
+	if !ok {
+		return
+	}
+	traders, err := s.database.GetTraders(ownerID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取交易员列表失败: %v", err)})
 		return
@@ -1194,6 +1543,19 @@ func (s *Server) handleTraderList(c *gin.Context) {
 
 	result := make([]map[string]interface{}, 0, len(traders))
 	for _, trader := range traders {
+		// 组织工作区只列出该组织中当前用户可见的交易员
+		role := config.OrgRoleOwner
+		if orgID != "" {
+			if trader.OrgID != orgID {
+				continue
+			}
+			access, err := s.database.GetTraderAccess(c.GetString("user_id"), trader.ID)
+			if err != nil || !config.OrgRoleAtLeast(access.Role, config.OrgRoleViewer) {
+				continue
+			}
+			role = access.Role
+		}
+
 		// 获取实时运行状态
 		isRunning := trader.IsRunning
 		if at, err := s.traderManager.GetTrader(trader.ID); err == nil {
@@ -1213,6 +1575,8 @@ func (s *Server) handleTraderList(c *gin.Context) {
 			"is_running":             isRunning,
 			"initial_balance":        trader.InitialBalance,
 			"system_prompt_template": trader.SystemPromptTemplate,
+			"org_id":                 trader.OrgID,
+			"role":                   role,
 		})
 	}
 
@@ -1221,7 +1585,6 @@ func (s *Server) handleTraderList(c *gin.Context) {
 
 // handleGetTraderConfig 获取交易员详细配置
 func (s *Server) handleGetTraderConfig(c *gin.Context) {
-	userID := c.GetString("user_id")
 	traderID := c.Param("id")
 
 	if traderID == "" {
@@ -1229,7 +1592,12 @@ func (s *Server) handleGetTraderConfig(c *gin.Context) {
 		return
 	}
 
-	traderConfig, _, _, err := s.database.GetTraderConfig(userID, traderID)
+	access, ok := s.requireTraderRole(c, traderID, config.OrgRoleViewer)
+	if !ok {
+		return
+	}
+
+	traderConfig, _, _, err := s.database.GetTraderConfig(access.OwnerID, traderID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("获取交易员配置失败: %v", err)})
 		return
@@ -1263,7 +1631,22 @@ func (s *Server) handleGetTraderConfig(c *gin.Context) {
 		"is_cross_margin":        traderConfig.IsCrossMargin,
 		"use_coin_pool":          traderConfig.UseCoinPool,
 		"use_oi_top":             traderConfig.UseOITop,
+		"decision_mode":          traderConfig.DecisionMode,
+		"execution_mode":         traderConfig.ExecutionMode,
+		"auto_approve_notional":  traderConfig.AutoApproveNotional,
+		"limit_order_timeout":    traderConfig.LimitOrderTimeout,
+		"limit_order_fallback":   traderConfig.LimitOrderFallback,
+		"market_timeframes":      traderConfig.MarketTimeframes,
+		"market_indicators":      traderConfig.MarketIndicators,
 		"is_running":             isRunning,
+		"org_id":                 traderConfig.OrgID,
+		"role":                   access.Role,
+	}
+	if traderConfig.RiskConfig != "" {
+		result["risk_config"] = json.RawMessage(traderConfig.RiskConfig)
+	}
+	if traderConfig.ExitPolicy != "" {
+		result["exit_policy"] = json.RawMessage(traderConfig.ExitPolicy)
 	}
 
 	c.JSON(http.StatusOK, result)
@@ -1276,6 +1659,9 @@ func (s *Server) handleStatus(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if _, ok := s.requireTraderRole(c, traderID, config.OrgRoleViewer); !ok {
+		return
+	}
 
 	trader, err := s.traderManager.GetTrader(traderID)
 	if err != nil {
@@ -1294,6 +1680,9 @@ func (s *Server) handleAccount(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if _, ok := s.requireTraderRole(c, traderID, config.OrgRoleViewer); !ok {
+		return
+	}
 
 	trader, err := s.traderManager.GetTrader(traderID)
 	if err != nil {
@@ -1327,6 +1716,9 @@ func (s *Server) handlePositions(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if _, ok := s.requireTraderRole(c, traderID, config.OrgRoleViewer); !ok {
+		return
+	}
 
 	trader, err := s.traderManager.GetTrader(traderID)
 	if err != nil {
@@ -1352,6 +1744,9 @@ func (s *Server) handleDecisions(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if _, ok := s.requireTraderRole(c, traderID, config.OrgRoleViewer); !ok {
+		return
+	}
 
 	trader, err := s.traderManager.GetTrader(traderID)
 	if err != nil {
@@ -1378,6 +1773,9 @@ func (s *Server) handleLatestDecisions(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if _, ok := s.requireTraderRole(c, traderID, config.OrgRoleViewer); !ok {
+		return
+	}
 
 	trader, err := s.traderManager.GetTrader(traderID)
 	if err != nil {
@@ -1417,6 +1815,9 @@ func (s *Server) handleStatistics(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if _, ok := s.requireTraderRole(c, traderID, config.OrgRoleViewer); !ok {
+		return
+	}
 
 	trader, err := s.traderManager.GetTrader(traderID)
 	if err != nil {
@@ -1472,7 +1873,7 @@ func (s *Server) handleEquityHistory(c *gin.Context) {
 
 	// 获取尽可能多的历史数据（几天的数据）
 	// 每3分钟一个周期：10000条 = 约20天的数据
-	records, err := trader.GetDecisionLogger().GetLatestRecords(10000)
+	records, err := logger.LatestSummaries(trader.GetDecisionLogger(), 10000)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": fmt.Sprintf("获取历史数据失败: %v", err),
@@ -1553,6 +1954,9 @@ func (s *Server) handlePerformance(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if _, ok := s.requireTraderRole(c, traderID, config.OrgRoleViewer); !ok {
+		return
+	}
 
 	trader, err := s.traderManager.GetTrader(traderID)
 	if err != nil {
@@ -1562,7 +1966,7 @@ func (s *Server) handlePerformance(c *gin.Context) {
 
 	// 分析最近100个周期的交易表现（避免长期持仓的交易记录丢失）
 	// 假设每3分钟一个周期，100个周期 = 5小时，足够覆盖大部分交易
-	performance, err := trader.GetDecisionLogger().AnalyzePerformance(100)
+	performance, err := trader.AnalyzePerformance(100)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": fmt.Sprintf("分析历史表现失败: %v", err),
@@ -1593,6 +1997,16 @@ func (s *Server) authMiddleware() gin.HandlerFunc {
 
 		tokenString := tokenParts[1]
 
+		// API 令牌（脚本/机器人使用，按权限范围限制可访问的接口）
+		if auth.IsAPIToken(tokenString) {
+			if !s.authenticateAPIToken(c, tokenString) {
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
 		// 黑名单检查
 		if auth.IsTokenBlacklisted(tokenString) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "token已失效，请重新登录"})
@@ -2005,6 +2419,12 @@ func (s *Server) Start() error {
 	log.Printf("  • DELETE /api/traders/:id    - 删除AI交易员")
 	log.Printf("  • POST /api/traders/:id/start - 启动AI交易员")
 	log.Printf("  • POST /api/traders/:id/stop  - 停止AI交易员")
+	log.Printf("  • GET  /api/traders/:id/events - AI交易员实时事件流（SSE）")
+	log.Printf("  • POST /api/traders/:id/orders - 手动开仓/平仓/部分平仓/调整止损止盈")
+	log.Printf("  • POST /api/traders/:id/flatten - 手动平掉交易员所有持仓")
+	log.Printf("  • GET  /api/traders/:id/orders/pending - 跟踪中的限价开仓挂单")
+	log.Printf("  • GET  /api/traders/:id/proposals - 审批模式下的AI决策提案")
+	log.Printf("  • POST /api/traders/:id/proposals/:proposal_id/approve|reject - 批准/拒绝提案")
 	log.Printf("  • GET  /api/models           - 获取AI模型配置")
 	log.Printf("  • PUT  /api/models           - 更新AI模型配置")
 	log.Printf("  • GET  /api/exchanges        - 获取交易所配置")
@@ -2014,8 +2434,22 @@ func (s *Server) Start() error {
 	log.Printf("  • GET  /api/positions?trader_id=xxx  - 指定trader的持仓列表")
 	log.Printf("  • GET  /api/decisions?trader_id=xxx  - 指定trader的决策日志")
 	log.Printf("  • GET  /api/decisions/latest?trader_id=xxx - 指定trader的最新决策")
+	log.Printf("  • GET  /api/decisions/query?trader_id=xxx&symbol=&action=&start=&end=&limit=&offset= - 指定trader的决策日志条件查询（分页）")
 	log.Printf("  • GET  /api/statistics?trader_id=xxx - 指定trader的统计信息")
 	log.Printf("  • GET  /api/performance?trader_id=xxx - 指定trader的AI学习表现分析")
+	log.Printf("  • GET/POST /api/webhooks      - Webhook 订阅列表/创建")
+	log.Printf("  • PUT/DELETE /api/webhooks/:id - 更新/删除 Webhook")
+	log.Printf("  • GET  /api/webhooks/:id/deliveries - Webhook 投递记录")
+	log.Printf("  • POST /api/telegram/bind-code - 生成 Telegram 机器人绑定码")
+	log.Printf("  • GET/POST /api/tokens        - API令牌列表/创建（read/trade/admin 范围）")
+	log.Printf("  • DELETE /api/tokens/:id      - 撤销API令牌")
+	log.Printf("  • GET/POST /api/orgs          - 组织列表/创建组织")
+	log.Printf("  • GET/PUT/DELETE /api/orgs/:org_id/members[/:user_id] - 组织成员与角色（owner/operator/viewer）")
+	log.Printf("  • GET/POST /api/orgs/:org_id/invitations - 组织邀请，POST /api/invitations/:token/accept 接受邀请")
+	log.Printf("  • PUT  /api/traders/:id/org    - 将交易员移入组织工作区")
+	log.Printf("  • GET/PUT/DELETE /api/traders/:id/permissions[/:user_id] - 单个交易员的成员权限覆盖")
+	log.Printf("  • GET  /api/admin/encryption/status - 敏感数据加密状态（管理员）")
+	log.Printf("  • POST /api/admin/encryption/rotate - 用当前密钥重新加密敏感数据（管理员）")
 	log.Println()
 
 	// 创建 http.Server 以支持 graceful shutdown
@@ -2033,6 +2467,9 @@ func (s *Server) Shutdown() error {
 		return nil
 	}
 
+	// 先断开事件流长连接，否则 Shutdown 会一直等待到超时
+	close(s.closing)
+
 	// 设置 5 秒超时
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -2220,7 +2657,7 @@ func (s *Server) getEquityHistoryForTraders(traderIDs []string) map[string]inter
 		}
 
 		// 获取历史数据（用于对比展示，限制数据量）
-		records, err := trader.GetDecisionLogger().GetLatestRecords(500)
+		records, err := logger.LatestSummaries(trader.GetDecisionLogger(), 500)
 		if err != nil {
 			errors[traderID] = fmt.Sprintf("获取历史数据失败: %v", err)
 			continue
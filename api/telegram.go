@@ -0,0 +1,92 @@
+package api
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"nofx/config"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// telegramBindCodeTTL 绑定码有效期
+const telegramBindCodeTTL = 10 * time.Minute
+
+// handleCreateTelegramBindCode 生成一次性 Telegram 绑定码（在机器人中发送 /bind <code>）
+//
+// 请求体：{"can_control": true} 表示允许该聊天执行暂停/恢复/平仓等控制命令，默认只读
+func (s *Server) handleCreateTelegramBindCode(c *gin.Context) {
+	userID := c.GetString("user_id")
+	var req struct {
+		CanControl bool `json:"can_control"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("生成绑定码失败: %v", err)})
+		return
+	}
+	code := &config.TelegramBindCode{
+		Code:       strings.ToUpper(hex.EncodeToString(buf)),
+		UserID:     userID,
+		CanControl: req.CanControl,
+		ExpiresAt:  time.Now().Add(telegramBindCodeTTL),
+	}
+	if err := s.database.CreateTelegramBindCode(code); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("保存绑定码失败: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":        code.Code,
+		"can_control": code.CanControl,
+		"expires_at":  code.ExpiresAt,
+		"command":     "/bind " + code.Code,
+	})
+}
+
+// handleListTelegramBindings 获取当前用户绑定的 Telegram 聊天
+func (s *Server) handleListTelegramBindings(c *gin.Context) {
+	userID := c.GetString("user_id")
+	bindings, err := s.database.GetTelegramBindings(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取绑定列表失败: %v", err)})
+		return
+	}
+	if bindings == nil {
+		bindings = []*config.TelegramBinding{}
+	}
+	c.JSON(http.StatusOK, bindings)
+}
+
+// handleDeleteTelegramBinding 解除 Telegram 聊天绑定
+func (s *Server) handleDeleteTelegramBinding(c *gin.Context) {
+	userID := c.GetString("user_id")
+	chatID, err := strconv.ParseInt(c.Param("chat_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 chat_id"})
+		return
+	}
+
+	if err := s.database.DeleteTelegramBinding(userID, chatID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "绑定不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("解除绑定失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已解除绑定"})
+}
@@ -0,0 +1,211 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"nofx/config"
+	"nofx/webhook"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// WebhookRequest 创建/更新 Webhook 请求
+type WebhookRequest struct {
+	URL      string   `json:"url"`
+	TraderID string   `json:"trader_id"` // 为空表示所有交易员
+	Events   []string `json:"events"`    // 为空表示全部事件
+	Secret   string   `json:"secret"`    // 创建时为空则自动生成；更新时为空保留原密钥
+	Enabled  *bool    `json:"enabled"`
+}
+
+// handleListWebhooks 获取当前用户的 Webhook 列表（密钥脱敏）
+func (s *Server) handleListWebhooks(c *gin.Context) {
+	userID := c.GetString("user_id")
+	webhooks, err := s.database.GetWebhooks(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取Webhook列表失败: %v", err)})
+		return
+	}
+
+	result := make([]*config.Webhook, 0, len(webhooks))
+	for _, w := range webhooks {
+		result = append(result, maskWebhookSecret(w))
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": result, "event_types": webhook.EventTypes})
+}
+
+// handleCreateWebhook 创建 Webhook（仅在创建时返回完整签名密钥）
+func (s *Server) handleCreateWebhook(c *gin.Context) {
+	userID := c.GetString("user_id")
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.validateWebhookRequest(userID, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Secret == "" {
+		secret, err := webhook.NewSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		req.Secret = secret
+	}
+
+	w := &config.Webhook{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		TraderID:  req.TraderID,
+		URL:       req.URL,
+		Secret:    req.Secret,
+		Events:    req.Events,
+		Enabled:   req.Enabled == nil || *req.Enabled,
+		CreatedAt: time.Now(),
+	}
+	if err := s.database.CreateWebhook(w); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("创建Webhook失败: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusCreated, w)
+}
+
+// handleUpdateWebhook 更新 Webhook
+func (s *Server) handleUpdateWebhook(c *gin.Context) {
+	userID := c.GetString("user_id")
+	existing, err := s.database.GetWebhook(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook不存在"})
+		return
+	}
+
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.validateWebhookRequest(userID, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	existing.URL = req.URL
+	existing.TraderID = req.TraderID
+	existing.Events = req.Events
+	existing.Secret = req.Secret
+	if req.Enabled != nil {
+		existing.Enabled = *req.Enabled
+	}
+	if err := s.database.UpdateWebhook(existing); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("更新Webhook失败: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, maskWebhookSecret(existing))
+}
+
+// handleDeleteWebhook 删除 Webhook
+func (s *Server) handleDeleteWebhook(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if err := s.database.DeleteWebhook(userID, c.Param("id")); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("删除Webhook失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook已删除"})
+}
+
+// handleWebhookDeliveries 获取 Webhook 最近的投递记录（limit 默认50，最大500）
+func (s *Server) handleWebhookDeliveries(c *gin.Context) {
+	userID := c.GetString("user_id")
+	w, err := s.database.GetWebhook(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook不存在"})
+		return
+	}
+
+	limit := 50
+	if limitStr := c.Query("limit"); limitStr != "" {
+		n, err := strconv.Atoi(limitStr)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 limit: " + limitStr})
+			return
+		}
+		limit = min(n, 500)
+	}
+
+	deliveries, err := s.database.GetWebhookDeliveries(w.ID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取投递记录失败: %v", err)})
+		return
+	}
+	if deliveries == nil {
+		deliveries = []*config.WebhookDelivery{}
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// handleTestWebhook 立即发送一条测试事件（不重试），返回投递结果
+func (s *Server) handleTestWebhook(c *gin.Context) {
+	userID := c.GetString("user_id")
+	w, err := s.database.GetWebhook(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook不存在"})
+		return
+	}
+
+	dispatcher := webhook.NewDispatcher(s.database)
+	dispatcher.SetRetry(1, 0)
+	delivery := dispatcher.Deliver(w, &webhook.Payload{
+		ID:        uuid.New().String(),
+		Event:     webhook.EventTest,
+		TraderID:  w.TraderID,
+		Timestamp: time.Now(),
+		Data:      map[string]any{"message": "nofx webhook 测试"},
+	})
+	c.JSON(http.StatusOK, delivery)
+}
+
+// validateWebhookRequest 校验 URL（禁止本机和内网地址）、事件类型和交易员归属
+func (s *Server) validateWebhookRequest(userID string, req *WebhookRequest) error {
+	req.URL = strings.TrimSpace(req.URL)
+	if err := webhook.ValidateURL(req.URL); err != nil {
+		return err
+	}
+	for _, e := range req.Events {
+		if !webhook.ValidEventType(e) {
+			return fmt.Errorf("不支持的事件类型: %s（可选: %s）", e, strings.Join(webhook.EventTypes, ", "))
+		}
+	}
+	if req.TraderID != "" {
+		access, err := s.database.GetTraderAccess(userID, req.TraderID)
+		if err != nil || !config.OrgRoleAtLeast(access.Role, config.OrgRoleViewer) {
+			return fmt.Errorf("交易员不存在或无访问权限: %s", req.TraderID)
+		}
+	}
+	return nil
+}
+
+// maskWebhookSecret 返回密钥脱敏后的副本
+func maskWebhookSecret(w *config.Webhook) *config.Webhook {
+	masked := *w
+	if len(masked.Secret) > 10 {
+		masked.Secret = masked.Secret[:10] + "****"
+	} else if masked.Secret != "" {
+		masked.Secret = "****"
+	}
+	return &masked
+}
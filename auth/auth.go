@@ -2,8 +2,11 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
@@ -152,3 +155,50 @@ func ValidateJWT(tokenString string) (*Claims, error) {
 func GetOTPQRCodeURL(secret, email string) string {
 	return fmt.Sprintf("otpauth://totp/%s:%s?secret=%s&issuer=%s", OTPIssuer, email, secret, OTPIssuer)
 }
+
+// APITokenPrefix API 令牌前缀（用于与 JWT 区分）
+const APITokenPrefix = "nofx_"
+
+// API 令牌权限范围
+const (
+	TokenScopeRead  = "read"  // 只读：仅允许 GET 请求
+	TokenScopeTrade = "trade" // 交易控制：允许创建/启停交易员、手动下单、审批提案等写操作
+	TokenScopeAdmin = "admin" // 管理：额外允许管理令牌、组织权限、交易所/模型密钥、Webhook 和 Telegram 绑定
+)
+
+var tokenScopeRank = map[string]int{
+	TokenScopeRead:  1,
+	TokenScopeTrade: 2,
+	TokenScopeAdmin: 3,
+}
+
+// ValidTokenScope 是否为有效的令牌权限范围
+func ValidTokenScope(scope string) bool {
+	return tokenScopeRank[scope] > 0
+}
+
+// TokenScopeAllows 令牌权限范围是否包含所需范围
+func TokenScopeAllows(scope, required string) bool {
+	return ValidTokenScope(scope) && tokenScopeRank[scope] >= tokenScopeRank[required]
+}
+
+// IsAPIToken 是否为 API 令牌（而非 JWT）
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
+
+// GenerateAPIToken 生成 API 令牌，返回明文（仅在创建时展示一次）和用于存储的哈希
+func GenerateAPIToken() (token, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("生成API令牌失败: %w", err)
+	}
+	token = APITokenPrefix + hex.EncodeToString(buf)
+	return token, HashAPIToken(token), nil
+}
+
+// HashAPIToken 计算 API 令牌的 SHA-256 哈希（数据库只保存哈希）
+func HashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,301 @@
+package backtest
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"nofx/market"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// klineWindow 每个周期提供给指标计算的K线数量（与实时WSMonitor保持一致）
+	klineWindow = 100
+	// fetchPageLimit 币安单次K线请求上限
+	fetchPageLimit = 1500
+)
+
+// symbolPattern 合法的回测币种（币种名会拼接进缓存/CSV文件路径，禁止路径字符）
+var symbolPattern = regexp.MustCompile(`^[A-Z0-9]+USDT$`)
+
+// ValidSymbol 判断币种是否为合法的 USDT 永续合约名称（如 BTCUSDT）
+func ValidSymbol(symbol string) bool {
+	return symbolPattern.MatchString(symbol)
+}
+
+// SymbolData 单个币种的历史K线
+type SymbolData struct {
+	Klines3m []market.Kline `json:"klines_3m"`
+	Klines4h []market.Kline `json:"klines_4h"`
+}
+
+// Dataset 回测数据集（symbol -> K线）
+type Dataset map[string]*SymbolData
+
+// KlineFetcher 按时间范围拉取K线（默认使用 market.APIClient）
+type KlineFetcher func(symbol, interval string, startTime, endTime int64, limit int) ([]market.Kline, error)
+
+// DataConfig 数据加载配置
+type DataConfig struct {
+	Symbols   []string
+	StartTime time.Time
+	EndTime   time.Time
+	CSVDir    string       // CSV目录（文件名: <SYMBOL>_<interval>.csv），优先使用
+	CacheDir  string       // 本地缓存目录，缺失时从交易所拉取并写入
+	Fetcher   KlineFetcher // 为空时使用 market.NewAPIClient().GetKlinesRange
+}
+
+// LoadDataset 加载回测所需的3分钟和4小时K线（含指标预热区间）
+func LoadDataset(cfg DataConfig) (Dataset, error) {
+	if len(cfg.Symbols) == 0 {
+		return nil, fmt.Errorf("回测币种不能为空")
+	}
+	if !cfg.EndTime.After(cfg.StartTime) {
+		return nil, fmt.Errorf("回测结束时间必须晚于开始时间")
+	}
+	if cfg.Fetcher == nil {
+		cfg.Fetcher = market.NewAPIClient().GetKlinesRange
+	}
+
+	dataset := make(Dataset)
+	for _, symbol := range cfg.Symbols {
+		symbol = market.Normalize(symbol)
+		if !ValidSymbol(symbol) {
+			return nil, fmt.Errorf("无效的币种: %s", symbol)
+		}
+		data := &SymbolData{}
+		for _, interval := range []string{"3m", "4h"} {
+			step := intervalDuration(interval)
+			// 预热：开始时间前至少 klineWindow 根K线，保证指标可计算
+			from := cfg.StartTime.Add(-step * klineWindow).UnixMilli()
+			to := cfg.EndTime.UnixMilli()
+
+			klines, err := loadKlines(cfg, symbol, interval, from, to)
+			if err != nil {
+				return nil, fmt.Errorf("加载 %s %s K线失败: %w", symbol, interval, err)
+			}
+			if len(klines) == 0 {
+				return nil, fmt.Errorf("%s %s 在回测区间内没有K线数据", symbol, interval)
+			}
+			if interval == "3m" {
+				data.Klines3m = klines
+			} else {
+				data.Klines4h = klines
+			}
+		}
+		dataset[symbol] = data
+	}
+	return dataset, nil
+}
+
+// loadKlines 按 CSV → 缓存 → 交易所 的顺序加载K线
+func loadKlines(cfg DataConfig, symbol, interval string, from, to int64) ([]market.Kline, error) {
+	if cfg.CSVDir != "" {
+		klines, err := LoadKlinesCSV(filepath.Join(cfg.CSVDir, fmt.Sprintf("%s_%s.csv", symbol, interval)))
+		if err != nil {
+			return nil, err
+		}
+		return filterRange(klines, from, to), nil
+	}
+
+	var cachePath string
+	if cfg.CacheDir != "" {
+		cachePath = filepath.Join(cfg.CacheDir, fmt.Sprintf("%s_%s.json", symbol, interval))
+		if cached, err := loadCache(cachePath); err == nil && coversRange(cached, interval, from, to) {
+			return filterRange(cached, from, to), nil
+		}
+	}
+
+	klines, err := fetchRange(cfg.Fetcher, symbol, interval, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	if cachePath != "" {
+		if err := saveCache(cachePath, klines); err != nil {
+			log.Printf("⚠️  写入K线缓存失败: %v", err)
+		}
+	}
+	return klines, nil
+}
+
+// fetchRange 分页拉取 [from, to] 区间的K线
+func fetchRange(fetch KlineFetcher, symbol, interval string, from, to int64) ([]market.Kline, error) {
+	var all []market.Kline
+	cursor := from
+	for cursor <= to {
+		page, err := fetch(symbol, interval, cursor, to, fetchPageLimit)
+		if err != nil {
+			return nil, err
+		}
+		if len(page) == 0 {
+			break
+		}
+		all = append(all, page...)
+		next := page[len(page)-1].OpenTime + 1
+		if next <= cursor {
+			break
+		}
+		cursor = next
+		if len(page) < fetchPageLimit {
+			break
+		}
+	}
+	log.Printf("📥 拉取 %s %s K线 %d 根", symbol, interval, len(all))
+	return dedupeSorted(all), nil
+}
+
+// LoadKlinesCSV 读取币安格式的K线CSV
+// 列顺序: open_time, open, high, low, close, volume, close_time, quote_volume, trades, taker_buy_base, taker_buy_quote
+// 首行为表头时自动跳过
+func LoadKlinesCSV(path string) ([]market.Kline, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+
+	var klines []market.Kline
+	line := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析CSV失败: %w", err)
+		}
+		line++
+		if len(record) < 7 {
+			return nil, fmt.Errorf("第 %d 行列数不足: %d", line, len(record))
+		}
+		if _, err := strconv.ParseInt(strings.TrimSpace(record[0]), 10, 64); err != nil {
+			if line == 1 {
+				continue // 表头
+			}
+			return nil, fmt.Errorf("第 %d 行开盘时间无效: %s", line, record[0])
+		}
+
+		kline, err := parseCSVKline(record)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行: %w", line, err)
+		}
+		klines = append(klines, kline)
+	}
+	return dedupeSorted(klines), nil
+}
+
+// parseCSVKline 解析一行CSV
+func parseCSVKline(record []string) (market.Kline, error) {
+	var k market.Kline
+	var err error
+
+	ints := func(i int) int64 {
+		if err != nil || i >= len(record) {
+			return 0
+		}
+		var v int64
+		v, err = strconv.ParseInt(strings.TrimSpace(record[i]), 10, 64)
+		return v
+	}
+	floats := func(i int) float64 {
+		if err != nil || i >= len(record) {
+			return 0
+		}
+		var v float64
+		v, err = strconv.ParseFloat(strings.TrimSpace(record[i]), 64)
+		return v
+	}
+
+	k.OpenTime = ints(0)
+	k.Open = floats(1)
+	k.High = floats(2)
+	k.Low = floats(3)
+	k.Close = floats(4)
+	k.Volume = floats(5)
+	k.CloseTime = ints(6)
+	k.QuoteVolume = floats(7)
+	k.Trades = int(ints(8))
+	k.TakerBuyBaseVolume = floats(9)
+	k.TakerBuyQuoteVolume = floats(10)
+	return k, err
+}
+
+// loadCache 读取JSON缓存
+func loadCache(path string) ([]market.Kline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var klines []market.Kline
+	if err := json.Unmarshal(data, &klines); err != nil {
+		return nil, err
+	}
+	return klines, nil
+}
+
+// saveCache 写入JSON缓存
+func saveCache(path string, klines []market.Kline) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(klines)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// coversRange 缓存是否覆盖所需区间
+func coversRange(klines []market.Kline, interval string, from, to int64) bool {
+	if len(klines) == 0 {
+		return false
+	}
+	step := intervalDuration(interval).Milliseconds()
+	return klines[0].OpenTime <= from+step && klines[len(klines)-1].OpenTime >= to-step
+}
+
+// filterRange 截取开盘时间在 [from, to] 内的K线
+func filterRange(klines []market.Kline, from, to int64) []market.Kline {
+	var result []market.Kline
+	for _, k := range klines {
+		if k.OpenTime >= from && k.OpenTime <= to {
+			result = append(result, k)
+		}
+	}
+	return result
+}
+
+// dedupeSorted 按开盘时间排序并去重
+func dedupeSorted(klines []market.Kline) []market.Kline {
+	sort.SliceStable(klines, func(i, j int) bool { return klines[i].OpenTime < klines[j].OpenTime })
+	result := klines[:0]
+	for i, k := range klines {
+		if i > 0 && k.OpenTime == result[len(result)-1].OpenTime {
+			continue
+		}
+		result = append(result, k)
+	}
+	return result
+}
+
+// intervalDuration K线周期对应的时长
+func intervalDuration(interval string) time.Duration {
+	switch interval {
+	case "4h":
+		return 4 * time.Hour
+	default:
+		return 3 * time.Minute
+	}
+}
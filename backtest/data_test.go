@@ -0,0 +1,109 @@
+package backtest
+
+import (
+	"nofx/market"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadKlinesCSV(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "BTCUSDT_3m.csv")
+	content := "open_time,open,high,low,close,volume,close_time,quote_volume,count,taker_buy_volume,taker_buy_quote_volume,ignore\n" +
+		"1735718580000,101,102,100,101.5,10,1735718759999,1015,5,4,404,0\n" +
+		"1735718400000,100,101,99,100.5,20,1735718579999,2010,8,9,900,0\n" +
+		"1735718400000,100,101,99,100.5,20,1735718579999,2010,8,9,900,0\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	klines, err := LoadKlinesCSV(path)
+	require.NoError(t, err)
+	require.Len(t, klines, 2, "应跳过表头并去重")
+
+	assert.Equal(t, int64(1735718400000), klines[0].OpenTime, "应按开盘时间排序")
+	assert.Equal(t, market.Kline{
+		OpenTime: 1735718580000, Open: 101, High: 102, Low: 100, Close: 101.5, Volume: 10,
+		CloseTime: 1735718759999, QuoteVolume: 1015, Trades: 5, TakerBuyBaseVolume: 4, TakerBuyQuoteVolume: 404,
+	}, klines[1])
+}
+
+func TestLoadKlinesCSVInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"列数不足", "1735718400000,100,101\n"},
+		{"价格无效", "1735718400000,abc,101,99,100,1,1735718579999\n"},
+		{"非首行时间无效", "1735718400000,100,101,99,100,1,1735718579999\nxx,100,101,99,100,1,1735718579999\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "bad.csv")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0644))
+			_, err := LoadKlinesCSV(path)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoadDatasetUsesCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	calls := 0
+	fetcher := func(symbol, interval string, startTime, endTime int64, limit int) ([]market.Kline, error) {
+		calls++
+		step := intervalDuration(interval).Milliseconds()
+		var klines []market.Kline
+		for ts := startTime - startTime%step; ts <= endTime && len(klines) < limit; ts += step {
+			klines = append(klines, market.Kline{OpenTime: ts, Close: 100, CloseTime: ts + step - 1})
+		}
+		return klines, nil
+	}
+
+	cfg := DataConfig{
+		Symbols:   []string{"BTCUSDT"},
+		StartTime: testStart,
+		EndTime:   testStart.Add(24 * time.Hour),
+		CacheDir:  cacheDir,
+		Fetcher:   fetcher,
+	}
+
+	data, err := LoadDataset(cfg)
+	require.NoError(t, err)
+	firstCalls := calls
+	// 3m（预热100根 + 24小时480根）和 4h 各请求一页
+	assert.Equal(t, 2, firstCalls)
+	assert.Len(t, data["BTCUSDT"].Klines3m, 581)
+	assert.FileExists(t, filepath.Join(cacheDir, "BTCUSDT_3m.json"))
+	assert.FileExists(t, filepath.Join(cacheDir, "BTCUSDT_4h.json"))
+
+	// 第二次加载命中缓存，不再请求交易所
+	cached, err := LoadDataset(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, firstCalls, calls)
+	assert.Equal(t, data, cached)
+}
+
+func TestLoadDatasetRejectsInvalidSymbol(t *testing.T) {
+	cfg := DataConfig{
+		Symbols:   []string{"../../x/fooUSDT"},
+		StartTime: testStart,
+		EndTime:   testStart.Add(time.Hour),
+		CacheDir:  t.TempDir(),
+		Fetcher: func(symbol, interval string, startTime, endTime int64, limit int) ([]market.Kline, error) {
+			t.Fatal("无效币种不应请求交易所")
+			return nil, nil
+		},
+	}
+	_, err := LoadDataset(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "无效的币种")
+
+	assert.True(t, ValidSymbol("1000PEPEUSDT"))
+	assert.False(t, ValidSymbol("BTC/USDT"))
+	assert.False(t, ValidSymbol("USDT"))
+}
@@ -0,0 +1,726 @@
+package backtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+	"nofx/mcp"
+	"nofx/trader"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultScanInterval 默认决策间隔（与实时交易员一致）
+	defaultScanInterval = 3 * time.Minute
+	// defaultTakerFeeRate 默认Taker手续费率
+	defaultTakerFeeRate = 0.0004
+	// performanceWindow 提供给AI的历史表现分析窗口（周期数，与实时交易员一致）
+	performanceWindow = 100
+	// minPositionValue 部分平仓后剩余仓位的最小价值，低于此值直接全平
+	minPositionValue = 10.0
+)
+
+// Config 回测配置
+type Config struct {
+	Symbols              []string      `json:"symbols"`
+	StartTime            time.Time     `json:"start_time"`
+	EndTime              time.Time     `json:"end_time"`
+	InitialBalance       float64       `json:"initial_balance"`
+	ScanInterval         time.Duration `json:"scan_interval"`          // 决策间隔（3分钟的整数倍，默认3分钟）
+	BTCETHLeverage       int           `json:"btc_eth_leverage"`       // 默认5
+	AltcoinLeverage      int           `json:"altcoin_leverage"`       // 默认5
+	TakerFeeRate         float64       `json:"taker_fee_rate"`         // 为0时使用默认值 0.04%
+	FundingRate          float64       `json:"funding_rate"`           // 每8小时资金费率（回测期间视为常数）
+	CustomPrompt         string        `json:"custom_prompt"`          // 自定义交易策略prompt
+	OverrideBasePrompt   bool          `json:"override_base_prompt"`   // 是否覆盖基础prompt
+	SystemPromptTemplate string        `json:"system_prompt_template"` // 系统提示词模板名称
+	DecisionMode         string        `json:"decision_mode"`          // 决策输出模式: text / tool_call（不支持 agent）
+
+	// Progress 每个周期结束后回调（可选）
+	Progress func(done, total int) `json:"-"`
+}
+
+// EquityPoint 权益曲线上的一个点
+type EquityPoint struct {
+	Timestamp     time.Time `json:"timestamp"`
+	Cycle         int       `json:"cycle"`
+	Equity        float64   `json:"equity"`
+	WalletBalance float64   `json:"wallet_balance"`
+	UnrealizedPnL float64   `json:"unrealized_pnl"`
+	PositionCount int       `json:"position_count"`
+	DrawdownPct   float64   `json:"drawdown_pct"` // 相对历史最高权益的回撤
+}
+
+// Result 回测结果
+type Result struct {
+	StartTime      time.Time                   `json:"start_time"`
+	EndTime        time.Time                   `json:"end_time"`
+	InitialBalance float64                     `json:"initial_balance"`
+	FinalEquity    float64                     `json:"final_equity"`
+	TotalReturnPct float64                     `json:"total_return_pct"`
+	MaxDrawdownPct float64                     `json:"max_drawdown_pct"`
+	TotalFees      float64                     `json:"total_fees"`
+	TotalFunding   float64                     `json:"total_funding"`
+	Cycles         int                         `json:"cycles"`
+	FailedCycles   int                         `json:"failed_cycles"`
+	EquityCurve    []EquityPoint               `json:"equity_curve"`
+	Performance    *logger.PerformanceAnalysis `json:"performance"`
+
+	// Records 每个周期的决策记录（时间戳为模拟时间）
+	Records []*logger.DecisionRecord `json:"-"`
+}
+
+// engine 单次回测的运行状态
+type engine struct {
+	cfg    Config
+	data   Dataset
+	client mcp.AIClient
+	trader *trader.PaperTrader
+
+	now              time.Time
+	positionOpenTime map[string]time.Time // symbol_side -> 模拟开仓时间
+	triggered        []trader.PaperTriggerFill
+	records          []*logger.DecisionRecord
+}
+
+// Run 使用历史K线逐周期重放决策流程：重建市场数据 → 构建上下文 → 调用AI → 在模拟账本上执行
+func Run(cfg Config, data Dataset, client mcp.AIClient) (*Result, error) {
+	if err := normalizeConfig(&cfg); err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, fmt.Errorf("AI客户端不能为空")
+	}
+	for _, symbol := range cfg.Symbols {
+		if sd, ok := data[symbol]; !ok || len(sd.Klines3m) == 0 || len(sd.Klines4h) == 0 {
+			return nil, fmt.Errorf("缺少 %s 的K线数据", symbol)
+		}
+	}
+
+	e := &engine{
+		cfg:              cfg,
+		data:             data,
+		client:           client,
+		now:              cfg.StartTime,
+		positionOpenTime: make(map[string]time.Time),
+	}
+
+	pt, err := trader.NewPaperTraderWithFeed(cfg.InitialBalance, cfg.TakerFeeRate, trader.PaperFeed{
+		Klines:      e.visibleKlines3m,
+		FundingRate: func(string) (float64, error) { return cfg.FundingRate, nil },
+		Now:         func() time.Time { return e.now },
+		OnTriggered: func(fill trader.PaperTriggerFill) { e.triggered = append(e.triggered, fill) },
+	})
+	if err != nil {
+		return nil, fmt.Errorf("创建模拟账户失败: %w", err)
+	}
+	e.trader = pt
+
+	total := int(cfg.EndTime.Sub(cfg.StartTime)/cfg.ScanInterval) + 1
+	log.Printf("🧪 开始回测: %s ~ %s, 币种 %v, 共 %d 个周期",
+		cfg.StartTime.Format("2006-01-02 15:04"), cfg.EndTime.Format("2006-01-02 15:04"), cfg.Symbols, total)
+
+	result := &Result{
+		StartTime:      cfg.StartTime,
+		EndTime:        cfg.EndTime,
+		InitialBalance: cfg.InitialBalance,
+	}
+
+	peakEquity := cfg.InitialBalance
+	cycle := 0
+	for t := cfg.StartTime; !t.After(cfg.EndTime); t = t.Add(cfg.ScanInterval) {
+		cycle++
+		e.now = t
+
+		record := e.runCycle(cycle)
+		e.records = append(e.records, record)
+		if !record.Success {
+			result.FailedCycles++
+		}
+
+		point, err := e.snapshotEquity(cycle)
+		if err != nil {
+			return nil, err
+		}
+		if point.Equity > peakEquity {
+			peakEquity = point.Equity
+		}
+		if peakEquity > 0 {
+			point.DrawdownPct = (peakEquity - point.Equity) / peakEquity * 100
+		}
+		if point.DrawdownPct > result.MaxDrawdownPct {
+			result.MaxDrawdownPct = point.DrawdownPct
+		}
+		result.EquityCurve = append(result.EquityCurve, point)
+
+		if cfg.Progress != nil {
+			cfg.Progress(cycle, total)
+		}
+	}
+
+	balance, _ := e.trader.GetBalance()
+	result.TotalFees, _ = balance["totalFees"].(float64)
+	result.TotalFunding, _ = balance["totalFunding"].(float64)
+
+	result.Cycles = cycle
+	if len(result.EquityCurve) > 0 {
+		result.FinalEquity = result.EquityCurve[len(result.EquityCurve)-1].Equity
+	}
+	result.TotalReturnPct = (result.FinalEquity - cfg.InitialBalance) / cfg.InitialBalance * 100
+	result.Performance = logger.AnalyzeRecords(e.records, nil)
+	result.Records = e.records
+
+	log.Printf("✓ 回测完成: 最终权益 %.2f USDT (%+.2f%%), 最大回撤 %.2f%%, 交易 %d 笔",
+		result.FinalEquity, result.TotalReturnPct, result.MaxDrawdownPct, result.Performance.TotalTrades)
+	return result, nil
+}
+
+// normalizeConfig 校验配置并填充默认值
+func normalizeConfig(cfg *Config) error {
+	if len(cfg.Symbols) == 0 {
+		return fmt.Errorf("回测币种不能为空")
+	}
+	for i, symbol := range cfg.Symbols {
+		cfg.Symbols[i] = market.Normalize(symbol)
+	}
+	if !cfg.EndTime.After(cfg.StartTime) {
+		return fmt.Errorf("回测结束时间必须晚于开始时间")
+	}
+	if cfg.InitialBalance <= 0 {
+		return fmt.Errorf("初始资金必须大于0")
+	}
+	if cfg.ScanInterval <= 0 {
+		cfg.ScanInterval = defaultScanInterval
+	}
+	if cfg.ScanInterval%defaultScanInterval != 0 {
+		return fmt.Errorf("决策间隔必须是3分钟的整数倍")
+	}
+	if cfg.BTCETHLeverage <= 0 {
+		cfg.BTCETHLeverage = 5
+	}
+	if cfg.AltcoinLeverage <= 0 {
+		cfg.AltcoinLeverage = 5
+	}
+	if cfg.TakerFeeRate <= 0 {
+		cfg.TakerFeeRate = defaultTakerFeeRate
+	}
+	if cfg.DecisionMode == decision.DecisionModeAgent {
+		// Agent 模式的数据查询工具读取实时行情，回测中会引入未来数据
+		return fmt.Errorf("回测不支持 agent 决策模式")
+	}
+	return nil
+}
+
+// runCycle 执行一个回测周期，返回该周期的决策记录
+func (e *engine) runCycle(cycle int) *logger.DecisionRecord {
+	record := &logger.DecisionRecord{
+		Timestamp:    e.now,
+		CycleNumber:  cycle,
+		ExecutionLog: []string{},
+		Success:      true,
+	}
+
+	ctx, err := e.buildContext(cycle)
+	if err != nil {
+		record.Success = false
+		record.ErrorMessage = fmt.Sprintf("构建交易上下文失败: %v", err)
+		return record
+	}
+
+	// 上个周期以来止损/止盈/强平的自动成交，记录为 auto_close 以便绩效统计
+	for _, fill := range e.triggered {
+		record.Decisions = append(record.Decisions, logger.DecisionAction{
+			Action:    "auto_close_" + fill.Side,
+			Symbol:    fill.Symbol,
+			Quantity:  fill.Quantity,
+			Price:     fill.Price,
+			Timestamp: fill.Time,
+			Success:   true,
+		})
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s %s 自动平仓 @ %.4f", fill.Symbol, fill.Side, fill.Reason, fill.Price))
+		delete(e.positionOpenTime, fill.Symbol+"_"+fill.Side)
+	}
+	e.triggered = nil
+
+	record.AccountState = logger.AccountSnapshot{
+		TotalBalance:          ctx.Account.TotalEquity,
+		AvailableBalance:      ctx.Account.AvailableBalance,
+		TotalUnrealizedProfit: ctx.Account.UnrealizedPnL,
+		PositionCount:         ctx.Account.PositionCount,
+		MarginUsedPct:         ctx.Account.MarginUsedPct,
+		InitialBalance:        e.cfg.InitialBalance,
+	}
+	for _, pos := range ctx.Positions {
+		record.Positions = append(record.Positions, logger.PositionSnapshot{
+			Symbol:           pos.Symbol,
+			Side:             pos.Side,
+			PositionAmt:      pos.Quantity,
+			EntryPrice:       pos.EntryPrice,
+			MarkPrice:        pos.MarkPrice,
+			UnrealizedProfit: pos.UnrealizedPnL,
+			Leverage:         float64(pos.Leverage),
+			LiquidationPrice: pos.LiquidationPrice,
+		})
+	}
+	for _, coin := range ctx.CandidateCoins {
+		record.CandidateCoins = append(record.CandidateCoins, coin.Symbol)
+	}
+
+	fullDecision, err := decision.GetFullDecisionWithCustomPrompt(ctx, e.client, e.cfg.CustomPrompt, e.cfg.OverrideBasePrompt, e.cfg.SystemPromptTemplate)
+	if fullDecision != nil {
+		record.SystemPrompt = fullDecision.SystemPrompt
+		record.InputPrompt = fullDecision.UserPrompt
+		record.CoTTrace = fullDecision.CoTTrace
+		record.AIRequestDurationMs = fullDecision.AIRequestDurationMs
+		if len(fullDecision.Decisions) > 0 {
+			decisionJSON, _ := json.MarshalIndent(fullDecision.Decisions, "", "  ")
+			record.DecisionJSON = string(decisionJSON)
+		}
+	}
+	if err != nil {
+		record.Success = false
+		record.ErrorMessage = fmt.Sprintf("获取AI决策失败: %v", err)
+		return record
+	}
+
+	for _, d := range trader.SortDecisionsByPriority(fullDecision.Decisions) {
+		actionRecord := logger.DecisionAction{
+			Action:    d.Action,
+			Symbol:    d.Symbol,
+			Leverage:  d.Leverage,
+			Timestamp: e.now,
+		}
+
+		if err := e.execute(&d, &actionRecord); err != nil {
+			actionRecord.Error = err.Error()
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 失败: %v", d.Symbol, d.Action, err))
+		} else {
+			actionRecord.Success = true
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s 成功", d.Symbol, d.Action))
+		}
+		record.Decisions = append(record.Decisions, actionRecord)
+	}
+
+	return record
+}
+
+// buildContext 根据模拟时刻的账户和历史行情构建决策上下文
+func (e *engine) buildContext(cycle int) (*decision.Context, error) {
+	balance, err := e.trader.GetBalance()
+	if err != nil {
+		return nil, fmt.Errorf("获取账户余额失败: %w", err)
+	}
+	positions, err := e.trader.GetPositions()
+	if err != nil {
+		return nil, fmt.Errorf("获取持仓失败: %w", err)
+	}
+
+	wallet, _ := balance["totalWalletBalance"].(float64)
+	unrealized, _ := balance["totalUnrealizedProfit"].(float64)
+	available, _ := balance["availableBalance"].(float64)
+	totalEquity := wallet + unrealized
+
+	// 持仓时长在 prompt 中按 time.Now() 计算，这里把模拟持仓时长平移到真实时钟上
+	wallNow := time.Now()
+	var positionInfos []decision.PositionInfo
+	totalMarginUsed := 0.0
+	for _, pos := range positions {
+		symbol := pos["symbol"].(string)
+		side := pos["side"].(string)
+		quantity := math.Abs(pos["positionAmt"].(float64))
+		markPrice := pos["markPrice"].(float64)
+		unrealizedPnl := pos["unRealizedProfit"].(float64)
+		leverage := int(pos["leverage"].(float64))
+
+		marginUsed := quantity * markPrice / float64(leverage)
+		totalMarginUsed += marginUsed
+		pnlPct := 0.0
+		if marginUsed > 0 {
+			pnlPct = unrealizedPnl / marginUsed * 100
+		}
+
+		posKey := symbol + "_" + side
+		openTime, ok := e.positionOpenTime[posKey]
+		if !ok {
+			openTime = e.now
+			e.positionOpenTime[posKey] = openTime
+		}
+
+		positionInfos = append(positionInfos, decision.PositionInfo{
+			Symbol:           symbol,
+			Side:             side,
+			EntryPrice:       pos["entryPrice"].(float64),
+			MarkPrice:        markPrice,
+			Quantity:         quantity,
+			Leverage:         leverage,
+			UnrealizedPnL:    unrealizedPnl,
+			UnrealizedPnLPct: pnlPct,
+			LiquidationPrice: pos["liquidationPrice"].(float64),
+			MarginUsed:       marginUsed,
+			UpdateTime:       wallNow.Add(-e.now.Sub(openTime)).UnixMilli(),
+		})
+	}
+
+	marketDataMap := make(map[string]*market.Data)
+	var candidates []decision.CandidateCoin
+	for _, symbol := range e.cfg.Symbols {
+		klines3m, klines4h := e.windowKlines(symbol)
+		if len(klines3m) == 0 || len(klines4h) == 0 {
+			continue
+		}
+		marketDataMap[symbol] = market.BuildData(symbol, klines3m, klines4h, &market.OIData{}, e.cfg.FundingRate)
+		candidates = append(candidates, decision.CandidateCoin{Symbol: symbol, Sources: []string{"backtest"}})
+	}
+	if len(marketDataMap) == 0 {
+		return nil, fmt.Errorf("%s 没有可用的历史K线", e.now.Format("2006-01-02 15:04"))
+	}
+
+	totalPnL := totalEquity - e.cfg.InitialBalance
+	marginUsedPct := 0.0
+	if totalEquity > 0 {
+		marginUsedPct = totalMarginUsed / totalEquity * 100
+	}
+
+	// 分析历史表现（与实时交易员相同：最近100个周期，预填充300个周期内的开仓）
+	recent := e.records
+	if len(recent) > performanceWindow {
+		recent = recent[len(recent)-performanceWindow:]
+	}
+	extended := e.records
+	if len(extended) > performanceWindow*3 {
+		extended = extended[len(extended)-performanceWindow*3:]
+	}
+
+	return &decision.Context{
+		CurrentTime:     e.now.Format("2006-01-02 15:04:05"),
+		RuntimeMinutes:  int(e.now.Sub(e.cfg.StartTime).Minutes()),
+		CallCount:       cycle,
+		BTCETHLeverage:  e.cfg.BTCETHLeverage,
+		AltcoinLeverage: e.cfg.AltcoinLeverage,
+		DecisionMode:    e.cfg.DecisionMode,
+		Account: decision.AccountInfo{
+			TotalEquity:      totalEquity,
+			AvailableBalance: available,
+			UnrealizedPnL:    unrealized,
+			TotalPnL:         totalPnL,
+			TotalPnLPct:      totalPnL / e.cfg.InitialBalance * 100,
+			MarginUsed:       totalMarginUsed,
+			MarginUsedPct:    marginUsedPct,
+			PositionCount:    len(positionInfos),
+		},
+		Positions:      positionInfos,
+		CandidateCoins: candidates,
+		MarketDataMap:  marketDataMap,
+		Performance:    logger.AnalyzeRecords(recent, extended),
+	}, nil
+}
+
+// snapshotEquity 记录当前权益
+func (e *engine) snapshotEquity(cycle int) (EquityPoint, error) {
+	balance, err := e.trader.GetBalance()
+	if err != nil {
+		return EquityPoint{}, fmt.Errorf("获取账户余额失败: %w", err)
+	}
+	positions, err := e.trader.GetPositions()
+	if err != nil {
+		return EquityPoint{}, fmt.Errorf("获取持仓失败: %w", err)
+	}
+
+	wallet, _ := balance["totalWalletBalance"].(float64)
+	unrealized, _ := balance["totalUnrealizedProfit"].(float64)
+	return EquityPoint{
+		Timestamp:     e.now,
+		Cycle:         cycle,
+		Equity:        wallet + unrealized,
+		WalletBalance: wallet,
+		UnrealizedPnL: unrealized,
+		PositionCount: len(positions),
+	}, nil
+}
+
+// visibleKlines3m 截至模拟时刻已收盘的3分钟K线（供模拟账户撮合）
+func (e *engine) visibleKlines3m(symbol string) ([]market.Kline, error) {
+	sd, ok := e.data[symbol]
+	if !ok {
+		return nil, fmt.Errorf("缺少 %s 的K线数据", symbol)
+	}
+	klines := closedBefore(sd.Klines3m, e.now)
+	if len(klines) == 0 {
+		return nil, fmt.Errorf("%s 在 %s 之前没有K线", symbol, e.now.Format("2006-01-02 15:04"))
+	}
+	if len(klines) > klineWindow {
+		klines = klines[len(klines)-klineWindow:]
+	}
+	return klines, nil
+}
+
+// windowKlines 构建指标计算用的K线窗口
+// 4小时K线包含已收盘部分和由3分钟K线合成的当前未收盘K线，与实时行情一致
+func (e *engine) windowKlines(symbol string) ([]market.Kline, []market.Kline) {
+	sd := e.data[symbol]
+	klines3m := closedBefore(sd.Klines3m, e.now)
+	if len(klines3m) == 0 {
+		return nil, nil
+	}
+
+	klines4h := closedBefore(sd.Klines4h, e.now)
+	bucketStart := e.now.Truncate(4 * time.Hour).UnixMilli()
+	if forming, ok := aggregateSince(klines3m, bucketStart); ok {
+		klines4h = append(append([]market.Kline{}, klines4h...), forming)
+	}
+
+	if len(klines3m) > klineWindow {
+		klines3m = klines3m[len(klines3m)-klineWindow:]
+	}
+	if len(klines4h) > klineWindow {
+		klines4h = klines4h[len(klines4h)-klineWindow:]
+	}
+	return klines3m, klines4h
+}
+
+// closedBefore 返回收盘时间早于 t 的K线（输入按时间正序）
+func closedBefore(klines []market.Kline, t time.Time) []market.Kline {
+	ms := t.UnixMilli()
+	idx := sort.Search(len(klines), func(i int) bool {
+		return klineCloseTime(klines[i]) >= ms
+	})
+	return klines[:idx]
+}
+
+// klineCloseTime K线收盘时间（CSV缺失时按开盘时间推算）
+func klineCloseTime(k market.Kline) int64 {
+	if k.CloseTime > 0 {
+		return k.CloseTime
+	}
+	return k.OpenTime + defaultScanInterval.Milliseconds() - 1
+}
+
+// aggregateSince 将开盘时间不早于 start 的K线合成为一根
+func aggregateSince(klines []market.Kline, start int64) (market.Kline, bool) {
+	idx := sort.Search(len(klines), func(i int) bool { return klines[i].OpenTime >= start })
+	if idx >= len(klines) {
+		return market.Kline{}, false
+	}
+
+	part := klines[idx:]
+	agg := market.Kline{
+		OpenTime:  start,
+		Open:      part[0].Open,
+		High:      part[0].High,
+		Low:       part[0].Low,
+		Close:     part[len(part)-1].Close,
+		CloseTime: start + (4 * time.Hour).Milliseconds() - 1,
+	}
+	for _, k := range part {
+		agg.High = math.Max(agg.High, k.High)
+		agg.Low = math.Min(agg.Low, k.Low)
+		agg.Volume += k.Volume
+		agg.QuoteVolume += k.QuoteVolume
+		agg.Trades += k.Trades
+		agg.TakerBuyBaseVolume += k.TakerBuyBaseVolume
+		agg.TakerBuyQuoteVolume += k.TakerBuyQuoteVolume
+	}
+	return agg, true
+}
+
+// currentPrice 模拟时刻的最新成交价
+func (e *engine) currentPrice(symbol string) (float64, error) {
+	klines, err := e.visibleKlines3m(symbol)
+	if err != nil {
+		return 0, err
+	}
+	return klines[len(klines)-1].Close, nil
+}
+
+// findPosition 查找持仓（side 为空时匹配任意方向）
+func (e *engine) findPosition(symbol, side string) (map[string]interface{}, error) {
+	positions, err := e.trader.GetPositions()
+	if err != nil {
+		return nil, fmt.Errorf("获取持仓失败: %w", err)
+	}
+	for _, pos := range positions {
+		if pos["symbol"] == symbol && (side == "" || pos["side"] == side) {
+			return pos, nil
+		}
+	}
+	return nil, nil
+}
+
+// execute 在模拟账户上执行单个决策（规则与实时交易员保持一致）
+func (e *engine) execute(d *decision.Decision, actionRecord *logger.DecisionAction) error {
+	switch d.Action {
+	case "open_long", "open_short":
+		return e.executeOpen(d, actionRecord)
+	case "close_long", "close_short":
+		return e.executeClose(d, actionRecord)
+	case "update_stop_loss", "update_take_profit":
+		return e.executeUpdateTrigger(d)
+	case "partial_close":
+		return e.executePartialClose(d, actionRecord)
+	case "hold", "wait":
+		return nil
+	default:
+		return fmt.Errorf("未知的action: %s", d.Action)
+	}
+}
+
+// executeOpen 开仓并设置止损止盈
+func (e *engine) executeOpen(d *decision.Decision, actionRecord *logger.DecisionAction) error {
+	side := strings.TrimPrefix(d.Action, "open_")
+	positionSide := strings.ToUpper(side)
+
+	existing, err := e.findPosition(d.Symbol, side)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("❌ %s 已有%s仓，拒绝开仓以防止仓位叠加超限", d.Symbol, sideLabel(side))
+	}
+
+	price, err := e.currentPrice(d.Symbol)
+	if err != nil {
+		return err
+	}
+	quantity := d.PositionSizeUSD / price
+	actionRecord.Quantity = quantity
+	actionRecord.Price = price
+
+	var order map[string]interface{}
+	if side == "long" {
+		order, err = e.trader.OpenLong(d.Symbol, quantity, d.Leverage)
+	} else {
+		order, err = e.trader.OpenShort(d.Symbol, quantity, d.Leverage)
+	}
+	if err != nil {
+		return err
+	}
+	if orderID, ok := order["orderId"].(int64); ok {
+		actionRecord.OrderID = orderID
+	}
+	e.positionOpenTime[d.Symbol+"_"+side] = e.now
+
+	if err := e.trader.SetStopLoss(d.Symbol, positionSide, quantity, d.StopLoss); err != nil {
+		log.Printf("  ⚠ 设置止损失败: %v", err)
+	}
+	if err := e.trader.SetTakeProfit(d.Symbol, positionSide, quantity, d.TakeProfit); err != nil {
+		log.Printf("  ⚠ 设置止盈失败: %v", err)
+	}
+	return nil
+}
+
+// executeClose 全部平仓
+func (e *engine) executeClose(d *decision.Decision, actionRecord *logger.DecisionAction) error {
+	side := strings.TrimPrefix(d.Action, "close_")
+
+	price, err := e.currentPrice(d.Symbol)
+	if err != nil {
+		return err
+	}
+	actionRecord.Price = price
+
+	var order map[string]interface{}
+	if side == "long" {
+		order, err = e.trader.CloseLong(d.Symbol, 0)
+	} else {
+		order, err = e.trader.CloseShort(d.Symbol, 0)
+	}
+	if err != nil {
+		return err
+	}
+	if orderID, ok := order["orderId"].(int64); ok {
+		actionRecord.OrderID = orderID
+	}
+	delete(e.positionOpenTime, d.Symbol+"_"+side)
+	return nil
+}
+
+// executeUpdateTrigger 调整止损或止盈
+func (e *engine) executeUpdateTrigger(d *decision.Decision) error {
+	pos, err := e.findPosition(d.Symbol, "")
+	if err != nil {
+		return err
+	}
+	if pos == nil {
+		return fmt.Errorf("持仓不存在: %s", d.Symbol)
+	}
+
+	positionSide := strings.ToUpper(pos["side"].(string))
+	quantity := math.Abs(pos["positionAmt"].(float64))
+	if d.Action == "update_stop_loss" {
+		return e.trader.SetStopLoss(d.Symbol, positionSide, quantity, d.NewStopLoss)
+	}
+	return e.trader.SetTakeProfit(d.Symbol, positionSide, quantity, d.NewTakeProfit)
+}
+
+// executePartialClose 部分平仓，剩余价值过小时自动全平
+func (e *engine) executePartialClose(d *decision.Decision, actionRecord *logger.DecisionAction) error {
+	if d.ClosePercentage <= 0 || d.ClosePercentage > 100 {
+		return fmt.Errorf("平仓百分比必须在 0-100 之间，当前: %.1f", d.ClosePercentage)
+	}
+
+	pos, err := e.findPosition(d.Symbol, "")
+	if err != nil {
+		return err
+	}
+	if pos == nil {
+		return fmt.Errorf("持仓不存在: %s", d.Symbol)
+	}
+
+	price, err := e.currentPrice(d.Symbol)
+	if err != nil {
+		return err
+	}
+	actionRecord.Price = price
+
+	side := pos["side"].(string)
+	positionSide := strings.ToUpper(side)
+	totalQuantity := math.Abs(pos["positionAmt"].(float64))
+	closeQuantity := totalQuantity * d.ClosePercentage / 100
+	remaining := totalQuantity - closeQuantity
+	actionRecord.Quantity = closeQuantity
+
+	if remaining*price > 0 && remaining*price <= minPositionValue {
+		d.Action = "close_" + side
+		actionRecord.Action = d.Action
+		return e.executeClose(d, actionRecord)
+	}
+
+	var order map[string]interface{}
+	if side == "long" {
+		order, err = e.trader.CloseLong(d.Symbol, closeQuantity)
+	} else {
+		order, err = e.trader.CloseShort(d.Symbol, closeQuantity)
+	}
+	if err != nil {
+		return fmt.Errorf("部分平仓失败: %w", err)
+	}
+	if orderID, ok := order["orderId"].(int64); ok {
+		actionRecord.OrderID = orderID
+	}
+
+	if d.NewStopLoss > 0 {
+		if err := e.trader.SetStopLoss(d.Symbol, positionSide, remaining, d.NewStopLoss); err != nil {
+			log.Printf("  ⚠️ 恢复止损失败: %v", err)
+		}
+	}
+	if d.NewTakeProfit > 0 {
+		if err := e.trader.SetTakeProfit(d.Symbol, positionSide, remaining, d.NewTakeProfit); err != nil {
+			log.Printf("  ⚠️ 恢复止盈失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// sideLabel 方向的中文名
+func sideLabel(side string) string {
+	if side == "long" {
+		return "多"
+	}
+	return "空"
+}
@@ -0,0 +1,157 @@
+package backtest
+
+import (
+	"nofx/market"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testStart = time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+
+// generateTrendKlines 生成线性趋势K线：第 i 根收盘价 = base + step*i
+func generateTrendKlines(from time.Time, interval time.Duration, count int, base, step float64) []market.Kline {
+	klines := make([]market.Kline, count)
+	ms := interval.Milliseconds()
+	for i := 0; i < count; i++ {
+		open := base + step*float64(i-1)
+		close := base + step*float64(i)
+		openTime := from.UnixMilli() + int64(i)*ms
+		klines[i] = market.Kline{
+			OpenTime:  openTime,
+			Open:      open,
+			High:      max(open, close),
+			Low:       min(open, close),
+			Close:     close,
+			Volume:    1000,
+			CloseTime: openTime + ms - 1,
+		}
+	}
+	return klines
+}
+
+// newTrendDataset 价格从 99 开始每3分钟上涨 0.01，回测开始时约为 100
+func newTrendDataset() Dataset {
+	from := testStart.Add(-100 * 3 * time.Minute)
+	return Dataset{
+		"BTCUSDT": {
+			Klines3m: generateTrendKlines(from, 3*time.Minute, 100+24*20, 99, 0.01),
+			Klines4h: generateTrendKlines(testStart.Add(-100*4*time.Hour), 4*time.Hour, 100, 80, 0.2),
+		},
+	}
+}
+
+const openLongResponse = "<reasoning>趋势向上</reasoning>\n<decision>\n```json\n" +
+	`[{"symbol":"BTCUSDT","action":"open_long","leverage":5,"position_size_usd":600,"stop_loss":98,"take_profit":101,"confidence":80,"risk_usd":12,"reasoning":"test"}]` +
+	"\n```\n</decision>"
+
+func TestRunTakeProfitTrade(t *testing.T) {
+	client := NewRecordedClient([]string{openLongResponse})
+
+	var progress []int
+	result, err := Run(Config{
+		Symbols:        []string{"BTCUSDT"},
+		StartTime:      testStart,
+		EndTime:        testStart.Add(8 * time.Hour),
+		InitialBalance: 1000,
+		ScanInterval:   time.Hour,
+		Progress:       func(done, total int) { progress = append(progress, done) },
+	}, newTrendDataset(), client)
+	require.NoError(t, err)
+
+	assert.Equal(t, 9, result.Cycles)
+	assert.Len(t, result.EquityCurve, 9)
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8, 9}, progress)
+	assert.Equal(t, 0, result.FailedCycles)
+
+	// 第一周期开仓
+	first := result.Records[0]
+	require.Len(t, first.Decisions, 1)
+	assert.True(t, first.Decisions[0].Success, first.Decisions[0].Error)
+	assert.InDelta(t, 6.0, first.Decisions[0].Quantity, 0.01)
+	assert.Equal(t, testStart, first.Timestamp)
+
+	// 价格约5小时后触及止盈 101，之后的周期应记录自动平仓
+	var autoClose bool
+	for _, record := range result.Records[1:] {
+		for _, action := range record.Decisions {
+			if action.Action == "auto_close_long" {
+				autoClose = true
+				assert.InDelta(t, 101.0, action.Price, 0.01)
+			}
+		}
+	}
+	assert.True(t, autoClose, "应记录止盈自动平仓")
+
+	require.NotNil(t, result.Performance)
+	assert.Equal(t, 1, result.Performance.TotalTrades)
+	assert.Equal(t, 1, result.Performance.WinningTrades)
+
+	// 收益 ≈ 6 * (101 - 100) - 手续费
+	assert.Greater(t, result.FinalEquity, 1000.0)
+	assert.InDelta(t, 1000+6-result.TotalFees, result.FinalEquity, 0.1)
+	assert.Equal(t, 0, result.EquityCurve[len(result.EquityCurve)-1].PositionCount)
+	assert.Greater(t, result.TotalFees, 0.0)
+}
+
+func TestRunRecordsInvalidResponse(t *testing.T) {
+	client := NewRecordedClient([]string{"<decision>\n```json\n[{\"symbol\":\"BTCUSDT\",\"action\":\"fly\"}]\n```\n</decision>"})
+
+	result, err := Run(Config{
+		Symbols:        []string{"btc"},
+		StartTime:      testStart,
+		EndTime:        testStart.Add(time.Hour),
+		InitialBalance: 1000,
+		ScanInterval:   30 * time.Minute,
+	}, newTrendDataset(), client)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, result.Cycles)
+	assert.Equal(t, 1, result.FailedCycles)
+	assert.False(t, result.Records[0].Success)
+	assert.Contains(t, result.Records[0].ErrorMessage, "获取AI决策失败")
+	assert.Equal(t, 1000.0, result.FinalEquity)
+}
+
+func TestRunValidatesConfig(t *testing.T) {
+	client := NewRecordedClient(nil)
+	data := newTrendDataset()
+
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"无币种", Config{StartTime: testStart, EndTime: testStart.Add(time.Hour), InitialBalance: 1000}},
+		{"时间倒置", Config{Symbols: []string{"BTCUSDT"}, StartTime: testStart, EndTime: testStart, InitialBalance: 1000}},
+		{"资金为0", Config{Symbols: []string{"BTCUSDT"}, StartTime: testStart, EndTime: testStart.Add(time.Hour)}},
+		{"间隔非3分钟倍数", Config{Symbols: []string{"BTCUSDT"}, StartTime: testStart, EndTime: testStart.Add(time.Hour), InitialBalance: 1000, ScanInterval: 5 * time.Minute}},
+		{"缺少数据", Config{Symbols: []string{"ETHUSDT"}, StartTime: testStart, EndTime: testStart.Add(time.Hour), InitialBalance: 1000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Run(tt.cfg, data, client)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestWindowKlinesSynthesizesForming4h(t *testing.T) {
+	e := &engine{data: newTrendDataset(), now: testStart.Add(time.Hour)}
+
+	klines3m, klines4h := e.windowKlines("BTCUSDT")
+	require.Len(t, klines3m, klineWindow)
+	require.Len(t, klines4h, klineWindow)
+
+	// 只能看到已收盘的3分钟K线
+	last3m := klines3m[len(klines3m)-1]
+	assert.Less(t, last3m.CloseTime, e.now.UnixMilli())
+
+	// 最后一根4小时K线由 08:00 之后的3分钟K线合成
+	forming := klines4h[len(klines4h)-1]
+	assert.Equal(t, testStart.UnixMilli(), forming.OpenTime)
+	assert.Equal(t, last3m.Close, forming.Close)
+	assert.InDelta(t, 20*1000.0, forming.Volume, 1e-9)
+}
@@ -0,0 +1,173 @@
+package backtest
+
+import (
+	"fmt"
+	"log"
+	"nofx/mcp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// JobStatus 回测任务状态
+type JobStatus string
+
+const (
+	JobPending   JobStatus = "pending"
+	JobRunning   JobStatus = "running"
+	JobCompleted JobStatus = "completed"
+	JobFailed    JobStatus = "failed"
+)
+
+// Job 异步回测任务
+type Job struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"-"`
+	Status     JobStatus  `json:"status"`
+	Config     Config     `json:"config"`
+	Progress   int        `json:"progress"` // 已完成周期数
+	Total      int        `json:"total"`    // 总周期数
+	Error      string     `json:"error,omitempty"`
+	Result     *Result    `json:"result,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+const (
+	// MaxActiveJobsPerUser 每个用户同时排队或运行的回测任务上限
+	MaxActiveJobsPerUser = 2
+	// finishedJobTTL 已结束任务的保留时长，超时后从内存中清除
+	finishedJobTTL = 24 * time.Hour
+)
+
+// ErrTooManyJobs 用户同时运行的回测任务已达上限
+var ErrTooManyJobs = fmt.Errorf("同时运行的回测任务不能超过 %d 个", MaxActiveJobsPerUser)
+
+// JobManager 管理异步回测任务（内存存储，进程重启后丢失）
+type JobManager struct {
+	mu     sync.RWMutex
+	jobs   map[string]*Job
+	nextID int
+}
+
+// NewJobManager 创建回测任务管理器
+func NewJobManager() *JobManager {
+	return &JobManager{jobs: make(map[string]*Job)}
+}
+
+// Submit 提交回测任务：后台加载数据并运行，立即返回任务快照
+// 用户排队或运行中的任务达到 MaxActiveJobsPerUser 时返回 ErrTooManyJobs
+func (m *JobManager) Submit(userID string, cfg Config, dataCfg DataConfig, client mcp.AIClient) (*Job, error) {
+	m.mu.Lock()
+	m.pruneLocked(time.Now())
+	active := 0
+	for _, job := range m.jobs {
+		if job.UserID == userID && !job.finished() {
+			active++
+		}
+	}
+	if active >= MaxActiveJobsPerUser {
+		m.mu.Unlock()
+		return nil, ErrTooManyJobs
+	}
+
+	m.nextID++
+	job := &Job{
+		ID:        fmt.Sprintf("bt_%d_%d", time.Now().Unix(), m.nextID),
+		UserID:    userID,
+		Status:    JobPending,
+		Config:    cfg,
+		CreatedAt: time.Now(),
+	}
+	m.jobs[job.ID] = job
+	snapshot := *job
+	m.mu.Unlock()
+
+	go m.run(job, cfg, dataCfg, client)
+	return &snapshot, nil
+}
+
+// finished 任务是否已结束
+func (j *Job) finished() bool {
+	return j.Status == JobCompleted || j.Status == JobFailed
+}
+
+// pruneLocked 清除结束超过 finishedJobTTL 的任务（调用方需持有写锁）
+func (m *JobManager) pruneLocked(now time.Time) {
+	for id, job := range m.jobs {
+		if job.finished() && job.FinishedAt != nil && now.Sub(*job.FinishedAt) > finishedJobTTL {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+// run 执行回测任务
+func (m *JobManager) run(job *Job, cfg Config, dataCfg DataConfig, client mcp.AIClient) {
+	m.update(job, func(j *Job) { j.Status = JobRunning })
+
+	cfg.Progress = func(done, total int) {
+		m.update(job, func(j *Job) {
+			j.Progress = done
+			j.Total = total
+		})
+	}
+
+	result, err := func() (*Result, error) {
+		data, err := LoadDataset(dataCfg)
+		if err != nil {
+			return nil, err
+		}
+		return Run(cfg, data, client)
+	}()
+
+	m.update(job, func(j *Job) {
+		now := time.Now()
+		j.FinishedAt = &now
+		if err != nil {
+			j.Status = JobFailed
+			j.Error = err.Error()
+			log.Printf("❌ 回测任务 %s 失败: %v", j.ID, err)
+			return
+		}
+		j.Status = JobCompleted
+		j.Result = result
+	})
+}
+
+// update 加锁修改任务
+func (m *JobManager) update(job *Job, fn func(j *Job)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	fn(job)
+}
+
+// Get 获取用户的任务快照
+func (m *JobManager) Get(userID, id string) (*Job, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	job, ok := m.jobs[id]
+	if !ok || job.UserID != userID {
+		return nil, false
+	}
+	snapshot := *job
+	return &snapshot, true
+}
+
+// List 列出用户的全部任务（不含结果详情，按创建时间倒序）
+func (m *JobManager) List(userID string) []*Job {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	jobs := []*Job{}
+	for _, job := range m.jobs {
+		if job.UserID != userID {
+			continue
+		}
+		snapshot := *job
+		snapshot.Result = nil
+		jobs = append(jobs, &snapshot)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.After(jobs[j].CreatedAt) })
+	return jobs
+}
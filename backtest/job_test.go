@@ -0,0 +1,39 @@
+package backtest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobManagerLimitsActiveJobs(t *testing.T) {
+	m := NewJobManager()
+	m.jobs["a"] = &Job{ID: "a", UserID: "u1", Status: JobRunning}
+	m.jobs["b"] = &Job{ID: "b", UserID: "u1", Status: JobPending}
+
+	_, err := m.Submit("u1", Config{}, DataConfig{}, nil)
+	assert.ErrorIs(t, err, ErrTooManyJobs)
+
+	// 其他用户不受影响（空币种的任务会立即失败结束）
+	job, err := m.Submit("u2", Config{}, DataConfig{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "u2", job.UserID)
+}
+
+func TestJobManagerPrunesFinishedJobs(t *testing.T) {
+	m := NewJobManager()
+	old := time.Now().Add(-finishedJobTTL - time.Minute)
+	recent := time.Now()
+	m.jobs["old"] = &Job{ID: "old", UserID: "u1", Status: JobCompleted, FinishedAt: &old}
+	m.jobs["recent"] = &Job{ID: "recent", UserID: "u1", Status: JobFailed, FinishedAt: &recent}
+
+	_, err := m.Submit("u1", Config{}, DataConfig{}, nil)
+	require.NoError(t, err)
+
+	_, ok := m.Get("u1", "old")
+	assert.False(t, ok, "结束超过保留时长的任务应被清除")
+	_, ok = m.Get("u1", "recent")
+	assert.True(t, ok)
+}
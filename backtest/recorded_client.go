@@ -0,0 +1,120 @@
+package backtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"nofx/logger"
+	"nofx/mcp"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// waitResponse 录制响应用尽后返回的默认决策
+const waitResponse = "<reasoning>录制响应已用尽，保持观望</reasoning>\n<decision>\n```json\n[]\n```\n</decision>"
+
+// RecordedClient 按顺序回放录制好的AI响应（实现 mcp.AIClient，不发起网络请求）
+// 用于可重复的回测和测试
+type RecordedClient struct {
+	mu        sync.Mutex
+	responses []string
+	next      int
+	exhausted bool
+}
+
+// NewRecordedClient 使用给定响应列表创建回放客户端
+func NewRecordedClient(responses []string) *RecordedClient {
+	return &RecordedClient{responses: responses}
+}
+
+// LoadRecordedClient 从文件或目录加载录制响应
+// - JSON文件：字符串数组，每个元素为一次完整的AI原始响应
+// - 目录：decision_logs 目录，按文件名顺序用 CoTTrace + DecisionJSON 重建响应
+func LoadRecordedClient(path string) (*RecordedClient, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取录制响应失败: %w", err)
+	}
+
+	if !info.IsDir() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取录制响应失败: %w", err)
+		}
+		var responses []string
+		if err := json.Unmarshal(data, &responses); err != nil {
+			return nil, fmt.Errorf("解析录制响应失败: %w", err)
+		}
+		return NewRecordedClient(responses), nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取决策日志目录失败: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	var responses []string
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(path, name))
+		if err != nil {
+			continue
+		}
+		var record logger.DecisionRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			continue
+		}
+		if record.DecisionJSON == "" {
+			continue
+		}
+		responses = append(responses, fmt.Sprintf("<reasoning>%s</reasoning>\n<decision>\n```json\n%s\n```\n</decision>",
+			record.CoTTrace, record.DecisionJSON))
+	}
+	if len(responses) == 0 {
+		return nil, fmt.Errorf("目录 %s 中没有可回放的决策记录", path)
+	}
+	return NewRecordedClient(responses), nil
+}
+
+// SetAPIKey 回放客户端无需密钥
+func (c *RecordedClient) SetAPIKey(apiKey string, customURL string, customModel string) {}
+
+// SetTimeout 回放客户端无需超时
+func (c *RecordedClient) SetTimeout(timeout time.Duration) {}
+
+// CallWithMessages 返回下一条录制响应
+func (c *RecordedClient) CallWithMessages(systemPrompt, userPrompt string) (string, error) {
+	return c.nextResponse(), nil
+}
+
+// CallWithRequest 返回下一条录制响应
+func (c *RecordedClient) CallWithRequest(req *mcp.Request) (string, error) {
+	return c.nextResponse(), nil
+}
+
+// nextResponse 取出下一条响应，用尽后返回观望
+func (c *RecordedClient) nextResponse() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.next >= len(c.responses) {
+		if !c.exhausted {
+			log.Printf("⚠️  录制响应已用尽（共 %d 条），后续周期保持观望", len(c.responses))
+			c.exhausted = true
+		}
+		return waitResponse
+	}
+	resp := c.responses[c.next]
+	c.next++
+	return resp
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"nofx/backtest"
+	"nofx/mcp"
+	"os"
+	"strings"
+	"time"
+)
+
+// runBacktestCommand 处理 `nofx backtest` 子命令
+//
+// 示例：
+//
+//	nofx backtest -symbols BTCUSDT,ETHUSDT -start 2025-01-01 -end 2025-01-03 -responses recorded.json
+//	nofx backtest -symbols BTCUSDT -start 2025-01-01 -end 2025-01-02 -provider deepseek -api-key sk-xxx
+func runBacktestCommand(args []string) {
+	fs := flag.NewFlagSet("backtest", flag.ExitOnError)
+	symbols := fs.String("symbols", "BTCUSDT", "回测币种，逗号分隔")
+	start := fs.String("start", "", "开始时间（2006-01-02 或 RFC3339，UTC）")
+	end := fs.String("end", "", "结束时间（2006-01-02 或 RFC3339，UTC）")
+	balance := fs.Float64("balance", 1000, "初始资金（USDT）")
+	interval := fs.Duration("interval", 3*time.Minute, "决策间隔（3分钟的整数倍）")
+	csvDir := fs.String("csv-dir", "", "CSV K线目录（文件名 <SYMBOL>_<interval>.csv），为空则使用缓存/交易所")
+	cacheDir := fs.String("cache-dir", "backtest_cache", "K线缓存目录")
+	responses := fs.String("responses", "", "录制的AI响应（JSON字符串数组文件或 decision_logs 目录），设置后不调用真实AI")
+	provider := fs.String("provider", "deepseek", "AI提供商: "+strings.Join(mcp.SupportedProviders(), " / "))
+	apiKey := fs.String("api-key", os.Getenv("BACKTEST_AI_API_KEY"), "AI API Key（默认读取 BACKTEST_AI_API_KEY）")
+	apiURL := fs.String("api-url", "", "自定义AI API地址")
+	model := fs.String("model", "", "自定义模型名称")
+	btcEthLeverage := fs.Int("btc-eth-leverage", 5, "BTC/ETH 杠杆倍数")
+	altcoinLeverage := fs.Int("altcoin-leverage", 5, "山寨币杠杆倍数")
+	feeRate := fs.Float64("fee", 0.0004, "Taker手续费率")
+	fundingRate := fs.Float64("funding-rate", 0, "每8小时资金费率（常数）")
+	template := fs.String("template", "default", "系统提示词模板")
+	promptFile := fs.String("prompt-file", "", "自定义策略prompt文件")
+	overridePrompt := fs.Bool("override-prompt", false, "自定义prompt是否覆盖基础prompt")
+	output := fs.String("output", "", "结果输出文件（JSON），为空则打印摘要")
+	fs.Parse(args)
+
+	startTime, err := parseCommandTime(*start)
+	if err != nil {
+		log.Fatalf("❌ 开始时间无效: %v", err)
+	}
+	endTime, err := parseCommandTime(*end)
+	if err != nil {
+		log.Fatalf("❌ 结束时间无效: %v", err)
+	}
+
+	var customPrompt string
+	if *promptFile != "" {
+		data, err := os.ReadFile(*promptFile)
+		if err != nil {
+			log.Fatalf("❌ 读取prompt文件失败: %v", err)
+		}
+		customPrompt = string(data)
+	}
+
+	var client mcp.AIClient
+	if *responses != "" {
+		recorded, err := backtest.LoadRecordedClient(*responses)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		client = recorded
+		log.Printf("🧪 使用录制的AI响应: %s", *responses)
+	} else {
+		if *apiKey == "" && *provider != mcp.ProviderOllama && *provider != mcp.ProviderLlamaCpp {
+			log.Fatalf("❌ 未设置 -responses 时必须提供 -api-key")
+		}
+		aiClient, err := mcp.NewClientForProvider(*provider, *apiKey, *apiURL, *model)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		client = aiClient
+		log.Printf("🤖 使用AI提供商: %s", *provider)
+	}
+
+	symbolList := splitSymbols(*symbols)
+	data, err := backtest.LoadDataset(backtest.DataConfig{
+		Symbols:   symbolList,
+		StartTime: startTime,
+		EndTime:   endTime,
+		CSVDir:    *csvDir,
+		CacheDir:  *cacheDir,
+	})
+	if err != nil {
+		log.Fatalf("❌ 加载历史数据失败: %v", err)
+	}
+
+	result, err := backtest.Run(backtest.Config{
+		Symbols:              symbolList,
+		StartTime:            startTime,
+		EndTime:              endTime,
+		InitialBalance:       *balance,
+		ScanInterval:         *interval,
+		BTCETHLeverage:       *btcEthLeverage,
+		AltcoinLeverage:      *altcoinLeverage,
+		TakerFeeRate:         *feeRate,
+		FundingRate:          *fundingRate,
+		CustomPrompt:         customPrompt,
+		OverrideBasePrompt:   *overridePrompt,
+		SystemPromptTemplate: *template,
+	}, data, client)
+	if err != nil {
+		log.Fatalf("❌ 回测失败: %v", err)
+	}
+
+	fmt.Println()
+	fmt.Printf("📊 回测区间: %s ~ %s\n", result.StartTime.Format("2006-01-02 15:04"), result.EndTime.Format("2006-01-02 15:04"))
+	fmt.Printf("   初始资金: %.2f USDT → 最终权益: %.2f USDT (%+.2f%%)\n", result.InitialBalance, result.FinalEquity, result.TotalReturnPct)
+	fmt.Printf("   最大回撤: %.2f%% | 手续费: %.2f | 资金费: %.2f\n", result.MaxDrawdownPct, result.TotalFees, result.TotalFunding)
+	fmt.Printf("   周期: %d（失败 %d）| 交易: %d | 胜率: %.1f%% | 盈亏比: %.2f | 夏普: %.2f\n",
+		result.Cycles, result.FailedCycles, result.Performance.TotalTrades, result.Performance.WinRate,
+		result.Performance.ProfitFactor, result.Performance.SharpeRatio)
+
+	if *output != "" {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			log.Fatalf("❌ 序列化回测结果失败: %v", err)
+		}
+		if err := os.WriteFile(*output, data, 0644); err != nil {
+			log.Fatalf("❌ 写入回测结果失败: %v", err)
+		}
+		fmt.Printf("   结果已保存: %s\n", *output)
+	}
+}
+
+// parseCommandTime 解析日期（UTC）或 RFC3339 时间
+func parseCommandTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, fmt.Errorf("不能为空")
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// splitSymbols 解析逗号分隔的币种列表
+func splitSymbols(value string) []string {
+	var symbols []string
+	for _, s := range strings.Split(value, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			symbols = append(symbols, strings.ToUpper(s))
+		}
+	}
+	return symbols
+}
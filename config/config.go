@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"nofx/crypto"
+	"nofx/signer"
 	"os"
 )
 
@@ -29,19 +31,27 @@ type TelegramConfig struct {
 
 // Config 总配置
 type Config struct {
-	BetaMode           bool           `json:"beta_mode"`
-	APIServerPort      int            `json:"api_server_port"`
-	UseDefaultCoins    bool           `json:"use_default_coins"`
-	DefaultCoins       []string       `json:"default_coins"`
-	CoinPoolAPIURL     string         `json:"coin_pool_api_url"`
-	OITopAPIURL        string         `json:"oi_top_api_url"`
-	MaxDailyLoss       float64        `json:"max_daily_loss"`
-	MaxDrawdown        float64        `json:"max_drawdown"`
-	StopTradingMinutes int            `json:"stop_trading_minutes"`
-	Leverage           LeverageConfig `json:"leverage"`
-	JWTSecret          string         `json:"jwt_secret"`
-	DataKLineTime      string         `json:"data_k_line_time"`
-	Log                *LogConfig     `json:"log"` // 日志配置
+	BetaMode           bool                     `json:"beta_mode"`
+	APIServerPort      int                      `json:"api_server_port"`
+	UseDefaultCoins    bool                     `json:"use_default_coins"`
+	DefaultCoins       []string                 `json:"default_coins"`
+	CoinPoolAPIURL     string                   `json:"coin_pool_api_url"`
+	OITopAPIURL        string                   `json:"oi_top_api_url"`
+	MaxDailyLoss       float64                  `json:"max_daily_loss"`
+	MaxDrawdown        float64                  `json:"max_drawdown"`
+	StopTradingMinutes int                      `json:"stop_trading_minutes"`
+	FlattenOnBreaker   bool                     `json:"flatten_on_breaker"` // 触发熔断时是否平掉所有持仓
+	AgentMaxTurns      int                      `json:"agent_max_turns"`    // Agent 决策模式每周期最多调用AI轮数
+	AgentMaxTokens     int                      `json:"agent_max_tokens"`   // Agent 决策模式每周期累计 token 上限
+	DecisionLogStore   string                   `json:"decision_log_store"` // 决策日志存储：json / sqlite
+	TelegramBotToken   string                   `json:"telegram_bot_token"` // 交互式 Telegram 机器人 Token（可选）
+	AdminEmails        []string                 `json:"admin_emails"`       // 可调用管理接口（如密钥轮换）的用户邮箱
+	DataKey            crypto.KeyProviderConfig `json:"data_key"`           // 数据加密密钥来源（env/file/vault/envelope）
+	Signer             signer.Config            `json:"signer"`             // 外部签名器（remote:/keystore: 私钥配置）
+	Leverage           LeverageConfig           `json:"leverage"`
+	JWTSecret          string                   `json:"jwt_secret"`
+	DataKLineTime      string                   `json:"data_k_line_time"`
+	Log                *LogConfig               `json:"log"` // 日志配置
 }
 
 // LoadConfig 从文件加载配置
@@ -5,10 +5,12 @@ import (
 	"database/sql"
 	"encoding/base32"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"nofx/crypto"
 	"nofx/market"
+	"nofx/mcp"
 	"os"
 	"slices"
 	"strings"
@@ -49,6 +51,54 @@ type DatabaseInterface interface {
 	ValidateBetaCode(code string) (bool, error)
 	UseBetaCode(code, userEmail string) error
 	GetBetaCodeStats() (total, used int, err error)
+	SaveLedgerEntries(traderID string, entries []*LedgerEntry) (int, error)
+	GetLedgerEntries(traderID string, since time.Time) ([]*LedgerEntry, error)
+	GetLatestLedgerTime(traderID string) (time.Time, error)
+	GetCircuitBreakerState(traderID string) (*CircuitBreakerState, error)
+	SaveCircuitBreakerState(state *CircuitBreakerState) error
+	GetPositionExitStates(traderID string) ([]*PositionExitState, error)
+	SavePositionExitState(state *PositionExitState) error
+	DeletePositionExitState(traderID, symbol, side string) error
+	GetTraderState(traderID string) (*TraderState, error)
+	SaveTraderState(state *TraderState) error
+	CreateWebhook(webhook *Webhook) error
+	UpdateWebhook(webhook *Webhook) error
+	DeleteWebhook(userID, id string) error
+	GetWebhook(userID, id string) (*Webhook, error)
+	GetWebhooks(userID string) ([]*Webhook, error)
+	GetWebhooksForTrader(traderID string) ([]*Webhook, error)
+	SaveWebhookDelivery(delivery *WebhookDelivery) error
+	GetWebhookDeliveries(webhookID string, limit int) ([]*WebhookDelivery, error)
+	CreateTelegramBindCode(code *TelegramBindCode) error
+	ConsumeTelegramBindCode(code string) (*TelegramBindCode, error)
+	SaveTelegramBinding(binding *TelegramBinding) error
+	GetTelegramBinding(chatID int64) (*TelegramBinding, error)
+	GetTelegramBindings(userID string) ([]*TelegramBinding, error)
+	DeleteTelegramBinding(userID string, chatID int64) error
+	CreateOrganization(org *Organization) error
+	GetOrganization(orgID string) (*Organization, error)
+	GetUserOrganizations(userID string) ([]*Organization, error)
+	DeleteOrganization(orgID string) error
+	GetOrgMemberRole(orgID, userID string) (string, error)
+	GetOrgMembers(orgID string) ([]*OrgMember, error)
+	SetOrgMemberRole(orgID, userID, role string) error
+	RemoveOrgMember(orgID, userID string) error
+	CreateOrgInvitation(invitation *OrgInvitation) error
+	GetOrgInvitations(orgID string) ([]*OrgInvitation, error)
+	DeleteOrgInvitation(orgID, token string) error
+	AcceptOrgInvitation(token, userID, email string) (*OrgInvitation, error)
+	SetTraderOrg(ownerID, traderID, orgID string) error
+	SetTraderPermission(traderID, userID, role string) error
+	DeleteTraderPermission(traderID, userID string) error
+	GetTraderPermissions(traderID string) ([]*TraderPermission, error)
+	GetTraderAccess(userID, traderID string) (*TraderAccess, error)
+	CreateAPIToken(token *APIToken) error
+	GetAPITokens(userID string) ([]*APIToken, error)
+	GetAPITokenByHash(tokenHash string) (*APIToken, error)
+	RevokeAPIToken(userID, id string) error
+	TouchAPIToken(id, ip string, usedAt time.Time) error
+	VerifySensitiveData() (*EncryptionReport, error)
+	ReEncryptSensitiveData() (*EncryptionReport, error)
 	Close() error
 }
 
@@ -194,6 +244,176 @@ func (d *Database) createTables() error {
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		)`,
 
+		// 成交/资金费账本表（从交易所同步的成交明细和资金费收支）
+		`CREATE TABLE IF NOT EXISTS trade_ledger (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			trader_id TEXT NOT NULL,
+			entry_type TEXT NOT NULL, -- 'trade' or 'funding'
+			exchange_id TEXT NOT NULL, -- 交易所成交ID/流水ID
+			order_id TEXT DEFAULT '',
+			symbol TEXT NOT NULL,
+			side TEXT DEFAULT '', -- BUY/SELL
+			position_side TEXT DEFAULT '', -- LONG/SHORT
+			price REAL DEFAULT 0,
+			quantity REAL DEFAULT 0,
+			fee REAL DEFAULT 0,
+			fee_asset TEXT DEFAULT '',
+			realized_pnl REAL DEFAULT 0,
+			amount REAL DEFAULT 0, -- 资金费金额（正数为收入）
+			time INTEGER NOT NULL, -- 毫秒时间戳
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(trader_id, entry_type, exchange_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_trade_ledger_trader_time ON trade_ledger(trader_id, time)`,
+
+		// 熔断状态表（净值高水位、日初净值和暂停截止时间，重启后恢复）
+		`CREATE TABLE IF NOT EXISTS circuit_breakers (
+			trader_id TEXT PRIMARY KEY,
+			peak_equity REAL DEFAULT 0,
+			day_start_equity REAL DEFAULT 0,
+			day_start_time INTEGER DEFAULT 0, -- 毫秒时间戳
+			stop_until INTEGER DEFAULT 0, -- 毫秒时间戳，0表示未触发
+			reason TEXT DEFAULT '',
+			tripped_at INTEGER DEFAULT 0, -- 毫秒时间戳
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// 持仓退出状态表（峰值收益、初始/当前止损，用于退出策略，重启后恢复）
+		`CREATE TABLE IF NOT EXISTS position_exit_states (
+			trader_id TEXT NOT NULL,
+			symbol TEXT NOT NULL,
+			side TEXT NOT NULL, -- long / short
+			entry_price REAL DEFAULT 0,
+			peak_pnl_pct REAL DEFAULT 0,
+			peak_price REAL DEFAULT 0,
+			initial_stop REAL DEFAULT 0,
+			current_stop REAL DEFAULT 0,
+			opened_at INTEGER DEFAULT 0, -- 毫秒时间戳
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (trader_id, symbol, side)
+		)`,
+
+		// 交易员运行状态快照表（周期计数、当日已实现盈亏、持仓时长和峰值收益缓存，每周期保存，重启后恢复）
+		`CREATE TABLE IF NOT EXISTS trader_states (
+			trader_id TEXT PRIMARY KEY,
+			call_count INTEGER DEFAULT 0,
+			start_time INTEGER DEFAULT 0, -- 毫秒时间戳
+			daily_realized_pnl REAL DEFAULT 0,
+			last_reset_time INTEGER DEFAULT 0, -- 毫秒时间戳
+			position_first_seen TEXT DEFAULT '{}', -- JSON: symbol_side -> 毫秒时间戳
+			peak_pnl TEXT DEFAULT '{}', -- JSON: symbol_side -> 峰值收益率
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Webhook 订阅表（按用户配置，向外部系统推送签名的交易事件）
+		`CREATE TABLE IF NOT EXISTS webhooks (
+			id TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL,
+			trader_id TEXT DEFAULT '', -- 为空表示该用户的所有交易员
+			url TEXT NOT NULL,
+			secret TEXT DEFAULT '', -- HMAC 签名密钥（加密存储）
+			events TEXT DEFAULT '[]', -- JSON: 订阅的事件类型，为空表示全部
+			enabled BOOLEAN DEFAULT 1,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_webhooks_user ON webhooks(user_id)`,
+
+		// Webhook 投递日志表（每次尝试一条）
+		`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			webhook_id TEXT NOT NULL,
+			delivery_id TEXT NOT NULL, -- 同一事件的多次重试共用
+			event_type TEXT NOT NULL,
+			trader_id TEXT DEFAULT '',
+			payload TEXT DEFAULT '',
+			attempt INTEGER DEFAULT 1,
+			status_code INTEGER DEFAULT 0,
+			error TEXT DEFAULT '',
+			success BOOLEAN DEFAULT 0,
+			duration_ms INTEGER DEFAULT 0,
+			created_at INTEGER DEFAULT 0 -- 毫秒时间戳
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, id)`,
+
+		// Telegram 聊天绑定表（chat 绑定到 nofx 用户后可使用机器人命令）
+		`CREATE TABLE IF NOT EXISTS telegram_bindings (
+			chat_id INTEGER PRIMARY KEY,
+			user_id TEXT NOT NULL,
+			username TEXT DEFAULT '',
+			can_control BOOLEAN DEFAULT 0, -- 是否允许暂停/恢复/平仓等控制命令
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+
+		// Telegram 绑定码表（Web 端生成，在机器人中发送 /bind <code> 完成绑定，一次性）
+		`CREATE TABLE IF NOT EXISTS telegram_bind_codes (
+			code TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL,
+			can_control BOOLEAN DEFAULT 0,
+			expires_at INTEGER NOT NULL -- 毫秒时间戳
+		)`,
+
+		// 组织表（团队工作区，组织内交易员使用 owner_id 账户下的交易所和模型配置）
+		`CREATE TABLE IF NOT EXISTS organizations (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			owner_id TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+
+		// 组织成员表
+		`CREATE TABLE IF NOT EXISTS org_members (
+			org_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			role TEXT NOT NULL, -- owner / operator / viewer
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (org_id, user_id),
+			FOREIGN KEY (org_id) REFERENCES organizations(id) ON DELETE CASCADE,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_org_members_user ON org_members(user_id)`,
+
+		// 组织邀请表（按邮箱邀请，一次性）
+		`CREATE TABLE IF NOT EXISTS org_invitations (
+			token TEXT PRIMARY KEY,
+			org_id TEXT NOT NULL,
+			email TEXT NOT NULL,
+			role TEXT NOT NULL,
+			invited_by TEXT NOT NULL,
+			expires_at INTEGER NOT NULL, -- 毫秒时间戳
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (org_id) REFERENCES organizations(id) ON DELETE CASCADE
+		)`,
+
+		// 交易员权限表（覆盖成员在组织中的角色，仅对单个交易员生效）
+		`CREATE TABLE IF NOT EXISTS trader_permissions (
+			trader_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			role TEXT NOT NULL, -- operator / viewer / none
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (trader_id, user_id)
+		)`,
+
+		// API 令牌表（脚本/机器人使用的个人访问令牌，只保存哈希）
+		`CREATE TABLE IF NOT EXISTS api_tokens (
+			id TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			token_hash TEXT UNIQUE NOT NULL, -- SHA-256，明文不落库
+			prefix TEXT DEFAULT '', -- 明文前缀（便于辨认令牌）
+			scope TEXT NOT NULL, -- read / trade / admin
+			expires_at INTEGER DEFAULT 0, -- 毫秒时间戳，0表示不过期
+			last_used_at INTEGER DEFAULT 0, -- 毫秒时间戳
+			last_used_ip TEXT DEFAULT '',
+			revoked_at INTEGER DEFAULT 0, -- 毫秒时间戳，0表示未撤销
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_api_tokens_user ON api_tokens(user_id)`,
+
 		// 触发器：自动更新 updated_at
 		`CREATE TRIGGER IF NOT EXISTS update_users_updated_at
 			AFTER UPDATE ON users
@@ -255,6 +475,17 @@ func (d *Database) createTables() error {
 		`ALTER TABLE traders ADD COLUMN use_coin_pool BOOLEAN DEFAULT 0`,               // 是否使用COIN POOL信号源
 		`ALTER TABLE traders ADD COLUMN use_oi_top BOOLEAN DEFAULT 0`,                  // 是否使用OI TOP信号源
 		`ALTER TABLE traders ADD COLUMN system_prompt_template TEXT DEFAULT 'default'`, // 系统提示词模板名称
+		`ALTER TABLE traders ADD COLUMN risk_config TEXT DEFAULT ''`,                   // 风控规则配置（JSON格式）
+		`ALTER TABLE traders ADD COLUMN exit_policy TEXT DEFAULT ''`,                   // 持仓退出策略配置（JSON格式）
+		`ALTER TABLE traders ADD COLUMN decision_mode TEXT DEFAULT 'text'`,             // 决策输出模式（text / tool_call / agent）
+		`ALTER TABLE traders ADD COLUMN execution_mode TEXT DEFAULT 'auto'`,            // 执行模式（auto=自动执行 / approval=人工审批）
+		`ALTER TABLE traders ADD COLUMN auto_approve_notional REAL DEFAULT 0`,          // 审批模式下名义价值低于该值的决策自动执行（0=全部需要审批）
+		`ALTER TABLE traders ADD COLUMN limit_order_timeout INTEGER DEFAULT 0`,         // 限价开仓单超时秒数（0=一个扫描周期）
+		`ALTER TABLE traders ADD COLUMN limit_order_fallback TEXT DEFAULT 'cancel'`,    // 限价单超时处理（cancel=撤单 / market=剩余部分转市价）
+		`ALTER TABLE traders ADD COLUMN market_timeframes TEXT DEFAULT ''`,             // 行情K线周期，逗号分隔（空=默认 3m,4h）
+		`ALTER TABLE traders ADD COLUMN market_indicators TEXT DEFAULT ''`,             // 行情指标，逗号分隔（空=默认 ema,macd,rsi,atr）
+		`ALTER TABLE traders ADD COLUMN org_id TEXT DEFAULT ''`,                        // 所属组织（空=个人工作区）
+		`ALTER TABLE trader_states ADD COLUMN pending_orders TEXT DEFAULT '[]'`,        // 跟踪中的限价开仓挂单（JSON）
 		`ALTER TABLE ai_models ADD COLUMN custom_api_url TEXT DEFAULT ''`,              // 自定义API地址
 		`ALTER TABLE ai_models ADD COLUMN custom_model_name TEXT DEFAULT ''`,           // 自定义模型名称
 	}
@@ -281,6 +512,10 @@ func (d *Database) initDefaultData() error {
 	}{
 		{"deepseek", "DeepSeek", "deepseek"},
 		{"qwen", "Qwen", "qwen"},
+		{"anthropic", "Anthropic Claude", "anthropic"},
+		{"gemini", "Google Gemini", "gemini"},
+		{"ollama", "Ollama (本地)", "ollama"},
+		{"llamacpp", "llama.cpp (本地)", "llamacpp"},
 	}
 
 	for _, model := range aiModels {
@@ -300,6 +535,7 @@ func (d *Database) initDefaultData() error {
 		{"binance", "Binance Futures", "binance"},
 		{"hyperliquid", "Hyperliquid", "hyperliquid"},
 		{"aster", "Aster DEX", "aster"},
+		{"paper", "Paper Trading", "paper"},
 	}
 
 	for _, exchange := range exchanges {
@@ -321,10 +557,16 @@ func (d *Database) initDefaultData() error {
 		"max_daily_loss":       "10.0",                                                                                // 最大日损失百分比
 		"max_drawdown":         "20.0",                                                                                // 最大回撤百分比
 		"stop_trading_minutes": "60",                                                                                  // 停止交易时间（分钟）
+		"flatten_on_breaker":   "false",                                                                               // 熔断时是否平掉所有持仓
+		"agent_max_turns":      "5",                                                                                   // Agent 决策模式每周期最多调用AI轮数
+		"agent_max_tokens":     "60000",                                                                               // Agent 决策模式每周期累计 token 上限
+		"decision_log_store":   "json",                                                                                // 决策日志存储：json（每周期一个文件）/ sqlite（带索引的单文件）
 		"btc_eth_leverage":     "5",                                                                                   // BTC/ETH杠杆倍数
 		"altcoin_leverage":     "5",                                                                                   // 山寨币杠杆倍数
 		"jwt_secret":           "",                                                                                    // JWT密钥，默认为空，由config.json或系统生成
 		"registration_enabled": "true",                                                                                // 默认允许注册
+		"telegram_bot_token":   "",                                                                                    // 交互式 Telegram 机器人 Token，为空时不启动
+		"admin_emails":         "[]",                                                                                  // 可调用管理接口的用户邮箱（JSON格式）
 	}
 
 	for key, value := range systemConfigs {
@@ -452,7 +694,7 @@ type ExchangeConfig struct {
 	ID        string `json:"id"`
 	UserID    string `json:"user_id"`
 	Name      string `json:"name"`
-	Type      string `json:"type"`
+	Type      string `json:"type"` // cex / dex / paper（模拟盘）
 	Enabled   bool   `json:"enabled"`
 	APIKey    string `json:"apiKey"`    // For Binance: API Key; For Hyperliquid: Agent Private Key (should have ~0 balance)
 	SecretKey string `json:"secretKey"` // For Binance: Secret Key; Not used for Hyperliquid
@@ -487,6 +729,16 @@ type TraderRecord struct {
 	OverrideBasePrompt   bool      `json:"override_base_prompt"`   // 是否覆盖基础prompt
 	SystemPromptTemplate string    `json:"system_prompt_template"` // 系统提示词模板名称
 	IsCrossMargin        bool      `json:"is_cross_margin"`        // 是否为全仓模式（true=全仓，false=逐仓）
+	RiskConfig           string    `json:"risk_config"`            // 风控规则配置（JSON格式，为空使用默认值）
+	ExitPolicy           string    `json:"exit_policy"`            // 持仓退出策略配置（JSON格式，为空使用默认值）
+	DecisionMode         string    `json:"decision_mode"`          // 决策输出模式（text=文本解析，tool_call=原生函数调用，agent=多轮数据查询）
+	ExecutionMode        string    `json:"execution_mode"`         // 执行模式（auto=自动执行，approval=AI决策需人工审批）
+	AutoApproveNotional  float64   `json:"auto_approve_notional"`  // 审批模式下名义价值低于该值（USDT）的决策自动执行，0=全部需要审批
+	LimitOrderTimeout    int       `json:"limit_order_timeout"`    // 限价开仓单超时秒数（0=一个扫描周期）
+	LimitOrderFallback   string    `json:"limit_order_fallback"`   // 限价单超时未成交的处理（cancel=撤单，market=剩余部分转市价）
+	MarketTimeframes     string    `json:"market_timeframes"`      // 行情K线周期，逗号分隔（空=默认 3m,4h）
+	MarketIndicators     string    `json:"market_indicators"`      // 行情指标，逗号分隔（空=默认 ema,macd,rsi,atr）
+	OrgID                string    `json:"org_id"`                 // 所属组织（空=个人工作区）
 	CreatedAt            time.Time `json:"created_at"`
 	UpdatedAt            time.Time `json:"updated_at"`
 }
@@ -501,6 +753,218 @@ type UserSignalSource struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// LedgerEntry 账本条目（成交或资金费）
+type LedgerEntry struct {
+	ID           int64     `json:"id"`
+	TraderID     string    `json:"trader_id"`
+	EntryType    string    `json:"entry_type"`  // trade / funding
+	ExchangeID   string    `json:"exchange_id"` // 交易所成交ID/流水ID（用于去重）
+	OrderID      string    `json:"order_id"`
+	Symbol       string    `json:"symbol"`
+	Side         string    `json:"side"`          // BUY/SELL（仅成交）
+	PositionSide string    `json:"position_side"` // LONG/SHORT（仅成交）
+	Price        float64   `json:"price"`
+	Quantity     float64   `json:"quantity"`
+	Fee          float64   `json:"fee"` // 手续费（正数表示支出）
+	FeeAsset     string    `json:"fee_asset"`
+	RealizedPnL  float64   `json:"realized_pnl"` // 交易所计算的已实现盈亏（不含手续费）
+	Amount       float64   `json:"amount"`       // 资金费金额（正数为收入，负数为支出）
+	Time         time.Time `json:"time"`
+}
+
+const (
+	LedgerEntryTrade   = "trade"
+	LedgerEntryFunding = "funding"
+)
+
+// CircuitBreakerState 交易员熔断状态
+type CircuitBreakerState struct {
+	TraderID       string    `json:"trader_id"`
+	PeakEquity     float64   `json:"peak_equity"`      // 净值高水位
+	DayStartEquity float64   `json:"day_start_equity"` // 当日起始净值
+	DayStartTime   time.Time `json:"day_start_time"`   // 当日统计起点
+	StopUntil      time.Time `json:"stop_until"`       // 暂停交易截止时间（零值表示未触发）
+	Reason         string    `json:"reason"`           // 最近一次触发原因
+	TrippedAt      time.Time `json:"tripped_at"`       // 最近一次触发时间
+}
+
+// PositionExitState 持仓退出状态（退出策略使用的峰值和止损信息）
+type PositionExitState struct {
+	TraderID    string    `json:"trader_id"`
+	Symbol      string    `json:"symbol"`
+	Side        string    `json:"side"`         // long / short
+	EntryPrice  float64   `json:"entry_price"`  // 开仓均价（变化时视为新持仓）
+	PeakPnLPct  float64   `json:"peak_pnl_pct"` // 最高收益率（杠杆后%）
+	PeakPrice   float64   `json:"peak_price"`   // 最有利标记价格
+	InitialStop float64   `json:"initial_stop"` // 开仓时的止损价
+	CurrentStop float64   `json:"current_stop"` // 当前交易所止损价
+	OpenedAt    time.Time `json:"opened_at"`
+}
+
+// TraderState 交易员运行状态快照（重启后恢复，使风控和提示词不受重启影响）
+type TraderState struct {
+	TraderID          string             `json:"trader_id"`
+	CallCount         int                `json:"call_count"`          // AI决策周期计数
+	StartTime         time.Time          `json:"start_time"`          // 首次启动时间（运行时长从此计算）
+	DailyRealizedPnL  float64            `json:"daily_realized_pnl"`  // 当日已实现盈亏
+	LastResetTime     time.Time          `json:"last_reset_time"`     // 日盈亏上次重置时间
+	PositionFirstSeen map[string]int64   `json:"position_first_seen"` // 持仓首次出现时间 (symbol_side -> 毫秒)
+	PeakPnL           map[string]float64 `json:"peak_pnl"`            // 持仓峰值收益率 (symbol_side -> %)
+	PendingOrders     json.RawMessage    `json:"pending_orders"`      // 跟踪中的限价开仓挂单（JSON，由 trader 包定义结构）
+}
+
+// Webhook 用户的 Webhook 订阅
+type Webhook struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	TraderID  string    `json:"trader_id"` // 为空表示该用户的所有交易员
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	Events    []string  `json:"events"` // 为空表示订阅全部事件
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Subscribes 是否订阅了指定事件类型
+func (w *Webhook) Subscribes(eventType string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery Webhook 投递记录（每次尝试一条）
+type WebhookDelivery struct {
+	ID         int64     `json:"id"`
+	WebhookID  string    `json:"webhook_id"`
+	DeliveryID string    `json:"delivery_id"` // 同一事件的多次重试共用
+	EventType  string    `json:"event_type"`
+	TraderID   string    `json:"trader_id"`
+	Payload    string    `json:"payload"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code"`
+	Error      string    `json:"error,omitempty"`
+	Success    bool      `json:"success"`
+	DurationMs int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TelegramBinding Telegram 聊天与用户的绑定关系
+type TelegramBinding struct {
+	ChatID     int64     `json:"chat_id"`
+	UserID     string    `json:"user_id"`
+	Username   string    `json:"username"`
+	CanControl bool      `json:"can_control"` // 是否允许控制类命令
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TelegramBindCode 一次性 Telegram 绑定码
+type TelegramBindCode struct {
+	Code       string    `json:"code"`
+	UserID     string    `json:"user_id"`
+	CanControl bool      `json:"can_control"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// 组织角色
+const (
+	OrgRoleOwner    = "owner"    // 所有者：管理成员、修改交易所和模型密钥、删除交易员
+	OrgRoleOperator = "operator" // 交易操作员：创建/修改/启停交易员、手动下单、审批提案
+	OrgRoleViewer   = "viewer"   // 观察者：只读查看决策、持仓和盈亏
+	OrgRoleNone     = "none"     // 无权限（仅用于交易员权限覆盖）
+)
+
+var orgRoleRank = map[string]int{
+	OrgRoleNone:     0,
+	OrgRoleViewer:   1,
+	OrgRoleOperator: 2,
+	OrgRoleOwner:    3,
+}
+
+// ValidOrgRole 是否为有效的组织成员角色
+func ValidOrgRole(role string) bool {
+	return role == OrgRoleOwner || role == OrgRoleOperator || role == OrgRoleViewer
+}
+
+// ValidTraderPermissionRole 是否为有效的交易员权限覆盖角色（不能覆盖为所有者）
+func ValidTraderPermissionRole(role string) bool {
+	return role == OrgRoleOperator || role == OrgRoleViewer || role == OrgRoleNone
+}
+
+// OrgRoleAtLeast 角色是否不低于要求的角色
+func OrgRoleAtLeast(role, required string) bool {
+	return orgRoleRank[role] >= orgRoleRank[required] && orgRoleRank[role] > 0
+}
+
+// Organization 组织（团队工作区）
+type Organization struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	OwnerID   string    `json:"owner_id"`       // 组织资源（交易员、交易所、模型）归属的账户
+	Role      string    `json:"role,omitempty"` // 当前用户在组织中的角色（仅列表查询时填充）
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OrgMember 组织成员
+type OrgMember struct {
+	OrgID     string    `json:"org_id"`
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OrgInvitation 组织邀请（一次性，按邮箱接受）
+type OrgInvitation struct {
+	Token     string    `json:"token"`
+	OrgID     string    `json:"org_id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	InvitedBy string    `json:"invited_by"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// TraderPermission 成员对单个交易员的角色覆盖
+type TraderPermission struct {
+	TraderID string `json:"trader_id"`
+	UserID   string `json:"user_id"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+}
+
+// TraderAccess 用户对交易员的访问权限
+type TraderAccess struct {
+	TraderID string `json:"trader_id"`
+	OwnerID  string `json:"owner_id"` // 交易员所属账户（数据库写操作使用该账户）
+	OrgID    string `json:"org_id"`
+	Role     string `json:"role"` // 无权限时为 none
+}
+
+// APIToken API 令牌（个人访问令牌）
+type APIToken struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	Name       string    `json:"name"`
+	TokenHash  string    `json:"-"`
+	Prefix     string    `json:"prefix"` // 明文前缀（便于辨认令牌）
+	Scope      string    `json:"scope"`  // read / trade / admin
+	ExpiresAt  time.Time `json:"expires_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	LastUsedIP string    `json:"last_used_ip"`
+	RevokedAt  time.Time `json:"revoked_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Active 令牌是否可用（未撤销且未过期）
+func (t *APIToken) Active(now time.Time) bool {
+	return t.RevokedAt.IsZero() && (t.ExpiresAt.IsZero() || now.Before(t.ExpiresAt))
+}
+
 // GenerateOTPSecret 生成OTP密钥
 func GenerateOTPSecret() (string, error) {
 	secret := make([]byte, 20)
@@ -650,13 +1114,16 @@ func (d *Database) GetAIModels(userID string) ([]*AIModelConfig, error) {
 // UpdateAIModel 更新AI模型配置，如果不存在则创建用户特定配置
 func (d *Database) UpdateAIModel(userID, id string, enabled bool, apiKey, customAPIURL, customModelName string) error {
 	// 先尝试精确匹配 ID（新版逻辑，支持多个相同 provider 的模型）
-	var existingID string
+	var existingID, existingProvider string
 	err := d.db.QueryRow(`
-		SELECT id FROM ai_models WHERE user_id = ? AND id = ? LIMIT 1
-	`, userID, id).Scan(&existingID)
+		SELECT id, provider FROM ai_models WHERE user_id = ? AND id = ? LIMIT 1
+	`, userID, id).Scan(&existingID, &existingProvider)
 
 	if err == nil {
 		// 找到了现有配置（精确匹配 ID），更新它
+		if err := validateAIProvider(existingProvider); err != nil {
+			return err
+		}
 		encryptedAPIKey := d.encryptSensitiveData(apiKey)
 		_, err = d.db.Exec(`
 			UPDATE ai_models SET enabled = ?, api_key = ?, custom_api_url = ?, custom_model_name = ?, updated_at = datetime('now')
@@ -674,6 +1141,9 @@ func (d *Database) UpdateAIModel(userID, id string, enabled bool, apiKey, custom
 	if err == nil {
 		// 找到了现有配置（通过 provider 匹配，兼容旧版），更新它
 		log.Printf("⚠️  使用旧版 provider 匹配更新模型: %s -> %s", provider, existingID)
+		if err := validateAIProvider(provider); err != nil {
+			return err
+		}
 		encryptedAPIKey := d.encryptSensitiveData(apiKey)
 		_, err = d.db.Exec(`
 			UPDATE ai_models SET enabled = ?, api_key = ?, custom_api_url = ?, custom_model_name = ?, updated_at = datetime('now')
@@ -697,6 +1167,10 @@ func (d *Database) UpdateAIModel(userID, id string, enabled bool, apiKey, custom
 		}
 	}
 
+	if err := validateAIProvider(provider); err != nil {
+		return err
+	}
+
 	// 获取模型的基本信息
 	var name string
 	err = d.db.QueryRow(`
@@ -849,6 +1323,9 @@ func (d *Database) UpdateExchange(userID, id string, enabled bool, apiKey, secre
 		} else if id == "aster" {
 			name = "Aster DEX"
 			typ = "dex"
+		} else if id == "paper" {
+			name = "Paper Trading"
+			typ = "paper"
 		} else {
 			name = id + " Exchange"
 			typ = "cex"
@@ -875,8 +1352,22 @@ func (d *Database) UpdateExchange(userID, id string, enabled bool, apiKey, secre
 	return nil
 }
 
+// ErrUnsupportedAIProvider AI模型的 provider 未在 mcp 注册表中注册
+var ErrUnsupportedAIProvider = errors.New("不支持的AI provider")
+
+// validateAIProvider 校验 ai_models.provider 是否为已注册的 provider（拼写错误会把 API Key 发往错误的厂商）
+func validateAIProvider(provider string) error {
+	if !mcp.IsSupportedProvider(provider) {
+		return fmt.Errorf("%w: %s（可选: %s）", ErrUnsupportedAIProvider, provider, strings.Join(mcp.SupportedProviders(), ", "))
+	}
+	return nil
+}
+
 // CreateAIModel 创建AI模型配置
 func (d *Database) CreateAIModel(userID, id, name, provider string, enabled bool, apiKey, customAPIURL string) error {
+	if err := validateAIProvider(provider); err != nil {
+		return err
+	}
 	_, err := d.db.Exec(`
 		INSERT OR IGNORE INTO ai_models (id, user_id, name, provider, enabled, api_key, custom_api_url) 
 		VALUES (?, ?, ?, ?, ?, ?, ?)
@@ -901,12 +1392,28 @@ func (d *Database) CreateExchange(userID, id, name, typ string, enabled bool, ap
 // CreateTrader 创建交易员
 func (d *Database) CreateTrader(trader *TraderRecord) error {
 	_, err := d.db.Exec(`
-		INSERT INTO traders (id, user_id, name, ai_model_id, exchange_id, initial_balance, scan_interval_minutes, is_running, btc_eth_leverage, altcoin_leverage, trading_symbols, use_coin_pool, use_oi_top, custom_prompt, override_base_prompt, system_prompt_template, is_cross_margin)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, trader.ID, trader.UserID, trader.Name, trader.AIModelID, trader.ExchangeID, trader.InitialBalance, trader.ScanIntervalMinutes, trader.IsRunning, trader.BTCETHLeverage, trader.AltcoinLeverage, trader.TradingSymbols, trader.UseCoinPool, trader.UseOITop, trader.CustomPrompt, trader.OverrideBasePrompt, trader.SystemPromptTemplate, trader.IsCrossMargin)
+		INSERT INTO traders (id, user_id, name, ai_model_id, exchange_id, initial_balance, scan_interval_minutes, is_running, btc_eth_leverage, altcoin_leverage, trading_symbols, use_coin_pool, use_oi_top, custom_prompt, override_base_prompt, system_prompt_template, is_cross_margin, risk_config, exit_policy, decision_mode, execution_mode, auto_approve_notional, limit_order_timeout, limit_order_fallback, market_timeframes, market_indicators, org_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, trader.ID, trader.UserID, trader.Name, trader.AIModelID, trader.ExchangeID, trader.InitialBalance, trader.ScanIntervalMinutes, trader.IsRunning, trader.BTCETHLeverage, trader.AltcoinLeverage, trader.TradingSymbols, trader.UseCoinPool, trader.UseOITop, trader.CustomPrompt, trader.OverrideBasePrompt, trader.SystemPromptTemplate, trader.IsCrossMargin, trader.RiskConfig, trader.ExitPolicy, trader.DecisionMode, executionModeOrDefault(trader.ExecutionMode), trader.AutoApproveNotional, trader.LimitOrderTimeout, limitOrderFallbackOrDefault(trader.LimitOrderFallback), trader.MarketTimeframes, trader.MarketIndicators, trader.OrgID)
 	return err
 }
 
+// executionModeOrDefault 未设置执行模式时使用自动执行
+func executionModeOrDefault(mode string) string {
+	if mode == "" {
+		return "auto"
+	}
+	return mode
+}
+
+// limitOrderFallbackOrDefault 未设置限价单超时处理时撤单
+func limitOrderFallbackOrDefault(fallback string) string {
+	if fallback == "" {
+		return "cancel"
+	}
+	return fallback
+}
+
 // GetTraders 获取用户的交易员
 func (d *Database) GetTraders(userID string) ([]*TraderRecord, error) {
 	rows, err := d.db.Query(`
@@ -916,7 +1423,14 @@ func (d *Database) GetTraders(userID string) ([]*TraderRecord, error) {
 		       COALESCE(use_coin_pool, 0) as use_coin_pool, COALESCE(use_oi_top, 0) as use_oi_top,
 		       COALESCE(custom_prompt, '') as custom_prompt, COALESCE(override_base_prompt, 0) as override_base_prompt,
 		       COALESCE(system_prompt_template, 'default') as system_prompt_template,
-		       COALESCE(is_cross_margin, 1) as is_cross_margin, created_at, updated_at
+		       COALESCE(is_cross_margin, 1) as is_cross_margin,
+		       COALESCE(risk_config, '') as risk_config, COALESCE(exit_policy, '') as exit_policy,
+		       COALESCE(decision_mode, 'text') as decision_mode,
+		       COALESCE(execution_mode, 'auto') as execution_mode, COALESCE(auto_approve_notional, 0) as auto_approve_notional,
+		       COALESCE(limit_order_timeout, 0) as limit_order_timeout, COALESCE(limit_order_fallback, 'cancel') as limit_order_fallback,
+		       COALESCE(market_timeframes, '') as market_timeframes, COALESCE(market_indicators, '') as market_indicators,
+		       COALESCE(org_id, '') as org_id,
+		       created_at, updated_at
 		FROM traders WHERE user_id = ? ORDER BY created_at DESC
 	`, userID)
 	if err != nil {
@@ -933,7 +1447,10 @@ func (d *Database) GetTraders(userID string) ([]*TraderRecord, error) {
 			&trader.BTCETHLeverage, &trader.AltcoinLeverage, &trader.TradingSymbols,
 			&trader.UseCoinPool, &trader.UseOITop,
 			&trader.CustomPrompt, &trader.OverrideBasePrompt, &trader.SystemPromptTemplate,
-			&trader.IsCrossMargin,
+			&trader.IsCrossMargin, &trader.RiskConfig, &trader.ExitPolicy, &trader.DecisionMode,
+			&trader.ExecutionMode, &trader.AutoApproveNotional,
+			&trader.LimitOrderTimeout, &trader.LimitOrderFallback,
+			&trader.MarketTimeframes, &trader.MarketIndicators, &trader.OrgID,
 			&trader.CreatedAt, &trader.UpdatedAt,
 		)
 		if err != nil {
@@ -958,12 +1475,20 @@ func (d *Database) UpdateTrader(trader *TraderRecord) error {
 			name = ?, ai_model_id = ?, exchange_id = ?,
 			scan_interval_minutes = ?, btc_eth_leverage = ?, altcoin_leverage = ?,
 			trading_symbols = ?, custom_prompt = ?, override_base_prompt = ?,
-			system_prompt_template = ?, is_cross_margin = ?, updated_at = CURRENT_TIMESTAMP
+			system_prompt_template = ?, is_cross_margin = ?, risk_config = ?, exit_policy = ?, decision_mode = ?,
+			execution_mode = ?, auto_approve_notional = ?,
+			limit_order_timeout = ?, limit_order_fallback = ?,
+			market_timeframes = ?, market_indicators = ?,
+			updated_at = CURRENT_TIMESTAMP
 		WHERE id = ? AND user_id = ?
 	`, trader.Name, trader.AIModelID, trader.ExchangeID,
 		trader.ScanIntervalMinutes, trader.BTCETHLeverage, trader.AltcoinLeverage,
 		trader.TradingSymbols, trader.CustomPrompt, trader.OverrideBasePrompt,
-		trader.SystemPromptTemplate, trader.IsCrossMargin, trader.ID, trader.UserID)
+		trader.SystemPromptTemplate, trader.IsCrossMargin, trader.RiskConfig, trader.ExitPolicy, trader.DecisionMode,
+		executionModeOrDefault(trader.ExecutionMode), trader.AutoApproveNotional,
+		trader.LimitOrderTimeout, limitOrderFallbackOrDefault(trader.LimitOrderFallback),
+		trader.MarketTimeframes, trader.MarketIndicators,
+		trader.ID, trader.UserID)
 	return err
 }
 
@@ -1004,6 +1529,16 @@ func (d *Database) GetTraderConfig(userID, traderID string) (*TraderRecord, *AIM
 			COALESCE(t.override_base_prompt, 0) as override_base_prompt,
 			COALESCE(t.system_prompt_template, 'default') as system_prompt_template,
 			COALESCE(t.is_cross_margin, 1) as is_cross_margin,
+			COALESCE(t.risk_config, '') as risk_config,
+			COALESCE(t.exit_policy, '') as exit_policy,
+			COALESCE(t.decision_mode, 'text') as decision_mode,
+			COALESCE(t.execution_mode, 'auto') as execution_mode,
+			COALESCE(t.auto_approve_notional, 0) as auto_approve_notional,
+			COALESCE(t.limit_order_timeout, 0) as limit_order_timeout,
+			COALESCE(t.limit_order_fallback, 'cancel') as limit_order_fallback,
+			COALESCE(t.market_timeframes, '') as market_timeframes,
+			COALESCE(t.market_indicators, '') as market_indicators,
+			COALESCE(t.org_id, '') as org_id,
 			t.created_at, t.updated_at,
 			a.id, a.user_id, a.name, a.provider, a.enabled, a.api_key,
 			COALESCE(a.custom_api_url, '') as custom_api_url,
@@ -1025,7 +1560,10 @@ func (d *Database) GetTraderConfig(userID, traderID string) (*TraderRecord, *AIM
 		&trader.BTCETHLeverage, &trader.AltcoinLeverage, &trader.TradingSymbols,
 		&trader.UseCoinPool, &trader.UseOITop,
 		&trader.CustomPrompt, &trader.OverrideBasePrompt, &trader.SystemPromptTemplate,
-		&trader.IsCrossMargin,
+		&trader.IsCrossMargin, &trader.RiskConfig, &trader.ExitPolicy, &trader.DecisionMode,
+		&trader.ExecutionMode, &trader.AutoApproveNotional,
+		&trader.LimitOrderTimeout, &trader.LimitOrderFallback,
+		&trader.MarketTimeframes, &trader.MarketIndicators, &trader.OrgID,
 		&trader.CreatedAt, &trader.UpdatedAt,
 		&aiModel.ID, &aiModel.UserID, &aiModel.Name, &aiModel.Provider, &aiModel.Enabled, &aiModel.APIKey,
 		&aiModel.CustomAPIURL, &aiModel.CustomModelName,
@@ -1064,106 +1602,1057 @@ func (d *Database) SetSystemConfig(key, value string) error {
 	return err
 }
 
-// CreateUserSignalSource 创建用户信号源配置
-func (d *Database) CreateUserSignalSource(userID, coinPoolURL, oiTopURL string) error {
-	_, err := d.db.Exec(`
-		INSERT OR REPLACE INTO user_signal_sources (user_id, coin_pool_url, oi_top_url, updated_at)
-		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
-	`, userID, coinPoolURL, oiTopURL)
-	return err
+// SaveLedgerEntries 批量写入账本条目（已存在的条目自动跳过），返回新增条数
+func (d *Database) SaveLedgerEntries(traderID string, entries []*LedgerEntry) (int, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT OR IGNORE INTO trade_ledger (trader_id, entry_type, exchange_id, order_id, symbol, side, position_side,
+		                                    price, quantity, fee, fee_asset, realized_pnl, amount, time)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	inserted := 0
+	for _, e := range entries {
+		result, err := stmt.Exec(traderID, e.EntryType, e.ExchangeID, e.OrderID, e.Symbol, e.Side, e.PositionSide,
+			e.Price, e.Quantity, e.Fee, e.FeeAsset, e.RealizedPnL, e.Amount, e.Time.UnixMilli())
+		if err != nil {
+			return 0, fmt.Errorf("写入账本失败: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			inserted++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return inserted, nil
 }
 
-// GetUserSignalSource 获取用户信号源配置
-func (d *Database) GetUserSignalSource(userID string) (*UserSignalSource, error) {
-	var source UserSignalSource
+// GetLedgerEntries 获取指定时间之后的账本条目（按时间正序）
+func (d *Database) GetLedgerEntries(traderID string, since time.Time) ([]*LedgerEntry, error) {
+	rows, err := d.db.Query(`
+		SELECT id, trader_id, entry_type, exchange_id, order_id, symbol, side, position_side,
+		       price, quantity, fee, fee_asset, realized_pnl, amount, time
+		FROM trade_ledger WHERE trader_id = ? AND time >= ? ORDER BY time ASC, id ASC
+	`, traderID, since.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*LedgerEntry
+	for rows.Next() {
+		var e LedgerEntry
+		var ts int64
+		if err := rows.Scan(&e.ID, &e.TraderID, &e.EntryType, &e.ExchangeID, &e.OrderID, &e.Symbol, &e.Side,
+			&e.PositionSide, &e.Price, &e.Quantity, &e.Fee, &e.FeeAsset, &e.RealizedPnL, &e.Amount, &ts); err != nil {
+			return nil, err
+		}
+		e.Time = time.UnixMilli(ts)
+		entries = append(entries, &e)
+	}
+	return entries, rows.Err()
+}
+
+// GetLatestLedgerTime 获取账本中最新条目的时间（无记录时返回零值）
+func (d *Database) GetLatestLedgerTime(traderID string) (time.Time, error) {
+	var latest sql.NullInt64
+	if err := d.db.QueryRow(`SELECT MAX(time) FROM trade_ledger WHERE trader_id = ?`, traderID).Scan(&latest); err != nil {
+		return time.Time{}, err
+	}
+	if !latest.Valid {
+		return time.Time{}, nil
+	}
+	return time.UnixMilli(latest.Int64), nil
+}
+
+// GetCircuitBreakerState 获取交易员熔断状态（无记录时返回 nil）
+func (d *Database) GetCircuitBreakerState(traderID string) (*CircuitBreakerState, error) {
+	var state CircuitBreakerState
+	var dayStart, stopUntil, trippedAt int64
 	err := d.db.QueryRow(`
-		SELECT id, user_id, coin_pool_url, oi_top_url, created_at, updated_at
-		FROM user_signal_sources WHERE user_id = ?
-	`, userID).Scan(
-		&source.ID, &source.UserID, &source.CoinPoolURL, &source.OITopURL,
-		&source.CreatedAt, &source.UpdatedAt,
-	)
+		SELECT trader_id, peak_equity, day_start_equity, day_start_time, stop_until, reason, tripped_at
+		FROM circuit_breakers WHERE trader_id = ?
+	`, traderID).Scan(&state.TraderID, &state.PeakEquity, &state.DayStartEquity, &dayStart, &stopUntil, &state.Reason, &trippedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	return &source, nil
+	state.DayStartTime = unixMilliOrZero(dayStart)
+	state.StopUntil = unixMilliOrZero(stopUntil)
+	state.TrippedAt = unixMilliOrZero(trippedAt)
+	return &state, nil
 }
 
-// UpdateUserSignalSource 更新用户信号源配置
-func (d *Database) UpdateUserSignalSource(userID, coinPoolURL, oiTopURL string) error {
+// SaveCircuitBreakerState 保存交易员熔断状态
+func (d *Database) SaveCircuitBreakerState(state *CircuitBreakerState) error {
 	_, err := d.db.Exec(`
-		UPDATE user_signal_sources SET coin_pool_url = ?, oi_top_url = ?, updated_at = CURRENT_TIMESTAMP
-		WHERE user_id = ?
-	`, coinPoolURL, oiTopURL, userID)
+		INSERT INTO circuit_breakers (trader_id, peak_equity, day_start_equity, day_start_time, stop_until, reason, tripped_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(trader_id) DO UPDATE SET
+			peak_equity = excluded.peak_equity,
+			day_start_equity = excluded.day_start_equity,
+			day_start_time = excluded.day_start_time,
+			stop_until = excluded.stop_until,
+			reason = excluded.reason,
+			tripped_at = excluded.tripped_at,
+			updated_at = CURRENT_TIMESTAMP
+	`, state.TraderID, state.PeakEquity, state.DayStartEquity, milliOrZero(state.DayStartTime),
+		milliOrZero(state.StopUntil), state.Reason, milliOrZero(state.TrippedAt))
 	return err
 }
 
-// GetCustomCoins 获取所有交易员自定义币种 / Get all trader-customized currencies
-func (d *Database) GetCustomCoins() []string {
-	var symbol string
-	var symbols []string
-	_ = d.db.QueryRow(`
-		SELECT GROUP_CONCAT(custom_coins , ',') as symbol
-		FROM main.traders where custom_coins != ''
-	`).Scan(&symbol)
-	// 检测用户是否未配置币种 - 兼容性
-	if symbol == "" {
-		symbolJSON, _ := d.GetSystemConfig("default_coins")
-		if err := json.Unmarshal([]byte(symbolJSON), &symbols); err != nil {
-			log.Printf("⚠️  解析default_coins配置失败: %v，使用硬编码默认值", err)
-			symbols = []string{"BTCUSDT", "ETHUSDT", "SOLUSDT", "BNBUSDT"}
-		}
+// GetPositionExitStates 获取交易员所有持仓的退出状态
+func (d *Database) GetPositionExitStates(traderID string) ([]*PositionExitState, error) {
+	rows, err := d.db.Query(`
+		SELECT trader_id, symbol, side, entry_price, peak_pnl_pct, peak_price, initial_stop, current_stop, opened_at
+		FROM position_exit_states WHERE trader_id = ?
+	`, traderID)
+	if err != nil {
+		return nil, err
 	}
-	// filter Symbol
-	for _, s := range strings.Split(symbol, ",") {
-		if s == "" {
-			continue
-		}
-		coin := market.Normalize(s)
-		if !slices.Contains(symbols, coin) {
-			symbols = append(symbols, coin)
+	defer rows.Close()
+
+	var states []*PositionExitState
+	for rows.Next() {
+		var state PositionExitState
+		var openedAt int64
+		if err := rows.Scan(&state.TraderID, &state.Symbol, &state.Side, &state.EntryPrice, &state.PeakPnLPct,
+			&state.PeakPrice, &state.InitialStop, &state.CurrentStop, &openedAt); err != nil {
+			return nil, err
 		}
+		state.OpenedAt = unixMilliOrZero(openedAt)
+		states = append(states, &state)
 	}
-	return symbols
+	return states, rows.Err()
 }
 
-// Close 关闭数据库连接
-func (d *Database) Close() error {
-	return d.db.Close()
+// SavePositionExitState 保存持仓退出状态
+func (d *Database) SavePositionExitState(state *PositionExitState) error {
+	_, err := d.db.Exec(`
+		INSERT INTO position_exit_states (trader_id, symbol, side, entry_price, peak_pnl_pct, peak_price,
+		                                  initial_stop, current_stop, opened_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(trader_id, symbol, side) DO UPDATE SET
+			entry_price = excluded.entry_price,
+			peak_pnl_pct = excluded.peak_pnl_pct,
+			peak_price = excluded.peak_price,
+			initial_stop = excluded.initial_stop,
+			current_stop = excluded.current_stop,
+			opened_at = excluded.opened_at,
+			updated_at = CURRENT_TIMESTAMP
+	`, state.TraderID, state.Symbol, state.Side, state.EntryPrice, state.PeakPnLPct, state.PeakPrice,
+		state.InitialStop, state.CurrentStop, milliOrZero(state.OpenedAt))
+	return err
 }
 
-// LoadBetaCodesFromFile 从文件加载内测码到数据库
-func (d *Database) LoadBetaCodesFromFile(filePath string) error {
-	// 读取文件内容
-	content, err := os.ReadFile(filePath)
+// DeletePositionExitState 删除持仓退出状态（平仓后调用）
+func (d *Database) DeletePositionExitState(traderID, symbol, side string) error {
+	_, err := d.db.Exec(`DELETE FROM position_exit_states WHERE trader_id = ? AND symbol = ? AND side = ?`,
+		traderID, symbol, side)
+	return err
+}
+
+// GetTraderState 获取交易员运行状态快照（无记录时返回 nil）
+func (d *Database) GetTraderState(traderID string) (*TraderState, error) {
+	state := TraderState{TraderID: traderID}
+	var startTime, lastReset int64
+	var firstSeen, peakPnL, pendingOrders string
+	err := d.db.QueryRow(`
+		SELECT call_count, start_time, daily_realized_pnl, last_reset_time, position_first_seen, peak_pnl,
+		       COALESCE(pending_orders, '[]')
+		FROM trader_states WHERE trader_id = ?
+	`, traderID).Scan(&state.CallCount, &startTime, &state.DailyRealizedPnL, &lastReset, &firstSeen, &peakPnL, &pendingOrders)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
 	if err != nil {
-		return fmt.Errorf("读取内测码文件失败: %w", err)
+		return nil, err
+	}
+	state.StartTime = unixMilliOrZero(startTime)
+	state.LastResetTime = unixMilliOrZero(lastReset)
+	if err := json.Unmarshal([]byte(firstSeen), &state.PositionFirstSeen); err != nil {
+		return nil, fmt.Errorf("解析持仓首次出现时间失败: %w", err)
 	}
+	if err := json.Unmarshal([]byte(peakPnL), &state.PeakPnL); err != nil {
+		return nil, fmt.Errorf("解析峰值收益缓存失败: %w", err)
+	}
+	state.PendingOrders = json.RawMessage(pendingOrders)
+	return &state, nil
+}
 
-	// 按行分割内测码
-	lines := strings.Split(string(content), "\n")
-	var codes []string
-	for _, line := range lines {
-		code := strings.TrimSpace(line)
-		if code != "" && !strings.HasPrefix(code, "#") {
-			codes = append(codes, code)
-		}
+// SaveTraderState 保存交易员运行状态快照
+func (d *Database) SaveTraderState(state *TraderState) error {
+	firstSeen, err := json.Marshal(state.PositionFirstSeen)
+	if err != nil {
+		return fmt.Errorf("序列化持仓首次出现时间失败: %w", err)
+	}
+	peakPnL, err := json.Marshal(state.PeakPnL)
+	if err != nil {
+		return fmt.Errorf("序列化峰值收益缓存失败: %w", err)
+	}
+	pendingOrders := "[]"
+	if len(state.PendingOrders) > 0 {
+		pendingOrders = string(state.PendingOrders)
 	}
+	_, err = d.db.Exec(`
+		INSERT INTO trader_states (trader_id, call_count, start_time, daily_realized_pnl, last_reset_time,
+		                           position_first_seen, peak_pnl, pending_orders, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(trader_id) DO UPDATE SET
+			call_count = excluded.call_count,
+			start_time = excluded.start_time,
+			daily_realized_pnl = excluded.daily_realized_pnl,
+			last_reset_time = excluded.last_reset_time,
+			position_first_seen = excluded.position_first_seen,
+			peak_pnl = excluded.peak_pnl,
+			pending_orders = excluded.pending_orders,
+			updated_at = CURRENT_TIMESTAMP
+	`, state.TraderID, state.CallCount, milliOrZero(state.StartTime), state.DailyRealizedPnL,
+		milliOrZero(state.LastResetTime), string(firstSeen), string(peakPnL), pendingOrders)
+	return err
+}
 
-	// 批量插入内测码
-	tx, err := d.db.Begin()
+// webhookDeliveryRetention 每个 Webhook 保留的投递记录数
+const webhookDeliveryRetention = 500
+
+const webhookColumns = `id, user_id, trader_id, url, secret, events, enabled, created_at`
+
+// CreateWebhook 创建 Webhook 订阅
+func (d *Database) CreateWebhook(webhook *Webhook) error {
+	events, err := json.Marshal(webhookEvents(webhook.Events))
 	if err != nil {
-		return fmt.Errorf("开始事务失败: %w", err)
+		return fmt.Errorf("序列化订阅事件失败: %w", err)
 	}
-	defer tx.Rollback()
+	_, err = d.db.Exec(`
+		INSERT INTO webhooks (id, user_id, trader_id, url, secret, events, enabled)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, webhook.ID, webhook.UserID, webhook.TraderID, webhook.URL, d.encryptSensitiveData(webhook.Secret),
+		string(events), webhook.Enabled)
+	return err
+}
 
-	stmt, err := tx.Prepare(`INSERT OR IGNORE INTO beta_codes (code) VALUES (?)`)
+// UpdateWebhook 更新 Webhook 订阅（Secret 为空时保留原密钥）
+func (d *Database) UpdateWebhook(webhook *Webhook) error {
+	events, err := json.Marshal(webhookEvents(webhook.Events))
 	if err != nil {
-		return fmt.Errorf("准备语句失败: %w", err)
+		return fmt.Errorf("序列化订阅事件失败: %w", err)
 	}
-	defer stmt.Close()
+	query := `UPDATE webhooks SET trader_id = ?, url = ?, events = ?, enabled = ?, updated_at = CURRENT_TIMESTAMP`
+	args := []interface{}{webhook.TraderID, webhook.URL, string(events), webhook.Enabled}
+	if webhook.Secret != "" {
+		query += `, secret = ?`
+		args = append(args, d.encryptSensitiveData(webhook.Secret))
+	}
+	query += ` WHERE id = ? AND user_id = ?`
+	args = append(args, webhook.ID, webhook.UserID)
 
-	insertedCount := 0
+	result, err := d.db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteWebhook 删除 Webhook 订阅及其投递记录
+func (d *Database) DeleteWebhook(userID, id string) error {
+	result, err := d.db.Exec(`DELETE FROM webhooks WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	_, err = d.db.Exec(`DELETE FROM webhook_deliveries WHERE webhook_id = ?`, id)
+	return err
+}
+
+// GetWebhook 获取用户的单个 Webhook 订阅（不存在时返回 sql.ErrNoRows）
+func (d *Database) GetWebhook(userID, id string) (*Webhook, error) {
+	rows, err := d.db.Query(`SELECT `+webhookColumns+` FROM webhooks WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	webhooks, err := d.scanWebhooks(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(webhooks) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return webhooks[0], nil
+}
+
+// GetWebhooks 获取用户的所有 Webhook 订阅
+func (d *Database) GetWebhooks(userID string) ([]*Webhook, error) {
+	rows, err := d.db.Query(`SELECT `+webhookColumns+` FROM webhooks WHERE user_id = ? ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return d.scanWebhooks(rows)
+}
+
+// GetWebhooksForTrader 获取应接收指定交易员事件的已启用 Webhook（交易员所属用户的订阅）
+func (d *Database) GetWebhooksForTrader(traderID string) ([]*Webhook, error) {
+	rows, err := d.db.Query(`
+		SELECT w.id, w.user_id, w.trader_id, w.url, w.secret, w.events, w.enabled, w.created_at
+		FROM webhooks w
+		JOIN traders t ON t.user_id = w.user_id
+		WHERE t.id = ? AND w.enabled = 1 AND (w.trader_id = '' OR w.trader_id = ?)
+	`, traderID, traderID)
+	if err != nil {
+		return nil, err
+	}
+	return d.scanWebhooks(rows)
+}
+
+// scanWebhooks 读取 Webhook 查询结果并解密密钥
+func (d *Database) scanWebhooks(rows *sql.Rows) ([]*Webhook, error) {
+	defer rows.Close()
+
+	var webhooks []*Webhook
+	for rows.Next() {
+		var webhook Webhook
+		var events string
+		if err := rows.Scan(&webhook.ID, &webhook.UserID, &webhook.TraderID, &webhook.URL, &webhook.Secret,
+			&events, &webhook.Enabled, &webhook.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(events), &webhook.Events); err != nil {
+			return nil, fmt.Errorf("解析订阅事件失败: %w", err)
+		}
+		webhook.Secret = d.decryptSensitiveData(webhook.Secret)
+		webhooks = append(webhooks, &webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// webhookEvents nil 存为空数组
+func webhookEvents(events []string) []string {
+	if events == nil {
+		return []string{}
+	}
+	return events
+}
+
+// SaveWebhookDelivery 记录一次 Webhook 投递尝试，并只保留最近的记录
+func (d *Database) SaveWebhookDelivery(delivery *WebhookDelivery) error {
+	if delivery.CreatedAt.IsZero() {
+		delivery.CreatedAt = time.Now()
+	}
+	result, err := d.db.Exec(`
+		INSERT INTO webhook_deliveries (webhook_id, delivery_id, event_type, trader_id, payload, attempt,
+		                                status_code, error, success, duration_ms, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, delivery.WebhookID, delivery.DeliveryID, delivery.EventType, delivery.TraderID, delivery.Payload,
+		delivery.Attempt, delivery.StatusCode, delivery.Error, delivery.Success, delivery.DurationMs,
+		milliOrZero(delivery.CreatedAt))
+	if err != nil {
+		return err
+	}
+	delivery.ID, _ = result.LastInsertId()
+
+	_, err = d.db.Exec(`
+		DELETE FROM webhook_deliveries WHERE webhook_id = ? AND id <= (
+			SELECT id FROM webhook_deliveries WHERE webhook_id = ? ORDER BY id DESC LIMIT 1 OFFSET ?
+		)
+	`, delivery.WebhookID, delivery.WebhookID, webhookDeliveryRetention)
+	return err
+}
+
+// GetWebhookDeliveries 获取 Webhook 最近的投递记录（按时间倒序）
+func (d *Database) GetWebhookDeliveries(webhookID string, limit int) ([]*WebhookDelivery, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := d.db.Query(`
+		SELECT id, webhook_id, delivery_id, event_type, trader_id, payload, attempt, status_code, error,
+		       success, duration_ms, created_at
+		FROM webhook_deliveries WHERE webhook_id = ? ORDER BY id DESC LIMIT ?
+	`, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*WebhookDelivery
+	for rows.Next() {
+		var delivery WebhookDelivery
+		var createdAt int64
+		if err := rows.Scan(&delivery.ID, &delivery.WebhookID, &delivery.DeliveryID, &delivery.EventType,
+			&delivery.TraderID, &delivery.Payload, &delivery.Attempt, &delivery.StatusCode, &delivery.Error,
+			&delivery.Success, &delivery.DurationMs, &createdAt); err != nil {
+			return nil, err
+		}
+		delivery.CreatedAt = unixMilliOrZero(createdAt)
+		deliveries = append(deliveries, &delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// CreateTelegramBindCode 保存绑定码（同时清理已过期的绑定码）
+func (d *Database) CreateTelegramBindCode(code *TelegramBindCode) error {
+	if _, err := d.db.Exec(`DELETE FROM telegram_bind_codes WHERE expires_at < ?`, time.Now().UnixMilli()); err != nil {
+		return err
+	}
+	_, err := d.db.Exec(`
+		INSERT INTO telegram_bind_codes (code, user_id, can_control, expires_at) VALUES (?, ?, ?, ?)
+	`, code.Code, code.UserID, code.CanControl, milliOrZero(code.ExpiresAt))
+	return err
+}
+
+// ConsumeTelegramBindCode 使用绑定码（一次性，不存在或已过期时返回 nil）
+func (d *Database) ConsumeTelegramBindCode(code string) (*TelegramBindCode, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	bindCode := TelegramBindCode{Code: code}
+	var expiresAt int64
+	err = tx.QueryRow(`SELECT user_id, can_control, expires_at FROM telegram_bind_codes WHERE code = ?`, code).
+		Scan(&bindCode.UserID, &bindCode.CanControl, &expiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`DELETE FROM telegram_bind_codes WHERE code = ?`, code); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	bindCode.ExpiresAt = unixMilliOrZero(expiresAt)
+	if time.Now().After(bindCode.ExpiresAt) {
+		return nil, nil
+	}
+	return &bindCode, nil
+}
+
+// SaveTelegramBinding 保存聊天绑定（同一聊天重新绑定时覆盖）
+func (d *Database) SaveTelegramBinding(binding *TelegramBinding) error {
+	_, err := d.db.Exec(`
+		INSERT INTO telegram_bindings (chat_id, user_id, username, can_control, created_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(chat_id) DO UPDATE SET
+			user_id = excluded.user_id,
+			username = excluded.username,
+			can_control = excluded.can_control,
+			created_at = CURRENT_TIMESTAMP
+	`, binding.ChatID, binding.UserID, binding.Username, binding.CanControl)
+	return err
+}
+
+// GetTelegramBinding 获取聊天绑定（未绑定时返回 nil）
+func (d *Database) GetTelegramBinding(chatID int64) (*TelegramBinding, error) {
+	var binding TelegramBinding
+	err := d.db.QueryRow(`
+		SELECT chat_id, user_id, username, can_control, created_at FROM telegram_bindings WHERE chat_id = ?
+	`, chatID).Scan(&binding.ChatID, &binding.UserID, &binding.Username, &binding.CanControl, &binding.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &binding, nil
+}
+
+// GetTelegramBindings 获取用户绑定的所有聊天
+func (d *Database) GetTelegramBindings(userID string) ([]*TelegramBinding, error) {
+	rows, err := d.db.Query(`
+		SELECT chat_id, user_id, username, can_control, created_at FROM telegram_bindings
+		WHERE user_id = ? ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bindings []*TelegramBinding
+	for rows.Next() {
+		var binding TelegramBinding
+		if err := rows.Scan(&binding.ChatID, &binding.UserID, &binding.Username, &binding.CanControl, &binding.CreatedAt); err != nil {
+			return nil, err
+		}
+		bindings = append(bindings, &binding)
+	}
+	return bindings, rows.Err()
+}
+
+// DeleteTelegramBinding 解除聊天绑定（userID 为空时不校验归属，供机器人 /unbind 使用）
+func (d *Database) DeleteTelegramBinding(userID string, chatID int64) error {
+	query := `DELETE FROM telegram_bindings WHERE chat_id = ?`
+	args := []interface{}{chatID}
+	if userID != "" {
+		query += ` AND user_id = ?`
+		args = append(args, userID)
+	}
+	result, err := d.db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ErrInvitationEmailMismatch 邀请邮箱与接受邀请的账户不一致
+var ErrInvitationEmailMismatch = errors.New("邀请邮箱与当前账户不一致")
+
+// CreateOrganization 创建组织，创建者成为所有者
+func (d *Database) CreateOrganization(org *Organization) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`INSERT INTO organizations (id, name, owner_id) VALUES (?, ?, ?)`, org.ID, org.Name, org.OwnerID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO org_members (org_id, user_id, role) VALUES (?, ?, ?)`, org.ID, org.OwnerID, OrgRoleOwner); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetOrganization 获取组织（不存在时返回 nil）
+func (d *Database) GetOrganization(orgID string) (*Organization, error) {
+	var org Organization
+	err := d.db.QueryRow(`SELECT id, name, owner_id, created_at FROM organizations WHERE id = ?`, orgID).
+		Scan(&org.ID, &org.Name, &org.OwnerID, &org.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// GetUserOrganizations 获取用户加入的组织（包含用户在各组织中的角色）
+func (d *Database) GetUserOrganizations(userID string) ([]*Organization, error) {
+	rows, err := d.db.Query(`
+		SELECT o.id, o.name, o.owner_id, m.role, o.created_at
+		FROM organizations o JOIN org_members m ON m.org_id = o.id
+		WHERE m.user_id = ? ORDER BY o.created_at
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orgs []*Organization
+	for rows.Next() {
+		var org Organization
+		if err := rows.Scan(&org.ID, &org.Name, &org.OwnerID, &org.Role, &org.CreatedAt); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, &org)
+	}
+	return orgs, rows.Err()
+}
+
+// DeleteOrganization 删除组织（组织内交易员回到所有者的个人工作区）
+func (d *Database) DeleteOrganization(orgID string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	queries := []string{
+		`DELETE FROM trader_permissions WHERE trader_id IN (SELECT id FROM traders WHERE org_id = ?)`,
+		`UPDATE traders SET org_id = '' WHERE org_id = ?`,
+		`DELETE FROM org_invitations WHERE org_id = ?`,
+		`DELETE FROM org_members WHERE org_id = ?`,
+		`DELETE FROM organizations WHERE id = ?`,
+	}
+	for _, query := range queries {
+		if _, err := tx.Exec(query, orgID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetOrgMemberRole 获取用户在组织中的角色（非成员时返回空字符串）
+func (d *Database) GetOrgMemberRole(orgID, userID string) (string, error) {
+	var role string
+	err := d.db.QueryRow(`SELECT role FROM org_members WHERE org_id = ? AND user_id = ?`, orgID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
+
+// GetOrgMembers 获取组织成员
+func (d *Database) GetOrgMembers(orgID string) ([]*OrgMember, error) {
+	rows, err := d.db.Query(`
+		SELECT m.org_id, m.user_id, COALESCE(u.email, ''), m.role, m.created_at
+		FROM org_members m LEFT JOIN users u ON u.id = m.user_id
+		WHERE m.org_id = ? ORDER BY m.created_at
+	`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []*OrgMember
+	for rows.Next() {
+		var member OrgMember
+		if err := rows.Scan(&member.OrgID, &member.UserID, &member.Email, &member.Role, &member.CreatedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, &member)
+	}
+	return members, rows.Err()
+}
+
+// SetOrgMemberRole 修改成员角色（成员不存在时返回 sql.ErrNoRows）
+func (d *Database) SetOrgMemberRole(orgID, userID, role string) error {
+	result, err := d.db.Exec(`UPDATE org_members SET role = ? WHERE org_id = ? AND user_id = ?`, role, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// RemoveOrgMember 移除成员（同时删除其在组织交易员上的权限覆盖，成员不存在时返回 sql.ErrNoRows）
+func (d *Database) RemoveOrgMember(orgID, userID string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		DELETE FROM trader_permissions WHERE user_id = ? AND trader_id IN (SELECT id FROM traders WHERE org_id = ?)
+	`, userID, orgID); err != nil {
+		return err
+	}
+	result, err := tx.Exec(`DELETE FROM org_members WHERE org_id = ? AND user_id = ?`, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return tx.Commit()
+}
+
+// CreateOrgInvitation 保存邀请（同时清理已过期的邀请）
+func (d *Database) CreateOrgInvitation(invitation *OrgInvitation) error {
+	if _, err := d.db.Exec(`DELETE FROM org_invitations WHERE expires_at < ?`, time.Now().UnixMilli()); err != nil {
+		return err
+	}
+	_, err := d.db.Exec(`
+		INSERT INTO org_invitations (token, org_id, email, role, invited_by, expires_at) VALUES (?, ?, ?, ?, ?, ?)
+	`, invitation.Token, invitation.OrgID, strings.ToLower(invitation.Email), invitation.Role, invitation.InvitedBy, milliOrZero(invitation.ExpiresAt))
+	return err
+}
+
+// GetOrgInvitations 获取组织未过期的邀请
+func (d *Database) GetOrgInvitations(orgID string) ([]*OrgInvitation, error) {
+	rows, err := d.db.Query(`
+		SELECT token, org_id, email, role, invited_by, expires_at FROM org_invitations
+		WHERE org_id = ? AND expires_at >= ? ORDER BY created_at
+	`, orgID, time.Now().UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invitations []*OrgInvitation
+	for rows.Next() {
+		var invitation OrgInvitation
+		var expiresAt int64
+		if err := rows.Scan(&invitation.Token, &invitation.OrgID, &invitation.Email, &invitation.Role,
+			&invitation.InvitedBy, &expiresAt); err != nil {
+			return nil, err
+		}
+		invitation.ExpiresAt = unixMilliOrZero(expiresAt)
+		invitations = append(invitations, &invitation)
+	}
+	return invitations, rows.Err()
+}
+
+// DeleteOrgInvitation 撤销邀请（不存在时返回 sql.ErrNoRows）
+func (d *Database) DeleteOrgInvitation(orgID, token string) error {
+	result, err := d.db.Exec(`DELETE FROM org_invitations WHERE org_id = ? AND token = ?`, orgID, token)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// AcceptOrgInvitation 接受邀请并加入组织（一次性，不存在或已过期时返回 nil；已是成员时保持原角色）
+func (d *Database) AcceptOrgInvitation(token, userID, email string) (*OrgInvitation, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	invitation := OrgInvitation{Token: token}
+	var expiresAt int64
+	err = tx.QueryRow(`SELECT org_id, email, role, invited_by, expires_at FROM org_invitations WHERE token = ?`, token).
+		Scan(&invitation.OrgID, &invitation.Email, &invitation.Role, &invitation.InvitedBy, &expiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	invitation.ExpiresAt = unixMilliOrZero(expiresAt)
+	if time.Now().After(invitation.ExpiresAt) {
+		tx.Exec(`DELETE FROM org_invitations WHERE token = ?`, token)
+		return nil, tx.Commit()
+	}
+	if !strings.EqualFold(invitation.Email, email) {
+		return nil, ErrInvitationEmailMismatch
+	}
+
+	if _, err := tx.Exec(`DELETE FROM org_invitations WHERE token = ?`, token); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO org_members (org_id, user_id, role) VALUES (?, ?, ?)
+		ON CONFLICT(org_id, user_id) DO NOTHING
+	`, invitation.OrgID, userID, invitation.Role); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// SetTraderOrg 将交易员移入组织（orgID 为空表示移回个人工作区，同时清除交易员权限覆盖）
+func (d *Database) SetTraderOrg(ownerID, traderID, orgID string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE traders SET org_id = ? WHERE id = ? AND user_id = ?`, orgID, traderID, ownerID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	if orgID == "" {
+		if _, err := tx.Exec(`DELETE FROM trader_permissions WHERE trader_id = ?`, traderID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SetTraderPermission 设置成员对单个交易员的角色覆盖
+func (d *Database) SetTraderPermission(traderID, userID, role string) error {
+	_, err := d.db.Exec(`
+		INSERT INTO trader_permissions (trader_id, user_id, role) VALUES (?, ?, ?)
+		ON CONFLICT(trader_id, user_id) DO UPDATE SET role = excluded.role
+	`, traderID, userID, role)
+	return err
+}
+
+// DeleteTraderPermission 删除交易员角色覆盖（恢复为组织角色，不存在时返回 sql.ErrNoRows）
+func (d *Database) DeleteTraderPermission(traderID, userID string) error {
+	result, err := d.db.Exec(`DELETE FROM trader_permissions WHERE trader_id = ? AND user_id = ?`, traderID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetTraderPermissions 获取交易员的角色覆盖列表
+func (d *Database) GetTraderPermissions(traderID string) ([]*TraderPermission, error) {
+	rows, err := d.db.Query(`
+		SELECT p.trader_id, p.user_id, COALESCE(u.email, ''), p.role
+		FROM trader_permissions p LEFT JOIN users u ON u.id = p.user_id
+		WHERE p.trader_id = ? ORDER BY p.created_at
+	`, traderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var permissions []*TraderPermission
+	for rows.Next() {
+		var permission TraderPermission
+		if err := rows.Scan(&permission.TraderID, &permission.UserID, &permission.Email, &permission.Role); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, &permission)
+	}
+	return permissions, rows.Err()
+}
+
+// GetTraderAccess 解析用户对交易员的访问权限（交易员不存在时返回 sql.ErrNoRows）
+//
+// 交易员所属账户为所有者；组织内交易员按成员角色授权，非所有者成员可被单个交易员的权限覆盖
+func (d *Database) GetTraderAccess(userID, traderID string) (*TraderAccess, error) {
+	access := TraderAccess{TraderID: traderID, Role: OrgRoleNone}
+	err := d.db.QueryRow(`SELECT user_id, COALESCE(org_id, '') FROM traders WHERE id = ?`, traderID).
+		Scan(&access.OwnerID, &access.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	if access.OwnerID == userID {
+		access.Role = OrgRoleOwner
+		return &access, nil
+	}
+	if access.OrgID == "" || userID == "" {
+		return &access, nil
+	}
+
+	role, err := d.GetOrgMemberRole(access.OrgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if role == "" {
+		return &access, nil
+	}
+	access.Role = role
+	if role != OrgRoleOwner {
+		var override string
+		err := d.db.QueryRow(`SELECT role FROM trader_permissions WHERE trader_id = ? AND user_id = ?`, traderID, userID).Scan(&override)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		if override != "" {
+			access.Role = override
+		}
+	}
+	return &access, nil
+}
+
+// CreateAPIToken 保存 API 令牌
+func (d *Database) CreateAPIToken(token *APIToken) error {
+	_, err := d.db.Exec(`
+		INSERT INTO api_tokens (id, user_id, name, token_hash, prefix, scope, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, token.ID, token.UserID, token.Name, token.TokenHash, token.Prefix, token.Scope, milliOrZero(token.ExpiresAt))
+	return err
+}
+
+// GetAPITokens 获取用户的 API 令牌（包括已撤销和已过期的）
+func (d *Database) GetAPITokens(userID string) ([]*APIToken, error) {
+	rows, err := d.db.Query(`
+		SELECT id, user_id, name, token_hash, prefix, scope, expires_at, last_used_at, last_used_ip, revoked_at, created_at
+		FROM api_tokens WHERE user_id = ? ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []*APIToken
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// GetAPITokenByHash 按哈希查询 API 令牌（不存在时返回 nil）
+func (d *Database) GetAPITokenByHash(tokenHash string) (*APIToken, error) {
+	token, err := scanAPIToken(d.db.QueryRow(`
+		SELECT id, user_id, name, token_hash, prefix, scope, expires_at, last_used_at, last_used_ip, revoked_at, created_at
+		FROM api_tokens WHERE token_hash = ?
+	`, tokenHash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return token, err
+}
+
+// scanAPIToken 扫描一行 API 令牌记录
+func scanAPIToken(row interface{ Scan(...any) error }) (*APIToken, error) {
+	var token APIToken
+	var expiresAt, lastUsedAt, revokedAt int64
+	if err := row.Scan(&token.ID, &token.UserID, &token.Name, &token.TokenHash, &token.Prefix, &token.Scope,
+		&expiresAt, &lastUsedAt, &token.LastUsedIP, &revokedAt, &token.CreatedAt); err != nil {
+		return nil, err
+	}
+	token.ExpiresAt = unixMilliOrZero(expiresAt)
+	token.LastUsedAt = unixMilliOrZero(lastUsedAt)
+	token.RevokedAt = unixMilliOrZero(revokedAt)
+	return &token, nil
+}
+
+// RevokeAPIToken 撤销 API 令牌（不存在或已撤销时返回 sql.ErrNoRows）
+func (d *Database) RevokeAPIToken(userID, id string) error {
+	result, err := d.db.Exec(`
+		UPDATE api_tokens SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at = 0
+	`, time.Now().UnixMilli(), id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// TouchAPIToken 记录 API 令牌最近使用时间和来源IP
+func (d *Database) TouchAPIToken(id, ip string, usedAt time.Time) error {
+	_, err := d.db.Exec(`UPDATE api_tokens SET last_used_at = ?, last_used_ip = ? WHERE id = ?`, milliOrZero(usedAt), ip, id)
+	return err
+}
+
+// milliOrZero 零值时间存为0
+func milliOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+// unixMilliOrZero 0 还原为零值时间
+func unixMilliOrZero(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
+// CreateUserSignalSource 创建用户信号源配置
+func (d *Database) CreateUserSignalSource(userID, coinPoolURL, oiTopURL string) error {
+	_, err := d.db.Exec(`
+		INSERT OR REPLACE INTO user_signal_sources (user_id, coin_pool_url, oi_top_url, updated_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+	`, userID, coinPoolURL, oiTopURL)
+	return err
+}
+
+// GetUserSignalSource 获取用户信号源配置
+func (d *Database) GetUserSignalSource(userID string) (*UserSignalSource, error) {
+	var source UserSignalSource
+	err := d.db.QueryRow(`
+		SELECT id, user_id, coin_pool_url, oi_top_url, created_at, updated_at
+		FROM user_signal_sources WHERE user_id = ?
+	`, userID).Scan(
+		&source.ID, &source.UserID, &source.CoinPoolURL, &source.OITopURL,
+		&source.CreatedAt, &source.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &source, nil
+}
+
+// UpdateUserSignalSource 更新用户信号源配置
+func (d *Database) UpdateUserSignalSource(userID, coinPoolURL, oiTopURL string) error {
+	_, err := d.db.Exec(`
+		UPDATE user_signal_sources SET coin_pool_url = ?, oi_top_url = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = ?
+	`, coinPoolURL, oiTopURL, userID)
+	return err
+}
+
+// GetCustomCoins 获取所有交易员自定义币种 / Get all trader-customized currencies
+func (d *Database) GetCustomCoins() []string {
+	var symbol string
+	var symbols []string
+	_ = d.db.QueryRow(`
+		SELECT GROUP_CONCAT(custom_coins , ',') as symbol
+		FROM main.traders where custom_coins != ''
+	`).Scan(&symbol)
+	// 检测用户是否未配置币种 - 兼容性
+	if symbol == "" {
+		symbolJSON, _ := d.GetSystemConfig("default_coins")
+		if err := json.Unmarshal([]byte(symbolJSON), &symbols); err != nil {
+			log.Printf("⚠️  解析default_coins配置失败: %v，使用硬编码默认值", err)
+			symbols = []string{"BTCUSDT", "ETHUSDT", "SOLUSDT", "BNBUSDT"}
+		}
+	}
+	// filter Symbol
+	for _, s := range strings.Split(symbol, ",") {
+		if s == "" {
+			continue
+		}
+		coin := market.Normalize(s)
+		if !slices.Contains(symbols, coin) {
+			symbols = append(symbols, coin)
+		}
+	}
+	return symbols
+}
+
+// Close 关闭数据库连接
+func (d *Database) Close() error {
+	return d.db.Close()
+}
+
+// LoadBetaCodesFromFile 从文件加载内测码到数据库
+func (d *Database) LoadBetaCodesFromFile(filePath string) error {
+	// 读取文件内容
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("读取内测码文件失败: %w", err)
+	}
+
+	// 按行分割内测码
+	lines := strings.Split(string(content), "\n")
+	var codes []string
+	for _, line := range lines {
+		code := strings.TrimSpace(line)
+		if code != "" && !strings.HasPrefix(code, "#") {
+			codes = append(codes, code)
+		}
+	}
+
+	// 批量插入内测码
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT OR IGNORE INTO beta_codes (code) VALUES (?)`)
+	if err != nil {
+		return fmt.Errorf("准备语句失败: %w", err)
+	}
+	defer stmt.Close()
+
+	insertedCount := 0
 	for _, code := range codes {
 		result, err := stmt.Exec(code)
 		if err != nil {
@@ -1273,3 +2762,162 @@ func (d *Database) decryptSensitiveData(encrypted string) string {
 
 	return decrypted
 }
+
+// sensitiveColumns 加密存储的敏感字段（密钥轮换时需要重新加密）
+var sensitiveColumns = []struct{ Table, Column string }{
+	{"ai_models", "api_key"},
+	{"exchanges", "api_key"},
+	{"exchanges", "secret_key"},
+	{"exchanges", "aster_private_key"},
+	{"webhooks", "secret"},
+}
+
+// EncryptionColumnReport 单个敏感字段的加密状态
+type EncryptionColumnReport struct {
+	Table         string         `json:"table"`
+	Column        string         `json:"column"`
+	Total         int            `json:"total"`         // 非空值数量
+	Current       int            `json:"current"`       // 已使用当前密钥加密
+	Plaintext     int            `json:"plaintext"`     // 未加密
+	Undecryptable int            `json:"undecryptable"` // 任何已加载密钥都无法解密
+	ByKeyID       map[string]int `json:"by_key_id"`     // 按密钥ID统计（v1 密文记为 "v1"）
+}
+
+// EncryptionReport 敏感数据加密状态报告
+type EncryptionReport struct {
+	KeyID          string                    `json:"key_id"`           // 当前密钥ID
+	PreviousKeyIDs []string                  `json:"previous_key_ids"` // 过渡期旧密钥ID
+	Columns        []*EncryptionColumnReport `json:"columns"`
+	ReEncrypted    int                       `json:"re_encrypted"` // 本次重新加密的数量
+}
+
+// Complete 所有敏感数据都已使用当前密钥加密（可以移除旧密钥）
+func (r *EncryptionReport) Complete() bool {
+	for _, col := range r.Columns {
+		if col.Current != col.Total {
+			return false
+		}
+	}
+	return true
+}
+
+// VerifySensitiveData 检查所有敏感字段的加密状态（逐条尝试解密）
+func (d *Database) VerifySensitiveData() (*EncryptionReport, error) {
+	if d.cryptoService == nil {
+		return nil, fmt.Errorf("加密服务未初始化")
+	}
+
+	report := d.newEncryptionReport()
+	for _, sc := range sensitiveColumns {
+		col := &EncryptionColumnReport{Table: sc.Table, Column: sc.Column, ByKeyID: make(map[string]int)}
+		values, err := querySensitiveValues(d.db, sc.Table, sc.Column)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range values {
+			d.countSensitiveValue(col, v.value)
+		}
+		report.Columns = append(report.Columns, col)
+	}
+	return report, nil
+}
+
+// ReEncryptSensitiveData 在一个事务中用当前密钥重新加密所有敏感字段
+// 任一值无法解密时回滚，不会留下部分轮换的数据
+func (d *Database) ReEncryptSensitiveData() (*EncryptionReport, error) {
+	if d.cryptoService == nil {
+		return nil, fmt.Errorf("加密服务未初始化")
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	report := d.newEncryptionReport()
+	for _, sc := range sensitiveColumns {
+		col := &EncryptionColumnReport{Table: sc.Table, Column: sc.Column, ByKeyID: make(map[string]int)}
+		values, err := querySensitiveValues(tx, sc.Table, sc.Column)
+		if err != nil {
+			return nil, err
+		}
+
+		update := fmt.Sprintf(`UPDATE %s SET %s = ? WHERE rowid = ?`, sc.Table, sc.Column)
+		for _, v := range values {
+			encrypted, changed, err := d.cryptoService.ReEncryptForStorage(v.value)
+			if err != nil {
+				return nil, fmt.Errorf("重新加密 %s.%s (rowid=%d) 失败: %w", sc.Table, sc.Column, v.rowID, err)
+			}
+			if changed {
+				if _, err := tx.Exec(update, encrypted, v.rowID); err != nil {
+					return nil, fmt.Errorf("更新 %s.%s 失败: %w", sc.Table, sc.Column, err)
+				}
+				report.ReEncrypted++
+			}
+			d.countSensitiveValue(col, encrypted)
+		}
+		report.Columns = append(report.Columns, col)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	log.Printf("🔐 敏感数据重新加密完成: %d 条已迁移到密钥 %s", report.ReEncrypted, report.KeyID)
+	return report, nil
+}
+
+func (d *Database) newEncryptionReport() *EncryptionReport {
+	return &EncryptionReport{
+		KeyID:          d.cryptoService.KeyID(),
+		PreviousKeyIDs: d.cryptoService.PreviousKeyIDs(),
+	}
+}
+
+// countSensitiveValue 统计单个值的加密状态
+func (d *Database) countSensitiveValue(col *EncryptionColumnReport, value string) {
+	col.Total++
+	if !d.cryptoService.IsEncryptedStorageValue(value) {
+		col.Plaintext++
+		return
+	}
+
+	keyID, err := d.cryptoService.StorageKeyID(value)
+	if keyID == "" {
+		keyID = "v1"
+	}
+	col.ByKeyID[keyID]++
+	if _, decErr := d.cryptoService.DecryptFromStorage(value); err != nil || decErr != nil {
+		col.Undecryptable++
+		return
+	}
+	if keyID == d.cryptoService.KeyID() {
+		col.Current++
+	}
+}
+
+type sensitiveValue struct {
+	rowID int64
+	value string
+}
+
+// querySensitiveValues 读取敏感字段的所有非空值
+func querySensitiveValues(q interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}, table, column string) ([]sensitiveValue, error) {
+	rows, err := q.Query(fmt.Sprintf(`SELECT rowid, %s FROM %s WHERE COALESCE(%s, '') != ''`, column, table, column))
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s.%s 失败: %w", table, column, err)
+	}
+	defer rows.Close()
+
+	var values []sensitiveValue
+	for rows.Next() {
+		var v sensitiveValue
+		if err := rows.Scan(&v.rowID, &v.value); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
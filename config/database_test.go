@@ -1,6 +1,9 @@
 package config
 
 import (
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"nofx/crypto"
 	"os"
 	"testing"
@@ -797,3 +800,672 @@ func TestConcurrentWritesWithWAL(t *testing.T) {
 		t.Errorf("并发写入失败次数过多: %d", errorCount)
 	}
 }
+
+// TestLedgerEntries_DeduplicateAndQuery 测试账本去重写入和按时间查询
+func TestLedgerEntries_DeduplicateAndQuery(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := []*LedgerEntry{
+		{EntryType: LedgerEntryTrade, ExchangeID: "1", OrderID: "100", Symbol: "BTCUSDT", Side: "BUY", PositionSide: "LONG",
+			Price: 50000, Quantity: 0.1, Fee: 2, FeeAsset: "USDT", Time: base},
+		{EntryType: LedgerEntryFunding, ExchangeID: "1", Symbol: "BTCUSDT", Amount: -0.5, Time: base.Add(8 * time.Hour)},
+		{EntryType: LedgerEntryTrade, ExchangeID: "2", OrderID: "101", Symbol: "BTCUSDT", Side: "SELL", PositionSide: "LONG",
+			Price: 51000, Quantity: 0.1, Fee: 2.04, FeeAsset: "USDT", RealizedPnL: 100, Time: base.Add(9 * time.Hour)},
+	}
+
+	inserted, err := db.SaveLedgerEntries("trader-1", entries)
+	if err != nil {
+		t.Fatalf("写入账本失败: %v", err)
+	}
+	if inserted != 3 {
+		t.Errorf("期望新增 3 条，实际 %d", inserted)
+	}
+
+	// 重叠同步：已存在的条目不重复写入（成交ID与资金费流水ID相同也不冲突）
+	inserted, err = db.SaveLedgerEntries("trader-1", entries[1:])
+	if err != nil {
+		t.Fatalf("重复写入账本失败: %v", err)
+	}
+	if inserted != 0 {
+		t.Errorf("重复写入应新增 0 条，实际 %d", inserted)
+	}
+
+	latest, err := db.GetLatestLedgerTime("trader-1")
+	if err != nil {
+		t.Fatalf("获取最新时间失败: %v", err)
+	}
+	if !latest.Equal(base.Add(9 * time.Hour)) {
+		t.Errorf("最新时间不正确: %v", latest)
+	}
+
+	got, err := db.GetLedgerEntries("trader-1", base.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("查询账本失败: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("期望 2 条记录，实际 %d", len(got))
+	}
+	if got[0].EntryType != LedgerEntryFunding || got[0].Amount != -0.5 {
+		t.Errorf("第一条应为资金费记录: %+v", got[0])
+	}
+	if got[1].RealizedPnL != 100 || got[1].PositionSide != "LONG" || !got[1].Time.Equal(base.Add(9*time.Hour)) {
+		t.Errorf("成交记录字段不正确: %+v", got[1])
+	}
+
+	// 其他交易员无记录
+	latest, err = db.GetLatestLedgerTime("trader-2")
+	if err != nil {
+		t.Fatalf("获取最新时间失败: %v", err)
+	}
+	if !latest.IsZero() {
+		t.Errorf("无记录时应返回零值，实际 %v", latest)
+	}
+}
+
+func TestCircuitBreakerState_SaveAndLoad(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	state, err := db.GetCircuitBreakerState("trader-1")
+	if err != nil {
+		t.Fatalf("查询熔断状态失败: %v", err)
+	}
+	if state != nil {
+		t.Fatalf("无记录时应返回 nil，实际 %+v", state)
+	}
+
+	dayStart := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	stopUntil := dayStart.Add(10 * time.Hour)
+	err = db.SaveCircuitBreakerState(&CircuitBreakerState{
+		TraderID:       "trader-1",
+		PeakEquity:     1200,
+		DayStartEquity: 1100,
+		DayStartTime:   dayStart,
+		StopUntil:      stopUntil,
+		Reason:         "当日亏损超过上限",
+		TrippedAt:      dayStart.Add(9 * time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("保存熔断状态失败: %v", err)
+	}
+
+	state, err = db.GetCircuitBreakerState("trader-1")
+	if err != nil || state == nil {
+		t.Fatalf("查询熔断状态失败: %v", err)
+	}
+	if state.PeakEquity != 1200 || state.DayStartEquity != 1100 || state.Reason != "当日亏损超过上限" {
+		t.Errorf("熔断状态字段不正确: %+v", state)
+	}
+	if !state.StopUntil.Equal(stopUntil) || !state.DayStartTime.Equal(dayStart) {
+		t.Errorf("熔断时间字段不正确: %+v", state)
+	}
+
+	// 解除熔断：零值时间应原样还原为零值
+	state.StopUntil = time.Time{}
+	state.Reason = ""
+	if err := db.SaveCircuitBreakerState(state); err != nil {
+		t.Fatalf("更新熔断状态失败: %v", err)
+	}
+	state, err = db.GetCircuitBreakerState("trader-1")
+	if err != nil {
+		t.Fatalf("查询熔断状态失败: %v", err)
+	}
+	if !state.StopUntil.IsZero() || state.Reason != "" || state.PeakEquity != 1200 {
+		t.Errorf("更新后的熔断状态不正确: %+v", state)
+	}
+}
+
+func TestPositionExitState_SaveAndDelete(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	openedAt := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	state := &PositionExitState{
+		TraderID:    "trader-1",
+		Symbol:      "BTCUSDT",
+		Side:        "long",
+		EntryPrice:  100000,
+		PeakPnLPct:  8.5,
+		PeakPrice:   101000,
+		InitialStop: 98000,
+		CurrentStop: 98000,
+		OpenedAt:    openedAt,
+	}
+	if err := db.SavePositionExitState(state); err != nil {
+		t.Fatalf("保存持仓退出状态失败: %v", err)
+	}
+	if err := db.SavePositionExitState(&PositionExitState{TraderID: "trader-1", Symbol: "ETHUSDT", Side: "short", EntryPrice: 3000}); err != nil {
+		t.Fatalf("保存持仓退出状态失败: %v", err)
+	}
+
+	// 移动止损后覆盖更新
+	state.CurrentStop = 100400
+	if err := db.SavePositionExitState(state); err != nil {
+		t.Fatalf("更新持仓退出状态失败: %v", err)
+	}
+
+	states, err := db.GetPositionExitStates("trader-1")
+	if err != nil {
+		t.Fatalf("查询持仓退出状态失败: %v", err)
+	}
+	if len(states) != 2 {
+		t.Fatalf("期望 2 条记录，实际 %d", len(states))
+	}
+	for _, s := range states {
+		if s.Symbol != "BTCUSDT" {
+			if !s.OpenedAt.IsZero() {
+				t.Errorf("零值开仓时间应原样还原: %+v", s)
+			}
+			continue
+		}
+		if s.CurrentStop != 100400 || s.InitialStop != 98000 || s.PeakPnLPct != 8.5 || !s.OpenedAt.Equal(openedAt) {
+			t.Errorf("持仓退出状态字段不正确: %+v", s)
+		}
+	}
+
+	if err := db.DeletePositionExitState("trader-1", "BTCUSDT", "long"); err != nil {
+		t.Fatalf("删除持仓退出状态失败: %v", err)
+	}
+	states, err = db.GetPositionExitStates("trader-1")
+	if err != nil {
+		t.Fatalf("查询持仓退出状态失败: %v", err)
+	}
+	if len(states) != 1 || states[0].Symbol != "ETHUSDT" {
+		t.Errorf("删除后剩余记录不正确: %+v", states)
+	}
+}
+
+func TestTraderState_SaveAndLoad(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	state, err := db.GetTraderState("trader-1")
+	if err != nil {
+		t.Fatalf("查询运行状态失败: %v", err)
+	}
+	if state != nil {
+		t.Fatalf("无记录时应返回 nil，实际 %+v", state)
+	}
+
+	startTime := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	err = db.SaveTraderState(&TraderState{
+		TraderID:          "trader-1",
+		CallCount:         42,
+		StartTime:         startTime,
+		DailyRealizedPnL:  -12.5,
+		LastResetTime:     startTime.Add(24 * time.Hour),
+		PositionFirstSeen: map[string]int64{"BTCUSDT_long": startTime.UnixMilli()},
+		PeakPnL:           map[string]float64{"BTCUSDT_long": 6.2},
+	})
+	if err != nil {
+		t.Fatalf("保存运行状态失败: %v", err)
+	}
+
+	// 覆盖更新
+	if err := db.SaveTraderState(&TraderState{TraderID: "trader-1", CallCount: 43, StartTime: startTime,
+		PeakPnL: map[string]float64{"BTCUSDT_long": 7}, PendingOrders: json.RawMessage(`[{"order_id":7}]`)}); err != nil {
+		t.Fatalf("更新运行状态失败: %v", err)
+	}
+
+	state, err = db.GetTraderState("trader-1")
+	if err != nil || state == nil {
+		t.Fatalf("查询运行状态失败: %v", err)
+	}
+	if state.CallCount != 43 || state.DailyRealizedPnL != 0 || !state.StartTime.Equal(startTime) || !state.LastResetTime.IsZero() {
+		t.Errorf("运行状态字段不正确: %+v", state)
+	}
+	if len(state.PositionFirstSeen) != 0 || state.PeakPnL["BTCUSDT_long"] != 7 {
+		t.Errorf("运行状态缓存不正确: %+v", state)
+	}
+	if string(state.PendingOrders) != `[{"order_id":7}]` {
+		t.Errorf("限价挂单不正确: %s", state.PendingOrders)
+	}
+}
+
+func TestWebhooks_CRUDAndTraderLookup(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	for _, tr := range []*TraderRecord{
+		{ID: "trader-a", UserID: "test-user-001", Name: "A", AIModelID: "deepseek", ExchangeID: "binance", InitialBalance: 1000},
+		{ID: "trader-b", UserID: "test-user-001", Name: "B", AIModelID: "deepseek", ExchangeID: "binance", InitialBalance: 1000},
+	} {
+		if err := db.CreateTrader(tr); err != nil {
+			t.Fatalf("创建交易员失败: %v", err)
+		}
+	}
+
+	webhooks := []*Webhook{
+		{ID: "wh-all", UserID: "test-user-001", URL: "https://example.com/all", Secret: "s1", Enabled: true},
+		{ID: "wh-a", UserID: "test-user-001", TraderID: "trader-a", URL: "https://example.com/a", Secret: "s2",
+			Events: []string{"position_opened"}, Enabled: true},
+		{ID: "wh-off", UserID: "test-user-001", URL: "https://example.com/off", Enabled: false},
+		{ID: "wh-other", UserID: "test-user-002", URL: "https://example.com/other", Enabled: true},
+	}
+	for _, w := range webhooks {
+		if err := db.CreateWebhook(w); err != nil {
+			t.Fatalf("创建Webhook失败: %v", err)
+		}
+	}
+
+	list, err := db.GetWebhooks("test-user-001")
+	if err != nil || len(list) != 3 {
+		t.Fatalf("期望用户有 3 个Webhook，实际 %d (err=%v)", len(list), err)
+	}
+
+	matched, err := db.GetWebhooksForTrader("trader-a")
+	if err != nil {
+		t.Fatalf("查询交易员Webhook失败: %v", err)
+	}
+	if len(matched) != 2 {
+		t.Fatalf("trader-a 应匹配 2 个已启用Webhook，实际 %d", len(matched))
+	}
+	matched, _ = db.GetWebhooksForTrader("trader-b")
+	if len(matched) != 1 || matched[0].ID != "wh-all" {
+		t.Fatalf("trader-b 应只匹配 wh-all，实际 %+v", matched)
+	}
+
+	w, err := db.GetWebhook("test-user-001", "wh-a")
+	if err != nil {
+		t.Fatalf("获取Webhook失败: %v", err)
+	}
+	if w.Secret != "s2" || !w.Subscribes("position_opened") || w.Subscribes("ai_failure") {
+		t.Errorf("Webhook字段不正确: %+v", w)
+	}
+
+	// 其他用户无法访问
+	if _, err := db.GetWebhook("test-user-002", "wh-a"); err != sql.ErrNoRows {
+		t.Errorf("其他用户应查询不到，实际 err=%v", err)
+	}
+
+	// 更新时密钥为空保留原密钥
+	w.URL = "https://example.com/a2"
+	w.Secret = ""
+	w.Enabled = false
+	if err := db.UpdateWebhook(w); err != nil {
+		t.Fatalf("更新Webhook失败: %v", err)
+	}
+	w, _ = db.GetWebhook("test-user-001", "wh-a")
+	if w.URL != "https://example.com/a2" || w.Secret != "s2" || w.Enabled {
+		t.Errorf("更新结果不正确: %+v", w)
+	}
+
+	if err := db.DeleteWebhook("test-user-002", "wh-all"); err != sql.ErrNoRows {
+		t.Errorf("删除其他用户的Webhook应返回 ErrNoRows，实际 %v", err)
+	}
+	if err := db.DeleteWebhook("test-user-001", "wh-all"); err != nil {
+		t.Fatalf("删除Webhook失败: %v", err)
+	}
+	if matched, _ := db.GetWebhooksForTrader("trader-a"); len(matched) != 0 {
+		t.Errorf("删除和停用后不应再匹配，实际 %d", len(matched))
+	}
+}
+
+func TestWebhookDeliveries_SaveAndList(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	for i := 1; i <= webhookDeliveryRetention+5; i++ {
+		if err := db.SaveWebhookDelivery(&WebhookDelivery{
+			WebhookID: "wh-1", DeliveryID: "d1", EventType: "position_opened", Attempt: i, StatusCode: 500,
+		}); err != nil {
+			t.Fatalf("保存投递记录失败: %v", err)
+		}
+	}
+
+	deliveries, err := db.GetWebhookDeliveries("wh-1", 1000)
+	if err != nil {
+		t.Fatalf("查询投递记录失败: %v", err)
+	}
+	if len(deliveries) != webhookDeliveryRetention {
+		t.Fatalf("应只保留最近 %d 条，实际 %d", webhookDeliveryRetention, len(deliveries))
+	}
+	if deliveries[0].Attempt != webhookDeliveryRetention+5 || deliveries[0].CreatedAt.IsZero() {
+		t.Errorf("投递记录应按时间倒序: %+v", deliveries[0])
+	}
+}
+
+func TestTelegramBindings(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if err := db.CreateTelegramBindCode(&TelegramBindCode{Code: "EXPIRED", UserID: "test-user-001",
+		ExpiresAt: time.Now().Add(-time.Minute)}); err != nil {
+		t.Fatalf("保存绑定码失败: %v", err)
+	}
+	if err := db.CreateTelegramBindCode(&TelegramBindCode{Code: "ABCD1234", UserID: "test-user-001", CanControl: true,
+		ExpiresAt: time.Now().Add(time.Minute)}); err != nil {
+		t.Fatalf("保存绑定码失败: %v", err)
+	}
+
+	if code, err := db.ConsumeTelegramBindCode("EXPIRED"); err != nil || code != nil {
+		t.Errorf("过期绑定码应无效，实际 %+v (err=%v)", code, err)
+	}
+	code, err := db.ConsumeTelegramBindCode("ABCD1234")
+	if err != nil || code == nil {
+		t.Fatalf("使用绑定码失败: %v", err)
+	}
+	if code.UserID != "test-user-001" || !code.CanControl {
+		t.Errorf("绑定码字段不正确: %+v", code)
+	}
+	if code, _ := db.ConsumeTelegramBindCode("ABCD1234"); code != nil {
+		t.Errorf("绑定码只能使用一次")
+	}
+
+	if err := db.SaveTelegramBinding(&TelegramBinding{ChatID: -100123, UserID: "test-user-001", Username: "alice"}); err != nil {
+		t.Fatalf("保存绑定失败: %v", err)
+	}
+	// 重新绑定覆盖
+	if err := db.SaveTelegramBinding(&TelegramBinding{ChatID: -100123, UserID: "test-user-001", Username: "alice", CanControl: true}); err != nil {
+		t.Fatalf("更新绑定失败: %v", err)
+	}
+
+	binding, err := db.GetTelegramBinding(-100123)
+	if err != nil || binding == nil {
+		t.Fatalf("查询绑定失败: %v", err)
+	}
+	if !binding.CanControl || binding.Username != "alice" {
+		t.Errorf("绑定字段不正确: %+v", binding)
+	}
+	if bindings, _ := db.GetTelegramBindings("test-user-001"); len(bindings) != 1 {
+		t.Errorf("期望 1 个绑定，实际 %d", len(bindings))
+	}
+
+	if err := db.DeleteTelegramBinding("test-user-002", -100123); err != sql.ErrNoRows {
+		t.Errorf("其他用户不能解除绑定，实际 err=%v", err)
+	}
+	if err := db.DeleteTelegramBinding("test-user-001", -100123); err != nil {
+		t.Fatalf("解除绑定失败: %v", err)
+	}
+	if binding, _ := db.GetTelegramBinding(-100123); binding != nil {
+		t.Errorf("解除后不应再查询到绑定")
+	}
+}
+
+// TestOrganizations_TraderAccess 测试组织成员角色、邀请和单个交易员的权限覆盖
+func TestOrganizations_TraderAccess(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	owner, operator, viewer, outsider := "test-user-001", "test-user-002", "test-user-003", "test-user-004"
+	if err := db.CreateOrganization(&Organization{ID: "org-1", Name: "Desk", OwnerID: owner}); err != nil {
+		t.Fatalf("创建组织失败: %v", err)
+	}
+	for _, tr := range []*TraderRecord{
+		{ID: "trader-org", UserID: owner, OrgID: "org-1", Name: "Org", AIModelID: "deepseek", ExchangeID: "binance", InitialBalance: 1000},
+		{ID: "trader-personal", UserID: owner, Name: "Personal", AIModelID: "deepseek", ExchangeID: "binance", InitialBalance: 1000},
+	} {
+		if err := db.CreateTrader(tr); err != nil {
+			t.Fatalf("创建交易员失败: %v", err)
+		}
+	}
+
+	// 邀请：过期、邮箱不一致、正常接受且只能使用一次
+	invitations := []*OrgInvitation{
+		{Token: "expired", OrgID: "org-1", Email: operator + "@test.com", Role: OrgRoleOperator, InvitedBy: owner, ExpiresAt: time.Now().Add(-time.Minute)},
+		{Token: "op", OrgID: "org-1", Email: "Test-User-002@test.com", Role: OrgRoleOperator, InvitedBy: owner, ExpiresAt: time.Now().Add(time.Hour)},
+		{Token: "view", OrgID: "org-1", Email: viewer + "@test.com", Role: OrgRoleViewer, InvitedBy: owner, ExpiresAt: time.Now().Add(time.Hour)},
+	}
+	for _, inv := range invitations {
+		if err := db.CreateOrgInvitation(inv); err != nil {
+			t.Fatalf("保存邀请失败: %v", err)
+		}
+	}
+	if pending, _ := db.GetOrgInvitations("org-1"); len(pending) != 2 {
+		t.Errorf("期望 2 个未过期邀请，实际 %d", len(pending))
+	}
+	if inv, err := db.AcceptOrgInvitation("expired", operator, operator+"@test.com"); err != nil || inv != nil {
+		t.Errorf("过期邀请应无效，实际 %+v (err=%v)", inv, err)
+	}
+	if _, err := db.AcceptOrgInvitation("op", outsider, outsider+"@test.com"); err != ErrInvitationEmailMismatch {
+		t.Errorf("邮箱不一致应被拒绝，实际 err=%v", err)
+	}
+	if inv, err := db.AcceptOrgInvitation("op", operator, operator+"@test.com"); err != nil || inv == nil {
+		t.Fatalf("接受邀请失败: %v", err)
+	}
+	if inv, _ := db.AcceptOrgInvitation("op", operator, operator+"@test.com"); inv != nil {
+		t.Errorf("邀请只能使用一次")
+	}
+	if _, err := db.AcceptOrgInvitation("view", viewer, viewer+"@test.com"); err != nil {
+		t.Fatalf("接受邀请失败: %v", err)
+	}
+
+	if members, _ := db.GetOrgMembers("org-1"); len(members) != 3 {
+		t.Errorf("期望 3 个成员，实际 %d", len(members))
+	}
+	if orgs, _ := db.GetUserOrganizations(viewer); len(orgs) != 1 || orgs[0].Role != OrgRoleViewer {
+		t.Errorf("观察者的组织列表不正确: %+v", orgs)
+	}
+
+	assertRole := func(userID, traderID, want string) {
+		t.Helper()
+		access, err := db.GetTraderAccess(userID, traderID)
+		if err != nil {
+			t.Fatalf("查询交易员权限失败: %v", err)
+		}
+		if access.Role != want {
+			t.Errorf("用户 %s 对 %s 的角色 = %s, want %s", userID, traderID, access.Role, want)
+		}
+		if access.OwnerID != owner {
+			t.Errorf("交易员所属账户 = %s, want %s", access.OwnerID, owner)
+		}
+	}
+	assertRole(owner, "trader-org", OrgRoleOwner)
+	assertRole(operator, "trader-org", OrgRoleOperator)
+	assertRole(viewer, "trader-org", OrgRoleViewer)
+	assertRole(outsider, "trader-org", OrgRoleNone)
+	// 个人工作区的交易员对组织成员不可见
+	assertRole(operator, "trader-personal", OrgRoleNone)
+
+	// 单个交易员的权限覆盖
+	if err := db.SetTraderPermission("trader-org", viewer, OrgRoleOperator); err != nil {
+		t.Fatalf("设置交易员权限失败: %v", err)
+	}
+	if err := db.SetTraderPermission("trader-org", operator, OrgRoleNone); err != nil {
+		t.Fatalf("设置交易员权限失败: %v", err)
+	}
+	assertRole(viewer, "trader-org", OrgRoleOperator)
+	assertRole(operator, "trader-org", OrgRoleNone)
+	if perms, _ := db.GetTraderPermissions("trader-org"); len(perms) != 2 {
+		t.Errorf("期望 2 个权限覆盖，实际 %d", len(perms))
+	}
+	if err := db.DeleteTraderPermission("trader-org", operator); err != nil {
+		t.Fatalf("删除交易员权限失败: %v", err)
+	}
+	assertRole(operator, "trader-org", OrgRoleOperator)
+
+	// 修改角色、移除成员（同时清除其权限覆盖）
+	if err := db.SetOrgMemberRole("org-1", operator, OrgRoleViewer); err != nil {
+		t.Fatalf("修改成员角色失败: %v", err)
+	}
+	assertRole(operator, "trader-org", OrgRoleViewer)
+	if err := db.RemoveOrgMember("org-1", viewer); err != nil {
+		t.Fatalf("移除成员失败: %v", err)
+	}
+	assertRole(viewer, "trader-org", OrgRoleNone)
+	if perms, _ := db.GetTraderPermissions("trader-org"); len(perms) != 0 {
+		t.Errorf("移除成员后应清除其权限覆盖，实际 %d", len(perms))
+	}
+	if err := db.RemoveOrgMember("org-1", viewer); err != sql.ErrNoRows {
+		t.Errorf("重复移除应返回 sql.ErrNoRows，实际 %v", err)
+	}
+
+	// 删除组织后交易员回到所有者的个人工作区
+	if err := db.DeleteOrganization("org-1"); err != nil {
+		t.Fatalf("删除组织失败: %v", err)
+	}
+	assertRole(operator, "trader-org", OrgRoleNone)
+	if traders, _ := db.GetTraders(owner); len(traders) != 2 || traders[0].OrgID != "" || traders[1].OrgID != "" {
+		t.Errorf("删除组织后交易员应回到个人工作区: %+v", traders)
+	}
+
+	if _, err := db.GetTraderAccess(owner, "missing"); err != sql.ErrNoRows {
+		t.Errorf("不存在的交易员应返回 sql.ErrNoRows，实际 %v", err)
+	}
+}
+
+// TestReEncryptSensitiveData_RotatesToNewKey 测试密钥轮换：过渡期新旧密钥都能解密，重新加密后只需新密钥
+func TestReEncryptSensitiveData_RotatesToNewKey(t *testing.T) {
+	dbPath := t.TempDir() + "/rotate.db"
+	rsaKeyPath := t.TempDir() + "/rsa_key"
+	oldKey, _ := crypto.GenerateDataKey()
+	newKey, _ := crypto.GenerateDataKey()
+
+	t.Setenv("DATA_ENCRYPTION_KEY", oldKey)
+	t.Setenv("DATA_ENCRYPTION_KEY_PREVIOUS", "")
+	oldService, err := crypto.NewCryptoService(rsaKeyPath)
+	if err != nil {
+		t.Fatalf("创建加密服务失败: %v", err)
+	}
+	db, err := NewDatabase(dbPath)
+	if err != nil {
+		t.Fatalf("创建数据库失败: %v", err)
+	}
+	defer db.Close()
+	db.SetCryptoService(oldService)
+	_ = db.CreateUser(&User{ID: "rotate-user", Email: "rotate@test.com", PasswordHash: "hash"})
+	if err := db.UpdateExchange("rotate-user", "binance", true, "old-api-key", "old-secret", false, "", "", "", ""); err != nil {
+		t.Fatalf("保存交易所配置失败: %v", err)
+	}
+
+	// 过渡期：新密钥为当前密钥，旧密钥仍可解密
+	t.Setenv("DATA_ENCRYPTION_KEY", newKey)
+	t.Setenv("DATA_ENCRYPTION_KEY_PREVIOUS", oldKey)
+	transition, err := crypto.NewCryptoService(rsaKeyPath)
+	if err != nil {
+		t.Fatalf("创建过渡期加密服务失败: %v", err)
+	}
+	db.SetCryptoService(transition)
+
+	report, err := db.VerifySensitiveData()
+	if err != nil {
+		t.Fatalf("检查加密状态失败: %v", err)
+	}
+	if report.Complete() || report.KeyID != transition.KeyID() || len(report.PreviousKeyIDs) != 1 {
+		t.Errorf("轮换前不应完成: %+v", report)
+	}
+
+	report, err = db.ReEncryptSensitiveData()
+	if err != nil {
+		t.Fatalf("重新加密失败: %v", err)
+	}
+	if !report.Complete() || report.ReEncrypted == 0 {
+		t.Errorf("重新加密后应全部使用新密钥: %+v", report)
+	}
+
+	// 移除旧密钥后仍能读取
+	t.Setenv("DATA_ENCRYPTION_KEY_PREVIOUS", "")
+	newService, err := crypto.NewCryptoService(rsaKeyPath)
+	if err != nil {
+		t.Fatalf("创建新加密服务失败: %v", err)
+	}
+	db.SetCryptoService(newService)
+	exchanges, err := db.GetExchanges("rotate-user")
+	if err != nil {
+		t.Fatalf("获取交易所配置失败: %v", err)
+	}
+	found := false
+	for _, ex := range exchanges {
+		if ex.ID != "binance" {
+			continue
+		}
+		found = true
+		if ex.APIKey != "old-api-key" || ex.SecretKey != "old-secret" {
+			t.Errorf("轮换后凭证不正确: %s / %s", ex.APIKey, ex.SecretKey)
+		}
+	}
+	if !found {
+		t.Error("未找到交易所配置")
+	}
+}
+
+// TestAPITokens_CreateLookupRevoke 测试 API 令牌按哈希查询、使用记录、过期和撤销
+func TestAPITokens_CreateLookupRevoke(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tokens := []*APIToken{
+		{ID: "tok-read", UserID: "test-user-001", Name: "dashboard", TokenHash: "hash-read", Prefix: "nofx_aaaa", Scope: "read",
+			ExpiresAt: time.Now().Add(time.Hour)},
+		{ID: "tok-expired", UserID: "test-user-001", Name: "old", TokenHash: "hash-expired", Scope: "trade",
+			ExpiresAt: time.Now().Add(-time.Minute)},
+		{ID: "tok-forever", UserID: "test-user-002", Name: "bot", TokenHash: "hash-forever", Scope: "admin"},
+	}
+	for _, token := range tokens {
+		if err := db.CreateAPIToken(token); err != nil {
+			t.Fatalf("保存API令牌失败: %v", err)
+		}
+	}
+
+	token, err := db.GetAPITokenByHash("hash-read")
+	if err != nil || token == nil {
+		t.Fatalf("按哈希查询失败: %v", err)
+	}
+	if token.ID != "tok-read" || token.Scope != "read" || !token.Active(time.Now()) {
+		t.Errorf("令牌字段不正确: %+v", token)
+	}
+	if token, _ := db.GetAPITokenByHash("missing"); token != nil {
+		t.Errorf("不存在的哈希应返回 nil")
+	}
+	if token, _ := db.GetAPITokenByHash("hash-expired"); token == nil || token.Active(time.Now()) {
+		t.Errorf("过期令牌不应可用: %+v", token)
+	}
+	if token, _ := db.GetAPITokenByHash("hash-forever"); token == nil || !token.ExpiresAt.IsZero() || !token.Active(time.Now()) {
+		t.Errorf("不过期的令牌应可用: %+v", token)
+	}
+
+	usedAt := time.Now().Truncate(time.Millisecond)
+	if err := db.TouchAPIToken("tok-read", "10.0.0.1", usedAt); err != nil {
+		t.Fatalf("更新使用记录失败: %v", err)
+	}
+	list, err := db.GetAPITokens("test-user-001")
+	if err != nil || len(list) != 2 {
+		t.Fatalf("期望用户有 2 个令牌，实际 %d (err=%v)", len(list), err)
+	}
+	for _, token := range list {
+		if token.ID == "tok-read" && (!token.LastUsedAt.Equal(usedAt) || token.LastUsedIP != "10.0.0.1") {
+			t.Errorf("使用记录不正确: %+v", token)
+		}
+	}
+
+	if err := db.RevokeAPIToken("test-user-002", "tok-read"); err != sql.ErrNoRows {
+		t.Errorf("其他用户不能撤销令牌，实际 err=%v", err)
+	}
+	if err := db.RevokeAPIToken("test-user-001", "tok-read"); err != nil {
+		t.Fatalf("撤销令牌失败: %v", err)
+	}
+	if token, _ := db.GetAPITokenByHash("hash-read"); token == nil || token.Active(time.Now()) {
+		t.Errorf("撤销后令牌不应可用: %+v", token)
+	}
+	if err := db.RevokeAPIToken("test-user-001", "tok-read"); err != sql.ErrNoRows {
+		t.Errorf("重复撤销应返回 sql.ErrNoRows，实际 %v", err)
+	}
+}
+
+// TestAIModels_RejectUnknownProvider 测试保存模型时拒绝未注册的 provider
+func TestAIModels_RejectUnknownProvider(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if err := db.UpdateAIModel("test-user-001", "test-user-001_antropic", true, "sk-test", "", ""); !errors.Is(err, ErrUnsupportedAIProvider) {
+		t.Errorf("未知 provider 应保存失败")
+	}
+	if err := db.CreateAIModel("test-user-001", "m-typo", "Typo", "antropic", true, "sk-test", ""); err == nil {
+		t.Errorf("未知 provider 应创建失败")
+	}
+	if err := db.UpdateAIModel("test-user-001", "test-user-001_anthropic", true, "sk-test", "", ""); err != nil {
+		t.Fatalf("已注册的 provider 应保存成功: %v", err)
+	}
+
+	models, err := db.GetAIModels("test-user-001")
+	if err != nil {
+		t.Fatalf("获取模型失败: %v", err)
+	}
+	for _, model := range models {
+		if model.Provider == "antropic" {
+			t.Errorf("不应保存未知 provider 的模型: %+v", model)
+		}
+	}
+}
@@ -21,9 +21,11 @@ import (
 )
 
 const (
-	storagePrefix    = "ENC:v1:"
-	storageDelimiter = ":"
-	dataKeyEnvName   = "DATA_ENCRYPTION_KEY"
+	storagePrefix          = "ENC:v2:" // ENC:v2:<密钥ID>:<nonce>:<密文>
+	legacyStoragePrefix    = "ENC:v1:" // ENC:v1:<nonce>:<密文>（不含密钥ID，解密时依次尝试所有密钥）
+	storageDelimiter       = ":"
+	dataKeyEnvName         = "DATA_ENCRYPTION_KEY"
+	previousDataKeyEnvName = "DATA_ENCRYPTION_KEY_PREVIOUS" // 轮换过渡期的旧密钥（逗号分隔），只用于解密
 )
 
 type EncryptedPayload struct {
@@ -43,12 +45,20 @@ type AADData struct {
 }
 
 type CryptoService struct {
-	privateKey *rsa.PrivateKey
-	publicKey  *rsa.PublicKey
-	dataKey    []byte
+	privateKey   *rsa.PrivateKey
+	publicKey    *rsa.PublicKey
+	dataKey      []byte
+	dataKeyID    string
+	previousKeys []storageKey // 轮换过渡期仍可解密的旧密钥
 }
 
+// NewCryptoService 创建加密服务（数据密钥来自环境变量 DATA_ENCRYPTION_KEY）
 func NewCryptoService(privateKeyPath string) (*CryptoService, error) {
+	return NewCryptoServiceWithProvider(privateKeyPath, &EnvKeyProvider{})
+}
+
+// NewCryptoServiceWithProvider 创建加密服务，数据密钥从指定来源加载
+func NewCryptoServiceWithProvider(privateKeyPath string, provider KeyProvider) (*CryptoService, error) {
 	// 读取私钥文件
 	privateKeyPEM, err := ioutil.ReadFile(privateKeyPath)
 	if err != nil {
@@ -68,16 +78,17 @@ func NewCryptoService(privateKeyPath string) (*CryptoService, error) {
 		return nil, fmt.Errorf("failed to parse private key: %w", err)
 	}
 
-	dataKey, err := loadDataKeyFromEnv()
+	dataKey, previousKeys, err := provider.DataKeys()
 	if err != nil {
-		return nil, fmt.Errorf("failed to load data encryption key: %w", err)
+		return nil, fmt.Errorf("failed to load data encryption key from %s: %w", provider.Name(), err)
 	}
 
-	return &CryptoService{
+	cs := &CryptoService{
 		privateKey: privateKey,
 		publicKey:  &privateKey.PublicKey,
-		dataKey:    dataKey,
-	}, nil
+	}
+	cs.setDataKeys(dataKey, previousKeys)
+	return cs, nil
 }
 
 func GenerateRSAKeyPair(privateKeyPath string) error {
@@ -155,15 +166,30 @@ func loadDataKeyFromEnv() ([]byte, error) {
 	if keyStr == "" {
 		return nil, fmt.Errorf("%s not set", dataKeyEnvName)
 	}
+	return parseDataKey(keyStr), nil
+}
+
+// loadPreviousDataKeysFromEnv 读取轮换过渡期的旧密钥（未设置时返回 nil）
+func loadPreviousDataKeysFromEnv() [][]byte {
+	var keys [][]byte
+	for _, keyStr := range strings.Split(os.Getenv(previousDataKeyEnvName), ",") {
+		if keyStr = strings.TrimSpace(keyStr); keyStr != "" {
+			keys = append(keys, parseDataKey(keyStr))
+		}
+	}
+	return keys
+}
 
+// parseDataKey 解析 base64/hex 编码的密钥，其他字符串取 SHA-256 作为密钥
+func parseDataKey(keyStr string) []byte {
 	if key, ok := decodePossibleKey(keyStr); ok {
-		return key, nil
+		return key
 	}
 
 	sum := sha256.Sum256([]byte(keyStr))
 	key := make([]byte, len(sum))
 	copy(key, sum[:])
-	return key, nil
+	return key
 }
 
 func decodePossibleKey(value string) ([]byte, bool) {
@@ -245,7 +271,7 @@ func (cs *CryptoService) EncryptForStorage(plaintext string, aadParts ...string)
 	aad := composeAAD(aadParts)
 	ciphertext := gcm.Seal(nil, nonce, []byte(plaintext), aad)
 
-	return storagePrefix +
+	return storagePrefix + cs.dataKeyID + storageDelimiter +
 		base64.StdEncoding.EncodeToString(nonce) + storageDelimiter +
 		base64.StdEncoding.EncodeToString(ciphertext), nil
 }
@@ -257,47 +283,32 @@ func (cs *CryptoService) DecryptFromStorage(value string, aadParts ...string) (s
 	if !cs.HasDataKey() {
 		return "", errors.New("data encryption key not configured")
 	}
-	if !isEncryptedStorageValue(value) {
-		return "", errors.New("value is not encrypted")
-	}
-
-	payload := strings.TrimPrefix(value, storagePrefix)
-	parts := strings.SplitN(payload, storageDelimiter, 2)
-	if len(parts) != 2 {
-		return "", errors.New("invalid encrypted payload format")
-	}
 
-	nonce, err := base64.StdEncoding.DecodeString(parts[0])
-	if err != nil {
-		return "", fmt.Errorf("decode nonce failed: %w", err)
-	}
-
-	ciphertext, err := base64.StdEncoding.DecodeString(parts[1])
-	if err != nil {
-		return "", fmt.Errorf("decode ciphertext failed: %w", err)
-	}
-
-	block, err := aes.NewCipher(cs.dataKey)
-	if err != nil {
-		return "", err
-	}
-
-	gcm, err := cipher.NewGCM(block)
+	envelope, err := parseStorageEnvelope(value)
 	if err != nil {
 		return "", err
 	}
 
-	if len(nonce) != gcm.NonceSize() {
-		return "", fmt.Errorf("invalid nonce size: expected %d, got %d", gcm.NonceSize(), len(nonce))
+	// v2 按密钥ID选择密钥；v1 没有密钥ID，依次尝试当前密钥和过渡期旧密钥
+	candidates := cs.allKeys()
+	if envelope.keyID != "" {
+		key, ok := cs.keyByID(envelope.keyID)
+		if !ok {
+			return "", fmt.Errorf("unknown data key id: %s", envelope.keyID)
+		}
+		candidates = []storageKey{key}
 	}
 
 	aad := composeAAD(aadParts)
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, aad)
-	if err != nil {
-		return "", fmt.Errorf("decryption failed: %w", err)
+	var lastErr error
+	for _, key := range candidates {
+		plaintext, err := openStorageValue(key.key, envelope, aad)
+		if err == nil {
+			return string(plaintext), nil
+		}
+		lastErr = err
 	}
-
-	return string(plaintext), nil
+	return "", fmt.Errorf("decryption failed: %w", lastErr)
 }
 
 func (cs *CryptoService) IsEncryptedStorageValue(value string) bool {
@@ -312,7 +323,7 @@ func composeAAD(parts []string) []byte {
 }
 
 func isEncryptedStorageValue(value string) bool {
-	return strings.HasPrefix(value, storagePrefix)
+	return strings.HasPrefix(value, storagePrefix) || strings.HasPrefix(value, legacyStoragePrefix)
 }
 
 func (cs *CryptoService) DecryptPayload(payload *EncryptedPayload) ([]byte, error) {
@@ -333,41 +333,3 @@ func (em *EncryptionManager) DecryptFromDatabase(encryptedBase64 string) (string
 
 	return string(plaintext), nil
 }
-
-// ==================== 密鑰輪換 ====================
-
-// RotateMasterKey 輪換主密鑰（需要重新加密所有數據）
-func (em *EncryptionManager) RotateMasterKey() error {
-	em.mu.Lock()
-	defer em.mu.Unlock()
-
-	log.Println("🔄 開始輪換主密鑰...")
-
-	// 生成新主密鑰
-	newMasterKey := make([]byte, 32)
-	if _, err := io.ReadFull(rand.Reader, newMasterKey); err != nil {
-		return err
-	}
-
-	// 備份舊密鑰
-	oldMasterKey := em.masterKey
-
-	// 更新密鑰
-	em.masterKey = newMasterKey
-
-	// 保存新密鑰
-	encoded := base64.StdEncoding.EncodeToString(newMasterKey)
-	backupFile := fmt.Sprintf("%s.backup.%d", masterKeyFile, os.Getpid())
-	if err := os.WriteFile(backupFile, []byte(base64.StdEncoding.EncodeToString(oldMasterKey)), 0600); err != nil {
-		return err
-	}
-	if err := os.WriteFile(masterKeyFile, []byte(encoded), 0600); err != nil {
-		return err
-	}
-
-	log.Println("✅ 主密鑰已輪換")
-	log.Printf("⚠️ 舊密鑰已備份到: %s", backupFile)
-	log.Printf("🔐 新主密鑰: %s", encoded)
-
-	return nil
-}
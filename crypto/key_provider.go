@@ -0,0 +1,285 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// 数据加密密钥来源类型（config.json 中 data_key.provider）
+const (
+	KeyProviderEnv      = "env"      // 环境变量 DATA_ENCRYPTION_KEY（默认）
+	KeyProviderFile     = "file"     // 本地密钥文件（如挂载的 Kubernetes Secret）
+	KeyProviderVault    = "vault"    // Vault transit 包装的数据密钥
+	KeyProviderEnvelope = "envelope" // 本地主密钥（KEK）包装的数据密钥，KMS 风格
+)
+
+const (
+	defaultRSAKeyFile     = "secrets/rsa_key"
+	defaultDataKeyFile    = "secrets/data_key"
+	defaultWrappedKeyFile = "secrets/data_key.wrapped"
+	defaultKEKFile        = "secrets/kek"
+)
+
+// KeyProvider 数据加密密钥来源
+type KeyProvider interface {
+	// Name 来源名称（用于日志）
+	Name() string
+	// DataKeys 返回当前密钥和轮换过渡期的旧密钥
+	DataKeys() (current []byte, previous [][]byte, err error)
+}
+
+// KeyWrapper 包装/解包数据密钥的主密钥服务（信封加密中只存储包装后的数据密钥）
+type KeyWrapper interface {
+	Name() string
+	WrapKey(key []byte) (string, error)
+	UnwrapKey(wrapped string) ([]byte, error)
+}
+
+// KeyProviderConfig 数据加密密钥来源配置（config.json 中的 data_key）
+type KeyProviderConfig struct {
+	Provider                string       `json:"provider"`                   // env / file / vault / envelope，为空使用 env
+	RSAKeyFile              string       `json:"rsa_key_file"`               // RSA 私钥路径，默认 secrets/rsa_key
+	KeyFile                 string       `json:"key_file"`                   // file: 数据密钥文件，默认 secrets/data_key
+	PreviousKeyFiles        []string     `json:"previous_key_files"`         // file: 轮换过渡期的旧密钥文件
+	WrappedKeyFile          string       `json:"wrapped_key_file"`           // vault/envelope: 包装后的数据密钥文件，默认 secrets/data_key.wrapped
+	PreviousWrappedKeyFiles []string     `json:"previous_wrapped_key_files"` // vault/envelope: 轮换过渡期的旧包装密钥文件
+	KEKFile                 string       `json:"kek_file"`                   // envelope: 主密钥文件，默认 secrets/kek
+	Vault                   *VaultConfig `json:"vault,omitempty"`
+}
+
+// RSAKeyPath RSA 私钥路径
+func (c KeyProviderConfig) RSAKeyPath() string {
+	if c.RSAKeyFile != "" {
+		return c.RSAKeyFile
+	}
+	return defaultRSAKeyFile
+}
+
+// NewKeyProvider 根据配置创建密钥来源
+func NewKeyProvider(cfg KeyProviderConfig) (KeyProvider, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Provider)) {
+	case "", KeyProviderEnv:
+		return &EnvKeyProvider{}, nil
+	case KeyProviderFile:
+		path := cfg.KeyFile
+		if path == "" {
+			path = defaultDataKeyFile
+		}
+		return &FileKeyProvider{Path: path, PreviousPaths: cfg.PreviousKeyFiles}, nil
+	case KeyProviderVault:
+		wrapper, err := NewVaultTransitWrapper(cfg.Vault)
+		if err != nil {
+			return nil, err
+		}
+		return newEnvelopeKeyProvider(cfg, wrapper), nil
+	case KeyProviderEnvelope:
+		path := cfg.KEKFile
+		if path == "" {
+			path = defaultKEKFile
+		}
+		return newEnvelopeKeyProvider(cfg, &LocalKEKWrapper{Path: path}), nil
+	default:
+		return nil, fmt.Errorf("不支持的密钥来源: %s（可选: env, file, vault, envelope）", cfg.Provider)
+	}
+}
+
+func newEnvelopeKeyProvider(cfg KeyProviderConfig, wrapper KeyWrapper) *EnvelopeKeyProvider {
+	path := cfg.WrappedKeyFile
+	if path == "" {
+		path = defaultWrappedKeyFile
+	}
+	return &EnvelopeKeyProvider{Wrapper: wrapper, WrappedKeyPath: path, PreviousWrappedKeyPaths: cfg.PreviousWrappedKeyFiles}
+}
+
+// EnvKeyProvider 从环境变量读取数据密钥（DATA_ENCRYPTION_KEY / DATA_ENCRYPTION_KEY_PREVIOUS）
+type EnvKeyProvider struct{}
+
+func (p *EnvKeyProvider) Name() string { return KeyProviderEnv }
+
+func (p *EnvKeyProvider) DataKeys() ([]byte, [][]byte, error) {
+	current, err := loadDataKeyFromEnv()
+	if err != nil {
+		return nil, nil, err
+	}
+	return current, loadPreviousDataKeysFromEnv(), nil
+}
+
+// FileKeyProvider 从本地文件读取数据密钥（base64/hex 编码）
+type FileKeyProvider struct {
+	Path          string
+	PreviousPaths []string
+}
+
+func (p *FileKeyProvider) Name() string { return KeyProviderFile + ":" + p.Path }
+
+func (p *FileKeyProvider) DataKeys() ([]byte, [][]byte, error) {
+	current, err := readKeyFile(p.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var previous [][]byte
+	for _, path := range p.PreviousPaths {
+		key, err := readKeyFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		previous = append(previous, key)
+	}
+	return current, previous, nil
+}
+
+// readKeyFile 读取密钥文件，文件权限对其他用户可读时给出警告
+func readKeyFile(path string) ([]byte, error) {
+	content, err := readSecretFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := decodePossibleKey(content)
+	if !ok {
+		return nil, fmt.Errorf("密钥文件 %s 不是有效的 base64/hex AES 密钥", path)
+	}
+	return key, nil
+}
+
+func readSecretFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("读取密钥文件失败: %w", err)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		log.Printf("⚠️  密钥文件 %s 权限过宽 (%v)，建议 chmod 600", path, info.Mode().Perm())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("读取密钥文件失败: %w", err)
+	}
+	content := strings.TrimSpace(string(data))
+	if content == "" {
+		return "", fmt.Errorf("密钥文件为空: %s", path)
+	}
+	return content, nil
+}
+
+// EnvelopeKeyProvider 信封加密：磁盘上只保存被主密钥包装的数据密钥，启动时解包
+type EnvelopeKeyProvider struct {
+	Wrapper                 KeyWrapper
+	WrappedKeyPath          string
+	PreviousWrappedKeyPaths []string
+}
+
+func (p *EnvelopeKeyProvider) Name() string { return p.Wrapper.Name() + ":" + p.WrappedKeyPath }
+
+func (p *EnvelopeKeyProvider) DataKeys() ([]byte, [][]byte, error) {
+	current, err := p.unwrapFile(p.WrappedKeyPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	var previous [][]byte
+	for _, path := range p.PreviousWrappedKeyPaths {
+		key, err := p.unwrapFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		previous = append(previous, key)
+	}
+	return current, previous, nil
+}
+
+func (p *EnvelopeKeyProvider) unwrapFile(path string) ([]byte, error) {
+	wrapped, err := readSecretFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := p.Wrapper.UnwrapKey(wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("解包数据密钥 %s 失败: %w", path, err)
+	}
+	if key, ok := normalizeAESKey(key); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("解包后的数据密钥长度无效: %s", path)
+}
+
+// GenerateWrappedKey 生成新的数据密钥并返回包装后的内容（明文密钥不落盘）
+func (p *EnvelopeKeyProvider) GenerateWrappedKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return p.Wrapper.WrapKey(key)
+}
+
+// localKEKPrefix 本地主密钥包装的数据密钥格式: nofx-kek:v1:<主密钥ID>:<base64(nonce|密文)>
+const localKEKPrefix = "nofx-kek:v1:"
+
+// localKEKAAD 包装数据密钥时的附加认证数据
+var localKEKAAD = []byte("nofx-data-key")
+
+// LocalKEKWrapper 使用本地主密钥文件包装数据密钥（主密钥应与数据库分开存放，如只读挂载卷）
+type LocalKEKWrapper struct {
+	Path string
+}
+
+func (w *LocalKEKWrapper) Name() string { return KeyProviderEnvelope }
+
+func (w *LocalKEKWrapper) gcm() (cipher.AEAD, string, error) {
+	kek, err := readKeyFile(w.Path)
+	if err != nil {
+		return nil, "", err
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, "", err
+	}
+	return gcm, DataKeyID(kek), nil
+}
+
+func (w *LocalKEKWrapper) WrapKey(key []byte) (string, error) {
+	gcm, kekID, err := w.gcm()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, key, localKEKAAD)
+	return localKEKPrefix + kekID + storageDelimiter + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (w *LocalKEKWrapper) UnwrapKey(wrapped string) ([]byte, error) {
+	if !strings.HasPrefix(wrapped, localKEKPrefix) {
+		return nil, errors.New("不是本地主密钥包装的数据密钥")
+	}
+	parts := strings.SplitN(strings.TrimPrefix(wrapped, localKEKPrefix), storageDelimiter, 2)
+	if len(parts) != 2 {
+		return nil, errors.New("包装密钥格式无效")
+	}
+
+	gcm, kekID, err := w.gcm()
+	if err != nil {
+		return nil, err
+	}
+	if parts[0] != kekID {
+		return nil, fmt.Errorf("主密钥不匹配: 数据密钥由 %s 包装，当前主密钥为 %s", parts[0], kekID)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("decode wrapped key failed: %w", err)
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("包装密钥长度无效")
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], localKEKAAD)
+}
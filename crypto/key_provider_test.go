@@ -0,0 +1,179 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestFile 写入 0600 权限的测试文件
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content+"\n"), 0600); err != nil {
+		t.Fatalf("写入 %s 失败: %v", path, err)
+	}
+}
+
+// TestFileKeyProvider 测试从密钥文件加载当前密钥和旧密钥
+func TestFileKeyProvider(t *testing.T) {
+	dir := t.TempDir()
+	current, _ := GenerateDataKey()
+	previous, _ := GenerateDataKey()
+	writeTestFile(t, filepath.Join(dir, "data_key"), current)
+	writeTestFile(t, filepath.Join(dir, "data_key.old"), previous)
+
+	provider, err := NewKeyProvider(KeyProviderConfig{
+		Provider:         "file",
+		KeyFile:          filepath.Join(dir, "data_key"),
+		PreviousKeyFiles: []string{filepath.Join(dir, "data_key.old")},
+	})
+	if err != nil {
+		t.Fatalf("创建密钥来源失败: %v", err)
+	}
+	key, previousKeys, err := provider.DataKeys()
+	if err != nil {
+		t.Fatalf("加载密钥失败: %v", err)
+	}
+	if base64.StdEncoding.EncodeToString(key) != current || len(previousKeys) != 1 ||
+		base64.StdEncoding.EncodeToString(previousKeys[0]) != previous {
+		t.Error("密钥文件内容与加载结果不一致")
+	}
+
+	writeTestFile(t, filepath.Join(dir, "bad_key"), "not a key")
+	if _, _, err := (&FileKeyProvider{Path: filepath.Join(dir, "bad_key")}).DataKeys(); err == nil {
+		t.Error("无效的密钥文件应报错")
+	}
+}
+
+// TestEnvelopeKeyProvider_LocalKEK 测试本地主密钥包装的数据密钥可用于存储加解密
+func TestEnvelopeKeyProvider_LocalKEK(t *testing.T) {
+	dir := t.TempDir()
+	kek, _ := GenerateDataKey()
+	writeTestFile(t, filepath.Join(dir, "kek"), kek)
+
+	cfg := KeyProviderConfig{
+		Provider:       "envelope",
+		RSAKeyFile:     filepath.Join(dir, "rsa_key"),
+		WrappedKeyFile: filepath.Join(dir, "data_key.wrapped"),
+		KEKFile:        filepath.Join(dir, "kek"),
+	}
+	provider, err := NewKeyProvider(cfg)
+	if err != nil {
+		t.Fatalf("创建密钥来源失败: %v", err)
+	}
+	wrapped, err := provider.(*EnvelopeKeyProvider).GenerateWrappedKey()
+	if err != nil {
+		t.Fatalf("生成包装密钥失败: %v", err)
+	}
+	if !strings.HasPrefix(wrapped, localKEKPrefix) {
+		t.Fatalf("包装密钥格式不正确: %s", wrapped)
+	}
+	writeTestFile(t, cfg.WrappedKeyFile, wrapped)
+
+	cs, err := NewCryptoServiceWithProvider(cfg.RSAKeyPath(), provider)
+	if err != nil {
+		t.Fatalf("创建加密服务失败: %v", err)
+	}
+	encrypted, err := cs.EncryptForStorage("secret-value")
+	if err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+	if decrypted, err := cs.DecryptFromStorage(encrypted); err != nil || decrypted != "secret-value" {
+		t.Errorf("解密结果不正确: %q (err=%v)", decrypted, err)
+	}
+
+	// 更换主密钥后无法解包
+	otherKEK, _ := GenerateDataKey()
+	writeTestFile(t, cfg.KEKFile, otherKEK)
+	if _, _, err := provider.DataKeys(); err == nil || !strings.Contains(err.Error(), "主密钥不匹配") {
+		t.Errorf("主密钥不匹配时应报错，实际 %v", err)
+	}
+}
+
+// newVaultTransitStub 模拟 Vault transit encrypt/decrypt 接口（密文为 vault:v1:<base64(明文)> 反转）
+func newVaultTransitStub(t *testing.T, token string) *httptest.Server {
+	t.Helper()
+	reverse := func(s string) string {
+		b := []byte(s)
+		for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+			b[i], b[j] = b[j], b[i]
+		}
+		return string(b)
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != token {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		switch r.URL.Path {
+		case "/v1/transit/encrypt/nofx":
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]string{"ciphertext": "vault:v1:" + reverse(body["plaintext"])}})
+		case "/v1/transit/decrypt/nofx":
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]string{"plaintext": reverse(strings.TrimPrefix(body["ciphertext"], "vault:v1:"))}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":["unsupported path"]}`))
+		}
+	}))
+}
+
+// TestVaultTransitWrapper 测试通过 Vault transit 接口包装/解包数据密钥
+func TestVaultTransitWrapper(t *testing.T) {
+	server := newVaultTransitStub(t, "test-token")
+	defer server.Close()
+
+	dir := t.TempDir()
+	cfg := KeyProviderConfig{
+		Provider:       "vault",
+		WrappedKeyFile: filepath.Join(dir, "data_key.wrapped"),
+		Vault:          &VaultConfig{Address: server.URL, Token: "test-token", KeyName: "nofx"},
+	}
+	provider, err := NewKeyProvider(cfg)
+	if err != nil {
+		t.Fatalf("创建密钥来源失败: %v", err)
+	}
+	wrapped, err := provider.(*EnvelopeKeyProvider).GenerateWrappedKey()
+	if err != nil {
+		t.Fatalf("包装数据密钥失败: %v", err)
+	}
+	writeTestFile(t, cfg.WrappedKeyFile, wrapped)
+
+	key, _, err := provider.DataKeys()
+	if err != nil || len(key) != 32 {
+		t.Fatalf("解包数据密钥失败: %v", err)
+	}
+	if again, _, _ := provider.DataKeys(); !bytes.Equal(key, again) {
+		t.Error("同一包装密钥应解包出相同的数据密钥")
+	}
+
+	cfg.Vault.Token = "wrong-token"
+	provider, _ = NewKeyProvider(cfg)
+	if _, _, err := provider.DataKeys(); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("token 无效时应返回 Vault 错误，实际 %v", err)
+	}
+}
+
+// TestNewKeyProvider_InvalidConfig 测试无效配置
+func TestNewKeyProvider_InvalidConfig(t *testing.T) {
+	t.Setenv("VAULT_ADDR", "")
+	t.Setenv("VAULT_TOKEN", "")
+	for _, cfg := range []KeyProviderConfig{
+		{Provider: "kms-unknown"},
+		{Provider: "vault"},
+		{Provider: "vault", Vault: &VaultConfig{KeyName: "nofx"}},
+		{Provider: "vault", Vault: &VaultConfig{Address: "http://127.0.0.1:8200", KeyName: "nofx"}},
+	} {
+		if _, err := NewKeyProvider(cfg); err == nil {
+			t.Errorf("配置 %+v 应报错", cfg)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// storageKey 带密钥ID的数据加密密钥
+type storageKey struct {
+	id  string
+	key []byte
+}
+
+// storageEnvelope 解析后的存储密文
+type storageEnvelope struct {
+	keyID      string // v1 密文为空
+	nonce      []byte
+	ciphertext []byte
+}
+
+// DataKeyID 计算密钥ID（密钥 SHA-256 的前 8 位十六进制，不泄露密钥本身）
+func DataKeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+// GenerateDataKey 生成新的 AES-256 数据加密密钥（base64 编码，可直接用作 DATA_ENCRYPTION_KEY）
+func GenerateDataKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// setDataKeys 设置当前密钥和过渡期旧密钥（与当前密钥相同的旧密钥会被忽略）
+func (cs *CryptoService) setDataKeys(current []byte, previous [][]byte) {
+	cs.dataKey = current
+	cs.dataKeyID = DataKeyID(current)
+	cs.previousKeys = nil
+	for _, key := range previous {
+		id := DataKeyID(key)
+		if _, exists := cs.keyByID(id); exists {
+			continue
+		}
+		cs.previousKeys = append(cs.previousKeys, storageKey{id: id, key: key})
+	}
+}
+
+// KeyID 当前数据加密密钥的ID
+func (cs *CryptoService) KeyID() string {
+	return cs.dataKeyID
+}
+
+// PreviousKeyIDs 过渡期旧密钥的ID
+func (cs *CryptoService) PreviousKeyIDs() []string {
+	ids := make([]string, 0, len(cs.previousKeys))
+	for _, key := range cs.previousKeys {
+		ids = append(ids, key.id)
+	}
+	return ids
+}
+
+func (cs *CryptoService) keyByID(id string) (storageKey, bool) {
+	for _, key := range cs.allKeys() {
+		if key.id == id {
+			return key, true
+		}
+	}
+	return storageKey{}, false
+}
+
+// allKeys 当前密钥在前，之后是过渡期旧密钥
+func (cs *CryptoService) allKeys() []storageKey {
+	if len(cs.dataKey) == 0 {
+		return cs.previousKeys
+	}
+	return append([]storageKey{{id: cs.dataKeyID, key: cs.dataKey}}, cs.previousKeys...)
+}
+
+// StorageKeyID 返回存储密文使用的密钥ID（v1 密文返回空字符串）
+func (cs *CryptoService) StorageKeyID(value string) (string, error) {
+	envelope, err := parseStorageEnvelope(value)
+	if err != nil {
+		return "", err
+	}
+	return envelope.keyID, nil
+}
+
+// NeedsReEncryption 值不是用当前密钥加密的（明文、v1 密文或旧密钥密文）
+func (cs *CryptoService) NeedsReEncryption(value string) bool {
+	if value == "" {
+		return false
+	}
+	keyID, err := cs.StorageKeyID(value)
+	return err != nil || keyID != cs.dataKeyID
+}
+
+// ReEncryptForStorage 用当前密钥重新加密存储值（明文直接加密），返回值是否发生变化
+func (cs *CryptoService) ReEncryptForStorage(value string, aadParts ...string) (string, bool, error) {
+	if !cs.NeedsReEncryption(value) {
+		return value, false, nil
+	}
+
+	plaintext := value
+	if isEncryptedStorageValue(value) {
+		decrypted, err := cs.DecryptFromStorage(value, aadParts...)
+		if err != nil {
+			return "", false, err
+		}
+		plaintext = decrypted
+	}
+
+	encrypted, err := cs.EncryptForStorage(plaintext, aadParts...)
+	if err != nil {
+		return "", false, err
+	}
+	return encrypted, true, nil
+}
+
+// parseStorageEnvelope 解析 v1/v2 存储密文
+func parseStorageEnvelope(value string) (*storageEnvelope, error) {
+	var envelope storageEnvelope
+	var parts []string
+	switch {
+	case strings.HasPrefix(value, storagePrefix):
+		parts = strings.SplitN(strings.TrimPrefix(value, storagePrefix), storageDelimiter, 3)
+		if len(parts) != 3 || parts[0] == "" {
+			return nil, errors.New("invalid encrypted payload format")
+		}
+		envelope.keyID = parts[0]
+		parts = parts[1:]
+	case strings.HasPrefix(value, legacyStoragePrefix):
+		parts = strings.SplitN(strings.TrimPrefix(value, legacyStoragePrefix), storageDelimiter, 2)
+		if len(parts) != 2 {
+			return nil, errors.New("invalid encrypted payload format")
+		}
+	default:
+		return nil, errors.New("value is not encrypted")
+	}
+
+	var err error
+	if envelope.nonce, err = base64.StdEncoding.DecodeString(parts[0]); err != nil {
+		return nil, fmt.Errorf("decode nonce failed: %w", err)
+	}
+	if envelope.ciphertext, err = base64.StdEncoding.DecodeString(parts[1]); err != nil {
+		return nil, fmt.Errorf("decode ciphertext failed: %w", err)
+	}
+	return &envelope, nil
+}
+
+// openStorageValue 用指定密钥解密存储密文
+func openStorageValue(key []byte, envelope *storageEnvelope, aad []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(envelope.nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce size: expected %d, got %d", gcm.NonceSize(), len(envelope.nonce))
+	}
+	return gcm.Open(nil, envelope.nonce, envelope.ciphertext, aad)
+}
@@ -0,0 +1,166 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const vaultRequestTimeout = 10 * time.Second
+
+// VaultConfig Vault transit 配置（兼容 HashiCorp Vault / OpenBao 的 transit 接口）
+type VaultConfig struct {
+	Address   string `json:"address"`    // 为空使用 VAULT_ADDR
+	Token     string `json:"token"`      // 为空依次使用 token_file、VAULT_TOKEN
+	TokenFile string `json:"token_file"` // 如 Vault Agent 写出的 token 文件
+	Namespace string `json:"namespace"`  // Vault Enterprise 命名空间（可选）
+	Mount     string `json:"mount"`      // transit 挂载路径，默认 transit
+	KeyName   string `json:"key_name"`   // transit 密钥名
+}
+
+// VaultTransitWrapper 使用 Vault transit 的 encrypt/decrypt 接口包装数据密钥（主密钥不离开 Vault）
+type VaultTransitWrapper struct {
+	address   string
+	token     string
+	namespace string
+	mount     string
+	keyName   string
+	client    *http.Client
+}
+
+// NewVaultTransitWrapper 创建 Vault transit 包装器
+func NewVaultTransitWrapper(cfg *VaultConfig) (*VaultTransitWrapper, error) {
+	if cfg == nil {
+		return nil, errors.New("vault 密钥来源缺少 vault 配置")
+	}
+
+	address := cfg.Address
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	if address == "" {
+		return nil, errors.New("未配置 Vault 地址（vault.address 或 VAULT_ADDR）")
+	}
+	if cfg.KeyName == "" {
+		return nil, errors.New("未配置 Vault transit 密钥名（vault.key_name）")
+	}
+
+	token := cfg.Token
+	if token == "" && cfg.TokenFile != "" {
+		content, err := readSecretFile(cfg.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+		token = content
+	}
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	if token == "" {
+		return nil, errors.New("未配置 Vault token（vault.token、vault.token_file 或 VAULT_TOKEN）")
+	}
+
+	mount := strings.Trim(cfg.Mount, "/")
+	if mount == "" {
+		mount = "transit"
+	}
+
+	return &VaultTransitWrapper{
+		address:   strings.TrimRight(address, "/"),
+		token:     token,
+		namespace: cfg.Namespace,
+		mount:     mount,
+		keyName:   cfg.KeyName,
+		client:    &http.Client{Timeout: vaultRequestTimeout},
+	}, nil
+}
+
+func (w *VaultTransitWrapper) Name() string {
+	return KeyProviderVault + ":" + w.mount + "/" + w.keyName
+}
+
+// WrapKey POST /v1/<mount>/encrypt/<key>，返回 vault:vN:... 密文
+func (w *VaultTransitWrapper) WrapKey(key []byte) (string, error) {
+	var resp struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	body := map[string]string{"plaintext": base64.StdEncoding.EncodeToString(key)}
+	if err := w.call("encrypt", body, &resp); err != nil {
+		return "", err
+	}
+	if resp.Data.Ciphertext == "" {
+		return "", errors.New("vault 未返回密文")
+	}
+	return resp.Data.Ciphertext, nil
+}
+
+// UnwrapKey POST /v1/<mount>/decrypt/<key>，返回数据密钥明文
+func (w *VaultTransitWrapper) UnwrapKey(wrapped string) ([]byte, error) {
+	if !strings.HasPrefix(wrapped, "vault:") {
+		return nil, errors.New("不是 Vault transit 密文")
+	}
+	var resp struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	if err := w.call("decrypt", map[string]string{"ciphertext": wrapped}, &resp); err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("decode vault plaintext failed: %w", err)
+	}
+	return key, nil
+}
+
+// call 调用 transit 接口，非 2xx 时返回 Vault 的 errors 信息
+func (w *VaultTransitWrapper) call(operation string, body any, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/v1/%s/%s/%s", w.address, w.mount, operation, url.PathEscape(w.keyName))
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Vault-Token", w.token)
+	if w.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", w.namespace)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求 Vault 失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("读取 Vault 响应失败: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var vaultErr struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.Unmarshal(data, &vaultErr)
+		return fmt.Errorf("vault transit %s 失败 (HTTP %d): %s", operation, resp.StatusCode, strings.Join(vaultErr.Errors, "; "))
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("解析 Vault 响应失败: %w", err)
+	}
+	return nil
+}
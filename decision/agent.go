@@ -0,0 +1,350 @@
+package decision
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"nofx/market"
+	"nofx/mcp"
+	"strings"
+	"time"
+)
+
+const (
+	maxAgentCandidates    = 50   // Agent 模式只发送概览，可覆盖更多候选币
+	maxAgentToolResultLen = 6000 // 单次工具结果最大字符数（避免撑爆上下文）
+
+	agentFinalTurnPrompt = "数据查询预算已用完，请根据已有信息立即给出最终决策（在正文写出思维链，并调用决策函数）。"
+	agentMixedCallResult = "决策函数需在数据查询结束后单独提交，本次调用已忽略。"
+)
+
+// AgentConfig Agent 模式的单周期预算
+type AgentConfig struct {
+	MaxTurns  int // 每个决策周期最多调用AI的轮数（含最终决策轮）
+	MaxTokens int // 每个决策周期累计 token 上限（0 表示不限制）
+}
+
+// DefaultAgentConfig 默认预算：最多5轮，累计6万 token
+func DefaultAgentConfig() AgentConfig {
+	return AgentConfig{MaxTurns: 5, MaxTokens: 60000}
+}
+
+// AgentTool 决策过程中可供模型调用的数据查询工具
+type AgentTool struct {
+	Tool    mcp.Tool
+	Handler func(args map[string]any) (string, error)
+}
+
+// NewAgentTool 创建数据查询工具
+func NewAgentTool(name, description string, properties map[string]any, required []string, handler func(args map[string]any) (string, error)) AgentTool {
+	return AgentTool{
+		Tool:    decisionTool(name, description, properties, required),
+		Handler: handler,
+	}
+}
+
+// DefaultAgentTools 基于实时行情的数据查询工具
+func DefaultAgentTools() []AgentTool {
+	return MarketAgentTools(market.DataConfig{})
+}
+
+// MarketAgentTools 基于实时行情的数据查询工具（get_market_data 按配置的周期和指标输出）
+func MarketAgentTools(cfg market.DataConfig) []AgentTool {
+	symbol := schemaProperty("string", "交易对，如 BTCUSDT")
+	return []AgentTool{
+		NewAgentTool("get_market_data", fmt.Sprintf("获取币种完整技术指标（%s、持仓量、资金费率）", cfg.Describe()),
+			map[string]any{"symbol": symbol}, []string{"symbol"}, marketDataTool(cfg)),
+		NewAgentTool("get_klines", "获取K线（OHLCV）",
+			map[string]any{
+				"symbol":   symbol,
+				"interval": map[string]any{"type": "string", "enum": []string{"1m", "3m", "5m", "15m", "1h", "4h", "1d"}},
+				"limit":    schemaProperty("integer", "K线数量（默认30，最多100）"),
+			}, []string{"symbol", "interval"}, getKlinesTool),
+		NewAgentTool("get_orderbook", "获取订单簿深度（买卖盘挂单及失衡度）",
+			map[string]any{
+				"symbol": symbol,
+				"limit":  schemaProperty("integer", "档位数（默认20，最多100）"),
+			}, []string{"symbol"}, getOrderBookTool),
+		NewAgentTool("get_funding_history", "获取历史资金费率",
+			map[string]any{
+				"symbol": symbol,
+				"limit":  schemaProperty("integer", "结算次数（默认10，最多50）"),
+			}, []string{"symbol"}, getFundingHistoryTool),
+	}
+}
+
+// agentResult Agent 多轮调用结果
+type agentResult struct {
+	Final      *mcp.Response // 最终一轮的响应（只保留决策函数调用）
+	Transcript []mcp.Message // system/user prompt 之后的全部对话
+	Turns      int
+	Tokens     int
+}
+
+// runAgentLoop 多轮调用：模型可先调用数据查询工具，最后通过决策函数提交决策
+// 达到轮数或 token 预算后，最后一轮只提供决策函数
+func runAgentLoop(caller mcp.ToolCaller, cfg AgentConfig, tools []AgentTool, systemPrompt, userPrompt string) (*agentResult, error) {
+	maxTurns := cfg.MaxTurns
+	if maxTurns <= 0 {
+		maxTurns = DefaultAgentConfig().MaxTurns
+	}
+
+	handlers := make(map[string]AgentTool, len(tools))
+	requestTools := DecisionTools()
+	for _, tool := range tools {
+		handlers[tool.Tool.Function.Name] = tool
+		requestTools = append(requestTools, tool.Tool)
+	}
+
+	messages := []mcp.Message{mcp.NewSystemMessage(systemPrompt), mcp.NewUserMessage(userPrompt)}
+	result := &agentResult{}
+	for turn := 1; ; turn++ {
+		final := turn >= maxTurns || (cfg.MaxTokens > 0 && result.Tokens >= cfg.MaxTokens)
+		req := &mcp.Request{Messages: messages, Tools: requestTools, ToolChoice: "auto"}
+		if final {
+			if turn > 1 {
+				messages = append(messages, mcp.NewUserMessage(agentFinalTurnPrompt))
+				req.Messages = messages
+			}
+			req.Tools = DecisionTools()
+		}
+
+		resp, err := caller.CallWithTools(req)
+		if err != nil {
+			result.Transcript = messages[2:]
+			return result, fmt.Errorf("第%d轮调用AI失败: %w", turn, err)
+		}
+		result.Turns = turn
+		result.Tokens += resp.Usage.TotalTokens
+		messages = append(messages, mcp.NewAssistantToolCallMessage(resp.Content, resp.ToolCalls))
+
+		lookups := 0
+		decisionCalls := make([]mcp.ToolCall, 0, len(resp.ToolCalls))
+		for _, call := range resp.ToolCalls {
+			if _, ok := handlers[call.Function.Name]; ok {
+				lookups++
+			} else {
+				decisionCalls = append(decisionCalls, call)
+			}
+		}
+		if lookups == 0 || final {
+			resp.ToolCalls = decisionCalls
+			result.Final = resp
+			result.Transcript = messages[2:]
+			return result, nil
+		}
+
+		// 执行数据查询，结果作为 tool 消息回传
+		log.Printf("🔎 Agent 第%d轮: 查询 %d 项数据（累计 %d tokens）", turn, lookups, result.Tokens)
+		for _, call := range resp.ToolCalls {
+			content := agentMixedCallResult
+			if tool, ok := handlers[call.Function.Name]; ok {
+				content = executeAgentTool(tool, call)
+			}
+			messages = append(messages, mcp.NewToolMessage(call.ID, content))
+		}
+	}
+}
+
+// executeAgentTool 执行数据查询工具（错误作为结果返回给模型，不中断决策）
+func executeAgentTool(tool AgentTool, call mcp.ToolCall) string {
+	args := make(map[string]any)
+	if raw := strings.TrimSpace(call.Function.Arguments); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &args); err != nil {
+			return fmt.Sprintf("参数解析失败: %v", err)
+		}
+	}
+	output, err := tool.Handler(args)
+	if err != nil {
+		log.Printf("⚠️  Agent 工具 %s 执行失败: %v", call.Function.Name, err)
+		return fmt.Sprintf("查询失败: %v", err)
+	}
+	// 按字符截断，避免切断多字节字符产生无效 UTF-8
+	if runes := []rune(output); len(runes) > maxAgentToolResultLen {
+		output = string(runes[:maxAgentToolResultLen]) + "\n...(已截断)"
+	}
+	return output
+}
+
+// ArgString 读取字符串参数
+func ArgString(args map[string]any, key string) string {
+	value, _ := args[key].(string)
+	return strings.TrimSpace(value)
+}
+
+// ArgInt 读取整数参数，缺失时使用默认值，并限制在 [1, max]
+func ArgInt(args map[string]any, key string, def, max int) int {
+	value := def
+	if v, ok := args[key].(float64); ok && v > 0 {
+		value = int(v)
+	}
+	if value > max {
+		value = max
+	}
+	if value < 1 {
+		value = 1
+	}
+	return value
+}
+
+func marketDataTool(cfg market.DataConfig) func(args map[string]any) (string, error) {
+	return func(args map[string]any) (string, error) {
+		symbol := market.Normalize(ArgString(args, "symbol"))
+		data, err := market.GetWithConfig(symbol, cfg)
+		if err != nil {
+			return "", err
+		}
+		return market.Format(data), nil
+	}
+}
+
+func getKlinesTool(args map[string]any) (string, error) {
+	symbol := market.Normalize(ArgString(args, "symbol"))
+	interval := ArgString(args, "interval")
+	if interval == "" {
+		interval = "1h"
+	}
+	klines, err := market.NewAPIClient().GetKlines(symbol, interval, ArgInt(args, "limit", 30, 100))
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s %s K线（时间 开 高 低 收 成交量）:\n", symbol, interval))
+	for _, k := range klines {
+		sb.WriteString(fmt.Sprintf("%s %g %g %g %g %.2f\n",
+			time.UnixMilli(k.OpenTime).UTC().Format("01-02 15:04"), k.Open, k.High, k.Low, k.Close, k.Volume))
+	}
+	return sb.String(), nil
+}
+
+func getOrderBookTool(args map[string]any) (string, error) {
+	symbol := market.Normalize(ArgString(args, "symbol"))
+	book, err := market.NewAPIClient().GetOrderBook(symbol, orderBookDepthLimit(ArgInt(args, "limit", 20, 100)))
+	if err != nil {
+		return "", err
+	}
+
+	var bidQty, askQty float64
+	for _, level := range book.Bids {
+		bidQty += level.Quantity
+	}
+	for _, level := range book.Asks {
+		askQty += level.Quantity
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s 订单簿 (%d档)\n", symbol, len(book.Bids)))
+	if len(book.Bids) > 0 && len(book.Asks) > 0 {
+		bestBid, bestAsk := book.Bids[0].Price, book.Asks[0].Price
+		sb.WriteString(fmt.Sprintf("买一 %g | 卖一 %g | 价差 %.4f%%\n", bestBid, bestAsk, (bestAsk-bestBid)/bestBid*100))
+	}
+	if bidQty+askQty > 0 {
+		sb.WriteString(fmt.Sprintf("买盘总量 %.2f | 卖盘总量 %.2f | 失衡度 %+.2f\n", bidQty, askQty, (bidQty-askQty)/(bidQty+askQty)))
+	}
+	sb.WriteString("卖盘（价格 数量）:\n")
+	for i := len(book.Asks) - 1; i >= 0; i-- {
+		sb.WriteString(fmt.Sprintf("  %g %g\n", book.Asks[i].Price, book.Asks[i].Quantity))
+	}
+	sb.WriteString("买盘（价格 数量）:\n")
+	for _, level := range book.Bids {
+		sb.WriteString(fmt.Sprintf("  %g %g\n", level.Price, level.Quantity))
+	}
+	return sb.String(), nil
+}
+
+// orderBookDepthLimit 将档位数调整为交易所支持的取值
+func orderBookDepthLimit(n int) int {
+	for _, limit := range []int{5, 10, 20, 50, 100} {
+		if n <= limit {
+			return limit
+		}
+	}
+	return 100
+}
+
+func getFundingHistoryTool(args map[string]any) (string, error) {
+	symbol := market.Normalize(ArgString(args, "symbol"))
+	records, err := market.NewAPIClient().GetFundingRateHistory(symbol, ArgInt(args, "limit", 10, 50))
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s 资金费率历史（结算时间 费率）:\n", symbol))
+	var sum float64
+	for _, r := range records {
+		sum += r.FundingRate
+		sb.WriteString(fmt.Sprintf("%s %.4f%%\n", time.UnixMilli(r.FundingTime).UTC().Format("01-02 15:04"), r.FundingRate*100))
+	}
+	if len(records) > 0 {
+		sb.WriteString(fmt.Sprintf("平均 %.4f%%\n", sum/float64(len(records))*100))
+	}
+	return sb.String(), nil
+}
+
+// buildAgentUserPrompt 构建 Agent 模式的 User Prompt（紧凑概览，详细数据由模型按需查询）
+func buildAgentUserPrompt(ctx *Context) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("时间: %s | 周期: #%d | 运行: %d分钟\n\n",
+		ctx.CurrentTime, ctx.CallCount, ctx.RuntimeMinutes))
+
+	equity := ctx.Account.TotalEquity
+	availablePct := 0.0
+	if equity > 0 {
+		availablePct = ctx.Account.AvailableBalance / equity * 100
+	}
+	sb.WriteString(fmt.Sprintf("账户: 净值%.2f | 余额%.2f (%.1f%%) | 盈亏%+.2f%% | 日盈亏%+.2f | 保证金%.1f%% | 持仓%d个\n\n",
+		equity, ctx.Account.AvailableBalance, availablePct, ctx.Account.TotalPnLPct,
+		ctx.Account.DailyPnL, ctx.Account.MarginUsedPct, ctx.Account.PositionCount))
+
+	if len(ctx.Positions) > 0 {
+		sb.WriteString("## 当前持仓\n")
+		for i, pos := range ctx.Positions {
+			sb.WriteString(fmt.Sprintf("%d. %s %s | 入场价%.4f 当前价%.4f | 仓位价值%.2f USDT | 盈亏%+.2f%% | 最高收益率%.2f%% | 杠杆%dx | 强平价%.4f\n",
+				i+1, pos.Symbol, strings.ToUpper(pos.Side), pos.EntryPrice, pos.MarkPrice,
+				math.Abs(pos.Quantity)*pos.MarkPrice, pos.UnrealizedPnLPct, pos.PeakPnLPct, pos.Leverage, pos.LiquidationPrice))
+		}
+		sb.WriteString("\n")
+	} else {
+		sb.WriteString("当前持仓: 无\n\n")
+	}
+
+	writePendingOrders(&sb, ctx.PendingOrders)
+
+	sb.WriteString(fmt.Sprintf("## 市场概览 (%d个)\n", len(ctx.MarketDataMap)))
+	sb.WriteString("币种 | 价格 | 1h | 4h | RSI7 | MACD | 资金费率 | 持仓量(M USD)\n")
+	seen := make(map[string]bool)
+	writeLine := func(symbol, tag string) {
+		data, ok := ctx.MarketDataMap[symbol]
+		if !ok || seen[symbol] {
+			return
+		}
+		seen[symbol] = true
+		oiValue := 0.0
+		if data.OpenInterest != nil {
+			oiValue = data.OpenInterest.Latest * data.CurrentPrice / 1_000_000
+		}
+		sb.WriteString(fmt.Sprintf("%s%s | %g | %+.2f%% | %+.2f%% | %.1f | %.4f | %.4f%% | %.1f\n",
+			symbol, tag, data.CurrentPrice, data.PriceChange1h, data.PriceChange4h,
+			data.CurrentRSI7, data.CurrentMACD, data.FundingRate*100, oiValue))
+	}
+	for _, pos := range ctx.Positions {
+		writeLine(pos.Symbol, " [持仓]")
+	}
+	for _, coin := range ctx.CandidateCoins {
+		tag := ""
+		if len(coin.Sources) > 1 {
+			tag = " [AI500+OI_Top]"
+		} else if len(coin.Sources) == 1 && coin.Sources[0] == "oi_top" {
+			tag = " [OI_Top]"
+		}
+		writeLine(coin.Symbol, tag)
+	}
+	sb.WriteString("\n---\n\n")
+	sb.WriteString("请先按需查询详细数据，再输出决策（思维链 + 决策函数调用）\n")
+
+	return sb.String()
+}
@@ -0,0 +1,172 @@
+package decision
+
+import (
+	"fmt"
+	"nofx/mcp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// scriptedCaller 按顺序返回预设响应的 ToolCaller
+type scriptedCaller struct {
+	responses []*mcp.Response
+	requests  []*mcp.Request
+}
+
+func (s *scriptedCaller) CallWithTools(req *mcp.Request) (*mcp.Response, error) {
+	// 复制消息，避免后续 append 影响已记录的请求
+	copied := *req
+	copied.Messages = append([]mcp.Message(nil), req.Messages...)
+	s.requests = append(s.requests, &copied)
+	if len(s.responses) == 0 {
+		return nil, fmt.Errorf("no more responses")
+	}
+	resp := s.responses[0]
+	s.responses = s.responses[1:]
+	return resp, nil
+}
+
+func lookupCall(id, name, args string) mcp.ToolCall {
+	return mcp.ToolCall{ID: id, Type: "function", Function: mcp.FunctionCall{Name: name, Arguments: args}}
+}
+
+func testAgentTools(calls *[]string) []AgentTool {
+	return []AgentTool{
+		NewAgentTool("get_klines", "K线", map[string]any{"symbol": schemaProperty("string", "")}, []string{"symbol"},
+			func(args map[string]any) (string, error) {
+				*calls = append(*calls, ArgString(args, "symbol"))
+				return "klines of " + ArgString(args, "symbol"), nil
+			}),
+	}
+}
+
+func TestRunAgentLoop_LookupThenDecide(t *testing.T) {
+	var calls []string
+	caller := &scriptedCaller{responses: []*mcp.Response{
+		{ToolCalls: []mcp.ToolCall{lookupCall("c1", "get_klines", `{"symbol":"BTCUSDT"}`)}, Usage: mcp.Usage{TotalTokens: 100}},
+		{Content: "BTC 趋势向上", ToolCalls: []mcp.ToolCall{lookupCall("c2", "wait", `{"symbol":"ALL","reasoning":"观望"}`)}, Usage: mcp.Usage{TotalTokens: 50}},
+	}}
+
+	result, err := runAgentLoop(caller, AgentConfig{MaxTurns: 5}, testAgentTools(&calls), "system", "user")
+	if err != nil {
+		t.Fatalf("Agent 循环失败: %v", err)
+	}
+	if result.Turns != 2 || result.Tokens != 150 {
+		t.Errorf("期望 2 轮 150 tokens，实际 %d 轮 %d tokens", result.Turns, result.Tokens)
+	}
+	if len(calls) != 1 || calls[0] != "BTCUSDT" {
+		t.Errorf("工具调用错误: %v", calls)
+	}
+
+	// 第二轮请求应包含工具结果
+	second := caller.requests[1].Messages
+	last := second[len(second)-1]
+	if last.Role != "tool" || last.ToolCallID != "c1" || last.Content != "klines of BTCUSDT" {
+		t.Errorf("工具结果未回传: %+v", last)
+	}
+
+	// 对话记录：assistant(查询) → tool → assistant(决策)
+	if len(result.Transcript) != 3 {
+		t.Fatalf("期望 3 条对话记录，实际 %d", len(result.Transcript))
+	}
+	if len(result.Final.ToolCalls) != 1 || result.Final.ToolCalls[0].Function.Name != "wait" {
+		t.Errorf("最终决策错误: %+v", result.Final.ToolCalls)
+	}
+}
+
+func TestRunAgentLoop_BudgetForcesFinalTurn(t *testing.T) {
+	var calls []string
+	lookup := &mcp.Response{ToolCalls: []mcp.ToolCall{lookupCall("c1", "get_klines", `{"symbol":"ETHUSDT"}`)}, Usage: mcp.Usage{TotalTokens: 10}}
+	caller := &scriptedCaller{responses: []*mcp.Response{
+		lookup,
+		lookup,
+		{ToolCalls: []mcp.ToolCall{
+			lookupCall("c3", "get_klines", `{"symbol":"ETHUSDT"}`),
+			lookupCall("c4", "hold", `{"symbol":"ETHUSDT","reasoning":"持有"}`),
+		}},
+	}}
+
+	result, err := runAgentLoop(caller, AgentConfig{MaxTurns: 3}, testAgentTools(&calls), "system", "user")
+	if err != nil {
+		t.Fatalf("Agent 循环失败: %v", err)
+	}
+	if result.Turns != 3 || len(calls) != 2 {
+		t.Errorf("期望 3 轮、2 次查询，实际 %d 轮、%d 次", result.Turns, len(calls))
+	}
+
+	// 最后一轮只提供决策函数，并提示预算用完
+	final := caller.requests[2]
+	if len(final.Tools) != len(DecisionTools()) {
+		t.Errorf("最后一轮应只提供决策函数，实际 %d 个", len(final.Tools))
+	}
+	if msg := final.Messages[len(final.Messages)-1]; msg.Role != "user" || !strings.Contains(msg.Content, "预算已用完") {
+		t.Errorf("最后一轮缺少预算提示: %+v", msg)
+	}
+	// 最后一轮的查询调用被丢弃
+	if len(result.Final.ToolCalls) != 1 || result.Final.ToolCalls[0].Function.Name != "hold" {
+		t.Errorf("最终决策错误: %+v", result.Final.ToolCalls)
+	}
+}
+
+func TestRunAgentLoop_TokenBudget(t *testing.T) {
+	var calls []string
+	caller := &scriptedCaller{responses: []*mcp.Response{
+		{ToolCalls: []mcp.ToolCall{lookupCall("c1", "get_klines", `{"symbol":"BTCUSDT"}`)}, Usage: mcp.Usage{TotalTokens: 5000}},
+		{Content: "<reasoning>够了</reasoning>", ToolCalls: []mcp.ToolCall{lookupCall("c2", "wait", `{"symbol":"ALL","reasoning":"观望"}`)}},
+	}}
+
+	result, err := runAgentLoop(caller, AgentConfig{MaxTurns: 10, MaxTokens: 1000}, testAgentTools(&calls), "system", "user")
+	if err != nil {
+		t.Fatalf("Agent 循环失败: %v", err)
+	}
+	if result.Turns != 2 {
+		t.Errorf("token 超限后应立即进入最终轮，实际 %d 轮", result.Turns)
+	}
+	if len(caller.requests[1].Tools) != len(DecisionTools()) {
+		t.Errorf("token 超限后应只提供决策函数")
+	}
+}
+
+func TestExecuteAgentTool_Errors(t *testing.T) {
+	tool := NewAgentTool("get_klines", "K线", map[string]any{}, []string{}, func(args map[string]any) (string, error) {
+		return "", fmt.Errorf("boom")
+	})
+
+	if got := executeAgentTool(tool, lookupCall("c1", "get_klines", `{bad`)); !strings.Contains(got, "参数解析失败") {
+		t.Errorf("参数错误应返回给模型: %q", got)
+	}
+	if got := executeAgentTool(tool, lookupCall("c1", "get_klines", `{}`)); !strings.Contains(got, "boom") {
+		t.Errorf("工具错误应返回给模型: %q", got)
+	}
+}
+
+func TestExecuteAgentTool_TruncatesOnRuneBoundary(t *testing.T) {
+	output := strings.Repeat("价格→", maxAgentToolResultLen)
+	tool := NewAgentTool("get_klines", "K线", map[string]any{}, []string{}, func(args map[string]any) (string, error) {
+		return output, nil
+	})
+
+	got := executeAgentTool(tool, lookupCall("c1", "get_klines", `{}`))
+	if !utf8.ValidString(got) {
+		t.Fatal("截断后的工具结果应为有效 UTF-8")
+	}
+	if !strings.HasSuffix(got, "...(已截断)") {
+		t.Errorf("超长结果应标记截断: %q", got[len(got)-20:])
+	}
+	if n := utf8.RuneCountInString(strings.TrimSuffix(got, "\n...(已截断)")); n != maxAgentToolResultLen {
+		t.Errorf("应保留 %d 个字符，实际 %d", maxAgentToolResultLen, n)
+	}
+}
+
+func TestBuildAgentUserPrompt_Compact(t *testing.T) {
+	ctx := &Context{
+		CurrentTime:    "2025-01-01 00:00:00",
+		Account:        AccountInfo{TotalEquity: 1000, AvailableBalance: 800},
+		CandidateCoins: []CandidateCoin{{Symbol: "SOLUSDT", Sources: []string{"ai500"}}},
+	}
+	prompt := buildAgentUserPrompt(ctx)
+	if !strings.Contains(prompt, "市场概览 (0个)") || !strings.Contains(prompt, "当前持仓: 无") {
+		t.Errorf("概览格式错误:\n%s", prompt)
+	}
+}
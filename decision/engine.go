@@ -81,11 +81,55 @@ type Context struct {
 	Account         AccountInfo             `json:"account"`
 	Positions       []PositionInfo          `json:"positions"`
 	CandidateCoins  []CandidateCoin         `json:"candidate_coins"`
-	MarketDataMap   map[string]*market.Data `json:"-"` // 不序列化，但内部使用
+	MarketDataMap   map[string]*market.Data `json:"-"` // 不序列化，但内部使用（为空时自动获取实时数据）
 	OITopDataMap    map[string]*OITopData   `json:"-"` // OI Top数据映射
 	Performance     interface{}             `json:"-"` // 历史表现分析（logger.PerformanceAnalysis）
+	ManualActions   []ManualAction          `json:"-"` // 上个周期之后用户手动执行的操作
+	PendingOrders   []PendingOrder          `json:"-"` // 限价开仓挂单（含上个周期之后结束的挂单）
 	BTCETHLeverage  int                     `json:"-"` // BTC/ETH杠杆倍数（从配置读取）
 	AltcoinLeverage int                     `json:"-"` // 山寨币杠杆倍数（从配置读取）
+	DecisionMode    string                  `json:"-"` // 决策输出模式: text / tool_call / agent（为空使用文本模式）
+	MarketData      market.DataConfig       `json:"-"` // 行情数据的K线周期和指标（为空使用默认 3m/4h 数据）
+
+	// Agent 模式：多轮数据查询的预算与可用工具（工具为空时使用 DefaultAgentTools）
+	Agent      AgentConfig `json:"-"`
+	AgentTools []AgentTool `json:"-"`
+}
+
+// PendingOrder 限价开仓挂单（在提示词中告知AI，避免重复开仓）
+type PendingOrder struct {
+	Symbol     string
+	Side       string // long / short
+	EntryType  string // limit / post_only
+	LimitPrice float64
+	Quantity   float64
+	FilledQty  float64
+	Status     string // 状态描述（如 挂单中、已成交、超时已撤销）
+	ExpiresAt  time.Time
+}
+
+// ManualAction 用户通过 API 手动执行的操作（在下一周期的提示词中告知AI）
+type ManualAction struct {
+	Time     time.Time
+	Symbol   string
+	Action   string
+	Price    float64
+	Quantity float64
+	Success  bool
+	Error    string
+	Note     string // 用户备注
+}
+
+// 开仓入场方式
+const (
+	EntryTypeMarket   = "market"    // 市价开仓（默认）
+	EntryTypeLimit    = "limit"     // 限价挂单，可能立即成交
+	EntryTypePostOnly = "post_only" // 只做 Maker，立即成交时被拒绝（手续费最低）
+)
+
+// IsLimitEntry 是否为限价入场（limit / post_only）
+func (d *Decision) IsLimitEntry() bool {
+	return d.EntryType == EntryTypeLimit || d.EntryType == EntryTypePostOnly
 }
 
 // Decision AI的交易决策
@@ -98,6 +142,8 @@ type Decision struct {
 	PositionSizeUSD float64 `json:"position_size_usd,omitempty"`
 	StopLoss        float64 `json:"stop_loss,omitempty"`
 	TakeProfit      float64 `json:"take_profit,omitempty"`
+	EntryType       string  `json:"entry_type,omitempty"`  // 入场方式: market（默认）, limit, post_only
+	LimitPrice      float64 `json:"limit_price,omitempty"` // limit / post_only 的挂单价
 
 	// 调整参数（新增）
 	NewStopLoss     float64 `json:"new_stop_loss,omitempty"`    // 用于 update_stop_loss
@@ -119,6 +165,11 @@ type FullDecision struct {
 	Timestamp    time.Time  `json:"timestamp"`
 	// AIRequestDurationMs 记录 AI API 调用耗时（毫秒）方便排查延迟问题
 	AIRequestDurationMs int64 `json:"ai_request_duration_ms,omitempty"`
+
+	// Agent 模式的多轮对话记录与消耗
+	Transcript []mcp.Message `json:"transcript,omitempty"`
+	AgentTurns int           `json:"agent_turns,omitempty"`
+	TokensUsed int           `json:"tokens_used,omitempty"`
 }
 
 // GetFullDecision 获取AI的完整交易决策（批量分析所有币种和持仓）
@@ -128,25 +179,79 @@ func GetFullDecision(ctx *Context, mcpClient mcp.AIClient) (*FullDecision, error
 
 // GetFullDecisionWithCustomPrompt 获取AI的完整交易决策（支持自定义prompt和模板选择）
 func GetFullDecisionWithCustomPrompt(ctx *Context, mcpClient mcp.AIClient, customPrompt string, overrideBase bool, templateName string) (*FullDecision, error) {
-	// 1. 为所有币种获取市场数据
-	if err := fetchMarketDataForContext(ctx); err != nil {
-		return nil, fmt.Errorf("获取市场数据失败: %w", err)
+	// 1. 为所有币种获取市场数据（调用方已提供时跳过，例如回测使用历史数据重建）
+	if ctx.MarketDataMap == nil {
+		if err := fetchMarketDataForContext(ctx); err != nil {
+			return nil, fmt.Errorf("获取市场数据失败: %w", err)
+		}
 	}
 
-	// 2. 构建 System Prompt（固定规则）和 User Prompt（动态数据）
-	systemPrompt := buildSystemPromptWithCustom(ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage, customPrompt, overrideBase, templateName)
+	// 2. 确定决策模式（客户端不支持 Function Calling 时退回文本模式）
+	mode := ctx.DecisionMode
+	toolCaller, supportsTools := mcpClient.(mcp.ToolCaller)
+	if (mode == DecisionModeToolCall || mode == DecisionModeAgent) && !supportsTools {
+		log.Printf("⚠️  AI客户端不支持 Function Calling，使用文本决策模式")
+		mode = DecisionModeText
+	}
+
+	// 3. 构建 System Prompt（固定规则）和 User Prompt（动态数据）
+	systemPrompt := buildSystemPromptWithCustom(ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage, customPrompt, overrideBase, templateName, mode)
 	userPrompt := buildUserPrompt(ctx)
+	if mode == DecisionModeAgent {
+		userPrompt = buildAgentUserPrompt(ctx)
+	}
+
+	// 4. 调用AI API并解析响应
+	var decision *FullDecision
+	var aiCallDuration time.Duration
+	if mode == DecisionModeAgent {
+		tools := ctx.AgentTools
+		if len(tools) == 0 {
+			tools = DefaultAgentTools()
+		}
+		aiCallStart := time.Now()
+		result, err := runAgentLoop(toolCaller, ctx.Agent, tools, systemPrompt, userPrompt)
+		aiCallDuration = time.Since(aiCallStart)
+		if err != nil {
+			return nil, fmt.Errorf("调用AI API失败: %w", err)
+		}
+		log.Printf("🔎 Agent 决策完成: %d轮, %d tokens", result.Turns, result.Tokens)
+		decision, err = ParseToolCallResponse(result.Final, ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage)
+		if decision != nil {
+			decision.Transcript = result.Transcript
+			decision.AgentTurns = result.Turns
+			decision.TokensUsed = result.Tokens
+		}
+		return finishDecision(decision, err, systemPrompt, userPrompt, aiCallDuration)
+	}
+	if mode == DecisionModeToolCall {
+		request, err := buildToolCallRequest(systemPrompt, userPrompt)
+		if err != nil {
+			return nil, fmt.Errorf("构建AI请求失败: %w", err)
+		}
+		aiCallStart := time.Now()
+		resp, err := toolCaller.CallWithTools(request)
+		aiCallDuration = time.Since(aiCallStart)
+		if err != nil {
+			return nil, fmt.Errorf("调用AI API失败: %w", err)
+		}
+		decision, err = ParseToolCallResponse(resp, ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage)
+		return finishDecision(decision, err, systemPrompt, userPrompt, aiCallDuration)
+	}
 
-	// 3. 调用AI API（使用 system + user prompt）
 	aiCallStart := time.Now()
 	aiResponse, err := mcpClient.CallWithMessages(systemPrompt, userPrompt)
-	aiCallDuration := time.Since(aiCallStart)
+	aiCallDuration = time.Since(aiCallStart)
 	if err != nil {
 		return nil, fmt.Errorf("调用AI API失败: %w", err)
 	}
 
-	// 4. 解析AI响应
-	decision, err := parseFullDecisionResponse(aiResponse, ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage)
+	decision, err = ParseFullDecisionResponse(aiResponse, ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage)
+	return finishDecision(decision, err, systemPrompt, userPrompt, aiCallDuration)
+}
+
+// finishDecision 补充决策的 prompt、时间戳和耗时
+func finishDecision(decision *FullDecision, err error, systemPrompt, userPrompt string, aiCallDuration time.Duration) (*FullDecision, error) {
 
 	// 无论是否有错误，都要保存 SystemPrompt 和 UserPrompt（用于调试和决策未执行后的问题定位）
 	if decision != nil {
@@ -181,6 +286,10 @@ func fetchMarketDataForContext(ctx *Context) error {
 
 	// 2. 候选币种数量根据账户状态动态调整
 	maxCandidates := calculateMaxCandidates(ctx)
+	if ctx.DecisionMode == DecisionModeAgent {
+		// Agent 模式只发送紧凑概览，详细数据由模型按需查询
+		maxCandidates = min(len(ctx.CandidateCoins), maxAgentCandidates)
+	}
 	for i, coin := range ctx.CandidateCoins {
 		if i >= maxCandidates {
 			break
@@ -196,7 +305,7 @@ func fetchMarketDataForContext(ctx *Context) error {
 	}
 
 	for symbol := range symbolSet {
-		data, err := market.Get(symbol)
+		data, err := market.GetWithConfig(symbol, ctx.MarketData)
 		if err != nil {
 			// 单个币种失败不影响整体，只记录错误
 			continue
@@ -273,14 +382,14 @@ func calculateMaxCandidates(ctx *Context) int {
 }
 
 // buildSystemPromptWithCustom 构建包含自定义内容的 System Prompt
-func buildSystemPromptWithCustom(accountEquity float64, btcEthLeverage, altcoinLeverage int, customPrompt string, overrideBase bool, templateName string) string {
+func buildSystemPromptWithCustom(accountEquity float64, btcEthLeverage, altcoinLeverage int, customPrompt string, overrideBase bool, templateName, mode string) string {
 	// 如果覆盖基础prompt且有自定义prompt，只使用自定义prompt
 	if overrideBase && customPrompt != "" {
 		return customPrompt
 	}
 
 	// 获取基础prompt（使用指定的模板）
-	basePrompt := buildSystemPrompt(accountEquity, btcEthLeverage, altcoinLeverage, templateName)
+	basePrompt := buildSystemPrompt(accountEquity, btcEthLeverage, altcoinLeverage, templateName, mode)
 
 	// 如果没有自定义prompt，直接返回基础prompt
 	if customPrompt == "" {
@@ -299,8 +408,8 @@ func buildSystemPromptWithCustom(accountEquity float64, btcEthLeverage, altcoinL
 	return sb.String()
 }
 
-// buildSystemPrompt 构建 System Prompt（使用模板+动态部分）
-func buildSystemPrompt(accountEquity float64, btcEthLeverage, altcoinLeverage int, templateName string) string {
+// buildSystemPrompt 构建 System Prompt（使用模板+动态部分），mode 决定输出格式说明
+func buildSystemPrompt(accountEquity float64, btcEthLeverage, altcoinLeverage int, templateName, mode string) string {
 	var sb strings.Builder
 
 	// 1. 加载提示词模板（核心交易策略部分）
@@ -339,6 +448,28 @@ func buildSystemPrompt(accountEquity float64, btcEthLeverage, altcoinLeverage in
 
 	// 3. 输出格式 - 动态生成
 	sb.WriteString("# 输出格式 (严格遵守)\n\n")
+	if mode == DecisionModeAgent {
+		sb.WriteString("用户消息只包含账户、持仓和市场概览。你可以先调用数据查询函数获取需要的详细数据（可多轮，但有次数和 token 预算），")
+		sb.WriteString("信息足够后，在回复正文中简洁写出思维链分析，并**为每个决策调用一次对应的决策函数**（函数名即 action）\n\n")
+		sb.WriteString("- 数据查询函数: 以 get_ 开头的函数（如 get_klines、get_orderbook，参数见函数定义）\n")
+		sb.WriteString("- 决策函数: open_long | open_short | close_long | close_short | update_stop_loss | update_take_profit | partial_close | hold | wait\n")
+		sb.WriteString("- 只查询真正影响决策的数据，不要在同一轮同时调用查询函数和决策函数\n")
+		sb.WriteString("- 无需任何操作时调用 wait，symbol 填 ALL\n")
+		sb.WriteString("- confidence: 0-100（开仓建议≥75）\n")
+		sb.WriteString("- 开仓时必填: leverage, position_size_usd, stop_loss, take_profit, confidence, risk_usd, reasoning\n")
+		sb.WriteString("- 开仓可选: entry_type (market | limit | post_only，默认 market), limit_price（限价，需在止损和止盈之间）。post_only 只做 Maker、手续费最低，未在超时前成交会被撤销或转为市价\n\n")
+		return sb.String()
+	}
+	if mode == DecisionModeToolCall {
+		sb.WriteString("先在回复正文中简洁写出你的思维链分析，然后**为每个决策调用一次对应的函数**（函数名即 action）\n\n")
+		sb.WriteString("- 可用函数: open_long | open_short | close_long | close_short | update_stop_loss | update_take_profit | partial_close | hold | wait\n")
+		sb.WriteString("- 不要在正文中输出JSON决策\n")
+		sb.WriteString("- 无需任何操作时调用 wait，symbol 填 ALL\n")
+		sb.WriteString("- confidence: 0-100（开仓建议≥75）\n")
+		sb.WriteString("- 开仓时必填: leverage, position_size_usd, stop_loss, take_profit, confidence, risk_usd, reasoning\n")
+		sb.WriteString("- 开仓可选: entry_type (market | limit | post_only，默认 market), limit_price（限价，需在止损和止盈之间）。post_only 只做 Maker、手续费最低，未在超时前成交会被撤销或转为市价\n\n")
+		return sb.String()
+	}
 	sb.WriteString("**必须使用XML标签 <reasoning> 和 <decision> 标签分隔思维链和决策JSON，避免解析错误**\n\n")
 	sb.WriteString("## 格式要求\n\n")
 	sb.WriteString("<reasoning>\n")
@@ -356,6 +487,7 @@ func buildSystemPrompt(accountEquity float64, btcEthLeverage, altcoinLeverage in
 	sb.WriteString("- `action`: open_long | open_short | close_long | close_short | update_stop_loss | update_take_profit | partial_close | hold | wait\n")
 	sb.WriteString("- `confidence`: 0-100（开仓建议≥75）\n")
 	sb.WriteString("- 开仓时必填: leverage, position_size_usd, stop_loss, take_profit, confidence, risk_usd, reasoning\n")
+	sb.WriteString("- 开仓可选: entry_type (market | limit | post_only，默认 market), limit_price（限价，需在止损和止盈之间）。post_only 只做 Maker、手续费最低，未在超时前成交会被撤销或转为市价\n")
 	sb.WriteString("- update_stop_loss 时必填: new_stop_loss (注意是 new_stop_loss，不是 stop_loss)\n")
 	sb.WriteString("- update_take_profit 时必填: new_take_profit (注意是 new_take_profit，不是 take_profit)\n")
 	sb.WriteString("- partial_close 时必填: close_percentage (0-100)\n\n")
@@ -363,6 +495,26 @@ func buildSystemPrompt(accountEquity float64, btcEthLeverage, altcoinLeverage in
 	return sb.String()
 }
 
+// writePendingOrders 输出限价开仓挂单（挂单中的订单占用保证金，成交后按决策设置止损止盈）
+func writePendingOrders(sb *strings.Builder, orders []PendingOrder) {
+	if len(orders) == 0 {
+		return
+	}
+	sb.WriteString("## 限价开仓单（挂单中的币种不要重复开仓，成交后自动设置止损止盈）\n")
+	for _, order := range orders {
+		sb.WriteString(fmt.Sprintf("- %s %s %s 限价 %.4f | 数量 %.4f", order.Symbol, strings.ToUpper(order.Side), order.EntryType, order.LimitPrice, order.Quantity))
+		if order.FilledQty > 0 {
+			sb.WriteString(fmt.Sprintf(" 已成交 %.4f", order.FilledQty))
+		}
+		sb.WriteString(fmt.Sprintf(" | %s", order.Status))
+		if !order.ExpiresAt.IsZero() {
+			sb.WriteString(fmt.Sprintf("（%s 超时）", order.ExpiresAt.Format("15:04:05")))
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n")
+}
+
 // buildUserPrompt 构建 User Prompt（动态数据）
 func buildUserPrompt(ctx *Context) string {
 	var sb strings.Builder
@@ -424,6 +576,33 @@ func buildUserPrompt(ctx *Context) string {
 		sb.WriteString("当前持仓: 无\n\n")
 	}
 
+	// 人工操作（上个周期之后）
+	if len(ctx.ManualActions) > 0 {
+		sb.WriteString("## 人工操作（上个周期后由用户手动执行或审批的操作，已反映在当前持仓中）\n")
+		for _, action := range ctx.ManualActions {
+			status := "成功"
+			if !action.Success {
+				status = "失败: " + action.Error
+			}
+			sb.WriteString(fmt.Sprintf("- %s %s %s", action.Time.Format("15:04:05"), action.Symbol, action.Action))
+			if action.Quantity > 0 {
+				sb.WriteString(fmt.Sprintf(" 数量 %.4f", action.Quantity))
+			}
+			if action.Price > 0 {
+				sb.WriteString(fmt.Sprintf(" @ %.4f", action.Price))
+			}
+			sb.WriteString(fmt.Sprintf(" | %s", status))
+			if action.Note != "" {
+				sb.WriteString(fmt.Sprintf(" | 备注: %s", action.Note))
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	// 限价开仓挂单
+	writePendingOrders(&sb, ctx.PendingOrders)
+
 	// 候选币种（完整市场数据）
 	sb.WriteString(fmt.Sprintf("## 候选币种 (%d个)\n\n", len(ctx.MarketDataMap)))
 	displayedCount := 0
@@ -468,8 +647,8 @@ func buildUserPrompt(ctx *Context) string {
 	return sb.String()
 }
 
-// parseFullDecisionResponse 解析AI的完整决策响应
-func parseFullDecisionResponse(aiResponse string, accountEquity float64, btcEthLeverage, altcoinLeverage int) (*FullDecision, error) {
+// ParseFullDecisionResponse 解析AI的完整决策响应（提取思维链和决策并校验）
+func ParseFullDecisionResponse(aiResponse string, accountEquity float64, btcEthLeverage, altcoinLeverage int) (*FullDecision, error) {
 	// 1. 提取思维链
 	cotTrace := extractCoTTrace(aiResponse)
 
@@ -794,6 +973,21 @@ func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoi
 			}
 		}
 
+		// 入场方式：限价挂单价必须在止损和止盈之间
+		switch d.EntryType {
+		case "", EntryTypeMarket, EntryTypeLimit, EntryTypePostOnly:
+		default:
+			return fmt.Errorf("无效的entry_type: %s（可选 market / limit / post_only）", d.EntryType)
+		}
+		if d.IsLimitEntry() {
+			if d.LimitPrice <= 0 {
+				return fmt.Errorf("%s 入场必须提供 limit_price", d.EntryType)
+			}
+			if d.LimitPrice <= math.Min(d.StopLoss, d.TakeProfit) || d.LimitPrice >= math.Max(d.StopLoss, d.TakeProfit) {
+				return fmt.Errorf("限价 %.4f 必须在止损 %.4f 和止盈 %.4f 之间", d.LimitPrice, d.StopLoss, d.TakeProfit)
+			}
+		}
+
 		// 验证风险回报比（必须≥1:3）
 		// 计算入场价（假设当前市价）
 		var entryPrice float64
@@ -804,6 +998,9 @@ func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoi
 			// 做空：入场价在止损和止盈之间
 			entryPrice = d.StopLoss - (d.StopLoss-d.TakeProfit)*0.2 // 假设在20%位置入场
 		}
+		if d.IsLimitEntry() {
+			entryPrice = d.LimitPrice // 限价入场：按挂单价计算
+		}
 
 		var riskPercent, rewardPercent, riskRewardRatio float64
 		if d.Action == "open_long" {
@@ -3,6 +3,7 @@ package decision
 import (
 	"strings"
 	"testing"
+	"time"
 )
 
 // TestBuildSystemPrompt_ContainsAllValidActions 测试 prompt 是否包含所有有效的 action
@@ -21,7 +22,7 @@ func TestBuildSystemPrompt_ContainsAllValidActions(t *testing.T) {
 	}
 
 	// 构建 prompt
-	prompt := buildSystemPrompt(1000.0, 10, 5, "default")
+	prompt := buildSystemPrompt(1000.0, 10, 5, "default", "")
 
 	// 验证每个有效 action 都在 prompt 中出现
 	for _, action := range validActions {
@@ -33,7 +34,7 @@ func TestBuildSystemPrompt_ContainsAllValidActions(t *testing.T) {
 
 // TestBuildSystemPrompt_ActionListCompleteness 测试 action 列表的完整性
 func TestBuildSystemPrompt_ActionListCompleteness(t *testing.T) {
-	prompt := buildSystemPrompt(1000.0, 10, 5, "default")
+	prompt := buildSystemPrompt(1000.0, 10, 5, "default", "")
 
 	// 检查是否包含关键的缺失 action
 	missingActions := []string{
@@ -48,3 +49,42 @@ func TestBuildSystemPrompt_ActionListCompleteness(t *testing.T) {
 		}
 	}
 }
+
+// TestBuildUserPrompt_ManualActions 测试上个周期后的手动操作会告知AI
+func TestBuildUserPrompt_ManualActions(t *testing.T) {
+	ctx := &Context{CurrentTime: "2025-01-01 00:00:00"}
+	if prompt := buildUserPrompt(ctx); strings.Contains(prompt, "人工操作") {
+		t.Errorf("没有手动操作时不应输出人工操作段落")
+	}
+
+	ctx.ManualActions = []ManualAction{
+		{Time: time.Date(2025, 1, 1, 8, 30, 0, 0, time.Local), Symbol: "BTCUSDT", Action: "close_long", Price: 61000, Quantity: 0.01, Success: true, Note: "止盈离场"},
+		{Time: time.Date(2025, 1, 1, 8, 31, 0, 0, time.Local), Symbol: "ETHUSDT", Action: "update_stop_loss", Error: "没有持仓"},
+	}
+	prompt := buildUserPrompt(ctx)
+	for _, want := range []string{"## 人工操作", "08:30:00 BTCUSDT close_long 数量 0.0100 @ 61000.0000 | 成功 | 备注: 止盈离场", "ETHUSDT update_stop_loss | 失败: 没有持仓"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("Prompt 缺少 %q:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestBuildUserPrompt_PendingOrders(t *testing.T) {
+	ctx := &Context{CurrentTime: "2025-01-01 00:00:00"}
+	if prompt := buildUserPrompt(ctx); strings.Contains(prompt, "限价开仓单") {
+		t.Errorf("没有挂单时不应输出挂单段落")
+	}
+
+	ctx.PendingOrders = []PendingOrder{
+		{Symbol: "BTCUSDT", Side: "long", EntryType: EntryTypePostOnly, LimitPrice: 60000, Quantity: 0.01, FilledQty: 0.004, Status: "挂单中",
+			ExpiresAt: time.Date(2025, 1, 1, 8, 33, 0, 0, time.Local)},
+		{Symbol: "ETHUSDT", Side: "short", EntryType: EntryTypeLimit, LimitPrice: 3100, Quantity: 1, Status: "已撤销：超时未成交，已撤单"},
+	}
+	prompt := buildUserPrompt(ctx)
+	for _, want := range []string{"## 限价开仓单", "BTCUSDT LONG post_only 限价 60000.0000 | 数量 0.0100 已成交 0.0040 | 挂单中（08:33:00 超时）",
+		"ETHUSDT SHORT limit 限价 3100.0000 | 数量 1.0000 | 已撤销：超时未成交，已撤单\n"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("Prompt 缺少 %q:\n%s", want, prompt)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package decision
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"nofx/mcp"
+	"strings"
+)
+
+// 决策输出模式
+const (
+	DecisionModeText     = "text"      // 文本模式：<reasoning>/<decision> 标签 + JSON 数组
+	DecisionModeToolCall = "tool_call" // 原生 Function Calling：每个动作对应一个函数
+	DecisionModeAgent    = "agent"     // 多轮 Agent：先按需查询数据，再通过函数提交决策
+)
+
+// ValidDecisionMode 判断决策模式是否合法（空字符串表示默认的文本模式）
+func ValidDecisionMode(mode string) bool {
+	return mode == "" || mode == DecisionModeText || mode == DecisionModeToolCall || mode == DecisionModeAgent
+}
+
+// DecisionTools 返回所有决策动作对应的函数定义（函数名即 action）
+func DecisionTools() []mcp.Tool {
+	symbol := schemaProperty("string", "交易对，如 BTCUSDT")
+	reasoning := schemaProperty("string", "简短的决策理由")
+	openParams := map[string]any{
+		"symbol":            symbol,
+		"leverage":          schemaProperty("integer", "杠杆倍数（不得超过系统提示中的上限）"),
+		"position_size_usd": schemaProperty("number", "仓位名义价值（USDT）"),
+		"stop_loss":         schemaProperty("number", "止损价"),
+		"take_profit":       schemaProperty("number", "止盈价"),
+		"entry_type":        schemaProperty("string", "入场方式: market（默认，市价）| limit（限价）| post_only（只做 Maker，手续费最低）"),
+		"limit_price":       schemaProperty("number", "限价（entry_type 为 limit / post_only 时必填，需在止损和止盈之间）"),
+		"confidence":        schemaProperty("integer", "信心度 0-100（开仓建议≥75）"),
+		"risk_usd":          schemaProperty("number", "最大美元风险"),
+		"reasoning":         reasoning,
+	}
+	openRequired := []string{"symbol", "leverage", "position_size_usd", "stop_loss", "take_profit", "confidence", "risk_usd", "reasoning"}
+
+	return []mcp.Tool{
+		decisionTool("open_long", "开多仓", openParams, openRequired),
+		decisionTool("open_short", "开空仓", openParams, openRequired),
+		decisionTool("close_long", "平掉多仓", map[string]any{"symbol": symbol, "reasoning": reasoning}, []string{"symbol", "reasoning"}),
+		decisionTool("close_short", "平掉空仓", map[string]any{"symbol": symbol, "reasoning": reasoning}, []string{"symbol", "reasoning"}),
+		decisionTool("update_stop_loss", "调整已有持仓的止损价", map[string]any{
+			"symbol":        symbol,
+			"new_stop_loss": schemaProperty("number", "新止损价"),
+			"reasoning":     reasoning,
+		}, []string{"symbol", "new_stop_loss", "reasoning"}),
+		decisionTool("update_take_profit", "调整已有持仓的止盈价", map[string]any{
+			"symbol":          symbol,
+			"new_take_profit": schemaProperty("number", "新止盈价"),
+			"reasoning":       reasoning,
+		}, []string{"symbol", "new_take_profit", "reasoning"}),
+		decisionTool("partial_close", "部分平仓", map[string]any{
+			"symbol":           symbol,
+			"close_percentage": schemaProperty("number", "平仓百分比（0-100）"),
+			"reasoning":        reasoning,
+		}, []string{"symbol", "close_percentage", "reasoning"}),
+		decisionTool("hold", "继续持有现有仓位", map[string]any{"symbol": symbol, "reasoning": reasoning}, []string{"symbol", "reasoning"}),
+		decisionTool("wait", "观望不操作（全部观望时 symbol 填 ALL）", map[string]any{"symbol": symbol, "reasoning": reasoning}, []string{"symbol", "reasoning"}),
+	}
+}
+
+func decisionTool(name, description string, properties map[string]any, required []string) mcp.Tool {
+	return mcp.Tool{
+		Type: "function",
+		Function: mcp.FunctionDef{
+			Name:        name,
+			Description: description,
+			Parameters: map[string]any{
+				"type":       "object",
+				"properties": properties,
+				"required":   required,
+			},
+		},
+	}
+}
+
+func schemaProperty(typ, description string) map[string]any {
+	return map[string]any{"type": typ, "description": description}
+}
+
+// buildToolCallRequest 构建 Function Calling 决策请求
+func buildToolCallRequest(systemPrompt, userPrompt string) (*mcp.Request, error) {
+	builder := mcp.NewRequestBuilder().
+		WithSystemPrompt(systemPrompt).
+		WithUserPrompt(userPrompt).
+		WithToolChoice("auto")
+	for _, tool := range DecisionTools() {
+		builder.AddTool(tool)
+	}
+	return builder.Build()
+}
+
+// ParseToolCallResponse 解析 Function Calling 响应（正文为思维链，工具调用为决策）并校验
+// 模型未返回任何工具调用时，按文本模式解析正文
+func ParseToolCallResponse(resp *mcp.Response, accountEquity float64, btcEthLeverage, altcoinLeverage int) (*FullDecision, error) {
+	if len(resp.ToolCalls) == 0 {
+		log.Printf("⚠️  模型未返回工具调用，按文本格式解析响应")
+		return ParseFullDecisionResponse(resp.Content, accountEquity, btcEthLeverage, altcoinLeverage)
+	}
+
+	cotTrace := strings.TrimSpace(resp.Content)
+	if match := reReasoningTag.FindStringSubmatch(cotTrace); match != nil {
+		cotTrace = strings.TrimSpace(match[1])
+	}
+
+	decisions, err := decisionsFromToolCalls(resp.ToolCalls)
+	if err != nil {
+		return &FullDecision{
+			CoTTrace:  cotTrace,
+			Decisions: []Decision{},
+		}, fmt.Errorf("提取决策失败: %w", err)
+	}
+
+	if err := validateDecisions(decisions, accountEquity, btcEthLeverage, altcoinLeverage); err != nil {
+		return &FullDecision{
+			CoTTrace:  cotTrace,
+			Decisions: decisions,
+		}, fmt.Errorf("决策验证失败: %w", err)
+	}
+
+	return &FullDecision{
+		CoTTrace:  cotTrace,
+		Decisions: decisions,
+	}, nil
+}
+
+// decisionsFromToolCalls 将工具调用转换为决策（函数名即 action）
+func decisionsFromToolCalls(calls []mcp.ToolCall) ([]Decision, error) {
+	decisions := make([]Decision, 0, len(calls))
+	for i, call := range calls {
+		args := strings.TrimSpace(call.Function.Arguments)
+		if args == "" {
+			args = "{}"
+		}
+		var d Decision
+		if err := json.Unmarshal([]byte(args), &d); err != nil {
+			return nil, fmt.Errorf("工具调用 #%d (%s) 参数解析失败: %w\n参数: %s", i+1, call.Function.Name, err, args)
+		}
+		d.Action = call.Function.Name
+		decisions = append(decisions, d)
+	}
+	return decisions, nil
+}
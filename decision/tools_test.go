@@ -0,0 +1,117 @@
+package decision
+
+import (
+	"nofx/mcp"
+	"strings"
+	"testing"
+)
+
+func TestDecisionTools(t *testing.T) {
+	tools := DecisionTools()
+
+	names := make(map[string]bool)
+	for _, tool := range tools {
+		if tool.Type != "function" {
+			t.Errorf("%s: 工具类型应为 function，实际 %s", tool.Function.Name, tool.Type)
+		}
+		if _, ok := tool.Function.Parameters["required"].([]string); !ok {
+			t.Errorf("%s: 缺少 required 字段", tool.Function.Name)
+		}
+		names[tool.Function.Name] = true
+	}
+
+	for _, action := range []string{"open_long", "open_short", "close_long", "close_short", "update_stop_loss", "update_take_profit", "partial_close", "hold", "wait"} {
+		if !names[action] {
+			t.Errorf("缺少动作 %s 的函数定义", action)
+		}
+	}
+}
+
+func TestParseToolCallResponse(t *testing.T) {
+	resp := &mcp.Response{
+		Content: "<reasoning>BTC 放量突破，SOL 移动止损</reasoning>",
+		ToolCalls: []mcp.ToolCall{
+			{ID: "1", Type: "function", Function: mcp.FunctionCall{
+				Name:      "open_long",
+				Arguments: `{"symbol":"BTCUSDT","leverage":5,"position_size_usd":5000,"stop_loss":95000,"take_profit":110000,"confidence":80,"risk_usd":200,"reasoning":"突破"}`,
+			}},
+			{ID: "2", Type: "function", Function: mcp.FunctionCall{
+				Name:      "update_stop_loss",
+				Arguments: `{"symbol":"SOLUSDT","new_stop_loss":155,"reasoning":"保本"}`,
+			}},
+		},
+	}
+
+	decision, err := ParseToolCallResponse(resp, 1000, 10, 5)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if decision.CoTTrace != "BTC 放量突破，SOL 移动止损" {
+		t.Errorf("思维链提取错误: %q", decision.CoTTrace)
+	}
+	if len(decision.Decisions) != 2 {
+		t.Fatalf("期望 2 个决策，实际 %d", len(decision.Decisions))
+	}
+	if d := decision.Decisions[0]; d.Action != "open_long" || d.Symbol != "BTCUSDT" || d.Leverage != 5 || d.StopLoss != 95000 {
+		t.Errorf("开仓决策解析错误: %+v", d)
+	}
+	if d := decision.Decisions[1]; d.Action != "update_stop_loss" || d.NewStopLoss != 155 {
+		t.Errorf("调整止损决策解析错误: %+v", d)
+	}
+}
+
+func TestParseToolCallResponse_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		call    mcp.FunctionCall
+		wantErr string
+	}{
+		{
+			name:    "参数不是合法JSON",
+			call:    mcp.FunctionCall{Name: "close_long", Arguments: `{"symbol":"BTCUSDT",`},
+			wantErr: "参数解析失败",
+		},
+		{
+			name:    "未知函数名",
+			call:    mcp.FunctionCall{Name: "buy_everything", Arguments: `{"symbol":"BTCUSDT"}`},
+			wantErr: "无效的action",
+		},
+		{
+			name:    "参数校验失败",
+			call:    mcp.FunctionCall{Name: "partial_close", Arguments: `{"symbol":"BTCUSDT","close_percentage":150}`},
+			wantErr: "平仓百分比",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &mcp.Response{ToolCalls: []mcp.ToolCall{{Type: "function", Function: tt.call}}}
+			_, err := ParseToolCallResponse(resp, 1000, 10, 5)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("期望错误包含 %q，实际 %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParseToolCallResponse_FallbackToText(t *testing.T) {
+	resp := &mcp.Response{Content: "<reasoning>观望</reasoning><decision>\n```json\n[{\"symbol\":\"ALL\",\"action\":\"wait\",\"reasoning\":\"无信号\"}]\n```\n</decision>"}
+
+	decision, err := ParseToolCallResponse(resp, 1000, 10, 5)
+	if err != nil {
+		t.Fatalf("文本回退解析失败: %v", err)
+	}
+	if len(decision.Decisions) != 1 || decision.Decisions[0].Action != "wait" {
+		t.Errorf("文本回退解析结果错误: %+v", decision.Decisions)
+	}
+}
+
+func TestBuildSystemPrompt_ToolCallMode(t *testing.T) {
+	prompt := buildSystemPrompt(1000.0, 10, 5, "default", DecisionModeToolCall)
+	if strings.Contains(prompt, "<decision>") {
+		t.Error("函数调用模式下不应要求 <decision> 标签")
+	}
+	if !strings.Contains(prompt, "调用一次对应的函数") {
+		t.Error("函数调用模式下应说明使用函数输出决策")
+	}
+}
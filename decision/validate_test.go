@@ -293,3 +293,39 @@ func stringContains(s, substr string) bool {
 	}
 	return false
 }
+
+func TestLimitEntryValidation(t *testing.T) {
+	base := Decision{Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 500, StopLoss: 49000, TakeProfit: 53000}
+	tests := []struct {
+		name       string
+		entryType  string
+		limitPrice float64
+		errorMsg   string // 为空表示应通过验证
+	}{
+		{"默认市价", "", 0, ""},
+		{"限价入场", EntryTypeLimit, 49900, ""},
+		{"只做Maker入场", EntryTypePostOnly, 49800, ""},
+		{"无效入场方式", "stop", 49900, "无效的entry_type"},
+		{"缺少限价", EntryTypeLimit, 0, "必须提供 limit_price"},
+		{"限价不在止损止盈之间", EntryTypePostOnly, 48000, "必须在止损"},
+		{"按限价计算风险回报比", EntryTypeLimit, 50500, "风险回报比过低"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := base
+			d.EntryType = tt.entryType
+			d.LimitPrice = tt.limitPrice
+			err := validateDecision(&d, 1000.0, 10, 5)
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("validateDecision() 不应报错: %v", err)
+				}
+				return
+			}
+			if err == nil || !contains(err.Error(), tt.errorMsg) {
+				t.Errorf("validateDecision() error = %v, want to contain %q", err, tt.errorMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package events
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 交易员事件类型
+const (
+	CycleStarted     = "cycle_started"     // 决策周期开始
+	AIResponse       = "ai_response"       // 收到AI决策（含失败）
+	DecisionExecuted = "decision_executed" // 决策执行成功
+	DecisionFailed   = "decision_failed"   // 决策执行失败
+	StopTriggered    = "stop_triggered"    // 触发熔断，暂停交易
+	DrawdownClose    = "drawdown_close"    // 退出策略/熔断强制平仓
+	StopMoved        = "stop_moved"        // 退出策略移动止损
+	BalanceSynced    = "balance_synced"    // 账户余额已同步
+	ProposalCreated  = "proposal_created"  // 审批模式下生成待审批提案
+	ProposalResolved = "proposal_resolved" // 提案已批准执行/拒绝/过期
+	OrderPlaced      = "order_placed"      // 限价开仓单已挂出
+	OrderFilled      = "order_filled"      // 限价开仓单成交（含部分成交后撤单、超时转市价）
+	OrderCanceled    = "order_canceled"    // 限价开仓单未成交即撤销
+)
+
+const (
+	defaultHistorySize = 1000 // 默认保留的最近事件数（用于断线重连补发）
+	subscriberBuffer   = 256  // 每个订阅者的缓冲区大小
+)
+
+// Event 交易员事件
+type Event struct {
+	ID       uint64         `json:"id"` // 进程内单调递增，可作为 SSE Last-Event-ID
+	Type     string         `json:"type"`
+	TraderID string         `json:"trader_id"`
+	Time     time.Time      `json:"time"`
+	Data     map[string]any `json:"data,omitempty"`
+}
+
+// Filter 订阅过滤条件，返回 true 表示接收该事件
+type Filter func(Event) bool
+
+// Bus 进程内事件总线
+//
+// 发布不会阻塞：订阅者缓冲区满时丢弃事件并计数，慢消费者不会拖慢交易主循环
+type Bus struct {
+	mu          sync.RWMutex
+	nextID      uint64
+	subscribers map[*Subscription]struct{}
+	history     []Event // 环形缓冲区
+	historyPos  int
+	historySize int
+}
+
+// NewBus 创建事件总线，historySize 为保留的最近事件数
+func NewBus(historySize int) *Bus {
+	if historySize <= 0 {
+		historySize = defaultHistorySize
+	}
+	return &Bus{
+		subscribers: make(map[*Subscription]struct{}),
+		historySize: historySize,
+	}
+}
+
+var defaultBus = NewBus(defaultHistorySize)
+
+// Default 返回全局事件总线（AutoTrader 发布、API 订阅）
+func Default() *Bus {
+	return defaultBus
+}
+
+// Publish 发布事件，返回带 ID 和时间的完整事件
+func (b *Bus) Publish(traderID, eventType string, data map[string]any) Event {
+	b.mu.Lock()
+	b.nextID++
+	event := Event{ID: b.nextID, Type: eventType, TraderID: traderID, Time: time.Now(), Data: data}
+	if len(b.history) < b.historySize {
+		b.history = append(b.history, event)
+	} else {
+		b.history[b.historyPos] = event
+		b.historyPos = (b.historyPos + 1) % b.historySize
+	}
+	subscribers := make([]*Subscription, 0, len(b.subscribers))
+	for sub := range b.subscribers {
+		subscribers = append(subscribers, sub)
+	}
+	b.mu.Unlock()
+
+	for _, sub := range subscribers {
+		sub.deliver(event)
+	}
+	return event
+}
+
+// Subscribe 订阅事件，同时返回 ID 大于 since 的历史事件（since 为 0 时不补发）
+// 历史事件与订阅在同一把锁内获取，两者之间不会遗漏事件
+func (b *Bus) Subscribe(since uint64, filter Filter) (*Subscription, []Event) {
+	sub := &Subscription{ch: make(chan Event, subscriberBuffer), filter: filter, bus: b}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var backlog []Event
+	if since > 0 {
+		for i := 0; i < len(b.history); i++ {
+			event := b.history[(b.historyPos+i)%len(b.history)]
+			if event.ID > since && (filter == nil || filter(event)) {
+				backlog = append(backlog, event)
+			}
+		}
+	}
+	b.subscribers[sub] = struct{}{}
+	return sub, backlog
+}
+
+// SubscriberCount 当前订阅者数量
+func (b *Bus) SubscriberCount() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subscribers)
+}
+
+// Subscription 事件订阅
+type Subscription struct {
+	ch      chan Event
+	filter  Filter
+	bus     *Bus
+	once    sync.Once
+	closed  atomic.Bool
+	dropped atomic.Int64
+}
+
+// Events 返回事件通道（Close 后关闭）
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Dropped 因缓冲区满被丢弃的事件数
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close 取消订阅
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.bus.mu.Lock()
+		delete(s.bus.subscribers, s)
+		s.closed.Store(true)
+		close(s.ch)
+		s.bus.mu.Unlock()
+	})
+}
+
+// deliver 非阻塞投递
+func (s *Subscription) deliver(event Event) {
+	if s.filter != nil && !s.filter(event) {
+		return
+	}
+	// 持有读锁，避免与 Close 关闭通道并发
+	s.bus.mu.RLock()
+	defer s.bus.mu.RUnlock()
+	if s.closed.Load() {
+		return
+	}
+	select {
+	case s.ch <- event:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// ForTrader 只接收指定交易员的事件，types 为空时接收所有类型
+func ForTrader(traderID string, types ...string) Filter {
+	allowed := make(map[string]bool, len(types))
+	for _, t := range types {
+		allowed[t] = true
+	}
+	return func(event Event) bool {
+		return event.TraderID == traderID && (len(allowed) == 0 || allowed[event.Type])
+	}
+}
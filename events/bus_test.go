@@ -0,0 +1,57 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBus_PublishFiltersByTraderAndType(t *testing.T) {
+	bus := NewBus(10)
+	sub, backlog := bus.Subscribe(0, ForTrader("t1", DecisionExecuted, DecisionFailed))
+	defer sub.Close()
+	assert.Empty(t, backlog)
+
+	bus.Publish("t2", DecisionExecuted, nil)
+	bus.Publish("t1", CycleStarted, map[string]any{"cycle": 1})
+	executed := bus.Publish("t1", DecisionExecuted, map[string]any{"symbol": "BTCUSDT"})
+
+	require.Len(t, sub.Events(), 1)
+	event := <-sub.Events()
+	assert.Equal(t, executed.ID, event.ID)
+	assert.Equal(t, "BTCUSDT", event.Data["symbol"])
+}
+
+func TestBus_SubscribeReplaysHistorySince(t *testing.T) {
+	bus := NewBus(3)
+	var ids []uint64
+	for i := 0; i < 5; i++ {
+		ids = append(ids, bus.Publish("t1", CycleStarted, map[string]any{"cycle": i}).ID)
+	}
+
+	// 只保留最近3条：since 为第1条时补发第3~5条（按顺序）
+	sub, backlog := bus.Subscribe(ids[0], ForTrader("t1"))
+	defer sub.Close()
+	require.Len(t, backlog, 3)
+	assert.Equal(t, ids[2:], []uint64{backlog[0].ID, backlog[1].ID, backlog[2].ID})
+
+	_, backlog = bus.Subscribe(ids[3], ForTrader("t1"))
+	require.Len(t, backlog, 1)
+	assert.Equal(t, ids[4], backlog[0].ID)
+}
+
+func TestBus_SlowSubscriberDropsInsteadOfBlocking(t *testing.T) {
+	bus := NewBus(10)
+	sub, _ := bus.Subscribe(0, nil)
+
+	for i := 0; i < subscriberBuffer+5; i++ {
+		bus.Publish("t1", BalanceSynced, nil)
+	}
+	assert.Equal(t, int64(5), sub.Dropped())
+
+	sub.Close()
+	sub.Close() // 重复关闭安全
+	assert.Equal(t, 0, bus.SubscriberCount())
+	bus.Publish("t1", BalanceSynced, nil) // 关闭后发布不会 panic
+}
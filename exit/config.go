@@ -0,0 +1,135 @@
+package exit
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Config 持仓退出策略配置（未配置的策略不启用）
+type Config struct {
+	CheckIntervalSeconds int                  `json:"check_interval_seconds"` // 检查间隔（秒）
+	ProfitRetrace        *ProfitRetraceConfig `json:"profit_retrace"`         // 盈利回撤保护
+	ATRTrailing          *ATRTrailingConfig   `json:"atr_trailing"`           // ATR 移动止损
+	BreakEven            *BreakEvenConfig     `json:"break_even"`             // 达到 N 倍 R 后止损移至保本
+	ProfitLocks          []ProfitLockStep     `json:"profit_locks"`           // 阶梯锁定利润
+	TimeExit             *TimeExitConfig      `json:"time_exit"`              // 超时平仓
+}
+
+// ProfitRetraceConfig 收益率达到 MinProfitPct 后，止损跟随锁定峰值收益的 (100-RetracePct)%
+type ProfitRetraceConfig struct {
+	MinProfitPct float64 `json:"min_profit_pct"` // 启用阈值（杠杆后收益率%）
+	RetracePct   float64 `json:"retrace_pct"`    // 允许从峰值收益回撤的比例（%）
+}
+
+// ATRTrailingConfig 止损跟随最有利价格，距离为 Multiplier 倍 ATR
+type ATRTrailingConfig struct {
+	Multiplier  float64 `json:"multiplier"`   // ATR 倍数
+	ActivationR float64 `json:"activation_r"` // 浮盈达到 N 倍 R 后启用（0 表示立即启用）
+	Timeframe   string  `json:"timeframe"`    // ATR 周期: "3m" 或 "4h"（默认 4h）
+}
+
+// BreakEvenConfig 浮盈达到 TriggerR 倍初始风险后，止损移至开仓价
+type BreakEvenConfig struct {
+	TriggerR  float64 `json:"trigger_r"`  // 触发倍数（R = |开仓价 - 初始止损价|）
+	OffsetPct float64 `json:"offset_pct"` // 保本价向盈利方向的偏移（价格%，用于覆盖手续费）
+}
+
+// ProfitLockStep 收益率达到 TriggerPct 后至少锁定 LockPct 的收益（均为杠杆后收益率%）
+type ProfitLockStep struct {
+	TriggerPct float64 `json:"trigger_pct"`
+	LockPct    float64 `json:"lock_pct"`
+}
+
+// TimeExitConfig 持仓超过 MaxHoldMinutes 后平仓
+type TimeExitConfig struct {
+	MaxHoldMinutes int     `json:"max_hold_minutes"`
+	MinProfitPct   float64 `json:"min_profit_pct"` // 超时后收益率低于该值才平仓（0 表示超时即平仓）
+}
+
+// DefaultConfig 默认退出策略（与原有"收益>5%且回撤≥40%"保护一致）
+func DefaultConfig() Config {
+	return Config{
+		CheckIntervalSeconds: 60,
+		ProfitRetrace:        &ProfitRetraceConfig{MinProfitPct: 5, RetracePct: 40},
+	}
+}
+
+// ParseConfig 解析交易员的退出策略JSON，未出现的字段沿用 base 中的值
+// 某个策略设置为 null 可关闭默认启用的策略；raw 为空时直接返回 base
+func ParseConfig(raw string, base Config) (Config, error) {
+	if strings.TrimSpace(raw) == "" {
+		return base, nil
+	}
+	cfg := base.clone()
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		return base, fmt.Errorf("解析退出策略配置失败: %w", err)
+	}
+	return cfg, cfg.Validate()
+}
+
+// Validate 检查配置取值是否合法
+func (c Config) Validate() error {
+	if c.CheckIntervalSeconds < 0 {
+		return fmt.Errorf("check_interval_seconds 不能为负数")
+	}
+	if r := c.ProfitRetrace; r != nil {
+		if r.MinProfitPct < 0 {
+			return fmt.Errorf("profit_retrace.min_profit_pct 不能为负数")
+		}
+		if r.RetracePct <= 0 || r.RetracePct >= 100 {
+			return fmt.Errorf("profit_retrace.retrace_pct 必须在 0-100 之间")
+		}
+	}
+	if a := c.ATRTrailing; a != nil {
+		if a.Multiplier <= 0 {
+			return fmt.Errorf("atr_trailing.multiplier 必须大于0")
+		}
+		if a.ActivationR < 0 {
+			return fmt.Errorf("atr_trailing.activation_r 不能为负数")
+		}
+		if a.Timeframe != "" && a.Timeframe != "3m" && a.Timeframe != "4h" {
+			return fmt.Errorf("atr_trailing.timeframe 只支持 3m 或 4h")
+		}
+	}
+	if b := c.BreakEven; b != nil {
+		if b.TriggerR <= 0 {
+			return fmt.Errorf("break_even.trigger_r 必须大于0")
+		}
+		if b.OffsetPct < 0 {
+			return fmt.Errorf("break_even.offset_pct 不能为负数")
+		}
+	}
+	for i, step := range c.ProfitLocks {
+		if step.TriggerPct <= 0 || step.LockPct < 0 || step.LockPct >= step.TriggerPct {
+			return fmt.Errorf("profit_locks[%d] 需满足 0 <= lock_pct < trigger_pct", i)
+		}
+	}
+	if t := c.TimeExit; t != nil && t.MaxHoldMinutes <= 0 {
+		return fmt.Errorf("time_exit.max_hold_minutes 必须大于0")
+	}
+	return nil
+}
+
+// clone 深拷贝配置（避免解析时修改 base 中的指针字段）
+func (c Config) clone() Config {
+	out := c
+	if c.ProfitRetrace != nil {
+		v := *c.ProfitRetrace
+		out.ProfitRetrace = &v
+	}
+	if c.ATRTrailing != nil {
+		v := *c.ATRTrailing
+		out.ATRTrailing = &v
+	}
+	if c.BreakEven != nil {
+		v := *c.BreakEven
+		out.BreakEven = &v
+	}
+	if c.TimeExit != nil {
+		v := *c.TimeExit
+		out.TimeExit = &v
+	}
+	out.ProfitLocks = append([]ProfitLockStep(nil), c.ProfitLocks...)
+	return out
+}
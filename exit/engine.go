@@ -0,0 +1,192 @@
+package exit
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Position 持仓快照
+type Position struct {
+	Symbol      string
+	Side        string // long / short
+	EntryPrice  float64
+	MarkPrice   float64
+	Leverage    int
+	InitialStop float64   // 开仓时的止损价（用于计算 R，0 表示未知）
+	CurrentStop float64   // 当前交易所止损价（0 表示未设置）
+	PeakPrice   float64   // 持仓期间最有利的标记价格
+	PeakPnLPct  float64   // 持仓期间最高收益率（杠杆后%）
+	OpenedAt    time.Time // 开仓时间
+	ATR         float64   // 当前 ATR（仅 ATR 移动止损使用）
+}
+
+// PnLPct 当前收益率（杠杆后%）
+func (p Position) PnLPct() float64 {
+	return p.pnlPctAt(p.MarkPrice)
+}
+
+// RiskPerUnit 初始风险 R（每单位价格距离），未知时返回0
+func (p Position) RiskPerUnit() float64 {
+	if p.InitialStop <= 0 {
+		return 0
+	}
+	return math.Abs(p.EntryPrice - p.InitialStop)
+}
+
+// pnlPctAt 指定价格对应的收益率（杠杆后%）
+func (p Position) pnlPctAt(price float64) float64 {
+	if p.EntryPrice <= 0 {
+		return 0
+	}
+	move := (price - p.EntryPrice) / p.EntryPrice
+	if p.Side == "short" {
+		move = -move
+	}
+	return move * float64(p.leverage()) * 100
+}
+
+// priceAtPnLPct 收益率（杠杆后%）对应的价格
+func (p Position) priceAtPnLPct(pct float64) float64 {
+	move := pct / 100 / float64(p.leverage())
+	if p.Side == "short" {
+		return p.EntryPrice * (1 - move)
+	}
+	return p.EntryPrice * (1 + move)
+}
+
+// favorableMove 峰值价格相对开仓价的有利距离
+func (p Position) favorableMove() float64 {
+	if p.Side == "short" {
+		return p.EntryPrice - p.PeakPrice
+	}
+	return p.PeakPrice - p.EntryPrice
+}
+
+// tighter 止损价 a 是否比 b 更贴近当前价格（b 为0表示未设置）
+func (p Position) tighter(a, b float64) bool {
+	if b <= 0 {
+		return true
+	}
+	if p.Side == "short" {
+		return a < b
+	}
+	return a > b
+}
+
+// crossed 止损价是否已被当前价格越过（与当前价几乎相同也视为越过，交易所会拒绝立即触发的止损单）
+func (p Position) crossed(stop float64) bool {
+	const tolerance = 1e-9
+	if p.Side == "short" {
+		return p.MarkPrice >= stop*(1-tolerance)
+	}
+	return p.MarkPrice <= stop*(1+tolerance)
+}
+
+func (p Position) leverage() int {
+	if p.Leverage <= 0 {
+		return 1
+	}
+	return p.Leverage
+}
+
+// Action 退出动作
+type Action struct {
+	Close     bool    // 立即市价平仓
+	StopPrice float64 // 新止损价（Close 为 false 时有效）
+	Reason    string
+}
+
+// Policy 退出策略
+type Policy interface {
+	// Name 策略名称（用于日志）
+	Name() string
+	// Evaluate 返回建议的退出动作，nil 表示当前不适用
+	Evaluate(pos Position, now time.Time) *Action
+}
+
+// Engine 退出策略引擎，汇总各策略后只收紧止损、不放宽
+type Engine struct {
+	policies      []Policy
+	checkInterval time.Duration
+	atrTimeframe  string
+}
+
+// NewEngine 根据配置创建退出策略引擎
+func NewEngine(cfg Config) *Engine {
+	e := &Engine{checkInterval: time.Duration(cfg.CheckIntervalSeconds) * time.Second}
+	if e.checkInterval <= 0 {
+		e.checkInterval = time.Minute
+	}
+
+	if cfg.TimeExit != nil {
+		e.Use(&TimeExitPolicy{Config: *cfg.TimeExit})
+	}
+	if cfg.ProfitRetrace != nil {
+		e.Use(&ProfitRetracePolicy{Config: *cfg.ProfitRetrace})
+	}
+	if cfg.BreakEven != nil {
+		e.Use(&BreakEvenPolicy{Config: *cfg.BreakEven})
+	}
+	if len(cfg.ProfitLocks) > 0 {
+		e.Use(&ProfitLockPolicy{Steps: cfg.ProfitLocks})
+	}
+	if cfg.ATRTrailing != nil {
+		e.Use(&ATRTrailingPolicy{Config: *cfg.ATRTrailing})
+		e.atrTimeframe = cfg.ATRTrailing.Timeframe
+		if e.atrTimeframe == "" {
+			e.atrTimeframe = "4h"
+		}
+	}
+	return e
+}
+
+// Use 追加自定义策略
+func (e *Engine) Use(policies ...Policy) {
+	e.policies = append(e.policies, policies...)
+}
+
+// Policies 返回当前策略列表
+func (e *Engine) Policies() []Policy {
+	return e.policies
+}
+
+// CheckInterval 持仓检查间隔
+func (e *Engine) CheckInterval() time.Duration {
+	return e.checkInterval
+}
+
+// ATRTimeframe 需要的 ATR 周期（为空表示不需要 ATR）
+func (e *Engine) ATRTimeframe() string {
+	return e.atrTimeframe
+}
+
+// Evaluate 汇总所有策略：任一策略要求平仓则平仓；否则取最紧的止损价
+// 仅当新止损比当前止损更紧时返回；止损价已被当前价格越过时改为平仓
+func (e *Engine) Evaluate(pos Position, now time.Time) *Action {
+	var best *Action
+	for _, policy := range e.policies {
+		action := policy.Evaluate(pos, now)
+		if action == nil {
+			continue
+		}
+		action.Reason = fmt.Sprintf("[%s] %s", policy.Name(), action.Reason)
+		if action.Close {
+			return action
+		}
+		if action.StopPrice <= 0 {
+			continue
+		}
+		if best == nil || pos.tighter(action.StopPrice, best.StopPrice) {
+			best = action
+		}
+	}
+
+	if best == nil || !pos.tighter(best.StopPrice, pos.CurrentStop) {
+		return nil
+	}
+	if pos.crossed(best.StopPrice) {
+		return &Action{Close: true, Reason: fmt.Sprintf("%s，当前价 %.4f 已越过止损位 %.4f", best.Reason, pos.MarkPrice, best.StopPrice)}
+	}
+	return best
+}
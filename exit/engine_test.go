@@ -0,0 +1,215 @@
+package exit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicies(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		policy    Policy
+		pos       Position
+		wantClose bool
+		wantStop  float64
+	}{
+		{
+			name:   "盈利回撤：峰值未达阈值不生效",
+			policy: &ProfitRetracePolicy{Config: ProfitRetraceConfig{MinProfitPct: 5, RetracePct: 40}},
+			pos:    Position{Side: "long", EntryPrice: 100, MarkPrice: 100.3, Leverage: 10, PeakPnLPct: 4},
+		},
+		{
+			name:     "盈利回撤：多仓锁定60%峰值收益",
+			policy:   &ProfitRetracePolicy{Config: ProfitRetraceConfig{MinProfitPct: 5, RetracePct: 40}},
+			pos:      Position{Side: "long", EntryPrice: 100, MarkPrice: 100.8, Leverage: 10, PeakPnLPct: 10},
+			wantStop: 100.6,
+		},
+		{
+			name:     "盈利回撤：空仓锁定60%峰值收益",
+			policy:   &ProfitRetracePolicy{Config: ProfitRetraceConfig{MinProfitPct: 5, RetracePct: 40}},
+			pos:      Position{Side: "short", EntryPrice: 100, MarkPrice: 99.2, Leverage: 10, PeakPnLPct: 10},
+			wantStop: 99.4,
+		},
+		{
+			name:   "ATR移动止损：未达激活R不生效",
+			policy: &ATRTrailingPolicy{Config: ATRTrailingConfig{Multiplier: 2, ActivationR: 1}},
+			pos:    Position{Side: "long", EntryPrice: 100, InitialStop: 95, PeakPrice: 103, ATR: 1},
+		},
+		{
+			name:     "ATR移动止损：多仓跟随峰值价",
+			policy:   &ATRTrailingPolicy{Config: ATRTrailingConfig{Multiplier: 2, ActivationR: 1}},
+			pos:      Position{Side: "long", EntryPrice: 100, InitialStop: 95, PeakPrice: 110, ATR: 1.5},
+			wantStop: 107,
+		},
+		{
+			name:     "ATR移动止损：空仓跟随峰值价",
+			policy:   &ATRTrailingPolicy{Config: ATRTrailingConfig{Multiplier: 2}},
+			pos:      Position{Side: "short", EntryPrice: 100, PeakPrice: 90, ATR: 1.5},
+			wantStop: 93,
+		},
+		{
+			name:   "保本止损：初始止损未知不生效",
+			policy: &BreakEvenPolicy{Config: BreakEvenConfig{TriggerR: 1}},
+			pos:    Position{Side: "long", EntryPrice: 100, PeakPrice: 120},
+		},
+		{
+			name:     "保本止损：达到1R后移至开仓价加偏移",
+			policy:   &BreakEvenPolicy{Config: BreakEvenConfig{TriggerR: 1, OffsetPct: 0.1}},
+			pos:      Position{Side: "long", EntryPrice: 100, InitialStop: 95, PeakPrice: 105},
+			wantStop: 100.1,
+		},
+		{
+			name:     "保本止损：空仓",
+			policy:   &BreakEvenPolicy{Config: BreakEvenConfig{TriggerR: 1.5}},
+			pos:      Position{Side: "short", EntryPrice: 100, InitialStop: 102, PeakPrice: 97},
+			wantStop: 100,
+		},
+		{
+			name: "阶梯锁利：取已越过的最高档",
+			policy: &ProfitLockPolicy{Steps: []ProfitLockStep{
+				{TriggerPct: 10, LockPct: 3},
+				{TriggerPct: 20, LockPct: 10},
+				{TriggerPct: 40, LockPct: 25},
+			}},
+			pos:      Position{Side: "long", EntryPrice: 100, Leverage: 5, PeakPnLPct: 25},
+			wantStop: 102,
+		},
+		{
+			name:   "超时平仓：未超时不生效",
+			policy: &TimeExitPolicy{Config: TimeExitConfig{MaxHoldMinutes: 60}},
+			pos:    Position{Side: "long", EntryPrice: 100, MarkPrice: 100, OpenedAt: now.Add(-30 * time.Minute)},
+		},
+		{
+			name:   "超时平仓：收益达标时保留",
+			policy: &TimeExitPolicy{Config: TimeExitConfig{MaxHoldMinutes: 60, MinProfitPct: 2}},
+			pos:    Position{Side: "long", EntryPrice: 100, MarkPrice: 101, Leverage: 5, OpenedAt: now.Add(-2 * time.Hour)},
+		},
+		{
+			name:      "超时平仓：收益不足时平仓",
+			policy:    &TimeExitPolicy{Config: TimeExitConfig{MaxHoldMinutes: 60, MinProfitPct: 2}},
+			pos:       Position{Side: "long", EntryPrice: 100, MarkPrice: 100.1, Leverage: 5, OpenedAt: now.Add(-2 * time.Hour)},
+			wantClose: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action := tt.policy.Evaluate(tt.pos, now)
+			if !tt.wantClose && tt.wantStop == 0 {
+				assert.Nil(t, action)
+				return
+			}
+			require.NotNil(t, action)
+			assert.Equal(t, tt.wantClose, action.Close)
+			assert.InDelta(t, tt.wantStop, action.StopPrice, 1e-6)
+		})
+	}
+}
+
+func TestEngineEvaluate(t *testing.T) {
+	now := time.Now()
+	engine := NewEngine(Config{
+		ProfitRetrace: &ProfitRetraceConfig{MinProfitPct: 5, RetracePct: 40},
+		BreakEven:     &BreakEvenConfig{TriggerR: 1},
+	})
+
+	t.Run("取最紧的止损", func(t *testing.T) {
+		// 盈利回撤锁定 6% → 100.6；保本 → 100
+		pos := Position{Side: "long", EntryPrice: 100, MarkPrice: 100.8, Leverage: 10, InitialStop: 98, PeakPrice: 101, PeakPnLPct: 10}
+		action := engine.Evaluate(pos, now)
+		require.NotNil(t, action)
+		assert.False(t, action.Close)
+		assert.InDelta(t, 100.6, action.StopPrice, 1e-6)
+		assert.Contains(t, action.Reason, "[盈利回撤]")
+	})
+
+	t.Run("只收紧不放宽", func(t *testing.T) {
+		pos := Position{Side: "long", EntryPrice: 100, MarkPrice: 100.8, Leverage: 10, PeakPnLPct: 10, CurrentStop: 100.7}
+		assert.Nil(t, engine.Evaluate(pos, now))
+
+		pos.Side = "short"
+		pos.MarkPrice = 99.2
+		pos.CurrentStop = 99.3
+		assert.Nil(t, engine.Evaluate(pos, now))
+	})
+
+	t.Run("止损价已被越过时改为平仓", func(t *testing.T) {
+		pos := Position{Side: "long", EntryPrice: 100, MarkPrice: 100.5, Leverage: 10, PeakPnLPct: 10}
+		action := engine.Evaluate(pos, now)
+		require.NotNil(t, action)
+		assert.True(t, action.Close)
+		assert.Contains(t, action.Reason, "已越过止损位")
+	})
+
+	t.Run("平仓动作优先", func(t *testing.T) {
+		e := NewEngine(Config{
+			ProfitRetrace: &ProfitRetraceConfig{MinProfitPct: 5, RetracePct: 40},
+			TimeExit:      &TimeExitConfig{MaxHoldMinutes: 10},
+		})
+		pos := Position{Side: "long", EntryPrice: 100, MarkPrice: 100.8, Leverage: 10, PeakPnLPct: 10, OpenedAt: now.Add(-time.Hour)}
+		action := e.Evaluate(pos, now)
+		require.NotNil(t, action)
+		assert.True(t, action.Close)
+		assert.Contains(t, action.Reason, "[超时平仓]")
+	})
+
+	t.Run("无策略时不动作", func(t *testing.T) {
+		e := NewEngine(Config{})
+		assert.Nil(t, e.Evaluate(Position{Side: "long", EntryPrice: 100, MarkPrice: 110, PeakPnLPct: 10}, now))
+		assert.Equal(t, time.Minute, e.CheckInterval())
+		assert.Empty(t, e.ATRTimeframe())
+	})
+}
+
+func TestNewEngine(t *testing.T) {
+	engine := NewEngine(Config{
+		CheckIntervalSeconds: 15,
+		ProfitRetrace:        &ProfitRetraceConfig{MinProfitPct: 5, RetracePct: 40},
+		ATRTrailing:          &ATRTrailingConfig{Multiplier: 2},
+		ProfitLocks:          []ProfitLockStep{{TriggerPct: 10, LockPct: 3}},
+	})
+	assert.Equal(t, 15*time.Second, engine.CheckInterval())
+	assert.Equal(t, "4h", engine.ATRTimeframe())
+
+	var names []string
+	for _, policy := range engine.Policies() {
+		names = append(names, policy.Name())
+	}
+	assert.Equal(t, []string{"盈利回撤", "阶梯锁利", "ATR移动止损"}, names)
+}
+
+func TestParseConfig(t *testing.T) {
+	base := DefaultConfig()
+
+	cfg, err := ParseConfig("", base)
+	require.NoError(t, err)
+	assert.Equal(t, base, cfg)
+
+	cfg, err = ParseConfig(`{"profit_retrace":{"retrace_pct":30},"break_even":{"trigger_r":1.5}}`, base)
+	require.NoError(t, err)
+	require.NotNil(t, cfg.ProfitRetrace)
+	assert.Equal(t, 5.0, cfg.ProfitRetrace.MinProfitPct)
+	assert.Equal(t, 30.0, cfg.ProfitRetrace.RetracePct)
+	require.NotNil(t, cfg.BreakEven)
+	assert.Equal(t, 1.5, cfg.BreakEven.TriggerR)
+	assert.Equal(t, 60, cfg.CheckIntervalSeconds)
+	// base 不应被修改
+	assert.Equal(t, 40.0, base.ProfitRetrace.RetracePct)
+
+	cfg, err = ParseConfig(`{"profit_retrace":null}`, base)
+	require.NoError(t, err)
+	assert.Nil(t, cfg.ProfitRetrace)
+
+	_, err = ParseConfig(`{"profit_locks":[{"trigger_pct":10,"lock_pct":12}]}`, base)
+	assert.Error(t, err)
+
+	_, err = ParseConfig(`{"atr_trailing":{"multiplier":2,"timeframe":"1h"}}`, base)
+	assert.Error(t, err)
+
+	_, err = ParseConfig(`{bad json`, base)
+	assert.Error(t, err)
+}
@@ -0,0 +1,128 @@
+package exit
+
+import (
+	"fmt"
+	"time"
+)
+
+// ProfitRetracePolicy 盈利回撤保护：止损跟随锁定峰值收益的一部分
+type ProfitRetracePolicy struct {
+	Config ProfitRetraceConfig
+}
+
+func (p *ProfitRetracePolicy) Name() string { return "盈利回撤" }
+
+func (p *ProfitRetracePolicy) Evaluate(pos Position, now time.Time) *Action {
+	if pos.PeakPnLPct <= 0 || pos.PeakPnLPct < p.Config.MinProfitPct {
+		return nil
+	}
+	lockPct := pos.PeakPnLPct * (1 - p.Config.RetracePct/100)
+	return &Action{
+		StopPrice: pos.priceAtPnLPct(lockPct),
+		Reason:    fmt.Sprintf("峰值收益 %.2f%%，锁定 %.2f%%", pos.PeakPnLPct, lockPct),
+	}
+}
+
+// ATRTrailingPolicy ATR 移动止损：止损 = 最有利价格 ∓ N × ATR
+type ATRTrailingPolicy struct {
+	Config ATRTrailingConfig
+}
+
+func (p *ATRTrailingPolicy) Name() string { return "ATR移动止损" }
+
+func (p *ATRTrailingPolicy) Evaluate(pos Position, now time.Time) *Action {
+	if pos.ATR <= 0 || pos.PeakPrice <= 0 {
+		return nil
+	}
+	if p.Config.ActivationR > 0 {
+		r := pos.RiskPerUnit()
+		if r <= 0 || pos.favorableMove() < p.Config.ActivationR*r {
+			return nil
+		}
+	}
+
+	distance := p.Config.Multiplier * pos.ATR
+	stop := pos.PeakPrice - distance
+	if pos.Side == "short" {
+		stop = pos.PeakPrice + distance
+	}
+	if stop <= 0 {
+		return nil
+	}
+	return &Action{
+		StopPrice: stop,
+		Reason:    fmt.Sprintf("峰值价 %.4f，%.1f×ATR(%.4f)", pos.PeakPrice, p.Config.Multiplier, pos.ATR),
+	}
+}
+
+// BreakEvenPolicy 浮盈达到 N 倍 R 后将止损移至保本
+type BreakEvenPolicy struct {
+	Config BreakEvenConfig
+}
+
+func (p *BreakEvenPolicy) Name() string { return "保本止损" }
+
+func (p *BreakEvenPolicy) Evaluate(pos Position, now time.Time) *Action {
+	r := pos.RiskPerUnit()
+	if r <= 0 || pos.favorableMove() < p.Config.TriggerR*r {
+		return nil
+	}
+	offset := pos.EntryPrice * p.Config.OffsetPct / 100
+	stop := pos.EntryPrice + offset
+	if pos.Side == "short" {
+		stop = pos.EntryPrice - offset
+	}
+	return &Action{
+		StopPrice: stop,
+		Reason:    fmt.Sprintf("浮盈达到 %.1fR", p.Config.TriggerR),
+	}
+}
+
+// ProfitLockPolicy 阶梯锁定利润：峰值收益越过某一档后，止损至少锁定该档收益
+type ProfitLockPolicy struct {
+	Steps []ProfitLockStep
+}
+
+func (p *ProfitLockPolicy) Name() string { return "阶梯锁利" }
+
+func (p *ProfitLockPolicy) Evaluate(pos Position, now time.Time) *Action {
+	var best *ProfitLockStep
+	for i := range p.Steps {
+		step := &p.Steps[i]
+		if pos.PeakPnLPct >= step.TriggerPct && (best == nil || step.LockPct > best.LockPct) {
+			best = step
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return &Action{
+		StopPrice: pos.priceAtPnLPct(best.LockPct),
+		Reason:    fmt.Sprintf("收益达到 %.2f%%，锁定 %.2f%%", best.TriggerPct, best.LockPct),
+	}
+}
+
+// TimeExitPolicy 持仓超时平仓
+type TimeExitPolicy struct {
+	Config TimeExitConfig
+}
+
+func (p *TimeExitPolicy) Name() string { return "超时平仓" }
+
+func (p *TimeExitPolicy) Evaluate(pos Position, now time.Time) *Action {
+	if pos.OpenedAt.IsZero() {
+		return nil
+	}
+	held := now.Sub(pos.OpenedAt)
+	if held < time.Duration(p.Config.MaxHoldMinutes)*time.Minute {
+		return nil
+	}
+	pnlPct := pos.PnLPct()
+	if p.Config.MinProfitPct != 0 && pnlPct >= p.Config.MinProfitPct {
+		return nil
+	}
+	return &Action{
+		Close:  true,
+		Reason: fmt.Sprintf("已持仓 %.0f 分钟（上限 %d），当前收益 %.2f%%", held.Minutes(), p.Config.MaxHoldMinutes, pnlPct),
+	}
+}
@@ -5,8 +5,12 @@ import (
 	"fmt"
 	"io/ioutil"
 	"math"
+	"nofx/mcp"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -27,8 +31,25 @@ type DecisionRecord struct {
 	ErrorMessage   string             `json:"error_message"`   // 错误信息（如果有）
 	// AIRequestDurationMs 记录 AI API 调用耗时（毫秒），方便评估调用性能
 	AIRequestDurationMs int64 `json:"ai_request_duration_ms,omitempty"`
+
+	// Agent 模式：多轮数据查询的完整对话记录与消耗
+	AgentTranscript []mcp.Message `json:"agent_transcript,omitempty"`
+	AgentTurns      int           `json:"agent_turns,omitempty"`
+	AgentTokens     int           `json:"agent_tokens,omitempty"`
+
+	// Source 决策来源：为空表示AI决策周期，manual 表示通过 API 手动执行的操作，approval 表示提案审批结果，breaker 表示熔断平仓
+	Source string `json:"source,omitempty"`
+	// Note 非AI操作的备注（操作员备注、审批备注或熔断原因），与AI思维链 CoTTrace 分开保存
+	Note string `json:"note,omitempty"`
 }
 
+// 决策来源标记
+const (
+	DecisionSourceManual   = "manual"   // 通过 API 手动执行的操作
+	DecisionSourceApproval = "approval" // 审批模式下提案的处理结果（批准执行/拒绝/过期）
+	DecisionSourceBreaker  = "breaker"  // 熔断触发时的自动平仓
+)
+
 // AccountSnapshot 账户状态快照
 type AccountSnapshot struct {
 	TotalBalance          float64 `json:"total_balance"`
@@ -53,15 +74,17 @@ type PositionSnapshot struct {
 
 // DecisionAction 决策动作
 type DecisionAction struct {
-	Action    string    `json:"action"`    // open_long, open_short, close_long, close_short, update_stop_loss, update_take_profit, partial_close
-	Symbol    string    `json:"symbol"`    // 币种
-	Quantity  float64   `json:"quantity"`  // 数量（部分平仓时使用）
-	Leverage  int       `json:"leverage"`  // 杠杆（开仓时）
-	Price     float64   `json:"price"`     // 执行价格
-	OrderID   int64     `json:"order_id"`  // 订单ID
-	Timestamp time.Time `json:"timestamp"` // 执行时间
-	Success   bool      `json:"success"`   // 是否成功
-	Error     string    `json:"error"`     // 错误信息
+	Action     string    `json:"action"`                // open_long, open_short, close_long, close_short, update_stop_loss, update_take_profit, partial_close
+	Symbol     string    `json:"symbol"`                // 币种
+	Quantity   float64   `json:"quantity"`              // 数量（部分平仓时使用）
+	Leverage   int       `json:"leverage"`              // 杠杆（开仓时）
+	Price      float64   `json:"price"`                 // 执行价格
+	OrderID    int64     `json:"order_id"`              // 订单ID
+	Timestamp  time.Time `json:"timestamp"`             // 执行时间
+	Success    bool      `json:"success"`               // 是否成功
+	Error      string    `json:"error"`                 // 错误信息
+	Source     string    `json:"source,omitempty"`      // 来源：为空表示AI，manual 表示手动操作
+	ProposalID string    `json:"proposal_id,omitempty"` // 审批模式下对应的提案ID
 }
 
 // IDecisionLogger 决策日志记录器接口
@@ -83,6 +106,7 @@ type IDecisionLogger interface {
 // DecisionLogger 决策日志记录器
 type DecisionLogger struct {
 	logDir      string
+	mu          sync.Mutex // 手动操作与决策周期可能并发写入
 	cycleNumber int
 }
 
@@ -104,12 +128,35 @@ func NewDecisionLogger(logDir string) IDecisionLogger {
 
 	return &DecisionLogger{
 		logDir:      logDir,
-		cycleNumber: 0,
+		cycleNumber: latestCycleNumber(logDir),
+	}
+}
+
+// latestCycleNumber 从已有文件名（decision_YYYYMMDD_HHMMSS_cycleN.json）中读取最大周期编号，重启后继续递增
+func latestCycleNumber(logDir string) int {
+	files, err := filepath.Glob(filepath.Join(logDir, "decision_*_cycle*.json"))
+	if err != nil {
+		return 0
 	}
+	latest := 0
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".json")
+		idx := strings.LastIndex(name, "_cycle")
+		if idx < 0 {
+			continue
+		}
+		if n, err := strconv.Atoi(name[idx+len("_cycle"):]); err == nil && n > latest {
+			latest = n
+		}
+	}
+	return latest
 }
 
 // LogDecision 记录决策
 func (l *DecisionLogger) LogDecision(record *DecisionRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
 	l.cycleNumber++
 	record.CycleNumber = l.cycleNumber
 	record.Timestamp = time.Now()
@@ -214,7 +261,8 @@ func (l *DecisionLogger) CleanOldRecords(days int) error {
 
 	removedCount := 0
 	for _, file := range files {
-		if file.IsDir() {
+		// 只清理 JSON 记录文件（同目录下可能有 SQLite 存储文件）
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
 			continue
 		}
 
@@ -297,20 +345,22 @@ type Statistics struct {
 
 // TradeOutcome 单笔交易结果
 type TradeOutcome struct {
-	Symbol        string    `json:"symbol"`         // 币种
-	Side          string    `json:"side"`           // long/short
-	Quantity      float64   `json:"quantity"`       // 仓位数量
-	Leverage      int       `json:"leverage"`       // 杠杆倍数
-	OpenPrice     float64   `json:"open_price"`     // 开仓价
-	ClosePrice    float64   `json:"close_price"`    // 平仓价
-	PositionValue float64   `json:"position_value"` // 仓位价值（quantity × openPrice）
-	MarginUsed    float64   `json:"margin_used"`    // 保证金使用（positionValue / leverage）
-	PnL           float64   `json:"pn_l"`           // 盈亏（USDT）
-	PnLPct        float64   `json:"pn_l_pct"`       // 盈亏百分比（相对保证金）
-	Duration      string    `json:"duration"`       // 持仓时长
-	OpenTime      time.Time `json:"open_time"`      // 开仓时间
-	CloseTime     time.Time `json:"close_time"`     // 平仓时间
-	WasStopLoss   bool      `json:"was_stop_loss"`  // 是否止损
+	Symbol        string    `json:"symbol"`            // 币种
+	Side          string    `json:"side"`              // long/short
+	Quantity      float64   `json:"quantity"`          // 仓位数量
+	Leverage      int       `json:"leverage"`          // 杠杆倍数
+	OpenPrice     float64   `json:"open_price"`        // 开仓价
+	ClosePrice    float64   `json:"close_price"`       // 平仓价
+	PositionValue float64   `json:"position_value"`    // 仓位价值（quantity × openPrice）
+	MarginUsed    float64   `json:"margin_used"`       // 保证金使用（positionValue / leverage）
+	PnL           float64   `json:"pn_l"`              // 盈亏（USDT）
+	PnLPct        float64   `json:"pn_l_pct"`          // 盈亏百分比（相对保证金）
+	Duration      string    `json:"duration"`          // 持仓时长
+	OpenTime      time.Time `json:"open_time"`         // 开仓时间
+	CloseTime     time.Time `json:"close_time"`        // 平仓时间
+	WasStopLoss   bool      `json:"was_stop_loss"`     // 是否止损
+	Fees          float64   `json:"fees,omitempty"`    // 开平仓手续费（仅账本统计）
+	Funding       float64   `json:"funding,omitempty"` // 持仓期间资金费收支（仅账本统计）
 }
 
 // PerformanceAnalysis 交易表现分析
@@ -327,8 +377,15 @@ type PerformanceAnalysis struct {
 	SymbolStats   map[string]*SymbolPerformance `json:"symbol_stats"`   // 各币种表现
 	BestSymbol    string                        `json:"best_symbol"`    // 表现最好的币种
 	WorstSymbol   string                        `json:"worst_symbol"`   // 表现最差的币种
+	Source        string                        `json:"source"`         // 统计来源：ledger（交易所成交账本）/ decisions（决策日志推断）
 }
 
+// 交易表现统计来源
+const (
+	PerformanceSourceLedger    = "ledger"
+	PerformanceSourceDecisions = "decisions"
+)
+
 // SymbolPerformance 币种表现统计
 type SymbolPerformance struct {
 	Symbol        string  `json:"symbol"`         // 币种
@@ -347,25 +404,37 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 		return nil, fmt.Errorf("读取历史记录失败: %w", err)
 	}
 
+	// 为了避免开仓记录在窗口外导致匹配失败，需要先从所有历史记录中找出未平仓的持仓
+	// 获取更多历史记录来构建完整的持仓状态（使用更大的窗口）
+	allRecords, err := l.GetLatestRecords(lookbackCycles * 3) // 扩大3倍窗口
+	if err != nil {
+		allRecords = nil
+	}
+
+	return AnalyzeRecords(records, allRecords), nil
+}
+
+// AnalyzeRecords 根据决策记录（按时间正序）计算交易表现
+// allRecords 为更大窗口的历史记录，用于补全窗口外的开仓信息，可为 nil
+func AnalyzeRecords(records, allRecords []*DecisionRecord) *PerformanceAnalysis {
 	if len(records) == 0 {
 		return &PerformanceAnalysis{
 			RecentTrades: []TradeOutcome{},
 			SymbolStats:  make(map[string]*SymbolPerformance),
-		}, nil
+			Source:       PerformanceSourceDecisions,
+		}
 	}
 
 	analysis := &PerformanceAnalysis{
 		RecentTrades: []TradeOutcome{},
 		SymbolStats:  make(map[string]*SymbolPerformance),
+		Source:       PerformanceSourceDecisions,
 	}
 
 	// 追踪持仓状态：symbol_side -> {side, openPrice, openTime, quantity, leverage}
 	openPositions := make(map[string]map[string]interface{})
 
-	// 为了避免开仓记录在窗口外导致匹配失败，需要先从所有历史记录中找出未平仓的持仓
-	// 获取更多历史记录来构建完整的持仓状态（使用更大的窗口）
-	allRecords, err := l.GetLatestRecords(lookbackCycles * 3) // 扩大3倍窗口
-	if err == nil && len(allRecords) > len(records) {
+	if len(allRecords) > len(records) {
 		// 先从扩大的窗口中收集所有开仓记录
 		for _, record := range allRecords {
 			for _, action := range record.Decisions {
@@ -622,6 +691,12 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 		}
 	}
 
+	finalizeAnalysis(analysis, records)
+	return analysis
+}
+
+// finalizeAnalysis 根据已收集的交易结果计算胜率、盈亏比、币种统计和夏普比率
+func finalizeAnalysis(analysis *PerformanceAnalysis, records []*DecisionRecord) {
 	// 计算统计指标
 	if analysis.TotalTrades > 0 {
 		analysis.WinRate = (float64(analysis.WinningTrades) / float64(analysis.TotalTrades)) * 100
@@ -681,14 +756,12 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 	}
 
 	// 计算夏普比率（需要至少2个数据点）
-	analysis.SharpeRatio = l.calculateSharpeRatio(records)
-
-	return analysis, nil
+	analysis.SharpeRatio = calculateSharpeRatio(records)
 }
 
 // calculateSharpeRatio 计算夏普比率
 // 基于账户净值的变化计算风险调整后收益
-func (l *DecisionLogger) calculateSharpeRatio(records []*DecisionRecord) float64 {
+func calculateSharpeRatio(records []*DecisionRecord) float64 {
 	if len(records) < 2 {
 		return 0.0
 	}
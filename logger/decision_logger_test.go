@@ -0,0 +1,20 @@
+package logger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecisionLogger_ContinuesCycleNumber(t *testing.T) {
+	dir := t.TempDir()
+	l := NewDecisionLogger(dir)
+	require.NoError(t, l.LogDecision(&DecisionRecord{}))
+	require.NoError(t, l.LogDecision(&DecisionRecord{}))
+
+	// 模拟重启：周期编号从已有文件的最大值继续
+	record := &DecisionRecord{}
+	require.NoError(t, NewDecisionLogger(dir).LogDecision(record))
+	assert.Equal(t, 3, record.CycleNumber)
+}
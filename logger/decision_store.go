@@ -0,0 +1,565 @@
+package logger
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"nofx/mcp"
+
+	_ "modernc.org/sqlite"
+)
+
+// 决策日志存储类型（系统配置 decision_log_store）
+const (
+	DecisionLogStoreJSON   = "json"   // 每个周期一个 JSON 文件（默认）
+	DecisionLogStoreSQLite = "sqlite" // 单个 SQLite 文件，带时间/周期/币种/动作索引
+)
+
+// DecisionStoreFile SQLite 决策日志文件名（位于 logDir 下）
+const DecisionStoreFile = "decisions.db"
+
+// DecisionQuery 决策记录查询条件（零值字段表示不过滤）
+type DecisionQuery struct {
+	Start    time.Time // 起始时间（含）
+	End      time.Time // 结束时间（不含）
+	MinCycle int       // 最小周期编号（含）
+	MaxCycle int       // 最大周期编号（含）
+	Symbol   string    // 决策动作涉及的币种
+	Action   string    // 决策动作类型（open_long / close_short ...）
+	Limit    int       // 每页条数，<=0 表示不限制
+	Offset   int       // 跳过条数
+	Desc     bool      // 按时间倒序（最新的在前）
+	Summary  bool      // 只返回摘要（不含 prompt、思维链、Agent 对话），用于图表等大批量查询
+}
+
+// DecisionPage 分页查询结果
+type DecisionPage struct {
+	Records []*DecisionRecord `json:"records"`
+	Total   int               `json:"total"` // 满足条件的记录总数（不受分页影响）
+	Limit   int               `json:"limit"`
+	Offset  int               `json:"offset"`
+}
+
+// DecisionQuerier 支持条件查询的决策日志（可选能力，通过类型断言使用）
+type DecisionQuerier interface {
+	QueryDecisions(q DecisionQuery) (*DecisionPage, error)
+}
+
+// NewDecisionLoggerForStore 按存储类型创建决策日志记录器
+// SQLite 打开失败时回退到 JSON 文件存储，避免交易员无法启动
+func NewDecisionLoggerForStore(store, logDir string) IDecisionLogger {
+	if store == DecisionLogStoreSQLite {
+		sqliteLogger, err := NewSQLiteDecisionLogger(logDir)
+		if err == nil {
+			return sqliteLogger
+		}
+		fmt.Printf("⚠️ 打开SQLite决策日志失败，回退到JSON文件: %v\n", err)
+	}
+	return NewDecisionLogger(logDir)
+}
+
+// matches 判断记录是否满足查询条件
+func (q DecisionQuery) matches(record *DecisionRecord) bool {
+	if !q.Start.IsZero() && record.Timestamp.Before(q.Start) {
+		return false
+	}
+	if !q.End.IsZero() && !record.Timestamp.Before(q.End) {
+		return false
+	}
+	if q.MinCycle > 0 && record.CycleNumber < q.MinCycle {
+		return false
+	}
+	if q.MaxCycle > 0 && record.CycleNumber > q.MaxCycle {
+		return false
+	}
+	if q.Symbol == "" && q.Action == "" {
+		return true
+	}
+	for _, action := range record.Decisions {
+		if (q.Symbol == "" || action.Symbol == q.Symbol) && (q.Action == "" || action.Action == q.Action) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordDetails 决策记录中体积较大的文本部分（摘要查询时不读取）
+type recordDetails struct {
+	SystemPrompt    string        `json:"system_prompt,omitempty"`
+	InputPrompt     string        `json:"input_prompt,omitempty"`
+	CoTTrace        string        `json:"cot_trace,omitempty"`
+	DecisionJSON    string        `json:"decision_json,omitempty"`
+	AgentTranscript []mcp.Message `json:"agent_transcript,omitempty"`
+}
+
+// summarize 返回去掉 prompt、思维链、Agent 对话后的记录副本
+func summarize(record *DecisionRecord) *DecisionRecord {
+	summary := *record
+	summary.SystemPrompt = ""
+	summary.InputPrompt = ""
+	summary.CoTTrace = ""
+	summary.DecisionJSON = ""
+	summary.AgentTranscript = nil
+	return &summary
+}
+
+// QueryDecisions 条件查询（JSON 文件存储需要读取全部文件，仅用于兼容；大量记录请使用 SQLite 存储）
+func (l *DecisionLogger) QueryDecisions(q DecisionQuery) (*DecisionPage, error) {
+	files, err := ioutil.ReadDir(l.logDir)
+	if err != nil {
+		return nil, fmt.Errorf("读取日志目录失败: %w", err)
+	}
+
+	var matched []*DecisionRecord
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(l.logDir, file.Name()))
+		if err != nil {
+			continue
+		}
+		var record DecisionRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			continue
+		}
+		if !q.matches(&record) {
+			continue
+		}
+		if q.Summary {
+			matched = append(matched, summarize(&record))
+		} else {
+			matched = append(matched, &record)
+		}
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		if q.Desc {
+			return matched[i].Timestamp.After(matched[j].Timestamp)
+		}
+		return matched[i].Timestamp.Before(matched[j].Timestamp)
+	})
+
+	page := &DecisionPage{Total: len(matched), Limit: q.Limit, Offset: q.Offset, Records: []*DecisionRecord{}}
+	if q.Offset < len(matched) {
+		matched = matched[q.Offset:]
+		if q.Limit > 0 && len(matched) > q.Limit {
+			matched = matched[:q.Limit]
+		}
+		page.Records = matched
+	}
+	return page, nil
+}
+
+// SQLiteDecisionLogger 基于 SQLite 的决策日志记录器
+//
+// 所有周期写入 logDir/decisions.db：
+//   - decision_records 每个周期一行，摘要与大文本（prompt/思维链）分列存储
+//   - decision_actions 每个决策动作一行，用于按币种/动作过滤
+//
+// 图表类查询只读取摘要列，无需解析完整记录
+type SQLiteDecisionLogger struct {
+	db          *sql.DB
+	path        string
+	mu          sync.Mutex // 保护 cycleNumber 与写入顺序
+	cycleNumber int
+}
+
+// NewSQLiteDecisionLogger 打开（或创建）logDir 下的 SQLite 决策日志
+// 周期编号从已有记录的最大值继续递增
+func NewSQLiteDecisionLogger(logDir string) (*SQLiteDecisionLogger, error) {
+	if logDir == "" {
+		logDir = "decision_logs"
+	}
+	if err := os.MkdirAll(logDir, 0700); err != nil {
+		return nil, fmt.Errorf("创建日志目录失败: %w", err)
+	}
+
+	path := filepath.Join(logDir, DecisionStoreFile)
+	// WAL 允许 API 读取与交易员写入并发进行；busy_timeout 避免迁移命令与交易员同时写入时报错
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("打开决策日志数据库失败: %w", err)
+	}
+
+	l := &SQLiteDecisionLogger{db: db, path: path}
+	if err := l.createTables(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("创建决策日志表失败: %w", err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		fmt.Printf("⚠ 设置决策日志文件权限失败: %v\n", err)
+	}
+
+	if err := db.QueryRow(`SELECT COALESCE(MAX(cycle_number), 0) FROM decision_records`).Scan(&l.cycleNumber); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("读取周期编号失败: %w", err)
+	}
+
+	return l, nil
+}
+
+// createTables 创建表与索引
+func (l *SQLiteDecisionLogger) createTables() error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS decision_records (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			timestamp INTEGER NOT NULL,
+			cycle_number INTEGER NOT NULL,
+			success INTEGER NOT NULL DEFAULT 0,
+			summary TEXT NOT NULL,
+			details TEXT NOT NULL DEFAULT '{}'
+		)`,
+		// 时间+周期唯一：重复导入同一 JSON 目录不会产生重复记录
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_decision_records_time_cycle ON decision_records(timestamp, cycle_number)`,
+		`CREATE INDEX IF NOT EXISTS idx_decision_records_cycle ON decision_records(cycle_number)`,
+		`CREATE TABLE IF NOT EXISTS decision_actions (
+			record_id INTEGER NOT NULL,
+			symbol TEXT NOT NULL,
+			action TEXT NOT NULL,
+			success INTEGER NOT NULL DEFAULT 0
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_decision_actions_record ON decision_actions(record_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_decision_actions_symbol ON decision_actions(symbol, record_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_decision_actions_action ON decision_actions(action, record_id)`,
+	}
+	for _, query := range queries {
+		if _, err := l.db.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close 关闭数据库
+func (l *SQLiteDecisionLogger) Close() error {
+	return l.db.Close()
+}
+
+// Path 返回数据库文件路径
+func (l *SQLiteDecisionLogger) Path() string {
+	return l.path
+}
+
+// LogDecision 记录决策
+func (l *SQLiteDecisionLogger) LogDecision(record *DecisionRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.cycleNumber++
+	record.CycleNumber = l.cycleNumber
+	record.Timestamp = time.Now()
+
+	if _, err := l.insert(record); err != nil {
+		return fmt.Errorf("写入决策记录失败: %w", err)
+	}
+
+	fmt.Printf("📝 决策记录已保存: %s (cycle %d)\n", DecisionStoreFile, record.CycleNumber)
+	return nil
+}
+
+// insert 写入一条记录（已存在相同时间+周期的记录时忽略），返回是否实际写入
+func (l *SQLiteDecisionLogger) insert(record *DecisionRecord) (bool, error) {
+	summary, err := json.Marshal(summarize(record))
+	if err != nil {
+		return false, fmt.Errorf("序列化决策记录失败: %w", err)
+	}
+	details, err := json.Marshal(recordDetails{
+		SystemPrompt:    record.SystemPrompt,
+		InputPrompt:     record.InputPrompt,
+		CoTTrace:        record.CoTTrace,
+		DecisionJSON:    record.DecisionJSON,
+		AgentTranscript: record.AgentTranscript,
+	})
+	if err != nil {
+		return false, fmt.Errorf("序列化决策记录失败: %w", err)
+	}
+
+	tx, err := l.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`INSERT OR IGNORE INTO decision_records (timestamp, cycle_number, success, summary, details)
+		VALUES (?, ?, ?, ?, ?)`,
+		record.Timestamp.UnixNano(), record.CycleNumber, record.Success, string(summary), string(details))
+	if err != nil {
+		return false, err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return false, nil
+	}
+	recordID, err := result.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+
+	for _, action := range record.Decisions {
+		if _, err := tx.Exec(`INSERT INTO decision_actions (record_id, symbol, action, success) VALUES (?, ?, ?, ?)`,
+			recordID, action.Symbol, action.Action, action.Success); err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// QueryDecisions 条件查询（按时间排序，支持分页）
+func (l *SQLiteDecisionLogger) QueryDecisions(q DecisionQuery) (*DecisionPage, error) {
+	var conditions []string
+	var args []interface{}
+
+	if !q.Start.IsZero() {
+		conditions = append(conditions, "r.timestamp >= ?")
+		args = append(args, q.Start.UnixNano())
+	}
+	if !q.End.IsZero() {
+		conditions = append(conditions, "r.timestamp < ?")
+		args = append(args, q.End.UnixNano())
+	}
+	if q.MinCycle > 0 {
+		conditions = append(conditions, "r.cycle_number >= ?")
+		args = append(args, q.MinCycle)
+	}
+	if q.MaxCycle > 0 {
+		conditions = append(conditions, "r.cycle_number <= ?")
+		args = append(args, q.MaxCycle)
+	}
+	if q.Symbol != "" || q.Action != "" {
+		// 同一个动作需同时满足币种与动作类型
+		sub := "EXISTS (SELECT 1 FROM decision_actions a WHERE a.record_id = r.id"
+		if q.Symbol != "" {
+			sub += " AND a.symbol = ?"
+			args = append(args, q.Symbol)
+		}
+		if q.Action != "" {
+			sub += " AND a.action = ?"
+			args = append(args, q.Action)
+		}
+		conditions = append(conditions, sub+")")
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	page := &DecisionPage{Limit: q.Limit, Offset: q.Offset, Records: []*DecisionRecord{}}
+	if err := l.db.QueryRow("SELECT COUNT(*) FROM decision_records r"+where, args...).Scan(&page.Total); err != nil {
+		return nil, fmt.Errorf("统计决策记录失败: %w", err)
+	}
+
+	columns := "r.summary, r.details"
+	if q.Summary {
+		columns = "r.summary, ''"
+	}
+	order := " ORDER BY r.timestamp ASC, r.id ASC"
+	if q.Desc {
+		order = " ORDER BY r.timestamp DESC, r.id DESC"
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = -1 // SQLite: LIMIT -1 表示不限制
+	}
+
+	rows, err := l.db.Query("SELECT "+columns+" FROM decision_records r"+where+order+" LIMIT ? OFFSET ?",
+		append(args, limit, q.Offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("查询决策记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var summary, details string
+		if err := rows.Scan(&summary, &details); err != nil {
+			return nil, fmt.Errorf("读取决策记录失败: %w", err)
+		}
+		record, err := decodeRecord(summary, details)
+		if err != nil {
+			continue
+		}
+		page.Records = append(page.Records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取决策记录失败: %w", err)
+	}
+	return page, nil
+}
+
+// decodeRecord 由摘要与大文本列还原记录（details 为空时只返回摘要）
+func decodeRecord(summary, details string) (*DecisionRecord, error) {
+	var record DecisionRecord
+	if err := json.Unmarshal([]byte(summary), &record); err != nil {
+		return nil, err
+	}
+	if details == "" {
+		return &record, nil
+	}
+	var d recordDetails
+	if err := json.Unmarshal([]byte(details), &d); err != nil {
+		return nil, err
+	}
+	record.SystemPrompt = d.SystemPrompt
+	record.InputPrompt = d.InputPrompt
+	record.CoTTrace = d.CoTTrace
+	record.DecisionJSON = d.DecisionJSON
+	record.AgentTranscript = d.AgentTranscript
+	return &record, nil
+}
+
+// latest 获取最近N条记录（按时间正序）
+func (l *SQLiteDecisionLogger) latest(n int, summary bool) ([]*DecisionRecord, error) {
+	if n <= 0 {
+		return []*DecisionRecord{}, nil
+	}
+	page, err := l.QueryDecisions(DecisionQuery{Limit: n, Desc: true, Summary: summary})
+	if err != nil {
+		return nil, err
+	}
+	records := page.Records
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+	return records, nil
+}
+
+// LatestSummaries 获取最近N条记录（按时间正序），用于净值曲线等只需要账户快照的场景
+// SQLite 存储只读取摘要列；其他存储回退到 GetLatestRecords
+func LatestSummaries(decisionLogger IDecisionLogger, n int) ([]*DecisionRecord, error) {
+	if sqliteLogger, ok := decisionLogger.(*SQLiteDecisionLogger); ok {
+		return sqliteLogger.latest(n, true)
+	}
+	return decisionLogger.GetLatestRecords(n)
+}
+
+// GetLatestRecords 获取最近N条记录（按时间正序：从旧到新）
+func (l *SQLiteDecisionLogger) GetLatestRecords(n int) ([]*DecisionRecord, error) {
+	return l.latest(n, false)
+}
+
+// GetRecordByDate 获取指定日期（按 date 所在时区）的所有记录
+func (l *SQLiteDecisionLogger) GetRecordByDate(date time.Time) ([]*DecisionRecord, error) {
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	page, err := l.QueryDecisions(DecisionQuery{Start: start, End: start.AddDate(0, 0, 1)})
+	if err != nil {
+		return nil, err
+	}
+	return page.Records, nil
+}
+
+// CleanOldRecords 清理N天前的旧记录
+func (l *SQLiteDecisionLogger) CleanOldRecords(days int) error {
+	cutoff := time.Now().AddDate(0, 0, -days).UnixNano()
+
+	tx, err := l.db.Begin()
+	if err != nil {
+		return fmt.Errorf("清理旧记录失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM decision_actions WHERE record_id IN (SELECT id FROM decision_records WHERE timestamp < ?)`, cutoff); err != nil {
+		return fmt.Errorf("清理旧记录失败: %w", err)
+	}
+	result, err := tx.Exec(`DELETE FROM decision_records WHERE timestamp < ?`, cutoff)
+	if err != nil {
+		return fmt.Errorf("清理旧记录失败: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("清理旧记录失败: %w", err)
+	}
+
+	if removed, _ := result.RowsAffected(); removed > 0 {
+		fmt.Printf("🗑️ 已清理 %d 条旧记录（%d天前）\n", removed, days)
+	}
+	return nil
+}
+
+// GetStatistics 获取统计信息
+func (l *SQLiteDecisionLogger) GetStatistics() (*Statistics, error) {
+	stats := &Statistics{}
+	if err := l.db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(success), 0) FROM decision_records`).
+		Scan(&stats.TotalCycles, &stats.SuccessfulCycles); err != nil {
+		return nil, fmt.Errorf("统计决策记录失败: %w", err)
+	}
+	stats.FailedCycles = stats.TotalCycles - stats.SuccessfulCycles
+
+	// partial_close / update_stop_loss / update_take_profit 不计入统计（与 JSON 存储一致）
+	if err := l.db.QueryRow(`SELECT
+			COALESCE(SUM(CASE WHEN action IN ('open_long', 'open_short') THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN action IN ('close_long', 'close_short', 'auto_close_long', 'auto_close_short') THEN 1 ELSE 0 END), 0)
+		FROM decision_actions WHERE success = 1`).
+		Scan(&stats.TotalOpenPositions, &stats.TotalClosePositions); err != nil {
+		return nil, fmt.Errorf("统计决策动作失败: %w", err)
+	}
+	return stats, nil
+}
+
+// AnalyzePerformance 分析最近N个周期的交易表现（只读取摘要）
+func (l *SQLiteDecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAnalysis, error) {
+	records, err := l.latest(lookbackCycles, true)
+	if err != nil {
+		return nil, fmt.Errorf("读取历史记录失败: %w", err)
+	}
+
+	// 扩大3倍窗口补全窗口外的开仓记录
+	allRecords, err := l.latest(lookbackCycles*3, true)
+	if err != nil {
+		allRecords = nil
+	}
+
+	return AnalyzeRecords(records, allRecords), nil
+}
+
+// ImportJSONDir 将 JSON 文件存储目录（decision_*.json）导入 SQLite 存储
+// 已存在相同时间+周期的记录会被跳过，可重复执行
+func (l *SQLiteDecisionLogger) ImportJSONDir(dir string) (imported, skipped int, err error) {
+	files, err := filepath.Glob(filepath.Join(dir, "decision_*.json"))
+	if err != nil {
+		return 0, 0, fmt.Errorf("查找日志文件失败: %w", err)
+	}
+	sort.Strings(files)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			fmt.Printf("⚠️ 读取 %s 失败: %v\n", file, err)
+			skipped++
+			continue
+		}
+		var record DecisionRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			fmt.Printf("⚠️ 解析 %s 失败: %v\n", file, err)
+			skipped++
+			continue
+		}
+
+		inserted, err := l.insert(&record)
+		if err != nil {
+			return imported, skipped, fmt.Errorf("导入 %s 失败: %w", file, err)
+		}
+		if !inserted {
+			skipped++
+			continue
+		}
+		imported++
+		if record.CycleNumber > l.cycleNumber {
+			l.cycleNumber = record.CycleNumber
+		}
+	}
+	return imported, skipped, nil
+}
@@ -0,0 +1,168 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSQLiteLogger(t *testing.T) *SQLiteDecisionLogger {
+	l, err := NewSQLiteDecisionLogger(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+func TestSQLiteDecisionLogger_LogAndQuery(t *testing.T) {
+	l := newTestSQLiteLogger(t)
+
+	actions := [][]DecisionAction{
+		{{Action: "open_long", Symbol: "BTCUSDT", Price: 100, Quantity: 1, Leverage: 5, Success: true}},
+		{{Action: "hold", Symbol: "BTCUSDT"}, {Action: "open_short", Symbol: "ETHUSDT", Price: 10, Quantity: 2, Leverage: 5, Success: true}},
+		{{Action: "close_long", Symbol: "BTCUSDT", Price: 110, Success: true}},
+		{},
+	}
+	for i, decisions := range actions {
+		require.NoError(t, l.LogDecision(&DecisionRecord{
+			SystemPrompt: "system", CoTTrace: fmt.Sprintf("思维链 %d", i),
+			AccountState: AccountSnapshot{TotalBalance: float64(1000 + i)},
+			Decisions:    decisions,
+			Success:      i != 3,
+		}))
+	}
+
+	// 最近记录按时间正序，包含完整内容
+	latest, err := l.GetLatestRecords(2)
+	require.NoError(t, err)
+	require.Len(t, latest, 2)
+	assert.Equal(t, 3, latest[0].CycleNumber)
+	assert.Equal(t, 4, latest[1].CycleNumber)
+	assert.Equal(t, "思维链 2", latest[0].CoTTrace)
+
+	// 按币种 + 动作过滤
+	page, err := l.QueryDecisions(DecisionQuery{Symbol: "BTCUSDT"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, page.Total)
+	page, err = l.QueryDecisions(DecisionQuery{Symbol: "BTCUSDT", Action: "open_short"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, page.Total, "币种与动作需匹配同一个决策动作")
+	page, err = l.QueryDecisions(DecisionQuery{Action: "open_short"})
+	require.NoError(t, err)
+	require.Len(t, page.Records, 1)
+	assert.Equal(t, 2, page.Records[0].CycleNumber)
+
+	// 周期范围 + 倒序分页 + 摘要
+	page, err = l.QueryDecisions(DecisionQuery{MinCycle: 2, Limit: 2, Offset: 1, Desc: true, Summary: true})
+	require.NoError(t, err)
+	assert.Equal(t, 3, page.Total)
+	require.Len(t, page.Records, 2)
+	assert.Equal(t, 3, page.Records[0].CycleNumber)
+	assert.Equal(t, 2, page.Records[1].CycleNumber)
+	assert.Empty(t, page.Records[0].CoTTrace)
+	assert.Equal(t, 1001.0, page.Records[1].AccountState.TotalBalance)
+
+	// 时间范围
+	page, err = l.QueryDecisions(DecisionQuery{Start: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	assert.Equal(t, 0, page.Total)
+
+	stats, err := l.GetStatistics()
+	require.NoError(t, err)
+	assert.Equal(t, &Statistics{TotalCycles: 4, SuccessfulCycles: 3, FailedCycles: 1, TotalOpenPositions: 2, TotalClosePositions: 1}, stats)
+
+	analysis, err := l.AnalyzePerformance(10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, analysis.TotalTrades)
+	assert.InDelta(t, 10.0, analysis.RecentTrades[0].PnL, 1e-9)
+}
+
+func TestSQLiteDecisionLogger_ContinuesCycleNumber(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewSQLiteDecisionLogger(dir)
+	require.NoError(t, err)
+	require.NoError(t, l.LogDecision(&DecisionRecord{}))
+	require.NoError(t, l.LogDecision(&DecisionRecord{}))
+	require.NoError(t, l.Close())
+
+	reopened, err := NewSQLiteDecisionLogger(dir)
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	record := &DecisionRecord{}
+	require.NoError(t, reopened.LogDecision(record))
+	assert.Equal(t, 3, record.CycleNumber)
+}
+
+func TestSQLiteDecisionLogger_CleanOldRecords(t *testing.T) {
+	l := newTestSQLiteLogger(t)
+	old := &DecisionRecord{
+		Timestamp: time.Now().AddDate(0, 0, -10), CycleNumber: 1,
+		Decisions: []DecisionAction{{Action: "open_long", Symbol: "BTCUSDT", Success: true}},
+	}
+	_, err := l.insert(old)
+	require.NoError(t, err)
+	require.NoError(t, l.LogDecision(&DecisionRecord{}))
+
+	require.NoError(t, l.CleanOldRecords(7))
+
+	stats, err := l.GetStatistics()
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.TotalCycles)
+	assert.Equal(t, 0, stats.TotalOpenPositions, "旧记录的决策动作应一并删除")
+}
+
+func TestSQLiteDecisionLogger_ImportJSONDir(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	for i := 1; i <= 3; i++ {
+		record := DecisionRecord{
+			Timestamp: base.Add(time.Duration(i) * 3 * time.Minute), CycleNumber: i,
+			InputPrompt: "prompt", Success: true,
+			Decisions: []DecisionAction{{Action: "wait", Symbol: "SOLUSDT"}},
+		}
+		data, err := json.Marshal(record)
+		require.NoError(t, err)
+		name := fmt.Sprintf("decision_%s_cycle%d.json", record.Timestamp.Format("20060102_150405"), i)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0600))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "decision_broken_cycle9.json"), []byte("{bad"), 0600))
+
+	l, err := NewSQLiteDecisionLogger(dir)
+	require.NoError(t, err)
+	defer l.Close()
+
+	imported, skipped, err := l.ImportJSONDir(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 3, imported)
+	assert.Equal(t, 1, skipped)
+
+	// 重复导入不会产生重复记录
+	imported, skipped, err = l.ImportJSONDir(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 0, imported)
+	assert.Equal(t, 4, skipped)
+
+	records, err := l.GetRecordByDate(base)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, "prompt", records[0].InputPrompt)
+	assert.True(t, records[0].Timestamp.Equal(base.Add(3*time.Minute)))
+
+	// 导入后新周期接着已有编号
+	record := &DecisionRecord{}
+	require.NoError(t, l.LogDecision(record))
+	assert.Equal(t, 4, record.CycleNumber)
+
+	// JSON 存储的条件查询与 SQLite 结果一致
+	page, err := NewDecisionLogger(dir).(*DecisionLogger).QueryDecisions(DecisionQuery{Symbol: "SOLUSDT", Limit: 2, Desc: true})
+	require.NoError(t, err)
+	assert.Equal(t, 3, page.Total)
+	require.Len(t, page.Records, 2)
+	assert.Equal(t, 3, page.Records[0].CycleNumber)
+}
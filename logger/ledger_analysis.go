@@ -0,0 +1,220 @@
+package logger
+
+import (
+	"nofx/config"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ledgerPosition 从成交账本重建的持仓（开仓到完全平仓为一笔交易）
+type ledgerPosition struct {
+	symbol      string
+	side        string // long / short
+	openQty     float64
+	openValue   float64
+	remaining   float64
+	closeQty    float64
+	closeValue  float64
+	realizedPnL float64
+	fees        float64
+	funding     float64
+	openTime    time.Time
+	openOrderID string
+}
+
+// AnalyzeLedger 根据交易所成交账本（成交、手续费、资金费）计算交易表现
+// 同一币种同一方向从首次开仓到完全平仓算作一笔交易，盈亏 = 交易所已实现盈亏 - 手续费 + 资金费
+// records 用于补充杠杆、识别止损平仓和计算夏普比率，可为 nil
+func AnalyzeLedger(entries []*config.LedgerEntry, records []*DecisionRecord) *PerformanceAnalysis {
+	analysis := &PerformanceAnalysis{
+		RecentTrades: []TradeOutcome{},
+		SymbolStats:  make(map[string]*SymbolPerformance),
+		Source:       PerformanceSourceLedger,
+	}
+
+	sorted := make([]*config.LedgerEntry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+
+	aiOrderIDs := make(map[string]bool)
+	for _, record := range records {
+		for _, action := range record.Decisions {
+			if action.Success && action.OrderID != 0 {
+				aiOrderIDs[strconv.FormatInt(action.OrderID, 10)] = true
+			}
+		}
+	}
+
+	positions := make(map[string]*ledgerPosition)
+	for _, entry := range sorted {
+		if entry.EntryType == config.LedgerEntryFunding {
+			// 资金费按币种分摊到当前持仓；账本窗口外开的仓无法归属，忽略
+			var holders []*ledgerPosition
+			for _, pos := range positions {
+				if pos.symbol == entry.Symbol {
+					holders = append(holders, pos)
+				}
+			}
+			for _, pos := range holders {
+				pos.funding += entry.Amount / float64(len(holders))
+			}
+			continue
+		}
+
+		side := strings.ToLower(entry.PositionSide)
+		if side != "long" && side != "short" {
+			continue
+		}
+		key := entry.Symbol + "_" + side
+		isOpen := (side == "long") == (entry.Side == "BUY")
+
+		if isOpen {
+			pos, exists := positions[key]
+			if !exists {
+				pos = &ledgerPosition{
+					symbol:      entry.Symbol,
+					side:        side,
+					openTime:    entry.Time,
+					openOrderID: entry.OrderID,
+				}
+				positions[key] = pos
+			}
+			pos.openQty += entry.Quantity
+			pos.openValue += entry.Quantity * entry.Price
+			pos.remaining += entry.Quantity
+			pos.fees += ledgerFee(entry)
+			continue
+		}
+
+		pos, exists := positions[key]
+		if !exists {
+			continue // 开仓在账本窗口之外
+		}
+
+		quantity := entry.Quantity
+		if quantity > pos.remaining {
+			quantity = pos.remaining
+		}
+		pnl := entry.RealizedPnL
+		if pnl == 0 {
+			avgOpen := pos.openValue / pos.openQty
+			if side == "long" {
+				pnl = (entry.Price - avgOpen) * quantity
+			} else {
+				pnl = (avgOpen - entry.Price) * quantity
+			}
+		}
+
+		pos.closeQty += quantity
+		pos.closeValue += quantity * entry.Price
+		pos.realizedPnL += pnl
+		pos.fees += ledgerFee(entry)
+		pos.remaining -= quantity
+
+		if pos.remaining > pos.openQty*1e-6 {
+			continue // 部分平仓，等待后续成交
+		}
+
+		outcome := pos.outcome(entry.Time, lookupLeverage(records, pos.symbol, pos.side, pos.openTime))
+		outcome.WasStopLoss = len(aiOrderIDs) > 0 && !aiOrderIDs[entry.OrderID] && outcome.PnL < 0
+		addTradeOutcome(analysis, outcome)
+		delete(positions, key)
+	}
+
+	finalizeAnalysis(analysis, records)
+	return analysis
+}
+
+// outcome 将完全平仓的持仓转换为交易结果
+func (pos *ledgerPosition) outcome(closeTime time.Time, leverage int) TradeOutcome {
+	openPrice := pos.openValue / pos.openQty
+	closePrice := 0.0
+	if pos.closeQty > 0 {
+		closePrice = pos.closeValue / pos.closeQty
+	}
+
+	totalPnL := pos.realizedPnL - pos.fees + pos.funding
+	positionValue := pos.openValue
+	marginUsed := positionValue / float64(leverage)
+	pnlPct := 0.0
+	if marginUsed > 0 {
+		pnlPct = (totalPnL / marginUsed) * 100
+	}
+
+	return TradeOutcome{
+		Symbol:        pos.symbol,
+		Side:          pos.side,
+		Quantity:      pos.openQty,
+		Leverage:      leverage,
+		OpenPrice:     openPrice,
+		ClosePrice:    closePrice,
+		PositionValue: positionValue,
+		MarginUsed:    marginUsed,
+		PnL:           totalPnL,
+		PnLPct:        pnlPct,
+		Duration:      closeTime.Sub(pos.openTime).String(),
+		OpenTime:      pos.openTime,
+		CloseTime:     closeTime,
+		Fees:          pos.fees,
+		Funding:       pos.funding,
+	}
+}
+
+// ledgerFee 折算为USDT的手续费
+// 以BNB等非稳定币抵扣的手续费无法在此折算，计为0
+func ledgerFee(entry *config.LedgerEntry) float64 {
+	switch entry.FeeAsset {
+	case "", "USDT", "USDC", "USD":
+		return entry.Fee
+	default:
+		return 0
+	}
+}
+
+// lookupLeverage 从决策记录中查找开仓时使用的杠杆（找不到时按1倍计算）
+func lookupLeverage(records []*DecisionRecord, symbol, side string, openTime time.Time) int {
+	leverage := 1
+	deadline := openTime.Add(time.Minute) // 成交时间可能略晚于日志中的执行时间
+	for _, record := range records {
+		for _, action := range record.Decisions {
+			if !action.Success || action.Symbol != symbol || action.Action != "open_"+side || action.Leverage <= 0 {
+				continue
+			}
+			if action.Timestamp.After(deadline) {
+				return leverage
+			}
+			leverage = action.Leverage
+		}
+	}
+	return leverage
+}
+
+// addTradeOutcome 记录一笔已完成交易并更新汇总和币种统计
+func addTradeOutcome(analysis *PerformanceAnalysis, outcome TradeOutcome) {
+	analysis.RecentTrades = append(analysis.RecentTrades, outcome)
+	analysis.TotalTrades++
+	if outcome.PnL > 0 {
+		analysis.WinningTrades++
+		analysis.AvgWin += outcome.PnL
+	} else if outcome.PnL < 0 {
+		analysis.LosingTrades++
+		analysis.AvgLoss += outcome.PnL
+	}
+
+	stats, exists := analysis.SymbolStats[outcome.Symbol]
+	if !exists {
+		stats = &SymbolPerformance{Symbol: outcome.Symbol}
+		analysis.SymbolStats[outcome.Symbol] = stats
+	}
+	stats.TotalTrades++
+	stats.TotalPnL += outcome.PnL
+	if outcome.PnL > 0 {
+		stats.WinningTrades++
+	} else if outcome.PnL < 0 {
+		stats.LosingTrades++
+	}
+}
@@ -0,0 +1,93 @@
+package logger
+
+import (
+	"nofx/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzeLedger(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	trade := func(id, orderID, symbol, side, positionSide string, price, qty, fee, pnl float64, offset time.Duration) *config.LedgerEntry {
+		return &config.LedgerEntry{
+			EntryType: config.LedgerEntryTrade, ExchangeID: id, OrderID: orderID, Symbol: symbol,
+			Side: side, PositionSide: positionSide, Price: price, Quantity: qty,
+			Fee: fee, FeeAsset: "USDT", RealizedPnL: pnl, Time: base.Add(offset),
+		}
+	}
+	funding := func(id, symbol string, amount float64, offset time.Duration) *config.LedgerEntry {
+		return &config.LedgerEntry{
+			EntryType: config.LedgerEntryFunding, ExchangeID: id, Symbol: symbol, Amount: amount, Time: base.Add(offset),
+		}
+	}
+
+	entries := []*config.LedgerEntry{
+		// 窗口外开仓的平仓成交：无法配对，忽略
+		trade("0", "9", "SOLUSDT", "SELL", "LONG", 150, 10, 0.6, 20, 0),
+		// BTC 多单分两笔开仓、两笔平仓，中间收取资金费
+		trade("1", "100", "BTCUSDT", "BUY", "LONG", 50000, 0.1, 2, 0, time.Minute),
+		trade("2", "100", "BTCUSDT", "BUY", "LONG", 52000, 0.1, 2, 0, 2*time.Minute),
+		funding("f1", "BTCUSDT", -1.5, 8*time.Hour),
+		trade("3", "101", "BTCUSDT", "SELL", "LONG", 53000, 0.1, 2, 200, 9*time.Hour),
+		trade("4", "102", "BTCUSDT", "SELL", "LONG", 50000, 0.1, 2, -100, 10*time.Hour),
+		// ETH 空单被交易所止损单平掉（订单不在决策记录里）
+		trade("5", "200", "ETHUSDT", "SELL", "SHORT", 3000, 1, 1.2, 0, 11*time.Hour),
+		trade("6", "201", "ETHUSDT", "BUY", "SHORT", 3100, 1, 1.24, 0, 12*time.Hour),
+	}
+
+	records := []*DecisionRecord{
+		{Timestamp: base, Decisions: []DecisionAction{
+			{Action: "open_long", Symbol: "BTCUSDT", Leverage: 5, OrderID: 100, Timestamp: base.Add(time.Minute), Success: true},
+		}},
+		{Timestamp: base.Add(9 * time.Hour), Decisions: []DecisionAction{
+			{Action: "partial_close", Symbol: "BTCUSDT", OrderID: 101, Timestamp: base.Add(9 * time.Hour), Success: true},
+			{Action: "close_long", Symbol: "BTCUSDT", OrderID: 102, Timestamp: base.Add(10 * time.Hour), Success: true},
+		}},
+		{Timestamp: base.Add(11 * time.Hour), Decisions: []DecisionAction{
+			{Action: "open_short", Symbol: "ETHUSDT", Leverage: 10, OrderID: 200, Timestamp: base.Add(11 * time.Hour), Success: true},
+		}},
+	}
+
+	analysis := AnalyzeLedger(entries, records)
+	assert.Equal(t, PerformanceSourceLedger, analysis.Source)
+	require.Equal(t, 2, analysis.TotalTrades)
+	require.Len(t, analysis.RecentTrades, 2)
+
+	// 最新的交易在前
+	eth := analysis.RecentTrades[0]
+	assert.Equal(t, "ETHUSDT", eth.Symbol)
+	assert.Equal(t, "short", eth.Side)
+	assert.Equal(t, 10, eth.Leverage)
+	assert.InDelta(t, -100-1.2-1.24, eth.PnL, 1e-9) // 交易所未给出已实现盈亏时按均价计算
+	assert.True(t, eth.WasStopLoss)
+
+	btc := analysis.RecentTrades[1]
+	assert.Equal(t, "long", btc.Side)
+	assert.Equal(t, 5, btc.Leverage)
+	assert.InDelta(t, 0.2, btc.Quantity, 1e-9)
+	assert.InDelta(t, 51000, btc.OpenPrice, 1e-9)
+	assert.InDelta(t, 51500, btc.ClosePrice, 1e-9)
+	assert.InDelta(t, 8, btc.Fees, 1e-9)
+	assert.InDelta(t, -1.5, btc.Funding, 1e-9)
+	assert.InDelta(t, 100-8-1.5, btc.PnL, 1e-9)
+	assert.InDelta(t, 10200/5.0, btc.MarginUsed, 1e-9)
+	assert.False(t, btc.WasStopLoss)
+	assert.Equal(t, base.Add(time.Minute), btc.OpenTime)
+
+	assert.Equal(t, 1, analysis.WinningTrades)
+	assert.Equal(t, 1, analysis.LosingTrades)
+	assert.InDelta(t, 50.0, analysis.WinRate, 1e-9)
+	assert.Equal(t, "BTCUSDT", analysis.BestSymbol)
+	assert.Equal(t, "ETHUSDT", analysis.WorstSymbol)
+	assert.NotContains(t, analysis.SymbolStats, "SOLUSDT")
+}
+
+func TestAnalyzeLedgerEmpty(t *testing.T) {
+	analysis := AnalyzeLedger(nil, nil)
+	assert.Equal(t, 0, analysis.TotalTrades)
+	assert.Empty(t, analysis.RecentTrades)
+	assert.NotNil(t, analysis.SymbolStats)
+}
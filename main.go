@@ -8,9 +8,13 @@ import (
 	"nofx/auth"
 	"nofx/config"
 	"nofx/crypto"
+	"nofx/events"
 	"nofx/manager"
 	"nofx/market"
 	"nofx/pool"
+	"nofx/signer"
+	"nofx/telegram"
+	"nofx/webhook"
 	"os"
 	"os/signal"
 	"strconv"
@@ -23,19 +27,27 @@ import (
 // ConfigFile 配置文件结构，只包含需要同步到数据库的字段
 // TODO 现在与config.Config相同，未来会被替换， 现在为了兼容性不得不保留当前文件
 type ConfigFile struct {
-	BetaMode           bool                  `json:"beta_mode"`
-	APIServerPort      int                   `json:"api_server_port"`
-	UseDefaultCoins    bool                  `json:"use_default_coins"`
-	DefaultCoins       []string              `json:"default_coins"`
-	CoinPoolAPIURL     string                `json:"coin_pool_api_url"`
-	OITopAPIURL        string                `json:"oi_top_api_url"`
-	MaxDailyLoss       float64               `json:"max_daily_loss"`
-	MaxDrawdown        float64               `json:"max_drawdown"`
-	StopTradingMinutes int                   `json:"stop_trading_minutes"`
-	Leverage           config.LeverageConfig `json:"leverage"`
-	JWTSecret          string                `json:"jwt_secret"`
-	DataKLineTime      string                `json:"data_k_line_time"`
-	Log                *config.LogConfig     `json:"log"` // 日志配置
+	BetaMode           bool                     `json:"beta_mode"`
+	APIServerPort      int                      `json:"api_server_port"`
+	UseDefaultCoins    bool                     `json:"use_default_coins"`
+	DefaultCoins       []string                 `json:"default_coins"`
+	CoinPoolAPIURL     string                   `json:"coin_pool_api_url"`
+	OITopAPIURL        string                   `json:"oi_top_api_url"`
+	MaxDailyLoss       float64                  `json:"max_daily_loss"`
+	MaxDrawdown        float64                  `json:"max_drawdown"`
+	StopTradingMinutes int                      `json:"stop_trading_minutes"`
+	FlattenOnBreaker   bool                     `json:"flatten_on_breaker"` // 触发熔断时是否平掉所有持仓
+	AgentMaxTurns      int                      `json:"agent_max_turns"`    // Agent 决策模式每周期最多调用AI轮数
+	AgentMaxTokens     int                      `json:"agent_max_tokens"`   // Agent 决策模式每周期累计 token 上限
+	DecisionLogStore   string                   `json:"decision_log_store"` // 决策日志存储：json / sqlite
+	TelegramBotToken   string                   `json:"telegram_bot_token"` // 交互式 Telegram 机器人 Token（可选）
+	AdminEmails        []string                 `json:"admin_emails"`       // 可调用管理接口（如密钥轮换）的用户邮箱
+	DataKey            crypto.KeyProviderConfig `json:"data_key"`           // 数据加密密钥来源（env/file/vault/envelope）
+	Signer             signer.Config            `json:"signer"`             // 外部签名器（remote:/keystore: 私钥配置）
+	Leverage           config.LeverageConfig    `json:"leverage"`
+	JWTSecret          string                   `json:"jwt_secret"`
+	DataKLineTime      string                   `json:"data_k_line_time"`
+	Log                *config.LogConfig        `json:"log"` // 日志配置
 }
 
 // loadConfigFile 读取并解析config.json文件
@@ -61,6 +73,16 @@ func loadConfigFile() (*ConfigFile, error) {
 	return &configFile, nil
 }
 
+// newCryptoService 按 config.json 中的 data_key 配置创建加密服务
+func newCryptoService(configFile *ConfigFile) (*crypto.CryptoService, error) {
+	provider, err := crypto.NewKeyProvider(configFile.DataKey)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("🔑 数据加密密钥来源: %s", provider.Name())
+	return crypto.NewCryptoServiceWithProvider(configFile.DataKey.RSAKeyPath(), provider)
+}
+
 // syncConfigToDatabase 将配置同步到数据库
 func syncConfigToDatabase(database *config.Database, configFile *ConfigFile) error {
 	if configFile == nil {
@@ -79,6 +101,7 @@ func syncConfigToDatabase(database *config.Database, configFile *ConfigFile) err
 		"max_daily_loss":       fmt.Sprintf("%.1f", configFile.MaxDailyLoss),
 		"max_drawdown":         fmt.Sprintf("%.1f", configFile.MaxDrawdown),
 		"stop_trading_minutes": strconv.Itoa(configFile.StopTradingMinutes),
+		"flatten_on_breaker":   fmt.Sprintf("%t", configFile.FlattenOnBreaker),
 	}
 
 	// 同步default_coins（转换为JSON字符串存储）
@@ -97,6 +120,33 @@ func syncConfigToDatabase(database *config.Database, configFile *ConfigFile) err
 		configs["altcoin_leverage"] = strconv.Itoa(configFile.Leverage.AltcoinLeverage)
 	}
 
+	// 同步 Agent 决策模式预算
+	if configFile.AgentMaxTurns > 0 {
+		configs["agent_max_turns"] = strconv.Itoa(configFile.AgentMaxTurns)
+	}
+	if configFile.AgentMaxTokens > 0 {
+		configs["agent_max_tokens"] = strconv.Itoa(configFile.AgentMaxTokens)
+	}
+
+	// 同步决策日志存储类型
+	if configFile.DecisionLogStore != "" {
+		configs["decision_log_store"] = configFile.DecisionLogStore
+	}
+
+	// 同步 Telegram 机器人 Token
+	if configFile.TelegramBotToken != "" {
+		configs["telegram_bot_token"] = configFile.TelegramBotToken
+	}
+
+	// 同步管理员邮箱（JSON数组，始终写入：从配置中移除所有管理员时需同时撤销数据库中的旧管理员）
+	adminEmails := configFile.AdminEmails
+	if adminEmails == nil {
+		adminEmails = []string{}
+	}
+	if adminEmailsJSON, err := json.Marshal(adminEmails); err == nil {
+		configs["admin_emails"] = string(adminEmailsJSON)
+	}
+
 	// 如果JWT密钥不为空，也同步
 	if configFile.JWTSecret != "" {
 		configs["jwt_secret"] = configFile.JWTSecret
@@ -160,6 +210,27 @@ func main() {
 	// In Docker Compose, variables are injected by the runtime and this is harmless.
 	_ = godotenv.Load()
 
+	// 子命令：历史回测 / 决策回放 / 决策日志迁移 / 数据密钥轮换 / 远程签名服务（不启动服务）
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "backtest":
+			runBacktestCommand(os.Args[2:])
+			return
+		case "replay":
+			runReplayCommand(os.Args[2:])
+			return
+		case "migrate-decisions":
+			runMigrateDecisionsCommand(os.Args[2:])
+			return
+		case "rotate-key":
+			runRotateKeyCommand(os.Args[2:])
+			return
+		case "signer-server":
+			runSignerServerCommand(os.Args[2:])
+			return
+		}
+	}
+
 	// 初始化数据库配置
 	dbPath := "config.db"
 	if len(os.Args) > 1 {
@@ -181,7 +252,7 @@ func main() {
 
 	// 初始化加密服务
 	log.Printf("🔐 初始化加密服务...")
-	cryptoService, err := crypto.NewCryptoService("secrets/rsa_key")
+	cryptoService, err := newCryptoService(configFile)
 	if err != nil {
 		log.Fatalf("❌ 初始化加密服务失败: %v", err)
 	}
@@ -219,6 +290,9 @@ func main() {
 	}
 	auth.SetJWTSecret(jwtSecret)
 
+	// 外部签名器配置（交易所私钥字段为 remote:0x<地址> / keystore:<路径> 时使用）
+	signer.Configure(configFile.Signer)
+
 	// 管理员模式下需要管理员密码，缺失则退出
 
 	log.Printf("✓ 配置数据库初始化成功")
@@ -337,6 +411,22 @@ func main() {
 		log.Printf("🔌 使用默认端口: %d", apiPort)
 	}
 
+	// 启动 Webhook 分发器（向用户配置的外部系统推送交易事件）
+	webhookDispatcher := webhook.NewDispatcher(database)
+	webhookDispatcher.Start(events.Default())
+
+	// 启动交互式 Telegram 机器人（配置了 telegram_bot_token 时）
+	var telegramBot *telegram.Bot
+	if token, _ := database.GetSystemConfig("telegram_bot_token"); token != "" {
+		bot, err := telegram.NewBot(token, database, telegram.NewManagerController(traderManager, database))
+		if err != nil {
+			log.Printf("⚠️  启动 Telegram 机器人失败: %v", err)
+		} else {
+			telegramBot = bot
+			telegramBot.Start(events.Default())
+		}
+	}
+
 	// 创建并启动API服务器
 	apiServer := api.NewServer(traderManager, database, cryptoService, apiPort)
 	go func() {
@@ -374,7 +464,14 @@ func main() {
 		log.Println("✅ API 服务器已安全关闭")
 	}
 
-	// 步骤 3: 关闭数据库连接 (确保所有写入完成)
+	// 步骤 3: 停止 Webhook 分发器（等待进行中的投递写完日志）和 Telegram 机器人
+	log.Println("🔌 停止 Webhook 分发器...")
+	webhookDispatcher.Stop()
+	if telegramBot != nil {
+		telegramBot.Stop()
+	}
+
+	// 步骤 4: 关闭数据库连接 (确保所有写入完成)
 	log.Println("💾 关闭数据库连接...")
 	if err := database.Close(); err != nil {
 		log.Printf("❌ 关闭数据库失败: %v", err)
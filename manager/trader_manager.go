@@ -6,6 +6,11 @@ import (
 	"fmt"
 	"log"
 	"nofx/config"
+	"nofx/decision"
+	"nofx/exit"
+	"nofx/logger"
+	"nofx/market"
+	"nofx/risk"
 	"nofx/trader"
 	"sort"
 	"strconv"
@@ -223,9 +228,7 @@ func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		HyperliquidPrivateKey: "",
 		HyperliquidTestnet:    exchangeCfg.Testnet,
 		CoinPoolAPIURL:        effectiveCoinPoolURL,
-		UseQwen:               aiModelCfg.Provider == "qwen",
-		DeepSeekKey:           "",
-		QwenKey:               "",
+		AIAPIKey:              aiModelCfg.APIKey,
 		CustomAPIURL:          aiModelCfg.CustomAPIURL,    // 自定义API URL
 		CustomModelName:       aiModelCfg.CustomModelName, // 自定义模型名称
 		ScanInterval:          time.Duration(traderCfg.ScanIntervalMinutes) * time.Minute,
@@ -235,10 +238,21 @@ func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		MaxDailyLoss:          maxDailyLoss,
 		MaxDrawdown:           maxDrawdown,
 		StopTradingTime:       time.Duration(stopTradingMinutes) * time.Minute,
+		FlattenOnBreaker:      flattenOnBreaker(database),
+		RiskConfig:            buildRiskConfig(traderCfg, maxDailyLoss, maxDrawdown),
+		ExitPolicy:            buildExitPolicy(traderCfg),
 		IsCrossMargin:         traderCfg.IsCrossMargin,
 		DefaultCoins:          defaultCoins,
 		TradingCoins:          tradingCoins,
 		SystemPromptTemplate:  traderCfg.SystemPromptTemplate, // 系统提示词模板
+		DecisionMode:          traderCfg.DecisionMode,
+		ExecutionMode:         traderCfg.ExecutionMode,
+		AutoApproveNotional:   traderCfg.AutoApproveNotional,
+		LimitOrderTimeout:     time.Duration(traderCfg.LimitOrderTimeout) * time.Second,
+		LimitOrderFallback:    traderCfg.LimitOrderFallback,
+		MarketData:            buildMarketDataConfig(traderCfg),
+		AgentConfig:           buildAgentConfig(database),
+		DecisionLogStore:      decisionLogStore(database),
 	}
 
 	// 根据交易所类型设置API密钥
@@ -254,13 +268,6 @@ func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		traderConfig.AsterPrivateKey = exchangeCfg.AsterPrivateKey
 	}
 
-	// 根据AI模型设置API密钥
-	if aiModelCfg.Provider == "qwen" {
-		traderConfig.QwenKey = aiModelCfg.APIKey
-	} else if aiModelCfg.Provider == "deepseek" {
-		traderConfig.DeepSeekKey = aiModelCfg.APIKey
-	}
-
 	// 创建trader实例
 	at, err := trader.NewAutoTrader(traderConfig, database, userID)
 	if err != nil {
@@ -330,9 +337,7 @@ func (tm *TraderManager) AddTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		HyperliquidPrivateKey: "",
 		HyperliquidTestnet:    exchangeCfg.Testnet,
 		CoinPoolAPIURL:        effectiveCoinPoolURL,
-		UseQwen:               aiModelCfg.Provider == "qwen",
-		DeepSeekKey:           "",
-		QwenKey:               "",
+		AIAPIKey:              aiModelCfg.APIKey,
 		CustomAPIURL:          aiModelCfg.CustomAPIURL,    // 自定义API URL
 		CustomModelName:       aiModelCfg.CustomModelName, // 自定义模型名称
 		ScanInterval:          time.Duration(traderCfg.ScanIntervalMinutes) * time.Minute,
@@ -342,9 +347,20 @@ func (tm *TraderManager) AddTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		MaxDailyLoss:          maxDailyLoss,
 		MaxDrawdown:           maxDrawdown,
 		StopTradingTime:       time.Duration(stopTradingMinutes) * time.Minute,
+		FlattenOnBreaker:      flattenOnBreaker(database),
+		RiskConfig:            buildRiskConfig(traderCfg, maxDailyLoss, maxDrawdown),
+		ExitPolicy:            buildExitPolicy(traderCfg),
 		IsCrossMargin:         traderCfg.IsCrossMargin,
 		DefaultCoins:          defaultCoins,
 		TradingCoins:          tradingCoins,
+		DecisionMode:          traderCfg.DecisionMode,
+		ExecutionMode:         traderCfg.ExecutionMode,
+		AutoApproveNotional:   traderCfg.AutoApproveNotional,
+		LimitOrderTimeout:     time.Duration(traderCfg.LimitOrderTimeout) * time.Second,
+		LimitOrderFallback:    traderCfg.LimitOrderFallback,
+		MarketData:            buildMarketDataConfig(traderCfg),
+		AgentConfig:           buildAgentConfig(database),
+		DecisionLogStore:      decisionLogStore(database),
 	}
 
 	// 根据交易所类型设置API密钥
@@ -360,13 +376,6 @@ func (tm *TraderManager) AddTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		traderConfig.AsterPrivateKey = exchangeCfg.AsterPrivateKey
 	}
 
-	// 根据AI模型设置API密钥
-	if aiModelCfg.Provider == "qwen" {
-		traderConfig.QwenKey = aiModelCfg.APIKey
-	} else if aiModelCfg.Provider == "deepseek" {
-		traderConfig.DeepSeekKey = aiModelCfg.APIKey
-	}
-
 	// 创建trader实例
 	at, err := trader.NewAutoTrader(traderConfig, database, userID)
 	if err != nil {
@@ -1037,15 +1046,26 @@ func (tm *TraderManager) loadSingleTrader(traderCfg *config.TraderRecord, aiMode
 		CoinPoolAPIURL:       effectiveCoinPoolURL,
 		CustomAPIURL:         aiModelCfg.CustomAPIURL,    // 自定义API URL
 		CustomModelName:      aiModelCfg.CustomModelName, // 自定义模型名称
-		UseQwen:              aiModelCfg.Provider == "qwen",
+		AIAPIKey:             aiModelCfg.APIKey,
 		MaxDailyLoss:         maxDailyLoss,
 		MaxDrawdown:          maxDrawdown,
 		StopTradingTime:      time.Duration(stopTradingMinutes) * time.Minute,
+		FlattenOnBreaker:     flattenOnBreaker(database),
+		RiskConfig:           buildRiskConfig(traderCfg, maxDailyLoss, maxDrawdown),
+		ExitPolicy:           buildExitPolicy(traderCfg),
 		IsCrossMargin:        traderCfg.IsCrossMargin,
 		DefaultCoins:         defaultCoins,
 		TradingCoins:         tradingCoins,
 		SystemPromptTemplate: traderCfg.SystemPromptTemplate, // 系统提示词模板
 		HyperliquidTestnet:   exchangeCfg.Testnet,            // Hyperliquid测试网
+		DecisionMode:         traderCfg.DecisionMode,
+		ExecutionMode:        traderCfg.ExecutionMode,
+		AutoApproveNotional:  traderCfg.AutoApproveNotional,
+		LimitOrderTimeout:    time.Duration(traderCfg.LimitOrderTimeout) * time.Second,
+		LimitOrderFallback:   traderCfg.LimitOrderFallback,
+		MarketData:           buildMarketDataConfig(traderCfg),
+		AgentConfig:          buildAgentConfig(database),
+		DecisionLogStore:     decisionLogStore(database),
 	}
 
 	// 根据交易所类型设置API密钥
@@ -1061,13 +1081,6 @@ func (tm *TraderManager) loadSingleTrader(traderCfg *config.TraderRecord, aiMode
 		traderConfig.AsterPrivateKey = exchangeCfg.AsterPrivateKey
 	}
 
-	// 根据AI模型设置API密钥
-	if aiModelCfg.Provider == "qwen" {
-		traderConfig.QwenKey = aiModelCfg.APIKey
-	} else if aiModelCfg.Provider == "deepseek" {
-		traderConfig.DeepSeekKey = aiModelCfg.APIKey
-	}
-
 	// 创建trader实例
 	at, err := trader.NewAutoTrader(traderConfig, database, userID)
 	if err != nil {
@@ -1090,6 +1103,82 @@ func (tm *TraderManager) loadSingleTrader(traderCfg *config.TraderRecord, aiMode
 	return nil
 }
 
+// flattenOnBreaker 读取系统配置：触发熔断时是否平掉所有持仓
+func flattenOnBreaker(database *config.Database) bool {
+	if database == nil {
+		return false
+	}
+	value, _ := database.GetSystemConfig("flatten_on_breaker")
+	return value == "true"
+}
+
+// buildAgentConfig 读取系统配置：Agent 决策模式每周期的轮数/token 预算（未配置时使用默认值）
+func buildAgentConfig(database *config.Database) decision.AgentConfig {
+	cfg := decision.DefaultAgentConfig()
+	if database == nil {
+		return cfg
+	}
+	if value, _ := database.GetSystemConfig("agent_max_turns"); value != "" {
+		if turns, err := strconv.Atoi(value); err == nil && turns > 0 {
+			cfg.MaxTurns = turns
+		}
+	}
+	if value, _ := database.GetSystemConfig("agent_max_tokens"); value != "" {
+		if tokens, err := strconv.Atoi(value); err == nil && tokens >= 0 {
+			cfg.MaxTokens = tokens
+		}
+	}
+	return cfg
+}
+
+// decisionLogStore 读取系统配置：决策日志存储类型（未配置时使用 JSON 文件）
+func decisionLogStore(database *config.Database) string {
+	if database == nil {
+		return logger.DecisionLogStoreJSON
+	}
+	value, _ := database.GetSystemConfig("decision_log_store")
+	if value == "" {
+		return logger.DecisionLogStoreJSON
+	}
+	return value
+}
+
+// buildRiskConfig 构建交易员的风控配置
+// 默认值来自系统配置（最大日亏损/最大回撤），交易员的 risk_config 可覆盖任意字段
+func buildRiskConfig(traderCfg *config.TraderRecord, maxDailyLoss, maxDrawdown float64) risk.Config {
+	base := risk.DefaultConfig()
+	base.MaxDailyLossPct = maxDailyLoss
+	base.MaxDrawdownPct = maxDrawdown
+
+	riskCfg, err := risk.ParseConfig(traderCfg.RiskConfig, base)
+	if err != nil {
+		log.Printf("⚠️  交易员 %s 的风控配置无效，使用默认值: %v", traderCfg.Name, err)
+		return base
+	}
+	return riskCfg
+}
+
+// buildExitPolicy 构建交易员的持仓退出策略（交易员的 exit_policy 可覆盖默认策略）
+func buildExitPolicy(traderCfg *config.TraderRecord) exit.Config {
+	base := exit.DefaultConfig()
+	exitCfg, err := exit.ParseConfig(traderCfg.ExitPolicy, base)
+	if err != nil {
+		log.Printf("⚠️  交易员 %s 的退出策略配置无效，使用默认值: %v", traderCfg.Name, err)
+		return exit.DefaultConfig()
+	}
+	return exitCfg
+}
+
+// buildMarketDataConfig 构建交易员的行情K线周期和指标配置（配置无效时使用默认 3m/4h 数据）
+func buildMarketDataConfig(traderCfg *config.TraderRecord) market.DataConfig {
+	cfg, err := market.ParseDataConfig(traderCfg.MarketTimeframes, traderCfg.MarketIndicators)
+	if err != nil {
+		log.Printf("⚠️  交易员 %s 的行情数据配置无效，使用默认值: %v", traderCfg.Name, err)
+		return market.DataConfig{}
+	}
+	return cfg
+}
+
 // RemoveTrader 从内存中移除指定的trader（不影响数据库）
 // 用于更新trader配置时强制重新加载
 func (tm *TraderManager) RemoveTrader(traderID string) {
@@ -1101,3 +1190,99 @@ func (tm *TraderManager) RemoveTrader(traderID string) {
 		log.Printf("✓ Trader %s 已从内存中移除", traderID)
 	}
 }
+
+// GetUserTraders 获取用户的所有交易员（按创建顺序，必要时先加载到内存）
+func (tm *TraderManager) GetUserTraders(database *config.Database, userID string) ([]*trader.AutoTrader, error) {
+	if err := tm.LoadUserTraders(database, userID); err != nil {
+		log.Printf("⚠️ 加载用户 %s 的交易员失败: %v", userID, err)
+	}
+
+	records, err := database.GetTraders(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取交易员列表失败: %w", err)
+	}
+
+	traders := make([]*trader.AutoTrader, 0, len(records))
+	for _, record := range records {
+		if at, err := tm.GetTrader(record.ID); err == nil {
+			traders = append(traders, at)
+		}
+	}
+	return traders, nil
+}
+
+// getUserTrader 校验交易员归属并返回内存中的交易员
+func (tm *TraderManager) getUserTrader(database *config.Database, userID, traderID string) (*trader.AutoTrader, error) {
+	if _, _, _, err := database.GetTraderConfig(userID, traderID); err != nil {
+		return nil, fmt.Errorf("交易员不存在或无访问权限")
+	}
+	return tm.GetTrader(traderID)
+}
+
+// StartTrader 启动用户的交易员并更新数据库运行状态
+func (tm *TraderManager) StartTrader(database *config.Database, userID, traderID string) error {
+	at, err := tm.getUserTrader(database, userID, traderID)
+	if err != nil {
+		return err
+	}
+	if isRunning, _ := at.GetStatus()["is_running"].(bool); isRunning {
+		return fmt.Errorf("交易员已在运行中")
+	}
+
+	go func() {
+		log.Printf("▶️  启动交易员 %s (%s)", traderID, at.GetName())
+		if err := at.Run(); err != nil {
+			log.Printf("❌ 交易员 %s 运行错误: %v", at.GetName(), err)
+		}
+	}()
+
+	if err := database.UpdateTraderStatus(userID, traderID, true); err != nil {
+		log.Printf("⚠️  更新交易员状态失败: %v", err)
+	}
+	return nil
+}
+
+// StopTrader 停止用户的交易员并更新数据库运行状态
+func (tm *TraderManager) StopTrader(database *config.Database, userID, traderID string) error {
+	at, err := tm.getUserTrader(database, userID, traderID)
+	if err != nil {
+		return err
+	}
+	if isRunning, _ := at.GetStatus()["is_running"].(bool); !isRunning {
+		return fmt.Errorf("交易员已停止")
+	}
+
+	at.Stop()
+	if err := database.UpdateTraderStatus(userID, traderID, false); err != nil {
+		log.Printf("⚠️  更新交易员状态失败: %v", err)
+	}
+	log.Printf("⏹  交易员 %s 已停止", at.GetName())
+	return nil
+}
+
+// FlattenAll 平掉用户交易员的所有持仓（与 API 手动全部平仓相同，记录为手动操作）
+func (tm *TraderManager) FlattenAll(database *config.Database, userID, traderID, note string) (*logger.DecisionRecord, error) {
+	at, err := tm.getUserTrader(database, userID, traderID)
+	if err != nil {
+		return nil, err
+	}
+	return at.FlattenAll(note)
+}
+
+// ApproveProposal 批准用户交易员的提案并执行
+func (tm *TraderManager) ApproveProposal(database *config.Database, userID, traderID, proposalID string) (*trader.Proposal, error) {
+	at, err := tm.getUserTrader(database, userID, traderID)
+	if err != nil {
+		return nil, err
+	}
+	return at.ApproveProposal(proposalID)
+}
+
+// RejectProposal 拒绝用户交易员的提案
+func (tm *TraderManager) RejectProposal(database *config.Database, userID, traderID, proposalID, reason string) (*trader.Proposal, error) {
+	at, err := tm.getUserTrader(database, userID, traderID)
+	if err != nil {
+		return nil, err
+	}
+	return at.RejectProposal(proposalID, reason)
+}
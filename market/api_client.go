@@ -57,6 +57,11 @@ func (c *APIClient) GetExchangeInfo() (*ExchangeInfo, error) {
 }
 
 func (c *APIClient) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
+	return c.GetKlinesRange(symbol, interval, 0, 0, limit)
+}
+
+// GetKlinesRange 获取指定时间范围内的K线（startTime/endTime 为毫秒时间戳，0 表示不限制）
+func (c *APIClient) GetKlinesRange(symbol, interval string, startTime, endTime int64, limit int) ([]Kline, error) {
 	url := fmt.Sprintf("%s/fapi/v1/klines", baseURL)
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -67,6 +72,12 @@ func (c *APIClient) GetKlines(symbol, interval string, limit int) ([]Kline, erro
 	q.Add("symbol", symbol)
 	q.Add("interval", interval)
 	q.Add("limit", strconv.Itoa(limit))
+	if startTime > 0 {
+		q.Add("startTime", strconv.FormatInt(startTime, 10))
+	}
+	if endTime > 0 {
+		q.Add("endTime", strconv.FormatInt(endTime, 10))
+	}
 	req.URL.RawQuery = q.Encode()
 
 	resp, err := c.client.Do(req)
@@ -158,3 +169,102 @@ func (c *APIClient) GetCurrentPrice(symbol string) (float64, error) {
 
 	return price, nil
 }
+
+// GetOrderBook 获取订单簿深度（limit 可选 5/10/20/50/100/500/1000）
+func (c *APIClient) GetOrderBook(symbol string, limit int) (*OrderBook, error) {
+	url := fmt.Sprintf("%s/fapi/v1/depth", baseURL)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	q := req.URL.Query()
+	q.Add("symbol", symbol)
+	q.Add("limit", strconv.Itoa(limit))
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var depth struct {
+		LastUpdateID int64       `json:"lastUpdateId"`
+		Bids         [][2]string `json:"bids"`
+		Asks         [][2]string `json:"asks"`
+	}
+	if err := json.Unmarshal(body, &depth); err != nil {
+		log.Printf("获取订单簿失败,响应内容: %s", string(body))
+		return nil, err
+	}
+
+	return &OrderBook{
+		Symbol:       symbol,
+		LastUpdateID: depth.LastUpdateID,
+		Bids:         parsePriceLevels(depth.Bids),
+		Asks:         parsePriceLevels(depth.Asks),
+	}, nil
+}
+
+func parsePriceLevels(raw [][2]string) []PriceLevel {
+	levels := make([]PriceLevel, 0, len(raw))
+	for _, level := range raw {
+		price, _ := strconv.ParseFloat(level[0], 64)
+		qty, _ := strconv.ParseFloat(level[1], 64)
+		levels = append(levels, PriceLevel{Price: price, Quantity: qty})
+	}
+	return levels
+}
+
+// GetFundingRateHistory 获取最近 limit 次资金费率结算记录（按时间正序）
+func (c *APIClient) GetFundingRateHistory(symbol string, limit int) ([]FundingRateRecord, error) {
+	url := fmt.Sprintf("%s/fapi/v1/fundingRate", baseURL)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	q := req.URL.Query()
+	q.Add("symbol", symbol)
+	q.Add("limit", strconv.Itoa(limit))
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []struct {
+		FundingTime int64  `json:"fundingTime"`
+		FundingRate string `json:"fundingRate"`
+		MarkPrice   string `json:"markPrice"`
+	}
+	if err := json.Unmarshal(body, &items); err != nil {
+		log.Printf("获取资金费率历史失败,响应内容: %s", string(body))
+		return nil, err
+	}
+
+	records := make([]FundingRateRecord, 0, len(items))
+	for _, item := range items {
+		rate, _ := strconv.ParseFloat(item.FundingRate, 64)
+		markPrice, _ := strconv.ParseFloat(item.MarkPrice, 64)
+		records = append(records, FundingRateRecord{
+			FundingTime: item.FundingTime,
+			FundingRate: rate,
+			MarkPrice:   markPrice,
+		})
+	}
+	return records, nil
+}
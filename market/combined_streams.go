@@ -52,15 +52,33 @@ func (c *CombinedStreamsClient) Connect() error {
 
 // BatchSubscribeKlines 批量订阅K线
 func (c *CombinedStreamsClient) BatchSubscribeKlines(symbols []string, interval string) error {
+	return c.batchSubscribe(symbols, func(symbol string) []string {
+		return []string{fmt.Sprintf("%s@kline_%s", strings.ToLower(symbol), interval)}
+	})
+}
+
+// BatchSubscribeOrderFlow 批量订阅增量深度和归集成交
+func (c *CombinedStreamsClient) BatchSubscribeOrderFlow(symbols []string) error {
+	return c.batchSubscribe(symbols, orderFlowStreams)
+}
+
+// orderFlowStreams 币种的增量深度和归集成交流名称
+func orderFlowStreams(symbol string) []string {
+	lower := strings.ToLower(symbol)
+	return []string{lower + "@depth@100ms", lower + "@aggTrade"}
+}
+
+// batchSubscribe 按批次订阅每个币种的流
+func (c *CombinedStreamsClient) batchSubscribe(symbols []string, streamsFor func(symbol string) []string) error {
 	// 将symbols分批处理
 	batches := c.splitIntoBatches(symbols, c.batchSize)
 
 	for i, batch := range batches {
 		log.Printf("订阅第 %d 批, 数量: %d", i+1, len(batch))
 
-		streams := make([]string, len(batch))
-		for j, symbol := range batch {
-			streams[j] = fmt.Sprintf("%s@kline_%s", strings.ToLower(symbol), interval)
+		var streams []string
+		for _, symbol := range batch {
+			streams = append(streams, streamsFor(symbol)...)
 		}
 
 		if err := c.subscribeStreams(streams); err != nil {
@@ -148,11 +166,10 @@ func (c *CombinedStreamsClient) handleCombinedMessage(message []byte) {
 		return
 	}
 
+	// 持有读锁发送（非阻塞），避免与 RemoveSubscriber 关闭通道并发
 	c.mu.RLock()
-	ch, exists := c.subscribers[combinedMsg.Stream]
-	c.mu.RUnlock()
-
-	if exists {
+	defer c.mu.RUnlock()
+	if ch, exists := c.subscribers[combinedMsg.Stream]; exists {
 		select {
 		case ch <- combinedMsg.Data:
 		default:
@@ -169,6 +186,16 @@ func (c *CombinedStreamsClient) AddSubscriber(stream string, bufferSize int) <-c
 	return ch
 }
 
+// RemoveSubscriber 移除订阅者并关闭其通道（订阅失败时回滚，消费协程随之退出）
+func (c *CombinedStreamsClient) RemoveSubscriber(stream string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ch, exists := c.subscribers[stream]; exists {
+		delete(c.subscribers, stream)
+		close(ch)
+	}
+}
+
 func (c *CombinedStreamsClient) handleReconnect() {
 	if !c.reconnect {
 		return
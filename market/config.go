@@ -0,0 +1,159 @@
+package market
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 可配置的技术指标
+const (
+	IndicatorEMA        = "ema"        // EMA20 序列 + EMA50
+	IndicatorMACD       = "macd"       // MACD(12, 26)
+	IndicatorRSI        = "rsi"        // RSI7 / RSI14
+	IndicatorATR        = "atr"        // ATR14
+	IndicatorBollinger  = "bollinger"  // 布林带(20, 2)
+	IndicatorVWAP       = "vwap"       // 成交量加权均价
+	IndicatorStochastic = "stochastic" // 随机指标(14, 3, 3)
+	IndicatorADX        = "adx"        // ADX(14) 与 +DI/-DI
+	IndicatorOBV        = "obv"        // 能量潮
+	IndicatorSupertrend = "supertrend" // 超级趋势(10, 3)
+)
+
+// maxTimeframes 单个交易员最多配置的K线周期数（每个周期都需要单独订阅K线流）
+const maxTimeframes = 4
+
+// intervalDurations 支持的K线周期
+var intervalDurations = map[string]time.Duration{
+	"1m":  time.Minute,
+	"3m":  3 * time.Minute,
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"30m": 30 * time.Minute,
+	"1h":  time.Hour,
+	"2h":  2 * time.Hour,
+	"4h":  4 * time.Hour,
+	"6h":  6 * time.Hour,
+	"8h":  8 * time.Hour,
+	"12h": 12 * time.Hour,
+	"1d":  24 * time.Hour,
+	"3d":  72 * time.Hour,
+	"1w":  168 * time.Hour,
+}
+
+// SupportedIndicators 可配置的指标（按输出顺序）
+var SupportedIndicators = []string{
+	IndicatorEMA, IndicatorMACD, IndicatorRSI, IndicatorATR,
+	IndicatorBollinger, IndicatorVWAP, IndicatorStochastic, IndicatorADX, IndicatorOBV, IndicatorSupertrend,
+}
+
+// defaultIndicators 只配置了周期时使用的指标（与默认 3m/4h 数据一致）
+var defaultIndicators = []string{IndicatorEMA, IndicatorMACD, IndicatorRSI, IndicatorATR}
+
+// DataConfig 交易员的行情数据配置（为空时使用默认的 3m/4h 数据）
+type DataConfig struct {
+	Timeframes []string // K线周期，如 1m/15m/1h/1d（为空使用 3m/4h）
+	Indicators []string // 指标列表（为空使用 ema/macd/rsi/atr）
+}
+
+// ParseDataConfig 解析逗号分隔的K线周期和指标配置并校验
+func ParseDataConfig(timeframes, indicators string) (DataConfig, error) {
+	cfg := DataConfig{
+		Timeframes: splitList(timeframes, false),
+		Indicators: splitList(indicators, true),
+	}
+	if err := cfg.Validate(); err != nil {
+		return DataConfig{}, err
+	}
+	return cfg, nil
+}
+
+// splitList 拆分逗号分隔的列表（去除空白和重复项）
+func splitList(value string, lower bool) []string {
+	var items []string
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if lower {
+			item = strings.ToLower(item)
+		}
+		if item == "" || seen[item] {
+			continue
+		}
+		seen[item] = true
+		items = append(items, item)
+	}
+	return items
+}
+
+// Validate 校验K线周期和指标是否受支持
+func (c DataConfig) Validate() error {
+	if len(c.Timeframes) > maxTimeframes {
+		return fmt.Errorf("最多配置%d个K线周期", maxTimeframes)
+	}
+	for _, interval := range c.Timeframes {
+		if _, ok := intervalDurations[interval]; !ok {
+			return fmt.Errorf("不支持的K线周期: %s", interval)
+		}
+	}
+	for _, indicator := range c.Indicators {
+		if !isSupportedIndicator(indicator) {
+			return fmt.Errorf("不支持的指标: %s（可选: %s）", indicator, strings.Join(SupportedIndicators, ", "))
+		}
+	}
+	return nil
+}
+
+func isSupportedIndicator(indicator string) bool {
+	for _, supported := range SupportedIndicators {
+		if indicator == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDefault 未配置周期和指标
+func (c DataConfig) IsDefault() bool {
+	return len(c.Timeframes) == 0 && len(c.Indicators) == 0
+}
+
+// Has 是否需要计算指定指标
+func (c DataConfig) Has(indicator string) bool {
+	indicators := c.Indicators
+	if len(indicators) == 0 {
+		indicators = defaultIndicators
+	}
+	for _, item := range indicators {
+		if item == indicator {
+			return true
+		}
+	}
+	return false
+}
+
+// SortedTimeframes 实际使用的K线周期（从短到长，第一个周期决定当前价格）
+func (c DataConfig) SortedTimeframes() []string {
+	timeframes := c.Timeframes
+	if len(timeframes) == 0 {
+		timeframes = subKlineTime
+	}
+	sorted := append([]string(nil), timeframes...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return intervalDurations[sorted[i]] < intervalDurations[sorted[j]]
+	})
+	return sorted
+}
+
+// Describe 配置的可读描述（用于工具说明和日志）
+func (c DataConfig) Describe() string {
+	if c.IsDefault() {
+		return "3分钟/4小时序列、EMA、MACD、RSI、ATR"
+	}
+	indicators := c.Indicators
+	if len(indicators) == 0 {
+		indicators = defaultIndicators
+	}
+	return fmt.Sprintf("%s 周期，指标: %s", strings.Join(c.SortedTimeframes(), "/"), strings.Join(indicators, ", "))
+}
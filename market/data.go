@@ -56,6 +56,86 @@ func Get(symbol string) (*Data, error) {
 		return nil, fmt.Errorf("4小时K线数据为空")
 	}
 
+	// 获取OI数据
+	oiData, err := getOpenInterestData(symbol)
+	if err != nil {
+		// OI失败不影响整体,使用默认值
+		oiData = &OIData{Latest: 0, Average: 0}
+	}
+
+	// 获取Funding Rate
+	fundingRate, _ := getFundingRate(symbol)
+
+	data := BuildData(symbol, klines3m, klines4h, oiData, fundingRate)
+	// 盘口与成交流（首次查询时订阅，同步完成前为 nil）
+	data.OrderFlow = WSMonitorCli.GetOrderFlow(symbol)
+	return data, nil
+}
+
+// GetWithConfig 按交易员配置的K线周期和指标获取市场数据（配置为空时等同于 Get）
+// 未预加载的周期在首次查询时通过 REST 拉取历史K线并订阅实时流
+func GetWithConfig(symbol string, cfg DataConfig) (*Data, error) {
+	if cfg.IsDefault() {
+		return Get(symbol)
+	}
+
+	symbol = Normalize(symbol)
+	timeframes := cfg.SortedTimeframes()
+	klinesByInterval := make(map[string][]Kline, len(timeframes))
+	for _, interval := range timeframes {
+		klines, err := WSMonitorCli.GetCurrentKlines(symbol, interval)
+		if err != nil {
+			return nil, fmt.Errorf("获取%sK线失败: %v", interval, err)
+		}
+		if len(klines) == 0 {
+			return nil, fmt.Errorf("%sK线数据为空", interval)
+		}
+		klinesByInterval[interval] = klines
+	}
+
+	if isStaleData(klinesByInterval[timeframes[0]], symbol) {
+		log.Printf("⚠️  WARNING: %s detected stale data (consecutive price freeze), skipping symbol", symbol)
+		return nil, fmt.Errorf("%s data is stale, possible cache failure", symbol)
+	}
+
+	oiData, err := getOpenInterestData(symbol)
+	if err != nil {
+		oiData = &OIData{Latest: 0, Average: 0}
+	}
+	fundingRate, _ := getFundingRate(symbol)
+
+	data := BuildDataWithConfig(symbol, klinesByInterval, cfg, oiData, fundingRate)
+	data.OrderFlow = WSMonitorCli.GetOrderFlow(symbol)
+	return data, nil
+}
+
+// BuildDataWithConfig 根据配置的各周期K线计算市场数据
+// 调用方需保证每个配置周期的K线均不为空；当前价格和概览指标取最短周期
+func BuildDataWithConfig(symbol string, klinesByInterval map[string][]Kline, cfg DataConfig, oiData *OIData, fundingRate float64) *Data {
+	timeframes := cfg.SortedTimeframes()
+	primary := klinesByInterval[timeframes[0]]
+	currentPrice := primary[len(primary)-1].Close
+
+	data := &Data{
+		Symbol:        symbol,
+		CurrentPrice:  currentPrice,
+		PriceChange1h: priceChangeOver(timeframes, klinesByInterval, currentPrice, time.Hour),
+		PriceChange4h: priceChangeOver(timeframes, klinesByInterval, currentPrice, 4*time.Hour),
+		CurrentEMA20:  calculateEMA(primary, 20),
+		CurrentMACD:   calculateMACD(primary),
+		CurrentRSI7:   calculateRSI(primary, 7),
+		OpenInterest:  oiData,
+		FundingRate:   fundingRate,
+	}
+	for _, interval := range timeframes {
+		data.Timeframes = append(data.Timeframes, calculateTimeframeData(interval, klinesByInterval[interval], cfg))
+	}
+	return data
+}
+
+// BuildData 根据3分钟和4小时K线计算市场数据（实时行情与回测共用）
+// 调用方需保证两组K线均不为空
+func BuildData(symbol string, klines3m, klines4h []Kline, oiData *OIData, fundingRate float64) *Data {
 	// 计算当前指标 (基于3分钟最新数据)
 	currentPrice := klines3m[len(klines3m)-1].Close
 	currentEMA20 := calculateEMA(klines3m, 20)
@@ -81,16 +161,6 @@ func Get(symbol string) (*Data, error) {
 		}
 	}
 
-	// 获取OI数据
-	oiData, err := getOpenInterestData(symbol)
-	if err != nil {
-		// OI失败不影响整体,使用默认值
-		oiData = &OIData{Latest: 0, Average: 0}
-	}
-
-	// 获取Funding Rate
-	fundingRate, _ := getFundingRate(symbol)
-
 	// 计算日内系列数据
 	intradayData := calculateIntradaySeries(klines3m)
 
@@ -109,7 +179,7 @@ func Get(symbol string) (*Data, error) {
 		FundingRate:       fundingRate,
 		IntradaySeries:    intradayData,
 		LongerTermContext: longerTermData,
-	}, nil
+	}
 }
 
 // calculateEMA 计算EMA
@@ -270,6 +340,9 @@ func calculateIntradaySeries(klines []Kline) *IntradayData {
 		}
 	}
 
+	// 主动买卖量差（K线自带的 TakerBuyBaseVolume）
+	data.TakerDelta = calculateTakerDelta(klines[start:])
+
 	// 计算3m ATR14
 	data.ATR14 = calculateATR(klines, 14)
 
@@ -408,14 +481,24 @@ func getFundingRate(symbol string) (float64, error) {
 	return rate, nil
 }
 
+// GetFundingRate 获取指定币种的最新资金费率（带1小时缓存）
+func GetFundingRate(symbol string) (float64, error) {
+	return getFundingRate(Normalize(symbol))
+}
+
 // Format 格式化输出市场数据
 func Format(data *Data) string {
 	var sb strings.Builder
 
 	// 使用动态精度格式化价格
 	priceStr := formatPriceWithDynamicPrecision(data.CurrentPrice)
-	sb.WriteString(fmt.Sprintf("current_price = %s, current_ema20 = %.3f, current_macd = %.3f, current_rsi (7 period) = %.3f\n\n",
-		priceStr, data.CurrentEMA20, data.CurrentMACD, data.CurrentRSI7))
+	if len(data.Timeframes) > 0 {
+		// 按交易员配置输出：概览只保留价格，指标在各周期段落中
+		sb.WriteString(fmt.Sprintf("current_price = %s\n\n", priceStr))
+	} else {
+		sb.WriteString(fmt.Sprintf("current_price = %s, current_ema20 = %.3f, current_macd = %.3f, current_rsi (7 period) = %.3f\n\n",
+			priceStr, data.CurrentEMA20, data.CurrentMACD, data.CurrentRSI7))
+	}
 
 	sb.WriteString(fmt.Sprintf("In addition, here is the latest %s open interest and funding rate for perps:\n\n",
 		data.Symbol))
@@ -430,6 +513,21 @@ func Format(data *Data) string {
 
 	sb.WriteString(fmt.Sprintf("Funding Rate: %.2e\n\n", data.FundingRate))
 
+	if of := data.OrderFlow; of != nil {
+		sb.WriteString(fmt.Sprintf("Order book: best bid %s / best ask %s, spread %.2f bps\n\n",
+			formatPriceWithDynamicPrecision(of.BestBid), formatPriceWithDynamicPrecision(of.BestAsk), of.SpreadBps))
+		sb.WriteString(fmt.Sprintf("Top %d levels depth: bids %.0f USD vs asks %.0f USD (imbalance %+.2f, positive = bid heavy)\n\n",
+			of.DepthLevels, of.BidDepthUSD, of.AskDepthUSD, of.Imbalance))
+		if of.TradeWindow > 0 {
+			sb.WriteString(fmt.Sprintf("Taker flow (last %s): buy %.0f USD vs sell %.0f USD, delta %+.0f USD\n\n",
+				of.TradeWindow.Round(time.Second), of.TakerBuyUSD, of.TakerSellUSD, of.TakerDeltaUSD))
+		}
+	}
+
+	for _, tf := range data.Timeframes {
+		formatTimeframe(&sb, tf)
+	}
+
 	if data.IntradaySeries != nil {
 		sb.WriteString("Intraday series (3‑minute intervals, oldest → latest):\n\n")
 
@@ -457,6 +555,10 @@ func Format(data *Data) string {
 			sb.WriteString(fmt.Sprintf("Volume: %s\n\n", formatFloatSlice(data.IntradaySeries.Volume)))
 		}
 
+		if len(data.IntradaySeries.TakerDelta) > 0 {
+			sb.WriteString(fmt.Sprintf("Taker buy − sell volume: %s\n\n", formatFloatSlice(data.IntradaySeries.TakerDelta)))
+		}
+
 		sb.WriteString(fmt.Sprintf("3m ATR (14‑period): %.3f\n\n", data.IntradaySeries.ATR14))
 	}
 
@@ -484,6 +586,63 @@ func Format(data *Data) string {
 	return sb.String()
 }
 
+// formatTimeframe 输出单个周期的序列和指标（未计算的指标不输出）
+func formatTimeframe(sb *strings.Builder, tf *TimeframeData) {
+	sb.WriteString(fmt.Sprintf("Series (%s intervals, oldest → latest):\n\n", tf.Interval))
+
+	if len(tf.Closes) > 0 {
+		sb.WriteString(fmt.Sprintf("Close prices: %s\n\n", formatFloatSlice(tf.Closes)))
+	}
+	if len(tf.Volume) > 0 {
+		sb.WriteString(fmt.Sprintf("Volume: %s\n\n", formatFloatSlice(tf.Volume)))
+	}
+	if len(tf.TakerDelta) > 0 {
+		sb.WriteString(fmt.Sprintf("Taker buy − sell volume: %s\n\n", formatFloatSlice(tf.TakerDelta)))
+	}
+	if len(tf.EMA20Values) > 0 {
+		sb.WriteString(fmt.Sprintf("EMA indicators (20‑period): %s\n\n", formatFloatSlice(tf.EMA20Values)))
+	}
+	if tf.EMA50 > 0 {
+		sb.WriteString(fmt.Sprintf("50‑Period EMA: %s\n\n", formatPriceWithDynamicPrecision(tf.EMA50)))
+	}
+	if len(tf.MACDValues) > 0 {
+		sb.WriteString(fmt.Sprintf("MACD indicators: %s\n\n", formatFloatSlice(tf.MACDValues)))
+	}
+	if len(tf.RSI7Values) > 0 {
+		sb.WriteString(fmt.Sprintf("RSI indicators (7‑Period): %s\n\n", formatFloatSlice(tf.RSI7Values)))
+	}
+	if len(tf.RSI14Values) > 0 {
+		sb.WriteString(fmt.Sprintf("RSI indicators (14‑Period): %s\n\n", formatFloatSlice(tf.RSI14Values)))
+	}
+	if tf.ATR14 > 0 {
+		sb.WriteString(fmt.Sprintf("%s ATR (14‑period): %.3f\n\n", tf.Interval, tf.ATR14))
+	}
+	if bb := tf.Bollinger; bb != nil {
+		sb.WriteString(fmt.Sprintf("Bollinger Bands (20, 2): upper %s / middle %s / lower %s, %%B %.2f, bandwidth %.2f%%\n\n",
+			formatPriceWithDynamicPrecision(bb.Upper), formatPriceWithDynamicPrecision(bb.Middle),
+			formatPriceWithDynamicPrecision(bb.Lower), bb.PercentB, bb.BandwidthPct))
+	}
+	if tf.VWAP > 0 {
+		sb.WriteString(fmt.Sprintf("VWAP: %s\n\n", formatPriceWithDynamicPrecision(tf.VWAP)))
+	}
+	if len(tf.StochK) > 0 {
+		sb.WriteString(fmt.Sprintf("Stochastic (14, 3, 3) %%K: %s, %%D: %s\n\n", formatFloatSlice(tf.StochK), formatFloatSlice(tf.StochD)))
+	}
+	if adx := tf.ADX; adx != nil {
+		sb.WriteString(fmt.Sprintf("ADX (14): %.2f, +DI %.2f, -DI %.2f\n\n", adx.ADX, adx.PlusDI, adx.MinusDI))
+	}
+	if len(tf.OBVValues) > 0 {
+		sb.WriteString(fmt.Sprintf("OBV: %s\n\n", formatFloatSlice(tf.OBVValues)))
+	}
+	if st := tf.Supertrend; st != nil {
+		trend := "downtrend"
+		if st.Uptrend {
+			trend = "uptrend"
+		}
+		sb.WriteString(fmt.Sprintf("Supertrend (10, 3): %s (%s)\n\n", formatPriceWithDynamicPrecision(st.Value), trend))
+	}
+}
+
 // formatPriceWithDynamicPrecision 根据价格区间动态选择精度
 // 这样可以完美支持从超低价 meme coin (< 0.0001) 到 BTC/ETH 的所有币种
 func formatPriceWithDynamicPrecision(price float64) string {
@@ -0,0 +1,335 @@
+package market
+
+import (
+	"math"
+	"time"
+)
+
+const seriesLength = 10 // 每个周期输出的序列长度
+
+// calculateTimeframeData 计算单个周期的序列和配置的指标
+func calculateTimeframeData(interval string, klines []Kline, cfg DataConfig) *TimeframeData {
+	data := &TimeframeData{Interval: interval}
+
+	start := len(klines) - seriesLength
+	if start < 0 {
+		start = 0
+	}
+	for i := start; i < len(klines); i++ {
+		data.Closes = append(data.Closes, klines[i].Close)
+		data.Volume = append(data.Volume, klines[i].Volume)
+
+		if cfg.Has(IndicatorEMA) && i >= 19 {
+			data.EMA20Values = append(data.EMA20Values, calculateEMA(klines[:i+1], 20))
+		}
+		if cfg.Has(IndicatorMACD) && i >= 25 {
+			data.MACDValues = append(data.MACDValues, calculateMACD(klines[:i+1]))
+		}
+		if cfg.Has(IndicatorRSI) {
+			if i >= 7 {
+				data.RSI7Values = append(data.RSI7Values, calculateRSI(klines[:i+1], 7))
+			}
+			if i >= 14 {
+				data.RSI14Values = append(data.RSI14Values, calculateRSI(klines[:i+1], 14))
+			}
+		}
+	}
+	data.TakerDelta = calculateTakerDelta(klines[start:])
+
+	if cfg.Has(IndicatorEMA) {
+		data.EMA50 = calculateEMA(klines, 50)
+	}
+	if cfg.Has(IndicatorATR) {
+		data.ATR14 = calculateATR(klines, 14)
+	}
+	if cfg.Has(IndicatorBollinger) {
+		data.Bollinger = calculateBollinger(klines, 20, 2)
+	}
+	if cfg.Has(IndicatorVWAP) {
+		data.VWAP = calculateVWAP(klines, intervalDurations[interval])
+	}
+	if cfg.Has(IndicatorStochastic) {
+		data.StochK, data.StochD = calculateStochastic(klines, 14, 3, 3)
+		data.StochK = lastN(data.StochK, seriesLength)
+		data.StochD = lastN(data.StochD, seriesLength)
+	}
+	if cfg.Has(IndicatorADX) {
+		data.ADX = calculateADX(klines, 14)
+	}
+	if cfg.Has(IndicatorOBV) {
+		data.OBVValues = lastN(calculateOBV(klines), seriesLength)
+	}
+	if cfg.Has(IndicatorSupertrend) {
+		data.Supertrend = calculateSupertrend(klines, 10, 3)
+	}
+	return data
+}
+
+// calculateBollinger 计算布林带（中轨为 period 期收盘价均值，上下轨为 ±mult 倍标准差）
+func calculateBollinger(klines []Kline, period int, mult float64) *BollingerData {
+	if len(klines) < period {
+		return nil
+	}
+
+	window := klines[len(klines)-period:]
+	mean := 0.0
+	for _, k := range window {
+		mean += k.Close
+	}
+	mean /= float64(period)
+
+	variance := 0.0
+	for _, k := range window {
+		variance += (k.Close - mean) * (k.Close - mean)
+	}
+	stdDev := math.Sqrt(variance / float64(period))
+
+	data := &BollingerData{
+		Upper:  mean + mult*stdDev,
+		Middle: mean,
+		Lower:  mean - mult*stdDev,
+	}
+	if width := data.Upper - data.Lower; width > 0 {
+		data.PercentB = (klines[len(klines)-1].Close - data.Lower) / width
+		if mean > 0 {
+			data.BandwidthPct = width / mean * 100
+		}
+	}
+	return data
+}
+
+// calculateVWAP 计算成交量加权均价（典型价格加权）
+// 日内周期从最新K线所在的 UTC 日开始累计，日线及以上周期使用全部K线
+func calculateVWAP(klines []Kline, interval time.Duration) float64 {
+	if len(klines) == 0 {
+		return 0
+	}
+
+	var anchor int64
+	if interval < 24*time.Hour {
+		last := time.UnixMilli(klines[len(klines)-1].OpenTime).UTC()
+		anchor = time.Date(last.Year(), last.Month(), last.Day(), 0, 0, 0, 0, time.UTC).UnixMilli()
+	}
+
+	sumPV, sumV := 0.0, 0.0
+	for _, k := range klines {
+		if k.OpenTime < anchor {
+			continue
+		}
+		typical := (k.High + k.Low + k.Close) / 3
+		sumPV += typical * k.Volume
+		sumV += k.Volume
+	}
+	if sumV == 0 {
+		return 0
+	}
+	return sumPV / sumV
+}
+
+// calculateStochastic 计算慢速随机指标，返回 %K 与 %D 完整序列
+// %K = kPeriod 期原始 %K 的 smooth 期均值，%D = %K 的 dPeriod 期均值
+func calculateStochastic(klines []Kline, kPeriod, smooth, dPeriod int) (kValues, dValues []float64) {
+	if len(klines) < kPeriod {
+		return nil, nil
+	}
+
+	raw := make([]float64, 0, len(klines)-kPeriod+1)
+	for i := kPeriod - 1; i < len(klines); i++ {
+		highest, lowest := klines[i].High, klines[i].Low
+		for _, k := range klines[i-kPeriod+1 : i] {
+			highest = math.Max(highest, k.High)
+			lowest = math.Min(lowest, k.Low)
+		}
+		value := 50.0 // 区间为零时视为中性
+		if highest > lowest {
+			value = (klines[i].Close - lowest) / (highest - lowest) * 100
+		}
+		raw = append(raw, value)
+	}
+
+	kValues = simpleMovingAverage(raw, smooth)
+	dValues = simpleMovingAverage(kValues, dPeriod)
+	return kValues, dValues
+}
+
+// calculateADX 计算 Wilder ADX 及 +DI/-DI（需要至少 2*period+1 根K线）
+func calculateADX(klines []Kline, period int) *ADXData {
+	if len(klines) < 2*period+1 {
+		return nil
+	}
+
+	var smoothTR, smoothPlus, smoothMinus, adx float64
+	var plusDI, minusDI float64
+	dxSum := 0.0
+	for i := 1; i < len(klines); i++ {
+		tr := trueRange(klines[i], klines[i-1])
+		upMove := klines[i].High - klines[i-1].High
+		downMove := klines[i-1].Low - klines[i].Low
+		plusDM, minusDM := 0.0, 0.0
+		if upMove > downMove && upMove > 0 {
+			plusDM = upMove
+		}
+		if downMove > upMove && downMove > 0 {
+			minusDM = downMove
+		}
+
+		if i <= period {
+			// 前 period 根累加作为初始平滑值
+			smoothTR += tr
+			smoothPlus += plusDM
+			smoothMinus += minusDM
+			if i < period {
+				continue
+			}
+		} else {
+			smoothTR = smoothTR - smoothTR/float64(period) + tr
+			smoothPlus = smoothPlus - smoothPlus/float64(period) + plusDM
+			smoothMinus = smoothMinus - smoothMinus/float64(period) + minusDM
+		}
+
+		plusDI, minusDI = 0, 0
+		if smoothTR > 0 {
+			plusDI = 100 * smoothPlus / smoothTR
+			minusDI = 100 * smoothMinus / smoothTR
+		}
+		dx := 0.0
+		if sum := plusDI + minusDI; sum > 0 {
+			dx = 100 * math.Abs(plusDI-minusDI) / sum
+		}
+
+		// 第一个 ADX 为前 period 个 DX 的均值，之后 Wilder 平滑
+		switch n := i - period + 1; {
+		case n < period:
+			dxSum += dx
+		case n == period:
+			adx = (dxSum + dx) / float64(period)
+		default:
+			adx = (adx*float64(period-1) + dx) / float64(period)
+		}
+	}
+
+	return &ADXData{ADX: adx, PlusDI: plusDI, MinusDI: minusDI}
+}
+
+// calculateOBV 计算能量潮序列（从第一根K线开始累计）
+func calculateOBV(klines []Kline) []float64 {
+	if len(klines) == 0 {
+		return nil
+	}
+
+	values := make([]float64, len(klines))
+	for i := 1; i < len(klines); i++ {
+		values[i] = values[i-1]
+		switch {
+		case klines[i].Close > klines[i-1].Close:
+			values[i] += klines[i].Volume
+		case klines[i].Close < klines[i-1].Close:
+			values[i] -= klines[i].Volume
+		}
+	}
+	return values
+}
+
+// calculateSupertrend 计算超级趋势（基于 Wilder ATR 的上下轨翻转）
+func calculateSupertrend(klines []Kline, period int, mult float64) *SupertrendData {
+	if len(klines) <= period {
+		return nil
+	}
+
+	// ATR 序列：前 period 根的均值作为初始值，之后 Wilder 平滑
+	atr := 0.0
+	for i := 1; i <= period; i++ {
+		atr += trueRange(klines[i], klines[i-1])
+	}
+	atr /= float64(period)
+
+	var finalUpper, finalLower float64
+	uptrend := true
+	for i := period; i < len(klines); i++ {
+		if i > period {
+			atr = (atr*float64(period-1) + trueRange(klines[i], klines[i-1])) / float64(period)
+		}
+		hl2 := (klines[i].High + klines[i].Low) / 2
+		basicUpper := hl2 + mult*atr
+		basicLower := hl2 - mult*atr
+
+		if i == period {
+			finalUpper, finalLower = basicUpper, basicLower
+			uptrend = klines[i].Close >= hl2
+			continue
+		}
+
+		prevClose := klines[i-1].Close
+		if basicUpper < finalUpper || prevClose > finalUpper {
+			finalUpper = basicUpper
+		}
+		if basicLower > finalLower || prevClose < finalLower {
+			finalLower = basicLower
+		}
+
+		close := klines[i].Close
+		if uptrend && close < finalLower {
+			uptrend = false
+		} else if !uptrend && close > finalUpper {
+			uptrend = true
+		}
+	}
+
+	if uptrend {
+		return &SupertrendData{Value: finalLower, Uptrend: true}
+	}
+	return &SupertrendData{Value: finalUpper, Uptrend: false}
+}
+
+// trueRange 真实波幅
+func trueRange(current, previous Kline) float64 {
+	return math.Max(current.High-current.Low,
+		math.Max(math.Abs(current.High-previous.Close), math.Abs(current.Low-previous.Close)))
+}
+
+// simpleMovingAverage 简单移动平均序列（长度为 len(values)-period+1）
+func simpleMovingAverage(values []float64, period int) []float64 {
+	if len(values) < period || period <= 0 {
+		return nil
+	}
+
+	result := make([]float64, 0, len(values)-period+1)
+	sum := 0.0
+	for i, v := range values {
+		sum += v
+		if i >= period {
+			sum -= values[i-period]
+		}
+		if i >= period-1 {
+			result = append(result, sum/float64(period))
+		}
+	}
+	return result
+}
+
+// lastN 取序列最后 n 个值
+func lastN(values []float64, n int) []float64 {
+	if len(values) > n {
+		return values[len(values)-n:]
+	}
+	return values
+}
+
+// priceChangeOver 计算 duration 内的价格变化百分比（使用能覆盖该时长的最短周期K线）
+func priceChangeOver(timeframes []string, klinesByInterval map[string][]Kline, currentPrice float64, duration time.Duration) float64 {
+	for _, interval := range timeframes {
+		step := intervalDurations[interval]
+		if step > duration {
+			break
+		}
+		klines := klinesByInterval[interval]
+		bars := int(duration / step)
+		if len(klines) <= bars {
+			continue
+		}
+		if past := klines[len(klines)-1-bars].Close; past > 0 {
+			return (currentPrice - past) / past * 100
+		}
+	}
+	return 0
+}
@@ -0,0 +1,212 @@
+package market
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// trendKlines 生成单边趋势K线（step > 0 上涨，step < 0 下跌）
+func trendKlines(count int, start, step float64) []Kline {
+	klines := make([]Kline, count)
+	for i := range klines {
+		open := start + float64(i)*step
+		close := open + step
+		klines[i] = Kline{
+			OpenTime: int64(i) * 900000,
+			Open:     open,
+			High:     math.Max(open, close) + 0.5,
+			Low:      math.Min(open, close) - 0.5,
+			Close:    close,
+			Volume:   100,
+		}
+	}
+	return klines
+}
+
+func TestParseDataConfig(t *testing.T) {
+	cfg, err := ParseDataConfig(" 1h, 15m ,1h", "Bollinger, adx,,ADX")
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if strings.Join(cfg.Timeframes, ",") != "1h,15m" || strings.Join(cfg.Indicators, ",") != "bollinger,adx" {
+		t.Errorf("应去除空白和重复项: %+v", cfg)
+	}
+	if got := strings.Join(cfg.SortedTimeframes(), ","); got != "15m,1h" {
+		t.Errorf("周期应按时长排序，实际 %s", got)
+	}
+	if !cfg.Has(IndicatorADX) || cfg.Has(IndicatorMACD) {
+		t.Error("只应包含配置的指标")
+	}
+
+	if cfg, _ := ParseDataConfig("", ""); !cfg.IsDefault() || !cfg.Has(IndicatorRSI) {
+		t.Error("空配置应使用默认周期和指标")
+	}
+
+	for _, tt := range []struct{ timeframes, indicators, wantErr string }{
+		{"2m", "", "不支持的K线周期: 2m"},
+		{"1h", "ichimoku", "不支持的指标: ichimoku"},
+		{"1m,5m,15m,1h,4h", "", "最多配置4个K线周期"},
+	} {
+		if _, err := ParseDataConfig(tt.timeframes, tt.indicators); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("ParseDataConfig(%q, %q) 错误 = %v, want %q", tt.timeframes, tt.indicators, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCalculateBollinger(t *testing.T) {
+	klines := make([]Kline, 20)
+	for i := range klines {
+		klines[i].Close = 99
+		if i%2 == 1 {
+			klines[i].Close = 101
+		}
+	}
+
+	bb := calculateBollinger(klines, 20, 2)
+	if bb == nil {
+		t.Fatal("K线足够时应返回布林带")
+	}
+	// 均值 100，标准差 1
+	if bb.Middle != 100 || bb.Upper != 102 || bb.Lower != 98 {
+		t.Errorf("布林带不正确: %+v", bb)
+	}
+	if math.Abs(bb.PercentB-0.75) > 1e-9 || math.Abs(bb.BandwidthPct-4) > 1e-9 {
+		t.Errorf("%%B/带宽不正确: %+v", bb)
+	}
+	if calculateBollinger(klines[:19], 20, 2) != nil {
+		t.Error("K线不足时应返回 nil")
+	}
+}
+
+func TestCalculateVWAP(t *testing.T) {
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	klines := []Kline{
+		{OpenTime: day.Add(-time.Hour).UnixMilli(), High: 50, Low: 50, Close: 50, Volume: 1000}, // 前一日
+		{OpenTime: day.UnixMilli(), High: 11, Low: 9, Close: 10, Volume: 1},
+		{OpenTime: day.Add(time.Hour).UnixMilli(), High: 22, Low: 18, Close: 20, Volume: 3},
+	}
+
+	// 日内周期只统计当日: (10*1 + 20*3) / 4
+	if got := calculateVWAP(klines, time.Hour); math.Abs(got-17.5) > 1e-9 {
+		t.Errorf("日内 VWAP = %v, want 17.5", got)
+	}
+	// 日线周期统计全部K线
+	if got := calculateVWAP(klines, 24*time.Hour); math.Abs(got-50070.0/1004) > 1e-9 {
+		t.Errorf("日线 VWAP = %v", got)
+	}
+}
+
+func TestCalculateStochasticAndOBV(t *testing.T) {
+	klines := trendKlines(30, 100, 1)
+	kValues, dValues := calculateStochastic(klines, 14, 3, 3)
+	// 原始 %K 序列 17 个，平滑后 15 个，%D 13 个
+	if len(kValues) != 15 || len(dValues) != 13 {
+		t.Fatalf("序列长度不正确: k=%d d=%d", len(kValues), len(dValues))
+	}
+	// 单边上涨时收盘价接近区间高点
+	if last := kValues[len(kValues)-1]; last < 90 || last > 100 {
+		t.Errorf("上涨趋势 %%K 应接近 100，实际 %v", last)
+	}
+
+	klines[5].Close = klines[4].Close // 平盘不改变 OBV
+	obv := calculateOBV(klines[:7])
+	expected := []float64{0, 100, 200, 300, 400, 400, 500}
+	for i := range expected {
+		if obv[i] != expected[i] {
+			t.Errorf("obv[%d] = %v, want %v", i, obv[i], expected[i])
+		}
+	}
+}
+
+func TestCalculateADX(t *testing.T) {
+	if calculateADX(trendKlines(28, 100, 1), 14) != nil {
+		t.Error("K线不足 2*period+1 时应返回 nil")
+	}
+
+	// 高低点持续抬升：只有 +DM，ADX 为 100
+	adx := calculateADX(trendKlines(40, 100, 1), 14)
+	if adx == nil || math.Abs(adx.ADX-100) > 1e-9 || adx.MinusDI != 0 || adx.PlusDI <= 0 {
+		t.Errorf("上涨趋势 ADX 不正确: %+v", adx)
+	}
+
+	adx = calculateADX(trendKlines(40, 200, -1), 14)
+	if adx == nil || adx.PlusDI != 0 || adx.MinusDI <= 0 {
+		t.Errorf("下跌趋势 DI 不正确: %+v", adx)
+	}
+}
+
+func TestCalculateSupertrend(t *testing.T) {
+	klines := trendKlines(30, 100, 1)
+	st := calculateSupertrend(klines, 10, 3)
+	if st == nil || !st.Uptrend || st.Value >= klines[len(klines)-1].Close {
+		t.Fatalf("上涨趋势中超级趋势应在价格下方: %+v", st)
+	}
+
+	// 急跌跌破下轨后翻转为下跌趋势
+	last := klines[len(klines)-1].Close
+	klines = append(klines, trendKlines(5, last, -8)...)
+	st = calculateSupertrend(klines, 10, 3)
+	if st == nil || st.Uptrend || st.Value <= klines[len(klines)-1].Close {
+		t.Errorf("急跌后超级趋势应翻转到价格上方: %+v", st)
+	}
+}
+
+func TestBuildDataWithConfig_Format(t *testing.T) {
+	cfg, err := ParseDataConfig("1h,15m", "bollinger,adx,supertrend")
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	klines15m := trendKlines(60, 100, 0.25)
+	klines1h := trendKlines(60, 90, 1)
+	data := BuildDataWithConfig("BTCUSDT", map[string][]Kline{"15m": klines15m, "1h": klines1h}, cfg, nil, 0.0001)
+
+	if data.CurrentPrice != klines15m[59].Close {
+		t.Errorf("当前价格应取最短周期: %v", data.CurrentPrice)
+	}
+	// 1小时变化 = 4根15分钟K线前
+	if want := (115 - 114.0) / 114 * 100; math.Abs(data.PriceChange1h-want) > 1e-9 {
+		t.Errorf("PriceChange1h = %v, want %v", data.PriceChange1h, want)
+	}
+	// 4小时变化优先使用最短周期 = 16根15分钟K线前
+	if want := (115 - 111.0) / 111 * 100; math.Abs(data.PriceChange4h-want) > 1e-9 {
+		t.Errorf("PriceChange4h = %v, want %v", data.PriceChange4h, want)
+	}
+	if len(data.Timeframes) != 2 || data.Timeframes[0].Interval != "15m" || data.Timeframes[1].Interval != "1h" {
+		t.Fatalf("周期数据不正确: %+v", data.Timeframes)
+	}
+	if tf := data.Timeframes[0]; tf.Bollinger == nil || tf.ADX == nil || tf.Supertrend == nil || tf.MACDValues != nil || tf.ATR14 != 0 {
+		t.Errorf("只应计算配置的指标: %+v", tf)
+	}
+
+	text := Format(data)
+	for _, want := range []string{
+		"current_price = 115.00\n",
+		"Series (15m intervals, oldest → latest):",
+		"Series (1h intervals, oldest → latest):",
+		"Bollinger Bands (20, 2): upper",
+		"ADX (14): 100.00, +DI",
+		"(uptrend)",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Format 缺少 %q:\n%s", want, text)
+		}
+	}
+	for _, unwanted := range []string{"current_ema20", "MACD", "RSI", "Intraday series", "Longer‑term context"} {
+		if strings.Contains(text, unwanted) {
+			t.Errorf("Format 不应输出未配置的 %q", unwanted)
+		}
+	}
+}
+
+func TestGetKlineDataMap_AnyInterval(t *testing.T) {
+	m := &WSMonitor{}
+	m.getKlineDataMap("15m").Store("BTCUSDT", []Kline{{Close: 1}})
+	if _, ok := m.getKlineDataMap("15m").Load("BTCUSDT"); !ok {
+		t.Error("非默认周期的K线应被缓存")
+	}
+	if _, ok := m.getKlineDataMap("1h").Load("BTCUSDT"); ok {
+		t.Error("不同周期的缓存应相互独立")
+	}
+}
@@ -15,13 +15,16 @@ type WSMonitor struct {
 	symbols        []string
 	featuresMap    sync.Map
 	alertsChan     chan Alert
-	klineDataMap3m sync.Map // 存储每个交易对的K线历史数据
-	klineDataMap4h sync.Map // 存储每个交易对的K线历史数据
+	klineDataMaps  sync.Map // 各周期的K线历史数据 (interval -> *sync.Map[symbol][]Kline)
 	tickerDataMap  sync.Map // 存储每个交易对的ticker数据
 	batchSize      int
 	filterSymbols  sync.Map // 使用sync.Map来存储需要监控的币种和其状态
 	symbolStats    sync.Map // 存储币种统计信息
 	FilterSymbol   []string //经过筛选的币种
+	orderBooks     sync.Map // 本地订单簿 (symbol -> *LocalOrderBook)
+	tradeFlows     sync.Map // 主动买卖成交流 (symbol -> *tradeFlow)
+
+	fetchOrderBook func(symbol string, limit int) (*OrderBook, error) // 深度快照拉取（为空时使用 REST API）
 }
 type SymbolStats struct {
 	LastActiveTime   time.Time
@@ -32,7 +35,10 @@ type SymbolStats struct {
 }
 
 var WSMonitorCli *WSMonitor
-var subKlineTime = []string{"3m", "4h"} // 管理订阅流的K线周期
+var subKlineTime = []string{"3m", "4h"} // 启动时预加载并订阅的K线周期（交易员配置的其他周期在首次查询时订阅）
+
+// orderFlowPreloadLimit 启动时预先订阅盘口和成交流的最大币种数（更多币种在首次查询时订阅）
+const orderFlowPreloadLimit = 50
 
 func NewWSMonitor(batchSize int) *WSMonitor {
 	WSMonitorCli = &WSMonitor{
@@ -89,25 +95,17 @@ func (m *WSMonitor) initializeHistoricalData() error {
 			defer wg.Done()
 			defer func() { <-semaphore }()
 
-			// 获取历史K线数据
-			klines, err := apiClient.GetKlines(s, "3m", 100)
-			if err != nil {
-				log.Printf("获取 %s 历史数据失败: %v", s, err)
-				return
-			}
-			if len(klines) > 0 {
-				m.klineDataMap3m.Store(s, klines)
-				log.Printf("已加载 %s 的历史K线数据-3m: %d 条", s, len(klines))
-			}
-			// 获取历史K线数据
-			klines4h, err := apiClient.GetKlines(s, "4h", 100)
-			if err != nil {
-				log.Printf("获取 %s 历史数据失败: %v", s, err)
-				return
-			}
-			if len(klines4h) > 0 {
-				m.klineDataMap4h.Store(s, klines4h)
-				log.Printf("已加载 %s 的历史K线数据-4h: %d 条", s, len(klines4h))
+			for _, st := range subKlineTime {
+				// 获取历史K线数据
+				klines, err := apiClient.GetKlines(s, st, 100)
+				if err != nil {
+					log.Printf("获取 %s 历史数据失败: %v", s, err)
+					return
+				}
+				if len(klines) > 0 {
+					m.getKlineDataMap(st).Store(s, klines)
+					log.Printf("已加载 %s 的历史K线数据-%s: %d 条", s, st, len(klines))
+				}
 			}
 		}(symbol)
 	}
@@ -163,6 +161,24 @@ func (m *WSMonitor) subscribeAll() error {
 			return err
 		}
 	}
+	if len(m.symbols) <= orderFlowPreloadLimit {
+		var symbols []string
+		for _, symbol := range m.symbols {
+			if m.subscribeOrderFlow(symbol) {
+				symbols = append(symbols, symbol)
+			}
+		}
+		if err := m.combinedClient.BatchSubscribeOrderFlow(symbols); err != nil {
+			log.Printf("❌ 订阅盘口和成交流失败: %v", err)
+			for _, symbol := range symbols {
+				m.unsubscribeOrderFlow(symbol)
+			}
+			return err
+		}
+		for _, symbol := range symbols {
+			m.startOrderBookSync(symbol)
+		}
+	}
 	log.Println("所有交易对订阅完成")
 	return nil
 }
@@ -178,16 +194,10 @@ func (m *WSMonitor) handleKlineData(symbol string, ch <-chan []byte, _time strin
 	}
 }
 
+// getKlineDataMap 获取指定周期的K线缓存（首次使用的周期自动创建）
 func (m *WSMonitor) getKlineDataMap(_time string) *sync.Map {
-	var klineDataMap *sync.Map
-	if _time == "3m" {
-		klineDataMap = &m.klineDataMap3m
-	} else if _time == "4h" {
-		klineDataMap = &m.klineDataMap4h
-	} else {
-		klineDataMap = &sync.Map{}
-	}
-	return klineDataMap
+	value, _ := m.klineDataMaps.LoadOrStore(_time, &sync.Map{})
+	return value.(*sync.Map)
 }
 func (m *WSMonitor) processKlineUpdate(symbol string, wsData KlineWSData, _time string) {
 	// 转换WebSocket数据为Kline结构
@@ -267,6 +277,133 @@ func (m *WSMonitor) GetCurrentKlines(symbol string, duration string) ([]Kline, e
 	return result, nil
 }
 
+// subscribeOrderFlow 注册增量深度和归集成交监听，已注册时返回 false
+// 订阅请求成功后由调用方通过 startOrderBookSync 开始同步订单簿
+func (m *WSMonitor) subscribeOrderFlow(symbol string) bool {
+	book := NewLocalOrderBook(symbol)
+	if _, loaded := m.orderBooks.LoadOrStore(symbol, book); loaded {
+		return false
+	}
+	m.tradeFlows.Store(symbol, newTradeFlow(tradeFlowWindow, time.Now()))
+
+	streams := orderFlowStreams(symbol)
+	go m.handleDepthData(symbol, book, m.combinedClient.AddSubscriber(streams[0], 1000))
+	go m.handleAggTradeData(symbol, m.combinedClient.AddSubscriber(streams[1], 1000))
+	return true
+}
+
+// startOrderBookSync 订阅成功后开始同步已注册的订单簿
+func (m *WSMonitor) startOrderBookSync(symbol string) {
+	if value, ok := m.orderBooks.Load(symbol); ok {
+		go m.syncOrderBook(symbol, value.(*LocalOrderBook))
+	}
+}
+
+// unsubscribeOrderFlow 撤销 subscribeOrderFlow 的注册（订阅请求失败时调用，下次 GetOrderFlow 会重新订阅）
+func (m *WSMonitor) unsubscribeOrderFlow(symbol string) {
+	m.orderBooks.Delete(symbol)
+	m.tradeFlows.Delete(symbol)
+	for _, stream := range orderFlowStreams(symbol) {
+		m.combinedClient.RemoveSubscriber(stream)
+	}
+}
+
+func (m *WSMonitor) handleDepthData(symbol string, book *LocalOrderBook, ch <-chan []byte) {
+	for data := range ch {
+		var depthData DepthWSData
+		if err := json.Unmarshal(data, &depthData); err != nil {
+			log.Printf("解析深度数据失败: %v", err)
+			continue
+		}
+		if err := book.Apply(&depthData); err != nil {
+			log.Printf("⚠️ %s %v，重新同步订单簿", symbol, err)
+			go m.syncOrderBook(symbol, book)
+		}
+	}
+}
+
+func (m *WSMonitor) handleAggTradeData(symbol string, ch <-chan []byte) {
+	for data := range ch {
+		var trade AggTradeWSData
+		if err := json.Unmarshal(data, &trade); err != nil {
+			log.Printf("解析成交数据失败: %v", err)
+			continue
+		}
+		if value, ok := m.tradeFlows.Load(symbol); ok {
+			value.(*tradeFlow).Add(&trade)
+		}
+	}
+}
+
+// syncOrderBook 拉取 REST 深度快照并与缓冲的增量衔接，失败时重试
+// 订单簿被撤销注册（订阅失败回滚）后停止重试
+func (m *WSMonitor) syncOrderBook(symbol string, book *LocalOrderBook) {
+	if !m.isRegisteredBook(symbol, book) || !book.beginSync() {
+		return
+	}
+	defer book.endSync()
+
+	fetch := m.fetchOrderBook
+	if fetch == nil {
+		fetch = NewAPIClient().GetOrderBook
+	}
+	for attempt := 1; attempt <= 5; attempt++ {
+		// 等待增量推送开始缓冲，保证快照之后的推送不遗漏
+		time.Sleep(time.Duration(attempt) * time.Second)
+		if !m.isRegisteredBook(symbol, book) {
+			return
+		}
+
+		snapshot, err := fetch(symbol, orderBookSnapshotLimit)
+		if err != nil {
+			log.Printf("⚠️ 获取 %s 深度快照失败 (第 %d 次): %v", symbol, attempt, err)
+			continue
+		}
+		if err := book.LoadSnapshot(snapshot); err != nil {
+			log.Printf("⚠️ %s 深度快照衔接失败 (第 %d 次): %v", symbol, attempt, err)
+			continue
+		}
+		return
+	}
+	log.Printf("❌ %s 订单簿同步失败，盘口数据暂不可用", symbol)
+}
+
+// isRegisteredBook 订单簿是否仍是该币种当前注册的订单簿
+func (m *WSMonitor) isRegisteredBook(symbol string, book *LocalOrderBook) bool {
+	value, ok := m.orderBooks.Load(symbol)
+	return ok && value.(*LocalOrderBook) == book
+}
+
+// GetOrderFlow 获取盘口和成交流特征（未订阅时动态订阅，同步完成前返回 nil）
+func (m *WSMonitor) GetOrderFlow(symbol string) *OrderFlowData {
+	value, exists := m.orderBooks.Load(symbol)
+	if !exists {
+		if m.subscribeOrderFlow(symbol) {
+			if err := m.combinedClient.BatchSubscribeOrderFlow([]string{symbol}); err != nil {
+				log.Printf("警告: 动态订阅 %s 盘口和成交流失败，下次调用时重试: %v", symbol, err)
+				m.unsubscribeOrderFlow(symbol)
+			} else {
+				m.startOrderBookSync(symbol)
+			}
+		}
+		return nil
+	}
+
+	book := value.(*LocalOrderBook)
+	data := book.Features(orderFlowDepthLevels, orderBookStaleAfter)
+	if data == nil {
+		if book.needsSync() {
+			go m.syncOrderBook(symbol, book)
+		}
+		return nil
+	}
+	if flow, ok := m.tradeFlows.Load(symbol); ok {
+		data.TakerBuyUSD, data.TakerSellUSD, data.TradeWindow = flow.(*tradeFlow).Totals(time.Now())
+		data.TakerDeltaUSD = data.TakerBuyUSD - data.TakerSellUSD
+	}
+	return data
+}
+
 func (m *WSMonitor) Close() {
 	m.wsClient.Close()
 	close(m.alertsChan)
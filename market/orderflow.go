@@ -0,0 +1,311 @@
+package market
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	orderBookSnapshotLimit = 100              // REST 深度快照档位数（只需保证前N档准确）
+	orderBookBufferLimit   = 1000             // 快照到达前最多缓冲的增量条数
+	orderBookStaleAfter    = 30 * time.Second // 超过该时间未更新的订单簿视为失效
+	orderFlowDepthLevels   = 20               // 统计盘口深度的档位数
+	tradeFlowWindow        = 5 * time.Minute  // 主动买卖成交统计窗口
+)
+
+// errOrderBookGap 增量推送序列断档，需要重新拉取快照
+var errOrderBookGap = errors.New("订单簿增量序列断档")
+
+// LocalOrderBook 本地维护的订单簿（REST 快照 + depth 增量推送）
+//
+// 同步流程遵循币安合约文档：快照到达前缓冲增量；丢弃 u < lastUpdateId 的推送；
+// 第一条推送需满足 U <= lastUpdateId <= u；之后每条推送的 pu 必须等于上一条的 u
+type LocalOrderBook struct {
+	mu           sync.Mutex
+	symbol       string
+	bids         map[float64]float64 // 价格 -> 数量
+	asks         map[float64]float64
+	lastUpdateID int64
+	synced       bool          // 已加载快照
+	bridged      bool          // 快照之后的第一条推送已衔接
+	syncing      bool          // 正在拉取快照
+	buffer       []DepthWSData // 快照到达前缓冲的增量
+	updatedAt    time.Time
+}
+
+// NewLocalOrderBook 创建未同步的本地订单簿
+func NewLocalOrderBook(symbol string) *LocalOrderBook {
+	return &LocalOrderBook{
+		symbol: symbol,
+		bids:   make(map[float64]float64),
+		asks:   make(map[float64]float64),
+	}
+}
+
+// Apply 应用一条增量推送；返回 errOrderBookGap 时订单簿已重置，需要重新加载快照
+func (b *LocalOrderBook) Apply(event *DepthWSData) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.synced {
+		b.buffer = append(b.buffer, *event)
+		if len(b.buffer) > orderBookBufferLimit {
+			b.buffer = b.buffer[len(b.buffer)-orderBookBufferLimit:]
+		}
+		return nil
+	}
+	if err := b.applyLocked(event); err != nil {
+		b.resetLocked()
+		return err
+	}
+	return nil
+}
+
+// LoadSnapshot 加载 REST 深度快照并回放缓冲的增量
+func (b *LocalOrderBook) LoadSnapshot(snapshot *OrderBook) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.bids = make(map[float64]float64, len(snapshot.Bids))
+	b.asks = make(map[float64]float64, len(snapshot.Asks))
+	for _, level := range snapshot.Bids {
+		b.bids[level.Price] = level.Quantity
+	}
+	for _, level := range snapshot.Asks {
+		b.asks[level.Price] = level.Quantity
+	}
+	b.lastUpdateID = snapshot.LastUpdateID
+	b.bridged = false
+	b.synced = true
+	b.updatedAt = time.Now()
+
+	buffered := b.buffer
+	b.buffer = nil
+	for i := range buffered {
+		if err := b.applyLocked(&buffered[i]); err != nil {
+			b.resetLocked()
+			return err
+		}
+	}
+	return nil
+}
+
+// applyLocked 按序列号校验并应用增量（调用方需持有锁）
+func (b *LocalOrderBook) applyLocked(event *DepthWSData) error {
+	if event.FinalUpdateID < b.lastUpdateID {
+		return nil // 快照之前的推送
+	}
+	if !b.bridged {
+		if event.FirstUpdateID > b.lastUpdateID {
+			return errOrderBookGap // 快照比推送旧，中间有遗漏
+		}
+		b.bridged = true
+	} else if event.PrevFinalUpdateID != b.lastUpdateID {
+		return errOrderBookGap
+	}
+
+	applyLevels(b.bids, event.Bids)
+	applyLevels(b.asks, event.Asks)
+	b.lastUpdateID = event.FinalUpdateID
+	b.updatedAt = time.Now()
+	return nil
+}
+
+// resetLocked 清空订单簿，等待重新同步（调用方需持有锁）
+func (b *LocalOrderBook) resetLocked() {
+	b.bids = make(map[float64]float64)
+	b.asks = make(map[float64]float64)
+	b.synced = false
+	b.bridged = false
+	b.buffer = nil
+}
+
+// beginSync 标记开始拉取快照，已有同步在进行时返回 false
+func (b *LocalOrderBook) beginSync() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.syncing {
+		return false
+	}
+	b.syncing = true
+	return true
+}
+
+// needsSync 订单簿未同步且没有同步在进行（如多次重试失败后）
+func (b *LocalOrderBook) needsSync() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.synced && !b.syncing
+}
+
+// endSync 结束快照拉取
+func (b *LocalOrderBook) endSync() {
+	b.mu.Lock()
+	b.syncing = false
+	b.mu.Unlock()
+}
+
+// Features 计算前 levels 档的盘口特征（未同步、已失效或盘口为空时返回 nil）
+func (b *LocalOrderBook) Features(levels int, staleAfter time.Duration) *OrderFlowData {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.synced || time.Since(b.updatedAt) > staleAfter {
+		return nil
+	}
+	bids := topLevels(b.bids, levels, true)
+	asks := topLevels(b.asks, levels, false)
+	if len(bids) == 0 || len(asks) == 0 {
+		return nil
+	}
+
+	data := &OrderFlowData{
+		BestBid:     bids[0].Price,
+		BestAsk:     asks[0].Price,
+		DepthLevels: levels,
+	}
+	if mid := (data.BestBid + data.BestAsk) / 2; mid > 0 {
+		data.SpreadBps = (data.BestAsk - data.BestBid) / mid * 10000
+	}
+	for _, level := range bids {
+		data.BidDepthUSD += level.Price * level.Quantity
+	}
+	for _, level := range asks {
+		data.AskDepthUSD += level.Price * level.Quantity
+	}
+	if total := data.BidDepthUSD + data.AskDepthUSD; total > 0 {
+		data.Imbalance = (data.BidDepthUSD - data.AskDepthUSD) / total
+	}
+	return data
+}
+
+// applyLevels 应用价位更新（数量为0表示删除该价位）
+func applyLevels(book map[float64]float64, levels [][2]string) {
+	for _, level := range levels {
+		price, err := strconv.ParseFloat(level[0], 64)
+		if err != nil {
+			continue
+		}
+		qty, err := strconv.ParseFloat(level[1], 64)
+		if err != nil {
+			continue
+		}
+		if qty == 0 {
+			delete(book, price)
+		} else {
+			book[price] = qty
+		}
+	}
+}
+
+// topLevels 取最优的 n 档（买盘价格从高到低，卖盘从低到高）
+func topLevels(book map[float64]float64, n int, descending bool) []PriceLevel {
+	levels := make([]PriceLevel, 0, len(book))
+	for price, qty := range book {
+		levels = append(levels, PriceLevel{Price: price, Quantity: qty})
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		if descending {
+			return levels[i].Price > levels[j].Price
+		}
+		return levels[i].Price < levels[j].Price
+	})
+	if len(levels) > n {
+		levels = levels[:n]
+	}
+	return levels
+}
+
+// tradeFlow 按秒汇总的主动买卖成交额（滚动窗口）
+type tradeFlow struct {
+	mu        sync.Mutex
+	window    time.Duration
+	startedAt time.Time
+	buckets   []tradeFlowBucket // 按时间正序
+}
+
+type tradeFlowBucket struct {
+	second  int64
+	buyUSD  float64
+	sellUSD float64
+}
+
+func newTradeFlow(window time.Duration, now time.Time) *tradeFlow {
+	return &tradeFlow{window: window, startedAt: now}
+}
+
+// Add 记录一笔归集成交
+func (f *tradeFlow) Add(trade *AggTradeWSData) {
+	price, err := strconv.ParseFloat(trade.Price, 64)
+	if err != nil {
+		return
+	}
+	qty, err := strconv.ParseFloat(trade.Quantity, 64)
+	if err != nil {
+		return
+	}
+	notional := price * qty
+	second := trade.TradeTime / 1000
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n := len(f.buckets)
+	if n == 0 || f.buckets[n-1].second < second {
+		f.buckets = append(f.buckets, tradeFlowBucket{second: second})
+		n++
+	}
+	// 乱序到达的成交计入最后一个桶（最多差几百毫秒，不影响窗口统计）
+	if trade.IsBuyerMaker {
+		f.buckets[n-1].sellUSD += notional
+	} else {
+		f.buckets[n-1].buyUSD += notional
+	}
+
+	cutoff := second - int64(f.window/time.Second)
+	drop := 0
+	for drop < len(f.buckets) && f.buckets[drop].second <= cutoff {
+		drop++
+	}
+	f.buckets = f.buckets[drop:]
+}
+
+// Totals 返回窗口内的主动买入/卖出成交额和实际统计时长
+func (f *tradeFlow) Totals(now time.Time) (buyUSD, sellUSD float64, window time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	cutoff := now.Add(-f.window).Unix()
+	for _, bucket := range f.buckets {
+		if bucket.second > cutoff {
+			buyUSD += bucket.buyUSD
+			sellUSD += bucket.sellUSD
+		}
+	}
+	window = time.Duration(math.Min(float64(f.window), float64(now.Sub(f.startedAt))))
+	return buyUSD, sellUSD, window
+}
+
+// calculateTakerDelta 每根K线的主动买入量减主动卖出量（K线不含主动买入数据时返回 nil）
+func calculateTakerDelta(klines []Kline) []float64 {
+	hasTakerData := false
+	for _, k := range klines {
+		if k.TakerBuyBaseVolume > 0 {
+			hasTakerData = true
+			break
+		}
+	}
+	if !hasTakerData {
+		return nil
+	}
+
+	deltas := make([]float64, 0, len(klines))
+	for _, k := range klines {
+		deltas = append(deltas, 2*k.TakerBuyBaseVolume-k.Volume)
+	}
+	return deltas
+}
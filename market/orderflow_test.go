@@ -0,0 +1,218 @@
+package market
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// depthEvent 构造增量深度推送
+func depthEvent(first, final, prev int64, bids, asks [][2]string) *DepthWSData {
+	return &DepthWSData{FirstUpdateID: first, FinalUpdateID: final, PrevFinalUpdateID: prev, Bids: bids, Asks: asks}
+}
+
+func testSnapshot() *OrderBook {
+	return &OrderBook{
+		Symbol:       "BTCUSDT",
+		LastUpdateID: 100,
+		Bids:         []PriceLevel{{Price: 99, Quantity: 2}, {Price: 98, Quantity: 3}},
+		Asks:         []PriceLevel{{Price: 101, Quantity: 1}, {Price: 102, Quantity: 4}},
+	}
+}
+
+func TestLocalOrderBook_SnapshotBridging(t *testing.T) {
+	book := NewLocalOrderBook("BTCUSDT")
+	if book.Features(20, time.Minute) != nil {
+		t.Fatal("未同步时不应返回盘口特征")
+	}
+
+	// 快照到达前的推送先缓冲：第一条早于快照被丢弃，第二条跨过快照序号
+	_ = book.Apply(depthEvent(90, 95, 89, [][2]string{{"97", "100"}}, nil))
+	_ = book.Apply(depthEvent(96, 105, 95, [][2]string{{"99", "0"}, {"99.5", "1"}}, nil))
+	if err := book.LoadSnapshot(testSnapshot()); err != nil {
+		t.Fatalf("加载快照失败: %v", err)
+	}
+
+	// 快照之后的推送按 pu 衔接
+	if err := book.Apply(depthEvent(106, 110, 105, nil, [][2]string{{"101", "3"}})); err != nil {
+		t.Fatalf("应用增量失败: %v", err)
+	}
+
+	data := book.Features(20, time.Minute)
+	if data == nil {
+		t.Fatal("同步后应返回盘口特征")
+	}
+	if data.BestBid != 99.5 || data.BestAsk != 101 {
+		t.Errorf("最优买卖价不正确: bid=%v ask=%v", data.BestBid, data.BestAsk)
+	}
+	// 买盘: 99.5*1 + 98*3 = 393.5（97 的推送早于快照，99 已删除）；卖盘: 101*3 + 102*4 = 711
+	if math.Abs(data.BidDepthUSD-393.5) > 1e-9 || math.Abs(data.AskDepthUSD-711) > 1e-9 {
+		t.Errorf("深度不正确: bids=%v asks=%v", data.BidDepthUSD, data.AskDepthUSD)
+	}
+	if math.Abs(data.Imbalance-(393.5-711)/(393.5+711)) > 1e-9 {
+		t.Errorf("盘口失衡不正确: %v", data.Imbalance)
+	}
+	if math.Abs(data.SpreadBps-1.5/100.25*10000) > 1e-9 {
+		t.Errorf("价差不正确: %v", data.SpreadBps)
+	}
+
+	// 只统计前N档
+	if data := book.Features(1, time.Minute); math.Abs(data.AskDepthUSD-303) > 1e-9 {
+		t.Errorf("前1档卖盘深度应为 303，实际 %v", data.AskDepthUSD)
+	}
+}
+
+func TestLocalOrderBook_GapDetection(t *testing.T) {
+	t.Run("快照早于第一条推送", func(t *testing.T) {
+		book := NewLocalOrderBook("BTCUSDT")
+		_ = book.Apply(depthEvent(120, 125, 119, nil, nil))
+		if err := book.LoadSnapshot(testSnapshot()); err != errOrderBookGap {
+			t.Errorf("期望断档错误，实际 %v", err)
+		}
+		if !book.needsSync() {
+			t.Error("断档后应需要重新同步")
+		}
+	})
+
+	t.Run("推送序列断档", func(t *testing.T) {
+		book := NewLocalOrderBook("BTCUSDT")
+		if err := book.LoadSnapshot(testSnapshot()); err != nil {
+			t.Fatalf("加载快照失败: %v", err)
+		}
+		if err := book.Apply(depthEvent(98, 103, 97, nil, nil)); err != nil {
+			t.Fatalf("第一条推送应衔接快照: %v", err)
+		}
+		if err := book.Apply(depthEvent(110, 112, 108, nil, nil)); err != errOrderBookGap {
+			t.Errorf("期望断档错误，实际 %v", err)
+		}
+		if book.Features(20, time.Minute) != nil {
+			t.Error("断档后不应返回盘口特征")
+		}
+	})
+
+	t.Run("长时间未更新视为失效", func(t *testing.T) {
+		book := NewLocalOrderBook("BTCUSDT")
+		_ = book.LoadSnapshot(testSnapshot())
+		book.updatedAt = time.Now().Add(-time.Minute)
+		if book.Features(20, 30*time.Second) != nil {
+			t.Error("失效的订单簿不应返回盘口特征")
+		}
+	})
+}
+
+func TestTradeFlow_Window(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	flow := newTradeFlow(5*time.Minute, start)
+
+	trade := func(at time.Time, price, qty string, buyerMaker bool) {
+		flow.Add(&AggTradeWSData{Price: price, Quantity: qty, TradeTime: at.UnixMilli(), IsBuyerMaker: buyerMaker})
+	}
+	trade(start, "100", "1", false)                   // 主动买入 100
+	trade(start.Add(2*time.Minute), "100", "3", true) // 主动卖出 300
+
+	buy, sell, window := flow.Totals(start.Add(3 * time.Minute))
+	if buy != 100 || sell != 300 || window != 3*time.Minute {
+		t.Errorf("订阅不足一个窗口: buy=%v sell=%v window=%v", buy, sell, window)
+	}
+
+	// 超出窗口的成交不再统计
+	trade(start.Add(6*time.Minute), "110", "2", false)            // 主动买入 220
+	trade(start.Add(6*time.Minute+time.Second), "110", "1", true) // 主动卖出 110
+	buy, sell, window = flow.Totals(start.Add(6*time.Minute + 2*time.Second))
+	if buy != 220 || sell != 410 || window != 5*time.Minute {
+		t.Errorf("窗口统计不正确: buy=%v sell=%v window=%v", buy, sell, window)
+	}
+}
+
+func TestCalculateTakerDelta(t *testing.T) {
+	klines := generateTestKlines(3)
+	if deltas := calculateTakerDelta(klines); deltas != nil {
+		t.Errorf("K线不含主动买入数据时应返回 nil，实际 %v", deltas)
+	}
+
+	klines[0].TakerBuyBaseVolume = 700 // 成交量 1000：买 700 卖 300
+	klines[1].TakerBuyBaseVolume = 300 // 成交量 1100：买 300 卖 800
+	deltas := calculateTakerDelta(klines)
+	expected := []float64{400, -500, -1200}
+	for i := range expected {
+		if deltas[i] != expected[i] {
+			t.Errorf("deltas[%d] = %v, want %v", i, deltas[i], expected[i])
+		}
+	}
+}
+
+func TestFormat_OrderFlow(t *testing.T) {
+	klines := generateTestKlines(30)
+	for i := range klines {
+		klines[i].TakerBuyBaseVolume = klines[i].Volume * 0.6
+	}
+	data := BuildData("BTCUSDT", klines, klines, nil, 0.0001)
+	if text := Format(data); strings.Contains(text, "Order book") {
+		t.Error("没有盘口数据时不应输出盘口段落")
+	}
+
+	data.OrderFlow = &OrderFlowData{
+		BestBid: 100.2, BestAsk: 100.3, SpreadBps: 9.98, DepthLevels: 20,
+		BidDepthUSD: 150000, AskDepthUSD: 50000, Imbalance: 0.5,
+		TradeWindow: 5 * time.Minute, TakerBuyUSD: 80000, TakerSellUSD: 120000, TakerDeltaUSD: -40000,
+	}
+	text := Format(data)
+	for _, want := range []string{
+		"Order book: best bid 100.20 / best ask 100.30, spread 9.98 bps",
+		"Top 20 levels depth: bids 150000 USD vs asks 50000 USD (imbalance +0.50",
+		"Taker flow (last 5m0s): buy 80000 USD vs sell 120000 USD, delta -40000 USD",
+		"Taker buy − sell volume: [",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Format 缺少 %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestGetOrderFlow_SubscribeFailureRetries(t *testing.T) {
+	// 组合流未连接：订阅请求失败
+	var fetches atomic.Int32
+	m := &WSMonitor{
+		combinedClient: NewCombinedStreamsClient(10),
+		fetchOrderBook: func(symbol string, limit int) (*OrderBook, error) {
+			fetches.Add(1)
+			return nil, errors.New("unexpected snapshot fetch")
+		},
+	}
+
+	if data := m.GetOrderFlow("BTCUSDT"); data != nil {
+		t.Fatalf("订阅失败时不应返回数据: %+v", data)
+	}
+	if _, exists := m.orderBooks.Load("BTCUSDT"); exists {
+		t.Error("订阅失败后应移除订单簿，下次调用重新订阅")
+	}
+	if _, exists := m.tradeFlows.Load("BTCUSDT"); exists {
+		t.Error("订阅失败后应移除成交流")
+	}
+	m.combinedClient.mu.RLock()
+	subscribers := len(m.combinedClient.subscribers)
+	m.combinedClient.mu.RUnlock()
+	if subscribers != 0 {
+		t.Errorf("订阅失败后应移除订阅者，实际 %d 个", subscribers)
+	}
+	if n := fetches.Load(); n != 0 {
+		t.Errorf("订阅失败时不应拉取深度快照，实际 %d 次", n)
+	}
+}
+
+func TestSyncOrderBook_StopsWhenUnregistered(t *testing.T) {
+	var fetches atomic.Int32
+	m := &WSMonitor{fetchOrderBook: func(symbol string, limit int) (*OrderBook, error) {
+		fetches.Add(1)
+		return nil, errors.New("unexpected snapshot fetch")
+	}}
+
+	// 订单簿已被撤销注册（订阅失败回滚）：不再拉取快照
+	m.syncOrderBook("BTCUSDT", NewLocalOrderBook("BTCUSDT"))
+	if n := fetches.Load(); n != 0 {
+		t.Errorf("未注册的订单簿不应拉取深度快照，实际 %d 次", n)
+	}
+}
@@ -15,6 +15,8 @@ type Data struct {
 	FundingRate       float64
 	IntradaySeries    *IntradayData
 	LongerTermContext *LongerTermData
+	OrderFlow         *OrderFlowData   // 盘口与成交流（实时流未同步或回测时为 nil）
+	Timeframes        []*TimeframeData // 按交易员配置计算的各周期数据（为空时使用 IntradaySeries/LongerTermContext）
 }
 
 // OIData Open Interest数据
@@ -31,6 +33,7 @@ type IntradayData struct {
 	RSI7Values  []float64
 	RSI14Values []float64
 	Volume      []float64
+	TakerDelta  []float64 // 主动买入量 - 主动卖出量（币本位，来自K线 TakerBuyBaseVolume）
 	ATR14       float64
 }
 
@@ -46,6 +49,49 @@ type LongerTermData struct {
 	RSI14Values   []float64
 }
 
+// TimeframeData 单个K线周期的序列与指标（只计算交易员配置的指标）
+type TimeframeData struct {
+	Interval    string
+	Closes      []float64 // 最近10根收盘价
+	Volume      []float64
+	TakerDelta  []float64 // 主动买入量 - 主动卖出量
+	EMA20Values []float64
+	EMA50       float64
+	MACDValues  []float64
+	RSI7Values  []float64
+	RSI14Values []float64
+	ATR14       float64
+	Bollinger   *BollingerData
+	VWAP        float64 // 当日(UTC)成交量加权均价，日线及以上周期为全部已加载K线的均价
+	StochK      []float64
+	StochD      []float64
+	ADX         *ADXData
+	OBVValues   []float64
+	Supertrend  *SupertrendData
+}
+
+// BollingerData 布林带(20, 2)
+type BollingerData struct {
+	Upper        float64
+	Middle       float64
+	Lower        float64
+	PercentB     float64 // 收盘价在带内的位置（0=下轨，1=上轨）
+	BandwidthPct float64 // 带宽占中轨的百分比
+}
+
+// ADXData 平均趋向指数(14)
+type ADXData struct {
+	ADX     float64
+	PlusDI  float64
+	MinusDI float64
+}
+
+// SupertrendData 超级趋势(10, 3)
+type SupertrendData struct {
+	Value   float64
+	Uptrend bool
+}
+
 // Binance API 响应结构
 type ExchangeInfo struct {
 	Symbols []SymbolInfo `json:"symbols"`
@@ -77,6 +123,42 @@ type Kline struct {
 
 type KlineResponse []interface{}
 
+// OrderFlowData 盘口与成交流特征（来自 depth / aggTrade 实时流）
+type OrderFlowData struct {
+	BestBid       float64
+	BestAsk       float64
+	SpreadBps     float64       // 买卖价差（基点，相对中间价）
+	DepthLevels   int           // 统计深度的档位数
+	BidDepthUSD   float64       // 前N档买盘挂单金额
+	AskDepthUSD   float64       // 前N档卖盘挂单金额
+	Imbalance     float64       // 盘口失衡 (买-卖)/(买+卖)，-1~1，正值表示买盘更厚
+	TradeWindow   time.Duration // 成交流统计窗口（订阅不足一个窗口时为实际时长）
+	TakerBuyUSD   float64       // 窗口内主动买入成交额
+	TakerSellUSD  float64       // 窗口内主动卖出成交额
+	TakerDeltaUSD float64       // 主动买入 - 主动卖出
+}
+
+// OrderBook 订单簿深度快照
+type OrderBook struct {
+	Symbol       string
+	LastUpdateID int64
+	Bids         []PriceLevel // 买盘（价格从高到低）
+	Asks         []PriceLevel // 卖盘（价格从低到高）
+}
+
+// PriceLevel 订单簿价位
+type PriceLevel struct {
+	Price    float64
+	Quantity float64
+}
+
+// FundingRateRecord 历史资金费率
+type FundingRateRecord struct {
+	FundingTime int64 // 结算时间（毫秒）
+	FundingRate float64
+	MarkPrice   float64
+}
+
 type PriceTicker struct {
 	Symbol string `json:"symbol"`
 	Price  string `json:"price"`
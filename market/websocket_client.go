@@ -47,6 +47,31 @@ type KlineWSData struct {
 	} `json:"k"`
 }
 
+// DepthWSData 增量深度推送（<symbol>@depth@100ms）
+type DepthWSData struct {
+	EventType         string      `json:"e"`
+	EventTime         int64       `json:"E"`
+	TransactionTime   int64       `json:"T"`
+	Symbol            string      `json:"s"`
+	FirstUpdateID     int64       `json:"U"`
+	FinalUpdateID     int64       `json:"u"`
+	PrevFinalUpdateID int64       `json:"pu"` // 上一条推送的 u，用于检测断档
+	Bids              [][2]string `json:"b"`
+	Asks              [][2]string `json:"a"`
+}
+
+// AggTradeWSData 归集成交推送（<symbol>@aggTrade）
+type AggTradeWSData struct {
+	EventType    string `json:"e"`
+	EventTime    int64  `json:"E"`
+	Symbol       string `json:"s"`
+	AggTradeID   int64  `json:"a"`
+	Price        string `json:"p"`
+	Quantity     string `json:"q"`
+	TradeTime    int64  `json:"T"`
+	IsBuyerMaker bool   `json:"m"` // true=主动卖出（买方挂单）
+}
+
 type TickerWSData struct {
 	EventType          string `json:"e"`
 	EventTime          int64  `json:"E"`
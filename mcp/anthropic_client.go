@@ -0,0 +1,275 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	ProviderAnthropic       = "anthropic"
+	DefaultAnthropicBaseURL = "https://api.anthropic.com/v1"
+	DefaultAnthropicModel   = "claude-sonnet-4-5"
+	AnthropicAPIVersion     = "2023-06-01"
+)
+
+// AnthropicClient Anthropic Messages API 客户端
+//
+// 与 OpenAI 格式的区别：
+//   - system prompt 为顶层字段，不在 messages 中
+//   - 工具调用/结果使用 tool_use / tool_result 内容块
+//   - 认证使用 x-api-key 头
+type AnthropicClient struct {
+	*Client
+}
+
+// NewAnthropicClient 创建 Anthropic 客户端
+func NewAnthropicClient() AIClient {
+	return NewAnthropicClientWithOptions()
+}
+
+// NewAnthropicClientWithOptions 创建 Anthropic 客户端（支持选项模式）
+//
+// 使用示例：
+//
+//	client := mcp.NewAnthropicClientWithOptions(
+//	    mcp.WithAPIKey("sk-ant-xxx"),
+//	    mcp.WithModel("claude-sonnet-4-5"),
+//	)
+func NewAnthropicClientWithOptions(opts ...ClientOption) AIClient {
+	anthropicOpts := []ClientOption{
+		WithProvider(ProviderAnthropic),
+		WithModel(DefaultAnthropicModel),
+		WithBaseURL(DefaultAnthropicBaseURL),
+	}
+	allOpts := append(anthropicOpts, opts...)
+
+	baseClient := NewClient(allOpts...).(*Client)
+	anthropicClient := &AnthropicClient{Client: baseClient}
+	baseClient.hooks = anthropicClient
+
+	return anthropicClient
+}
+
+func (ac *AnthropicClient) SetAPIKey(apiKey string, customURL string, customModel string) {
+	applyProviderSettings(ac.Client, "Anthropic", apiKey, customURL, customModel)
+}
+
+func (ac *AnthropicClient) setAuthHeader(reqHeaders http.Header) {
+	reqHeaders.Set("x-api-key", ac.APIKey)
+	reqHeaders.Set("anthropic-version", AnthropicAPIVersion)
+}
+
+func (ac *AnthropicClient) buildUrl() string {
+	if ac.UseFullURL {
+		return ac.BaseURL
+	}
+	return fmt.Sprintf("%s/messages", ac.BaseURL)
+}
+
+func (ac *AnthropicClient) buildMCPRequestBody(systemPrompt, userPrompt string) map[string]any {
+	req := &Request{Model: ac.Model, Messages: []Message{NewUserMessage(userPrompt)}}
+	if systemPrompt != "" {
+		req.Messages = append([]Message{NewSystemMessage(systemPrompt)}, req.Messages...)
+	}
+	return ac.buildRequestBodyFromRequest(req)
+}
+
+// buildRequestBodyFromRequest 转换为 Messages API 格式
+func (ac *AnthropicClient) buildRequestBodyFromRequest(req *Request) map[string]any {
+	var systemParts []string
+	var messages []map[string]any
+
+	// 相邻同角色消息合并（API 要求 user/assistant 交替出现，多个 tool_result 需放在同一条 user 消息中）
+	appendBlocks := func(role string, blocks []map[string]any) {
+		if len(blocks) == 0 {
+			return
+		}
+		if n := len(messages); n > 0 && messages[n-1]["role"] == role {
+			messages[n-1]["content"] = append(messages[n-1]["content"].([]map[string]any), blocks...)
+			return
+		}
+		messages = append(messages, map[string]any{"role": role, "content": blocks})
+	}
+
+	for _, msg := range req.Messages {
+		switch msg.Role {
+		case "system":
+			systemParts = append(systemParts, msg.Content)
+		case "tool":
+			appendBlocks("user", []map[string]any{{
+				"type":        "tool_result",
+				"tool_use_id": msg.ToolCallID,
+				"content":     msg.Content,
+			}})
+		case "assistant":
+			var blocks []map[string]any
+			if msg.Content != "" {
+				blocks = append(blocks, map[string]any{"type": "text", "text": msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				blocks = append(blocks, map[string]any{
+					"type":  "tool_use",
+					"id":    call.ID,
+					"name":  call.Function.Name,
+					"input": argumentsObject(call.Function.Arguments),
+				})
+			}
+			appendBlocks("assistant", blocks)
+		default:
+			if msg.Content != "" {
+				appendBlocks("user", []map[string]any{{"type": "text", "text": msg.Content}})
+			}
+		}
+	}
+
+	model := req.Model
+	if model == "" {
+		model = ac.Model
+	}
+	requestBody := map[string]any{
+		"model":    model,
+		"messages": messages,
+	}
+	if len(systemParts) > 0 {
+		requestBody["system"] = strings.Join(systemParts, "\n\n")
+	}
+
+	if req.MaxTokens != nil {
+		requestBody["max_tokens"] = *req.MaxTokens
+	} else {
+		requestBody["max_tokens"] = ac.MaxTokens // Messages API 必填
+	}
+	if req.Temperature != nil {
+		requestBody["temperature"] = *req.Temperature
+	} else {
+		requestBody["temperature"] = ac.config.Temperature
+	}
+	if req.TopP != nil {
+		requestBody["top_p"] = *req.TopP
+	}
+	if len(req.Stop) > 0 {
+		requestBody["stop_sequences"] = req.Stop
+	}
+
+	if len(req.Tools) > 0 {
+		tools := make([]map[string]any, 0, len(req.Tools))
+		for _, tool := range req.Tools {
+			schema := tool.Function.Parameters
+			if schema == nil {
+				schema = map[string]any{"type": "object"}
+			}
+			tools = append(tools, map[string]any{
+				"name":         tool.Function.Name,
+				"description":  tool.Function.Description,
+				"input_schema": schema,
+			})
+		}
+		requestBody["tools"] = tools
+
+		switch req.ToolChoice {
+		case "", "auto":
+			requestBody["tool_choice"] = map[string]any{"type": "auto"}
+		case "none":
+			requestBody["tool_choice"] = map[string]any{"type": "none"}
+		case "required":
+			requestBody["tool_choice"] = map[string]any{"type": "any"}
+		default:
+			requestBody["tool_choice"] = map[string]any{"type": "tool", "name": req.ToolChoice}
+		}
+	}
+
+	return requestBody
+}
+
+func (ac *AnthropicClient) parseMCPResponse(body []byte) (string, error) {
+	resp, err := ac.parseToolCallResponse(body)
+	if err != nil {
+		return "", err
+	}
+	return resp.Content, nil
+}
+
+// parseToolCallResponse 解析 content 中的 text / tool_use 内容块
+func (ac *AnthropicClient) parseToolCallResponse(body []byte) (*Response, error) {
+	var result struct {
+		Content []struct {
+			Type  string          `json:"type"`
+			Text  string          `json:"text"`
+			ID    string          `json:"id"`
+			Name  string          `json:"name"`
+			Input json.RawMessage `json:"input"`
+		} `json:"content"`
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+	if len(result.Content) == 0 {
+		return nil, fmt.Errorf("API返回空响应")
+	}
+
+	resp := &Response{Usage: Usage{
+		PromptTokens:     result.Usage.InputTokens,
+		CompletionTokens: result.Usage.OutputTokens,
+		TotalTokens:      result.Usage.InputTokens + result.Usage.OutputTokens,
+	}}
+	var texts []string
+	for _, block := range result.Content {
+		switch block.Type {
+		case "text":
+			texts = append(texts, block.Text)
+		case "tool_use":
+			args := string(block.Input)
+			if args == "" || args == "null" {
+				args = "{}"
+			}
+			resp.ToolCalls = append(resp.ToolCalls, ToolCall{
+				ID:       block.ID,
+				Type:     "function",
+				Function: FunctionCall{Name: block.Name, Arguments: args},
+			})
+		}
+	}
+	resp.Content = strings.Join(texts, "\n")
+	return resp, nil
+}
+
+// isRetryableError 额外重试服务过载（HTTP 529 overloaded_error）
+func (ac *AnthropicClient) isRetryableError(err error) bool {
+	return strings.Contains(err.Error(), "overloaded") || ac.Client.isRetryableError(err)
+}
+
+// argumentsObject 将工具调用参数（JSON 字符串）转换为对象，无效时返回空对象
+func argumentsObject(arguments string) json.RawMessage {
+	if arguments = strings.TrimSpace(arguments); arguments == "" || !json.Valid([]byte(arguments)) {
+		return json.RawMessage("{}")
+	}
+	return json.RawMessage(arguments)
+}
+
+// applyProviderSettings 设置 API Key 及可选的自定义 BaseURL / Model（为空时保留默认值）
+func applyProviderSettings(client *Client, name, apiKey, customURL, customModel string) {
+	client.APIKey = apiKey
+
+	if len(apiKey) > 8 {
+		client.logger.Infof("🔧 [MCP] %s API Key: %s...%s", name, apiKey[:4], apiKey[len(apiKey)-4:])
+	}
+	if customURL != "" {
+		client.BaseURL = customURL
+		client.logger.Infof("🔧 [MCP] %s 使用自定义 BaseURL: %s", name, customURL)
+	} else {
+		client.logger.Infof("🔧 [MCP] %s 使用默认 BaseURL: %s", name, client.BaseURL)
+	}
+	if customModel != "" {
+		client.Model = customModel
+		client.logger.Infof("🔧 [MCP] %s 使用自定义 Model: %s", name, customModel)
+	} else {
+		client.logger.Infof("🔧 [MCP] %s 使用默认 Model: %s", name, client.Model)
+	}
+}
@@ -0,0 +1,138 @@
+package mcp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNewAnthropicClient_Default(t *testing.T) {
+	client, ok := NewAnthropicClient().(*AnthropicClient)
+	if !ok {
+		t.Fatal("client should be *AnthropicClient")
+	}
+	if client.Provider != ProviderAnthropic || client.BaseURL != DefaultAnthropicBaseURL || client.Model != DefaultAnthropicModel {
+		t.Errorf("unexpected defaults: %s", client.String())
+	}
+	if client.buildUrl() != DefaultAnthropicBaseURL+"/messages" {
+		t.Errorf("unexpected url: %s", client.buildUrl())
+	}
+}
+
+func TestAnthropicClient_CallWithMessages(t *testing.T) {
+	mockHTTP := NewMockHTTPClient()
+	mockHTTP.Response = `{"content":[{"type":"text","text":"hello"}],"usage":{"input_tokens":10,"output_tokens":5}}`
+
+	client := NewAnthropicClientWithOptions(
+		WithHTTPClient(mockHTTP.ToHTTPClient()),
+		WithLogger(NewMockLogger()),
+		WithAPIKey("sk-ant-test"),
+	)
+
+	result, err := client.CallWithMessages("system prompt", "user prompt")
+	if err != nil {
+		t.Fatalf("should not error: %v", err)
+	}
+	if result != "hello" {
+		t.Errorf("unexpected result: %q", result)
+	}
+
+	req := mockHTTP.GetLastRequest()
+	if req.Header.Get("x-api-key") != "sk-ant-test" || req.Header.Get("anthropic-version") != AnthropicAPIVersion {
+		t.Errorf("unexpected auth headers: %v", req.Header)
+	}
+	if req.Header.Get("Authorization") != "" {
+		t.Error("should not send Bearer token")
+	}
+
+	var body map[string]any
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode request body: %v", err)
+	}
+	if body["system"] != "system prompt" {
+		t.Errorf("system prompt should be top-level, got %v", body["system"])
+	}
+	if messages := body["messages"].([]any); len(messages) != 1 {
+		t.Errorf("expected 1 message, got %d", len(messages))
+	}
+	if body["max_tokens"] == nil {
+		t.Error("max_tokens is required")
+	}
+}
+
+func TestAnthropicClient_ToolCalls(t *testing.T) {
+	mockHTTP := NewMockHTTPClient()
+	mockHTTP.Response = `{
+		"content": [
+			{"type": "text", "text": "BTC 突破"},
+			{"type": "tool_use", "id": "toolu_1", "name": "open_long", "input": {"symbol": "BTCUSDT", "leverage": 5}}
+		],
+		"usage": {"input_tokens": 100, "output_tokens": 20}
+	}`
+
+	client := NewAnthropicClientWithOptions(
+		WithHTTPClient(mockHTTP.ToHTTPClient()),
+		WithLogger(NewMockLogger()),
+		WithAPIKey("sk-ant-test"),
+	)
+
+	calls := []ToolCall{
+		{ID: "toolu_0", Type: "function", Function: FunctionCall{Name: "get_klines", Arguments: `{"symbol":"BTCUSDT"}`}},
+		{ID: "toolu_9", Type: "function", Function: FunctionCall{Name: "get_orderbook", Arguments: ``}},
+	}
+	request := NewRequestBuilder().
+		WithSystemPrompt("system").
+		WithUserPrompt("decide").
+		AddMessages(
+			NewAssistantToolCallMessage("", calls),
+			NewToolMessage("toolu_0", "klines..."),
+			NewToolMessage("toolu_9", "orderbook..."),
+		).
+		AddFunction("open_long", "开多仓", map[string]any{"type": "object"}).
+		WithToolChoice("auto").
+		MustBuild()
+
+	resp, err := client.(ToolCaller).CallWithTools(request)
+	if err != nil {
+		t.Fatalf("should not error: %v", err)
+	}
+	if resp.Content != "BTC 突破" || len(resp.ToolCalls) != 1 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if call := resp.ToolCalls[0]; call.ID != "toolu_1" || call.Function.Name != "open_long" || call.Function.Arguments != `{"symbol": "BTCUSDT", "leverage": 5}` {
+		t.Errorf("unexpected tool call: %+v", call)
+	}
+	if resp.Usage.TotalTokens != 120 {
+		t.Errorf("unexpected usage: %+v", resp.Usage)
+	}
+
+	var body struct {
+		Messages []struct {
+			Role    string           `json:"role"`
+			Content []map[string]any `json:"content"`
+		} `json:"messages"`
+		Tools      []map[string]any `json:"tools"`
+		ToolChoice map[string]any   `json:"tool_choice"`
+	}
+	if err := json.NewDecoder(mockHTTP.GetLastRequest().Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode request body: %v", err)
+	}
+	// user → assistant(tool_use×2) → user(tool_result×2)
+	if len(body.Messages) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(body.Messages))
+	}
+	if m := body.Messages[1]; m.Role != "assistant" || len(m.Content) != 2 || m.Content[0]["type"] != "tool_use" {
+		t.Errorf("unexpected assistant message: %+v", m)
+	}
+	if input, ok := body.Messages[1].Content[1]["input"].(map[string]any); !ok || len(input) != 0 {
+		t.Errorf("empty arguments should become {}, got %v", body.Messages[1].Content[1]["input"])
+	}
+	if m := body.Messages[2]; m.Role != "user" || len(m.Content) != 2 || m.Content[1]["tool_use_id"] != "toolu_9" {
+		t.Errorf("tool results should be merged into one user message: %+v", m)
+	}
+	if len(body.Tools) != 1 || body.Tools[0]["input_schema"] == nil {
+		t.Errorf("unexpected tools: %v", body.Tools)
+	}
+	if body.ToolChoice["type"] != "auto" {
+		t.Errorf("unexpected tool_choice: %v", body.ToolChoice)
+	}
+}
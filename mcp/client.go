@@ -135,7 +135,7 @@ func (client *Client) SetTimeout(timeout time.Duration) {
 
 // CallWithMessages 模板方法 - 固定的重试流程（不可重写）
 func (client *Client) CallWithMessages(systemPrompt, userPrompt string) (string, error) {
-	if client.APIKey == "" {
+	if client.APIKey == "" && client.hooks.requiresAPIKey() {
 		return "", fmt.Errorf("AI API密钥未设置，请先调用 SetAPIKey")
 	}
 
@@ -328,6 +328,11 @@ func (client *Client) isRetryableError(err error) bool {
 	return false
 }
 
+// requiresAPIKey 调用前是否必须设置 API Key（本地模型服务可重写为 false）
+func (client *Client) requiresAPIKey() bool {
+	return true
+}
+
 // ============================================================
 // 构建器模式 API（高级功能）
 // ============================================================
@@ -348,7 +353,7 @@ func (client *Client) isRetryableError(err error) bool {
 //       Build()
 //   result, err := client.CallWithRequest(request)
 func (client *Client) CallWithRequest(req *Request) (string, error) {
-	if client.APIKey == "" {
+	if client.APIKey == "" && client.hooks.requiresAPIKey() {
 		return "", fmt.Errorf("AI API密钥未设置，请先调用 SetAPIKey")
 	}
 
@@ -394,17 +399,33 @@ func (client *Client) CallWithRequest(req *Request) (string, error) {
 
 // callWithRequest 单次调用 AI API（使用 Request 对象）
 func (client *Client) callWithRequest(req *Request) (string, error) {
+	body, err := client.doRequest(req)
+	if err != nil {
+		return "", err
+	}
+
+	// 解析响应
+	result, err := client.hooks.parseMCPResponse(body)
+	if err != nil {
+		return "", fmt.Errorf("fail to parse AI server response: %w", err)
+	}
+
+	return result, nil
+}
+
+// doRequest 发送 Request 对象并返回原始响应体
+func (client *Client) doRequest(req *Request) ([]byte, error) {
 	// 打印当前 AI 配置
 	client.logger.Infof("📡 [%s] Request AI Server with Builder: BaseURL: %s", client.String(), client.BaseURL)
 	client.logger.Debugf("[%s] Messages count: %d", client.String(), len(req.Messages))
 
-	// 构建请求体（从 Request 对象）
-	requestBody := client.buildRequestBodyFromRequest(req)
+	// 构建请求体（通过 hooks 实现动态分派，支持非 OpenAI 格式的 provider）
+	requestBody := client.hooks.buildRequestBodyFromRequest(req)
 
 	// 序列化请求体
 	jsonData, err := client.hooks.marshalRequestBody(requestBody)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// 构建 URL
@@ -414,45 +435,140 @@ func (client *Client) callWithRequest(req *Request) (string, error) {
 	// 创建 HTTP 请求
 	httpReq, err := client.hooks.buildRequest(url, jsonData)
 	if err != nil {
-		return "", fmt.Errorf("创建请求失败: %w", err)
+		return nil, fmt.Errorf("创建请求失败: %w", err)
 	}
 
 	// 发送 HTTP 请求
 	resp, err := client.httpClient.Do(httpReq)
 	if err != nil {
-		return "", fmt.Errorf("发送请求失败: %w", err)
+		return nil, fmt.Errorf("发送请求失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// 读取响应体
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("读取响应失败: %w", err)
+		return nil, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	// 检查 HTTP 状态码
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API返回错误 (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("API返回错误 (status %d): %s", resp.StatusCode, string(body))
 	}
 
-	// 解析响应
-	result, err := client.hooks.parseMCPResponse(body)
+	return body, nil
+}
+
+// CallWithTools 使用 Function Calling 调用 AI API，返回文本内容和结构化的工具调用
+//
+// 使用示例：
+//   request := NewRequestBuilder().
+//       WithSystemPrompt("You are a trader").
+//       WithUserPrompt("BTC 行情...").
+//       AddFunction("open_long", "开多仓", schema).
+//       WithToolChoice("auto").
+//       MustBuild()
+//   resp, err := client.CallWithTools(request)
+func (client *Client) CallWithTools(req *Request) (*Response, error) {
+	if client.APIKey == "" && client.hooks.requiresAPIKey() {
+		return nil, fmt.Errorf("AI API密钥未设置，请先调用 SetAPIKey")
+	}
+
+	if req.Model == "" {
+		req.Model = client.Model
+	}
+
+	var lastErr error
+	maxRetries := client.config.MaxRetries
+
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		if attempt > 1 {
+			client.logger.Warnf("⚠️  AI API调用失败，正在重试 (%d/%d)...", attempt, maxRetries)
+		}
+
+		result, err := client.callWithTools(req)
+		if err == nil {
+			if attempt > 1 {
+				client.logger.Infof("✓ AI API重试成功")
+			}
+			return result, nil
+		}
+
+		lastErr = err
+		if !client.hooks.isRetryableError(err) {
+			return nil, err
+		}
+
+		if attempt < maxRetries {
+			waitTime := client.config.RetryWaitBase * time.Duration(attempt)
+			client.logger.Infof("⏳ 等待%v后重试...", waitTime)
+			time.Sleep(waitTime)
+		}
+	}
+
+	return nil, fmt.Errorf("重试%d次后仍然失败: %w", maxRetries, lastErr)
+}
+
+// callWithTools 单次 Function Calling 调用
+func (client *Client) callWithTools(req *Request) (*Response, error) {
+	body, err := client.doRequest(req)
 	if err != nil {
-		return "", fmt.Errorf("fail to parse AI server response: %w", err)
+		return nil, err
+	}
+
+	result, err := client.hooks.parseToolCallResponse(body)
+	if err != nil {
+		return nil, fmt.Errorf("fail to parse AI server response: %w", err)
 	}
+	client.logger.Debugf("[%s] Tool calls: %d", client.String(), len(result.ToolCalls))
 
 	return result, nil
 }
 
+// parseToolCallResponse 解析 OpenAI 兼容格式的 message.content 和 message.tool_calls
+func (client *Client) parseToolCallResponse(body []byte) (*Response, error) {
+	var result struct {
+		Choices []struct {
+			Message struct {
+				Content   *string    `json:"content"` // 只有工具调用时可能为 null
+				ToolCalls []ToolCall `json:"tool_calls"`
+			} `json:"message"`
+		} `json:"choices"`
+		Usage Usage `json:"usage"`
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+
+	if len(result.Choices) == 0 {
+		return nil, fmt.Errorf("API返回空响应")
+	}
+
+	message := result.Choices[0].Message
+	resp := &Response{ToolCalls: message.ToolCalls, Usage: result.Usage}
+	if message.Content != nil {
+		resp.Content = *message.Content
+	}
+	return resp, nil
+}
+
 // buildRequestBodyFromRequest 从 Request 对象构建请求体
 func (client *Client) buildRequestBodyFromRequest(req *Request) map[string]any {
 	// 转换 Message 为 API 格式
-	messages := make([]map[string]string, 0, len(req.Messages))
+	messages := make([]map[string]any, 0, len(req.Messages))
 	for _, msg := range req.Messages {
-		messages = append(messages, map[string]string{
+		message := map[string]any{
 			"role":    msg.Role,
 			"content": msg.Content,
-		})
+		}
+		if len(msg.ToolCalls) > 0 {
+			message["tool_calls"] = msg.ToolCalls
+		}
+		if msg.ToolCallID != "" {
+			message["tool_call_id"] = msg.ToolCallID
+		}
+		messages = append(messages, message)
 	}
 
 	// 构建基础请求体
@@ -1,6 +1,7 @@
 package mcp
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
 	"testing"
@@ -417,3 +418,126 @@ func findSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+func TestClient_CallWithTools(t *testing.T) {
+	mockHTTP := NewMockHTTPClient()
+	mockHTTP.Response = `{
+		"choices": [{
+			"message": {
+				"content": "BTC 突破，开多",
+				"tool_calls": [{
+					"id": "call_1",
+					"type": "function",
+					"function": {"name": "open_long", "arguments": "{\"symbol\":\"BTCUSDT\",\"leverage\":5}"}
+				}]
+			}
+		}]
+	}`
+
+	client := NewClient(
+		WithHTTPClient(mockHTTP.ToHTTPClient()),
+		WithLogger(NewMockLogger()),
+		WithAPIKey("sk-test-key"),
+	)
+
+	request := NewRequestBuilder().
+		WithUserPrompt("decide").
+		AddFunction("open_long", "开多仓", map[string]any{"type": "object"}).
+		WithToolChoice("auto").
+		MustBuild()
+
+	toolCaller, ok := client.(ToolCaller)
+	if !ok {
+		t.Fatal("Client should implement ToolCaller")
+	}
+	resp, err := toolCaller.CallWithTools(request)
+	if err != nil {
+		t.Fatalf("should not error: %v", err)
+	}
+
+	if resp.Content != "BTC 突破，开多" {
+		t.Errorf("unexpected content: %q", resp.Content)
+	}
+	if len(resp.ToolCalls) != 1 {
+		t.Fatalf("expected 1 tool call, got %d", len(resp.ToolCalls))
+	}
+	call := resp.ToolCalls[0]
+	if call.ID != "call_1" || call.Function.Name != "open_long" || call.Function.Arguments != `{"symbol":"BTCUSDT","leverage":5}` {
+		t.Errorf("unexpected tool call: %+v", call)
+	}
+
+	// 验证请求体包含 tools 和 tool_choice
+	var body map[string]interface{}
+	if err := json.NewDecoder(mockHTTP.GetLastRequest().Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode request body: %v", err)
+	}
+	if tools, ok := body["tools"].([]interface{}); !ok || len(tools) != 1 {
+		t.Errorf("tools should be sent, got %v", body["tools"])
+	}
+	if body["tool_choice"] != "auto" {
+		t.Errorf("expected tool_choice auto, got %v", body["tool_choice"])
+	}
+}
+
+func TestClient_ParseToolCallResponse_NullContent(t *testing.T) {
+	client := NewClient().(*Client)
+
+	resp, err := client.parseToolCallResponse([]byte(`{"choices":[{"message":{"content":null,"tool_calls":[{"id":"c1","type":"function","function":{"name":"wait","arguments":"{}"}}]}}]}`))
+	if err != nil {
+		t.Fatalf("should not error: %v", err)
+	}
+	if resp.Content != "" || len(resp.ToolCalls) != 1 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	if _, err := client.parseToolCallResponse([]byte(`{"choices":[]}`)); err == nil {
+		t.Error("empty choices should error")
+	}
+}
+
+func TestClient_CallWithTools_ToolMessages(t *testing.T) {
+	mockHTTP := NewMockHTTPClient()
+	mockHTTP.Response = `{"choices":[{"message":{"content":"done"}}],"usage":{"prompt_tokens":120,"completion_tokens":30,"total_tokens":150}}`
+
+	client := NewClient(
+		WithHTTPClient(mockHTTP.ToHTTPClient()),
+		WithLogger(NewMockLogger()),
+		WithAPIKey("sk-test-key"),
+	)
+
+	calls := []ToolCall{{ID: "call_1", Type: "function", Function: FunctionCall{Name: "get_klines", Arguments: `{"symbol":"BTCUSDT"}`}}}
+	request := NewRequestBuilder().
+		WithUserPrompt("decide").
+		AddMessages(NewAssistantToolCallMessage("", calls), NewToolMessage("call_1", "klines...")).
+		AddFunction("get_klines", "K线", map[string]any{"type": "object"}).
+		MustBuild()
+
+	resp, err := client.(ToolCaller).CallWithTools(request)
+	if err != nil {
+		t.Fatalf("should not error: %v", err)
+	}
+	if resp.Usage.TotalTokens != 150 || resp.Usage.PromptTokens != 120 {
+		t.Errorf("unexpected usage: %+v", resp.Usage)
+	}
+
+	var body struct {
+		Messages []struct {
+			Role       string     `json:"role"`
+			Content    string     `json:"content"`
+			ToolCalls  []ToolCall `json:"tool_calls"`
+			ToolCallID string     `json:"tool_call_id"`
+		} `json:"messages"`
+	}
+	if err := json.NewDecoder(mockHTTP.GetLastRequest().Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode request body: %v", err)
+	}
+	if len(body.Messages) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(body.Messages))
+	}
+	if m := body.Messages[1]; m.Role != "assistant" || len(m.ToolCalls) != 1 || m.ToolCalls[0].Function.Name != "get_klines" {
+		t.Errorf("assistant tool call message not serialized: %+v", m)
+	}
+	if m := body.Messages[2]; m.Role != "tool" || m.ToolCallID != "call_1" || m.Content != "klines..." {
+		t.Errorf("tool message not serialized: %+v", m)
+	}
+}
@@ -0,0 +1,262 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	ProviderGemini       = "gemini"
+	DefaultGeminiBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+	DefaultGeminiModel   = "gemini-2.5-flash"
+)
+
+// GeminiClient Google Gemini generateContent 客户端
+//
+// 与 OpenAI 格式的区别：
+//   - 模型名在 URL 中（/models/{model}:generateContent）
+//   - 消息为 contents[].parts[]，助手角色为 model，system prompt 为 systemInstruction
+//   - 工具调用/结果使用 functionCall / functionResponse，按函数名关联（无调用ID）
+//   - 认证使用 x-goog-api-key 头
+type GeminiClient struct {
+	*Client
+}
+
+// NewGeminiClient 创建 Gemini 客户端
+func NewGeminiClient() AIClient {
+	return NewGeminiClientWithOptions()
+}
+
+// NewGeminiClientWithOptions 创建 Gemini 客户端（支持选项模式）
+//
+// 使用示例：
+//
+//	client := mcp.NewGeminiClientWithOptions(
+//	    mcp.WithAPIKey("AIza-xxx"),
+//	    mcp.WithModel("gemini-2.5-pro"),
+//	)
+func NewGeminiClientWithOptions(opts ...ClientOption) AIClient {
+	geminiOpts := []ClientOption{
+		WithProvider(ProviderGemini),
+		WithModel(DefaultGeminiModel),
+		WithBaseURL(DefaultGeminiBaseURL),
+	}
+	allOpts := append(geminiOpts, opts...)
+
+	baseClient := NewClient(allOpts...).(*Client)
+	geminiClient := &GeminiClient{Client: baseClient}
+	baseClient.hooks = geminiClient
+
+	return geminiClient
+}
+
+func (gc *GeminiClient) SetAPIKey(apiKey string, customURL string, customModel string) {
+	applyProviderSettings(gc.Client, "Gemini", apiKey, customURL, customModel)
+}
+
+func (gc *GeminiClient) setAuthHeader(reqHeaders http.Header) {
+	reqHeaders.Set("x-goog-api-key", gc.APIKey)
+}
+
+func (gc *GeminiClient) buildUrl() string {
+	if gc.UseFullURL {
+		return gc.BaseURL
+	}
+	return fmt.Sprintf("%s/models/%s:generateContent", gc.BaseURL, gc.Model)
+}
+
+func (gc *GeminiClient) buildMCPRequestBody(systemPrompt, userPrompt string) map[string]any {
+	req := &Request{Messages: []Message{NewUserMessage(userPrompt)}}
+	if systemPrompt != "" {
+		req.Messages = append([]Message{NewSystemMessage(systemPrompt)}, req.Messages...)
+	}
+	return gc.buildRequestBodyFromRequest(req)
+}
+
+// buildRequestBodyFromRequest 转换为 generateContent 格式
+// 注意：模型名在 URL 中，Request.Model 不会生效
+func (gc *GeminiClient) buildRequestBodyFromRequest(req *Request) map[string]any {
+	var systemParts []map[string]any
+	var contents []map[string]any
+	callNames := make(map[string]string) // 工具调用ID → 函数名（functionResponse 按函数名关联）
+
+	// 相邻同角色消息合并（多个 functionResponse 需放在同一条消息中）
+	appendParts := func(role string, parts []map[string]any) {
+		if len(parts) == 0 {
+			return
+		}
+		if n := len(contents); n > 0 && contents[n-1]["role"] == role {
+			contents[n-1]["parts"] = append(contents[n-1]["parts"].([]map[string]any), parts...)
+			return
+		}
+		contents = append(contents, map[string]any{"role": role, "parts": parts})
+	}
+
+	for _, msg := range req.Messages {
+		switch msg.Role {
+		case "system":
+			systemParts = append(systemParts, map[string]any{"text": msg.Content})
+		case "tool":
+			appendParts("user", []map[string]any{{
+				"functionResponse": map[string]any{
+					"name":     callNames[msg.ToolCallID],
+					"response": map[string]any{"content": msg.Content},
+				},
+			}})
+		case "assistant":
+			var parts []map[string]any
+			if msg.Content != "" {
+				parts = append(parts, map[string]any{"text": msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				callNames[call.ID] = call.Function.Name
+				parts = append(parts, map[string]any{
+					"functionCall": map[string]any{
+						"name": call.Function.Name,
+						"args": argumentsObject(call.Function.Arguments),
+					},
+				})
+			}
+			appendParts("model", parts)
+		default:
+			if msg.Content != "" {
+				appendParts("user", []map[string]any{{"text": msg.Content}})
+			}
+		}
+	}
+
+	requestBody := map[string]any{"contents": contents}
+	if len(systemParts) > 0 {
+		requestBody["systemInstruction"] = map[string]any{"parts": systemParts}
+	}
+
+	generationConfig := map[string]any{
+		"temperature":     gc.config.Temperature,
+		"maxOutputTokens": gc.MaxTokens,
+	}
+	if req.Temperature != nil {
+		generationConfig["temperature"] = *req.Temperature
+	}
+	if req.MaxTokens != nil {
+		generationConfig["maxOutputTokens"] = *req.MaxTokens
+	}
+	if req.TopP != nil {
+		generationConfig["topP"] = *req.TopP
+	}
+	if len(req.Stop) > 0 {
+		generationConfig["stopSequences"] = req.Stop
+	}
+	requestBody["generationConfig"] = generationConfig
+
+	if len(req.Tools) > 0 {
+		declarations := make([]map[string]any, 0, len(req.Tools))
+		for _, tool := range req.Tools {
+			declaration := map[string]any{
+				"name":        tool.Function.Name,
+				"description": tool.Function.Description,
+			}
+			if tool.Function.Parameters != nil {
+				declaration["parameters"] = tool.Function.Parameters
+			}
+			declarations = append(declarations, declaration)
+		}
+		requestBody["tools"] = []map[string]any{{"functionDeclarations": declarations}}
+
+		callingConfig := map[string]any{"mode": "AUTO"}
+		switch req.ToolChoice {
+		case "", "auto":
+		case "none":
+			callingConfig["mode"] = "NONE"
+		case "required":
+			callingConfig["mode"] = "ANY"
+		default:
+			callingConfig["mode"] = "ANY"
+			callingConfig["allowedFunctionNames"] = []string{req.ToolChoice}
+		}
+		requestBody["toolConfig"] = map[string]any{"functionCallingConfig": callingConfig}
+	}
+
+	return requestBody
+}
+
+func (gc *GeminiClient) parseMCPResponse(body []byte) (string, error) {
+	resp, err := gc.parseToolCallResponse(body)
+	if err != nil {
+		return "", err
+	}
+	return resp.Content, nil
+}
+
+// parseToolCallResponse 解析 candidates[0].content.parts 中的 text / functionCall
+func (gc *GeminiClient) parseToolCallResponse(body []byte) (*Response, error) {
+	var result struct {
+		Candidates []struct {
+			Content struct {
+				Parts []struct {
+					Text         string `json:"text"`
+					Thought      bool   `json:"thought"`
+					FunctionCall *struct {
+						ID   string          `json:"id"`
+						Name string          `json:"name"`
+						Args json.RawMessage `json:"args"`
+					} `json:"functionCall"`
+				} `json:"parts"`
+			} `json:"content"`
+			FinishReason string `json:"finishReason"`
+		} `json:"candidates"`
+		UsageMetadata struct {
+			PromptTokenCount     int `json:"promptTokenCount"`
+			CandidatesTokenCount int `json:"candidatesTokenCount"`
+			TotalTokenCount      int `json:"totalTokenCount"`
+		} `json:"usageMetadata"`
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+	if len(result.Candidates) == 0 {
+		return nil, fmt.Errorf("API返回空响应")
+	}
+	candidate := result.Candidates[0]
+	if len(candidate.Content.Parts) == 0 {
+		return nil, fmt.Errorf("API返回空响应 (finishReason: %s)", candidate.FinishReason)
+	}
+
+	resp := &Response{Usage: Usage{
+		PromptTokens:     result.UsageMetadata.PromptTokenCount,
+		CompletionTokens: result.UsageMetadata.CandidatesTokenCount,
+		TotalTokens:      result.UsageMetadata.TotalTokenCount,
+	}}
+	var texts []string
+	for _, part := range candidate.Content.Parts {
+		if part.FunctionCall != nil {
+			id := part.FunctionCall.ID
+			if id == "" {
+				id = fmt.Sprintf("call_%d", len(resp.ToolCalls)+1)
+			}
+			args := string(part.FunctionCall.Args)
+			if args == "" || args == "null" {
+				args = "{}"
+			}
+			resp.ToolCalls = append(resp.ToolCalls, ToolCall{
+				ID:       id,
+				Type:     "function",
+				Function: FunctionCall{Name: part.FunctionCall.Name, Arguments: args},
+			})
+			continue
+		}
+		if part.Text != "" && !part.Thought {
+			texts = append(texts, part.Text)
+		}
+	}
+	resp.Content = strings.Join(texts, "")
+	return resp, nil
+}
+
+// isRetryableError 额外重试限流和服务过载（RESOURCE_EXHAUSTED / UNAVAILABLE）
+func (gc *GeminiClient) isRetryableError(err error) bool {
+	errStr := err.Error()
+	return strings.Contains(errStr, "RESOURCE_EXHAUSTED") || strings.Contains(errStr, "UNAVAILABLE") || gc.Client.isRetryableError(err)
+}
@@ -0,0 +1,132 @@
+package mcp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNewGeminiClient_Default(t *testing.T) {
+	client, ok := NewGeminiClient().(*GeminiClient)
+	if !ok {
+		t.Fatal("client should be *GeminiClient")
+	}
+	if client.Provider != ProviderGemini || client.Model != DefaultGeminiModel {
+		t.Errorf("unexpected defaults: %s", client.String())
+	}
+	want := DefaultGeminiBaseURL + "/models/" + DefaultGeminiModel + ":generateContent"
+	if client.buildUrl() != want {
+		t.Errorf("url should be %s, got %s", want, client.buildUrl())
+	}
+}
+
+func TestGeminiClient_CallWithMessages(t *testing.T) {
+	mockHTTP := NewMockHTTPClient()
+	mockHTTP.Response = `{"candidates":[{"content":{"role":"model","parts":[{"text":"hel"},{"text":"lo"}]}}]}`
+
+	client := NewGeminiClientWithOptions(
+		WithHTTPClient(mockHTTP.ToHTTPClient()),
+		WithLogger(NewMockLogger()),
+		WithAPIKey("AIza-test"),
+	)
+
+	result, err := client.CallWithMessages("system prompt", "user prompt")
+	if err != nil {
+		t.Fatalf("should not error: %v", err)
+	}
+	if result != "hello" {
+		t.Errorf("unexpected result: %q", result)
+	}
+
+	req := mockHTTP.GetLastRequest()
+	if req.Header.Get("x-goog-api-key") != "AIza-test" {
+		t.Errorf("unexpected auth headers: %v", req.Header)
+	}
+
+	var body map[string]any
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode request body: %v", err)
+	}
+	if body["systemInstruction"] == nil {
+		t.Error("system prompt should be sent as systemInstruction")
+	}
+	if contents := body["contents"].([]any); len(contents) != 1 {
+		t.Errorf("expected 1 content, got %d", len(contents))
+	}
+	if _, ok := body["model"]; ok {
+		t.Error("model should be in url, not body")
+	}
+}
+
+func TestGeminiClient_ToolCalls(t *testing.T) {
+	mockHTTP := NewMockHTTPClient()
+	mockHTTP.Response = `{
+		"candidates": [{"content": {"role": "model", "parts": [
+			{"text": "思考中", "thought": true},
+			{"text": "BTC 突破"},
+			{"functionCall": {"name": "open_long", "args": {"symbol": "BTCUSDT"}}},
+			{"functionCall": {"name": "wait", "args": {}}}
+		]}}],
+		"usageMetadata": {"promptTokenCount": 80, "candidatesTokenCount": 20, "totalTokenCount": 100}
+	}`
+
+	client := NewGeminiClientWithOptions(
+		WithHTTPClient(mockHTTP.ToHTTPClient()),
+		WithLogger(NewMockLogger()),
+		WithAPIKey("AIza-test"),
+	)
+
+	calls := []ToolCall{{ID: "call_1", Type: "function", Function: FunctionCall{Name: "get_klines", Arguments: `{"symbol":"BTCUSDT"}`}}}
+	request := NewRequestBuilder().
+		WithUserPrompt("decide").
+		AddMessages(NewAssistantToolCallMessage("", calls), NewToolMessage("call_1", "klines...")).
+		AddFunction("open_long", "开多仓", map[string]any{"type": "object"}).
+		WithToolChoice("required").
+		MustBuild()
+
+	resp, err := client.(ToolCaller).CallWithTools(request)
+	if err != nil {
+		t.Fatalf("should not error: %v", err)
+	}
+	if resp.Content != "BTC 突破" {
+		t.Errorf("thought parts should be skipped, got %q", resp.Content)
+	}
+	if len(resp.ToolCalls) != 2 || resp.ToolCalls[0].ID != "call_1" || resp.ToolCalls[1].ID != "call_2" {
+		t.Fatalf("unexpected tool calls: %+v", resp.ToolCalls)
+	}
+	if resp.ToolCalls[0].Function.Arguments != `{"symbol": "BTCUSDT"}` {
+		t.Errorf("unexpected arguments: %s", resp.ToolCalls[0].Function.Arguments)
+	}
+	if resp.Usage.TotalTokens != 100 {
+		t.Errorf("unexpected usage: %+v", resp.Usage)
+	}
+
+	var body struct {
+		Contents []struct {
+			Role  string           `json:"role"`
+			Parts []map[string]any `json:"parts"`
+		} `json:"contents"`
+		ToolConfig struct {
+			FunctionCallingConfig map[string]any `json:"functionCallingConfig"`
+		} `json:"toolConfig"`
+	}
+	if err := json.NewDecoder(mockHTTP.GetLastRequest().Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode request body: %v", err)
+	}
+	if len(body.Contents) != 3 || body.Contents[1].Role != "model" {
+		t.Fatalf("unexpected contents: %+v", body.Contents)
+	}
+	response, ok := body.Contents[2].Parts[0]["functionResponse"].(map[string]any)
+	if !ok || response["name"] != "get_klines" {
+		t.Errorf("functionResponse should reference function name: %+v", body.Contents[2])
+	}
+	if body.ToolConfig.FunctionCallingConfig["mode"] != "ANY" {
+		t.Errorf("unexpected tool config: %+v", body.ToolConfig)
+	}
+}
+
+func TestGeminiClient_EmptyCandidate(t *testing.T) {
+	client := NewGeminiClient().(*GeminiClient)
+	if _, err := client.parseToolCallResponse([]byte(`{"candidates":[{"finishReason":"SAFETY"}]}`)); err == nil {
+		t.Error("blocked candidate should error")
+	}
+}
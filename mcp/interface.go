@@ -13,6 +13,11 @@ type AIClient interface {
 	CallWithRequest(req *Request) (string, error) // 构建器模式 API（支持高级功能）
 }
 
+// ToolCaller 支持原生 Function Calling 的客户端（OpenAI 兼容接口的 tool_calls）
+type ToolCaller interface {
+	CallWithTools(req *Request) (*Response, error)
+}
+
 // clientHooks 内部钩子接口（用于子类重写特定步骤）
 // 这些方法只在包内部使用，实现动态分派
 type clientHooks interface {
@@ -21,10 +26,13 @@ type clientHooks interface {
 	call(systemPrompt, userPrompt string) (string, error)
 
 	buildMCPRequestBody(systemPrompt, userPrompt string) map[string]any
+	buildRequestBodyFromRequest(req *Request) map[string]any
 	buildUrl() string
 	buildRequest(url string, jsonData []byte) (*http.Request, error)
 	setAuthHeader(reqHeaders http.Header)
 	marshalRequestBody(requestBody map[string]any) ([]byte, error)
 	parseMCPResponse(body []byte) (string, error)
+	parseToolCallResponse(body []byte) (*Response, error)
 	isRetryableError(err error) bool
+	requiresAPIKey() bool
 }
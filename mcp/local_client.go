@@ -0,0 +1,63 @@
+package mcp
+
+import (
+	"net/http"
+)
+
+const (
+	ProviderOllama       = "ollama"
+	DefaultOllamaBaseURL = "http://localhost:11434/v1"
+	DefaultOllamaModel   = "qwen2.5:14b"
+
+	ProviderLlamaCpp       = "llamacpp"
+	DefaultLlamaCppBaseURL = "http://localhost:8080/v1"
+	DefaultLlamaCppModel   = "local"
+)
+
+// LocalClient 本地模型服务客户端（Ollama / llama.cpp server 的 OpenAI 兼容接口）
+//
+// 与云端 provider 的区别：API Key 可选，未设置时不发送认证头
+type LocalClient struct {
+	*Client
+	name string // 日志中显示的服务名称
+}
+
+// NewOllamaClientWithOptions 创建 Ollama 客户端（默认 http://localhost:11434/v1）
+func NewOllamaClientWithOptions(opts ...ClientOption) AIClient {
+	return newLocalClient("Ollama", append([]ClientOption{
+		WithProvider(ProviderOllama),
+		WithModel(DefaultOllamaModel),
+		WithBaseURL(DefaultOllamaBaseURL),
+	}, opts...))
+}
+
+// NewLlamaCppClientWithOptions 创建 llama.cpp server 客户端（默认 http://localhost:8080/v1）
+func NewLlamaCppClientWithOptions(opts ...ClientOption) AIClient {
+	return newLocalClient("llama.cpp", append([]ClientOption{
+		WithProvider(ProviderLlamaCpp),
+		WithModel(DefaultLlamaCppModel),
+		WithBaseURL(DefaultLlamaCppBaseURL),
+	}, opts...))
+}
+
+func newLocalClient(name string, opts []ClientOption) AIClient {
+	baseClient := NewClient(opts...).(*Client)
+	localClient := &LocalClient{Client: baseClient, name: name}
+	baseClient.hooks = localClient
+
+	return localClient
+}
+
+func (lc *LocalClient) SetAPIKey(apiKey string, customURL string, customModel string) {
+	applyProviderSettings(lc.Client, lc.name, apiKey, customURL, customModel)
+}
+
+func (lc *LocalClient) setAuthHeader(reqHeaders http.Header) {
+	if lc.APIKey != "" {
+		lc.Client.setAuthHeader(reqHeaders)
+	}
+}
+
+func (lc *LocalClient) requiresAPIKey() bool {
+	return false
+}
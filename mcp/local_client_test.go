@@ -0,0 +1,87 @@
+package mcp
+
+import (
+	"testing"
+)
+
+func TestLocalClient_NoAPIKey(t *testing.T) {
+	mockHTTP := NewMockHTTPClient()
+	mockHTTP.SetSuccessResponse("local response")
+
+	client := NewOllamaClientWithOptions(
+		WithHTTPClient(mockHTTP.ToHTTPClient()),
+		WithLogger(NewMockLogger()),
+	)
+
+	result, err := client.CallWithMessages("system", "user")
+	if err != nil {
+		t.Fatalf("local client should not require API key: %v", err)
+	}
+	if result != "local response" {
+		t.Errorf("unexpected result: %q", result)
+	}
+
+	req := mockHTTP.GetLastRequest()
+	if req.URL.String() != DefaultOllamaBaseURL+"/chat/completions" {
+		t.Errorf("unexpected url: %s", req.URL.String())
+	}
+	if req.Header.Get("Authorization") != "" {
+		t.Error("should not send Authorization header without API key")
+	}
+}
+
+func TestNewClientForProvider(t *testing.T) {
+	tests := []struct {
+		provider string
+		check    func(AIClient) bool
+	}{
+		{ProviderDeepSeek, func(c AIClient) bool { _, ok := c.(*DeepSeekClient); return ok }},
+		{ProviderQwen, func(c AIClient) bool { _, ok := c.(*QwenClient); return ok }},
+		{ProviderAnthropic, func(c AIClient) bool { _, ok := c.(*AnthropicClient); return ok }},
+		{ProviderGemini, func(c AIClient) bool { _, ok := c.(*GeminiClient); return ok }},
+		{ProviderOllama, func(c AIClient) bool { _, ok := c.(*LocalClient); return ok }},
+		{ProviderLlamaCpp, func(c AIClient) bool { _, ok := c.(*LocalClient); return ok }},
+		{ProviderCustom, func(c AIClient) bool { cl, ok := c.(*Client); return ok && cl.Provider == ProviderCustom }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			client, err := NewClientForProvider(tt.provider, "sk-test-key", "", "", WithLogger(NewMockLogger()))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.check(client) {
+				t.Errorf("unexpected client type %T", client)
+			}
+		})
+	}
+}
+
+func TestNewClientForProvider_Unknown(t *testing.T) {
+	if _, err := NewClientForProvider("antropic", "sk-test-key", "", ""); err == nil {
+		t.Fatal("unknown provider should return an error")
+	}
+	if IsSupportedProvider("antropic") {
+		t.Error("unknown provider should not be supported")
+	}
+}
+
+func TestRegisterProvider(t *testing.T) {
+	RegisterProvider("test_provider", NewClient)
+	defer func() {
+		providersMu.Lock()
+		delete(providers, "test_provider")
+		providersMu.Unlock()
+	}()
+
+	if !IsSupportedProvider("test_provider") {
+		t.Fatal("registered provider should be supported")
+	}
+	client, err := NewClientForProvider("test_provider", "sk-test-key", "http://example.com", "m1", WithLogger(NewMockLogger()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c := client.(*Client); c.BaseURL != "http://example.com" || c.Model != "m1" {
+		t.Errorf("SetAPIKey should be applied: %s", c.String())
+	}
+}
@@ -0,0 +1,67 @@
+package mcp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ProviderFactory 创建指定 provider 客户端的工厂函数
+type ProviderFactory func(opts ...ClientOption) AIClient
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]ProviderFactory{
+		ProviderDeepSeek:  NewDeepSeekClientWithOptions,
+		ProviderQwen:      NewQwenClientWithOptions,
+		ProviderCustom:    NewClient,
+		ProviderAnthropic: NewAnthropicClientWithOptions,
+		ProviderGemini:    NewGeminiClientWithOptions,
+		ProviderOllama:    NewOllamaClientWithOptions,
+		ProviderLlamaCpp:  NewLlamaCppClientWithOptions,
+	}
+)
+
+// RegisterProvider 注册（或覆盖）provider，注册后即可通过 ai_models.provider 使用
+func RegisterProvider(name string, factory ProviderFactory) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[name] = factory
+}
+
+// IsSupportedProvider 判断 provider 是否已注册
+func IsSupportedProvider(name string) bool {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	_, ok := providers[name]
+	return ok
+}
+
+// SupportedProviders 返回所有已注册的 provider（按名称排序）
+func SupportedProviders() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewClientForProvider 根据 ai_models.provider 创建并配置对应的 AI 客户端
+//
+// provider 从注册表查找，未知 provider 返回错误（避免把 API Key 发送到其他厂商的接口）
+func NewClientForProvider(provider, apiKey, customURL, customModel string, opts ...ClientOption) (AIClient, error) {
+	providersMu.RLock()
+	factory, ok := providers[provider]
+	providersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("未知的AI provider: %q（可选: %s）", provider, strings.Join(SupportedProviders(), ", "))
+	}
+
+	client := factory(opts...)
+	client.SetAPIKey(apiKey, customURL, customModel)
+	return client, nil
+}
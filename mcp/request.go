@@ -2,8 +2,11 @@ package mcp
 
 // Message 表示一条对话消息
 type Message struct {
-	Role    string `json:"role"`    // "system", "user", "assistant"
+	Role    string `json:"role"`    // "system", "user", "assistant", "tool"
 	Content string `json:"content"` // 消息内容
+
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // assistant 消息中的工具调用
+	ToolCallID string     `json:"tool_call_id,omitempty"` // tool 消息对应的工具调用ID
 }
 
 // Tool 表示 AI 可以调用的工具/函数
@@ -70,3 +73,48 @@ func NewAssistantMessage(content string) Message {
 		Content: content,
 	}
 }
+
+// NewAssistantToolCallMessage 创建包含工具调用的助手消息（多轮工具调用时回传给模型）
+func NewAssistantToolCallMessage(content string, toolCalls []ToolCall) Message {
+	return Message{
+		Role:      "assistant",
+		Content:   content,
+		ToolCalls: toolCalls,
+	}
+}
+
+// NewToolMessage 创建工具执行结果消息
+func NewToolMessage(toolCallID, content string) Message {
+	return Message{
+		Role:       "tool",
+		Content:    content,
+		ToolCallID: toolCallID,
+	}
+}
+
+// ToolCall 模型返回的工具调用
+type ToolCall struct {
+	ID       string       `json:"id"`
+	Type     string       `json:"type"` // 通常为 "function"
+	Function FunctionCall `json:"function"`
+}
+
+// FunctionCall 工具调用的函数名和参数
+type FunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // JSON 字符串
+}
+
+// Response AI API 响应（文本内容 + 工具调用）
+type Response struct {
+	Content   string     `json:"content"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	Usage     Usage      `json:"usage"`
+}
+
+// Usage token 用量（服务端未返回时为0）
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
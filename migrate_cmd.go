@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"nofx/logger"
+	"os"
+	"path/filepath"
+)
+
+// runMigrateDecisionsCommand 处理 `nofx migrate-decisions` 子命令：将 JSON 决策日志目录导入 SQLite 存储
+//
+// 每个目录导入到该目录下的 decisions.db，JSON 文件保留不动；可重复执行（已导入的记录会跳过）。
+// 导入完成后在 config.json 中设置 "decision_log_store": "sqlite" 并重启服务。
+//
+// 示例：
+//
+//	nofx migrate-decisions decision_logs/<trader_id>
+//	nofx migrate-decisions -all decision_logs
+func runMigrateDecisionsCommand(args []string) {
+	fs := flag.NewFlagSet("migrate-decisions", flag.ExitOnError)
+	all := fs.Bool("all", false, "参数为上级目录，导入其下每个交易员子目录")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		log.Fatalf("❌ 请指定决策日志目录，例如: nofx migrate-decisions decision_logs/<trader_id>")
+	}
+
+	var dirs []string
+	for _, arg := range fs.Args() {
+		if !*all {
+			dirs = append(dirs, arg)
+			continue
+		}
+		entries, err := os.ReadDir(arg)
+		if err != nil {
+			log.Fatalf("❌ 读取目录 %s 失败: %v", arg, err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				dirs = append(dirs, filepath.Join(arg, entry.Name()))
+			}
+		}
+	}
+
+	totalImported, totalSkipped := 0, 0
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			log.Fatalf("❌ %s 不是有效目录", dir)
+		}
+
+		store, err := logger.NewSQLiteDecisionLogger(dir)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		imported, skipped, err := store.ImportJSONDir(dir)
+		store.Close()
+		if err != nil {
+			log.Fatalf("❌ %s: %v", dir, err)
+		}
+
+		log.Printf("✓ %s: 导入 %d 条，跳过 %d 条 → %s", dir, imported, skipped, store.Path())
+		totalImported += imported
+		totalSkipped += skipped
+	}
+
+	log.Printf("📊 迁移完成: %d 个目录，导入 %d 条，跳过 %d 条", len(dirs), totalImported, totalSkipped)
+	log.Printf("💡 在 config.json 中设置 \"decision_log_store\": \"sqlite\" 并重启后生效")
+}
@@ -0,0 +1,166 @@
+package replay
+
+import (
+	"fmt"
+	"nofx/decision"
+	"sort"
+	"strings"
+)
+
+// ActionDiff 同一币种在原决策与回放决策之间的差异
+type ActionDiff struct {
+	Symbol   string             `json:"symbol"`
+	Kind     string             `json:"kind"` // added / removed / changed
+	Original *decision.Decision `json:"original,omitempty"`
+	Replayed *decision.Decision `json:"replayed,omitempty"`
+	Changes  []string           `json:"changes,omitempty"` // 字段级差异，如 "stop_loss: 95 → 96"
+}
+
+// DiffDecisions 按币种对齐两组决策并列出动作、仓位和止盈止损的差异
+// 同一币种有多条决策时按出现顺序配对
+func DiffDecisions(original, replayed []decision.Decision) []ActionDiff {
+	origBySymbol := groupBySymbol(original)
+	replBySymbol := groupBySymbol(replayed)
+
+	symbolSet := make(map[string]bool)
+	for symbol := range origBySymbol {
+		symbolSet[symbol] = true
+	}
+	for symbol := range replBySymbol {
+		symbolSet[symbol] = true
+	}
+	symbols := make([]string, 0, len(symbolSet))
+	for symbol := range symbolSet {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	diffs := []ActionDiff{}
+	for _, symbol := range symbols {
+		origList := origBySymbol[symbol]
+		replList := replBySymbol[symbol]
+		n := len(origList)
+		if len(replList) > n {
+			n = len(replList)
+		}
+
+		for i := 0; i < n; i++ {
+			switch {
+			case i >= len(replList):
+				d := origList[i]
+				diffs = append(diffs, ActionDiff{Symbol: symbol, Kind: "removed", Original: &d})
+			case i >= len(origList):
+				d := replList[i]
+				diffs = append(diffs, ActionDiff{Symbol: symbol, Kind: "added", Replayed: &d})
+			default:
+				o, r := origList[i], replList[i]
+				if changes := compareDecision(o, r); len(changes) > 0 {
+					diffs = append(diffs, ActionDiff{Symbol: symbol, Kind: "changed", Original: &o, Replayed: &r, Changes: changes})
+				}
+			}
+		}
+	}
+	return diffs
+}
+
+// groupBySymbol 按币种分组（保持原顺序）
+func groupBySymbol(decisions []decision.Decision) map[string][]decision.Decision {
+	result := make(map[string][]decision.Decision)
+	for _, d := range decisions {
+		result[d.Symbol] = append(result[d.Symbol], d)
+	}
+	return result
+}
+
+// compareDecision 比较影响交易的字段（忽略 reasoning/confidence 等描述性字段）
+func compareDecision(o, r decision.Decision) []string {
+	var changes []string
+	if o.Action != r.Action {
+		changes = append(changes, fmt.Sprintf("action: %s → %s", o.Action, r.Action))
+	}
+	if o.Leverage != r.Leverage {
+		changes = append(changes, fmt.Sprintf("leverage: %d → %d", o.Leverage, r.Leverage))
+	}
+
+	fields := []struct {
+		name     string
+		old, new float64
+	}{
+		{"position_size_usd", o.PositionSizeUSD, r.PositionSizeUSD},
+		{"stop_loss", o.StopLoss, r.StopLoss},
+		{"take_profit", o.TakeProfit, r.TakeProfit},
+		{"new_stop_loss", o.NewStopLoss, r.NewStopLoss},
+		{"new_take_profit", o.NewTakeProfit, r.NewTakeProfit},
+		{"close_percentage", o.ClosePercentage, r.ClosePercentage},
+	}
+	for _, f := range fields {
+		if formatNumber(f.old) != formatNumber(f.new) {
+			changes = append(changes, fmt.Sprintf("%s: %s → %s", f.name, formatNumber(f.old), formatNumber(f.new)))
+		}
+	}
+	return changes
+}
+
+// formatNumber 格式化数值（去掉多余的0，避免浮点误差造成误报）
+func formatNumber(v float64) string {
+	s := fmt.Sprintf("%.6f", v)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// summarizeDecision 决策的单行摘要
+func summarizeDecision(d *decision.Decision) string {
+	if d == nil {
+		return "-"
+	}
+	switch d.Action {
+	case "open_long", "open_short":
+		return fmt.Sprintf("%s %dx %sU SL %s TP %s", d.Action, d.Leverage,
+			formatNumber(d.PositionSizeUSD), formatNumber(d.StopLoss), formatNumber(d.TakeProfit))
+	case "update_stop_loss":
+		return fmt.Sprintf("%s %s", d.Action, formatNumber(d.NewStopLoss))
+	case "update_take_profit":
+		return fmt.Sprintf("%s %s", d.Action, formatNumber(d.NewTakeProfit))
+	case "partial_close":
+		return fmt.Sprintf("%s %s%%", d.Action, formatNumber(d.ClosePercentage))
+	default:
+		return d.Action
+	}
+}
+
+// FormatReport 将回放报告渲染为左右对照的文本
+func FormatReport(report *Report) string {
+	var sb strings.Builder
+
+	model := report.Model
+	if model == "" {
+		model = "(客户端默认)"
+	}
+	temperature := "(客户端默认)"
+	if report.Temperature != nil {
+		temperature = formatNumber(*report.Temperature)
+	}
+	sb.WriteString(fmt.Sprintf("🔁 决策回放: 模型 %s, 温度 %s\n", model, temperature))
+	sb.WriteString(fmt.Sprintf("   共 %d 条 | 一致 %d | 有差异 %d | 失败 %d\n\n",
+		report.Summary.Total, report.Summary.Identical, report.Summary.Changed, report.Summary.Failed))
+
+	for _, entry := range report.Entries {
+		header := fmt.Sprintf("#%d %s", entry.CycleNumber, entry.Timestamp.Format("2006-01-02 15:04:05"))
+		if entry.Error != "" {
+			sb.WriteString(fmt.Sprintf("%s ❌ %s\n", header, entry.Error))
+		} else if len(entry.Diffs) == 0 {
+			sb.WriteString(fmt.Sprintf("%s ✓ 一致\n", header))
+			continue
+		} else {
+			sb.WriteString(fmt.Sprintf("%s ⚠️ %d 处差异\n", header, len(entry.Diffs)))
+		}
+
+		for _, diff := range entry.Diffs {
+			sb.WriteString(fmt.Sprintf("  %-12s %-45s | %s\n", diff.Symbol, summarizeDecision(diff.Original), summarizeDecision(diff.Replayed)))
+			for _, change := range diff.Changes {
+				sb.WriteString(fmt.Sprintf("  %-12s   · %s\n", "", change))
+			}
+		}
+	}
+	return sb.String()
+}
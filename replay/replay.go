@@ -0,0 +1,197 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/mcp"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Options 回放参数
+type Options struct {
+	Model           string   // 覆盖模型名称（为空使用客户端默认模型）
+	Temperature     *float64 // 覆盖温度（为空使用客户端默认温度）
+	BTCETHLeverage  int      // 解析校验使用的BTC/ETH杠杆上限（默认5）
+	AltcoinLeverage int      // 解析校验使用的山寨币杠杆上限（默认5）
+}
+
+// Source 一条待回放的决策记录及其来源文件
+type Source struct {
+	File   string
+	Record *logger.DecisionRecord
+}
+
+// Entry 单条记录的回放结果
+type Entry struct {
+	File        string              `json:"file"`
+	CycleNumber int                 `json:"cycle_number"`
+	Timestamp   time.Time           `json:"timestamp"`
+	Original    []decision.Decision `json:"original"`
+	Replayed    []decision.Decision `json:"replayed"`
+	ReplayedCoT string              `json:"replayed_cot,omitempty"`
+	Diffs       []ActionDiff        `json:"diffs"`
+	Error       string              `json:"error,omitempty"`
+}
+
+// Summary 回放汇总
+type Summary struct {
+	Total     int `json:"total"`
+	Identical int `json:"identical"` // 决策完全一致
+	Changed   int `json:"changed"`   // 存在差异
+	Failed    int `json:"failed"`    // 调用或解析失败
+}
+
+// Report 回放报告
+type Report struct {
+	Model       string   `json:"model,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	Entries     []Entry  `json:"entries"`
+	Summary     Summary  `json:"summary"`
+}
+
+// LoadSources 加载决策日志
+// paths 可以是单个记录文件或 decision_logs 目录；from/to 非零时按记录时间过滤（含边界）
+func LoadSources(paths []string, from, to time.Time) ([]Source, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取决策日志失败: %w", err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取决策日志目录失败: %w", err)
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+
+	var sources []Source
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("读取决策记录失败: %w", err)
+		}
+		var record logger.DecisionRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			log.Printf("⚠️  跳过无法解析的决策记录 %s: %v", file, err)
+			continue
+		}
+		if !from.IsZero() && record.Timestamp.Before(from) {
+			continue
+		}
+		if !to.IsZero() && record.Timestamp.After(to) {
+			continue
+		}
+		if record.SystemPrompt == "" || record.InputPrompt == "" {
+			log.Printf("⚠️  跳过缺少prompt的决策记录 %s", file)
+			continue
+		}
+		sources = append(sources, Source{File: file, Record: &record})
+	}
+
+	sort.SliceStable(sources, func(i, j int) bool {
+		return sources[i].Record.Timestamp.Before(sources[j].Record.Timestamp)
+	})
+	return sources, nil
+}
+
+// Run 将历史记录中的原始prompt重新发送给AI，并与原决策逐项对比（不执行任何交易）
+func Run(sources []Source, client mcp.AIClient, opts Options) *Report {
+	if opts.BTCETHLeverage <= 0 {
+		opts.BTCETHLeverage = 5
+	}
+	if opts.AltcoinLeverage <= 0 {
+		opts.AltcoinLeverage = 5
+	}
+
+	report := &Report{
+		Model:       opts.Model,
+		Temperature: opts.Temperature,
+		Entries:     []Entry{},
+	}
+
+	for i, src := range sources {
+		log.Printf("🔁 回放 [%d/%d] %s", i+1, len(sources), filepath.Base(src.File))
+		entry := replayOne(src, client, opts)
+		report.Entries = append(report.Entries, entry)
+
+		report.Summary.Total++
+		switch {
+		case entry.Error != "":
+			report.Summary.Failed++
+		case len(entry.Diffs) == 0:
+			report.Summary.Identical++
+		default:
+			report.Summary.Changed++
+		}
+	}
+	return report
+}
+
+// replayOne 回放单条记录
+func replayOne(src Source, client mcp.AIClient, opts Options) Entry {
+	record := src.Record
+	entry := Entry{
+		File:        src.File,
+		CycleNumber: record.CycleNumber,
+		Timestamp:   record.Timestamp,
+		Original:    []decision.Decision{},
+		Replayed:    []decision.Decision{},
+	}
+
+	if record.DecisionJSON != "" {
+		if err := json.Unmarshal([]byte(record.DecisionJSON), &entry.Original); err != nil {
+			entry.Error = fmt.Sprintf("解析原始决策失败: %v", err)
+			return entry
+		}
+	}
+
+	builder := mcp.NewRequestBuilder().
+		WithSystemPrompt(record.SystemPrompt).
+		WithUserPrompt(record.InputPrompt)
+	if opts.Model != "" {
+		builder = builder.WithModel(opts.Model)
+	}
+	if opts.Temperature != nil {
+		builder = builder.WithTemperature(*opts.Temperature)
+	}
+	req, err := builder.Build()
+	if err != nil {
+		entry.Error = fmt.Sprintf("构建请求失败: %v", err)
+		return entry
+	}
+
+	response, err := client.CallWithRequest(req)
+	if err != nil {
+		entry.Error = fmt.Sprintf("调用AI API失败: %v", err)
+		return entry
+	}
+
+	// 使用记录时的账户净值重新解析，保证校验规则与当时一致
+	fullDecision, err := decision.ParseFullDecisionResponse(response, record.AccountState.TotalBalance, opts.BTCETHLeverage, opts.AltcoinLeverage)
+	if fullDecision != nil {
+		entry.ReplayedCoT = fullDecision.CoTTrace
+		entry.Replayed = fullDecision.Decisions
+	}
+	if err != nil {
+		entry.Error = fmt.Sprintf("解析AI响应失败: %v", err)
+	}
+
+	entry.Diffs = DiffDecisions(entry.Original, entry.Replayed)
+	return entry
+}
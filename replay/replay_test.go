@@ -0,0 +1,168 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/mcp"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClient 记录收到的请求并返回预设响应
+type fakeClient struct {
+	requests  []*mcp.Request
+	responses []string
+	err       error
+}
+
+func (f *fakeClient) SetAPIKey(apiKey string, customURL string, customModel string) {}
+func (f *fakeClient) SetTimeout(timeout time.Duration)                              {}
+func (f *fakeClient) CallWithMessages(systemPrompt, userPrompt string) (string, error) {
+	return "", fmt.Errorf("not implemented")
+}
+func (f *fakeClient) CallWithRequest(req *mcp.Request) (string, error) {
+	f.requests = append(f.requests, req)
+	if f.err != nil {
+		return "", f.err
+	}
+	resp := f.responses[0]
+	f.responses = f.responses[1:]
+	return resp, nil
+}
+
+func wrapResponse(decisionJSON string) string {
+	return "<reasoning>重新分析</reasoning>\n<decision>\n```json\n" + decisionJSON + "\n```\n</decision>"
+}
+
+// writeRecord 写入一条决策记录文件
+func writeRecord(t *testing.T, dir string, cycle int, ts time.Time, decisionJSON string) string {
+	record := logger.DecisionRecord{
+		Timestamp:    ts,
+		CycleNumber:  cycle,
+		SystemPrompt: "system",
+		InputPrompt:  fmt.Sprintf("user-%d", cycle),
+		DecisionJSON: decisionJSON,
+		AccountState: logger.AccountSnapshot{TotalBalance: 1000},
+	}
+	data, err := json.Marshal(record)
+	require.NoError(t, err)
+	path := filepath.Join(dir, fmt.Sprintf("decision_%s_cycle%d.json", ts.Format("20060102_150405"), cycle))
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	return path
+}
+
+const openLongJSON = `[{"symbol":"BTCUSDT","action":"open_long","leverage":5,"position_size_usd":600,"stop_loss":95,"take_profit":120,"reasoning":"a"}]`
+
+func TestLoadSourcesFiltersAndSorts(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeRecord(t, dir, 3, base.Add(2*time.Hour), "")
+	writeRecord(t, dir, 1, base, "")
+	writeRecord(t, dir, 2, base.Add(time.Hour), "")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0600))
+
+	sources, err := LoadSources([]string{dir}, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, sources, 3)
+	assert.Equal(t, []int{1, 2, 3}, []int{sources[0].Record.CycleNumber, sources[1].Record.CycleNumber, sources[2].Record.CycleNumber})
+
+	sources, err = LoadSources([]string{dir}, base.Add(30*time.Minute), base.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, sources, 1)
+	assert.Equal(t, 2, sources[0].Record.CycleNumber)
+
+	_, err = LoadSources([]string{filepath.Join(dir, "missing")}, time.Time{}, time.Time{})
+	assert.Error(t, err)
+}
+
+func TestRunSendsOriginalPromptsWithOverrides(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeRecord(t, dir, 1, base, openLongJSON)
+	writeRecord(t, dir, 2, base.Add(3*time.Minute), openLongJSON)
+	writeRecord(t, dir, 3, base.Add(6*time.Minute), "")
+
+	sources, err := LoadSources([]string{dir}, time.Time{}, time.Time{})
+	require.NoError(t, err)
+
+	client := &fakeClient{responses: []string{
+		wrapResponse(openLongJSON), // 一致
+		wrapResponse(`[{"symbol":"BTCUSDT","action":"open_long","leverage":5,"position_size_usd":500,"stop_loss":96,"take_profit":120,"reasoning":"b"}]`),
+		wrapResponse(`[{"symbol":"BTCUSDT","action":"fly"}]`),
+	}}
+	temp := 0.2
+	report := Run(sources, client, Options{Model: "qwen-max", Temperature: &temp})
+
+	require.Len(t, client.requests, 3)
+	req := client.requests[1]
+	assert.Equal(t, "qwen-max", req.Model)
+	require.NotNil(t, req.Temperature)
+	assert.Equal(t, 0.2, *req.Temperature)
+	require.Len(t, req.Messages, 2)
+	assert.Equal(t, "system", req.Messages[0].Content)
+	assert.Equal(t, "user-2", req.Messages[1].Content)
+
+	assert.Equal(t, Summary{Total: 3, Identical: 1, Changed: 1, Failed: 1}, report.Summary)
+	assert.Empty(t, report.Entries[0].Diffs)
+	require.Len(t, report.Entries[1].Diffs, 1)
+	assert.Equal(t, []string{"position_size_usd: 600 → 500", "stop_loss: 95 → 96"}, report.Entries[1].Diffs[0].Changes)
+	assert.Contains(t, report.Entries[2].Error, "解析AI响应失败")
+
+	text := FormatReport(report)
+	assert.Contains(t, text, "模型 qwen-max, 温度 0.2")
+	assert.Contains(t, text, "stop_loss: 95 → 96")
+}
+
+func TestRunRecordsCallError(t *testing.T) {
+	dir := t.TempDir()
+	writeRecord(t, dir, 1, time.Now(), openLongJSON)
+	sources, err := LoadSources([]string{dir}, time.Time{}, time.Time{})
+	require.NoError(t, err)
+
+	report := Run(sources, &fakeClient{err: fmt.Errorf("timeout")}, Options{})
+	assert.Equal(t, 1, report.Summary.Failed)
+	assert.Contains(t, report.Entries[0].Error, "调用AI API失败")
+	assert.Len(t, report.Entries[0].Original, 1)
+}
+
+func TestDiffDecisions(t *testing.T) {
+	open := decision.Decision{Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 600, StopLoss: 95, TakeProfit: 120}
+	hold := decision.Decision{Symbol: "ETHUSDT", Action: "hold"}
+	short := decision.Decision{Symbol: "BTCUSDT", Action: "open_short", Leverage: 3, PositionSizeUSD: 600, StopLoss: 105, TakeProfit: 80}
+
+	tests := []struct {
+		name     string
+		original []decision.Decision
+		replayed []decision.Decision
+		kinds    []string
+		changes  []string
+	}{
+		{"完全一致", []decision.Decision{open, hold}, []decision.Decision{hold, open}, []string{}, nil},
+		{"新增动作", []decision.Decision{open}, []decision.Decision{open, hold}, []string{"added"}, nil},
+		{"删除动作", []decision.Decision{open, hold}, []decision.Decision{open}, []string{"removed"}, nil},
+		{"方向改变", []decision.Decision{open}, []decision.Decision{short}, []string{"changed"},
+			[]string{"action: open_long → open_short", "leverage: 5 → 3", "stop_loss: 95 → 105", "take_profit: 120 → 80"}},
+		{"忽略浮点误差", []decision.Decision{open}, []decision.Decision{{Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 600.0000000001, StopLoss: 95, TakeProfit: 120}}, []string{}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diffs := DiffDecisions(tt.original, tt.replayed)
+			kinds := []string{}
+			for _, d := range diffs {
+				kinds = append(kinds, d.Kind)
+			}
+			assert.Equal(t, tt.kinds, kinds)
+			if tt.changes != nil {
+				assert.Equal(t, tt.changes, diffs[0].Changes)
+			}
+		})
+	}
+}
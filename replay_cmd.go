@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"nofx/mcp"
+	"nofx/replay"
+	"os"
+	"strings"
+	"time"
+)
+
+// runReplayCommand 处理 `nofx replay` 子命令：用其他模型/温度重放历史决策并输出对照差异
+//
+// 示例：
+//
+//	nofx replay -provider qwen -api-key sk-xxx -temperature 0.2 decision_logs/<trader_id>
+//	nofx replay -from 2025-01-01 -to 2025-01-02 -output diff.json decision_logs/<trader_id>
+func runReplayCommand(args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	from := fs.String("from", "", "起始时间（2006-01-02 或 RFC3339，UTC），为空不限制")
+	to := fs.String("to", "", "结束时间（2006-01-02 或 RFC3339，UTC），为空不限制")
+	limit := fs.Int("limit", 0, "最多回放最近N条记录（0为全部）")
+	provider := fs.String("provider", "deepseek", "AI提供商: "+strings.Join(mcp.SupportedProviders(), " / "))
+	apiKey := fs.String("api-key", os.Getenv("REPLAY_AI_API_KEY"), "AI API Key（默认读取 REPLAY_AI_API_KEY）")
+	apiURL := fs.String("api-url", "", "自定义AI API地址")
+	model := fs.String("model", "", "回放使用的模型名称（为空使用提供商默认模型）")
+	temperature := fs.Float64("temperature", -1, "回放温度（<0 使用客户端默认值）")
+	btcEthLeverage := fs.Int("btc-eth-leverage", 5, "解析校验用的BTC/ETH杠杆上限")
+	altcoinLeverage := fs.Int("altcoin-leverage", 5, "解析校验用的山寨币杠杆上限")
+	output := fs.String("output", "", "完整报告输出文件（JSON）")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		log.Fatalf("❌ 请指定决策日志文件或目录，例如: nofx replay decision_logs/<trader_id>")
+	}
+	if *apiKey == "" {
+		log.Fatalf("❌ 必须提供 -api-key")
+	}
+
+	var fromTime, toTime time.Time
+	var err error
+	if *from != "" {
+		if fromTime, err = parseCommandTime(*from); err != nil {
+			log.Fatalf("❌ 起始时间无效: %v", err)
+		}
+	}
+	if *to != "" {
+		if toTime, err = parseCommandTime(*to); err != nil {
+			log.Fatalf("❌ 结束时间无效: %v", err)
+		}
+	}
+
+	sources, err := replay.LoadSources(fs.Args(), fromTime, toTime)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if *limit > 0 && len(sources) > *limit {
+		sources = sources[len(sources)-*limit:]
+	}
+	if len(sources) == 0 {
+		log.Fatalf("❌ 没有符合条件的决策记录")
+	}
+
+	opts := replay.Options{
+		Model:           *model,
+		BTCETHLeverage:  *btcEthLeverage,
+		AltcoinLeverage: *altcoinLeverage,
+	}
+	if *temperature >= 0 {
+		opts.Temperature = temperature
+	}
+
+	client, err := mcp.NewClientForProvider(*provider, *apiKey, *apiURL, *model)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	report := replay.Run(sources, client, opts)
+
+	fmt.Println()
+	fmt.Print(replay.FormatReport(report))
+
+	if *output != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatalf("❌ 序列化回放报告失败: %v", err)
+		}
+		if err := os.WriteFile(*output, data, 0644); err != nil {
+			log.Fatalf("❌ 写入回放报告失败: %v", err)
+		}
+		fmt.Printf("\n报告已保存: %s\n", *output)
+	}
+}
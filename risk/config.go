@@ -0,0 +1,73 @@
+package risk
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Config 组合风控规则配置（数值为0表示不启用该规则）
+type Config struct {
+	MaxPositions             int        `json:"max_positions"`               // 最大同时持仓数（币种+方向）
+	MaxMarginUsagePct        float64    `json:"max_margin_usage_pct"`        // 保证金使用率上限（%）
+	MaxGrossExposurePct      float64    `json:"max_gross_exposure_pct"`      // 总名义敞口上限（多+空，占净值%）
+	MaxNetExposurePct        float64    `json:"max_net_exposure_pct"`        // 净敞口上限（|多-空|，占净值%）
+	MaxMajorNotionalPct      float64    `json:"max_major_notional_pct"`      // BTC/ETH 单币名义价值上限（占净值%）
+	MaxAltNotionalPct        float64    `json:"max_alt_notional_pct"`        // 山寨币单币名义价值上限（占净值%）
+	MaxDailyLossPct          float64    `json:"max_daily_loss_pct"`          // 当日已实现亏损上限（占日初净值%）
+	MaxDrawdownPct           float64    `json:"max_drawdown_pct"`            // 净值从峰值回撤上限（%）
+	MaxCorrelatedExposurePct float64    `json:"max_correlated_exposure_pct"` // 同一相关组同方向敞口上限（占净值%）
+	CorrelationGroups        [][]string `json:"correlation_groups"`          // 相关组，为空时所有币种视为同一组
+	MinNotional              float64    `json:"min_notional"`                // 缩减后的最小开仓金额（低于则拒绝）
+}
+
+// DefaultConfig 默认风控配置（与系统提示词中的硬约束保持一致）
+func DefaultConfig() Config {
+	return Config{
+		MaxPositions:        3,
+		MaxMarginUsagePct:   90,
+		MaxMajorNotionalPct: 1000, // 10倍净值
+		MaxAltNotionalPct:   150,  // 1.5倍净值
+		MinNotional:         12,   // 交易所最小名义价值 10 USDT + 安全边际
+	}
+}
+
+// ParseConfig 解析交易员的风控配置JSON，未出现的字段沿用 base 中的值
+// raw 为空时直接返回 base
+func ParseConfig(raw string, base Config) (Config, error) {
+	cfg := base
+	if strings.TrimSpace(raw) == "" {
+		return cfg, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		return base, fmt.Errorf("解析风控配置失败: %w", err)
+	}
+	return cfg, cfg.Validate()
+}
+
+// Validate 检查配置取值是否合法
+func (c Config) Validate() error {
+	if c.MaxPositions < 0 {
+		return fmt.Errorf("max_positions 不能为负数")
+	}
+	values := map[string]float64{
+		"max_margin_usage_pct":        c.MaxMarginUsagePct,
+		"max_gross_exposure_pct":      c.MaxGrossExposurePct,
+		"max_net_exposure_pct":        c.MaxNetExposurePct,
+		"max_major_notional_pct":      c.MaxMajorNotionalPct,
+		"max_alt_notional_pct":        c.MaxAltNotionalPct,
+		"max_daily_loss_pct":          c.MaxDailyLossPct,
+		"max_drawdown_pct":            c.MaxDrawdownPct,
+		"max_correlated_exposure_pct": c.MaxCorrelatedExposurePct,
+		"min_notional":                c.MinNotional,
+	}
+	for name, v := range values {
+		if v < 0 {
+			return fmt.Errorf("%s 不能为负数", name)
+		}
+	}
+	if c.MaxMarginUsagePct > 100 {
+		return fmt.Errorf("max_margin_usage_pct 不能超过100")
+	}
+	return nil
+}
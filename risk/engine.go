@@ -0,0 +1,152 @@
+package risk
+
+import (
+	"fmt"
+	"math"
+)
+
+// Position 当前持仓快照
+type Position struct {
+	Symbol   string  // 币种
+	Side     string  // long / short
+	Notional float64 // 名义价值（数量 × 标记价格）
+	Margin   float64 // 占用保证金
+}
+
+// Account 账户风险快照
+type Account struct {
+	Equity           float64 // 当前净值
+	MarginUsed       float64 // 已用保证金
+	PeakEquity       float64 // 历史峰值净值
+	DailyRealizedPnL float64 // 当日已实现盈亏
+	Positions        []Position
+}
+
+// Open 记录新开仓位（用于同一周期内后续决策的评估）
+func (a *Account) Open(order Order) {
+	margin := order.Notional
+	if order.Leverage > 0 {
+		margin = order.Notional / float64(order.Leverage)
+	}
+	a.MarginUsed += margin
+	a.Positions = append(a.Positions, Position{Symbol: order.Symbol, Side: order.Side, Notional: order.Notional, Margin: margin})
+}
+
+// Close 移除已平仓位
+func (a *Account) Close(symbol, side string) {
+	kept := a.Positions[:0]
+	for _, pos := range a.Positions {
+		if pos.Symbol == symbol && pos.Side == side {
+			a.MarginUsed -= pos.Margin
+			continue
+		}
+		kept = append(kept, pos)
+	}
+	a.Positions = kept
+}
+
+// Order 待执行的开仓订单
+type Order struct {
+	Symbol   string
+	Side     string  // long / short
+	Notional float64 // 开仓名义价值（USDT），规则可将其缩减
+	Leverage int
+}
+
+// Rule 风控规则
+type Rule interface {
+	// Name 规则名称（用于日志和拒绝原因）
+	Name() string
+	// Check 检查开仓订单：可缩减 order.Notional，返回错误表示拒绝
+	Check(account *Account, order *Order) error
+}
+
+// Result 风控评估结果
+type Result struct {
+	Notional    float64  // 最终允许的开仓名义价值
+	Adjustments []string // 被缩减时的说明
+}
+
+// Engine 组合风控引擎，按顺序执行规则链
+type Engine struct {
+	rules       []Rule
+	minNotional float64
+}
+
+// NewEngine 根据配置创建包含内置规则的风控引擎
+func NewEngine(cfg Config) *Engine {
+	e := &Engine{minNotional: cfg.MinNotional}
+
+	// 先执行直接拒绝类规则，再执行缩减类规则
+	if cfg.MaxDrawdownPct > 0 {
+		e.Use(&DrawdownRule{MaxPct: cfg.MaxDrawdownPct})
+	}
+	if cfg.MaxDailyLossPct > 0 {
+		e.Use(&DailyLossRule{MaxPct: cfg.MaxDailyLossPct})
+	}
+	if cfg.MaxPositions > 0 {
+		e.Use(&MaxPositionsRule{Max: cfg.MaxPositions})
+	}
+	if cfg.MaxMajorNotionalPct > 0 || cfg.MaxAltNotionalPct > 0 {
+		e.Use(&SymbolNotionalRule{MajorPct: cfg.MaxMajorNotionalPct, AltPct: cfg.MaxAltNotionalPct})
+	}
+	if cfg.MaxGrossExposurePct > 0 {
+		e.Use(&GrossExposureRule{MaxPct: cfg.MaxGrossExposurePct})
+	}
+	if cfg.MaxNetExposurePct > 0 {
+		e.Use(&NetExposureRule{MaxPct: cfg.MaxNetExposurePct})
+	}
+	if cfg.MaxCorrelatedExposurePct > 0 {
+		e.Use(&CorrelatedExposureRule{MaxPct: cfg.MaxCorrelatedExposurePct, Groups: cfg.CorrelationGroups})
+	}
+	if cfg.MaxMarginUsagePct > 0 {
+		e.Use(&MarginUsageRule{MaxPct: cfg.MaxMarginUsagePct})
+	}
+	return e
+}
+
+// Use 追加自定义规则
+func (e *Engine) Use(rules ...Rule) {
+	e.rules = append(e.rules, rules...)
+}
+
+// Rules 返回当前规则链
+func (e *Engine) Rules() []Rule {
+	return e.rules
+}
+
+// Evaluate 依次执行规则链，返回允许的开仓金额；任一规则拒绝则返回错误
+func (e *Engine) Evaluate(account *Account, order Order) (*Result, error) {
+	if account.Equity <= 0 {
+		return nil, fmt.Errorf("账户净值无效(%.2f)，拒绝开仓", account.Equity)
+	}
+
+	result := &Result{}
+	for _, rule := range e.rules {
+		before := order.Notional
+		if err := rule.Check(account, &order); err != nil {
+			return nil, fmt.Errorf("[%s] %w", rule.Name(), err)
+		}
+		if order.Notional < before-1e-9 {
+			result.Adjustments = append(result.Adjustments,
+				fmt.Sprintf("[%s] 仓位 %.2f → %.2f USDT", rule.Name(), before, order.Notional))
+		}
+	}
+
+	if len(result.Adjustments) > 0 && order.Notional < e.minNotional {
+		return nil, fmt.Errorf("风控缩减后仓位 %.2f USDT 低于最小开仓金额 %.2f USDT", order.Notional, e.minNotional)
+	}
+	result.Notional = order.Notional
+	return result, nil
+}
+
+// capNotional 将订单缩减到剩余额度以内，额度耗尽时拒绝
+func capNotional(order *Order, headroom float64, what string) error {
+	if headroom <= 0 {
+		return fmt.Errorf("%s已达上限，剩余额度 %.2f USDT", what, math.Max(headroom, 0))
+	}
+	if order.Notional > headroom {
+		order.Notional = headroom
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package risk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAccount(positions ...Position) *Account {
+	account := &Account{Equity: 1000, PeakEquity: 1000}
+	for _, pos := range positions {
+		account.MarginUsed += pos.Margin
+		account.Positions = append(account.Positions, pos)
+	}
+	return account
+}
+
+func TestEngineEvaluate(t *testing.T) {
+	tests := []struct {
+		name         string
+		cfg          Config
+		account      *Account
+		order        Order
+		wantErr      string
+		wantNotional float64
+	}{
+		{
+			name:         "无规则时原样通过",
+			cfg:          Config{},
+			account:      newAccount(),
+			order:        Order{Symbol: "SOLUSDT", Side: "long", Notional: 500, Leverage: 5},
+			wantNotional: 500,
+		},
+		{
+			name:    "净值无效直接拒绝",
+			cfg:     Config{},
+			account: &Account{},
+			order:   Order{Symbol: "SOLUSDT", Side: "long", Notional: 500, Leverage: 5},
+			wantErr: "账户净值无效",
+		},
+		{
+			name:    "回撤超限拒绝",
+			cfg:     Config{MaxDrawdownPct: 20},
+			account: &Account{Equity: 750, PeakEquity: 1000},
+			order:   Order{Symbol: "SOLUSDT", Side: "long", Notional: 100, Leverage: 5},
+			wantErr: "[最大回撤]",
+		},
+		{
+			name:    "当日亏损超限拒绝",
+			cfg:     Config{MaxDailyLossPct: 5},
+			account: &Account{Equity: 940, PeakEquity: 1000, DailyRealizedPnL: -60},
+			order:   Order{Symbol: "SOLUSDT", Side: "long", Notional: 100, Leverage: 5},
+			wantErr: "[日亏损]",
+		},
+		{
+			name: "持仓数达到上限拒绝新币种",
+			cfg:  Config{MaxPositions: 1},
+			account: newAccount(
+				Position{Symbol: "BTCUSDT", Side: "long", Notional: 200, Margin: 20},
+			),
+			order:   Order{Symbol: "ETHUSDT", Side: "long", Notional: 100, Leverage: 5},
+			wantErr: "[最大持仓数]",
+		},
+		{
+			name: "同币种同方向加仓不计入持仓数",
+			cfg:  Config{MaxPositions: 1},
+			account: newAccount(
+				Position{Symbol: "BTCUSDT", Side: "long", Notional: 200, Margin: 20},
+			),
+			order:        Order{Symbol: "BTCUSDT", Side: "long", Notional: 100, Leverage: 5},
+			wantNotional: 100,
+		},
+		{
+			name:         "山寨币单币敞口缩减",
+			cfg:          Config{MaxMajorNotionalPct: 1000, MaxAltNotionalPct: 150, MinNotional: 12},
+			account:      newAccount(Position{Symbol: "SOLUSDT", Side: "long", Notional: 1000, Margin: 200}),
+			order:        Order{Symbol: "SOLUSDT", Side: "long", Notional: 1000, Leverage: 5},
+			wantNotional: 500,
+		},
+		{
+			name:         "BTC使用主流币额度",
+			cfg:          Config{MaxMajorNotionalPct: 1000, MaxAltNotionalPct: 150},
+			account:      newAccount(),
+			order:        Order{Symbol: "BTCUSDT", Side: "long", Notional: 5000, Leverage: 10},
+			wantNotional: 5000,
+		},
+		{
+			name: "总敞口耗尽拒绝",
+			cfg:  Config{MaxGrossExposurePct: 300},
+			account: newAccount(
+				Position{Symbol: "BTCUSDT", Side: "long", Notional: 2000, Margin: 200},
+				Position{Symbol: "ETHUSDT", Side: "short", Notional: 1000, Margin: 100},
+			),
+			order:   Order{Symbol: "SOLUSDT", Side: "long", Notional: 100, Leverage: 5},
+			wantErr: "[总敞口]",
+		},
+		{
+			name:         "净敞口缩减同方向开仓",
+			cfg:          Config{MaxNetExposurePct: 200},
+			account:      newAccount(Position{Symbol: "BTCUSDT", Side: "long", Notional: 1500, Margin: 150}),
+			order:        Order{Symbol: "ETHUSDT", Side: "long", Notional: 1000, Leverage: 10},
+			wantNotional: 500,
+		},
+		{
+			name:         "反向开仓降低净敞口不受限",
+			cfg:          Config{MaxNetExposurePct: 200},
+			account:      newAccount(Position{Symbol: "BTCUSDT", Side: "long", Notional: 1500, Margin: 150}),
+			order:        Order{Symbol: "ETHUSDT", Side: "short", Notional: 1000, Leverage: 10},
+			wantNotional: 1000,
+		},
+		{
+			name: "相关组同向敞口缩减",
+			cfg: Config{
+				MaxCorrelatedExposurePct: 300,
+				CorrelationGroups:        [][]string{{"SOLUSDT", "AVAXUSDT"}},
+			},
+			account: newAccount(
+				Position{Symbol: "SOLUSDT", Side: "long", Notional: 2500, Margin: 250},
+				Position{Symbol: "BTCUSDT", Side: "long", Notional: 5000, Margin: 500},
+			),
+			order:        Order{Symbol: "AVAXUSDT", Side: "long", Notional: 1000, Leverage: 10},
+			wantNotional: 500,
+		},
+		{
+			name: "不在任何相关组的币种不受约束",
+			cfg: Config{
+				MaxCorrelatedExposurePct: 300,
+				CorrelationGroups:        [][]string{{"SOLUSDT", "AVAXUSDT"}},
+			},
+			account:      newAccount(Position{Symbol: "SOLUSDT", Side: "long", Notional: 3000, Margin: 300}),
+			order:        Order{Symbol: "DOGEUSDT", Side: "long", Notional: 1000, Leverage: 10},
+			wantNotional: 1000,
+		},
+		{
+			name:         "保证金使用率按杠杆换算缩减",
+			cfg:          Config{MaxMarginUsagePct: 90},
+			account:      newAccount(Position{Symbol: "BTCUSDT", Side: "long", Notional: 8000, Margin: 800}),
+			order:        Order{Symbol: "ETHUSDT", Side: "long", Notional: 2000, Leverage: 10},
+			wantNotional: 1000,
+		},
+		{
+			name:    "缩减后低于最小开仓金额拒绝",
+			cfg:     Config{MaxGrossExposurePct: 100, MinNotional: 12},
+			account: newAccount(Position{Symbol: "BTCUSDT", Side: "long", Notional: 995, Margin: 100}),
+			order:   Order{Symbol: "ETHUSDT", Side: "long", Notional: 100, Leverage: 10},
+			wantErr: "低于最小开仓金额",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewEngine(tt.cfg).Evaluate(tt.account, tt.order)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.InDelta(t, tt.wantNotional, result.Notional, 1e-9)
+			assert.Equal(t, tt.wantNotional < tt.order.Notional, len(result.Adjustments) > 0)
+		})
+	}
+}
+
+type blockSymbolRule struct {
+	symbol string
+}
+
+func (r *blockSymbolRule) Name() string { return "黑名单" }
+
+func (r *blockSymbolRule) Check(account *Account, order *Order) error {
+	if order.Symbol == r.symbol {
+		return assert.AnError
+	}
+	return nil
+}
+
+func TestEngineCustomRule(t *testing.T) {
+	engine := NewEngine(Config{})
+	engine.Use(&blockSymbolRule{symbol: "DOGEUSDT"})
+	require.Len(t, engine.Rules(), 1)
+
+	_, err := engine.Evaluate(newAccount(), Order{Symbol: "DOGEUSDT", Side: "long", Notional: 100, Leverage: 5})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "[黑名单]")
+
+	_, err = engine.Evaluate(newAccount(), Order{Symbol: "SOLUSDT", Side: "long", Notional: 100, Leverage: 5})
+	assert.NoError(t, err)
+}
+
+func TestAccountOpenClose(t *testing.T) {
+	account := newAccount()
+	account.Open(Order{Symbol: "BTCUSDT", Side: "long", Notional: 1000, Leverage: 10})
+	account.Open(Order{Symbol: "ETHUSDT", Side: "short", Notional: 500, Leverage: 5})
+	require.Len(t, account.Positions, 2)
+	assert.InDelta(t, 200, account.MarginUsed, 1e-9)
+
+	account.Close("BTCUSDT", "long")
+	require.Len(t, account.Positions, 1)
+	assert.Equal(t, "ETHUSDT", account.Positions[0].Symbol)
+	assert.InDelta(t, 100, account.MarginUsed, 1e-9)
+}
+
+func TestParseConfig(t *testing.T) {
+	base := DefaultConfig()
+	base.MaxDailyLossPct = 10
+
+	cfg, err := ParseConfig("", base)
+	require.NoError(t, err)
+	assert.Equal(t, base, cfg)
+
+	cfg, err = ParseConfig(`{"max_positions": 5, "correlation_groups": [["SOLUSDT","AVAXUSDT"]]}`, base)
+	require.NoError(t, err)
+	assert.Equal(t, 5, cfg.MaxPositions)
+	assert.Equal(t, 10.0, cfg.MaxDailyLossPct) // 未出现的字段沿用 base
+	assert.Equal(t, [][]string{{"SOLUSDT", "AVAXUSDT"}}, cfg.CorrelationGroups)
+
+	_, err = ParseConfig(`{"max_drawdown_pct": -1}`, base)
+	assert.Error(t, err)
+
+	_, err = ParseConfig(`{"max_margin_usage_pct": 120}`, base)
+	assert.Error(t, err)
+
+	cfg, err = ParseConfig(`not json`, base)
+	assert.Error(t, err)
+	assert.Equal(t, base, cfg)
+}
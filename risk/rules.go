@@ -0,0 +1,194 @@
+package risk
+
+import (
+	"fmt"
+	"math"
+)
+
+// DrawdownRule 净值从峰值回撤超过阈值时禁止开仓
+type DrawdownRule struct {
+	MaxPct float64
+}
+
+func (r *DrawdownRule) Name() string { return "最大回撤" }
+
+func (r *DrawdownRule) Check(account *Account, order *Order) error {
+	if account.PeakEquity <= 0 || account.Equity >= account.PeakEquity {
+		return nil
+	}
+	drawdown := (account.PeakEquity - account.Equity) / account.PeakEquity * 100
+	if drawdown >= r.MaxPct {
+		return fmt.Errorf("净值从峰值 %.2f 回撤 %.2f%%，超过上限 %.2f%%", account.PeakEquity, drawdown, r.MaxPct)
+	}
+	return nil
+}
+
+// DailyLossRule 当日已实现亏损超过阈值时禁止开仓
+type DailyLossRule struct {
+	MaxPct float64
+}
+
+func (r *DailyLossRule) Name() string { return "日亏损" }
+
+func (r *DailyLossRule) Check(account *Account, order *Order) error {
+	if account.DailyRealizedPnL >= 0 {
+		return nil
+	}
+	dayStartEquity := account.Equity - account.DailyRealizedPnL
+	if dayStartEquity <= 0 {
+		return nil
+	}
+	lossPct := -account.DailyRealizedPnL / dayStartEquity * 100
+	if lossPct >= r.MaxPct {
+		return fmt.Errorf("当日已实现亏损 %.2f USDT (%.2f%%)，超过上限 %.2f%%", -account.DailyRealizedPnL, lossPct, r.MaxPct)
+	}
+	return nil
+}
+
+// MaxPositionsRule 限制同时持仓数量（同币种同方向加仓不计入新持仓）
+type MaxPositionsRule struct {
+	Max int
+}
+
+func (r *MaxPositionsRule) Name() string { return "最大持仓数" }
+
+func (r *MaxPositionsRule) Check(account *Account, order *Order) error {
+	seen := make(map[string]bool)
+	for _, pos := range account.Positions {
+		seen[pos.Symbol+"_"+pos.Side] = true
+	}
+	if seen[order.Symbol+"_"+order.Side] {
+		return nil
+	}
+	if len(seen) >= r.Max {
+		return fmt.Errorf("已有 %d 个持仓，达到上限 %d", len(seen), r.Max)
+	}
+	return nil
+}
+
+// SymbolNotionalRule 限制单币种名义价值（BTC/ETH 与山寨币分别设置）
+type SymbolNotionalRule struct {
+	MajorPct float64
+	AltPct   float64
+}
+
+func (r *SymbolNotionalRule) Name() string { return "单币敞口" }
+
+func (r *SymbolNotionalRule) Check(account *Account, order *Order) error {
+	limitPct := r.AltPct
+	if isMajor(order.Symbol) {
+		limitPct = r.MajorPct
+	}
+	if limitPct <= 0 {
+		return nil
+	}
+
+	existing := 0.0
+	for _, pos := range account.Positions {
+		if pos.Symbol == order.Symbol {
+			existing += pos.Notional
+		}
+	}
+	return capNotional(order, account.Equity*limitPct/100-existing, order.Symbol+" 名义价值")
+}
+
+// GrossExposureRule 限制总名义敞口（多+空）
+type GrossExposureRule struct {
+	MaxPct float64
+}
+
+func (r *GrossExposureRule) Name() string { return "总敞口" }
+
+func (r *GrossExposureRule) Check(account *Account, order *Order) error {
+	gross := 0.0
+	for _, pos := range account.Positions {
+		gross += pos.Notional
+	}
+	return capNotional(order, account.Equity*r.MaxPct/100-gross, "总名义敞口")
+}
+
+// NetExposureRule 限制净敞口（多-空），只约束扩大净敞口方向的开仓
+type NetExposureRule struct {
+	MaxPct float64
+}
+
+func (r *NetExposureRule) Name() string { return "净敞口" }
+
+func (r *NetExposureRule) Check(account *Account, order *Order) error {
+	net := 0.0
+	for _, pos := range account.Positions {
+		net += signedNotional(pos.Side, pos.Notional)
+	}
+	limit := account.Equity * r.MaxPct / 100
+	if order.Side == "short" {
+		net = -net
+	}
+	return capNotional(order, limit-net, "净敞口")
+}
+
+// CorrelatedExposureRule 限制同一相关组内同方向的名义敞口
+// 未配置分组时所有币种视为同一组（加密资产普遍与BTC高度相关）
+type CorrelatedExposureRule struct {
+	MaxPct float64
+	Groups [][]string
+}
+
+func (r *CorrelatedExposureRule) Name() string { return "相关性敞口" }
+
+func (r *CorrelatedExposureRule) Check(account *Account, order *Order) error {
+	inGroup := r.groupOf(order.Symbol)
+	if inGroup == nil {
+		return nil
+	}
+
+	exposure := 0.0
+	for _, pos := range account.Positions {
+		if pos.Side == order.Side && inGroup(pos.Symbol) {
+			exposure += pos.Notional
+		}
+	}
+	return capNotional(order, account.Equity*r.MaxPct/100-exposure, "相关组同向敞口")
+}
+
+// groupOf 返回判断币种是否与 symbol 同组的函数（nil 表示不受此规则约束）
+func (r *CorrelatedExposureRule) groupOf(symbol string) func(string) bool {
+	if len(r.Groups) == 0 {
+		return func(string) bool { return true }
+	}
+	for _, symbols := range r.Groups {
+		group := make(map[string]bool, len(symbols))
+		for _, s := range symbols {
+			group[s] = true
+		}
+		if group[symbol] {
+			return func(s string) bool { return group[s] }
+		}
+	}
+	return nil
+}
+
+// MarginUsageRule 限制保证金使用率（按订单杠杆换算为名义价值额度）
+type MarginUsageRule struct {
+	MaxPct float64
+}
+
+func (r *MarginUsageRule) Name() string { return "保证金使用率" }
+
+func (r *MarginUsageRule) Check(account *Account, order *Order) error {
+	leverage := math.Max(float64(order.Leverage), 1)
+	headroom := (account.Equity*r.MaxPct/100 - account.MarginUsed) * leverage
+	return capNotional(order, headroom, "保证金使用率")
+}
+
+// isMajor 是否为 BTC/ETH
+func isMajor(symbol string) bool {
+	return symbol == "BTCUSDT" || symbol == "ETHUSDT"
+}
+
+// signedNotional 多头为正、空头为负的名义价值
+func signedNotional(side string, notional float64) float64 {
+	if side == "short" {
+		return -notional
+	}
+	return notional
+}
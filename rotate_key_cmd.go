@@ -0,0 +1,160 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"nofx/config"
+	"nofx/crypto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// runRotateKeyCommand 处理 `nofx rotate-key` 子命令：数据加密密钥轮换
+//
+// 轮换流程：
+//  1. nofx rotate-key -generate 生成新密钥（按 config.json 的 data_key.provider 输出明文密钥或写出包装后的密钥文件）
+//  2. 新密钥设为当前密钥、旧密钥加入过渡期配置（DATA_ENCRYPTION_KEY_PREVIOUS / previous_key_files / previous_wrapped_key_files）并重启服务
+//  3. nofx rotate-key [-db config.db] 在一个事务中用新密钥重新加密所有凭证（也可调用管理接口）
+//  4. nofx rotate-key -verify 确认全部使用新密钥后，移除过渡期旧密钥并重启
+func runRotateKeyCommand(args []string) {
+	fs := flag.NewFlagSet("rotate-key", flag.ExitOnError)
+	dbPath := fs.String("db", "config.db", "配置数据库路径")
+	generate := fs.Bool("generate", false, "只生成新的数据加密密钥并退出")
+	verify := fs.Bool("verify", false, "只检查加密状态，不修改数据")
+	noBackup := fs.Bool("no-backup", false, "重新加密前不备份数据库")
+	fs.Parse(args)
+
+	configFile, err := loadConfigFile()
+	if err != nil {
+		log.Fatalf("❌ 读取config.json失败: %v", err)
+	}
+
+	if *generate {
+		if err := generateDataKey(configFile.DataKey); err != nil {
+			log.Fatalf("❌ 生成密钥失败: %v", err)
+		}
+		return
+	}
+
+	if _, err := os.Stat(*dbPath); err != nil {
+		log.Fatalf("❌ 数据库文件不存在: %s", *dbPath)
+	}
+
+	cryptoService, err := newCryptoService(configFile)
+	if err != nil {
+		log.Fatalf("❌ 初始化加密服务失败: %v", err)
+	}
+
+	if !*verify && !*noBackup {
+		backupPath := fmt.Sprintf("%s.pre_rotation_backup", *dbPath)
+		data, err := os.ReadFile(*dbPath)
+		if err != nil {
+			log.Fatalf("❌ 读取数据库失败: %v", err)
+		}
+		if err := os.WriteFile(backupPath, data, 0600); err != nil {
+			log.Fatalf("❌ 备份数据库失败: %v", err)
+		}
+		log.Printf("📦 数据库已备份到: %s", backupPath)
+	}
+
+	database, err := config.NewDatabase(*dbPath)
+	if err != nil {
+		log.Fatalf("❌ 打开数据库失败: %v", err)
+	}
+	defer database.Close()
+	database.SetCryptoService(cryptoService)
+
+	var report *config.EncryptionReport
+	if *verify {
+		report, err = database.VerifySensitiveData()
+	} else {
+		report, err = database.ReEncryptSensitiveData()
+	}
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	printEncryptionReport(report)
+	if !report.Complete() {
+		os.Exit(1)
+	}
+}
+
+// generateDataKey 按密钥来源生成新的数据加密密钥
+// env 直接输出明文密钥；file 写出密钥文件；vault/envelope 只写出包装后的密钥，明文不落盘
+func generateDataKey(cfg crypto.KeyProviderConfig) error {
+	provider, err := crypto.NewKeyProvider(cfg)
+	if err != nil {
+		return err
+	}
+
+	switch p := provider.(type) {
+	case *crypto.FileKeyProvider:
+		key, err := crypto.GenerateDataKey()
+		if err != nil {
+			return err
+		}
+		path, err := writeNewKeyFile(p.Path, key)
+		if err != nil {
+			return err
+		}
+		log.Printf("💡 新密钥已写入 %s：将当前密钥文件加入 previous_key_files，新文件设为 key_file 后重启服务", path)
+	case *crypto.EnvelopeKeyProvider:
+		wrapped, err := p.GenerateWrappedKey()
+		if err != nil {
+			return err
+		}
+		path, err := writeNewKeyFile(p.WrappedKeyPath, wrapped)
+		if err != nil {
+			return err
+		}
+		log.Printf("💡 包装后的新密钥已写入 %s：将当前文件加入 previous_wrapped_key_files，新文件设为 wrapped_key_file 后重启服务", path)
+	default:
+		key, err := crypto.GenerateDataKey()
+		if err != nil {
+			return err
+		}
+		fmt.Println(key)
+		log.Printf("💡 将当前 DATA_ENCRYPTION_KEY 移到 DATA_ENCRYPTION_KEY_PREVIOUS，再把上面的新密钥设为 DATA_ENCRYPTION_KEY 后重启服务")
+	}
+	return nil
+}
+
+// writeNewKeyFile 密钥文件不存在时直接写入，否则写入 <path>.new，不覆盖正在使用的密钥
+func writeNewKeyFile(path, content string) (string, error) {
+	if _, err := os.Stat(path); err == nil {
+		path += ".new"
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(content+"\n"), 0600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// printEncryptionReport 输出各敏感字段的加密状态
+func printEncryptionReport(report *config.EncryptionReport) {
+	log.Printf("🔑 当前密钥: %s | 过渡期旧密钥: %s", report.KeyID, strings.Join(report.PreviousKeyIDs, ", "))
+	for _, col := range report.Columns {
+		keyIDs := make([]string, 0, len(col.ByKeyID))
+		for keyID, count := range col.ByKeyID {
+			keyIDs = append(keyIDs, fmt.Sprintf("%s=%d", keyID, count))
+		}
+		sort.Strings(keyIDs)
+		log.Printf("  %s.%s: 共 %d 条 | 当前密钥 %d | 明文 %d | 无法解密 %d | %s",
+			col.Table, col.Column, col.Total, col.Current, col.Plaintext, col.Undecryptable, strings.Join(keyIDs, " "))
+	}
+	if report.ReEncrypted > 0 {
+		log.Printf("✅ 本次重新加密 %d 条", report.ReEncrypted)
+	}
+	if report.Complete() {
+		log.Printf("✅ 所有凭证均已使用当前密钥加密，可以移除 DATA_ENCRYPTION_KEY_PREVIOUS")
+	} else {
+		log.Printf("⚠️  仍有凭证未使用当前密钥加密")
+	}
+}
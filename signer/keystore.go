@@ -0,0 +1,61 @@
+package signer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// KeystoreSigner 从加密 keystore 文件（Web3 Secret Storage 格式）加载私钥签名
+// 私钥只以加密形式落盘，通常由远程签名服务使用
+type KeystoreSigner struct {
+	*LocalSigner
+	path string
+}
+
+// NewKeystoreSigner 使用口令解密 keystore 文件
+func NewKeystoreSigner(path, passphrase string) (*KeystoreSigner, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("未配置 keystore 口令（keystore_passphrase_file 或 %s）", passphraseEnvName)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取 keystore 失败: %w", err)
+	}
+	key, err := keystore.DecryptKey(data, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("解密 keystore %s 失败: %w", path, err)
+	}
+	return &KeystoreSigner{LocalSigner: NewLocalSigner(key.PrivateKey), path: path}, nil
+}
+
+// WriteKeystore 将十六进制私钥加密写入 keystore 文件，返回地址
+func WriteKeystore(path, privateKeyHex, passphrase string, scryptN, scryptP int) (common.Address, error) {
+	local, err := NewLocalSignerFromHex(privateKeyHex)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if passphrase == "" {
+		return common.Address{}, fmt.Errorf("keystore 口令不能为空")
+	}
+
+	key := &keystore.Key{
+		Address:    crypto.PubkeyToAddress(local.key.PublicKey),
+		PrivateKey: local.key,
+	}
+	data, err := keystore.EncryptKey(key, passphrase, scryptN, scryptP)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("加密 keystore 失败: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return common.Address{}, err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return common.Address{}, err
+	}
+	return key.Address, nil
+}
@@ -0,0 +1,144 @@
+package signer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	ethmath "github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// 远程签名服务支持的载荷类型
+const (
+	PayloadHyperliquidAgent = "hyperliquid_agent" // Hyperliquid L1 action 的 EIP-712 Agent 消息
+	PayloadAster            = "aster"             // Aster API 钱包请求参数
+)
+
+// Payload 待签名的结构化载荷
+//
+// 签名服务根据载荷自行计算摘要，只签名这两类交易所消息，不接受任意哈希
+type Payload struct {
+	Type             string            `json:"type"`
+	HyperliquidAgent *HyperliquidAgent `json:"hyperliquid_agent,omitempty"`
+	Aster            *AsterParams      `json:"aster,omitempty"`
+}
+
+// HyperliquidAgent Hyperliquid phantom agent 消息
+type HyperliquidAgent struct {
+	Source       string      `json:"source"`        // a 主网 / b 测试网
+	ConnectionID common.Hash `json:"connection_id"` // keccak256(msgpack(action) || nonce || 0x00)
+}
+
+// AsterParams Aster API 钱包签名参数
+type AsterParams struct {
+	Params string         `json:"params"` // 规范化后的请求参数 JSON
+	User   common.Address `json:"user"`   // 主钱包地址
+	Signer common.Address `json:"signer"` // API 钱包地址（即签名地址）
+	Nonce  uint64         `json:"nonce"`
+}
+
+var errInvalidPayload = errors.New("签名载荷不完整")
+
+// Digest 计算载荷的待签名摘要
+func (p Payload) Digest() ([]byte, error) {
+	switch p.Type {
+	case PayloadHyperliquidAgent:
+		if p.HyperliquidAgent == nil {
+			return nil, errInvalidPayload
+		}
+		return p.HyperliquidAgent.digest()
+	case PayloadAster:
+		if p.Aster == nil {
+			return nil, errInvalidPayload
+		}
+		return p.Aster.digest()
+	default:
+		return nil, fmt.Errorf("不支持的签名载荷类型: %q", p.Type)
+	}
+}
+
+// checkSigner 签名服务校验载荷允许由该地址签名（Aster 载荷中的 signer 必须是签名地址本身）
+func (p Payload) checkSigner(address common.Address) error {
+	if p.Type == PayloadAster && p.Aster != nil && p.Aster.Signer != address {
+		return fmt.Errorf("Aster 载荷的 signer %s 与签名地址 %s 不一致", p.Aster.Signer.Hex(), address.Hex())
+	}
+	return nil
+}
+
+// digest Hyperliquid EIP-712 Agent 摘要（domain: Exchange / 1 / chainId 1337）
+func (a *HyperliquidAgent) digest() ([]byte, error) {
+	if a.Source != "a" && a.Source != "b" {
+		return nil, fmt.Errorf("无效的 Hyperliquid source: %q", a.Source)
+	}
+	chainID := ethmath.HexOrDecimal256(*big.NewInt(1337))
+	typedData := apitypes.TypedData{
+		Domain: apitypes.TypedDataDomain{
+			ChainId:           &chainID,
+			Name:              "Exchange",
+			Version:           "1",
+			VerifyingContract: "0x0000000000000000000000000000000000000000",
+		},
+		Types: apitypes.Types{
+			"Agent": []apitypes.Type{
+				{Name: "source", Type: "string"},
+				{Name: "connectionId", Type: "bytes32"},
+			},
+			"EIP712Domain": []apitypes.Type{
+				{Name: "name", Type: "string"},
+				{Name: "version", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+		},
+		PrimaryType: "Agent",
+		Message:     apitypes.TypedDataMessage{"source": a.Source, "connectionId": a.ConnectionID.Bytes()},
+	}
+	digest, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return nil, fmt.Errorf("计算 EIP-712 摘要失败: %w", err)
+	}
+	return digest, nil
+}
+
+// digest Aster 摘要：以太坊签名消息前缀 + keccak256(abi.encode(params, user, signer, nonce))
+func (a *AsterParams) digest() ([]byte, error) {
+	if !json.Valid([]byte(a.Params)) {
+		return nil, errors.New("Aster 请求参数不是有效的 JSON")
+	}
+	tString, _ := abi.NewType("string", "", nil)
+	tAddress, _ := abi.NewType("address", "", nil)
+	tUint256, _ := abi.NewType("uint256", "", nil)
+	arguments := abi.Arguments{{Type: tString}, {Type: tAddress}, {Type: tAddress}, {Type: tUint256}}
+
+	packed, err := arguments.Pack(a.Params, a.User, a.Signer, new(big.Int).SetUint64(a.Nonce))
+	if err != nil {
+		return nil, fmt.Errorf("ABI编码失败: %w", err)
+	}
+	hash := crypto.Keccak256(packed)
+	prefixed := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(hash), hash)
+	return crypto.Keccak256([]byte(prefixed)), nil
+}
+
+// payloadSigner 只接受结构化载荷的签名器（远程签名服务）
+type payloadSigner interface {
+	SignPayload(p Payload) ([]byte, error)
+}
+
+// SignPayload 对结构化载荷签名，返回 65 字节 [R || S || V]，V 为 0/1
+//
+// 远程签名器把载荷发给签名服务，由签名服务计算摘要；其他签名器在本地计算摘要后签名
+func SignPayload(s Signer, p Payload) ([]byte, error) {
+	if ps, ok := s.(payloadSigner); ok {
+		return ps.SignPayload(p)
+	}
+	digest, err := p.Digest()
+	if err != nil {
+		return nil, err
+	}
+	return s.SignHash(digest)
+}
@@ -0,0 +1,109 @@
+package signer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const remoteRequestTimeout = 10 * time.Second
+
+// SignRequest 远程签名请求（签名服务根据载荷自行计算摘要）
+type SignRequest struct {
+	Address string  `json:"address"` // 签名地址
+	Payload Payload `json:"payload"` // 待签名的结构化载荷
+}
+
+// SignResponse 远程签名响应
+type SignResponse struct {
+	Signature string `json:"signature,omitempty"` // 0x 开头的 65 字节签名，V 为 0/1
+	Error     string `json:"error,omitempty"`
+}
+
+// RemoteSigner 通过 HTTP 调用远程签名服务（私钥只存在于签名服务中）
+type RemoteSigner struct {
+	url     string
+	token   string
+	address common.Address
+	client  *http.Client
+}
+
+// NewRemoteSigner 创建远程签名器
+func NewRemoteSigner(url, token, address string) (*RemoteSigner, error) {
+	if url == "" {
+		return nil, errors.New("未配置远程签名服务地址（signer.remote_url）")
+	}
+	if token == "" {
+		return nil, fmt.Errorf("未配置远程签名服务 token（signer.remote_token_file 或 %s）", tokenEnvName)
+	}
+	if !common.IsHexAddress(address) {
+		return nil, fmt.Errorf("无效的签名地址: %s", address)
+	}
+	return &RemoteSigner{
+		url:     strings.TrimRight(url, "/"),
+		token:   token,
+		address: common.HexToAddress(address),
+		client:  &http.Client{Timeout: remoteRequestTimeout},
+	}, nil
+}
+
+func (s *RemoteSigner) Address() common.Address { return s.address }
+
+// SignHash 远程签名服务不签名任意哈希，需通过 SignPayload 提交结构化载荷
+func (s *RemoteSigner) SignHash(hash []byte) ([]byte, error) {
+	return nil, errors.New("远程签名服务只签名结构化载荷（Hyperliquid Agent 消息或 Aster 请求参数）")
+}
+
+// SignPayload 请求远程签名，并校验签名确实来自配置的地址
+func (s *RemoteSigner) SignPayload(p Payload) ([]byte, error) {
+	hash, err := p.Digest()
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(SignRequest{Address: s.address.Hex(), Payload: p})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url+"/v1/sign", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+s.token)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求远程签名服务失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if err != nil {
+		return nil, fmt.Errorf("读取远程签名响应失败: %w", err)
+	}
+	var result SignResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("解析远程签名响应失败 (HTTP %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("远程签名失败 (HTTP %d): %s", resp.StatusCode, result.Error)
+	}
+
+	signature, err := hexutil.Decode(result.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("解析签名失败: %w", err)
+	}
+	if err := VerifySignature(s.address, hash, signature); err != nil {
+		return nil, err
+	}
+	return signature, nil
+}
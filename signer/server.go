@@ -0,0 +1,103 @@
+package signer
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Server 远程签名服务的参考实现（nofx signer-server）
+// 只为已加载的地址签名，所有请求需要 Bearer token
+//
+// 只签名 Hyperliquid Agent 消息和 Aster 请求参数两类结构化载荷，摘要由服务端计算，不接受任意哈希。
+// 但 Agent 消息的 connectionId 本身是 action 的哈希，服务端无法得知具体下单内容：
+// 持有 token 的一方仍可以该地址的名义提交任意交易所操作，token 须与私钥同等保管，服务只应监听内网地址
+type Server struct {
+	token   string
+	signers map[common.Address]Signer
+}
+
+// NewServer 创建签名服务
+func NewServer(token string, signers ...Signer) *Server {
+	s := &Server{token: token, signers: make(map[common.Address]Signer)}
+	for _, signer := range signers {
+		s.signers[signer.Address()] = signer
+	}
+	return s
+}
+
+// Addresses 已加载的签名地址
+func (s *Server) Addresses() []string {
+	addresses := make([]string, 0, len(s.signers))
+	for addr := range s.signers {
+		addresses = append(addresses, addr.Hex())
+	}
+	return addresses
+}
+
+// Handler HTTP 路由：POST /v1/sign、GET /v1/addresses
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/sign", s.authorize(s.handleSign))
+	mux.HandleFunc("GET /v1/addresses", s.authorize(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"addresses": s.Addresses()})
+	}))
+	return mux
+}
+
+func (s *Server) authorize(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if s.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			writeJSON(w, http.StatusUnauthorized, SignResponse{Error: "unauthorized"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) handleSign(w http.ResponseWriter, r *http.Request) {
+	var req SignRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, SignResponse{Error: "invalid request"})
+		return
+	}
+	if !common.IsHexAddress(req.Address) {
+		writeJSON(w, http.StatusBadRequest, SignResponse{Error: "invalid address"})
+		return
+	}
+	signer, ok := s.signers[common.HexToAddress(req.Address)]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, SignResponse{Error: "unknown address"})
+		return
+	}
+	if err := req.Payload.checkSigner(signer.Address()); err != nil {
+		writeJSON(w, http.StatusBadRequest, SignResponse{Error: err.Error()})
+		return
+	}
+	hash, err := req.Payload.Digest()
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, SignResponse{Error: err.Error()})
+		return
+	}
+
+	signature, err := signer.SignHash(hash)
+	if err != nil {
+		log.Printf("❌ 签名失败 (%s): %v", req.Address, err)
+		writeJSON(w, http.StatusInternalServerError, SignResponse{Error: "sign failed"})
+		return
+	}
+	log.Printf("✍️  已签名: %s %s %s", signer.Address().Hex(), req.Payload.Type, hexutil.Encode(hash))
+	writeJSON(w, http.StatusOK, SignResponse{Signature: hexutil.Encode(signature)})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,146 @@
+package signer
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// 钱包私钥配置支持的签名器前缀（交易所配置中的私钥字段）
+const (
+	RemotePrefix   = "remote:"   // remote:0x<地址>，由远程签名服务签名，私钥不进入 nofx 进程
+	KeystorePrefix = "keystore:" // keystore:<文件路径>，加密的 keystore 文件 + 口令
+)
+
+const (
+	tokenEnvName      = "NOFX_SIGNER_TOKEN"        // 远程签名服务 token
+	passphraseEnvName = "NOFX_KEYSTORE_PASSPHRASE" // keystore 口令
+)
+
+// Signer 以太坊 secp256k1 签名器（Hyperliquid / Aster 下单签名）
+type Signer interface {
+	// Address 签名地址
+	Address() common.Address
+	// SignHash 对 32 字节哈希签名，返回 65 字节 [R || S || V]，V 为 0/1
+	// 远程签名器不支持任意哈希，交易所下单签名应使用 SignPayload
+	SignHash(hash []byte) ([]byte, error)
+}
+
+// Config 签名器配置（config.json 中的 signer）
+type Config struct {
+	RemoteURL              string `json:"remote_url"`               // 远程签名服务地址，如 http://127.0.0.1:8701
+	RemoteTokenFile        string `json:"remote_token_file"`        // 远程签名服务 token 文件，为空使用 NOFX_SIGNER_TOKEN
+	KeystorePassphraseFile string `json:"keystore_passphrase_file"` // keystore 口令文件，为空使用 NOFX_KEYSTORE_PASSPHRASE
+}
+
+var (
+	config   Config
+	configMu sync.RWMutex
+)
+
+// Configure 设置全局签名器配置（启动时调用）
+func Configure(cfg Config) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	config = cfg
+}
+
+func currentConfig() Config {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return config
+}
+
+// New 根据私钥字段创建签名器：
+//   - remote:0x<地址>  远程签名服务
+//   - keystore:<路径>  加密 keystore 文件
+//   - 其他按十六进制私钥解析（兼容原有配置）
+func New(spec string) (Signer, error) {
+	spec = strings.TrimSpace(spec)
+	cfg := currentConfig()
+
+	switch {
+	case strings.HasPrefix(spec, RemotePrefix):
+		token, err := readSecret(cfg.RemoteTokenFile, tokenEnvName)
+		if err != nil {
+			return nil, err
+		}
+		return NewRemoteSigner(cfg.RemoteURL, token, strings.TrimPrefix(spec, RemotePrefix))
+	case strings.HasPrefix(spec, KeystorePrefix):
+		passphrase, err := readSecret(cfg.KeystorePassphraseFile, passphraseEnvName)
+		if err != nil {
+			return nil, err
+		}
+		return NewKeystoreSigner(strings.TrimPrefix(spec, KeystorePrefix), passphrase)
+	default:
+		return NewLocalSignerFromHex(spec)
+	}
+}
+
+// IsExternal 私钥字段是否指向外部签名器（不包含私钥明文）
+func IsExternal(spec string) bool {
+	spec = strings.TrimSpace(spec)
+	return strings.HasPrefix(spec, RemotePrefix) || strings.HasPrefix(spec, KeystorePrefix)
+}
+
+// readSecret 优先读取文件，否则读取环境变量
+func readSecret(path, envName string) (string, error) {
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("读取 %s 失败: %w", path, err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	return strings.TrimSpace(os.Getenv(envName)), nil
+}
+
+// LocalSigner 进程内私钥签名（原有方式，也是远程签名服务的参考实现）
+type LocalSigner struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+// NewLocalSigner 使用私钥创建签名器
+func NewLocalSigner(key *ecdsa.PrivateKey) *LocalSigner {
+	return &LocalSigner{key: key, address: crypto.PubkeyToAddress(key.PublicKey)}
+}
+
+// NewLocalSignerFromHex 解析十六进制私钥（可带 0x 前缀）
+func NewLocalSignerFromHex(privateKeyHex string) (*LocalSigner, error) {
+	privateKeyHex = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(privateKeyHex)), "0x")
+	key, err := crypto.HexToECDSA(privateKeyHex)
+	if err != nil {
+		return nil, fmt.Errorf("解析私钥失败: %w", err)
+	}
+	return NewLocalSigner(key), nil
+}
+
+func (s *LocalSigner) Address() common.Address { return s.address }
+
+func (s *LocalSigner) SignHash(hash []byte) ([]byte, error) {
+	return crypto.Sign(hash, s.key)
+}
+
+// PrivateKey 返回私钥（仅供需要原始私钥的 SDK 使用）
+func (s *LocalSigner) PrivateKey() *ecdsa.PrivateKey { return s.key }
+
+// VerifySignature 校验签名是否由指定地址生成
+func VerifySignature(address common.Address, hash, signature []byte) error {
+	if len(signature) != crypto.SignatureLength {
+		return fmt.Errorf("签名长度异常: %d", len(signature))
+	}
+	pub, err := crypto.SigToPub(hash, signature)
+	if err != nil {
+		return fmt.Errorf("恢复签名公钥失败: %w", err)
+	}
+	if recovered := crypto.PubkeyToAddress(*pub); recovered != address {
+		return fmt.Errorf("签名地址不匹配: 期望 %s，实际 %s", address.Hex(), recovered.Hex())
+	}
+	return nil
+}
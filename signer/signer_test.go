@@ -0,0 +1,163 @@
+package signer
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const testPrivateKey = "0x4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+
+func TestNew_LocalSigner(t *testing.T) {
+	s, err := New(testPrivateKey)
+	if err != nil {
+		t.Fatalf("创建签名器失败: %v", err)
+	}
+	local, ok := s.(*LocalSigner)
+	if !ok {
+		t.Fatalf("十六进制私钥应创建 LocalSigner，实际 %T", s)
+	}
+	if local.Address() != crypto.PubkeyToAddress(local.PrivateKey().PublicKey) {
+		t.Error("地址与私钥不一致")
+	}
+
+	hash := crypto.Keccak256([]byte("nofx"))
+	sig, err := s.SignHash(hash)
+	if err != nil {
+		t.Fatalf("签名失败: %v", err)
+	}
+	if err := VerifySignature(s.Address(), hash, sig); err != nil {
+		t.Errorf("签名校验失败: %v", err)
+	}
+
+	if _, err := New("invalid_key"); err == nil || !strings.Contains(err.Error(), "解析私钥失败") {
+		t.Errorf("无效私钥应报错，实际 %v", err)
+	}
+}
+
+func TestKeystoreSigner(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.json")
+	address, err := WriteKeystore(path, testPrivateKey, "correct horse", keystore.LightScryptN, keystore.LightScryptP)
+	if err != nil {
+		t.Fatalf("写入 keystore 失败: %v", err)
+	}
+
+	t.Setenv(passphraseEnvName, "correct horse")
+	s, err := New(KeystorePrefix + path)
+	if err != nil {
+		t.Fatalf("加载 keystore 失败: %v", err)
+	}
+	if s.Address() != address {
+		t.Errorf("keystore 地址 = %s, want %s", s.Address().Hex(), address.Hex())
+	}
+
+	if _, err := NewKeystoreSigner(path, "wrong"); err == nil {
+		t.Error("口令错误时应报错")
+	}
+}
+
+func TestRemoteSigner(t *testing.T) {
+	local, _ := NewLocalSignerFromHex(testPrivateKey)
+	other, _ := crypto.GenerateKey()
+	server := httptest.NewServer(NewServer("test-token", local).Handler())
+	defer server.Close()
+
+	Configure(Config{RemoteURL: server.URL})
+	defer Configure(Config{})
+	t.Setenv(tokenEnvName, "test-token")
+
+	s, err := New(RemotePrefix + local.Address().Hex())
+	if err != nil {
+		t.Fatalf("创建远程签名器失败: %v", err)
+	}
+	if !IsExternal(RemotePrefix + local.Address().Hex()) {
+		t.Error("remote: 应识别为外部签名器")
+	}
+
+	payload := Payload{
+		Type:             PayloadHyperliquidAgent,
+		HyperliquidAgent: &HyperliquidAgent{Source: "a", ConnectionID: crypto.Keccak256Hash([]byte("order"))},
+	}
+	sig, err := SignPayload(s, payload)
+	if err != nil {
+		t.Fatalf("远程签名失败: %v", err)
+	}
+	want, err := SignPayload(local, payload)
+	if err != nil {
+		t.Fatalf("本地签名失败: %v", err)
+	}
+	if hexutil.Encode(sig) != hexutil.Encode(want) {
+		t.Error("远程签名应与本地签名一致")
+	}
+
+	aster := Payload{
+		Type:  PayloadAster,
+		Aster: &AsterParams{Params: `{"symbol":"BTCUSDT"}`, User: common.HexToAddress("0x1234567890123456789012345678901234567890"), Signer: local.Address(), Nonce: 1},
+	}
+	if _, err := SignPayload(s, aster); err != nil {
+		t.Errorf("Aster 载荷签名失败: %v", err)
+	}
+	aster.Aster.Signer = crypto.PubkeyToAddress(other.PublicKey)
+	if _, err := SignPayload(s, aster); err == nil || !strings.Contains(err.Error(), "不一致") {
+		t.Errorf("Aster signer 与签名地址不一致时应被拒绝，实际 %v", err)
+	}
+
+	// 签名服务不签名任意哈希
+	if _, err := s.SignHash(crypto.Keccak256([]byte("order"))); err == nil {
+		t.Error("远程签名器不应签名任意哈希")
+	}
+	resp, err := http.Post(server.URL+"/v1/sign", "application/json",
+		strings.NewReader(fmt.Sprintf(`{"address":%q,"hash":%q}`, local.Address().Hex(), hexutil.Encode(crypto.Keccak256([]byte("x"))))))
+	if err != nil {
+		t.Fatalf("请求签名服务失败: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("无 token 请求应返回 401，实际 %d", resp.StatusCode)
+	}
+	raw, _ := http.NewRequest(http.MethodPost, server.URL+"/v1/sign",
+		strings.NewReader(fmt.Sprintf(`{"address":%q,"hash":%q}`, local.Address().Hex(), hexutil.Encode(crypto.Keccak256([]byte("x"))))))
+	raw.Header.Set("Authorization", "Bearer test-token")
+	resp, err = http.DefaultClient.Do(raw)
+	if err != nil {
+		t.Fatalf("请求签名服务失败: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("只提交哈希的请求应返回 400，实际 %d", resp.StatusCode)
+	}
+
+	// 签名服务未加载的地址
+	unknown, _ := NewRemoteSigner(server.URL, "test-token", crypto.PubkeyToAddress(other.PublicKey).Hex())
+	if _, err := unknown.SignPayload(payload); err == nil || !strings.Contains(err.Error(), "unknown address") {
+		t.Errorf("未知地址应被拒绝，实际 %v", err)
+	}
+
+	// token 错误
+	badToken, _ := NewRemoteSigner(server.URL, "wrong", local.Address().Hex())
+	if _, err := badToken.SignPayload(payload); err == nil || !strings.Contains(err.Error(), "unauthorized") {
+		t.Errorf("token 错误应被拒绝，实际 %v", err)
+	}
+
+	if _, err := SignPayload(s, Payload{Type: "raw_hash"}); err == nil {
+		t.Error("不支持的载荷类型应报错")
+	}
+}
+
+func TestVerifySignature_WrongAddress(t *testing.T) {
+	local, _ := NewLocalSignerFromHex(testPrivateKey)
+	other, _ := crypto.GenerateKey()
+	hash := crypto.Keccak256([]byte("nofx"))
+	sig, _ := local.SignHash(hash)
+	if err := VerifySignature(crypto.PubkeyToAddress(other.PublicKey), hash, sig); err == nil {
+		t.Error("地址不匹配时应报错")
+	}
+}
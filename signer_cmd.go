@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"log"
+	"net/http"
+	"nofx/signer"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+)
+
+// runSignerServerCommand 处理 `nofx signer-server` 子命令：远程签名服务的参考实现
+//
+// 私钥以加密 keystore 文件保存在签名服务所在主机，nofx 交易进程只保存 remote:0x<地址>，
+// 下单时把待签名的交易所消息（Hyperliquid Agent 消息 / Aster 请求参数）发给签名服务，由签名服务计算摘要后签名。
+// 持有 token 即可以该地址的名义提交交易所操作，token 须与私钥同等保管。token 和口令优先读取文件，否则读取 NOFX_SIGNER_TOKEN / NOFX_KEYSTORE_PASSPHRASE。
+//
+// 示例：
+//
+//	nofx signer-server -import -keystore keys/agent.json < agent.hex   # 把十六进制私钥加密为 keystore
+//	nofx signer-server -keystore keys/agent.json,keys/aster.json -token-file secrets/signer_token
+func runSignerServerCommand(args []string) {
+	fs := flag.NewFlagSet("signer-server", flag.ExitOnError)
+	listen := fs.String("listen", "127.0.0.1:8701", "监听地址")
+	keystores := fs.String("keystore", "", "keystore 文件路径（逗号分隔多个）")
+	tokenFile := fs.String("token-file", "", "访问 token 文件（为空使用 NOFX_SIGNER_TOKEN）")
+	passphraseFile := fs.String("passphrase-file", "", "keystore 口令文件（为空使用 NOFX_KEYSTORE_PASSPHRASE）")
+	importKey := fs.Bool("import", false, "从标准输入读取十六进制私钥，加密写入 -keystore 后退出")
+	fs.Parse(args)
+
+	passphrase := readSecretOrEnv(*passphraseFile, "NOFX_KEYSTORE_PASSPHRASE")
+	paths := splitNonEmpty(*keystores)
+	if len(paths) == 0 {
+		log.Fatalf("❌ 请通过 -keystore 指定 keystore 文件")
+	}
+
+	if *importKey {
+		if len(paths) != 1 {
+			log.Fatalf("❌ -import 只能指定一个 keystore 文件")
+		}
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			log.Fatalf("❌ 读取私钥失败: %v", err)
+		}
+		address, err := signer.WriteKeystore(paths[0], strings.TrimSpace(line), passphrase, keystore.StandardScryptN, keystore.StandardScryptP)
+		if err != nil {
+			log.Fatalf("❌ 写入 keystore 失败: %v", err)
+		}
+		log.Printf("✅ keystore 已写入 %s，地址: %s", paths[0], address.Hex())
+		log.Printf("💡 交易所配置中的私钥填写 remote:%s", address.Hex())
+		return
+	}
+
+	token := readSecretOrEnv(*tokenFile, "NOFX_SIGNER_TOKEN")
+	if token == "" {
+		log.Fatalf("❌ 未配置访问 token（-token-file 或 NOFX_SIGNER_TOKEN）")
+	}
+
+	var signers []signer.Signer
+	for _, path := range paths {
+		s, err := signer.NewKeystoreSigner(path, passphrase)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		log.Printf("🔑 已加载签名地址: %s (%s)", s.Address().Hex(), path)
+		signers = append(signers, s)
+	}
+
+	server := &http.Server{
+		Addr:              *listen,
+		Handler:           signer.NewServer(token, signers...).Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	log.Printf("✍️  签名服务已启动: http://%s", *listen)
+	if err := server.ListenAndServe(); err != nil {
+		log.Fatalf("❌ 签名服务退出: %v", err)
+	}
+}
+
+// readSecretOrEnv 优先读取文件内容，否则读取环境变量
+func readSecretOrEnv(path, envName string) string {
+	if path == "" {
+		return strings.TrimSpace(os.Getenv(envName))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalf("❌ 读取 %s 失败: %v", path, err)
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// splitNonEmpty 拆分逗号分隔的列表
+func splitNonEmpty(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
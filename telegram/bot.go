@@ -0,0 +1,237 @@
+package telegram
+
+import (
+	"fmt"
+	"log"
+	"nofx/config"
+	"nofx/events"
+	"nofx/logger"
+	"nofx/manager"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxMessageRunes Telegram 单条消息长度上限
+const maxMessageRunes = 4096
+
+// Bot 交互式 Telegram 机器人（长轮询接收命令）
+//
+// 与 logger.TelegramSender 单向推送日志不同，Bot 将聊天绑定到 nofx 用户，
+// 通过 TraderManager 查询和控制该用户的交易员
+type Bot struct {
+	api     *tgbotapi.BotAPI
+	handler *Handler
+	sub     *events.Subscription // 提案推送订阅
+
+	stop chan struct{}
+	once sync.Once
+	wg   sync.WaitGroup
+}
+
+// NewBot 创建机器人
+func NewBot(token string, store Store, controller Controller) (*Bot, error) {
+	api, err := tgbotapi.NewBotAPI(token)
+	if err != nil {
+		return nil, fmt.Errorf("创建telegram bot失败: %w", err)
+	}
+	api.Debug = false
+
+	return &Bot{
+		api:     api,
+		handler: NewHandler(store, controller),
+		stop:    make(chan struct{}),
+	}, nil
+}
+
+// Username 机器人用户名（用于前端展示绑定入口）
+func (b *Bot) Username() string {
+	return b.api.Self.UserName
+}
+
+// Start 注册命令菜单并开始接收消息，同时订阅事件总线推送待审批提案
+func (b *Bot) Start(bus *events.Bus) {
+	menu := make([]tgbotapi.BotCommand, 0, len(commands)+2)
+	for _, cmd := range commands {
+		menu = append(menu, tgbotapi.BotCommand{Command: cmd.name, Description: cmd.desc})
+	}
+	menu = append(menu,
+		tgbotapi.BotCommand{Command: "bind", Description: "绑定 nofx 账户"},
+		tgbotapi.BotCommand{Command: "help", Description: "命令帮助"},
+	)
+	if _, err := b.api.Request(tgbotapi.NewSetMyCommands(menu...)); err != nil {
+		log.Printf("⚠️ 注册 Telegram 命令菜单失败: %v", err)
+	}
+
+	u := tgbotapi.NewUpdate(0)
+	u.Timeout = 30
+	updates := b.api.GetUpdatesChan(u)
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		for {
+			select {
+			case update, ok := <-updates:
+				if !ok {
+					return
+				}
+				b.handleUpdate(update)
+			case <-b.stop:
+				return
+			}
+		}
+	}()
+
+	b.sub, _ = bus.Subscribe(0, func(event events.Event) bool {
+		return event.Type == events.ProposalCreated
+	})
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		for event := range b.sub.Events() {
+			for _, notice := range b.handler.ProposalNotices(event) {
+				b.reply(notice.ChatID, notice.Reply)
+			}
+		}
+	}()
+	log.Printf("📱 Telegram 机器人 @%s 已启动", b.Username())
+}
+
+// Stop 停止接收消息
+func (b *Bot) Stop() {
+	b.once.Do(func() {
+		close(b.stop)
+		b.api.StopReceivingUpdates()
+		if b.sub != nil {
+			b.sub.Close()
+		}
+	})
+	b.wg.Wait()
+}
+
+// handleUpdate 处理一条更新（命令消息或确认按钮回调）
+func (b *Bot) handleUpdate(update tgbotapi.Update) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("❌ 处理 Telegram 消息 panic: %v", r)
+		}
+	}()
+
+	switch {
+	case update.Message != nil:
+		msg := update.Message
+		username := ""
+		if msg.From != nil {
+			username = msg.From.UserName
+		}
+		b.reply(msg.Chat.ID, b.handler.HandleMessage(msg.Chat.ID, username, msg.Text))
+
+	case update.CallbackQuery != nil:
+		query := update.CallbackQuery
+		if _, err := b.api.Request(tgbotapi.NewCallback(query.ID, "")); err != nil {
+			log.Printf("⚠️ 应答 Telegram 回调失败: %v", err)
+		}
+		if query.Message == nil {
+			return
+		}
+		reply := b.handler.HandleCallback(query.Message.Chat.ID, query.Data)
+		if reply.Text == "" {
+			return
+		}
+		// 用执行结果替换确认消息（同时移除按钮，防止重复点击）
+		edit := tgbotapi.NewEditMessageText(query.Message.Chat.ID, query.Message.MessageID, truncate(reply.Text))
+		if _, err := b.api.Send(edit); err != nil {
+			b.reply(query.Message.Chat.ID, reply)
+		}
+	}
+}
+
+// reply 发送回复（纯文本，避免交易员名称等内容破坏 Markdown 解析）
+func (b *Bot) reply(chatID int64, reply Reply) {
+	if reply.Text == "" {
+		return
+	}
+	msg := tgbotapi.NewMessage(chatID, truncate(reply.Text))
+	if reply.Keyboard != nil {
+		msg.ReplyMarkup = *reply.Keyboard
+	}
+	if _, err := b.api.Send(msg); err != nil {
+		log.Printf("⚠️ 发送 Telegram 消息失败 (chat %d): %v", chatID, err)
+	}
+}
+
+// truncate 截断超长消息
+func truncate(text string) string {
+	runes := []rune(text)
+	if len(runes) <= maxMessageRunes {
+		return text
+	}
+	return string(runes[:maxMessageRunes-1]) + "…"
+}
+
+// managerController 通过 TraderManager 查询和控制交易员
+type managerController struct {
+	traderManager *manager.TraderManager
+	database      *config.Database
+}
+
+// NewManagerController 创建基于 TraderManager 的控制器
+func NewManagerController(traderManager *manager.TraderManager, database *config.Database) Controller {
+	return &managerController{traderManager: traderManager, database: database}
+}
+
+// UserTraders 用户的所有交易员
+func (c *managerController) UserTraders(userID string) ([]Trader, error) {
+	traders, err := c.traderManager.GetUserTraders(c.database, userID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Trader, 0, len(traders))
+	for _, t := range traders {
+		result = append(result, t)
+	}
+	return result, nil
+}
+
+// PauseTrader 停止交易员决策循环
+func (c *managerController) PauseTrader(userID, traderID string) error {
+	return c.traderManager.StopTrader(c.database, userID, traderID)
+}
+
+// ResumeTrader 重新启动交易员
+func (c *managerController) ResumeTrader(userID, traderID string) error {
+	return c.traderManager.StartTrader(c.database, userID, traderID)
+}
+
+// FlattenAll 平掉交易员所有持仓（记录为手动操作）
+func (c *managerController) FlattenAll(userID, traderID, note string) (*logger.DecisionRecord, error) {
+	return c.traderManager.FlattenAll(c.database, userID, traderID, note)
+}
+
+// TraderOwner 交易员所属用户
+func (c *managerController) TraderOwner(traderID string) (string, error) {
+	at, err := c.traderManager.GetTrader(traderID)
+	if err != nil {
+		return "", err
+	}
+	return at.GetUserID(), nil
+}
+
+// ApproveProposal 批准提案并执行（执行失败时返回错误）
+func (c *managerController) ApproveProposal(userID, traderID, proposalID string) error {
+	proposal, err := c.traderManager.ApproveProposal(c.database, userID, traderID, proposalID)
+	if err != nil {
+		return err
+	}
+	if proposal.Result != nil && !proposal.Result.Success {
+		return fmt.Errorf("执行失败: %s", proposal.Result.Error)
+	}
+	return nil
+}
+
+// RejectProposal 拒绝提案
+func (c *managerController) RejectProposal(userID, traderID, proposalID string) error {
+	_, err := c.traderManager.RejectProposal(c.database, userID, traderID, proposalID, "Telegram 拒绝")
+	return err
+}
@@ -0,0 +1,548 @@
+package telegram
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"nofx/config"
+	"nofx/logger"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	confirmTTL         = 60 * time.Second // 危险操作确认的有效期
+	maxCoTPreviewRunes = 400              // /lastdecision 显示的思维链长度
+)
+
+// Trader 机器人使用的交易员接口（由 *trader.AutoTrader 实现）
+type Trader interface {
+	GetID() string
+	GetName() string
+	GetStatus() map[string]interface{}
+	GetAccountInfo() (map[string]interface{}, error)
+	GetPositions() ([]map[string]interface{}, error)
+	GetDecisionLogger() logger.IDecisionLogger
+}
+
+// Controller 交易员查询与控制（所有操作都按用户校验交易员归属）
+type Controller interface {
+	UserTraders(userID string) ([]Trader, error)
+	PauseTrader(userID, traderID string) error
+	ResumeTrader(userID, traderID string) error
+	FlattenAll(userID, traderID, note string) (*logger.DecisionRecord, error)
+	TraderOwner(traderID string) (string, error)
+	ApproveProposal(userID, traderID, proposalID string) error
+	RejectProposal(userID, traderID, proposalID string) error
+}
+
+// Store 聊天绑定存储（由 config.Database 实现）
+type Store interface {
+	ConsumeTelegramBindCode(code string) (*config.TelegramBindCode, error)
+	SaveTelegramBinding(binding *config.TelegramBinding) error
+	GetTelegramBinding(chatID int64) (*config.TelegramBinding, error)
+	DeleteTelegramBinding(userID string, chatID int64) error
+	GetTelegramBindings(userID string) ([]*config.TelegramBinding, error)
+}
+
+// Reply 命令回复（Keyboard 非空时附带确认按钮）
+type Reply struct {
+	Text     string
+	Keyboard *tgbotapi.InlineKeyboardMarkup
+}
+
+// command 机器人命令
+type command struct {
+	name    string
+	args    string
+	desc    string
+	control bool // 需要绑定时授予控制权限
+	confirm bool // 执行前需要二次确认
+	run     func(h *Handler, userID string, target Trader, args []string) string
+}
+
+// commands 绑定后可用的命令（/start /help /bind /unbind 单独处理）
+var commands = []*command{
+	{name: "status", args: "[交易员]", desc: "交易员运行状态", run: (*Handler).cmdStatus},
+	{name: "positions", args: "[交易员]", desc: "当前持仓", run: (*Handler).cmdPositions},
+	{name: "pnl", args: "[交易员]", desc: "账户净值与盈亏", run: (*Handler).cmdPnL},
+	{name: "lastdecision", args: "[交易员]", desc: "最近一次AI决策", run: (*Handler).cmdLastDecision},
+	{name: "pause", args: "<交易员>", desc: "暂停交易员（停止决策循环）", control: true, confirm: true, run: (*Handler).cmdPause},
+	{name: "resume", args: "<交易员>", desc: "恢复交易员", control: true, run: (*Handler).cmdResume},
+	{name: "closeall", args: "<交易员>", desc: "市价平掉交易员所有持仓", control: true, confirm: true, run: (*Handler).cmdCloseAll},
+}
+
+// pendingAction 等待确认的危险操作
+type pendingAction struct {
+	chatID    int64
+	userID    string
+	cmd       *command
+	target    Trader
+	expiresAt time.Time
+}
+
+// Handler 解析并执行机器人命令（与 Telegram API 解耦，便于测试）
+type Handler struct {
+	store      Store
+	controller Controller
+
+	mu        sync.Mutex
+	pending   map[string]*pendingAction
+	proposals map[string]*proposalAction // 审批按钮令牌 -> 提案
+}
+
+// NewHandler 创建命令处理器
+func NewHandler(store Store, controller Controller) *Handler {
+	return &Handler{
+		store:      store,
+		controller: controller,
+		pending:    make(map[string]*pendingAction),
+		proposals:  make(map[string]*proposalAction),
+	}
+}
+
+// HandleMessage 处理一条文本消息，非命令消息返回空回复
+func (h *Handler) HandleMessage(chatID int64, username, text string) Reply {
+	name, args := parseCommand(text)
+	if name == "" {
+		return Reply{}
+	}
+
+	switch name {
+	case "start", "help":
+		return Reply{Text: helpText()}
+	case "bind":
+		return Reply{Text: h.bind(chatID, username, args)}
+	}
+
+	binding, err := h.store.GetTelegramBinding(chatID)
+	if err != nil {
+		log.Printf("⚠️ 读取 Telegram 绑定失败 (chat %d): %v", chatID, err)
+		return Reply{Text: "❌ 读取绑定信息失败，请稍后再试"}
+	}
+	if binding == nil {
+		return Reply{Text: "🔒 当前聊天未绑定 nofx 账户。\n请在网页端生成绑定码后发送 /bind <绑定码>"}
+	}
+
+	if name == "unbind" {
+		if err := h.store.DeleteTelegramBinding("", chatID); err != nil {
+			return Reply{Text: fmt.Sprintf("❌ 解除绑定失败: %v", err)}
+		}
+		return Reply{Text: "✓ 已解除绑定"}
+	}
+
+	cmd := findCommand(name)
+	if cmd == nil {
+		return Reply{Text: fmt.Sprintf("未知命令 /%s，发送 /help 查看可用命令", name)}
+	}
+	if cmd.control && !binding.CanControl {
+		return Reply{Text: fmt.Sprintf("⛔ 当前绑定为只读权限，无法执行 /%s", cmd.name)}
+	}
+
+	var target Trader
+	if cmd.control || len(args) > 0 {
+		target, err = h.resolveTrader(binding.UserID, args, cmd)
+		if err != nil {
+			return Reply{Text: err.Error()}
+		}
+	}
+
+	if cmd.confirm {
+		return h.requestConfirm(chatID, binding.UserID, cmd, target)
+	}
+	return Reply{Text: cmd.run(h, binding.UserID, target, args)}
+}
+
+// HandleCallback 处理确认按钮回调
+func (h *Handler) HandleCallback(chatID int64, data string) Reply {
+	verb, token, ok := strings.Cut(data, ":")
+	if ok && (verb == "approve" || verb == "reject") {
+		return h.handleProposalCallback(chatID, verb, token)
+	}
+	if !ok || (verb != "confirm" && verb != "cancel") {
+		return Reply{}
+	}
+
+	h.mu.Lock()
+	action := h.pending[token]
+	if action != nil && action.chatID == chatID {
+		delete(h.pending, token)
+	}
+	h.mu.Unlock()
+
+	if action == nil || action.chatID != chatID {
+		return Reply{Text: "⚠️ 操作不存在或已处理"}
+	}
+	if verb == "cancel" {
+		return Reply{Text: fmt.Sprintf("已取消 /%s %s", action.cmd.name, action.target.GetName())}
+	}
+	if time.Now().After(action.expiresAt) {
+		return Reply{Text: "⌛ 确认已超时，请重新发送命令"}
+	}
+
+	// 确认期间绑定可能已被解除或降权，执行前重新校验
+	binding, err := h.store.GetTelegramBinding(chatID)
+	if err != nil || binding == nil || binding.UserID != action.userID || !binding.CanControl {
+		return Reply{Text: "⛔ 绑定已变更，操作未执行"}
+	}
+
+	log.Printf("📱 Telegram 执行 /%s %s (chat %d, user %s)", action.cmd.name, action.target.GetID(), chatID, action.userID)
+	return Reply{Text: action.cmd.run(h, action.userID, action.target, nil)}
+}
+
+// bind 使用绑定码将当前聊天绑定到用户
+func (h *Handler) bind(chatID int64, username string, args []string) string {
+	if len(args) == 0 {
+		return "用法: /bind <绑定码>（在 nofx 网页端生成）"
+	}
+	code, err := h.store.ConsumeTelegramBindCode(strings.TrimSpace(args[0]))
+	if err != nil {
+		log.Printf("⚠️ 校验 Telegram 绑定码失败: %v", err)
+		return "❌ 绑定失败，请稍后再试"
+	}
+	if code == nil {
+		return "❌ 绑定码无效或已过期"
+	}
+
+	binding := &config.TelegramBinding{ChatID: chatID, UserID: code.UserID, Username: username, CanControl: code.CanControl}
+	if err := h.store.SaveTelegramBinding(binding); err != nil {
+		log.Printf("⚠️ 保存 Telegram 绑定失败: %v", err)
+		return "❌ 绑定失败，请稍后再试"
+	}
+	log.Printf("📱 Telegram 聊天 %d 已绑定用户 %s（控制权限: %v）", chatID, code.UserID, code.CanControl)
+
+	permission := "只读"
+	if code.CanControl {
+		permission = "查询 + 控制"
+	}
+	return fmt.Sprintf("✓ 绑定成功（权限: %s）\n\n%s", permission, helpText())
+}
+
+// requestConfirm 登记待确认操作并返回确认按钮
+func (h *Handler) requestConfirm(chatID int64, userID string, cmd *command, target Trader) Reply {
+	token := newToken()
+	h.mu.Lock()
+	now := time.Now()
+	for key, action := range h.pending {
+		if now.After(action.expiresAt) {
+			delete(h.pending, key)
+		}
+	}
+	h.pending[token] = &pendingAction{chatID: chatID, userID: userID, cmd: cmd, target: target, expiresAt: now.Add(confirmTTL)}
+	h.mu.Unlock()
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("✅ 确认", "confirm:"+token),
+		tgbotapi.NewInlineKeyboardButtonData("取消", "cancel:"+token),
+	))
+	text := fmt.Sprintf("⚠️ 确认对交易员 %s (%s) 执行 /%s（%s）？\n%d 秒内有效",
+		target.GetName(), target.GetID(), cmd.name, cmd.desc, int(confirmTTL.Seconds()))
+	return Reply{Text: text, Keyboard: &keyboard}
+}
+
+// resolveTrader 按序号、ID 或名称查找用户的交易员；未指定时仅在只有一个交易员时自动选择
+func (h *Handler) resolveTrader(userID string, args []string, cmd *command) (Trader, error) {
+	traders, err := h.controller.UserTraders(userID)
+	if err != nil {
+		return nil, fmt.Errorf("❌ 获取交易员失败: %v", err)
+	}
+	if len(traders) == 0 {
+		return nil, fmt.Errorf("当前账户没有交易员")
+	}
+
+	if len(args) == 0 {
+		if len(traders) == 1 {
+			return traders[0], nil
+		}
+		return nil, fmt.Errorf("请指定交易员: /%s %s\n%s", cmd.name, cmd.args, traderChoices(traders))
+	}
+
+	key := strings.Join(args, " ")
+	if index, err := strconv.Atoi(key); err == nil && index >= 1 && index <= len(traders) {
+		return traders[index-1], nil
+	}
+	for _, t := range traders {
+		if t.GetID() == key || strings.EqualFold(t.GetName(), key) {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("找不到交易员 %q\n%s", key, traderChoices(traders))
+}
+
+// targets 指定交易员时只返回该交易员，否则返回用户所有交易员
+func (h *Handler) targets(userID string, target Trader) ([]Trader, error) {
+	if target != nil {
+		return []Trader{target}, nil
+	}
+	return h.controller.UserTraders(userID)
+}
+
+// cmdStatus /status
+func (h *Handler) cmdStatus(userID string, target Trader, _ []string) string {
+	traders, err := h.targets(userID, target)
+	if err != nil {
+		return fmt.Sprintf("❌ 获取交易员失败: %v", err)
+	}
+	if len(traders) == 0 {
+		return "当前账户没有交易员"
+	}
+
+	var b strings.Builder
+	b.WriteString("📊 交易员状态\n")
+	for i, t := range traders {
+		status := t.GetStatus()
+		state := "⏹ 已停止"
+		if running, _ := status["is_running"].(bool); running {
+			state = "▶️ 运行中"
+		}
+		fmt.Fprintf(&b, "\n%d. %s (%s)\n   %s | 周期 #%v | 运行 %v 分钟 | 间隔 %v\n",
+			i+1, t.GetName(), t.GetID(), state, status["call_count"], status["runtime_minutes"], status["scan_interval"])
+		if stopUntil, _ := status["stop_until"].(string); stopUntil != "" {
+			if until, err := time.Parse(time.RFC3339, stopUntil); err == nil && time.Now().Before(until) {
+				fmt.Fprintf(&b, "   🚨 熔断暂停至 %s\n", until.Format("01-02 15:04"))
+			}
+		}
+	}
+	return b.String()
+}
+
+// cmdPositions /positions
+func (h *Handler) cmdPositions(userID string, target Trader, _ []string) string {
+	traders, err := h.targets(userID, target)
+	if err != nil {
+		return fmt.Sprintf("❌ 获取交易员失败: %v", err)
+	}
+
+	var b strings.Builder
+	for _, t := range traders {
+		fmt.Fprintf(&b, "📈 %s\n", t.GetName())
+		positions, err := t.GetPositions()
+		if err != nil {
+			fmt.Fprintf(&b, "   ❌ %v\n\n", err)
+			continue
+		}
+		if len(positions) == 0 {
+			b.WriteString("   无持仓\n\n")
+			continue
+		}
+		for _, pos := range positions {
+			fmt.Fprintf(&b, "   %v %s %v @ %.4f → %.4f | %dx | 盈亏 %+.2f (%+.2f%%)\n",
+				pos["symbol"], strings.ToUpper(fmt.Sprint(pos["side"])), pos["quantity"],
+				toFloat(pos["entry_price"]), toFloat(pos["mark_price"]), toInt(pos["leverage"]),
+				toFloat(pos["unrealized_pnl"]), toFloat(pos["unrealized_pnl_pct"]))
+		}
+		b.WriteString("\n")
+	}
+	if b.Len() == 0 {
+		return "当前账户没有交易员"
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// cmdPnL /pnl
+func (h *Handler) cmdPnL(userID string, target Trader, _ []string) string {
+	traders, err := h.targets(userID, target)
+	if err != nil {
+		return fmt.Sprintf("❌ 获取交易员失败: %v", err)
+	}
+
+	var b strings.Builder
+	for _, t := range traders {
+		fmt.Fprintf(&b, "💰 %s\n", t.GetName())
+		account, err := t.GetAccountInfo()
+		if err != nil {
+			fmt.Fprintf(&b, "   ❌ %v\n\n", err)
+			continue
+		}
+		fmt.Fprintf(&b, "   净值 %.2f USDT（初始 %.2f）\n", toFloat(account["total_equity"]), toFloat(account["initial_balance"]))
+		fmt.Fprintf(&b, "   总盈亏 %+.2f (%+.2f%%) | 未实现 %+.2f\n",
+			toFloat(account["total_pnl"]), toFloat(account["total_pnl_pct"]), toFloat(account["unrealized_profit"]))
+		fmt.Fprintf(&b, "   持仓 %d 个 | 保证金占用 %.1f%%\n\n", toInt(account["position_count"]), toFloat(account["margin_used_pct"]))
+	}
+	if b.Len() == 0 {
+		return "当前账户没有交易员"
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// cmdLastDecision /lastdecision
+func (h *Handler) cmdLastDecision(userID string, target Trader, _ []string) string {
+	traders, err := h.targets(userID, target)
+	if err != nil {
+		return fmt.Sprintf("❌ 获取交易员失败: %v", err)
+	}
+
+	var b strings.Builder
+	for _, t := range traders {
+		fmt.Fprintf(&b, "🧠 %s\n", t.GetName())
+		records, err := t.GetDecisionLogger().GetLatestRecords(1)
+		if err != nil {
+			fmt.Fprintf(&b, "   ❌ %v\n\n", err)
+			continue
+		}
+		if len(records) == 0 {
+			b.WriteString("   暂无决策记录\n\n")
+			continue
+		}
+		b.WriteString(formatDecision(records[len(records)-1]))
+		b.WriteString("\n")
+	}
+	if b.Len() == 0 {
+		return "当前账户没有交易员"
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// cmdPause /pause
+func (h *Handler) cmdPause(userID string, target Trader, _ []string) string {
+	if err := h.controller.PauseTrader(userID, target.GetID()); err != nil {
+		return fmt.Sprintf("❌ 暂停失败: %v", err)
+	}
+	return fmt.Sprintf("⏸ 交易员 %s 已暂停（持仓和交易所止损单保留）", target.GetName())
+}
+
+// cmdResume /resume
+func (h *Handler) cmdResume(userID string, target Trader, _ []string) string {
+	if err := h.controller.ResumeTrader(userID, target.GetID()); err != nil {
+		return fmt.Sprintf("❌ 恢复失败: %v", err)
+	}
+	return fmt.Sprintf("▶️ 交易员 %s 已恢复运行", target.GetName())
+}
+
+// cmdCloseAll /closeall（与 API 手动全部平仓相同的执行路径，记录为手动操作）
+func (h *Handler) cmdCloseAll(userID string, target Trader, _ []string) string {
+	record, err := h.controller.FlattenAll(userID, target.GetID(), "Telegram 手动平仓")
+	if err != nil {
+		return fmt.Sprintf("❌ 平仓失败: %v", err)
+	}
+	closed := 0
+	for _, action := range record.Decisions {
+		if action.Success {
+			closed++
+		}
+	}
+	if closed < len(record.Decisions) {
+		return fmt.Sprintf("⚠️ 交易员 %s 已平仓 %d/%d 个持仓，部分失败\n%s", target.GetName(), closed, len(record.Decisions), formatDecision(record))
+	}
+	return fmt.Sprintf("✓ 交易员 %s 已平仓 %d 个持仓", target.GetName(), closed)
+}
+
+// formatDecision 格式化决策记录摘要
+func formatDecision(record *logger.DecisionRecord) string {
+	var b strings.Builder
+	result := "✓ 成功"
+	if !record.Success {
+		result = "❌ 失败"
+	}
+	if record.Source == logger.DecisionSourceManual {
+		result += " | 人工操作"
+	}
+	fmt.Fprintf(&b, "   周期 #%d | %s | %s\n", record.CycleNumber, record.Timestamp.Format("01-02 15:04:05"), result)
+	if record.ErrorMessage != "" {
+		fmt.Fprintf(&b, "   错误: %s\n", record.ErrorMessage)
+	}
+	for _, action := range record.Decisions {
+		mark := "✓"
+		if !action.Success {
+			mark = "✗"
+		}
+		fmt.Fprintf(&b, "   %s %s %s", mark, action.Symbol, action.Action)
+		if action.Price > 0 {
+			fmt.Fprintf(&b, " @ %.4f", action.Price)
+		}
+		if action.Error != "" {
+			fmt.Fprintf(&b, "（%s）", action.Error)
+		}
+		b.WriteString("\n")
+	}
+	if note := strings.TrimSpace(record.Note); note != "" {
+		fmt.Fprintf(&b, "   备注: %s\n", note)
+	}
+	if cot := strings.TrimSpace(record.CoTTrace); cot != "" {
+		runes := []rune(cot)
+		if len(runes) > maxCoTPreviewRunes {
+			cot = string(runes[:maxCoTPreviewRunes]) + "…"
+		}
+		fmt.Fprintf(&b, "   思维链: %s\n", cot)
+	}
+	return b.String()
+}
+
+// parseCommand 解析 "/cmd@bot arg1 arg2"，非命令返回空名称
+func parseCommand(text string) (string, []string) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return "", nil
+	}
+	name := strings.TrimPrefix(fields[0], "/")
+	if at := strings.Index(name, "@"); at >= 0 {
+		name = name[:at]
+	}
+	return strings.ToLower(name), fields[1:]
+}
+
+// findCommand 查找命令定义
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// helpText 命令帮助
+func helpText() string {
+	var b strings.Builder
+	b.WriteString("🤖 nofx 交易机器人\n\n")
+	b.WriteString("/bind <绑定码> - 绑定 nofx 账户\n")
+	b.WriteString("/unbind - 解除绑定\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(&b, "/%s %s - %s", cmd.name, cmd.args, cmd.desc)
+		if cmd.confirm {
+			b.WriteString("（需确认）")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n交易员可使用 /status 中的序号、ID 或名称指定")
+	return b.String()
+}
+
+// traderChoices 可选交易员列表
+func traderChoices(traders []Trader) string {
+	names := make([]string, 0, len(traders))
+	for i, t := range traders {
+		names = append(names, fmt.Sprintf("%d. %s", i+1, t.GetName()))
+	}
+	return "可选: " + strings.Join(names, "  ")
+}
+
+// toFloat 读取数值字段
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	}
+	return 0
+}
+
+// toInt 读取整数字段
+func toInt(v interface{}) int {
+	return int(toFloat(v))
+}
+
+// newToken 生成确认令牌
+func newToken() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
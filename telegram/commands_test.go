@@ -0,0 +1,262 @@
+package telegram
+
+import (
+	"fmt"
+	"nofx/config"
+	"nofx/logger"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTrader struct {
+	id, name  string
+	running   bool
+	positions []map[string]interface{}
+	logger    logger.IDecisionLogger
+}
+
+func (f *fakeTrader) GetID() string   { return f.id }
+func (f *fakeTrader) GetName() string { return f.name }
+func (f *fakeTrader) GetStatus() map[string]interface{} {
+	return map[string]interface{}{"is_running": f.running, "call_count": 7, "runtime_minutes": 30, "scan_interval": "3m0s"}
+}
+func (f *fakeTrader) GetAccountInfo() (map[string]interface{}, error) {
+	return map[string]interface{}{"total_equity": 1100.0, "initial_balance": 1000.0, "total_pnl": 100.0,
+		"total_pnl_pct": 10.0, "position_count": len(f.positions)}, nil
+}
+func (f *fakeTrader) GetPositions() ([]map[string]interface{}, error) { return f.positions, nil }
+func (f *fakeTrader) GetDecisionLogger() logger.IDecisionLogger       { return f.logger }
+
+type fakeController struct {
+	traders map[string][]*fakeTrader
+	calls   []string
+}
+
+func (f *fakeController) UserTraders(userID string) ([]Trader, error) {
+	var result []Trader
+	for _, t := range f.traders[userID] {
+		result = append(result, t)
+	}
+	return result, nil
+}
+
+func (f *fakeController) PauseTrader(userID, traderID string) error {
+	f.calls = append(f.calls, "pause "+traderID)
+	return nil
+}
+
+func (f *fakeController) ResumeTrader(userID, traderID string) error {
+	f.calls = append(f.calls, "resume "+traderID)
+	return nil
+}
+
+func (f *fakeController) FlattenAll(userID, traderID, note string) (*logger.DecisionRecord, error) {
+	f.calls = append(f.calls, "closeall "+traderID)
+	return &logger.DecisionRecord{
+		Success: true,
+		Source:  logger.DecisionSourceManual,
+		Decisions: []logger.DecisionAction{
+			{Symbol: "BTCUSDT", Action: "close_long", Success: true},
+			{Symbol: "ETHUSDT", Action: "close_short", Success: true},
+		},
+	}, nil
+}
+
+func (f *fakeController) TraderOwner(traderID string) (string, error) {
+	for userID, traders := range f.traders {
+		for _, t := range traders {
+			if t.id == traderID {
+				return userID, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("交易员不存在")
+}
+
+func (f *fakeController) ApproveProposal(userID, traderID, proposalID string) error {
+	f.calls = append(f.calls, "approve "+proposalID)
+	return nil
+}
+
+func (f *fakeController) RejectProposal(userID, traderID, proposalID string) error {
+	f.calls = append(f.calls, "reject "+proposalID)
+	return nil
+}
+
+type fakeStore struct {
+	codes    map[string]*config.TelegramBindCode
+	bindings map[int64]*config.TelegramBinding
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{codes: map[string]*config.TelegramBindCode{}, bindings: map[int64]*config.TelegramBinding{}}
+}
+
+func (f *fakeStore) ConsumeTelegramBindCode(code string) (*config.TelegramBindCode, error) {
+	c := f.codes[code]
+	delete(f.codes, code)
+	return c, nil
+}
+
+func (f *fakeStore) SaveTelegramBinding(binding *config.TelegramBinding) error {
+	f.bindings[binding.ChatID] = binding
+	return nil
+}
+
+func (f *fakeStore) GetTelegramBinding(chatID int64) (*config.TelegramBinding, error) {
+	return f.bindings[chatID], nil
+}
+
+func (f *fakeStore) DeleteTelegramBinding(userID string, chatID int64) error {
+	delete(f.bindings, chatID)
+	return nil
+}
+
+func (f *fakeStore) GetTelegramBindings(userID string) ([]*config.TelegramBinding, error) {
+	var result []*config.TelegramBinding
+	for _, binding := range f.bindings {
+		if binding.UserID == userID {
+			result = append(result, binding)
+		}
+	}
+	return result, nil
+}
+
+func newTestHandler(t *testing.T) (*Handler, *fakeStore, *fakeController) {
+	decisionLogger := logger.NewDecisionLogger(t.TempDir())
+	require.NoError(t, decisionLogger.LogDecision(&logger.DecisionRecord{
+		Success: true, CoTTrace: "BTC 突破前高",
+		Decisions: []logger.DecisionAction{{Action: "open_long", Symbol: "BTCUSDT", Price: 60000, Success: true}},
+	}))
+
+	store := newFakeStore()
+	controller := &fakeController{traders: map[string][]*fakeTrader{
+		"user-1": {
+			{id: "t1", name: "Alpha", running: true, logger: decisionLogger, positions: []map[string]interface{}{
+				{"symbol": "BTCUSDT", "side": "long", "quantity": 0.01, "entry_price": 60000.0, "mark_price": 61000.0,
+					"leverage": 5, "unrealized_pnl": 10.0, "unrealized_pnl_pct": 8.3},
+			}},
+			{id: "t2", name: "Beta", logger: decisionLogger},
+		},
+	}}
+	return NewHandler(store, controller), store, controller
+}
+
+func TestHandler_RequiresBinding(t *testing.T) {
+	h, store, _ := newTestHandler(t)
+
+	assert.Contains(t, h.HandleMessage(1, "alice", "/status").Text, "未绑定")
+	assert.Empty(t, h.HandleMessage(1, "alice", "hello").Text, "非命令消息不回复")
+	assert.Contains(t, h.HandleMessage(1, "alice", "/help").Text, "/closeall")
+
+	assert.Contains(t, h.HandleMessage(1, "alice", "/bind WRONG").Text, "无效")
+
+	store.codes["ABCD1234"] = &config.TelegramBindCode{Code: "ABCD1234", UserID: "user-1", ExpiresAt: time.Now().Add(time.Minute)}
+	assert.Contains(t, h.HandleMessage(1, "alice", "/bind@nofx_bot ABCD1234").Text, "绑定成功")
+	require.NotNil(t, store.bindings[1])
+	assert.Equal(t, "user-1", store.bindings[1].UserID)
+	assert.Contains(t, h.HandleMessage(2, "mallory", "/bind ABCD1234").Text, "无效", "绑定码只能使用一次")
+
+	assert.Contains(t, h.HandleMessage(1, "alice", "/unbind").Text, "已解除绑定")
+	assert.Contains(t, h.HandleMessage(1, "alice", "/pnl").Text, "未绑定")
+}
+
+func TestHandler_ReadCommands(t *testing.T) {
+	h, store, _ := newTestHandler(t)
+	store.bindings[1] = &config.TelegramBinding{ChatID: 1, UserID: "user-1"}
+
+	status := h.HandleMessage(1, "", "/status").Text
+	assert.Contains(t, status, "1. Alpha (t1)")
+	assert.Contains(t, status, "2. Beta (t2)")
+	assert.Contains(t, status, "运行中")
+
+	positions := h.HandleMessage(1, "", "/positions alpha").Text
+	assert.Contains(t, positions, "BTCUSDT LONG 0.01")
+	assert.NotContains(t, positions, "Beta")
+
+	assert.Contains(t, h.HandleMessage(1, "", "/pnl 2").Text, "净值 1100.00")
+
+	decision := h.HandleMessage(1, "", "/lastdecision t1").Text
+	assert.Contains(t, decision, "open_long")
+	assert.Contains(t, decision, "BTC 突破前高")
+
+	assert.Contains(t, h.HandleMessage(1, "", "/positions gamma").Text, "找不到交易员")
+	assert.Contains(t, h.HandleMessage(1, "", "/foo").Text, "未知命令")
+}
+
+func TestHandler_ControlRequiresPermission(t *testing.T) {
+	h, store, controller := newTestHandler(t)
+	store.bindings[1] = &config.TelegramBinding{ChatID: 1, UserID: "user-1", CanControl: false}
+
+	reply := h.HandleMessage(1, "", "/resume t2")
+	assert.Contains(t, reply.Text, "只读权限")
+	assert.Empty(t, controller.calls)
+
+	store.bindings[1].CanControl = true
+	assert.Contains(t, h.HandleMessage(1, "", "/resume").Text, "请指定交易员", "多个交易员时必须指定")
+	assert.Contains(t, h.HandleMessage(1, "", "/resume Beta").Text, "已恢复")
+	assert.Equal(t, []string{"resume t2"}, controller.calls)
+}
+
+func TestHandler_DestructiveCommandsNeedConfirmation(t *testing.T) {
+	h, store, controller := newTestHandler(t)
+	store.bindings[1] = &config.TelegramBinding{ChatID: 1, UserID: "user-1", CanControl: true}
+
+	reply := h.HandleMessage(1, "", "/closeall Alpha")
+	require.NotNil(t, reply.Keyboard)
+	assert.Empty(t, controller.calls, "确认前不执行")
+
+	confirm := *reply.Keyboard.InlineKeyboard[0][0].CallbackData
+
+	// 其他聊天无法代为确认
+	assert.Contains(t, h.HandleCallback(2, confirm).Text, "不存在")
+	assert.Empty(t, controller.calls)
+
+	assert.Contains(t, h.HandleCallback(1, confirm).Text, "已平仓 2 个")
+	assert.Equal(t, []string{"closeall t1"}, controller.calls)
+	assert.Contains(t, h.HandleCallback(1, confirm).Text, "已处理", "同一确认只能执行一次")
+
+	// 取消
+	reply = h.HandleMessage(1, "", "/pause 1")
+	cancel := *reply.Keyboard.InlineKeyboard[0][1].CallbackData
+	assert.Contains(t, h.HandleCallback(1, cancel).Text, "已取消")
+	assert.Len(t, controller.calls, 1)
+
+	// 确认前权限被收回
+	reply = h.HandleMessage(1, "", "/pause 1")
+	confirm = *reply.Keyboard.InlineKeyboard[0][0].CallbackData
+	store.bindings[1].CanControl = false
+	assert.Contains(t, h.HandleCallback(1, confirm).Text, "绑定已变更")
+	assert.Len(t, controller.calls, 1)
+}
+
+func TestHandler_ConfirmationExpires(t *testing.T) {
+	h, store, controller := newTestHandler(t)
+	store.bindings[1] = &config.TelegramBinding{ChatID: 1, UserID: "user-1", CanControl: true}
+
+	reply := h.HandleMessage(1, "", "/pause Alpha")
+	data := *reply.Keyboard.InlineKeyboard[0][0].CallbackData
+	_, token, _ := strings.Cut(data, ":")
+	h.pending[token].expiresAt = time.Now().Add(-time.Second)
+
+	assert.Contains(t, h.HandleCallback(1, data).Text, "超时")
+	assert.Empty(t, controller.calls)
+}
+
+func TestParseCommand(t *testing.T) {
+	cases := map[string]string{
+		"/status":              "status []",
+		"/Pause@nofx_bot  t1 ": "pause [t1]",
+		"/closeall My Trader":  "closeall [My Trader]",
+		"status":               " []",
+		"":                     " []",
+	}
+	for input, want := range cases {
+		name, args := parseCommand(input)
+		assert.Equal(t, want, fmt.Sprintf("%s %v", name, args), input)
+	}
+}
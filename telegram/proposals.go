@@ -0,0 +1,157 @@
+package telegram
+
+import (
+	"fmt"
+	"log"
+	"nofx/events"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Notice 主动推送给某个聊天的消息
+type Notice struct {
+	ChatID int64
+	Reply  Reply
+}
+
+// proposalAction 推送到聊天、等待批准/拒绝的提案
+type proposalAction struct {
+	chatID     int64
+	userID     string
+	traderID   string
+	proposalID string
+	summary    string
+	expiresAt  time.Time
+}
+
+// ProposalNotices 为新提案生成推送消息
+//
+// 发送给交易员所属用户绑定的所有聊天；有控制权限的聊天附带批准/拒绝按钮
+func (h *Handler) ProposalNotices(event events.Event) []Notice {
+	if event.Type != events.ProposalCreated {
+		return nil
+	}
+	userID, err := h.controller.TraderOwner(event.TraderID)
+	if err != nil {
+		log.Printf("⚠️ 查询交易员 %s 所属用户失败: %v", event.TraderID, err)
+		return nil
+	}
+	bindings, err := h.store.GetTelegramBindings(userID)
+	if err != nil {
+		log.Printf("⚠️ 读取用户 %s 的 Telegram 绑定失败: %v", userID, err)
+		return nil
+	}
+	if len(bindings) == 0 {
+		return nil
+	}
+
+	traderName := event.TraderID
+	if traders, err := h.controller.UserTraders(userID); err == nil {
+		for _, t := range traders {
+			if t.GetID() == event.TraderID {
+				traderName = t.GetName()
+			}
+		}
+	}
+	proposalID, _ := event.Data["proposal_id"].(string)
+	expiresAt, _ := event.Data["expires_at"].(time.Time)
+	summary := fmt.Sprintf("%v %v", event.Data["symbol"], event.Data["action"])
+	text := formatProposal(traderName, event.Data, expiresAt)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now()
+	for key, action := range h.proposals {
+		if now.After(action.expiresAt) {
+			delete(h.proposals, key)
+		}
+	}
+
+	notices := make([]Notice, 0, len(bindings))
+	for _, binding := range bindings {
+		notice := Notice{ChatID: binding.ChatID, Reply: Reply{Text: text}}
+		if binding.CanControl {
+			token := newToken()
+			h.proposals[token] = &proposalAction{
+				chatID:     binding.ChatID,
+				userID:     userID,
+				traderID:   event.TraderID,
+				proposalID: proposalID,
+				summary:    summary,
+				expiresAt:  expiresAt,
+			}
+			keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("✅ 批准执行", "approve:"+token),
+				tgbotapi.NewInlineKeyboardButtonData("🚫 拒绝", "reject:"+token),
+			))
+			notice.Reply.Keyboard = &keyboard
+		}
+		notices = append(notices, notice)
+	}
+	return notices
+}
+
+// handleProposalCallback 处理提案的批准/拒绝按钮
+func (h *Handler) handleProposalCallback(chatID int64, verb, token string) Reply {
+	h.mu.Lock()
+	action := h.proposals[token]
+	if action != nil && action.chatID == chatID {
+		delete(h.proposals, token)
+	}
+	h.mu.Unlock()
+
+	if action == nil || action.chatID != chatID {
+		return Reply{Text: "⚠️ 提案不存在或已处理"}
+	}
+	if time.Now().After(action.expiresAt) {
+		return Reply{Text: fmt.Sprintf("⌛ 提案 %s 已过期，未执行", action.summary)}
+	}
+
+	// 推送后绑定可能已被解除或降权，执行前重新校验
+	binding, err := h.store.GetTelegramBinding(chatID)
+	if err != nil || binding == nil || binding.UserID != action.userID || !binding.CanControl {
+		return Reply{Text: "⛔ 绑定已变更，操作未执行"}
+	}
+
+	log.Printf("📱 Telegram %s 提案 %s (trader %s, chat %d)", verb, action.proposalID, action.traderID, chatID)
+	if verb == "reject" {
+		if err := h.controller.RejectProposal(action.userID, action.traderID, action.proposalID); err != nil {
+			return Reply{Text: fmt.Sprintf("❌ 拒绝提案 %s 失败: %v", action.summary, err)}
+		}
+		return Reply{Text: fmt.Sprintf("🚫 已拒绝提案 %s", action.summary)}
+	}
+	if err := h.controller.ApproveProposal(action.userID, action.traderID, action.proposalID); err != nil {
+		return Reply{Text: fmt.Sprintf("❌ 提案 %s 未成功执行: %v", action.summary, err)}
+	}
+	return Reply{Text: fmt.Sprintf("✓ 提案 %s 已批准并执行", action.summary)}
+}
+
+// formatProposal 格式化提案推送内容
+func formatProposal(traderName string, data map[string]any, expiresAt time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "🗳 交易员 %s 有新的AI决策等待审批\n\n", traderName)
+	fmt.Fprintf(&b, "%v %v | 名义价值 %.2f USDT\n", data["symbol"], data["action"], toFloat(data["notional"]))
+	switch data["action"] {
+	case "open_long", "open_short":
+		fmt.Fprintf(&b, "杠杆 %dx | 止损 %.4f | 止盈 %.4f\n", toInt(data["leverage"]), toFloat(data["stop_loss"]), toFloat(data["take_profit"]))
+	case "partial_close":
+		fmt.Fprintf(&b, "平仓比例 %.1f%%\n", toFloat(data["close_percentage"]))
+	case "update_stop_loss":
+		fmt.Fprintf(&b, "新止损 %.4f\n", toFloat(data["new_stop_loss"]))
+	case "update_take_profit":
+		fmt.Fprintf(&b, "新止盈 %.4f\n", toFloat(data["new_take_profit"]))
+	}
+	if reasoning, _ := data["reasoning"].(string); reasoning != "" {
+		runes := []rune(reasoning)
+		if len(runes) > maxCoTPreviewRunes {
+			reasoning = string(runes[:maxCoTPreviewRunes]) + "…"
+		}
+		fmt.Fprintf(&b, "理由: %s\n", reasoning)
+	}
+	if !expiresAt.IsZero() {
+		fmt.Fprintf(&b, "\n有效期至 %s", expiresAt.Format("15:04:05"))
+	}
+	return b.String()
+}
@@ -0,0 +1,67 @@
+package telegram
+
+import (
+	"nofx/config"
+	"nofx/events"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func proposalEvent(proposalID string, expiresAt time.Time) events.Event {
+	return events.Event{Type: events.ProposalCreated, TraderID: "t1", Data: map[string]any{
+		"proposal_id": proposalID, "symbol": "BTCUSDT", "action": "open_long", "notional": 500.0,
+		"leverage": 5, "stop_loss": 58000.0, "take_profit": 66000.0, "reasoning": "突破确认", "expires_at": expiresAt,
+	}}
+}
+
+func TestProposalNotices(t *testing.T) {
+	h, store, controller := newTestHandler(t)
+	store.bindings[1] = &config.TelegramBinding{ChatID: 1, UserID: "user-1", CanControl: true}
+	store.bindings[2] = &config.TelegramBinding{ChatID: 2, UserID: "user-1"}
+	store.bindings[3] = &config.TelegramBinding{ChatID: 3, UserID: "user-2", CanControl: true}
+
+	assert.Empty(t, h.ProposalNotices(events.Event{Type: events.CycleStarted, TraderID: "t1"}))
+
+	notices := h.ProposalNotices(proposalEvent("p1", time.Now().Add(time.Minute)))
+	require.Len(t, notices, 2, "只推送给交易员所属用户的聊天")
+
+	byChat := map[int64]Reply{}
+	for _, notice := range notices {
+		byChat[notice.ChatID] = notice.Reply
+	}
+	assert.Contains(t, byChat[1].Text, "Alpha")
+	assert.Contains(t, byChat[1].Text, "BTCUSDT open_long | 名义价值 500.00 USDT")
+	assert.Contains(t, byChat[1].Text, "突破确认")
+	require.NotNil(t, byChat[1].Keyboard)
+	assert.Nil(t, byChat[2].Keyboard, "只读聊天不显示审批按钮")
+
+	approve := *byChat[1].Keyboard.InlineKeyboard[0][0].CallbackData
+	assert.Contains(t, h.HandleCallback(2, approve).Text, "不存在", "其他聊天无法代为审批")
+	assert.Contains(t, h.HandleCallback(1, approve).Text, "已批准并执行")
+	assert.Contains(t, h.HandleCallback(1, approve).Text, "已处理")
+	assert.Equal(t, []string{"approve p1"}, controller.calls)
+
+	notices = h.ProposalNotices(proposalEvent("p2", time.Now().Add(time.Minute)))
+	for _, notice := range notices {
+		if notice.ChatID == 1 {
+			reject := *notice.Reply.Keyboard.InlineKeyboard[0][1].CallbackData
+			assert.Contains(t, h.HandleCallback(1, reject).Text, "已拒绝")
+		}
+	}
+	assert.Equal(t, []string{"approve p1", "reject p2"}, controller.calls)
+}
+
+func TestProposalNotices_Expired(t *testing.T) {
+	h, store, controller := newTestHandler(t)
+	store.bindings[1] = &config.TelegramBinding{ChatID: 1, UserID: "user-1", CanControl: true}
+
+	notices := h.ProposalNotices(proposalEvent("p1", time.Now().Add(-time.Second)))
+	require.Len(t, notices, 1)
+	approve := *notices[0].Reply.Keyboard.InlineKeyboard[0][0].CallbackData
+
+	assert.Contains(t, h.HandleCallback(1, approve).Text, "已过期")
+	assert.Empty(t, controller.calls)
+}
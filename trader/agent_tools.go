@@ -0,0 +1,68 @@
+package trader
+
+import (
+	"fmt"
+	"nofx/decision"
+	"nofx/market"
+	"strings"
+)
+
+// agentPositionHistoryLookback 查询历史仓位时分析的决策周期数
+const agentPositionHistoryLookback = 500
+
+// agentTools Agent 模式可用的数据查询工具（行情工具 + 本 Trader 的历史仓位）
+// 非 Agent 模式返回 nil
+func (at *AutoTrader) agentTools() []decision.AgentTool {
+	if at.config.DecisionMode != decision.DecisionModeAgent {
+		return nil
+	}
+	tools := decision.MarketAgentTools(at.config.MarketData)
+	tools = append(tools, decision.NewAgentTool(
+		"get_position_history",
+		"获取本账户在该币种上最近已平仓的交易（盈亏、持仓时长、是否止损）及该币种汇总表现",
+		map[string]any{
+			"symbol": map[string]any{"type": "string", "description": "交易对，如 BTCUSDT"},
+		},
+		[]string{"symbol"},
+		at.positionHistoryTool,
+	))
+	return tools
+}
+
+// positionHistoryTool 查询指定币种的历史平仓记录
+func (at *AutoTrader) positionHistoryTool(args map[string]any) (string, error) {
+	symbol := market.Normalize(decision.ArgString(args, "symbol"))
+
+	performance, err := at.AnalyzePerformance(agentPositionHistoryLookback)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	count := 0
+	for _, trade := range performance.RecentTrades { // 最新的在前
+		if trade.Symbol != symbol {
+			continue
+		}
+		if count == 0 {
+			sb.WriteString(fmt.Sprintf("%s 历史交易（最近在前）:\n", symbol))
+		}
+		count++
+		stopTag := ""
+		if trade.WasStopLoss {
+			stopTag = " [止损]"
+		}
+		sb.WriteString(fmt.Sprintf("%s %s %dx | 开%.4f → 平%.4f | 盈亏%+.2f USDT (%+.2f%%) | 持仓%s%s\n",
+			trade.CloseTime.Format("01-02 15:04"), strings.ToUpper(trade.Side), trade.Leverage,
+			trade.OpenPrice, trade.ClosePrice, trade.PnL, trade.PnLPct, trade.Duration, stopTag))
+	}
+	if count == 0 {
+		return fmt.Sprintf("%s 暂无历史交易记录", symbol), nil
+	}
+
+	if stats, ok := performance.SymbolStats[symbol]; ok && stats != nil {
+		sb.WriteString(fmt.Sprintf("汇总: %d笔 | 胜率%.1f%% | 总盈亏%+.2f USDT\n",
+			stats.TotalTrades, stats.WinRate, stats.TotalPnL))
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,392 @@
+package trader
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/events"
+	"nofx/logger"
+	"sort"
+	"time"
+)
+
+// 执行模式
+const (
+	ExecutionModeAuto     = "auto"     // AI决策直接执行
+	ExecutionModeApproval = "approval" // AI决策作为提案，人工审批后执行
+)
+
+// 提案状态
+const (
+	ProposalPending  = "pending"  // 等待审批
+	ProposalExecuted = "executed" // 已批准并执行成功
+	ProposalFailed   = "failed"   // 已批准但执行失败
+	ProposalRejected = "rejected" // 已拒绝
+	ProposalExpired  = "expired"  // 审批超时或被新周期取代
+)
+
+// proposalRetention 已处理提案在内存中的保留时长（用于查询）
+const proposalRetention = 24 * time.Hour
+
+// 提案审批错误
+var (
+	ErrProposalNotFound = errors.New("提案不存在")
+	ErrProposalResolved = errors.New("提案已处理")
+)
+
+// ValidExecutionMode 检查执行模式是否有效（空值视为自动执行）
+func ValidExecutionMode(mode string) bool {
+	return mode == "" || mode == ExecutionModeAuto || mode == ExecutionModeApproval
+}
+
+// Proposal 审批模式下等待人工审批的AI决策
+//
+// 提案只保存在内存中：有效期不超过一个扫描周期，重启后行情已变化，不再恢复
+type Proposal struct {
+	ID         string                 `json:"id"`
+	TraderID   string                 `json:"trader_id"`
+	Cycle      int                    `json:"cycle"` // 生成提案的AI周期
+	Decision   decision.Decision      `json:"decision"`
+	Notional   float64                `json:"notional"` // 名义价值（USDT）
+	Status     string                 `json:"status"`
+	CreatedAt  time.Time              `json:"created_at"`
+	ExpiresAt  time.Time              `json:"expires_at"`
+	ResolvedAt time.Time              `json:"resolved_at,omitempty"`
+	Reason     string                 `json:"reason,omitempty"` // 拒绝原因/过期原因
+	Result     *logger.DecisionAction `json:"result,omitempty"` // 批准后的执行结果
+}
+
+// executionMode 当前执行模式
+func (at *AutoTrader) executionMode() string {
+	if at.config.ExecutionMode == "" {
+		return ExecutionModeAuto
+	}
+	return at.config.ExecutionMode
+}
+
+// proposalNotional 决策涉及的名义价值：开仓为仓位大小，其他操作为对应持仓的市值
+func proposalNotional(d *decision.Decision, positions []decision.PositionInfo) float64 {
+	if d.Action == "open_long" || d.Action == "open_short" {
+		return d.PositionSizeUSD
+	}
+
+	side := ""
+	switch d.Action {
+	case "close_long":
+		side = "long"
+	case "close_short":
+		side = "short"
+	}
+	for _, pos := range positions {
+		if pos.Symbol != d.Symbol || (side != "" && pos.Side != side) {
+			continue
+		}
+		value := pos.Quantity * pos.MarkPrice
+		if d.Action == "partial_close" {
+			value *= d.ClosePercentage / 100
+		}
+		return value
+	}
+	return 0
+}
+
+// proposeIfRequired 审批模式下将需要审批的决策登记为提案，返回 nil 表示直接执行
+func (at *AutoTrader) proposeIfRequired(d *decision.Decision, positions []decision.PositionInfo) *Proposal {
+	if at.config.ExecutionMode != ExecutionModeApproval || d.Action == "hold" || d.Action == "wait" {
+		return nil
+	}
+
+	notional := proposalNotional(d, positions)
+	if threshold := at.config.AutoApproveNotional; threshold > 0 && notional < threshold {
+		log.Printf("  ✓ %s %s 名义价值 %.2f USDT 低于自动批准阈值 %.2f，直接执行", d.Symbol, d.Action, notional, threshold)
+		return nil
+	}
+
+	ttl := at.config.ScanInterval
+	if ttl <= 0 {
+		ttl = 3 * time.Minute
+	}
+	now := time.Now()
+	proposal := &Proposal{
+		ID:        newProposalID(),
+		TraderID:  at.id,
+		Cycle:     at.callCount,
+		Decision:  *d,
+		Notional:  notional,
+		Status:    ProposalPending,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	at.proposalMu.Lock()
+	if at.proposals == nil {
+		at.proposals = make(map[string]*Proposal)
+	}
+	at.proposals[proposal.ID] = proposal
+	at.proposalMu.Unlock()
+
+	log.Printf("  ⏳ %s %s 等待人工审批（提案 %s，名义价值 %.2f USDT）", d.Symbol, d.Action, proposal.ID, notional)
+	at.publishEvent(events.ProposalCreated, map[string]any{
+		"proposal_id":       proposal.ID,
+		"symbol":            d.Symbol,
+		"action":            d.Action,
+		"notional":          notional,
+		"leverage":          d.Leverage,
+		"position_size_usd": d.PositionSizeUSD,
+		"stop_loss":         d.StopLoss,
+		"take_profit":       d.TakeProfit,
+		"new_stop_loss":     d.NewStopLoss,
+		"new_take_profit":   d.NewTakeProfit,
+		"close_percentage":  d.ClosePercentage,
+		"reasoning":         d.Reasoning,
+		"expires_at":        proposal.ExpiresAt,
+	})
+	return proposal
+}
+
+// expireProposals 将过期的待审批提案标记为过期并写入决策日志
+//
+// supersede 为 true 时（新的AI周期开始）所有待审批提案都视为过期，避免与新决策重复执行
+func (at *AutoTrader) expireProposals(supersede bool) {
+	now := time.Now()
+	var expired []*Proposal
+
+	at.proposalMu.Lock()
+	for id, p := range at.proposals {
+		if p.Status != ProposalPending {
+			if now.Sub(p.ResolvedAt) > proposalRetention {
+				delete(at.proposals, id)
+			}
+			continue
+		}
+		if !supersede && now.Before(p.ExpiresAt) {
+			continue
+		}
+		p.Status = ProposalExpired
+		p.ResolvedAt = now
+		p.Reason = "审批超时"
+		if supersede && now.Before(p.ExpiresAt) {
+			p.Reason = "新的决策周期已开始"
+		}
+		expired = append(expired, p)
+	}
+	at.proposalMu.Unlock()
+
+	if len(expired) == 0 {
+		return
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].CreatedAt.Before(expired[j].CreatedAt) })
+
+	record := at.newProposalRecord("提案过期")
+	for _, p := range expired {
+		log.Printf("⌛ [%s] 提案 %s 已过期 (%s %s): %s", at.name, p.ID, p.Decision.Symbol, p.Decision.Action, p.Reason)
+		at.appendProposalResult(record, p, at.proposalAction(p, "提案已过期: "+p.Reason))
+	}
+	at.logProposalRecord(record)
+}
+
+// ApproveProposal 批准提案并立即执行（与AI决策相同的执行路径，同样经过组合风控）
+func (at *AutoTrader) ApproveProposal(id string) (*Proposal, error) {
+	at.expireProposals(false)
+
+	at.proposalMu.Lock()
+	p := at.proposals[id]
+	if p == nil {
+		at.proposalMu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrProposalNotFound, id)
+	}
+	if p.Status != ProposalPending {
+		status := p.Status
+		at.proposalMu.Unlock()
+		return nil, fmt.Errorf("%w（%s）", ErrProposalResolved, status)
+	}
+	// 先移出待审批状态，防止重复批准
+	p.Status = ProposalExecuted
+	p.ResolvedAt = time.Now()
+	at.proposalMu.Unlock()
+
+	d := p.Decision
+	actionRecord := logger.DecisionAction{
+		Action:     d.Action,
+		Symbol:     d.Symbol,
+		Leverage:   d.Leverage,
+		Timestamp:  time.Now(),
+		Source:     logger.DecisionSourceApproval,
+		ProposalID: p.ID,
+	}
+
+	var execErr error
+	isOpen := d.Action == "open_long" || d.Action == "open_short"
+	if stopUntil := at.pausedUntil(); isOpen && time.Now().Before(stopUntil) {
+		execErr = fmt.Errorf("熔断暂停中（至 %s），禁止开仓", stopUntil.Format("15:04:05"))
+	} else {
+		at.execMu.Lock()
+		// 审批发生在周期之间，重新加载风控账户快照
+		at.riskAccount = nil
+		execErr = at.executeDecisionWithRecord(&d, &actionRecord)
+		at.execMu.Unlock()
+	}
+
+	at.proposalMu.Lock()
+	if execErr != nil {
+		actionRecord.Error = execErr.Error()
+		p.Status = ProposalFailed
+	} else {
+		actionRecord.Success = true
+	}
+	p.Result = &actionRecord
+	result := *p
+	at.proposalMu.Unlock()
+
+	record := at.newProposalRecord("人工审批通过")
+	at.appendProposalResult(record, p, actionRecord)
+	at.logProposalRecord(record)
+
+	if execErr != nil {
+		log.Printf("❌ [%s] 提案 %s 执行失败 (%s %s): %v", at.name, p.ID, d.Symbol, d.Action, execErr)
+		at.publishEvent(events.DecisionFailed, approvalEventData(&actionRecord))
+	} else {
+		log.Printf("✓ [%s] 提案 %s 已批准并执行 (%s %s)", at.name, p.ID, d.Symbol, d.Action)
+		at.publishEvent(events.DecisionExecuted, approvalEventData(&actionRecord))
+	}
+	return &result, nil
+}
+
+// RejectProposal 拒绝提案
+func (at *AutoTrader) RejectProposal(id, reason string) (*Proposal, error) {
+	at.expireProposals(false)
+
+	at.proposalMu.Lock()
+	p := at.proposals[id]
+	if p == nil {
+		at.proposalMu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrProposalNotFound, id)
+	}
+	if p.Status != ProposalPending {
+		status := p.Status
+		at.proposalMu.Unlock()
+		return nil, fmt.Errorf("%w（%s）", ErrProposalResolved, status)
+	}
+	p.Status = ProposalRejected
+	p.ResolvedAt = time.Now()
+	p.Reason = reason
+	result := *p
+	at.proposalMu.Unlock()
+
+	message := "提案被人工拒绝"
+	if reason != "" {
+		message += ": " + reason
+	}
+	log.Printf("🚫 [%s] 提案 %s 已拒绝 (%s %s)", at.name, p.ID, p.Decision.Symbol, p.Decision.Action)
+
+	record := at.newProposalRecord(message)
+	at.appendProposalResult(record, p, at.proposalAction(p, message))
+	at.logProposalRecord(record)
+	return &result, nil
+}
+
+// GetProposals 获取提案列表（待审批在前，其余按创建时间倒序）
+func (at *AutoTrader) GetProposals() []Proposal {
+	at.expireProposals(false)
+
+	at.proposalMu.Lock()
+	result := make([]Proposal, 0, len(at.proposals))
+	for _, p := range at.proposals {
+		result = append(result, *p)
+	}
+	at.proposalMu.Unlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		if (result[i].Status == ProposalPending) != (result[j].Status == ProposalPending) {
+			return result[i].Status == ProposalPending
+		}
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	return result
+}
+
+// pendingProposalCount 待审批提案数量
+func (at *AutoTrader) pendingProposalCount() int {
+	at.proposalMu.Lock()
+	defer at.proposalMu.Unlock()
+	count := 0
+	for _, p := range at.proposals {
+		if p.Status == ProposalPending {
+			count++
+		}
+	}
+	return count
+}
+
+// proposalAction 未执行提案（拒绝/过期）的决策动作记录
+func (at *AutoTrader) proposalAction(p *Proposal, message string) logger.DecisionAction {
+	return logger.DecisionAction{
+		Action:     p.Decision.Action,
+		Symbol:     p.Decision.Symbol,
+		Leverage:   p.Decision.Leverage,
+		Timestamp:  p.ResolvedAt,
+		Error:      message,
+		Source:     logger.DecisionSourceApproval,
+		ProposalID: p.ID,
+	}
+}
+
+// newProposalRecord 创建提案处理结果的决策记录
+func (at *AutoTrader) newProposalRecord(note string) *logger.DecisionRecord {
+	return &logger.DecisionRecord{
+		ExecutionLog: []string{},
+		Success:      true,
+		Source:       logger.DecisionSourceApproval,
+		Note:         note,
+	}
+}
+
+// appendProposalResult 将提案结果写入决策记录并发布事件
+func (at *AutoTrader) appendProposalResult(record *logger.DecisionRecord, p *Proposal, action logger.DecisionAction) {
+	if action.Success {
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ 提案 %s: %s %s 已批准并执行", p.ID, action.Symbol, action.Action))
+	} else {
+		record.Success = false
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ 提案 %s: %s %s %s", p.ID, action.Symbol, action.Action, action.Error))
+	}
+	record.Decisions = append(record.Decisions, action)
+
+	at.publishEvent(events.ProposalResolved, map[string]any{
+		"proposal_id": p.ID,
+		"symbol":      action.Symbol,
+		"action":      action.Action,
+		"status":      p.Status,
+		"error":       action.Error,
+	})
+}
+
+// logProposalRecord 写入提案处理结果（附带当前账户状态）
+func (at *AutoTrader) logProposalRecord(record *logger.DecisionRecord) {
+	if !record.Success {
+		record.ErrorMessage = "提案未执行或执行失败"
+	}
+	at.fillAccountSnapshot(record)
+	if err := at.decisionLogger.LogDecision(record); err != nil {
+		log.Printf("⚠ 保存提案处理记录失败: %v", err)
+	}
+}
+
+// approvalEventData 审批后执行的事件数据（带 source 和提案ID）
+func approvalEventData(action *logger.DecisionAction) map[string]any {
+	data := actionEventData(action)
+	data["source"] = logger.DecisionSourceApproval
+	data["proposal_id"] = action.ProposalID
+	return data
+}
+
+// newProposalID 生成提案ID
+func newProposalID() string {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("p%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
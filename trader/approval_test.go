@@ -0,0 +1,120 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+	"strings"
+	"time"
+)
+
+// ============================================================
+// 人工审批模式测试
+// ============================================================
+
+func (s *AutoTraderTestSuite) TestProposalNotional() {
+	positions := []decision.PositionInfo{
+		{Symbol: "BTCUSDT", Side: "long", Quantity: 0.1, MarkPrice: 50000},
+		{Symbol: "ETHUSDT", Side: "short", Quantity: 2, MarkPrice: 3000},
+	}
+	s.Equal(800.0, proposalNotional(&decision.Decision{Action: "open_short", Symbol: "SOLUSDT", PositionSizeUSD: 800}, positions))
+	s.Equal(5000.0, proposalNotional(&decision.Decision{Action: "close_long", Symbol: "BTCUSDT"}, positions))
+	s.Equal(0.0, proposalNotional(&decision.Decision{Action: "close_short", Symbol: "BTCUSDT"}, positions))
+	s.Equal(1500.0, proposalNotional(&decision.Decision{Action: "partial_close", Symbol: "ETHUSDT", ClosePercentage: 25}, positions))
+	s.Equal(6000.0, proposalNotional(&decision.Decision{Action: "update_stop_loss", Symbol: "ETHUSDT", NewStopLoss: 3100}, positions))
+}
+
+func (s *AutoTraderTestSuite) TestProposeIfRequired() {
+	open := &decision.Decision{Action: "open_long", Symbol: "BTCUSDT", PositionSizeUSD: 500, Leverage: 5}
+
+	s.Nil(s.autoTrader.proposeIfRequired(open, nil), "自动模式直接执行")
+
+	s.autoTrader.config.ExecutionMode = ExecutionModeApproval
+	s.Nil(s.autoTrader.proposeIfRequired(&decision.Decision{Action: "hold", Symbol: "BTCUSDT"}, nil))
+
+	proposal := s.autoTrader.proposeIfRequired(open, nil)
+	s.Require().NotNil(proposal)
+	s.Equal(ProposalPending, proposal.Status)
+	s.Equal(500.0, proposal.Notional)
+	s.WithinDuration(time.Now().Add(s.config.ScanInterval), proposal.ExpiresAt, time.Second)
+	s.Equal(1, s.autoTrader.pendingProposalCount())
+
+	s.autoTrader.config.AutoApproveNotional = 1000
+	s.Nil(s.autoTrader.proposeIfRequired(open, nil), "低于自动批准阈值直接执行")
+	s.autoTrader.config.AutoApproveNotional = 500
+	s.NotNil(s.autoTrader.proposeIfRequired(open, nil), "等于阈值仍需审批")
+}
+
+func (s *AutoTraderTestSuite) TestApproveProposal() {
+	s.autoTrader.decisionLogger = logger.NewDecisionLogger(s.T().TempDir())
+	s.autoTrader.config.ExecutionMode = ExecutionModeApproval
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 51000.0}, nil
+	})
+	s.mockTrader.positions = []map[string]interface{}{
+		{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.1, "entryPrice": 50000.0, "markPrice": 51000.0, "unRealizedProfit": 100.0},
+	}
+	defer func() { s.mockTrader.positions = []map[string]interface{}{} }()
+
+	proposal := s.autoTrader.proposeIfRequired(&decision.Decision{Action: "close_long", Symbol: "BTCUSDT"}, nil)
+	s.Require().NotNil(proposal)
+
+	_, err := s.autoTrader.ApproveProposal("missing")
+	s.ErrorIs(err, ErrProposalNotFound)
+
+	result, err := s.autoTrader.ApproveProposal(proposal.ID)
+	s.Require().NoError(err)
+	s.Equal(ProposalExecuted, result.Status)
+	s.Require().NotNil(result.Result)
+	s.True(result.Result.Success)
+	s.Equal(51000.0, result.Result.Price)
+
+	_, err = s.autoTrader.ApproveProposal(proposal.ID)
+	s.ErrorIs(err, ErrProposalResolved, "同一提案只能执行一次")
+
+	records, err := s.autoTrader.decisionLogger.GetLatestRecords(10)
+	s.Require().NoError(err)
+	s.Require().Len(records, 1)
+	s.Equal(logger.DecisionSourceApproval, records[0].Source)
+	s.Equal(proposal.ID, records[0].Decisions[0].ProposalID)
+	s.True(records[0].Success)
+}
+
+func (s *AutoTraderTestSuite) TestRejectAndExpireProposals() {
+	s.autoTrader.decisionLogger = logger.NewDecisionLogger(s.T().TempDir())
+	s.autoTrader.config.ExecutionMode = ExecutionModeApproval
+
+	open := &decision.Decision{Action: "open_long", Symbol: "BTCUSDT", PositionSizeUSD: 500, Leverage: 5}
+	rejected := s.autoTrader.proposeIfRequired(open, nil)
+	superseded := s.autoTrader.proposeIfRequired(open, nil)
+	timedOut := s.autoTrader.proposeIfRequired(open, nil)
+	s.autoTrader.proposals[timedOut.ID].ExpiresAt = time.Now().Add(-time.Second)
+
+	result, err := s.autoTrader.RejectProposal(rejected.ID, "趋势不明")
+	s.Require().NoError(err)
+	s.Equal(ProposalRejected, result.Status)
+
+	// 查询时清理超时提案
+	proposals := s.autoTrader.GetProposals()
+	s.Require().Len(proposals, 3)
+	s.Equal(superseded.ID, proposals[0].ID, "待审批提案排在前面")
+	s.Equal(ProposalExpired, s.autoTrader.proposals[timedOut.ID].Status)
+
+	// 新周期开始时取代剩余提案
+	s.autoTrader.expireProposals(true)
+	s.Equal(ProposalExpired, s.autoTrader.proposals[superseded.ID].Status)
+	s.Equal("新的决策周期已开始", s.autoTrader.proposals[superseded.ID].Reason)
+	s.Equal(0, s.autoTrader.pendingProposalCount())
+
+	// 拒绝和过期都写入决策日志，并在下一周期告知AI
+	actions := s.autoTrader.recentManualActions()
+	s.Require().Len(actions, 3)
+	var errs []string
+	for _, action := range actions {
+		s.False(action.Success)
+		errs = append(errs, action.Error)
+	}
+	s.Contains(strings.Join(errs, "\n"), "趋势不明")
+	s.Contains(strings.Join(errs, "\n"), "审批超时")
+	s.Contains(strings.Join(errs, "\n"), "新的决策周期已开始")
+}
@@ -2,7 +2,6 @@ package trader
 
 import (
 	"context"
-	"crypto/ecdsa"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -10,29 +9,27 @@ import (
 	"io"
 	"log"
 	"math"
-	"math/big"
 	"net/http"
 	"net/url"
 	"nofx/hook"
+	"nofx/signer"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
 )
 
 // AsterTrader Aster交易平台实现
 type AsterTrader struct {
-	ctx        context.Context
-	user       string            // 主钱包地址 (ERC20)
-	signer     string            // API钱包地址
-	privateKey *ecdsa.PrivateKey // API钱包私钥
-	client     *http.Client
-	baseURL    string
+	ctx       context.Context
+	user      string        // 主钱包地址 (ERC20)
+	signer    string        // API钱包地址
+	keySigner signer.Signer // API钱包签名器（本地私钥、keystore 或远程签名服务）
+	client    *http.Client
+	baseURL   string
 
 	// 缓存交易对精度信息
 	symbolPrecision map[string]SymbolPrecision
@@ -50,12 +47,16 @@ type SymbolPrecision struct {
 // NewAsterTrader 创建Aster交易器
 // user: 主钱包地址 (登录地址)
 // signer: API钱包地址 (从 https://www.asterdex.com/en/api-wallet 获取)
-// privateKey: API钱包私钥 (从 https://www.asterdex.com/en/api-wallet 获取)
-func NewAsterTrader(user, signer, privateKeyHex string) (*AsterTrader, error) {
-	// 解析私钥
-	privKey, err := crypto.HexToECDSA(strings.TrimPrefix(privateKeyHex, "0x"))
+// privateKey: API钱包私钥 (从 https://www.asterdex.com/en/api-wallet 获取)，也可以是 remote:0x<地址> / keystore:<路径>
+func NewAsterTrader(user, signerAddr, privateKeyHex string) (*AsterTrader, error) {
+	keySigner, err := signer.New(privateKeyHex)
 	if err != nil {
-		return nil, fmt.Errorf("解析私钥失败: %w", err)
+		return nil, fmt.Errorf("创建签名器失败: %w", err)
+	}
+	if signerAddr == "" {
+		signerAddr = keySigner.Address().Hex()
+	} else if !strings.EqualFold(signerAddr, keySigner.Address().Hex()) {
+		log.Printf("⚠️  Aster API钱包地址 %s 与签名器地址 %s 不一致，签名可能被拒绝", signerAddr, keySigner.Address().Hex())
 	}
 	client := &http.Client{
 		Timeout: 30 * time.Second, // 增加到30秒
@@ -73,8 +74,8 @@ func NewAsterTrader(user, signer, privateKeyHex string) (*AsterTrader, error) {
 	return &AsterTrader{
 		ctx:             context.Background(),
 		user:            user,
-		signer:          signer,
-		privateKey:      privKey,
+		signer:          signerAddr,
+		keySigner:       keySigner,
 		symbolPrecision: make(map[string]SymbolPrecision),
 		client:          client,
 		baseURL:         "https://fapi.asterdex.com",
@@ -278,36 +279,16 @@ func (t *AsterTrader) sign(params map[string]interface{}, nonce uint64) error {
 		return err
 	}
 
-	// ABI编码: (string, address, address, uint256)
-	addrUser := common.HexToAddress(t.user)
-	addrSigner := common.HexToAddress(t.signer)
-	nonceBig := new(big.Int).SetUint64(nonce)
-
-	tString, _ := abi.NewType("string", "", nil)
-	tAddress, _ := abi.NewType("address", "", nil)
-	tUint256, _ := abi.NewType("uint256", "", nil)
-
-	arguments := abi.Arguments{
-		{Type: tString},
-		{Type: tAddress},
-		{Type: tAddress},
-		{Type: tUint256},
-	}
-
-	packed, err := arguments.Pack(jsonStr, addrUser, addrSigner, nonceBig)
-	if err != nil {
-		return fmt.Errorf("ABI编码失败: %w", err)
-	}
-
-	// Keccak256哈希
-	hash := crypto.Keccak256(packed)
-
-	// 以太坊签名消息前缀
-	prefixedMsg := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(hash), hash)
-	msgHash := crypto.Keccak256Hash([]byte(prefixedMsg))
-
-	// ECDSA签名
-	sig, err := crypto.Sign(msgHash.Bytes(), t.privateKey)
+	// 签名载荷: 以太坊签名消息前缀 + keccak256(abi.encode(params, user, signer, nonce))
+	sig, err := signer.SignPayload(t.keySigner, signer.Payload{
+		Type: signer.PayloadAster,
+		Aster: &signer.AsterParams{
+			Params: jsonStr,
+			User:   common.HexToAddress(t.user),
+			Signer: common.HexToAddress(t.signer),
+			Nonce:  nonce,
+		},
+	})
 	if err != nil {
 		return fmt.Errorf("签名失败: %w", err)
 	}
@@ -707,6 +688,107 @@ func (t *AsterTrader) OpenShort(symbol string, quantity float64, leverage int) (
 	return result, nil
 }
 
+// PlaceLimitOrder 限价开仓（不取消已有挂单）
+func (t *AsterTrader) PlaceLimitOrder(symbol, side string, quantity, price float64, leverage int, timeInForce string) (map[string]interface{}, error) {
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, fmt.Errorf("设置杠杆失败: %w", err)
+	}
+
+	formattedPrice, err := t.formatPrice(symbol, price)
+	if err != nil {
+		return nil, err
+	}
+	formattedQty, err := t.formatQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+	if formattedQty <= 0 {
+		return nil, fmt.Errorf("开仓数量过小，格式化后为 0 (原始: %.8f)", quantity)
+	}
+	prec, err := t.getPrecision(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	orderSide := "BUY"
+	if side == "short" {
+		orderSide = "SELL"
+	}
+	params := map[string]interface{}{
+		"symbol":       symbol,
+		"positionSide": "BOTH",
+		"type":         "LIMIT",
+		"side":         orderSide,
+		"timeInForce":  timeInForce,
+		"quantity":     t.formatFloatWithPrecision(formattedQty, prec.QuantityPrecision),
+		"price":        t.formatFloatWithPrecision(formattedPrice, prec.PricePrecision),
+	}
+
+	body, err := t.request("POST", "/fapi/v3/order", params)
+	if err != nil {
+		return nil, fmt.Errorf("限价开仓失败: %w", err)
+	}
+
+	var order map[string]interface{}
+	if err := json.Unmarshal(body, &order); err != nil {
+		return nil, err
+	}
+	orderID, _ := order["orderId"].(float64)
+
+	log.Printf("✓ 限价单已提交: %s %s 数量: %v 价格: %v (%s)", symbol, side, params["quantity"], params["price"], timeInForce)
+
+	result := make(map[string]interface{})
+	result["orderId"] = int64(orderID)
+	result["symbol"] = symbol
+	result["status"] = order["status"]
+	result["quantity"] = formattedQty
+	return result, nil
+}
+
+// GetOrderStatus 查询订单状态
+func (t *AsterTrader) GetOrderStatus(symbol string, orderID int64) (map[string]interface{}, error) {
+	body, err := t.request("GET", "/fapi/v3/order", map[string]interface{}{
+		"symbol":  symbol,
+		"orderId": orderID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+
+	var order struct {
+		Status      string `json:"status"`
+		ExecutedQty string `json:"executedQty"`
+		AvgPrice    string `json:"avgPrice"`
+	}
+	if err := json.Unmarshal(body, &order); err != nil {
+		return nil, fmt.Errorf("解析订单数据失败: %w", err)
+	}
+
+	executedQty, _ := strconv.ParseFloat(order.ExecutedQty, 64)
+	avgPrice, _ := strconv.ParseFloat(order.AvgPrice, 64)
+
+	result := make(map[string]interface{})
+	result["orderId"] = orderID
+	result["symbol"] = symbol
+	result["status"] = order.Status
+	result["executedQty"] = executedQty
+	result["avgPrice"] = avgPrice
+	return result, nil
+}
+
+// CancelOrder 撤销指定订单
+func (t *AsterTrader) CancelOrder(symbol string, orderID int64) error {
+	_, err := t.request("DELETE", "/fapi/v1/order", map[string]interface{}{
+		"symbol":  symbol,
+		"orderId": orderID,
+	})
+	if err != nil {
+		return fmt.Errorf("撤销订单失败: %w", err)
+	}
+	log.Printf("  ✓ 已撤销 %s 订单 %d", symbol, orderID)
+	return nil
+}
+
 // CloseLong 平多单
 func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
 	// 如果数量为0，获取当前持仓数量
@@ -1041,6 +1123,16 @@ func (t *AsterTrader) SetTakeProfit(symbol string, positionSide string, quantity
 
 // CancelStopLossOrders 仅取消止损单（不影响止盈单）
 func (t *AsterTrader) CancelStopLossOrders(symbol string) error {
+	return t.cancelStopLossOrders(symbol, "")
+}
+
+// CancelSideStopLossOrders 仅取消指定持仓方向的止损单（单向持仓模式下 BOTH 方向的止损单同样取消）
+func (t *AsterTrader) CancelSideStopLossOrders(symbol string, positionSide string) error {
+	return t.cancelStopLossOrders(symbol, strings.ToUpper(positionSide))
+}
+
+// cancelStopLossOrders 取消止损单，positionSide 为空时取消所有方向
+func (t *AsterTrader) cancelStopLossOrders(symbol, positionSide string) error {
 	// 获取该币种的所有未完成订单
 	params := map[string]interface{}{
 		"symbol": symbol,
@@ -1056,16 +1148,19 @@ func (t *AsterTrader) CancelStopLossOrders(symbol string) error {
 		return fmt.Errorf("解析订单数据失败: %w", err)
 	}
 
-	// 过滤出止损单并取消（取消所有方向的止损单，包括LONG和SHORT）
+	// 过滤出止损单并取消（未指定方向时取消所有方向的止损单，包括LONG和SHORT）
 	canceledCount := 0
 	var cancelErrors []error
 	for _, order := range orders {
 		orderType, _ := order["type"].(string)
+		orderSide, _ := order["positionSide"].(string)
+		if positionSide != "" && orderSide != positionSide && orderSide != "BOTH" {
+			continue
+		}
 
 		// 只取消止损订单（不取消止盈订单）
 		if orderType == "STOP_MARKET" || orderType == "STOP" {
 			orderID, _ := order["orderId"].(float64)
-			positionSide, _ := order["positionSide"].(string)
 			cancelParams := map[string]interface{}{
 				"symbol":  symbol,
 				"orderId": int64(orderID),
@@ -1080,7 +1175,7 @@ func (t *AsterTrader) CancelStopLossOrders(symbol string) error {
 			}
 
 			canceledCount++
-			log.Printf("  ✓ 已取消止损单 (订单ID: %d, 类型: %s, 方向: %s)", int64(orderID), orderType, positionSide)
+			log.Printf("  ✓ 已取消止损单 (订单ID: %d, 类型: %s, 方向: %s)", int64(orderID), orderType, orderSide)
 		}
 	}
 
@@ -1230,3 +1325,124 @@ func (t *AsterTrader) FormatQuantity(symbol string, quantity float64) (string, e
 	}
 	return fmt.Sprintf("%v", formatted), nil
 }
+
+// GetTrades 获取成交明细（实现Trader接口）
+// userTrades 接口必须指定币种，因此先通过手续费流水找出有成交的币种
+func (t *AsterTrader) GetTrades(startTime int64) ([]TradeFill, error) {
+	commissions, err := t.getIncome("COMMISSION", startTime)
+	if err != nil {
+		return nil, fmt.Errorf("获取手续费流水失败: %w", err)
+	}
+
+	symbols := make(map[string]bool)
+	for _, income := range commissions {
+		if income.Symbol != "" {
+			symbols[income.Symbol] = true
+		}
+	}
+
+	var fills []TradeFill
+	for symbol := range symbols {
+		body, err := t.request("GET", "/fapi/v3/userTrades", map[string]interface{}{
+			"symbol":    symbol,
+			"startTime": startTime,
+			"limit":     1000,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("获取 %s 成交记录失败: %w", symbol, err)
+		}
+
+		var trades []struct {
+			ID              int64  `json:"id"`
+			OrderID         int64  `json:"orderId"`
+			Symbol          string `json:"symbol"`
+			Side            string `json:"side"`
+			Price           string `json:"price"`
+			Qty             string `json:"qty"`
+			Commission      string `json:"commission"`
+			CommissionAsset string `json:"commissionAsset"`
+			RealizedPnl     string `json:"realizedPnl"`
+			Time            int64  `json:"time"`
+		}
+		if err := json.Unmarshal(body, &trades); err != nil {
+			return nil, fmt.Errorf("解析 %s 成交记录失败: %w", symbol, err)
+		}
+
+		for _, trade := range trades {
+			price, _ := strconv.ParseFloat(trade.Price, 64)
+			qty, _ := strconv.ParseFloat(trade.Qty, 64)
+			fee, _ := strconv.ParseFloat(trade.Commission, 64)
+			realizedPnL, _ := strconv.ParseFloat(trade.RealizedPnl, 64)
+
+			// Aster 使用单向持仓（BOTH），根据是否产生已实现盈亏区分开平仓：
+			// 开仓成交的已实现盈亏为0，买入开多/卖出开空；平仓成交则相反
+			positionSide := "LONG"
+			if (trade.Side == "SELL") == (realizedPnL == 0) {
+				positionSide = "SHORT"
+			}
+
+			fills = append(fills, TradeFill{
+				ID:           strconv.FormatInt(trade.ID, 10),
+				OrderID:      strconv.FormatInt(trade.OrderID, 10),
+				Symbol:       trade.Symbol,
+				Side:         trade.Side,
+				PositionSide: positionSide,
+				Price:        price,
+				Quantity:     qty,
+				Fee:          fee,
+				FeeAsset:     trade.CommissionAsset,
+				RealizedPnL:  realizedPnL,
+				Time:         trade.Time,
+			})
+		}
+	}
+
+	return fills, nil
+}
+
+// GetFundingPayments 获取资金费收支（实现Trader接口）
+func (t *AsterTrader) GetFundingPayments(startTime int64) ([]FundingPayment, error) {
+	incomes, err := t.getIncome("FUNDING_FEE", startTime)
+	if err != nil {
+		return nil, fmt.Errorf("获取资金费流水失败: %w", err)
+	}
+
+	payments := make([]FundingPayment, 0, len(incomes))
+	for _, income := range incomes {
+		amount, _ := strconv.ParseFloat(income.Income, 64)
+		payments = append(payments, FundingPayment{
+			ID:     strconv.FormatInt(income.TranID, 10),
+			Symbol: income.Symbol,
+			Amount: amount,
+			Time:   income.Time,
+		})
+	}
+	return payments, nil
+}
+
+// asterIncome 资金流水记录
+type asterIncome struct {
+	Symbol     string `json:"symbol"`
+	IncomeType string `json:"incomeType"`
+	Income     string `json:"income"`
+	Time       int64  `json:"time"`
+	TranID     int64  `json:"tranId"`
+}
+
+// getIncome 获取指定类型的资金流水
+func (t *AsterTrader) getIncome(incomeType string, startTime int64) ([]asterIncome, error) {
+	body, err := t.request("GET", "/fapi/v3/income", map[string]interface{}{
+		"incomeType": incomeType,
+		"startTime":  startTime,
+		"limit":      1000,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var incomes []asterIncome
+	if err := json.Unmarshal(body, &incomes); err != nil {
+		return nil, err
+	}
+	return incomes, nil
+}
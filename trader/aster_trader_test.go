@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"nofx/signer"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/crypto"
@@ -195,7 +196,7 @@ func NewAsterTraderTestSuite(t *testing.T) *AsterTraderTestSuite {
 		ctx:             context.Background(),
 		user:            "0x1234567890123456789012345678901234567890",
 		signer:          "0xabcdefabcdefabcdefabcdefabcdefabcdefabcd",
-		privateKey:      privateKey,
+		keySigner:       signer.NewLocalSigner(privateKey),
 		client:          mockServer.Client(),
 		baseURL:         mockServer.URL, // 使用 mock server 的 URL
 		symbolPrecision: make(map[string]SymbolPrecision),
@@ -291,7 +292,7 @@ func TestNewAsterTrader(t *testing.T) {
 				if trader != nil {
 					assert.Equal(t, tt.user, trader.user)
 					assert.Equal(t, tt.signer, trader.signer)
-					assert.NotNil(t, trader.privateKey)
+					assert.NotNil(t, trader.keySigner)
 				}
 			}
 		})
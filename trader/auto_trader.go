@@ -5,11 +5,15 @@ import (
 	"fmt"
 	"log"
 	"math"
+	"nofx/config"
 	"nofx/decision"
+	"nofx/events"
+	"nofx/exit"
 	"nofx/logger"
 	"nofx/market"
 	"nofx/mcp"
 	"nofx/pool"
+	"nofx/risk"
 	"strings"
 	"sync"
 	"time"
@@ -20,10 +24,10 @@ type AutoTraderConfig struct {
 	// Trader标识
 	ID      string // Trader唯一标识（用于日志目录等）
 	Name    string // Trader显示名称
-	AIModel string // AI模型: "qwen" 或 "deepseek"
+	AIModel string // AI provider（对应 ai_models.provider，如 deepseek / qwen / anthropic / gemini / ollama / custom）
 
 	// 交易平台选择
-	Exchange string // "binance", "hyperliquid" 或 "aster"
+	Exchange string // "binance", "hyperliquid", "aster" 或 "paper"（模拟盘）
 
 	// 币安API配置
 	BinanceAPIKey    string
@@ -41,14 +45,9 @@ type AutoTraderConfig struct {
 
 	CoinPoolAPIURL string
 
-	// AI配置
-	UseQwen     bool
-	DeepSeekKey string
-	QwenKey     string
-
-	// 自定义AI API配置
+	// AI配置（自定义 URL/模型为空时使用 provider 默认值）
+	AIAPIKey        string
 	CustomAPIURL    string
-	CustomAPIKey    string
 	CustomModelName string
 
 	// 扫描配置
@@ -61,10 +60,13 @@ type AutoTraderConfig struct {
 	BTCETHLeverage  int // BTC和ETH的杠杆倍数
 	AltcoinLeverage int // 山寨币的杠杆倍数
 
-	// 风险控制（仅作为提示，AI可自主决定）
-	MaxDailyLoss    float64       // 最大日亏损百分比（提示）
-	MaxDrawdown     float64       // 最大回撤百分比（提示）
-	StopTradingTime time.Duration // 触发风控后暂停时长
+	// 风险控制
+	MaxDailyLoss     float64       // 最大日亏损百分比（触发熔断，同时作为 RiskConfig 的默认值）
+	MaxDrawdown      float64       // 最大回撤百分比（触发熔断，同时作为 RiskConfig 的默认值）
+	StopTradingTime  time.Duration // 触发熔断后暂停时长
+	FlattenOnBreaker bool          // 触发熔断时是否平掉所有持仓
+	RiskConfig       risk.Config   // 组合风控规则（开仓前强制执行，不受AI决策影响）
+	ExitPolicy       exit.Config   // 持仓退出策略（移动止损、保本、锁利、超时平仓）
 
 	// 仓位模式
 	IsCrossMargin bool // true=全仓模式, false=逐仓模式
@@ -75,6 +77,24 @@ type AutoTraderConfig struct {
 
 	// 系统提示词模板
 	SystemPromptTemplate string // 系统提示词模板名称（如 "default", "aggressive"）
+
+	// 决策输出模式
+	DecisionMode string               // "text"=XML标签+JSON文本, "tool_call"=原生函数调用, "agent"=多轮数据查询（为空使用文本模式）
+	AgentConfig  decision.AgentConfig // Agent 模式每周期的轮数/token 预算
+
+	// 决策日志存储类型："json"=每周期一个文件（默认）, "sqlite"=带索引的单文件
+	DecisionLogStore string
+
+	// 执行模式："auto"=自动执行AI决策（默认）, "approval"=AI决策作为提案等待人工审批
+	ExecutionMode       string
+	AutoApproveNotional float64 // 审批模式下名义价值低于该值（USDT）的决策自动执行，0=全部需要审批
+
+	// 限价开仓：超时未成交时撤单（"cancel"，默认）或剩余部分转市价（"market"）
+	LimitOrderTimeout  time.Duration // 限价单有效时长（0=一个扫描周期）
+	LimitOrderFallback string
+
+	// 行情数据：提示词中输出的K线周期和指标（为空使用默认 3m/4h 数据）
+	MarketData market.DataConfig
 }
 
 // AutoTrader 自动交易器
@@ -108,6 +128,30 @@ type AutoTrader struct {
 	lastBalanceSyncTime   time.Time          // 上次余额同步时间
 	database              interface{}        // 数据库引用（用于自动更新余额）
 	userID                string             // 用户ID
+	riskEngine            *risk.Engine       // 组合风控引擎
+	riskAccount           *risk.Account      // 本周期风控账户快照
+	peakEquity            float64            // 净值高水位（用于回撤风控和熔断）
+	dayStartEquity        float64            // 当日起始净值（用于日亏损熔断）
+	breakerReason         string             // 最近一次熔断原因
+	breakerTrippedAt      time.Time          // 最近一次熔断时间
+	breakerMu             sync.Mutex         // 熔断状态锁
+	execMu                sync.Mutex         // 下单执行锁（决策周期与手动操作互斥）
+
+	// 实时事件（周期开始、AI响应、决策执行、熔断等），供 API 推送
+	eventBus *events.Bus
+
+	// 持仓退出策略
+	exitEngine *exit.Engine                         // 持仓退出策略引擎
+	exitStates map[string]*config.PositionExitState // 持仓退出状态 (symbol_side -> 状态)
+	exitMu     sync.Mutex                           // 退出状态锁
+
+	// 人工审批模式的待审批提案
+	proposals  map[string]*Proposal // 提案ID -> 提案（含最近已处理的提案）
+	proposalMu sync.Mutex           // 提案锁
+
+	// 跟踪中的限价开仓单
+	pendingOrders  map[int64]*PendingOrder // 订单ID -> 挂单（含尚未告知AI的已结束订单）
+	pendingOrderMu sync.Mutex              // 挂单锁
 }
 
 // NewAutoTrader 创建自动交易器
@@ -120,38 +164,18 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 		config.Name = "Default Trader"
 	}
 	if config.AIModel == "" {
-		if config.UseQwen {
-			config.AIModel = "qwen"
-		} else {
-			config.AIModel = "deepseek"
-		}
+		config.AIModel = mcp.ProviderDeepSeek
 	}
 
-	mcpClient := mcp.New()
-
-	// 初始化AI
-	if config.AIModel == "custom" {
-		// 使用自定义API
-		mcpClient.SetAPIKey(config.CustomAPIKey, config.CustomAPIURL, config.CustomModelName)
-		log.Printf("🤖 [%s] 使用自定义AI API: %s (模型: %s)", config.Name, config.CustomAPIURL, config.CustomModelName)
-	} else if config.UseQwen || config.AIModel == "qwen" {
-		// 使用Qwen (支持自定义URL和Model)
-		mcpClient = mcp.NewQwenClient()
-		mcpClient.SetAPIKey(config.QwenKey, config.CustomAPIURL, config.CustomModelName)
-		if config.CustomAPIURL != "" || config.CustomModelName != "" {
-			log.Printf("🤖 [%s] 使用阿里云Qwen AI (自定义URL: %s, 模型: %s)", config.Name, config.CustomAPIURL, config.CustomModelName)
-		} else {
-			log.Printf("🤖 [%s] 使用阿里云Qwen AI", config.Name)
-		}
+	// 初始化AI（按 provider 从 mcp 注册表创建客户端）
+	mcpClient, err := mcp.NewClientForProvider(config.AIModel, config.AIAPIKey, config.CustomAPIURL, config.CustomModelName)
+	if err != nil {
+		return nil, fmt.Errorf("初始化AI客户端失败: %w", err)
+	}
+	if config.CustomAPIURL != "" || config.CustomModelName != "" {
+		log.Printf("🤖 [%s] 使用AI provider: %s (自定义URL: %s, 模型: %s)", config.Name, config.AIModel, config.CustomAPIURL, config.CustomModelName)
 	} else {
-		// 默认使用DeepSeek (支持自定义URL和Model)
-		mcpClient = mcp.NewDeepSeekClient()
-		mcpClient.SetAPIKey(config.DeepSeekKey, config.CustomAPIURL, config.CustomModelName)
-		if config.CustomAPIURL != "" || config.CustomModelName != "" {
-			log.Printf("🤖 [%s] 使用DeepSeek AI (自定义URL: %s, 模型: %s)", config.Name, config.CustomAPIURL, config.CustomModelName)
-		} else {
-			log.Printf("🤖 [%s] 使用DeepSeek AI", config.Name)
-		}
+		log.Printf("🤖 [%s] 使用AI provider: %s", config.Name, config.AIModel)
 	}
 
 	// 初始化币种池API
@@ -166,7 +190,6 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 
 	// 根据配置创建对应的交易器
 	var trader Trader
-	var err error
 
 	// 记录仓位模式（通用）
 	marginModeStr := "全仓"
@@ -191,6 +214,12 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 		if err != nil {
 			return nil, fmt.Errorf("初始化Aster交易器失败: %w", err)
 		}
+	case "paper":
+		log.Printf("🏦 [%s] 使用模拟盘交易（实时行情，虚拟资金）", config.Name)
+		trader, err = NewPaperTrader(config.InitialBalance, fmt.Sprintf("paper_accounts/%s.json", config.ID))
+		if err != nil {
+			return nil, fmt.Errorf("初始化模拟盘交易器失败: %w", err)
+		}
 	default:
 		return nil, fmt.Errorf("不支持的交易平台: %s", config.Exchange)
 	}
@@ -202,7 +231,7 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 
 	// 初始化决策日志记录器（使用trader ID创建独立目录）
 	logDir := fmt.Sprintf("decision_logs/%s", config.ID)
-	decisionLogger := logger.NewDecisionLogger(logDir)
+	decisionLogger := logger.NewDecisionLoggerForStore(config.DecisionLogStore, logDir)
 
 	// 设置默认系统提示词模板
 	systemPromptTemplate := config.SystemPromptTemplate
@@ -211,7 +240,7 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 		systemPromptTemplate = "adaptive"
 	}
 
-	return &AutoTrader{
+	at := &AutoTrader{
 		id:                    config.ID,
 		name:                  config.Name,
 		aiModel:               config.AIModel,
@@ -236,14 +265,25 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 		lastBalanceSyncTime:   time.Now(), // 初始化为当前时间
 		database:              database,
 		userID:                userID,
-	}, nil
+		riskEngine:            risk.NewEngine(config.RiskConfig),
+		exitEngine:            exit.NewEngine(config.ExitPolicy),
+		eventBus:              events.Default(),
+	}
+
+	// 恢复熔断状态（暂停在重启后继续生效）
+	at.loadCircuitBreaker()
+	// 恢复持仓峰值收益和止损状态
+	at.loadExitStates()
+	// 恢复周期计数、当日已实现盈亏和持仓时长（重启对风控和提示词不可见）
+	at.loadTraderState()
+
+	return at, nil
 }
 
 // Run 运行自动交易主循环
 func (at *AutoTrader) Run() error {
 	at.isRunning = true
 	at.stopMonitorCh = make(chan struct{})
-	at.startTime = time.Now()
 
 	log.Println("🚀 AI驱动自动交易系统启动")
 	log.Printf("💰 初始余额: %.2f USDT", at.initialBalance)
@@ -252,8 +292,10 @@ func (at *AutoTrader) Run() error {
 	at.monitorWg.Add(1)
 	defer at.monitorWg.Done()
 
-	// 启动回撤监控
-	at.startDrawdownMonitor()
+	// 启动持仓退出策略监控
+	at.startExitMonitor()
+	// 启动限价挂单成交监控
+	at.startPendingOrderMonitor()
 
 	ticker := time.NewTicker(at.config.ScanInterval)
 	defer ticker.Stop()
@@ -292,10 +334,16 @@ func (at *AutoTrader) Stop() {
 // runCycle 运行一个交易周期（使用AI全权决策）
 func (at *AutoTrader) runCycle() error {
 	at.callCount++
+	// 每周期结束时保存运行状态快照（包括提前返回的周期）
+	defer at.saveTraderState()
 
 	log.Print("\n" + strings.Repeat("=", 70) + "\n")
 	log.Printf("⏰ %s - AI决策周期 #%d", time.Now().Format("2006-01-02 15:04:05"), at.callCount)
 	log.Println(strings.Repeat("=", 70))
+	at.publishEvent(events.CycleStarted, map[string]any{"cycle": at.callCount})
+
+	// 上一周期未处理的提案被新周期取代
+	at.expireProposals(true)
 
 	// 创建决策记录
 	record := &logger.DecisionRecord{
@@ -304,8 +352,8 @@ func (at *AutoTrader) runCycle() error {
 	}
 
 	// 1. 检查是否需要停止交易
-	if time.Now().Before(at.stopUntil) {
-		remaining := at.stopUntil.Sub(time.Now())
+	if stopUntil := at.pausedUntil(); time.Now().Before(stopUntil) {
+		remaining := stopUntil.Sub(time.Now())
 		log.Printf("⏸ 风险控制：暂停交易中，剩余 %.0f 分钟", remaining.Minutes())
 		record.Success = false
 		record.ErrorMessage = fmt.Sprintf("风险控制暂停中，剩余 %.0f 分钟", remaining.Minutes())
@@ -315,14 +363,24 @@ func (at *AutoTrader) runCycle() error {
 
 	// 2. 重置日盈亏（每天重置）
 	if time.Since(at.lastResetTime) > 24*time.Hour {
+		at.execMu.Lock()
 		at.dailyPnL = 0
 		at.dailyRealizedPnL = 0
-		at.lastResetTime = time.Now()
+		at.execMu.Unlock()
+		at.resetDailyBaseline()
 		log.Println("📅 日盈亏已重置")
 	}
 
-	// 4. 收集交易上下文
+	// 3. 同步交易所成交账本（失败不影响本周期决策）
+	if err := at.syncLedger(); err != nil {
+		log.Printf("⚠️  同步成交账本失败: %v", err)
+	}
+
+	// 4. 检查限价挂单（成交后设置止损止盈，超时撤单或转市价），再收集交易上下文
+	at.checkPendingOrders()
+	at.execMu.Lock()
 	ctx, err := at.buildTradingContext()
+	at.execMu.Unlock()
 	if err != nil {
 		record.Success = false
 		record.ErrorMessage = fmt.Sprintf("构建交易上下文失败: %v", err)
@@ -330,6 +388,14 @@ func (at *AutoTrader) runCycle() error {
 		return fmt.Errorf("构建交易上下文失败: %w", err)
 	}
 
+	at.publishEvent(events.BalanceSynced, map[string]any{
+		"total_equity":      ctx.Account.TotalEquity,
+		"available_balance": ctx.Account.AvailableBalance,
+		"unrealized_pnl":    ctx.Account.UnrealizedPnL,
+		"daily_pnl":         ctx.Account.DailyPnL,
+		"position_count":    ctx.Account.PositionCount,
+	})
+
 	// 保存账户状态快照
 	record.AccountState = logger.AccountSnapshot{
 		TotalBalance:          ctx.Account.TotalEquity - ctx.Account.UnrealizedPnL,
@@ -354,6 +420,23 @@ func (at *AutoTrader) runCycle() error {
 		})
 	}
 
+	// 熔断检查：日亏损或回撤超限时暂停交易
+	if reason := at.checkCircuitBreaker(ctx.Account.TotalEquity); reason != "" {
+		record.Success = false
+		record.ErrorMessage = fmt.Sprintf("触发熔断: %s", reason)
+		if at.config.FlattenOnBreaker {
+			record.Note = record.ErrorMessage
+			at.flattenOnBreaker(record)
+		}
+		at.decisionLogger.LogDecision(record)
+		return nil
+	}
+
+	// 刷新风控账户快照
+	at.execMu.Lock()
+	at.refreshRiskAccount(ctx)
+	at.execMu.Unlock()
+
 	log.Print(strings.Repeat("=", 70))
 	for _, coin := range ctx.CandidateCoins {
 		record.CandidateCoins = append(record.CandidateCoins, coin.Symbol)
@@ -365,6 +448,7 @@ func (at *AutoTrader) runCycle() error {
 	// 5. 调用AI获取完整决策
 	log.Printf("🤖 正在请求AI分析并决策... [模板: %s]", at.systemPromptTemplate)
 	decision, err := decision.GetFullDecisionWithCustomPrompt(ctx, at.mcpClient, at.customPrompt, at.overrideBasePrompt, at.systemPromptTemplate)
+	at.publishAIResponse(decision, err)
 
 	if decision != nil && decision.AIRequestDurationMs > 0 {
 		record.AIRequestDurationMs = decision.AIRequestDurationMs
@@ -378,6 +462,9 @@ func (at *AutoTrader) runCycle() error {
 		record.SystemPrompt = decision.SystemPrompt // 保存系统提示词
 		record.InputPrompt = decision.UserPrompt
 		record.CoTTrace = decision.CoTTrace
+		record.AgentTranscript = decision.Transcript
+		record.AgentTurns = decision.AgentTurns
+		record.AgentTokens = decision.TokensUsed
 		if len(decision.Decisions) > 0 {
 			decisionJSON, _ := json.MarshalIndent(decision.Decisions, "", "  ")
 			record.DecisionJSON = string(decisionJSON)
@@ -438,7 +525,7 @@ func (at *AutoTrader) runCycle() error {
 	log.Print(strings.Repeat("-", 70))
 
 	// 8. 对决策排序：确保先平仓后开仓（防止仓位叠加超限）
-	sortedDecisions := sortDecisionsByPriority(decision.Decisions)
+	sortedDecisions := SortDecisionsByPriority(decision.Decisions)
 
 	log.Println("🔄 执行顺序（已优化）: 先平仓→后开仓")
 	for i, d := range sortedDecisions {
@@ -446,7 +533,8 @@ func (at *AutoTrader) runCycle() error {
 	}
 	log.Println()
 
-	// 执行决策并记录结果
+	// 执行决策并记录结果（与手动操作互斥）
+	at.execMu.Lock()
 	for _, d := range sortedDecisions {
 		actionRecord := logger.DecisionAction{
 			Action:    d.Action,
@@ -458,19 +546,31 @@ func (at *AutoTrader) runCycle() error {
 			Success:   false,
 		}
 
+		// 审批模式：登记为提案，等待人工批准后执行
+		if proposal := at.proposeIfRequired(&d, ctx.Positions); proposal != nil {
+			actionRecord.ProposalID = proposal.ID
+			actionRecord.Error = "等待人工审批"
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⏳ %s %s 等待人工审批（提案 %s）", d.Symbol, d.Action, proposal.ID))
+			record.Decisions = append(record.Decisions, actionRecord)
+			continue
+		}
+
 		if err := at.executeDecisionWithRecord(&d, &actionRecord); err != nil {
 			log.Printf("❌ 执行决策失败 (%s %s): %v", d.Symbol, d.Action, err)
 			actionRecord.Error = err.Error()
 			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 失败: %v", d.Symbol, d.Action, err))
+			at.publishEvent(events.DecisionFailed, actionEventData(&actionRecord))
 		} else {
 			actionRecord.Success = true
 			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s 成功", d.Symbol, d.Action))
+			at.publishEvent(events.DecisionExecuted, actionEventData(&actionRecord))
 			// 成功执行后短暂延迟
 			time.Sleep(1 * time.Second)
 		}
 
 		record.Decisions = append(record.Decisions, actionRecord)
 	}
+	at.execMu.Unlock()
 
 	// 9. 保存决策记录
 	if err := at.decisionLogger.LogDecision(record); err != nil {
@@ -604,7 +704,7 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 
 	// 5. 分析历史表现（最近100个周期，避免长期持仓的交易记录丢失）
 	// 假设每3分钟一个周期，100个周期 = 5小时，足够覆盖大部分交易
-	performance, err := at.decisionLogger.AnalyzePerformance(100)
+	performance, err := at.AnalyzePerformance(100)
 	if err != nil {
 		log.Printf("⚠️  分析历史表现失败: %v", err)
 		// 不影响主流程，继续执行（但设置performance为nil以避免传递错误数据）
@@ -618,6 +718,10 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		CallCount:       at.callCount,
 		BTCETHLeverage:  at.config.BTCETHLeverage,  // 使用配置的杠杆倍数
 		AltcoinLeverage: at.config.AltcoinLeverage, // 使用配置的杠杆倍数
+		DecisionMode:    at.config.DecisionMode,
+		MarketData:      at.config.MarketData,
+		Agent:           at.config.AgentConfig,
+		AgentTools:      at.agentTools(),
 		Account: decision.AccountInfo{
 			TotalEquity:      totalEquity,
 			AvailableBalance: availableBalance,
@@ -631,7 +735,9 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		},
 		Positions:      positionInfos,
 		CandidateCoins: candidateCoins,
-		Performance:    performance, // 添加历史表现分析
+		Performance:    performance,              // 添加历史表现分析
+		ManualActions:  at.recentManualActions(), // 上个周期之后的手动操作
+		PendingOrders:  at.pendingOrderInfos(),
 	}
 
 	return ctx, nil
@@ -639,6 +745,19 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 
 // executeDecisionWithRecord 执行AI决策并记录详细信息
 func (at *AutoTrader) executeDecisionWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
+	// 组合风控：开仓前强制检查，可能拒绝或缩减仓位
+	if err := at.checkRisk(decision); err != nil {
+		return err
+	}
+	if err := at.dispatchDecision(decision, actionRecord); err != nil {
+		return err
+	}
+	at.trackRiskAccount(decision)
+	return nil
+}
+
+// dispatchDecision 按action分发执行
+func (at *AutoTrader) dispatchDecision(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
 	switch decision.Action {
 	case "open_long":
 		return at.executeOpenLongWithRecord(decision, actionRecord)
@@ -675,6 +794,9 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 			}
 		}
 	}
+	if err := at.checkNoPendingOrder(decision.Symbol, "long"); err != nil {
+		return err
+	}
 
 	// 获取当前价格
 	marketData, err := market.Get(decision.Symbol)
@@ -714,6 +836,11 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 		// 继续执行，不影响交易
 	}
 
+	// 限价入场：挂单后由挂单监控在成交时设置止损止盈
+	if decision.IsLimitEntry() {
+		return at.placeLimitEntry(decision, "long", actionRecord)
+	}
+
 	// 开仓
 	order, err := at.trader.OpenLong(decision.Symbol, quantity, decision.Leverage)
 	if err != nil {
@@ -732,9 +859,13 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 	at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
 
 	// 设置止损止盈
+	stopLoss := decision.StopLoss
 	if err := at.trader.SetStopLoss(decision.Symbol, "LONG", quantity, decision.StopLoss); err != nil {
 		log.Printf("  ⚠ 设置止损失败: %v", err)
+		stopLoss = 0
 	}
+	// 重置该持仓的退出策略状态，记录初始止损（用于计算R）
+	at.recordStopLoss(decision.Symbol, "long", stopLoss, true)
 	if err := at.trader.SetTakeProfit(decision.Symbol, "LONG", quantity, decision.TakeProfit); err != nil {
 		log.Printf("  ⚠ 设置止盈失败: %v", err)
 	}
@@ -755,6 +886,9 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 			}
 		}
 	}
+	if err := at.checkNoPendingOrder(decision.Symbol, "short"); err != nil {
+		return err
+	}
 
 	// 获取当前价格
 	marketData, err := market.Get(decision.Symbol)
@@ -794,6 +928,11 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 		// 继续执行，不影响交易
 	}
 
+	// 限价入场：挂单后由挂单监控在成交时设置止损止盈
+	if decision.IsLimitEntry() {
+		return at.placeLimitEntry(decision, "short", actionRecord)
+	}
+
 	// 开仓
 	order, err := at.trader.OpenShort(decision.Symbol, quantity, decision.Leverage)
 	if err != nil {
@@ -812,9 +951,13 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 	at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
 
 	// 设置止损止盈
+	stopLoss := decision.StopLoss
 	if err := at.trader.SetStopLoss(decision.Symbol, "SHORT", quantity, decision.StopLoss); err != nil {
 		log.Printf("  ⚠ 设置止损失败: %v", err)
+		stopLoss = 0
 	}
+	// 重置该持仓的退出策略状态，记录初始止损（用于计算R）
+	at.recordStopLoss(decision.Symbol, "short", stopLoss, true)
 	if err := at.trader.SetTakeProfit(decision.Symbol, "SHORT", quantity, decision.TakeProfit); err != nil {
 		log.Printf("  ⚠ 设置止盈失败: %v", err)
 	}
@@ -826,6 +969,11 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 func (at *AutoTrader) executeCloseLongWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
 	log.Printf("  🔄 平多仓: %s", decision.Symbol)
 
+	// 先撤销同币种同方向的限价开仓挂单，避免平仓后挂单成交重新开仓
+	if canceled := at.cancelPendingOrders(decision.Symbol, "long", "平仓前撤销挂单"); canceled > 0 {
+		log.Printf("  🚫 已撤销 %d 个限价开仓挂单", canceled)
+	}
+
 	// 获取当前价格
 	marketData, err := market.Get(decision.Symbol)
 	if err != nil {
@@ -861,6 +1009,7 @@ func (at *AutoTrader) executeCloseLongWithRecord(decision *decision.Decision, ac
 		}
 	}
 
+	at.ClearPeakPnLCache(decision.Symbol, "long")
 	log.Printf("  ✓ 平仓成功")
 	return nil
 }
@@ -869,6 +1018,11 @@ func (at *AutoTrader) executeCloseLongWithRecord(decision *decision.Decision, ac
 func (at *AutoTrader) executeCloseShortWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
 	log.Printf("  🔄 平空仓: %s", decision.Symbol)
 
+	// 先撤销同币种同方向的限价开仓挂单，避免平仓后挂单成交重新开仓
+	if canceled := at.cancelPendingOrders(decision.Symbol, "short", "平仓前撤销挂单"); canceled > 0 {
+		log.Printf("  🚫 已撤销 %d 个限价开仓挂单", canceled)
+	}
+
 	// 获取当前价格
 	marketData, err := market.Get(decision.Symbol)
 	if err != nil {
@@ -901,6 +1055,7 @@ func (at *AutoTrader) executeCloseShortWithRecord(decision *decision.Decision, a
 		}
 	}
 
+	at.ClearPeakPnLCache(decision.Symbol, "short")
 	log.Printf("  ✓ 平仓成功")
 	return nil
 }
@@ -985,6 +1140,7 @@ func (at *AutoTrader) executeUpdateStopLossWithRecord(decision *decision.Decisio
 		return fmt.Errorf("修改止损失败: %w", err)
 	}
 
+	at.recordStopLoss(decision.Symbol, side, decision.NewStopLoss, false)
 	log.Printf("  ✓ 止损已调整: %.2f (当前价格: %.2f)", decision.NewStopLoss, marketData.CurrentPrice)
 	return nil
 }
@@ -1217,6 +1373,11 @@ func (at *AutoTrader) GetID() string {
 	return at.id
 }
 
+// GetUserID 获取trader所属用户ID
+func (at *AutoTrader) GetUserID() string {
+	return at.userID
+}
+
 // GetName 获取trader名称
 func (at *AutoTrader) GetName() string {
 	return at.name
@@ -1259,11 +1420,6 @@ func (at *AutoTrader) GetDecisionLogger() logger.IDecisionLogger {
 
 // GetStatus 获取系统状态（用于API）
 func (at *AutoTrader) GetStatus() map[string]interface{} {
-	aiProvider := "DeepSeek"
-	if at.config.UseQwen {
-		aiProvider = "Qwen"
-	}
-
 	return map[string]interface{}{
 		"trader_id":       at.id,
 		"trader_name":     at.name,
@@ -1275,9 +1431,20 @@ func (at *AutoTrader) GetStatus() map[string]interface{} {
 		"call_count":      at.callCount,
 		"initial_balance": at.initialBalance,
 		"scan_interval":   at.config.ScanInterval.String(),
-		"stop_until":      at.stopUntil.Format(time.RFC3339),
+		"stop_until":      at.pausedUntil().Format(time.RFC3339),
 		"last_reset_time": at.lastResetTime.Format(time.RFC3339),
-		"ai_provider":     aiProvider,
+		"ai_provider":     at.aiModel,
+
+		"execution_mode":        at.executionMode(),
+		"auto_approve_notional": at.config.AutoApproveNotional,
+		"pending_proposals":     at.pendingProposalCount(),
+
+		"limit_order_timeout":  at.limitOrderTimeout().String(),
+		"limit_order_fallback": at.limitOrderFallback(),
+		"pending_orders":       at.openPendingOrderCount(),
+
+		"market_timeframes": at.config.MarketData.SortedTimeframes(),
+		"market_indicators": at.config.MarketData.Indicators,
 	}
 }
 
@@ -1428,9 +1595,9 @@ func calculatePnLPercentage(unrealizedPnl, marginUsed float64) float64 {
 	return 0.0
 }
 
-// sortDecisionsByPriority 对决策排序：先平仓，再开仓，最后hold/wait
+// SortDecisionsByPriority 对决策排序：先平仓，再开仓，最后hold/wait
 // 这样可以避免换仓时仓位叠加超限
-func sortDecisionsByPriority(decisions []decision.Decision) []decision.Decision {
+func SortDecisionsByPriority(decisions []decision.Decision) []decision.Decision {
 	if len(decisions) <= 1 {
 		return decisions
 	}
@@ -1538,105 +1705,6 @@ func normalizeSymbol(symbol string) string {
 	return symbol
 }
 
-// 启动回撤监控
-func (at *AutoTrader) startDrawdownMonitor() {
-	at.monitorWg.Add(1)
-	go func() {
-		defer at.monitorWg.Done()
-
-		ticker := time.NewTicker(1 * time.Minute) // 每分钟检查一次
-		defer ticker.Stop()
-
-		log.Println("📊 启动持仓回撤监控（每分钟检查一次）")
-
-		for {
-			select {
-			case <-ticker.C:
-				at.checkPositionDrawdown()
-			case <-at.stopMonitorCh:
-				log.Println("⏹ 停止持仓回撤监控")
-				return
-			}
-		}
-	}()
-}
-
-// 检查持仓回撤情况
-func (at *AutoTrader) checkPositionDrawdown() {
-	// 获取当前持仓
-	positions, err := at.trader.GetPositions()
-	if err != nil {
-		log.Printf("❌ 回撤监控：获取持仓失败: %v", err)
-		return
-	}
-
-	for _, pos := range positions {
-		symbol := pos["symbol"].(string)
-		side := pos["side"].(string)
-		entryPrice := pos["entryPrice"].(float64)
-		markPrice := pos["markPrice"].(float64)
-		quantity := pos["positionAmt"].(float64)
-		if quantity < 0 {
-			quantity = -quantity // 空仓数量为负，转为正数
-		}
-
-		// 计算当前盈亏百分比
-		leverage := 10 // 默认值
-		if lev, ok := pos["leverage"].(float64); ok {
-			leverage = int(lev)
-		}
-
-		var currentPnLPct float64
-		if side == "long" {
-			currentPnLPct = ((markPrice - entryPrice) / entryPrice) * float64(leverage) * 100
-		} else {
-			currentPnLPct = ((entryPrice - markPrice) / entryPrice) * float64(leverage) * 100
-		}
-
-		// 构造持仓唯一标识（区分多空）
-		posKey := symbol + "_" + side
-
-		// 获取该持仓的历史最高收益
-		at.peakPnLCacheMutex.RLock()
-		peakPnLPct, exists := at.peakPnLCache[posKey]
-		at.peakPnLCacheMutex.RUnlock()
-
-		if !exists {
-			// 如果没有历史最高记录，使用当前盈亏作为初始值
-			peakPnLPct = currentPnLPct
-			at.UpdatePeakPnL(symbol, side, currentPnLPct)
-		} else {
-			// 更新峰值缓存
-			at.UpdatePeakPnL(symbol, side, currentPnLPct)
-		}
-
-		// 计算回撤（从最高点下跌的幅度）
-		var drawdownPct float64
-		if peakPnLPct > 0 && currentPnLPct < peakPnLPct {
-			drawdownPct = ((peakPnLPct - currentPnLPct) / peakPnLPct) * 100
-		}
-
-		// 检查平仓条件：收益大于5%且回撤超过40%
-		if currentPnLPct > 5.0 && drawdownPct >= 40.0 {
-			log.Printf("🚨 触发回撤平仓条件: %s %s | 当前收益: %.2f%% | 最高收益: %.2f%% | 回撤: %.2f%%",
-				symbol, side, currentPnLPct, peakPnLPct, drawdownPct)
-
-			// 执行平仓
-			if err := at.emergencyClosePosition(symbol, side); err != nil {
-				log.Printf("❌ 回撤平仓失败 (%s %s): %v", symbol, side, err)
-			} else {
-				log.Printf("✅ 回撤平仓成功: %s %s", symbol, side)
-				// 平仓后清理该持仓的缓存
-				at.ClearPeakPnLCache(symbol, side)
-			}
-		} else if currentPnLPct > 5.0 {
-			// 记录接近平仓条件的情况（用于调试）
-			log.Printf("📊 回撤监控: %s %s | 收益: %.2f%% | 最高: %.2f%% | 回撤: %.2f%%",
-				symbol, side, currentPnLPct, peakPnLPct, drawdownPct)
-		}
-	}
-}
-
 // 紧急平仓函数
 func (at *AutoTrader) emergencyClosePosition(symbol, side string) error {
 	switch side {
@@ -1692,8 +1760,10 @@ func (at *AutoTrader) UpdatePeakPnL(symbol, side string, currentPnLPct float64)
 // ClearPeakPnLCache 清除指定持仓的峰值缓存
 func (at *AutoTrader) ClearPeakPnLCache(symbol, side string) {
 	at.peakPnLCacheMutex.Lock()
-	defer at.peakPnLCacheMutex.Unlock()
-
 	posKey := symbol + "_" + side
 	delete(at.peakPnLCache, posKey)
+	at.peakPnLCacheMutex.Unlock()
+
+	// 同时清理持久化的退出状态
+	at.deleteExitState(symbol, side)
 }
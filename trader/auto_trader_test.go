@@ -4,13 +4,16 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"strings"
 	"testing"
 	"time"
 
 	"nofx/decision"
+	"nofx/exit"
 	"nofx/logger"
 	"nofx/market"
 	"nofx/pool"
+	"nofx/risk"
 
 	"github.com/agiledragon/gomonkey/v2"
 	"github.com/stretchr/testify/suite"
@@ -108,6 +111,7 @@ func (s *AutoTraderTestSuite) SetupTest() {
 		lastBalanceSyncTime:   time.Now(),
 		database:              s.mockDB,
 		userID:                "test_user",
+		exitEngine:            exit.NewEngine(exit.DefaultConfig()),
 	}
 }
 
@@ -143,7 +147,7 @@ func (s *AutoTraderTestSuite) TestSortDecisionsByPriority() {
 
 	for _, tt := range tests {
 		s.Run(tt.name, func() {
-			result := sortDecisionsByPriority(tt.input)
+			result := SortDecisionsByPriority(tt.input)
 
 			s.Equal(len(tt.input), len(result), "结果长度应该相同")
 
@@ -833,7 +837,49 @@ func (s *AutoTraderTestSuite) TestExecuteDecisionWithRecord() {
 	})
 }
 
-func (s *AutoTraderTestSuite) TestCheckPositionDrawdown() {
+func (s *AutoTraderTestSuite) TestExecuteDecisionWithRecord_RiskEngine() {
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 100.0}, nil
+	})
+	s.mockTrader.positions = []map[string]interface{}{
+		{
+			"symbol":      "BTCUSDT",
+			"side":        "long",
+			"positionAmt": 0.02,
+			"entryPrice":  50000.0,
+			"markPrice":   50000.0,
+			"leverage":    10.0,
+		},
+	}
+	s.autoTrader.riskEngine = risk.NewEngine(risk.Config{MaxPositions: 2, MaxAltNotionalPct: 10, MinNotional: 12})
+
+	s.Run("超限仓位被缩减", func() {
+		d := &decision.Decision{Action: "open_long", Symbol: "SOLUSDT", PositionSizeUSD: 5000, Leverage: 5}
+		err := s.autoTrader.executeDecisionWithRecord(d, &logger.DecisionAction{})
+		s.NoError(err)
+		s.InDelta(1010.0, d.PositionSizeUSD, 1e-9) // 净值 10100 的 10%
+		s.Len(s.autoTrader.riskAccount.Positions, 2)
+	})
+
+	s.Run("持仓数达到上限时拒绝", func() {
+		d := &decision.Decision{Action: "open_short", Symbol: "ETHUSDT", PositionSizeUSD: 500, Leverage: 5}
+		err := s.autoTrader.executeDecisionWithRecord(d, &logger.DecisionAction{})
+		s.Error(err)
+		s.Contains(err.Error(), "风控拒绝")
+		s.Contains(err.Error(), "[最大持仓数]")
+	})
+
+	s.Run("平仓后释放持仓名额", func() {
+		err := s.autoTrader.executeDecisionWithRecord(&decision.Decision{Action: "close_long", Symbol: "BTCUSDT"}, &logger.DecisionAction{})
+		s.NoError(err)
+
+		d := &decision.Decision{Action: "open_short", Symbol: "ETHUSDT", PositionSizeUSD: 500, Leverage: 5}
+		err = s.autoTrader.executeDecisionWithRecord(d, &logger.DecisionAction{})
+		s.NoError(err)
+	})
+}
+
+func (s *AutoTraderTestSuite) TestCheckExitPolicies_ProfitRetrace() {
 	tests := []struct {
 		name             string
 		setupPositions   func()
@@ -940,7 +986,7 @@ func (s *AutoTraderTestSuite) TestCheckPositionDrawdown() {
 				defer tt.cleanupFailures()
 			}
 
-			s.autoTrader.checkPositionDrawdown()
+			s.autoTrader.checkExitPolicies()
 
 			if !tt.skipCacheCheck {
 				cache := s.autoTrader.GetPeakPnLCache()
@@ -952,8 +998,10 @@ func (s *AutoTraderTestSuite) TestCheckPositionDrawdown() {
 				}
 			}
 
-			// 清理状态
+			// 清理状态（每个用例都是独立的持仓）
 			s.mockTrader.positions = []map[string]interface{}{}
+			s.autoTrader.peakPnLCache = make(map[string]float64)
+			s.autoTrader.exitStates = nil
 		})
 	}
 }
@@ -983,6 +1031,12 @@ type MockTrader struct {
 	shouldFailOpenLong   bool
 	shouldFailCloseLong  bool
 	shouldFailCloseShort bool
+	fills                []TradeFill
+	funding              []FundingPayment
+	orders               map[int64]map[string]interface{} // 限价单状态（orderId -> 状态）
+	nextOrderID          int64
+	stopLosses           map[string]float64 // 交易所止损单（symbol_side -> 止损价）
+	rejectStopPrice      float64            // 设置该止损价时返回错误（模拟交易所拒绝）
 }
 
 func (m *MockTrader) GetBalance() (map[string]interface{}, error) {
@@ -1059,6 +1113,13 @@ func (m *MockTrader) GetMarketPrice(symbol string) (float64, error) {
 }
 
 func (m *MockTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	if m.rejectStopPrice > 0 && stopPrice == m.rejectStopPrice {
+		return errors.New("order would immediately trigger")
+	}
+	if m.stopLosses == nil {
+		m.stopLosses = make(map[string]float64)
+	}
+	m.stopLosses[symbol+"_"+strings.ToLower(positionSide)] = stopPrice
 	return nil
 }
 
@@ -1067,6 +1128,13 @@ func (m *MockTrader) SetTakeProfit(symbol string, positionSide string, quantity,
 }
 
 func (m *MockTrader) CancelStopLossOrders(symbol string) error {
+	delete(m.stopLosses, symbol+"_long")
+	delete(m.stopLosses, symbol+"_short")
+	return nil
+}
+
+func (m *MockTrader) CancelSideStopLossOrders(symbol string, positionSide string) error {
+	delete(m.stopLosses, symbol+"_"+strings.ToLower(positionSide))
 	return nil
 }
 
@@ -1086,6 +1154,50 @@ func (m *MockTrader) FormatQuantity(symbol string, quantity float64) (string, er
 	return fmt.Sprintf("%.4f", quantity), nil
 }
 
+func (m *MockTrader) GetTrades(startTime int64) ([]TradeFill, error) {
+	return m.fills, nil
+}
+
+func (m *MockTrader) GetFundingPayments(startTime int64) ([]FundingPayment, error) {
+	return m.funding, nil
+}
+
+func (m *MockTrader) PlaceLimitOrder(symbol, side string, quantity, price float64, leverage int, timeInForce string) (map[string]interface{}, error) {
+	if m.orders == nil {
+		m.orders = make(map[int64]map[string]interface{})
+	}
+	m.nextOrderID++
+	m.orders[m.nextOrderID] = map[string]interface{}{
+		"orderId":     m.nextOrderID,
+		"symbol":      symbol,
+		"side":        side,
+		"price":       price,
+		"quantity":    quantity,
+		"timeInForce": timeInForce,
+		"status":      OrderStatusNew,
+		"executedQty": 0.0,
+		"avgPrice":    0.0,
+	}
+	return map[string]interface{}{"orderId": m.nextOrderID, "symbol": symbol, "status": OrderStatusNew, "quantity": quantity}, nil
+}
+
+func (m *MockTrader) GetOrderStatus(symbol string, orderID int64) (map[string]interface{}, error) {
+	order, ok := m.orders[orderID]
+	if !ok {
+		return nil, errors.New("order not found")
+	}
+	return order, nil
+}
+
+func (m *MockTrader) CancelOrder(symbol string, orderID int64) error {
+	order, ok := m.orders[orderID]
+	if !ok || !IsOrderOpen(order["status"].(string)) {
+		return errors.New("order not open")
+	}
+	order["status"] = OrderStatusCanceled
+	return nil
+}
+
 // ============================================================
 // 测试套件入口
 // ============================================================
@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"log"
+	"math"
 	"nofx/hook"
 	"strconv"
 	"strings"
@@ -532,8 +533,105 @@ func (t *FuturesTrader) CloseShort(symbol string, quantity float64) (map[string]
 	return result, nil
 }
 
+// PlaceLimitOrder 限价开仓（不取消已有委托单，避免撤掉同币种的其他挂单）
+func (t *FuturesTrader) PlaceLimitOrder(symbol, side string, quantity, price float64, leverage int, timeInForce string) (map[string]interface{}, error) {
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+	quantityFloat, parseErr := strconv.ParseFloat(quantityStr, 64)
+	if parseErr != nil || quantityFloat <= 0 {
+		return nil, fmt.Errorf("开仓数量过小，格式化后为 0 (原始: %.8f → 格式化: %s)", quantity, quantityStr)
+	}
+	if quantityFloat*price < t.GetMinNotional(symbol) {
+		return nil, fmt.Errorf("订单金额 %.2f USDT 低于最小要求 %.2f USDT", quantityFloat*price, t.GetMinNotional(symbol))
+	}
+
+	priceStr, err := t.FormatPrice(symbol, price)
+	if err != nil {
+		return nil, err
+	}
+
+	orderSide, posSide := futures.SideTypeBuy, futures.PositionSideTypeLong
+	if side == "short" {
+		orderSide, posSide = futures.SideTypeSell, futures.PositionSideTypeShort
+	}
+
+	order, err := t.client.NewCreateOrderService().
+		Symbol(symbol).
+		Side(orderSide).
+		PositionSide(posSide).
+		Type(futures.OrderTypeLimit).
+		TimeInForce(futures.TimeInForceType(timeInForce)).
+		Quantity(quantityStr).
+		Price(priceStr).
+		NewClientOrderID(getBrOrderID()).
+		Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("限价开仓失败: %w", err)
+	}
+
+	log.Printf("✓ 限价单已提交: %s %s 数量: %s 价格: %s (%s)", symbol, side, quantityStr, priceStr, timeInForce)
+
+	result := make(map[string]interface{})
+	result["orderId"] = order.OrderID
+	result["symbol"] = order.Symbol
+	result["status"] = string(order.Status)
+	result["quantity"] = quantityFloat
+	return result, nil
+}
+
+// GetOrderStatus 查询订单状态
+func (t *FuturesTrader) GetOrderStatus(symbol string, orderID int64) (map[string]interface{}, error) {
+	order, err := t.client.NewGetOrderService().
+		Symbol(symbol).
+		OrderID(orderID).
+		Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+
+	executedQty, _ := strconv.ParseFloat(order.ExecutedQuantity, 64)
+	avgPrice, _ := strconv.ParseFloat(order.AvgPrice, 64)
+
+	result := make(map[string]interface{})
+	result["orderId"] = order.OrderID
+	result["symbol"] = order.Symbol
+	result["status"] = string(order.Status)
+	result["executedQty"] = executedQty
+	result["avgPrice"] = avgPrice
+	return result, nil
+}
+
+// CancelOrder 撤销指定订单
+func (t *FuturesTrader) CancelOrder(symbol string, orderID int64) error {
+	_, err := t.client.NewCancelOrderService().
+		Symbol(symbol).
+		OrderID(orderID).
+		Do(context.Background())
+	if err != nil {
+		return fmt.Errorf("撤销订单失败: %w", err)
+	}
+	log.Printf("  ✓ 已撤销 %s 订单 %d", symbol, orderID)
+	return nil
+}
+
 // CancelStopLossOrders 仅取消止损单（不影响止盈单）
 func (t *FuturesTrader) CancelStopLossOrders(symbol string) error {
+	return t.cancelStopLossOrders(symbol, "")
+}
+
+// CancelSideStopLossOrders 仅取消指定持仓方向的止损单（单向持仓模式下 BOTH 方向的止损单同样取消）
+func (t *FuturesTrader) CancelSideStopLossOrders(symbol string, positionSide string) error {
+	return t.cancelStopLossOrders(symbol, strings.ToUpper(positionSide))
+}
+
+// cancelStopLossOrders 取消止损单，positionSide 为空时取消所有方向
+func (t *FuturesTrader) cancelStopLossOrders(symbol, positionSide string) error {
 	// 获取该币种的所有未完成订单
 	orders, err := t.client.NewListOpenOrdersService().
 		Symbol(symbol).
@@ -543,11 +641,14 @@ func (t *FuturesTrader) CancelStopLossOrders(symbol string) error {
 		return fmt.Errorf("获取未完成订单失败: %w", err)
 	}
 
-	// 过滤出止损单并取消（取消所有方向的止损单，包括LONG和SHORT）
+	// 过滤出止损单并取消（未指定方向时取消所有方向的止损单，包括LONG和SHORT）
 	canceledCount := 0
 	var cancelErrors []error
 	for _, order := range orders {
 		orderType := order.Type
+		if positionSide != "" && order.PositionSide != futures.PositionSideType(positionSide) && order.PositionSide != futures.PositionSideTypeBoth {
+			continue
+		}
 
 		// 只取消止损订单（不取消止盈订单）
 		if orderType == futures.OrderTypeStopMarket || orderType == futures.OrderTypeStop {
@@ -847,6 +948,33 @@ func (t *FuturesTrader) GetSymbolPrecision(symbol string) (int, error) {
 	return 3, nil // 默认精度为3
 }
 
+// FormatPrice 按交易对的价格步长（PRICE_FILTER tickSize）格式化价格
+func (t *FuturesTrader) FormatPrice(symbol string, price float64) (string, error) {
+	exchangeInfo, err := t.client.NewExchangeInfoService().Do(context.Background())
+	if err != nil {
+		return "", fmt.Errorf("获取交易规则失败: %w", err)
+	}
+
+	for _, s := range exchangeInfo.Symbols {
+		if s.Symbol != symbol {
+			continue
+		}
+		for _, filter := range s.Filters {
+			if filter["filterType"] == "PRICE_FILTER" {
+				tickSizeStr, _ := filter["tickSize"].(string)
+				tickSize, _ := strconv.ParseFloat(tickSizeStr, 64)
+				if tickSize <= 0 {
+					break
+				}
+				rounded := math.Round(price/tickSize) * tickSize
+				return strconv.FormatFloat(rounded, 'f', calculatePrecision(tickSizeStr), 64), nil
+			}
+		}
+	}
+
+	return strconv.FormatFloat(price, 'f', -1, 64), nil
+}
+
 // calculatePrecision 从stepSize计算精度
 func calculatePrecision(stepSize string) int {
 	// 去除尾部的0
@@ -915,3 +1043,102 @@ func stringContains(s, substr string) bool {
 	}
 	return false
 }
+
+// GetTrades 获取成交明细
+// 币安的 userTrades 接口必须指定币种，因此先通过手续费流水找出有成交的币种
+func (t *FuturesTrader) GetTrades(startTime int64) ([]TradeFill, error) {
+	commissions, err := t.getIncomeHistory("COMMISSION", startTime)
+	if err != nil {
+		return nil, fmt.Errorf("获取手续费流水失败: %w", err)
+	}
+
+	symbols := make(map[string]bool)
+	for _, income := range commissions {
+		if income.Symbol != "" {
+			symbols[income.Symbol] = true
+		}
+	}
+
+	var fills []TradeFill
+	for symbol := range symbols {
+		fromTime := startTime
+		for {
+			trades, err := t.client.NewListAccountTradeService().
+				Symbol(symbol).
+				StartTime(fromTime).
+				Limit(1000).
+				Do(context.Background())
+			if err != nil {
+				return nil, fmt.Errorf("获取 %s 成交记录失败: %w", symbol, err)
+			}
+
+			for _, trade := range trades {
+				price, _ := strconv.ParseFloat(trade.Price, 64)
+				qty, _ := strconv.ParseFloat(trade.Quantity, 64)
+				fee, _ := strconv.ParseFloat(trade.Commission, 64)
+				realizedPnL, _ := strconv.ParseFloat(trade.RealizedPnl, 64)
+				fills = append(fills, TradeFill{
+					ID:           strconv.FormatInt(trade.ID, 10),
+					OrderID:      strconv.FormatInt(trade.OrderID, 10),
+					Symbol:       trade.Symbol,
+					Side:         string(trade.Side),
+					PositionSide: string(trade.PositionSide),
+					Price:        price,
+					Quantity:     qty,
+					Fee:          fee,
+					FeeAsset:     trade.CommissionAsset,
+					RealizedPnL:  realizedPnL,
+					Time:         trade.Time,
+				})
+			}
+
+			if len(trades) < 1000 {
+				break
+			}
+			fromTime = trades[len(trades)-1].Time + 1
+		}
+	}
+
+	return fills, nil
+}
+
+// GetFundingPayments 获取资金费收支
+func (t *FuturesTrader) GetFundingPayments(startTime int64) ([]FundingPayment, error) {
+	incomes, err := t.getIncomeHistory("FUNDING_FEE", startTime)
+	if err != nil {
+		return nil, fmt.Errorf("获取资金费流水失败: %w", err)
+	}
+
+	payments := make([]FundingPayment, 0, len(incomes))
+	for _, income := range incomes {
+		amount, _ := strconv.ParseFloat(income.Income, 64)
+		payments = append(payments, FundingPayment{
+			ID:     strconv.FormatInt(income.TranID, 10),
+			Symbol: income.Symbol,
+			Amount: amount,
+			Time:   income.Time,
+		})
+	}
+	return payments, nil
+}
+
+// getIncomeHistory 分页获取指定类型的资金流水
+func (t *FuturesTrader) getIncomeHistory(incomeType string, startTime int64) ([]*futures.IncomeHistory, error) {
+	var result []*futures.IncomeHistory
+	fromTime := startTime
+	for {
+		incomes, err := t.client.NewGetIncomeHistoryService().
+			IncomeType(incomeType).
+			StartTime(fromTime).
+			Limit(1000).
+			Do(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, incomes...)
+		if len(incomes) < 1000 {
+			return result, nil
+		}
+		fromTime = incomes[len(incomes)-1].Time + 1
+	}
+}
@@ -0,0 +1,235 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/config"
+	"nofx/events"
+	"nofx/logger"
+	"time"
+)
+
+// circuitBreakerStore 熔断状态存储（由 config.Database 实现）
+type circuitBreakerStore interface {
+	GetCircuitBreakerState(traderID string) (*config.CircuitBreakerState, error)
+	SaveCircuitBreakerState(state *config.CircuitBreakerState) error
+}
+
+// breakerStore 返回可用的熔断状态存储（数据库未实现时返回 nil）
+func (at *AutoTrader) breakerStore() circuitBreakerStore {
+	store, _ := at.database.(circuitBreakerStore)
+	return store
+}
+
+// loadCircuitBreaker 从数据库恢复熔断状态（重启后继续暂停）
+func (at *AutoTrader) loadCircuitBreaker() {
+	store := at.breakerStore()
+	if store == nil {
+		return
+	}
+	state, err := store.GetCircuitBreakerState(at.id)
+	if err != nil {
+		log.Printf("⚠️ [%s] 读取熔断状态失败: %v", at.name, err)
+		return
+	}
+	if state == nil {
+		return
+	}
+
+	at.breakerMu.Lock()
+	defer at.breakerMu.Unlock()
+	at.peakEquity = state.PeakEquity
+	at.dayStartEquity = state.DayStartEquity
+	if !state.DayStartTime.IsZero() {
+		at.lastResetTime = state.DayStartTime
+	}
+	at.stopUntil = state.StopUntil
+	at.breakerReason = state.Reason
+	at.breakerTrippedAt = state.TrippedAt
+	if time.Now().Before(at.stopUntil) {
+		log.Printf("⏸ [%s] 恢复熔断状态：暂停至 %s（%s）", at.name, at.stopUntil.Format("2006-01-02 15:04:05"), at.breakerReason)
+	}
+}
+
+// pausedUntil 返回暂停交易截止时间
+func (at *AutoTrader) pausedUntil() time.Time {
+	at.breakerMu.Lock()
+	defer at.breakerMu.Unlock()
+	return at.stopUntil
+}
+
+// resetDailyBaseline 日切时清空日初净值，下一周期重新记录
+func (at *AutoTrader) resetDailyBaseline() {
+	at.breakerMu.Lock()
+	defer at.breakerMu.Unlock()
+	at.dayStartEquity = 0
+	at.lastResetTime = time.Now()
+}
+
+// checkCircuitBreaker 更新净值高水位和日初净值，超过最大日亏损或最大回撤时触发熔断
+// 返回非空字符串表示本周期已触发熔断（内容为触发原因），熔断平仓由调用方通过 flattenOnBreaker 执行
+func (at *AutoTrader) checkCircuitBreaker(equity float64) string {
+	if equity <= 0 {
+		return ""
+	}
+
+	at.breakerMu.Lock()
+	now := time.Now()
+
+	// 上一次熔断暂停结束：以当前净值重新作为基准，避免立即再次触发
+	if !at.stopUntil.IsZero() && !now.Before(at.stopUntil) {
+		log.Printf("🔄 [%s] 熔断暂停结束，以当前净值 %.2f 重置回撤和日亏损基准", at.name, equity)
+		at.stopUntil = time.Time{}
+		at.peakEquity = equity
+		at.dayStartEquity = equity
+	}
+
+	if equity > at.peakEquity {
+		at.peakEquity = equity
+	}
+	if at.dayStartEquity <= 0 {
+		at.dayStartEquity = equity
+	}
+
+	dailyLossPct := (at.dayStartEquity - equity) / at.dayStartEquity * 100
+	drawdownPct := (at.peakEquity - equity) / at.peakEquity * 100
+
+	reason := ""
+	switch {
+	case at.config.MaxDailyLoss > 0 && dailyLossPct >= at.config.MaxDailyLoss:
+		reason = fmt.Sprintf("当日亏损 %.2f%%（日初净值 %.2f → %.2f）超过上限 %.2f%%",
+			dailyLossPct, at.dayStartEquity, equity, at.config.MaxDailyLoss)
+	case at.config.MaxDrawdown > 0 && drawdownPct >= at.config.MaxDrawdown:
+		reason = fmt.Sprintf("净值回撤 %.2f%%（峰值 %.2f → %.2f）超过上限 %.2f%%",
+			drawdownPct, at.peakEquity, equity, at.config.MaxDrawdown)
+	}
+
+	if reason != "" {
+		at.stopUntil = now.Add(at.config.StopTradingTime)
+		at.breakerReason = reason
+		at.breakerTrippedAt = now
+	}
+	state := at.breakerStateLocked()
+	at.breakerMu.Unlock()
+
+	if reason != "" {
+		log.Printf("🚨 [%s] 触发熔断: %s，暂停交易至 %s", at.name, reason, state.StopUntil.Format("2006-01-02 15:04:05"))
+		at.publishEvent(events.StopTriggered, map[string]any{
+			"reason":     reason,
+			"equity":     equity,
+			"stop_until": state.StopUntil,
+			"flatten":    at.config.FlattenOnBreaker,
+		})
+	}
+
+	at.saveCircuitBreaker(state)
+	return reason
+}
+
+// ResetCircuitBreaker 手动解除熔断，并以当前净值重置回撤和日亏损基准
+func (at *AutoTrader) ResetCircuitBreaker() error {
+	equity := 0.0
+	if balance, err := at.trader.GetBalance(); err == nil {
+		wallet, _ := balance["totalWalletBalance"].(float64)
+		unrealized, _ := balance["totalUnrealizedProfit"].(float64)
+		equity = wallet + unrealized
+	} else {
+		log.Printf("⚠️ [%s] 获取账户净值失败，基准将在下一周期重新记录: %v", at.name, err)
+	}
+
+	at.breakerMu.Lock()
+	at.stopUntil = time.Time{}
+	at.breakerReason = ""
+	at.peakEquity = equity
+	at.dayStartEquity = equity
+	state := at.breakerStateLocked()
+	at.breakerMu.Unlock()
+
+	log.Printf("🔄 [%s] 熔断已手动解除（当前净值 %.2f）", at.name, equity)
+	store := at.breakerStore()
+	if store == nil {
+		return nil
+	}
+	if err := store.SaveCircuitBreakerState(state); err != nil {
+		return fmt.Errorf("保存熔断状态失败: %w", err)
+	}
+	return nil
+}
+
+// GetCircuitBreakerStatus 获取熔断状态
+func (at *AutoTrader) GetCircuitBreakerStatus() map[string]interface{} {
+	at.breakerMu.Lock()
+	defer at.breakerMu.Unlock()
+
+	status := map[string]interface{}{
+		"tripped":          time.Now().Before(at.stopUntil),
+		"reason":           at.breakerReason,
+		"peak_equity":      at.peakEquity,
+		"day_start_equity": at.dayStartEquity,
+		"max_daily_loss":   at.config.MaxDailyLoss,
+		"max_drawdown":     at.config.MaxDrawdown,
+		"stop_minutes":     int(at.config.StopTradingTime.Minutes()),
+		"flatten":          at.config.FlattenOnBreaker,
+	}
+	if !at.stopUntil.IsZero() {
+		status["stop_until"] = at.stopUntil.Format(time.RFC3339)
+	}
+	if !at.breakerTrippedAt.IsZero() {
+		status["tripped_at"] = at.breakerTrippedAt.Format(time.RFC3339)
+	}
+	return status
+}
+
+// breakerStateLocked 生成待持久化的熔断状态（调用方需持有 breakerMu）
+func (at *AutoTrader) breakerStateLocked() *config.CircuitBreakerState {
+	return &config.CircuitBreakerState{
+		TraderID:       at.id,
+		PeakEquity:     at.peakEquity,
+		DayStartEquity: at.dayStartEquity,
+		DayStartTime:   at.lastResetTime,
+		StopUntil:      at.stopUntil,
+		Reason:         at.breakerReason,
+		TrippedAt:      at.breakerTrippedAt,
+	}
+}
+
+// saveCircuitBreaker 持久化熔断状态（失败仅记录日志）
+func (at *AutoTrader) saveCircuitBreaker(state *config.CircuitBreakerState) {
+	store := at.breakerStore()
+	if store == nil {
+		return
+	}
+	if err := store.SaveCircuitBreakerState(state); err != nil {
+		log.Printf("⚠️ [%s] 保存熔断状态失败: %v", at.name, err)
+	}
+}
+
+// flattenOnBreaker 熔断时撤销所有限价开仓挂单并平掉所有持仓，平仓动作以 source=breaker 追加到本周期决策记录
+//
+// 与手动全部平仓相同的执行路径（持有 execMu），平仓结果会出现在决策日志和下一周期的提示词中
+func (at *AutoTrader) flattenOnBreaker(record *logger.DecisionRecord) {
+	at.execMu.Lock()
+	defer at.execMu.Unlock()
+
+	// 先撤销所有限价开仓挂单，避免暂停期间挂单成交重新开仓
+	if canceled := at.cancelPendingOrders("", "", "熔断撤单"); canceled > 0 {
+		log.Printf("🚨 [%s] 熔断撤销 %d 个限价开仓挂单", at.name, canceled)
+		record.Source = logger.DecisionSourceBreaker
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🚫 熔断撤销 %d 个限价开仓挂单", canceled))
+	}
+
+	decisions, err := at.positionCloseDecisions("熔断平仓")
+	if err != nil {
+		log.Printf("❌ [%s] 熔断平仓失败: %v", at.name, err)
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ 熔断平仓失败: %v", err))
+		return
+	}
+	if len(decisions) == 0 {
+		return
+	}
+
+	log.Printf("🚨 [%s] 熔断平仓 (%d 个持仓)", at.name, len(decisions))
+	record.Source = logger.DecisionSourceBreaker
+	at.riskAccount = nil
+	at.executeSourcedDecisions(decisions, record, logger.DecisionSourceBreaker)
+}
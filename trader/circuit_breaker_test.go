@@ -0,0 +1,173 @@
+package trader
+
+import (
+	"nofx/config"
+	"nofx/events"
+	"nofx/logger"
+	"nofx/market"
+	"testing"
+	"time"
+
+	"github.com/agiledragon/gomonkey/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryBreakerStore 内存熔断状态存储
+type memoryBreakerStore struct {
+	state *config.CircuitBreakerState
+}
+
+func (m *memoryBreakerStore) GetCircuitBreakerState(traderID string) (*config.CircuitBreakerState, error) {
+	return m.state, nil
+}
+
+func (m *memoryBreakerStore) SaveCircuitBreakerState(state *config.CircuitBreakerState) error {
+	m.state = state
+	return nil
+}
+
+func newBreakerTestTrader(cfg AutoTraderConfig, mock *MockTrader, store *memoryBreakerStore) *AutoTrader {
+	cfg.ID = "breaker_trader"
+	cfg.Name = "Breaker Trader"
+	if cfg.StopTradingTime == 0 {
+		cfg.StopTradingTime = time.Hour
+	}
+	return &AutoTrader{
+		id:                    cfg.ID,
+		name:                  cfg.Name,
+		config:                cfg,
+		trader:                mock,
+		lastResetTime:         time.Now(),
+		positionFirstSeenTime: make(map[string]int64),
+		peakPnLCache:          make(map[string]float64),
+		database:              store,
+	}
+}
+
+func TestCircuitBreaker_DailyLoss(t *testing.T) {
+	store := &memoryBreakerStore{}
+	at := newBreakerTestTrader(AutoTraderConfig{MaxDailyLoss: 10, MaxDrawdown: 50}, &MockTrader{}, store)
+
+	assert.Empty(t, at.checkCircuitBreaker(1000))
+	assert.Empty(t, at.checkCircuitBreaker(950))
+	require.NotNil(t, store.state)
+	assert.Equal(t, 1000.0, store.state.DayStartEquity)
+	assert.True(t, store.state.StopUntil.IsZero())
+
+	reason := at.checkCircuitBreaker(890)
+	assert.Contains(t, reason, "当日亏损")
+	assert.True(t, time.Now().Before(at.pausedUntil()))
+	assert.Equal(t, reason, store.state.Reason)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), store.state.StopUntil, 5*time.Second)
+
+	status := at.GetCircuitBreakerStatus()
+	assert.Equal(t, true, status["tripped"])
+	assert.Equal(t, reason, status["reason"])
+}
+
+func TestCircuitBreaker_DrawdownAcrossDays(t *testing.T) {
+	store := &memoryBreakerStore{}
+	at := newBreakerTestTrader(AutoTraderConfig{MaxDailyLoss: 10, MaxDrawdown: 15}, &MockTrader{}, store)
+
+	assert.Empty(t, at.checkCircuitBreaker(1000))
+	at.resetDailyBaseline()
+	assert.Empty(t, at.checkCircuitBreaker(910))
+	at.resetDailyBaseline()
+	assert.Empty(t, at.checkCircuitBreaker(860))
+
+	// 日内亏损不大，但从峰值 1000 累计回撤超过 15%
+	reason := at.checkCircuitBreaker(840)
+	assert.Contains(t, reason, "净值回撤")
+	assert.Equal(t, 1000.0, store.state.PeakEquity)
+}
+
+func TestCircuitBreaker_FlattenPositions(t *testing.T) {
+	patches := gomonkey.NewPatches()
+	defer patches.Reset()
+	patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 100}, nil
+	})
+
+	mock := &MockTrader{
+		positions: []map[string]interface{}{
+			{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.5, "entryPrice": 110.0},
+			{"symbol": "ETHUSDT", "side": "short", "positionAmt": -2.0, "entryPrice": 90.0},
+		},
+	}
+	at := newBreakerTestTrader(AutoTraderConfig{MaxDailyLoss: 5, FlattenOnBreaker: true}, mock, &memoryBreakerStore{})
+	at.peakPnLCache["BTCUSDT_long"] = 12
+	at.peakPnLCache["ETHUSDT_short"] = 8
+
+	at.checkCircuitBreaker(1000)
+	assert.NotEmpty(t, at.checkCircuitBreaker(940))
+
+	record := &logger.DecisionRecord{}
+	at.flattenOnBreaker(record)
+	assert.Equal(t, logger.DecisionSourceBreaker, record.Source)
+	require.Len(t, record.Decisions, 2)
+	for _, action := range record.Decisions {
+		assert.True(t, action.Success)
+		assert.Equal(t, logger.DecisionSourceBreaker, action.Source)
+	}
+	assert.Empty(t, at.peakPnLCache)
+}
+
+func TestCircuitBreaker_RestoreAndReset(t *testing.T) {
+	store := &memoryBreakerStore{state: &config.CircuitBreakerState{
+		TraderID:       "breaker_trader",
+		PeakEquity:     1000,
+		DayStartEquity: 1000,
+		DayStartTime:   time.Now().Add(-time.Hour),
+		StopUntil:      time.Now().Add(30 * time.Minute),
+		Reason:         "当日亏损超过上限",
+		TrippedAt:      time.Now().Add(-30 * time.Minute),
+	}}
+	mock := &MockTrader{balance: map[string]interface{}{
+		"totalWalletBalance":    850.0,
+		"totalUnrealizedProfit": 0.0,
+	}}
+	at := newBreakerTestTrader(AutoTraderConfig{MaxDailyLoss: 10, MaxDrawdown: 20}, mock, store)
+
+	// 重启后恢复暂停状态
+	at.loadCircuitBreaker()
+	assert.True(t, time.Now().Before(at.pausedUntil()))
+	assert.Equal(t, 1000.0, at.peakEquity)
+
+	// 手动解除后以当前净值作为新基准，不会立即再次触发
+	require.NoError(t, at.ResetCircuitBreaker())
+	assert.True(t, at.pausedUntil().IsZero())
+	assert.True(t, store.state.StopUntil.IsZero())
+	assert.Equal(t, 850.0, store.state.PeakEquity)
+	assert.Empty(t, at.checkCircuitBreaker(850))
+}
+
+func TestCircuitBreaker_RebaseAfterPauseExpires(t *testing.T) {
+	store := &memoryBreakerStore{}
+	at := newBreakerTestTrader(AutoTraderConfig{MaxDailyLoss: 10}, &MockTrader{}, store)
+
+	at.checkCircuitBreaker(1000)
+	require.NotEmpty(t, at.checkCircuitBreaker(880))
+
+	// 模拟暂停已到期
+	at.stopUntil = time.Now().Add(-time.Minute)
+	assert.Empty(t, at.checkCircuitBreaker(870))
+	assert.True(t, at.pausedUntil().IsZero())
+	assert.Equal(t, 870.0, store.state.DayStartEquity)
+}
+
+func TestCircuitBreaker_PublishesStopTriggered(t *testing.T) {
+	at := newBreakerTestTrader(AutoTraderConfig{MaxDailyLoss: 10}, &MockTrader{}, &memoryBreakerStore{})
+	at.eventBus = events.NewBus(10)
+	sub, _ := at.eventBus.Subscribe(0, events.ForTrader(at.id, events.StopTriggered))
+	defer sub.Close()
+
+	at.checkCircuitBreaker(1000)
+	assert.Empty(t, sub.Events())
+
+	reason := at.checkCircuitBreaker(880)
+	require.Len(t, sub.Events(), 1)
+	event := <-sub.Events()
+	assert.Equal(t, reason, event.Data["reason"])
+	assert.Equal(t, 880.0, event.Data["equity"])
+}
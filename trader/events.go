@@ -0,0 +1,56 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/events"
+	"nofx/logger"
+)
+
+// publishEvent 发布交易员事件（未设置事件总线时忽略）
+func (at *AutoTrader) publishEvent(eventType string, data map[string]any) {
+	if at.eventBus == nil {
+		return
+	}
+	at.eventBus.Publish(at.id, eventType, data)
+}
+
+// publishAIResponse 发布AI响应事件（只包含决策摘要，不含提示词和思维链）
+func (at *AutoTrader) publishAIResponse(full *decision.FullDecision, err error) {
+	data := map[string]any{"cycle": at.callCount}
+	if err != nil {
+		data["error"] = err.Error()
+	}
+	if full != nil {
+		data["duration_ms"] = full.AIRequestDurationMs
+		decisions := make([]map[string]any, 0, len(full.Decisions))
+		for _, d := range full.Decisions {
+			decisions = append(decisions, map[string]any{
+				"symbol":    d.Symbol,
+				"action":    d.Action,
+				"reasoning": d.Reasoning,
+			})
+		}
+		data["decisions"] = decisions
+		if full.AgentTurns > 0 {
+			data["agent_turns"] = full.AgentTurns
+			data["agent_tokens"] = full.TokensUsed
+		}
+	}
+	at.publishEvent(events.AIResponse, data)
+}
+
+// actionEventData 决策执行结果的事件数据
+func actionEventData(action *logger.DecisionAction) map[string]any {
+	data := map[string]any{
+		"symbol":   action.Symbol,
+		"action":   action.Action,
+		"price":    action.Price,
+		"quantity": action.Quantity,
+		"leverage": action.Leverage,
+		"order_id": action.OrderID,
+	}
+	if action.Error != "" {
+		data["error"] = action.Error
+	}
+	return data
+}
@@ -0,0 +1,319 @@
+package trader
+
+import (
+	"log"
+	"math"
+	"nofx/config"
+	"nofx/events"
+	"nofx/exit"
+	"nofx/market"
+	"strings"
+	"time"
+)
+
+// exitStateStore 持仓退出状态存储（由 config.Database 实现）
+type exitStateStore interface {
+	GetPositionExitStates(traderID string) ([]*config.PositionExitState, error)
+	SavePositionExitState(state *config.PositionExitState) error
+	DeletePositionExitState(traderID, symbol, side string) error
+}
+
+// exitStore 返回可用的退出状态存储（数据库未实现时返回 nil）
+func (at *AutoTrader) exitStore() exitStateStore {
+	store, _ := at.database.(exitStateStore)
+	return store
+}
+
+// loadExitStates 从数据库恢复持仓退出状态、峰值收益缓存和持仓首次出现时间
+func (at *AutoTrader) loadExitStates() {
+	store := at.exitStore()
+	if store == nil {
+		return
+	}
+	states, err := store.GetPositionExitStates(at.id)
+	if err != nil {
+		log.Printf("⚠️ [%s] 读取持仓退出状态失败: %v", at.name, err)
+		return
+	}
+
+	at.exitMu.Lock()
+	defer at.exitMu.Unlock()
+	if at.exitStates == nil {
+		at.exitStates = make(map[string]*config.PositionExitState)
+	}
+	for _, state := range states {
+		posKey := state.Symbol + "_" + state.Side
+		at.exitStates[posKey] = state
+		at.peakPnLCache[posKey] = state.PeakPnLPct
+		if !state.OpenedAt.IsZero() {
+			at.positionFirstSeenTime[posKey] = state.OpenedAt.UnixMilli()
+		}
+	}
+	if len(states) > 0 {
+		log.Printf("📊 [%s] 恢复 %d 个持仓的退出状态", at.name, len(states))
+	}
+}
+
+// startExitMonitor 启动持仓退出策略监控
+func (at *AutoTrader) startExitMonitor() {
+	if at.exitEngine == nil {
+		return
+	}
+	interval := at.exitEngine.CheckInterval()
+
+	at.monitorWg.Add(1)
+	go func() {
+		defer at.monitorWg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		log.Printf("📊 启动持仓退出策略监控（每 %v 检查一次）", interval)
+
+		for {
+			select {
+			case <-ticker.C:
+				at.checkExitPolicies()
+			case <-at.stopMonitorCh:
+				log.Println("⏹ 停止持仓退出策略监控")
+				return
+			}
+		}
+	}()
+}
+
+// checkExitPolicies 按退出策略检查所有持仓：收紧交易所止损单，或在需要时直接平仓
+//
+// 整个检查持有 execMu，避免与AI决策、手动操作的平仓和止损调整交错执行
+func (at *AutoTrader) checkExitPolicies() {
+	if at.exitEngine == nil {
+		return
+	}
+	at.execMu.Lock()
+	defer at.execMu.Unlock()
+
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		log.Printf("❌ 退出策略：获取持仓失败: %v", err)
+		return
+	}
+
+	now := time.Now()
+	active := make(map[string]bool)
+	for _, pos := range positions {
+		symbol, _ := pos["symbol"].(string)
+		side, _ := pos["side"].(string)
+		entryPrice, _ := pos["entryPrice"].(float64)
+		markPrice, _ := pos["markPrice"].(float64)
+		quantity, _ := pos["positionAmt"].(float64)
+		quantity = math.Abs(quantity)
+		if quantity == 0 || entryPrice <= 0 {
+			continue
+		}
+		leverage := 10 // 默认值
+		if lev, ok := pos["leverage"].(float64); ok && lev > 0 {
+			leverage = int(lev)
+		}
+		active[symbol+"_"+side] = true
+
+		exitPos := exit.Position{
+			Symbol:     symbol,
+			Side:       side,
+			EntryPrice: entryPrice,
+			MarkPrice:  markPrice,
+			Leverage:   leverage,
+		}
+
+		// 更新峰值收益和最有利价格
+		at.UpdatePeakPnL(symbol, side, exitPos.PnLPct())
+		state := at.trackExitState(symbol, side, entryPrice, markPrice, now)
+		exitPos.PeakPnLPct = state.PeakPnLPct
+		exitPos.PeakPrice = state.PeakPrice
+		exitPos.InitialStop = state.InitialStop
+		exitPos.CurrentStop = state.CurrentStop
+		exitPos.OpenedAt = state.OpenedAt
+		if timeframe := at.exitEngine.ATRTimeframe(); timeframe != "" {
+			exitPos.ATR = currentATR(symbol, timeframe)
+		}
+
+		action := at.exitEngine.Evaluate(exitPos, now)
+		if action == nil {
+			continue
+		}
+
+		if action.Close {
+			log.Printf("🚨 触发退出策略平仓: %s %s | %s", symbol, side, action.Reason)
+			if err := at.emergencyClosePosition(symbol, side); err != nil {
+				log.Printf("❌ 退出策略平仓失败 (%s %s): %v", symbol, side, err)
+				continue
+			}
+			log.Printf("✅ 退出策略平仓成功: %s %s", symbol, side)
+			at.publishEvent(events.DrawdownClose, map[string]any{
+				"symbol": symbol, "side": side, "reason": action.Reason, "mark_price": markPrice,
+			})
+			at.ClearPeakPnLCache(symbol, side)
+			continue
+		}
+
+		at.moveStopLoss(symbol, side, quantity, state.CurrentStop, action)
+	}
+
+	at.pruneExitStates(active)
+}
+
+// moveStopLoss 用交易所原生止损单替换该方向的当前止损
+//
+// 只取消本方向的止损单（双向持仓时保留另一方向的止损）；新止损设置失败时（如价格已越过新止损被交易所拒绝）
+// 重新挂回原止损，避免持仓失去交易所止损保护
+func (at *AutoTrader) moveStopLoss(symbol, side string, quantity, currentStop float64, action *exit.Action) {
+	positionSide := strings.ToUpper(side)
+	if err := at.trader.CancelSideStopLossOrders(symbol, positionSide); err != nil {
+		log.Printf("  ⚠ 取消旧止损单失败 (%s %s): %v", symbol, side, err)
+		// 不中断执行，继续设置新止损
+	}
+	if err := at.trader.SetStopLoss(symbol, positionSide, quantity, action.StopPrice); err != nil {
+		log.Printf("❌ 退出策略移动止损失败 (%s %s): %v", symbol, side, err)
+		if currentStop <= 0 {
+			return
+		}
+		if err := at.trader.SetStopLoss(symbol, positionSide, quantity, currentStop); err != nil {
+			log.Printf("🚨 恢复原止损失败 (%s %s @ %.4f)，持仓当前没有交易所止损: %v", symbol, side, currentStop, err)
+			return
+		}
+		log.Printf("  ↩ 已恢复原止损: %s %s @ %.4f", symbol, side, currentStop)
+		return
+	}
+	log.Printf("🛡️ 退出策略移动止损: %s %s → %.4f | %s", symbol, side, action.StopPrice, action.Reason)
+	at.recordStopLoss(symbol, side, action.StopPrice, false)
+	at.publishEvent(events.StopMoved, map[string]any{
+		"symbol": symbol, "side": side, "stop_price": action.StopPrice, "reason": action.Reason,
+	})
+}
+
+// trackExitState 更新持仓退出状态（开仓价变化视为新持仓），返回状态副本
+func (at *AutoTrader) trackExitState(symbol, side string, entryPrice, markPrice float64, now time.Time) config.PositionExitState {
+	posKey := symbol + "_" + side
+
+	at.exitMu.Lock()
+	state := at.exitStates[posKey]
+	renewed := state != nil && state.EntryPrice > 0 && math.Abs(state.EntryPrice-entryPrice) > entryPrice*1e-6
+	at.exitMu.Unlock()
+
+	if renewed {
+		// 同方向重新开仓：旧的峰值和止损不再适用
+		at.ClearPeakPnLCache(symbol, side)
+		at.UpdatePeakPnL(symbol, side, exit.Position{Side: side, EntryPrice: entryPrice, MarkPrice: markPrice}.PnLPct())
+	}
+
+	at.peakPnLCacheMutex.RLock()
+	peakPnLPct := at.peakPnLCache[posKey]
+	at.peakPnLCacheMutex.RUnlock()
+
+	at.exitMu.Lock()
+	defer at.exitMu.Unlock()
+	state = at.exitStateLocked(symbol, side, now)
+	if state.EntryPrice == 0 {
+		state.EntryPrice = entryPrice
+	}
+	if state.PeakPrice == 0 ||
+		(side == "long" && markPrice > state.PeakPrice) ||
+		(side == "short" && markPrice < state.PeakPrice) {
+		state.PeakPrice = markPrice
+	}
+	state.PeakPnLPct = peakPnLPct
+	at.saveExitStateLocked(state)
+	return *state
+}
+
+// recordStopLoss 记录交易所止损价（开仓、AI调整止损或退出策略移动止损后调用）
+// initial=true 表示新开仓：重置该持仓的退出状态并记录初始止损（用于计算 R）
+func (at *AutoTrader) recordStopLoss(symbol, side string, stopPrice float64, initial bool) {
+	if initial {
+		at.ClearPeakPnLCache(symbol, side)
+	}
+
+	at.exitMu.Lock()
+	defer at.exitMu.Unlock()
+	state := at.exitStateLocked(symbol, side, time.Now())
+	if initial || state.InitialStop == 0 {
+		state.InitialStop = stopPrice
+	}
+	state.CurrentStop = stopPrice
+	at.saveExitStateLocked(state)
+}
+
+// pruneExitStates 清理已不存在的持仓的退出状态（如被交易所止损单平掉）
+func (at *AutoTrader) pruneExitStates(active map[string]bool) {
+	at.exitMu.Lock()
+	var closed []*config.PositionExitState
+	for posKey, state := range at.exitStates {
+		if !active[posKey] {
+			closed = append(closed, state)
+		}
+	}
+	at.exitMu.Unlock()
+
+	for _, state := range closed {
+		at.ClearPeakPnLCache(state.Symbol, state.Side)
+	}
+}
+
+// deleteExitState 删除持仓退出状态
+func (at *AutoTrader) deleteExitState(symbol, side string) {
+	at.exitMu.Lock()
+	_, exists := at.exitStates[symbol+"_"+side]
+	delete(at.exitStates, symbol+"_"+side)
+	at.exitMu.Unlock()
+
+	store := at.exitStore()
+	if store == nil || !exists {
+		return
+	}
+	if err := store.DeletePositionExitState(at.id, symbol, side); err != nil {
+		log.Printf("⚠️ [%s] 删除持仓退出状态失败 (%s %s): %v", at.name, symbol, side, err)
+	}
+}
+
+// exitStateLocked 获取或创建持仓退出状态（调用方需持有 exitMu）
+func (at *AutoTrader) exitStateLocked(symbol, side string, now time.Time) *config.PositionExitState {
+	if at.exitStates == nil {
+		at.exitStates = make(map[string]*config.PositionExitState)
+	}
+	posKey := symbol + "_" + side
+	state, exists := at.exitStates[posKey]
+	if !exists {
+		state = &config.PositionExitState{TraderID: at.id, Symbol: symbol, Side: side, OpenedAt: now}
+		at.exitStates[posKey] = state
+	}
+	return state
+}
+
+// saveExitStateLocked 持久化持仓退出状态（失败仅记录日志，调用方需持有 exitMu）
+func (at *AutoTrader) saveExitStateLocked(state *config.PositionExitState) {
+	store := at.exitStore()
+	if store == nil {
+		return
+	}
+	if err := store.SavePositionExitState(state); err != nil {
+		log.Printf("⚠️ [%s] 保存持仓退出状态失败 (%s %s): %v", at.name, state.Symbol, state.Side, err)
+	}
+}
+
+// currentATR 获取指定周期的 ATR14（失败返回0，ATR 移动止损本轮不生效）
+func currentATR(symbol, timeframe string) float64 {
+	data, err := market.Get(symbol)
+	if err != nil {
+		log.Printf("⚠️ 获取 %s ATR 失败: %v", symbol, err)
+		return 0
+	}
+	if timeframe == "3m" {
+		if data.IntradaySeries != nil {
+			return data.IntradaySeries.ATR14
+		}
+		return 0
+	}
+	if data.LongerTermContext != nil {
+		return data.LongerTermContext.ATR14
+	}
+	return 0
+}
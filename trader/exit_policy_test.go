@@ -0,0 +1,125 @@
+package trader
+
+import (
+	"nofx/config"
+	"nofx/exit"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryExitStore 内存持仓退出状态存储
+type memoryExitStore struct {
+	states map[string]config.PositionExitState
+}
+
+func (m *memoryExitStore) GetPositionExitStates(traderID string) ([]*config.PositionExitState, error) {
+	var states []*config.PositionExitState
+	for _, state := range m.states {
+		s := state
+		states = append(states, &s)
+	}
+	return states, nil
+}
+
+func (m *memoryExitStore) SavePositionExitState(state *config.PositionExitState) error {
+	m.states[state.Symbol+"_"+state.Side] = *state
+	return nil
+}
+
+func (m *memoryExitStore) DeletePositionExitState(traderID, symbol, side string) error {
+	delete(m.states, symbol+"_"+side)
+	return nil
+}
+
+func newExitTestTrader(cfg exit.Config, mock *MockTrader, store *memoryExitStore) *AutoTrader {
+	return &AutoTrader{
+		id:                    "exit_trader",
+		name:                  "Exit Trader",
+		trader:                mock,
+		positionFirstSeenTime: make(map[string]int64),
+		peakPnLCache:          make(map[string]float64),
+		database:              store,
+		exitEngine:            exit.NewEngine(cfg),
+	}
+}
+
+func TestCheckExitPolicies_TrailStopAndRestore(t *testing.T) {
+	store := &memoryExitStore{states: make(map[string]config.PositionExitState)}
+	mock := &MockTrader{}
+	cfg := exit.Config{BreakEven: &exit.BreakEvenConfig{TriggerR: 1}}
+	at := newExitTestTrader(cfg, mock, store)
+
+	// 开仓时记录初始止损
+	at.recordStopLoss("BTCUSDT", "long", 95, true)
+	require.Contains(t, store.states, "BTCUSDT_long")
+	assert.Equal(t, 95.0, store.states["BTCUSDT_long"].InitialStop)
+
+	// 浮盈未达 1R：不移动止损
+	mock.positions = []map[string]interface{}{
+		{"symbol": "BTCUSDT", "side": "long", "entryPrice": 100.0, "markPrice": 103.0, "positionAmt": 1.0, "leverage": 5.0},
+	}
+	at.checkExitPolicies()
+	assert.Equal(t, 95.0, store.states["BTCUSDT_long"].CurrentStop)
+
+	// 浮盈达到 1R：止损移至保本
+	mock.positions[0]["markPrice"] = 105.0
+	at.checkExitPolicies()
+	state := store.states["BTCUSDT_long"]
+	assert.Equal(t, 100.0, state.CurrentStop)
+	assert.Equal(t, 95.0, state.InitialStop)
+	assert.Equal(t, 105.0, state.PeakPrice)
+
+	// 重启后恢复峰值与止损
+	restored := newExitTestTrader(cfg, mock, store)
+	restored.loadExitStates()
+	assert.Equal(t, state.PeakPnLPct, restored.peakPnLCache["BTCUSDT_long"])
+	require.NotNil(t, restored.exitStates["BTCUSDT_long"])
+	assert.Equal(t, 100.0, restored.exitStates["BTCUSDT_long"].CurrentStop)
+
+	// 持仓被交易所止损单平掉后清理状态
+	mock.positions = nil
+	restored.checkExitPolicies()
+	assert.Empty(t, store.states)
+	assert.Empty(t, restored.peakPnLCache)
+}
+
+func TestCheckExitPolicies_NewPositionResetsState(t *testing.T) {
+	store := &memoryExitStore{states: make(map[string]config.PositionExitState)}
+	mock := &MockTrader{positions: []map[string]interface{}{
+		{"symbol": "ETHUSDT", "side": "short", "entryPrice": 3000.0, "markPrice": 2900.0, "positionAmt": -1.0, "leverage": 1.0},
+	}}
+	at := newExitTestTrader(exit.Config{}, mock, store)
+
+	at.checkExitPolicies()
+	assert.Equal(t, 2900.0, store.states["ETHUSDT_short"].PeakPrice)
+
+	// 开仓价变化视为新持仓，旧峰值不再沿用
+	mock.positions[0]["entryPrice"] = 3100.0
+	mock.positions[0]["markPrice"] = 3050.0
+	at.checkExitPolicies()
+	state := store.states["ETHUSDT_short"]
+	assert.Equal(t, 3100.0, state.EntryPrice)
+	assert.Equal(t, 3050.0, state.PeakPrice)
+	assert.InDelta(t, 1.6129, state.PeakPnLPct, 1e-3)
+}
+
+func TestCheckExitPolicies_RestoreStopWhenMoveRejected(t *testing.T) {
+	store := &memoryExitStore{states: make(map[string]config.PositionExitState)}
+	mock := &MockTrader{rejectStopPrice: 100}
+	at := newExitTestTrader(exit.Config{BreakEven: &exit.BreakEvenConfig{TriggerR: 1}}, mock, store)
+
+	require.NoError(t, mock.SetStopLoss("BTCUSDT", "LONG", 1, 95))
+	require.NoError(t, mock.SetStopLoss("BTCUSDT", "SHORT", 1, 110))
+	at.recordStopLoss("BTCUSDT", "long", 95, true)
+
+	// 保本止损被交易所拒绝：恢复原止损，另一方向的止损不受影响
+	mock.positions = []map[string]interface{}{
+		{"symbol": "BTCUSDT", "side": "long", "entryPrice": 100.0, "markPrice": 105.0, "positionAmt": 1.0, "leverage": 5.0},
+	}
+	at.checkExitPolicies()
+	assert.Equal(t, 95.0, mock.stopLosses["BTCUSDT_long"])
+	assert.Equal(t, 110.0, mock.stopLosses["BTCUSDT_short"])
+	assert.Equal(t, 95.0, store.states["BTCUSDT_long"].CurrentStop)
+}
@@ -0,0 +1,306 @@
+package trader
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"net/http"
+	"nofx/signer"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sonirico/go-hyperliquid"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// hyperliquidExchange HyperliquidTrader 使用的 Exchange 操作
+// 本地私钥直接使用 SDK 的 *hyperliquid.Exchange，外部签名器使用 signerExchange
+type hyperliquidExchange interface {
+	Info() *hyperliquid.Info
+	Order(ctx context.Context, req hyperliquid.CreateOrderRequest, builder *hyperliquid.BuilderInfo) (hyperliquid.OrderStatus, error)
+	Cancel(ctx context.Context, coin string, oid int64) (*hyperliquid.APIResponse[hyperliquid.CancelOrderResponse], error)
+	UpdateLeverage(ctx context.Context, leverage int, name string, isCross bool) (*hyperliquid.UserState, error)
+}
+
+// signerExchange 通过 signer.Signer 签名 L1 action 的 Exchange 实现（SDK 只接受原始私钥）
+// action 的 msgpack 字段顺序与 SDK / Python SDK 保持一致
+type signerExchange struct {
+	info      *hyperliquid.Info
+	signer    signer.Signer
+	apiURL    string
+	isMainnet bool
+	client    *http.Client
+	lastNonce atomic.Int64
+}
+
+func newSignerExchange(ctx context.Context, s signer.Signer, apiURL string) *signerExchange {
+	return &signerExchange{
+		info:      hyperliquid.NewInfo(ctx, apiURL, true, nil, nil),
+		signer:    s,
+		apiURL:    apiURL,
+		isMainnet: apiURL == hyperliquid.MainnetAPIURL,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// hlLimit / hlTrigger / hlOrderType / hlOrderWire / hlOrderAction 与 SDK 的下单 wire 格式一致
+type hlLimit struct {
+	Tif hyperliquid.Tif `json:"tif" msgpack:"tif"`
+}
+
+type hlTrigger struct {
+	IsMarket  bool             `json:"isMarket"  msgpack:"isMarket"`
+	TriggerPx string           `json:"triggerPx" msgpack:"triggerPx"`
+	Tpsl      hyperliquid.Tpsl `json:"tpsl"      msgpack:"tpsl"`
+}
+
+type hlOrderType struct {
+	Limit   *hlLimit   `json:"limit,omitempty"   msgpack:"limit,omitempty"`
+	Trigger *hlTrigger `json:"trigger,omitempty" msgpack:"trigger,omitempty"`
+}
+
+type hlOrderWire struct {
+	Asset      int         `json:"a"           msgpack:"a"`
+	IsBuy      bool        `json:"b"           msgpack:"b"`
+	LimitPx    string      `json:"p"           msgpack:"p"`
+	Size       string      `json:"s"           msgpack:"s"`
+	ReduceOnly bool        `json:"r"           msgpack:"r"`
+	OrderType  hlOrderType `json:"t"           msgpack:"t"`
+	Cloid      *string     `json:"c,omitempty" msgpack:"c,omitempty"`
+}
+
+type hlOrderAction struct {
+	Type     string        `json:"type"     msgpack:"type"`
+	Orders   []hlOrderWire `json:"orders"   msgpack:"orders"`
+	Grouping string        `json:"grouping" msgpack:"grouping"`
+}
+
+func (e *signerExchange) Info() *hyperliquid.Info { return e.info }
+
+// Order 下单（不支持 builder fee）
+func (e *signerExchange) Order(ctx context.Context, req hyperliquid.CreateOrderRequest, builder *hyperliquid.BuilderInfo) (hyperliquid.OrderStatus, error) {
+	if builder != nil {
+		return hyperliquid.OrderStatus{}, fmt.Errorf("外部签名器暂不支持 builder fee")
+	}
+	if req.ClientOrderID != nil && *req.ClientOrderID != "" {
+		return hyperliquid.OrderStatus{}, fmt.Errorf("外部签名器暂不支持 client order id")
+	}
+
+	price, err := hlFloatToWire(req.Price)
+	if err != nil {
+		return hyperliquid.OrderStatus{}, err
+	}
+	size, err := hlFloatToWire(req.Size)
+	if err != nil {
+		return hyperliquid.OrderStatus{}, err
+	}
+	wire := hlOrderWire{
+		Asset:      e.info.NameToAsset(req.Coin),
+		IsBuy:      req.IsBuy,
+		LimitPx:    price,
+		Size:       size,
+		ReduceOnly: req.ReduceOnly,
+	}
+	switch {
+	case req.OrderType.Limit != nil:
+		wire.OrderType.Limit = &hlLimit{Tif: req.OrderType.Limit.Tif}
+	case req.OrderType.Trigger != nil:
+		triggerPx, err := hlFloatToWire(req.OrderType.Trigger.TriggerPx)
+		if err != nil {
+			return hyperliquid.OrderStatus{}, err
+		}
+		wire.OrderType.Trigger = &hlTrigger{
+			IsMarket:  req.OrderType.Trigger.IsMarket,
+			TriggerPx: triggerPx,
+			Tpsl:      req.OrderType.Trigger.Tpsl,
+		}
+	}
+
+	action := hlOrderAction{Type: "order", Orders: []hlOrderWire{wire}, Grouping: string(hyperliquid.GroupingNA)}
+	var resp hyperliquid.APIResponse[hyperliquid.OrderResponse]
+	if err := e.executeAction(ctx, action, &resp); err != nil {
+		return hyperliquid.OrderStatus{}, err
+	}
+	if !resp.Ok {
+		return hyperliquid.OrderStatus{}, fmt.Errorf("failed to create order: %s", resp.Err)
+	}
+	if len(resp.Data.Statuses) == 0 {
+		return hyperliquid.OrderStatus{}, fmt.Errorf("no status for order: %s", resp.Err)
+	}
+	status := resp.Data.Statuses[0]
+	if status.Error != nil {
+		return status, fmt.Errorf("%s", *status.Error)
+	}
+	return status, nil
+}
+
+// Cancel 撤单
+func (e *signerExchange) Cancel(ctx context.Context, coin string, oid int64) (*hyperliquid.APIResponse[hyperliquid.CancelOrderResponse], error) {
+	action := hyperliquid.CancelAction{
+		Type:    "cancel",
+		Cancels: []hyperliquid.CancelOrderWire{{Asset: e.info.NameToAsset(coin), OrderID: oid}},
+	}
+	var resp hyperliquid.APIResponse[hyperliquid.CancelOrderResponse]
+	if err := e.executeAction(ctx, action, &resp); err != nil {
+		return nil, err
+	}
+	if !resp.Ok || resp.Status == "err" {
+		if resp.Err != "" {
+			return &resp, fmt.Errorf("%s", resp.Err)
+		}
+		return &resp, fmt.Errorf("cancel failed")
+	}
+	if err := resp.Data.Statuses.FirstError(); err != nil {
+		return &resp, err
+	}
+	return &resp, nil
+}
+
+// UpdateLeverage 设置杠杆
+func (e *signerExchange) UpdateLeverage(ctx context.Context, leverage int, name string, isCross bool) (*hyperliquid.UserState, error) {
+	action := hyperliquid.UpdateLeverageAction{
+		Type:     "updateLeverage",
+		Asset:    e.info.NameToAsset(name),
+		IsCross:  isCross,
+		Leverage: leverage,
+	}
+	var result hyperliquid.UserState
+	if err := e.executeAction(ctx, action, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// executeAction 签名并提交 action 到 /exchange
+func (e *signerExchange) executeAction(ctx context.Context, action, result any) error {
+	nonce := e.nextNonce()
+	sig, err := signL1Action(e.signer, action, nonce, e.isMainnet)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(map[string]any{"action": action, "nonce": nonce, "signature": sig})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.apiURL+"/exchange", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+	}
+	return json.Unmarshal(body, result)
+}
+
+// nextNonce 毫秒时间戳，保证单调递增
+func (e *signerExchange) nextNonce() int64 {
+	for {
+		last := e.lastNonce.Load()
+		candidate := time.Now().UnixMilli()
+		if candidate <= last {
+			candidate = last + 1
+		}
+		if e.lastNonce.CompareAndSwap(last, candidate) {
+			return candidate
+		}
+	}
+}
+
+// signL1Action 按 Hyperliquid L1 action 规则生成 EIP-712 Agent 消息并交给签名器签名
+func signL1Action(s signer.Signer, action any, nonce int64, isMainnet bool) (hyperliquid.SignatureResult, error) {
+	payload, err := l1ActionPayload(action, nonce, isMainnet)
+	if err != nil {
+		return hyperliquid.SignatureResult{}, err
+	}
+	sig, err := signer.SignPayload(s, payload)
+	if err != nil {
+		return hyperliquid.SignatureResult{}, fmt.Errorf("签名失败: %w", err)
+	}
+	if len(sig) != crypto.SignatureLength {
+		return hyperliquid.SignatureResult{}, fmt.Errorf("签名长度异常: %d", len(sig))
+	}
+	return hyperliquid.SignatureResult{
+		R: hexutil.EncodeBig(new(big.Int).SetBytes(sig[:32])),
+		S: hexutil.EncodeBig(new(big.Int).SetBytes(sig[32:64])),
+		V: int(sig[64]) + 27,
+	}, nil
+}
+
+// l1ActionPayload 生成 phantom agent 的签名载荷（EIP-712 摘要由签名器计算）
+// connectionId = keccak256(msgpack(action) || nonce(8字节) || 0x00)，不支持 vault 地址和 expiresAfter
+func l1ActionPayload(action any, nonce int64, isMainnet bool) (signer.Payload, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.UseCompactInts(true)
+	if err := enc.Encode(action); err != nil {
+		return signer.Payload{}, fmt.Errorf("msgpack 编码 action 失败: %w", err)
+	}
+	data := msgpackStr16ToStr8(buf.Bytes())
+	data = binary.BigEndian.AppendUint64(data, uint64(nonce))
+	data = append(data, 0x00)
+
+	source := "b"
+	if isMainnet {
+		source = "a"
+	}
+	return signer.Payload{
+		Type: signer.PayloadHyperliquidAgent,
+		HyperliquidAgent: &signer.HyperliquidAgent{
+			Source:       source,
+			ConnectionID: crypto.Keccak256Hash(data),
+		},
+	}, nil
+}
+
+// msgpackStr16ToStr8 将长度小于 256 的 str16 转为 str8，与 Python msgpack 编码一致
+func msgpackStr16ToStr8(data []byte) []byte {
+	result := make([]byte, 0, len(data))
+	for i := 0; i < len(data); {
+		if data[i] == 0xda && i+2 < len(data) {
+			length := int(data[i+1])<<8 | int(data[i+2])
+			if length < 256 && i+3+length <= len(data) {
+				result = append(result, 0xd9, byte(length))
+				result = append(result, data[i+3:i+3+length]...)
+				i += 3 + length
+				continue
+			}
+		}
+		result = append(result, data[i])
+		i++
+	}
+	return result
+}
+
+// hlFloatToWire 价格/数量转为 Hyperliquid wire 格式（最多 8 位小数，去掉末尾 0）
+func hlFloatToWire(x float64) (string, error) {
+	rounded := strconv.FormatFloat(x, 'f', 8, 64)
+	if parsed, _ := strconv.ParseFloat(rounded, 64); math.Abs(parsed-x) >= 1e-12 {
+		return "", fmt.Errorf("float_to_wire causes rounding: %f", x)
+	}
+	if rounded == "-0.00000000" {
+		rounded = "0.00000000"
+	}
+	return strings.TrimRight(strings.TrimRight(rounded, "0"), "."), nil
+}
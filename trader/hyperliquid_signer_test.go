@@ -0,0 +1,105 @@
+package trader
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"nofx/signer"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sonirico/go-hyperliquid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSignL1Action_MatchesSDK 外部签名器生成的签名应与 SDK 使用原始私钥的签名一致
+func TestSignL1Action_MatchesSDK(t *testing.T) {
+	privateKey, err := crypto.HexToECDSA("4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318")
+	require.NoError(t, err)
+	local := signer.NewLocalSigner(privateKey)
+
+	actions := []any{
+		hlOrderAction{
+			Type: "order",
+			Orders: []hlOrderWire{{
+				Asset: 0, IsBuy: true, LimitPx: "65000.5", Size: "0.012",
+				OrderType: hlOrderType{Limit: &hlLimit{Tif: hyperliquid.TifIoc}},
+			}},
+			Grouping: "na",
+		},
+		hlOrderAction{
+			Type: "order",
+			Orders: []hlOrderWire{{
+				Asset: 1, IsBuy: false, LimitPx: "3100", Size: "1.5", ReduceOnly: true,
+				OrderType: hlOrderType{Trigger: &hlTrigger{IsMarket: true, TriggerPx: "3100", Tpsl: hyperliquid.StopLoss}},
+			}},
+			Grouping: "na",
+		},
+		hyperliquid.CancelAction{Type: "cancel", Cancels: []hyperliquid.CancelOrderWire{{Asset: 0, OrderID: 123456}}},
+		hyperliquid.UpdateLeverageAction{Type: "updateLeverage", Asset: 3, IsCross: true, Leverage: 10},
+	}
+
+	for _, isMainnet := range []bool{true, false} {
+		for _, action := range actions {
+			nonce := int64(1700000000123)
+			want, err := hyperliquid.SignL1Action(privateKey, action, "", nonce, nil, isMainnet)
+			require.NoError(t, err)
+			got, err := signL1Action(local, action, nonce, isMainnet)
+			require.NoError(t, err)
+			assert.Equal(t, want, got, "action=%+v mainnet=%v", action, isMainnet)
+		}
+	}
+}
+
+// remoteOnlySigner 不暴露私钥的签名器（模拟远程签名服务）
+type remoteOnlySigner struct{ signer.Signer }
+
+// TestSignerExchange_Order 外部签名器下单时提交 action 和签名到 /exchange
+func TestSignerExchange_Order(t *testing.T) {
+	privateKey, _ := crypto.GenerateKey()
+	local := signer.NewLocalSigner(privateKey)
+
+	var posted map[string]json.RawMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case r.URL.Path == "/info" && strings.Contains(string(body), `"spotMeta"`):
+			w.Write([]byte(`{"universe":[],"tokens":[]}`))
+		case r.URL.Path == "/info":
+			w.Write([]byte(`{"universe":[{"name":"BTC","szDecimals":4},{"name":"ETH","szDecimals":3}],"marginTables":[]}`))
+		case r.URL.Path == "/exchange":
+			json.Unmarshal(body, &posted)
+			w.Write([]byte(`{"status":"ok","response":{"type":"order","data":{"statuses":[{"resting":{"oid":42}}]}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	exchange := newSignerExchange(context.Background(), remoteOnlySigner{local}, server.URL)
+	status, err := exchange.Order(context.Background(), hyperliquid.CreateOrderRequest{
+		Coin: "ETH", IsBuy: true, Price: 3000, Size: 0.5,
+		OrderType: hyperliquid.OrderType{Limit: &hyperliquid.LimitOrderType{Tif: hyperliquid.TifGtc}},
+	}, nil)
+	require.NoError(t, err)
+	require.NotNil(t, status.Resting)
+	assert.Equal(t, int64(42), status.Resting.Oid)
+
+	assert.JSONEq(t, `{"type":"order","orders":[{"a":1,"b":true,"p":"3000","s":"0.5","r":false,"t":{"limit":{"tif":"Gtc"}}}],"grouping":"na"}`, string(posted["action"]))
+	var nonce int64
+	require.NoError(t, json.Unmarshal(posted["nonce"], &nonce))
+	var sig hyperliquid.SignatureResult
+	require.NoError(t, json.Unmarshal(posted["signature"], &sig))
+	want, err := hyperliquid.SignL1Action(privateKey, hlOrderAction{
+		Type: "order",
+		Orders: []hlOrderWire{{Asset: 1, IsBuy: true, LimitPx: "3000", Size: "0.5",
+			OrderType: hlOrderType{Limit: &hlLimit{Tif: hyperliquid.TifGtc}}}},
+		Grouping: "na",
+	}, "", nonce, nil, false)
+	require.NoError(t, err)
+	assert.Equal(t, want, sig)
+}
@@ -1,23 +1,27 @@
 package trader
 
 import (
+	"bytes"
 	"context"
 	"crypto/ecdsa"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
+	"nofx/signer"
 	"strconv"
 	"strings"
 	"sync"
 
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/sonirico/go-hyperliquid"
 )
 
 // HyperliquidTrader Hyperliquid交易器
 type HyperliquidTrader struct {
-	exchange      *hyperliquid.Exchange
+	exchange      hyperliquidExchange
 	ctx           context.Context
+	apiURL        string
 	walletAddr    string
 	meta          *hyperliquid.Meta // 缓存meta信息（包含精度等）
 	metaMutex     sync.RWMutex      // 保护meta字段的并发访问
@@ -25,14 +29,11 @@ type HyperliquidTrader struct {
 }
 
 // NewHyperliquidTrader 创建Hyperliquid交易器
+// privateKeyHex 可以是十六进制私钥，也可以是 remote:0x<地址> / keystore:<路径> 形式的外部签名器
 func NewHyperliquidTrader(privateKeyHex string, walletAddr string, testnet bool) (*HyperliquidTrader, error) {
-	// 去掉私钥的 0x 前缀（如果有，不区分大小写）
-	privateKeyHex = strings.TrimPrefix(strings.ToLower(privateKeyHex), "0x")
-
-	// 解析私钥
-	privateKey, err := crypto.HexToECDSA(privateKeyHex)
+	keySigner, err := signer.New(privateKeyHex)
 	if err != nil {
-		return nil, fmt.Errorf("解析私钥失败: %w", err)
+		return nil, fmt.Errorf("创建签名器失败: %w", err)
 	}
 
 	// 选择API URL
@@ -43,7 +44,7 @@ func NewHyperliquidTrader(privateKeyHex string, walletAddr string, testnet bool)
 
 	// Security enhancement: Implement Agent Wallet best practices
 	// Reference: https://hyperliquid.gitbook.io/hyperliquid-docs/for-developers/api/nonces-and-api-wallets
-	agentAddr := crypto.PubkeyToAddress(*privateKey.Public().(*ecdsa.PublicKey)).Hex()
+	agentAddr := keySigner.Address().Hex()
 
 	if walletAddr == "" {
 		return nil, fmt.Errorf("❌ Configuration error: Main wallet address (hyperliquid_wallet_addr) not provided\n" +
@@ -69,15 +70,22 @@ func NewHyperliquidTrader(privateKeyHex string, walletAddr string, testnet bool)
 	ctx := context.Background()
 
 	// 创建Exchange客户端（Exchange包含Info功能）
-	exchange := hyperliquid.NewExchange(
-		ctx,
-		privateKey,
-		apiURL,
-		nil,        // Meta will be fetched automatically
-		"",         // vault address (empty for personal account)
-		walletAddr, // wallet address
-		nil,        // SpotMeta will be fetched automatically
-	)
+	// 进程内持有私钥时使用 SDK，远程签名器只传递待签名哈希
+	var exchange hyperliquidExchange
+	if local, ok := keySigner.(interface{ PrivateKey() *ecdsa.PrivateKey }); ok {
+		exchange = hyperliquid.NewExchange(
+			ctx,
+			local.PrivateKey(),
+			apiURL,
+			nil,        // Meta will be fetched automatically
+			"",         // vault address (empty for personal account)
+			walletAddr, // wallet address
+			nil,        // SpotMeta will be fetched automatically
+		)
+	} else {
+		log.Printf("✓ 使用外部签名器: %s", agentAddr)
+		exchange = newSignerExchange(ctx, keySigner, apiURL)
+	}
 
 	log.Printf("✓ Hyperliquid交易器初始化成功 (testnet=%v, wallet=%s)", testnet, walletAddr)
 
@@ -123,6 +131,7 @@ func NewHyperliquidTrader(privateKeyHex string, walletAddr string, testnet bool)
 	return &HyperliquidTrader{
 		exchange:      exchange,
 		ctx:           ctx,
+		apiURL:        apiURL,
 		walletAddr:    walletAddr,
 		meta:          meta,
 		isCrossMargin: true, // 默认使用全仓模式
@@ -633,6 +642,113 @@ func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (map[str
 
 // CancelStopOrders 取消该币种的止盈/止
 
+// hyperliquidTifs 限价单有效方式映射（GTX 对应 Hyperliquid 的 Alo：只做 Maker）
+var hyperliquidTifs = map[string]hyperliquid.Tif{
+	TimeInForceGTC:      hyperliquid.TifGtc,
+	TimeInForceIOC:      hyperliquid.TifIoc,
+	TimeInForcePostOnly: hyperliquid.TifAlo,
+}
+
+// PlaceLimitOrder 限价开仓（不取消已有挂单）
+func (t *HyperliquidTrader) PlaceLimitOrder(symbol, side string, quantity, price float64, leverage int, timeInForce string) (map[string]interface{}, error) {
+	tif, ok := hyperliquidTifs[timeInForce]
+	if !ok {
+		return nil, fmt.Errorf("不支持的限价单有效方式: %s", timeInForce)
+	}
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+
+	coin := convertSymbolToHyperliquid(symbol)
+	roundedQuantity := t.roundToSzDecimals(coin, quantity)
+	if roundedQuantity <= 0 {
+		return nil, fmt.Errorf("开仓数量过小，格式化后为 0 (原始: %.8f)", quantity)
+	}
+	roundedPrice := t.roundPriceToSigfigs(price)
+
+	status, err := t.exchange.Order(t.ctx, hyperliquid.CreateOrderRequest{
+		Coin:  coin,
+		IsBuy: side == "long",
+		Size:  roundedQuantity,
+		Price: roundedPrice,
+		OrderType: hyperliquid.OrderType{
+			Limit: &hyperliquid.LimitOrderType{Tif: tif},
+		},
+		ReduceOnly: false,
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("限价开仓失败: %w", err)
+	}
+	if status.Error != nil {
+		return nil, fmt.Errorf("限价开仓失败: %s", *status.Error)
+	}
+
+	result := make(map[string]interface{})
+	result["symbol"] = symbol
+	result["quantity"] = roundedQuantity
+	switch {
+	case status.Resting != nil:
+		result["orderId"] = status.Resting.Oid
+		result["status"] = OrderStatusNew
+	case status.Filled != nil:
+		result["orderId"] = int64(status.Filled.Oid)
+		result["status"] = OrderStatusFilled
+	default:
+		return nil, fmt.Errorf("限价开仓失败: 未返回订单状态")
+	}
+
+	log.Printf("✓ 限价单已提交: %s %s 数量: %.4f 价格: %.4f (%s)", symbol, side, roundedQuantity, roundedPrice, timeInForce)
+	return result, nil
+}
+
+// GetOrderStatus 查询订单状态（成交均价按限价估算）
+func (t *HyperliquidTrader) GetOrderStatus(symbol string, orderID int64) (map[string]interface{}, error) {
+	res, err := t.exchange.Info().QueryOrderByOid(t.ctx, t.walletAddr, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+	if res.Status != hyperliquid.OrderQueryStatusSuccess {
+		return nil, fmt.Errorf("订单 %d 不存在", orderID)
+	}
+
+	order := res.Order.Order
+	origSz, _ := strconv.ParseFloat(order.OrigSz, 64)
+	remainingSz, _ := strconv.ParseFloat(order.Sz, 64)
+	limitPx, _ := strconv.ParseFloat(order.LimitPx, 64)
+	executedQty := origSz - remainingSz
+
+	status := OrderStatusCanceled
+	switch value := string(res.Order.Status); {
+	case value == string(hyperliquid.OrderStatusValueOpen):
+		status = OrderStatusNew
+		if executedQty > 0 {
+			status = OrderStatusPartiallyFilled
+		}
+	case value == string(hyperliquid.OrderStatusValueFilled):
+		status = OrderStatusFilled
+		executedQty = origSz
+	case strings.HasSuffix(value, "Rejected"):
+		status = OrderStatusRejected
+	}
+
+	result := make(map[string]interface{})
+	result["orderId"] = orderID
+	result["symbol"] = symbol
+	result["status"] = status
+	result["executedQty"] = executedQty
+	result["avgPrice"] = limitPx
+	return result, nil
+}
+
+// CancelOrder 撤销指定订单
+func (t *HyperliquidTrader) CancelOrder(symbol string, orderID int64) error {
+	if _, err := t.exchange.Cancel(t.ctx, convertSymbolToHyperliquid(symbol), orderID); err != nil {
+		return fmt.Errorf("撤销订单失败: %w", err)
+	}
+	log.Printf("  ✓ 已撤销 %s 订单 %d", symbol, orderID)
+	return nil
+}
+
 // CancelStopLossOrders 仅取消止损单（Hyperliquid 暂无法区分止损和止盈，取消所有）
 func (t *HyperliquidTrader) CancelStopLossOrders(symbol string) error {
 	// Hyperliquid SDK 的 OpenOrder 结构不暴露 trigger 字段
@@ -641,6 +757,11 @@ func (t *HyperliquidTrader) CancelStopLossOrders(symbol string) error {
 	return t.CancelStopOrders(symbol)
 }
 
+// CancelSideStopLossOrders 取消指定持仓方向的止损单（Hyperliquid 为单向持仓，等同于 CancelStopLossOrders）
+func (t *HyperliquidTrader) CancelSideStopLossOrders(symbol string, positionSide string) error {
+	return t.CancelStopLossOrders(symbol)
+}
+
 // CancelTakeProfitOrders 仅取消止盈单（Hyperliquid 暂无法区分止损和止盈，取消所有）
 func (t *HyperliquidTrader) CancelTakeProfitOrders(symbol string) error {
 	// Hyperliquid SDK 的 OpenOrder 结构不暴露 trigger 字段
@@ -887,6 +1008,109 @@ func (t *HyperliquidTrader) roundPriceToSigfigs(price float64) float64 {
 	return rounded
 }
 
+// GetTrades 获取成交明细
+func (t *HyperliquidTrader) GetTrades(startTime int64) ([]TradeFill, error) {
+	hlFills, err := t.exchange.Info().UserFillsByTime(t.ctx, t.walletAddr, startTime, nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取成交记录失败: %w", err)
+	}
+
+	fills := make([]TradeFill, 0, len(hlFills))
+	for _, f := range hlFills {
+		// Dir 形如 "Open Long" / "Close Short"，现货等其他方向忽略
+		var positionSide string
+		switch {
+		case strings.HasSuffix(f.Dir, "Long"):
+			positionSide = "LONG"
+		case strings.HasSuffix(f.Dir, "Short"):
+			positionSide = "SHORT"
+		default:
+			continue
+		}
+		side := "SELL"
+		if f.Side == "B" {
+			side = "BUY"
+		}
+
+		price, _ := strconv.ParseFloat(f.Price, 64)
+		size, _ := strconv.ParseFloat(f.Size, 64)
+		fee, _ := strconv.ParseFloat(f.Fee, 64)
+		closedPnL, _ := strconv.ParseFloat(f.ClosedPnl, 64)
+		fills = append(fills, TradeFill{
+			ID:           strconv.FormatInt(f.Tid, 10),
+			OrderID:      strconv.FormatInt(f.Oid, 10),
+			Symbol:       f.Coin + "USDT",
+			Side:         side,
+			PositionSide: positionSide,
+			Price:        price,
+			Quantity:     size,
+			Fee:          fee,
+			FeeAsset:     f.FeeToken,
+			RealizedPnL:  closedPnL,
+			Time:         f.Time,
+		})
+	}
+	return fills, nil
+}
+
+// GetFundingPayments 获取资金费收支
+// SDK 的 UserFundingHistory 未解析 delta 字段，这里直接请求 /info 接口
+func (t *HyperliquidTrader) GetFundingPayments(startTime int64) ([]FundingPayment, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"type":      "userFunding",
+		"user":      t.walletAddr,
+		"startTime": startTime,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(t.ctx, http.MethodPost, t.apiURL+"/info", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("获取资金费记录失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取资金费记录失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取资金费记录失败 (status %d): %s", resp.StatusCode, string(respBody))
+	}
+
+	var records []struct {
+		Time  int64  `json:"time"`
+		Hash  string `json:"hash"`
+		Delta struct {
+			Coin string `json:"coin"`
+			USDC string `json:"usdc"`
+		} `json:"delta"`
+	}
+	if err := json.Unmarshal(respBody, &records); err != nil {
+		return nil, fmt.Errorf("解析资金费记录失败: %w", err)
+	}
+
+	payments := make([]FundingPayment, 0, len(records))
+	for _, r := range records {
+		amount, _ := strconv.ParseFloat(r.Delta.USDC, 64)
+		payments = append(payments, FundingPayment{
+			// 同一笔结算哈希可能覆盖多个币种
+			ID:     r.Hash + ":" + r.Delta.Coin,
+			Symbol: r.Delta.Coin + "USDT",
+			Amount: amount,
+			Time:   r.Time,
+		})
+	}
+	return payments, nil
+}
+
 // convertSymbolToHyperliquid 将标准symbol转换为Hyperliquid格式
 // 例如: "BTCUSDT" -> "BTC"
 func convertSymbolToHyperliquid(symbol string) string {
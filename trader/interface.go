@@ -39,6 +39,9 @@ type Trader interface {
 	// CancelStopLossOrders 仅取消止损单（修复 BUG：调整止损时不删除止盈）
 	CancelStopLossOrders(symbol string) error
 
+	// CancelSideStopLossOrders 仅取消指定持仓方向（LONG/SHORT）的止损单，双向持仓时不影响另一方向
+	CancelSideStopLossOrders(symbol string, positionSide string) error
+
 	// CancelTakeProfitOrders 仅取消止盈单（修复 BUG：调整止盈时不删除止损）
 	CancelTakeProfitOrders(symbol string) error
 
@@ -50,4 +53,70 @@ type Trader interface {
 
 	// FormatQuantity 格式化数量到正确的精度
 	FormatQuantity(symbol string, quantity float64) (string, error)
+
+	// GetTrades 获取 startTime（毫秒）之后的成交明细（含手续费和已实现盈亏）
+	GetTrades(startTime int64) ([]TradeFill, error)
+
+	// GetFundingPayments 获取 startTime（毫秒）之后的资金费收支
+	GetFundingPayments(startTime int64) ([]FundingPayment, error)
+
+	// PlaceLimitOrder 限价开仓（side: long/short，timeInForce: GTC/IOC/GTX，GTX 为只做 Maker）
+	// 返回的 orderId 为 int64，可用于 GetOrderStatus / CancelOrder
+	PlaceLimitOrder(symbol, side string, quantity, price float64, leverage int, timeInForce string) (map[string]interface{}, error)
+
+	// GetOrderStatus 查询订单状态（status/executedQty/avgPrice，状态取值见 OrderStatus* 常量）
+	GetOrderStatus(symbol string, orderID int64) (map[string]interface{}, error)
+
+	// CancelOrder 撤销指定订单
+	CancelOrder(symbol string, orderID int64) error
+}
+
+// 限价单有效方式
+const (
+	TimeInForceGTC      = "GTC" // 一直有效直到撤销
+	TimeInForceIOC      = "IOC" // 立即成交，未成交部分撤销
+	TimeInForcePostOnly = "GTX" // 只做 Maker，会立即成交时直接拒绝
+)
+
+// 订单状态（各交易所统一映射为币安的取值）
+const (
+	OrderStatusNew             = "NEW"
+	OrderStatusPartiallyFilled = "PARTIALLY_FILLED"
+	OrderStatusFilled          = "FILLED"
+	OrderStatusCanceled        = "CANCELED"
+	OrderStatusRejected        = "REJECTED"
+	OrderStatusExpired         = "EXPIRED"
+)
+
+// IsOrderOpen 订单是否仍在挂单中
+func IsOrderOpen(status string) bool {
+	return status == OrderStatusNew || status == OrderStatusPartiallyFilled
+}
+
+// TradeFill 交易所成交明细
+type TradeFill struct {
+	ID           string  `json:"id"`            // 成交ID（交易所内唯一）
+	OrderID      string  `json:"order_id"`      // 订单ID
+	Symbol       string  `json:"symbol"`        // 币种，如 BTCUSDT
+	Side         string  `json:"side"`          // BUY/SELL
+	PositionSide string  `json:"position_side"` // LONG/SHORT
+	Price        float64 `json:"price"`         // 成交价
+	Quantity     float64 `json:"quantity"`      // 成交数量
+	Fee          float64 `json:"fee"`           // 手续费（正数表示支出）
+	FeeAsset     string  `json:"fee_asset"`     // 手续费币种
+	RealizedPnL  float64 `json:"realized_pnl"`  // 交易所计算的已实现盈亏（不含手续费，开仓为0）
+	Time         int64   `json:"time"`          // 成交时间（毫秒）
+}
+
+// IsOpen 是否为开仓成交（买入开多 / 卖出开空）
+func (f TradeFill) IsOpen() bool {
+	return (f.PositionSide == "LONG") == (f.Side == "BUY")
+}
+
+// FundingPayment 资金费收支
+type FundingPayment struct {
+	ID     string  `json:"id"`     // 流水ID（交易所内唯一）
+	Symbol string  `json:"symbol"` // 币种
+	Amount float64 `json:"amount"` // 金额（正数为收入，负数为支出）
+	Time   int64   `json:"time"`   // 结算时间（毫秒）
 }
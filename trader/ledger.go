@@ -0,0 +1,121 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/config"
+	"nofx/logger"
+	"time"
+)
+
+const (
+	ledgerSyncOverlap   = 10 * time.Minute   // 增量同步时向前重叠的时间（避免漏掉延迟入账的成交）
+	ledgerMaxLookback   = 7 * 24 * time.Hour // 最多向前同步的时间（币安成交接口单次查询上限7天）
+	ledgerRecordsFactor = 3                  // 账本分析时读取的决策记录倍数（用于补充杠杆等信息）
+)
+
+// ledgerStore 成交账本存储（由 config.Database 实现）
+type ledgerStore interface {
+	SaveLedgerEntries(traderID string, entries []*config.LedgerEntry) (int, error)
+	GetLedgerEntries(traderID string, since time.Time) ([]*config.LedgerEntry, error)
+	GetLatestLedgerTime(traderID string) (time.Time, error)
+}
+
+// ledger 返回可用的账本存储（数据库未实现时返回 nil）
+func (at *AutoTrader) ledger() ledgerStore {
+	store, _ := at.database.(ledgerStore)
+	return store
+}
+
+// syncLedger 从交易所增量同步成交和资金费到账本
+func (at *AutoTrader) syncLedger() error {
+	store := at.ledger()
+	if store == nil {
+		return nil
+	}
+
+	latest, err := store.GetLatestLedgerTime(at.id)
+	if err != nil {
+		return fmt.Errorf("读取账本同步位置失败: %w", err)
+	}
+	since := latest.Add(-ledgerSyncOverlap)
+	if earliest := time.Now().Add(-ledgerMaxLookback); since.Before(earliest) {
+		since = earliest
+	}
+
+	fills, err := at.trader.GetTrades(since.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("获取成交记录失败: %w", err)
+	}
+	payments, err := at.trader.GetFundingPayments(since.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("获取资金费记录失败: %w", err)
+	}
+
+	entries := make([]*config.LedgerEntry, 0, len(fills)+len(payments))
+	for _, f := range fills {
+		entries = append(entries, &config.LedgerEntry{
+			EntryType:    config.LedgerEntryTrade,
+			ExchangeID:   f.ID,
+			OrderID:      f.OrderID,
+			Symbol:       f.Symbol,
+			Side:         f.Side,
+			PositionSide: f.PositionSide,
+			Price:        f.Price,
+			Quantity:     f.Quantity,
+			Fee:          f.Fee,
+			FeeAsset:     f.FeeAsset,
+			RealizedPnL:  f.RealizedPnL,
+			Time:         time.UnixMilli(f.Time),
+		})
+	}
+	for _, p := range payments {
+		entries = append(entries, &config.LedgerEntry{
+			EntryType:  config.LedgerEntryFunding,
+			ExchangeID: p.ID,
+			Symbol:     p.Symbol,
+			Amount:     p.Amount,
+			Time:       time.UnixMilli(p.Time),
+		})
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	inserted, err := store.SaveLedgerEntries(at.id, entries)
+	if err != nil {
+		return err
+	}
+	if inserted > 0 {
+		log.Printf("📒 账本同步: 新增 %d 条成交/资金费记录", inserted)
+	}
+	return nil
+}
+
+// AnalyzePerformance 分析最近N个周期的交易表现
+// 优先使用交易所成交账本（含手续费和资金费），账本为空时回退到根据决策日志推断
+func (at *AutoTrader) AnalyzePerformance(lookbackCycles int) (*logger.PerformanceAnalysis, error) {
+	store := at.ledger()
+	if store == nil {
+		return at.decisionLogger.AnalyzePerformance(lookbackCycles)
+	}
+
+	records, err := at.decisionLogger.GetLatestRecords(lookbackCycles * ledgerRecordsFactor)
+	if err != nil {
+		return nil, fmt.Errorf("读取历史记录失败: %w", err)
+	}
+
+	since := time.Now().Add(-ledgerMaxLookback)
+	if len(records) > 0 && records[0].Timestamp.After(since) {
+		since = records[0].Timestamp
+	}
+	entries, err := store.GetLedgerEntries(at.id, since)
+	if err != nil {
+		return nil, fmt.Errorf("读取成交账本失败: %w", err)
+	}
+	if len(entries) == 0 {
+		return at.decisionLogger.AnalyzePerformance(lookbackCycles)
+	}
+
+	return logger.AnalyzeLedger(entries, records), nil
+}
@@ -0,0 +1,280 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/events"
+	"nofx/logger"
+	"strings"
+	"time"
+)
+
+// manualActionLookback 读取手动操作时回看的决策记录数
+const manualActionLookback = 20
+
+// manualActions 允许手动执行的操作
+var manualActions = map[string]bool{
+	"open_long":          true,
+	"open_short":         true,
+	"close_long":         true,
+	"close_short":        true,
+	"partial_close":      true,
+	"update_stop_loss":   true,
+	"update_take_profit": true,
+}
+
+// validateManualDecision 校验手动操作参数
+//
+// 与AI决策不同，手动操作不强制风险回报比，但杠杆超过配置上限时直接拒绝（不自动修正）
+func (at *AutoTrader) validateManualDecision(d *decision.Decision) error {
+	if !manualActions[d.Action] {
+		return fmt.Errorf("不支持的手动操作: %s", d.Action)
+	}
+	if d.Symbol == "" {
+		return fmt.Errorf("币种不能为空")
+	}
+
+	switch d.Action {
+	case "open_long", "open_short":
+		maxLeverage := at.config.AltcoinLeverage
+		if d.Symbol == "BTCUSDT" || d.Symbol == "ETHUSDT" {
+			maxLeverage = at.config.BTCETHLeverage
+		}
+		if d.Leverage <= 0 {
+			return fmt.Errorf("杠杆必须大于0: %d", d.Leverage)
+		}
+		if maxLeverage > 0 && d.Leverage > maxLeverage {
+			return fmt.Errorf("%s 杠杆超过配置上限 (%dx > %dx)", d.Symbol, d.Leverage, maxLeverage)
+		}
+		if d.PositionSizeUSD <= 0 {
+			return fmt.Errorf("仓位大小必须大于0: %.2f", d.PositionSizeUSD)
+		}
+		if d.StopLoss <= 0 || d.TakeProfit <= 0 {
+			return fmt.Errorf("止损和止盈必须大于0")
+		}
+		if d.Action == "open_long" && d.StopLoss >= d.TakeProfit {
+			return fmt.Errorf("做多时止损价必须小于止盈价")
+		}
+		if d.Action == "open_short" && d.StopLoss <= d.TakeProfit {
+			return fmt.Errorf("做空时止损价必须大于止盈价")
+		}
+	case "partial_close":
+		if d.ClosePercentage <= 0 || d.ClosePercentage > 100 {
+			return fmt.Errorf("平仓百分比必须在0-100之间: %.1f", d.ClosePercentage)
+		}
+	case "update_stop_loss":
+		if d.NewStopLoss <= 0 {
+			return fmt.Errorf("新止损价格必须大于0: %.2f", d.NewStopLoss)
+		}
+	case "update_take_profit":
+		if d.NewTakeProfit <= 0 {
+			return fmt.Errorf("新止盈价格必须大于0: %.2f", d.NewTakeProfit)
+		}
+	}
+	return nil
+}
+
+// ExecuteManualDecisions 立即执行手动操作（不等待AI周期）
+//
+// 操作通过与AI决策相同的 executeXWithRecord 执行（同样经过组合风控），
+// 结果以 source=manual 写入决策日志，并在下一周期的提示词中告知AI。
+// 参数校验失败时不执行任何操作；执行失败的操作记录在返回的决策记录中
+func (at *AutoTrader) ExecuteManualDecisions(decisions []decision.Decision, note string) (*logger.DecisionRecord, error) {
+	if len(decisions) == 0 {
+		return nil, fmt.Errorf("没有需要执行的操作")
+	}
+	for i := range decisions {
+		decisions[i].Symbol = strings.ToUpper(strings.TrimSpace(decisions[i].Symbol))
+		if err := at.validateManualDecision(&decisions[i]); err != nil {
+			return nil, fmt.Errorf("手动操作 #%d 参数无效: %w", i+1, err)
+		}
+		isOpen := decisions[i].Action == "open_long" || decisions[i].Action == "open_short"
+		if stopUntil := at.pausedUntil(); isOpen && time.Now().Before(stopUntil) {
+			return nil, fmt.Errorf("熔断暂停中（至 %s），禁止开仓", stopUntil.Format("15:04:05"))
+		}
+	}
+
+	at.execMu.Lock()
+	defer at.execMu.Unlock()
+
+	log.Printf("🖐️ [%s] 执行手动操作 (%d 个)", at.name, len(decisions))
+	record := newManualRecord(note)
+	at.executeManualRecord(decisions, record)
+	return record, nil
+}
+
+// newManualRecord 创建手动操作的决策记录
+func newManualRecord(note string) *logger.DecisionRecord {
+	record := &logger.DecisionRecord{
+		ExecutionLog: []string{},
+		Success:      true,
+		Source:       logger.DecisionSourceManual,
+		Note:         note,
+	}
+	if note != "" {
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("备注: %s", note))
+	}
+	return record
+}
+
+// executeManualRecord 执行手动操作并写入决策日志（调用方需持有 execMu）
+func (at *AutoTrader) executeManualRecord(decisions []decision.Decision, record *logger.DecisionRecord) {
+	decisionJSON, _ := json.MarshalIndent(decisions, "", "  ")
+	record.DecisionJSON = string(decisionJSON)
+
+	// 手动操作可能发生在周期之间，重新加载风控账户快照
+	at.riskAccount = nil
+	at.executeSourcedDecisions(decisions, record, logger.DecisionSourceManual)
+	if !record.Success {
+		record.ErrorMessage = "部分手动操作执行失败"
+	}
+
+	at.fillAccountSnapshot(record)
+
+	if err := at.decisionLogger.LogDecision(record); err != nil {
+		log.Printf("⚠ 保存手动操作记录失败: %v", err)
+	}
+}
+
+// fillAccountSnapshot 记录当前账户状态（净值图表按记录绘制，缺失会出现断点）
+func (at *AutoTrader) fillAccountSnapshot(record *logger.DecisionRecord) {
+	account, err := at.GetAccountInfo()
+	if err != nil {
+		log.Printf("⚠️ [%s] 获取账户状态失败: %v", at.name, err)
+		return
+	}
+	equity, _ := account["total_equity"].(float64)
+	unrealized, _ := account["unrealized_profit"].(float64)
+	available, _ := account["available_balance"].(float64)
+	marginUsedPct, _ := account["margin_used_pct"].(float64)
+	positionCount, _ := account["position_count"].(int)
+	record.AccountState = logger.AccountSnapshot{
+		TotalBalance:          equity - unrealized,
+		AvailableBalance:      available,
+		TotalUnrealizedProfit: unrealized,
+		PositionCount:         positionCount,
+		MarginUsedPct:         marginUsedPct,
+		InitialBalance:        at.initialBalance,
+	}
+}
+
+// executeSourcedDecisions 按优先级执行非AI周期产生的操作，结果追加到决策记录（调用方需持有 execMu）
+func (at *AutoTrader) executeSourcedDecisions(decisions []decision.Decision, record *logger.DecisionRecord, source string) {
+	for _, d := range SortDecisionsByPriority(decisions) {
+		actionRecord := logger.DecisionAction{
+			Action:    d.Action,
+			Symbol:    d.Symbol,
+			Leverage:  d.Leverage,
+			Timestamp: time.Now(),
+			Source:    source,
+		}
+
+		if err := at.executeDecisionWithRecord(&d, &actionRecord); err != nil {
+			log.Printf("❌ [%s] 操作失败 (%s %s, 来源: %s): %v", at.name, d.Symbol, d.Action, source, err)
+			actionRecord.Error = err.Error()
+			record.Success = false
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 失败: %v", d.Symbol, d.Action, err))
+			at.publishEvent(events.DecisionFailed, sourcedEventData(&actionRecord))
+		} else {
+			actionRecord.Success = true
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s 成功", d.Symbol, d.Action))
+			at.publishEvent(events.DecisionExecuted, sourcedEventData(&actionRecord))
+		}
+		record.Decisions = append(record.Decisions, actionRecord)
+	}
+}
+
+// sourcedEventData 非AI操作的事件数据（带 source 标记）
+func sourcedEventData(action *logger.DecisionAction) map[string]any {
+	data := actionEventData(action)
+	data["source"] = action.Source
+	return data
+}
+
+// positionCloseDecisions 为当前所有持仓生成 close_long / close_short 决策
+func (at *AutoTrader) positionCloseDecisions(reasoning string) ([]decision.Decision, error) {
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		return nil, fmt.Errorf("获取持仓失败: %w", err)
+	}
+
+	var decisions []decision.Decision
+	for _, pos := range positions {
+		symbol, _ := pos["symbol"].(string)
+		side, _ := pos["side"].(string)
+		if amt, _ := pos["positionAmt"].(float64); amt == 0 || symbol == "" {
+			continue
+		}
+		decisions = append(decisions, decision.Decision{Symbol: symbol, Action: "close_" + side, Reasoning: reasoning})
+	}
+	return decisions, nil
+}
+
+// FlattenAll 手动撤销所有限价开仓挂单并平掉所有持仓（逐个生成 close_long / close_short 并记录为手动操作）
+func (at *AutoTrader) FlattenAll(note string) (*logger.DecisionRecord, error) {
+	at.execMu.Lock()
+	defer at.execMu.Unlock()
+
+	// 先撤单再读取持仓：撤单时已成交的部分也会被平掉
+	canceled := at.cancelPendingOrders("", "", "手动全部平仓")
+	decisions, err := at.positionCloseDecisions("手动全部平仓")
+	if err != nil {
+		return nil, err
+	}
+	if len(decisions) == 0 && canceled == 0 {
+		return nil, fmt.Errorf("当前没有持仓或挂单")
+	}
+
+	log.Printf("🖐️ [%s] 手动全部平仓 (%d 个持仓，已撤销 %d 个挂单)", at.name, len(decisions), canceled)
+	record := newManualRecord(note)
+	if canceled > 0 {
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🚫 已撤销 %d 个限价开仓挂单", canceled))
+	}
+	at.executeManualRecord(decisions, record)
+	return record, nil
+}
+
+// isUserRecord 是否为AI周期之外产生的操作记录（手动操作、提案审批结果或熔断平仓）
+func isUserRecord(record *logger.DecisionRecord) bool {
+	switch record.Source {
+	case logger.DecisionSourceManual, logger.DecisionSourceApproval, logger.DecisionSourceBreaker:
+		return true
+	}
+	return false
+}
+
+// recentManualActions 上一个AI周期之后的手动操作和提案审批结果（按时间正序）
+func (at *AutoTrader) recentManualActions() []decision.ManualAction {
+	records, err := at.decisionLogger.GetLatestRecords(manualActionLookback)
+	if err != nil {
+		return nil
+	}
+
+	// 从最新记录向前回溯，遇到第一条AI实际看到提示词的周期记录为止（熔断暂停等提前结束的周期不算）
+	start := len(records)
+	for start > 0 && (isUserRecord(records[start-1]) || records[start-1].InputPrompt == "") {
+		start--
+	}
+
+	var actions []decision.ManualAction
+	for _, record := range records[start:] {
+		if !isUserRecord(record) {
+			continue
+		}
+		for _, action := range record.Decisions {
+			actions = append(actions, decision.ManualAction{
+				Time:     action.Timestamp,
+				Symbol:   action.Symbol,
+				Action:   action.Action,
+				Price:    action.Price,
+				Quantity: action.Quantity,
+				Success:  action.Success,
+				Error:    action.Error,
+				Note:     record.Note,
+			})
+		}
+	}
+	return actions
+}
@@ -0,0 +1,113 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+	"time"
+)
+
+// ============================================================
+// 手动操作测试
+// ============================================================
+
+func (s *AutoTraderTestSuite) TestExecuteManualDecisions_Validation() {
+	tests := []struct {
+		name        string
+		decision    decision.Decision
+		expectedErr string
+	}{
+		{"不支持的操作", decision.Decision{Action: "hold", Symbol: "BTCUSDT"}, "不支持的手动操作"},
+		{"缺少币种", decision.Decision{Action: "close_long"}, "币种不能为空"},
+		{"杠杆超过配置上限", decision.Decision{Action: "open_long", Symbol: "SOLUSDT", Leverage: 20,
+			PositionSizeUSD: 100, StopLoss: 90, TakeProfit: 120}, "杠杆超过配置上限"},
+		{"止损止盈方向错误", decision.Decision{Action: "open_short", Symbol: "BTCUSDT", Leverage: 5,
+			PositionSizeUSD: 100, StopLoss: 50000, TakeProfit: 52000}, "做空时止损价必须大于止盈价"},
+		{"部分平仓百分比无效", decision.Decision{Action: "partial_close", Symbol: "BTCUSDT", ClosePercentage: 120}, "平仓百分比"},
+		{"新止损无效", decision.Decision{Action: "update_stop_loss", Symbol: "BTCUSDT"}, "新止损价格必须大于0"},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			record, err := s.autoTrader.ExecuteManualDecisions([]decision.Decision{tt.decision}, "")
+			s.Error(err)
+			s.Contains(err.Error(), tt.expectedErr)
+			s.Nil(record)
+		})
+	}
+
+	s.Run("熔断暂停中禁止开仓", func() {
+		s.autoTrader.stopUntil = time.Now().Add(time.Hour)
+		defer func() { s.autoTrader.stopUntil = time.Time{} }()
+
+		_, err := s.autoTrader.ExecuteManualDecisions([]decision.Decision{{Action: "open_long", Symbol: "BTCUSDT",
+			Leverage: 5, PositionSizeUSD: 100, StopLoss: 49000, TakeProfit: 52000}}, "")
+		s.Error(err)
+		s.Contains(err.Error(), "禁止开仓")
+	})
+}
+
+func (s *AutoTraderTestSuite) TestExecuteManualDecisions_RecordsManualSource() {
+	decisionLogger := logger.NewDecisionLogger(s.T().TempDir())
+	s.autoTrader.decisionLogger = decisionLogger
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 51000.0}, nil
+	})
+	s.mockTrader.positions = []map[string]interface{}{
+		{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.1, "entryPrice": 50000.0, "markPrice": 51000.0,
+			"unRealizedProfit": 100.0, "leverage": 10.0},
+	}
+	defer func() { s.mockTrader.positions = []map[string]interface{}{} }()
+
+	// 上一个AI周期
+	s.Require().NoError(decisionLogger.LogDecision(&logger.DecisionRecord{Success: true, InputPrompt: "prompt"}))
+	s.Empty(s.autoTrader.recentManualActions())
+
+	record, err := s.autoTrader.ExecuteManualDecisions([]decision.Decision{{Action: "close_long", Symbol: "btcusdt"}}, "止盈离场")
+	s.Require().NoError(err)
+	s.True(record.Success)
+	s.Equal(logger.DecisionSourceManual, record.Source)
+	s.Equal("止盈离场", record.Note)
+	s.Empty(record.CoTTrace, "备注不应写入AI思维链")
+	s.Require().Len(record.Decisions, 1)
+	s.Equal(logger.DecisionSourceManual, record.Decisions[0].Source)
+	s.Equal("BTCUSDT", record.Decisions[0].Symbol)
+	s.Equal(51000.0, record.Decisions[0].Price)
+	s.Equal(10000.0, record.AccountState.TotalBalance)
+
+	// 熔断暂停等未调用AI的周期不影响回溯
+	s.Require().NoError(decisionLogger.LogDecision(&logger.DecisionRecord{Success: false, ErrorMessage: "风险控制暂停中"}))
+
+	actions := s.autoTrader.recentManualActions()
+	s.Require().Len(actions, 1)
+	s.Equal("close_long", actions[0].Action)
+	s.Equal("止盈离场", actions[0].Note)
+	s.True(actions[0].Success)
+
+	// AI看到之后不再重复提示
+	s.Require().NoError(decisionLogger.LogDecision(&logger.DecisionRecord{Success: true, InputPrompt: "prompt"}))
+	s.Empty(s.autoTrader.recentManualActions())
+}
+
+func (s *AutoTraderTestSuite) TestFlattenAll() {
+	s.autoTrader.decisionLogger = logger.NewDecisionLogger(s.T().TempDir())
+
+	_, err := s.autoTrader.FlattenAll("")
+	s.Error(err)
+	s.Contains(err.Error(), "没有持仓")
+
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 3000.0}, nil
+	})
+	s.mockTrader.positions = []map[string]interface{}{
+		{"symbol": "ETHUSDT", "side": "short", "positionAmt": -1.0, "entryPrice": 3100.0, "markPrice": 3000.0, "unRealizedProfit": 0.0},
+		{"symbol": "SOLUSDT", "side": "long", "positionAmt": 0.0, "markPrice": 150.0, "unRealizedProfit": 0.0},
+	}
+	defer func() { s.mockTrader.positions = []map[string]interface{}{} }()
+
+	record, err := s.autoTrader.FlattenAll("收盘前清仓")
+	s.Require().NoError(err)
+	s.Require().Len(record.Decisions, 1, "数量为0的持仓应跳过")
+	s.Equal("close_short", record.Decisions[0].Action)
+	s.Equal("ETHUSDT", record.Decisions[0].Symbol)
+}
@@ -0,0 +1,1113 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"nofx/market"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	paperDefaultTakerFeeRate      = 0.0004             // 默认吃单手续费率（与币安一致）
+	paperDefaultMakerFeeRate      = 0.0002             // 默认挂单手续费率（与币安一致）
+	paperMaintenanceMarginRate    = 0.004              // 维持保证金率（用于估算强平价）
+	paperFundingIntervalMs        = 8 * 60 * 60 * 1000 // 资金费结算间隔（8小时，UTC 00:00/08:00/16:00）
+	paperDefaultLeverage          = 10
+	paperKlineInterval            = "3m"
+	paperQuantityPrecisionDefault = 3
+	paperMaxLedgerEntries         = 1000 // 成交/资金费明细最多保留条数
+	paperMaxClosedOrders          = 100  // 已结束的限价单最多保留条数（用于查询订单状态）
+)
+
+// paperPosition 模拟持仓
+type paperPosition struct {
+	Symbol          string  `json:"symbol"`
+	Side            string  `json:"side"` // "long" or "short"
+	Quantity        float64 `json:"quantity"`
+	EntryPrice      float64 `json:"entry_price"`
+	MarkPrice       float64 `json:"mark_price"`
+	Leverage        int     `json:"leverage"`
+	Margin          float64 `json:"margin"`            // 占用保证金（开仓名义价值/杠杆）
+	StopLoss        float64 `json:"stop_loss"`         // 止损触发价（0表示未设置）
+	TakeProfit      float64 `json:"take_profit"`       // 止盈触发价（0表示未设置）
+	LastFundingTime int64   `json:"last_funding_time"` // 上次结算资金费的时间（毫秒）
+
+	// K线扫描进度：记录上次检查时所在K线的开盘时间及其最高/最低价，
+	// 下次检查时只把新出现的价格区间用于止损止盈判断
+	LastKlineOpen int64   `json:"last_kline_open"`
+	LastHigh      float64 `json:"last_high"`
+	LastLow       float64 `json:"last_low"`
+}
+
+// paperOrder 模拟限价挂单
+type paperOrder struct {
+	OrderID     int64   `json:"order_id"`
+	Symbol      string  `json:"symbol"`
+	Side        string  `json:"side"` // "long" or "short"
+	Quantity    float64 `json:"quantity"`
+	Price       float64 `json:"price"`
+	Leverage    int     `json:"leverage"`
+	TimeInForce string  `json:"time_in_force"`
+	Status      string  `json:"status"`
+	ExecutedQty float64 `json:"executed_qty"`
+	AvgPrice    float64 `json:"avg_price"`
+
+	// K线扫描进度（同 paperPosition），挂单只会被下单之后出现的价格成交
+	LastKlineOpen int64   `json:"last_kline_open"`
+	LastHigh      float64 `json:"last_high"`
+	LastLow       float64 `json:"last_low"`
+}
+
+// margin 挂单占用的保证金（按限价计算）
+func (o *paperOrder) margin() float64 {
+	return o.Quantity * o.Price / float64(o.Leverage)
+}
+
+// paperAccountState 模拟账户持久化状态
+type paperAccountState struct {
+	WalletBalance float64                   `json:"wallet_balance"`
+	TotalFees     float64                   `json:"total_fees"`
+	TotalFunding  float64                   `json:"total_funding"` // 正数表示净支付
+	NextOrderID   int64                     `json:"next_order_id"`
+	Leverage      map[string]int            `json:"leverage"`
+	CrossMargin   map[string]bool           `json:"cross_margin"`
+	Positions     map[string]*paperPosition `json:"positions"` // key: symbol_side
+	NextFillID    int64                     `json:"next_fill_id"`
+	Fills         []TradeFill               `json:"fills"`
+	Funding       []FundingPayment          `json:"funding"`
+	Orders        []*paperOrder             `json:"orders"` // 限价挂单（含最近已结束的订单）
+}
+
+// PaperTrader 模拟盘交易器
+// 使用实时行情（market.WSMonitorCli K线）撮合，维护虚拟保证金账本，
+// 计入手续费、资金费，并根据K线最高/最低价判断止损止盈和强平
+type PaperTrader struct {
+	mu           sync.Mutex
+	state        paperAccountState
+	takerFeeRate float64
+	makerFeeRate float64
+	stateFile    string // 状态文件路径（为空则不持久化）
+
+	// 行情来源（可替换，便于测试）
+	klineFunc       func(symbol string) ([]market.Kline, error)
+	fundingRateFunc func(symbol string, fundingTime int64) (float64, error) // 指定结算时间点（毫秒）的资金费率
+	nowFunc         func() time.Time
+	onTriggered     func(fill PaperTriggerFill)
+}
+
+// NewPaperTrader 创建模拟盘交易器
+// stateFile 不为空时，账户状态会持久化到该文件，重启后自动恢复
+func NewPaperTrader(initialBalance float64, stateFile string) (*PaperTrader, error) {
+	if initialBalance <= 0 {
+		return nil, fmt.Errorf("模拟盘初始资金必须大于0")
+	}
+
+	t := &PaperTrader{
+		state: paperAccountState{
+			WalletBalance: initialBalance,
+			NextOrderID:   1,
+			Leverage:      make(map[string]int),
+			CrossMargin:   make(map[string]bool),
+			Positions:     make(map[string]*paperPosition),
+		},
+		takerFeeRate:    paperDefaultTakerFeeRate,
+		makerFeeRate:    paperDefaultMakerFeeRate,
+		stateFile:       stateFile,
+		klineFunc:       defaultPaperKlines,
+		fundingRateFunc: historicalFundingRate,
+		nowFunc:         time.Now,
+	}
+
+	if stateFile != "" {
+		if err := t.loadState(); err != nil {
+			return nil, fmt.Errorf("加载模拟盘状态失败: %w", err)
+		}
+	}
+
+	log.Printf("🧪 模拟盘账户就绪: 钱包余额 %.2f USDT, 持仓 %d 个", t.state.WalletBalance, len(t.state.Positions))
+	return t, nil
+}
+
+// PaperFeed 模拟盘行情源（回测时由历史K线和模拟时钟驱动）
+type PaperFeed struct {
+	Klines      func(symbol string) ([]market.Kline, error) // 截至当前时刻的3分钟K线（按时间正序）
+	FundingRate func(symbol string) (float64, error)        // 资金费率，为 nil 时不结算资金费
+	Now         func() time.Time                            // 当前时刻
+	OnTriggered func(fill PaperTriggerFill)                 // 止损/止盈/强平成交回调（可选）
+}
+
+// PaperTriggerFill 止损/止盈/强平自动成交记录
+type PaperTriggerFill struct {
+	Symbol   string
+	Side     string // long / short
+	Quantity float64
+	Price    float64
+	Reason   string // 止损 / 止盈 / 强平
+	Time     time.Time
+}
+
+// NewPaperTraderWithFeed 使用自定义行情源创建模拟盘交易器（不持久化）
+func NewPaperTraderWithFeed(initialBalance float64, takerFeeRate float64, feed PaperFeed) (*PaperTrader, error) {
+	if feed.Klines == nil || feed.Now == nil {
+		return nil, fmt.Errorf("模拟盘行情源缺少K线或时钟")
+	}
+
+	t, err := NewPaperTrader(initialBalance, "")
+	if err != nil {
+		return nil, err
+	}
+	if takerFeeRate >= 0 {
+		t.takerFeeRate = takerFeeRate
+	}
+	t.klineFunc = feed.Klines
+	t.nowFunc = feed.Now
+	t.onTriggered = feed.OnTriggered
+	t.fundingRateFunc = func(string, int64) (float64, error) { return 0, nil }
+	if feed.FundingRate != nil {
+		t.fundingRateFunc = func(symbol string, _ int64) (float64, error) { return feed.FundingRate(symbol) }
+	}
+	return t, nil
+}
+
+// defaultPaperKlines 从WebSocket监控器获取3分钟K线
+func defaultPaperKlines(symbol string) ([]market.Kline, error) {
+	if market.WSMonitorCli == nil {
+		return nil, fmt.Errorf("行情监控器未启动")
+	}
+	return market.WSMonitorCli.GetCurrentKlines(symbol, paperKlineInterval)
+}
+
+// historicalFundingRate 从交易所资金费率历史中查找指定结算时间点的费率
+// 结算记录尚未出现（刚过结算点）时返回错误，由下次同步重试
+func historicalFundingRate(symbol string, fundingTime int64) (float64, error) {
+	// 按最短1小时结算间隔估算需要回溯的记录数
+	limit := int((time.Now().UnixMilli()-fundingTime)/(60*60*1000)) + 2
+	if limit > 1000 {
+		limit = 1000
+	}
+	records, err := market.NewAPIClient().GetFundingRateHistory(symbol, limit)
+	if err != nil {
+		return 0, err
+	}
+	for _, record := range records {
+		// 交易所记录的结算时间可能比整点晚几毫秒
+		if diff := record.FundingTime - fundingTime; diff >= -60*1000 && diff <= 60*1000 {
+			return record.FundingRate, nil
+		}
+	}
+	return 0, fmt.Errorf("未找到 %s 在 %s 的资金费率记录", symbol, time.UnixMilli(fundingTime).UTC().Format("2006-01-02 15:04"))
+}
+
+// loadState 从状态文件恢复账户
+func (t *PaperTrader) loadState() error {
+	data, err := os.ReadFile(t.stateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var state paperAccountState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("解析状态文件失败: %w", err)
+	}
+	if state.Leverage == nil {
+		state.Leverage = make(map[string]int)
+	}
+	if state.CrossMargin == nil {
+		state.CrossMargin = make(map[string]bool)
+	}
+	if state.Positions == nil {
+		state.Positions = make(map[string]*paperPosition)
+	}
+	if state.NextOrderID <= 0 {
+		state.NextOrderID = 1
+	}
+	t.state = state
+	return nil
+}
+
+// saveState 持久化账户状态（调用方需持有锁）
+func (t *PaperTrader) saveState() {
+	if t.stateFile == "" {
+		return
+	}
+
+	data, err := json.MarshalIndent(t.state, "", "  ")
+	if err != nil {
+		log.Printf("⚠️ 序列化模拟盘状态失败: %v", err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(t.stateFile), 0700); err != nil {
+		log.Printf("⚠️ 创建模拟盘状态目录失败: %v", err)
+		return
+	}
+	if err := os.WriteFile(t.stateFile, data, 0600); err != nil {
+		log.Printf("⚠️ 保存模拟盘状态失败: %v", err)
+	}
+}
+
+// positionKey 持仓索引键
+func positionKey(symbol, side string) string {
+	return symbol + "_" + side
+}
+
+// latestKlines 获取K线并校验非空
+func (t *PaperTrader) latestKlines(symbol string) ([]market.Kline, error) {
+	klines, err := t.klineFunc(symbol)
+	if err != nil {
+		return nil, fmt.Errorf("获取 %s K线失败: %w", symbol, err)
+	}
+	if len(klines) == 0 {
+		return nil, fmt.Errorf("%s K线数据为空", symbol)
+	}
+	return klines, nil
+}
+
+// sync 推进账户状态：结算资金费、检查止损止盈/强平、刷新标记价格（调用方需持有锁）
+func (t *PaperTrader) sync() {
+	changed := t.matchLimitOrders()
+	now := t.nowFunc().UnixMilli()
+
+	for key, pos := range t.state.Positions {
+		klines, err := t.latestKlines(pos.Symbol)
+		if err != nil {
+			log.Printf("⚠️ 模拟盘: %v", err)
+			continue
+		}
+
+		if t.evaluateTriggers(key, pos, klines) {
+			changed = true
+			continue
+		}
+
+		pos.MarkPrice = klines[len(klines)-1].Close
+		if t.settleFunding(pos, now) {
+			changed = true
+		}
+	}
+
+	if changed {
+		t.saveState()
+	}
+}
+
+// evaluateTriggers 用上次检查以来新出现的K线价格区间判断强平、止损、止盈
+// 同一根K线内同时触及止损和止盈时，保守地按止损处理
+// 返回 true 表示持仓已被平掉
+func (t *PaperTrader) evaluateTriggers(key string, pos *paperPosition, klines []market.Kline) bool {
+	liqPrice := pos.liquidationPrice()
+
+	for _, k := range klines {
+		if k.OpenTime < pos.LastKlineOpen {
+			continue
+		}
+
+		var low, high float64
+		gapOpen := 0.0
+		if k.OpenTime == pos.LastKlineOpen {
+			// 同一根K线：只有突破上次记录的高低点才算新价格
+			low, high = k.Close, k.Close
+			if k.Low < pos.LastLow {
+				low = k.Low
+			}
+			if k.High > pos.LastHigh {
+				high = k.High
+			}
+		} else {
+			low, high = k.Low, k.High
+			gapOpen = k.Open
+		}
+
+		pos.LastKlineOpen = k.OpenTime
+		pos.LastHigh = k.High
+		pos.LastLow = k.Low
+
+		if price, reason, hit := pos.checkTrigger(low, high, gapOpen, liqPrice); hit {
+			quantity := pos.Quantity
+			t.closePosition(key, pos, quantity, price, t.nextOrderID())
+			log.Printf("🧪 模拟盘 %s %s %s 触发，成交价 %.4f", pos.Symbol, pos.Side, reason, price)
+			if t.onTriggered != nil {
+				t.onTriggered(PaperTriggerFill{
+					Symbol:   pos.Symbol,
+					Side:     pos.Side,
+					Quantity: quantity,
+					Price:    price,
+					Reason:   reason,
+					Time:     time.UnixMilli(k.OpenTime),
+				})
+			}
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkTrigger 判断价格区间 [low, high] 是否触发强平/止损/止盈，返回成交价
+// gapOpen > 0 时表示新K线的开盘价，若开盘即越过触发价则按开盘价成交（跳空滑点）
+func (pos *paperPosition) checkTrigger(low, high, gapOpen, liqPrice float64) (float64, string, bool) {
+	if pos.Side == "long" {
+		if pos.StopLoss > 0 && low <= pos.StopLoss && pos.StopLoss > liqPrice {
+			return fillBelow(pos.StopLoss, gapOpen), "止损", true
+		}
+		if liqPrice > 0 && low <= liqPrice {
+			return liqPrice, "强平", true
+		}
+		if pos.TakeProfit > 0 && high >= pos.TakeProfit {
+			return fillAbove(pos.TakeProfit, gapOpen), "止盈", true
+		}
+		return 0, "", false
+	}
+
+	if pos.StopLoss > 0 && high >= pos.StopLoss && (liqPrice <= 0 || pos.StopLoss < liqPrice) {
+		return fillAbove(pos.StopLoss, gapOpen), "止损", true
+	}
+	if liqPrice > 0 && high >= liqPrice {
+		return liqPrice, "强平", true
+	}
+	if pos.TakeProfit > 0 && low <= pos.TakeProfit {
+		return fillBelow(pos.TakeProfit, gapOpen), "止盈", true
+	}
+	return 0, "", false
+}
+
+// fillBelow 向下触发的成交价（开盘已跌破触发价时按开盘价成交）
+func fillBelow(trigger, gapOpen float64) float64 {
+	if gapOpen > 0 && gapOpen < trigger {
+		return gapOpen
+	}
+	return trigger
+}
+
+// fillAbove 向上触发的成交价（开盘已涨破触发价时按开盘价成交）
+func fillAbove(trigger, gapOpen float64) float64 {
+	if gapOpen > trigger {
+		return gapOpen
+	}
+	return trigger
+}
+
+// settleFunding 结算自上次以来经过的资金费时间点（调用方需持有锁）
+// 多头在费率为正时支付，空头收取；费率为负时相反
+// 每个结算点使用该时间点的费率；获取费率失败时停止结算且不推进结算时间，下次同步重试
+func (t *PaperTrader) settleFunding(pos *paperPosition, now int64) bool {
+	settled := false
+	for next := (pos.LastFundingTime/paperFundingIntervalMs + 1) * paperFundingIntervalMs; next <= now; next += paperFundingIntervalMs {
+		rate, err := t.fundingRateFunc(pos.Symbol, next)
+		if err != nil {
+			log.Printf("⚠️ 模拟盘: 获取 %s 资金费率失败，稍后重试结算: %v", pos.Symbol, err)
+			break
+		}
+
+		payment := pos.Quantity * pos.MarkPrice * rate
+		if pos.Side == "short" {
+			payment = -payment
+		}
+		t.state.WalletBalance -= payment
+		t.state.TotalFunding += payment
+		t.state.NextFillID++
+		t.state.Funding = append(t.state.Funding, FundingPayment{
+			ID:     strconv.FormatInt(t.state.NextFillID, 10),
+			Symbol: pos.Symbol,
+			Amount: -payment,
+			Time:   next,
+		})
+		if len(t.state.Funding) > paperMaxLedgerEntries {
+			t.state.Funding = t.state.Funding[len(t.state.Funding)-paperMaxLedgerEntries:]
+		}
+		pos.LastFundingTime = next
+		settled = true
+		log.Printf("🧪 模拟盘 %s %s 资金费结算: 费率 %.6f, %+.4f USDT", pos.Symbol, pos.Side, rate, -payment)
+	}
+	return settled
+}
+
+// liquidationPrice 估算强平价（按逐仓公式近似，全仓同样适用以保持保守）
+func (pos *paperPosition) liquidationPrice() float64 {
+	if pos.Leverage <= 0 || pos.EntryPrice <= 0 {
+		return 0
+	}
+	lev := float64(pos.Leverage)
+	if pos.Side == "long" {
+		return pos.EntryPrice * (1 - 1/lev + paperMaintenanceMarginRate)
+	}
+	return pos.EntryPrice * (1 + 1/lev - paperMaintenanceMarginRate)
+}
+
+// unrealizedPnL 未实现盈亏
+func (pos *paperPosition) unrealizedPnL() float64 {
+	if pos.Side == "long" {
+		return (pos.MarkPrice - pos.EntryPrice) * pos.Quantity
+	}
+	return (pos.EntryPrice - pos.MarkPrice) * pos.Quantity
+}
+
+// closePosition 按指定价格平掉持仓的一部分或全部（调用方需持有锁）
+func (t *PaperTrader) closePosition(key string, pos *paperPosition, quantity, price float64, orderID int64) float64 {
+	if quantity > pos.Quantity {
+		quantity = pos.Quantity
+	}
+
+	var pnl float64
+	if pos.Side == "long" {
+		pnl = (price - pos.EntryPrice) * quantity
+	} else {
+		pnl = (pos.EntryPrice - price) * quantity
+	}
+	fee := quantity * price * t.takerFeeRate
+
+	t.state.WalletBalance += pnl - fee
+	t.state.TotalFees += fee
+	t.recordFill(orderID, pos.Symbol, pos.Side, false, quantity, price, fee, pnl)
+
+	ratio := quantity / pos.Quantity
+	pos.Margin -= pos.Margin * ratio
+	pos.Quantity -= quantity
+	pos.MarkPrice = price
+
+	if pos.Quantity <= 1e-12 {
+		delete(t.state.Positions, key)
+	}
+	return pnl
+}
+
+// recordFill 记录一笔成交明细（调用方需持有锁）
+func (t *PaperTrader) recordFill(orderID int64, symbol, side string, isOpen bool, quantity, price, fee, realizedPnL float64) {
+	positionSide := "LONG"
+	if side == "short" {
+		positionSide = "SHORT"
+	}
+	orderSide := "SELL"
+	if (side == "long") == isOpen {
+		orderSide = "BUY"
+	}
+
+	t.state.NextFillID++
+	t.state.Fills = append(t.state.Fills, TradeFill{
+		ID:           strconv.FormatInt(t.state.NextFillID, 10),
+		OrderID:      strconv.FormatInt(orderID, 10),
+		Symbol:       symbol,
+		Side:         orderSide,
+		PositionSide: positionSide,
+		Price:        price,
+		Quantity:     quantity,
+		Fee:          fee,
+		FeeAsset:     "USDT",
+		RealizedPnL:  realizedPnL,
+		Time:         t.nowFunc().UnixMilli(),
+	})
+	if len(t.state.Fills) > paperMaxLedgerEntries {
+		t.state.Fills = t.state.Fills[len(t.state.Fills)-paperMaxLedgerEntries:]
+	}
+}
+
+// nextOrderID 生成模拟订单ID（调用方需持有锁）
+func (t *PaperTrader) nextOrderID() int64 {
+	id := t.state.NextOrderID
+	t.state.NextOrderID++
+	return id
+}
+
+// GetBalance 获取账户余额
+func (t *PaperTrader) GetBalance() (map[string]interface{}, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sync()
+
+	totalUnrealized := 0.0
+	totalMargin := 0.0
+	for _, pos := range t.state.Positions {
+		totalUnrealized += pos.unrealizedPnL()
+		totalMargin += pos.Margin
+	}
+
+	available := t.state.WalletBalance + totalUnrealized - totalMargin - t.reservedOrderMargin()
+	if available < 0 {
+		available = 0
+	}
+
+	result := make(map[string]interface{})
+	result["totalWalletBalance"] = t.state.WalletBalance
+	result["availableBalance"] = available
+	result["totalUnrealizedProfit"] = totalUnrealized
+	result["totalFees"] = t.state.TotalFees
+	result["totalFunding"] = t.state.TotalFunding
+	return result, nil
+}
+
+// GetPositions 获取所有持仓
+func (t *PaperTrader) GetPositions() ([]map[string]interface{}, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sync()
+
+	result := []map[string]interface{}{}
+	for _, pos := range t.state.Positions {
+		posAmt := pos.Quantity
+		if pos.Side == "short" {
+			posAmt = -posAmt // 与币安保持一致：空仓数量为负
+		}
+
+		posMap := make(map[string]interface{})
+		posMap["symbol"] = pos.Symbol
+		posMap["side"] = pos.Side
+		posMap["positionAmt"] = posAmt
+		posMap["entryPrice"] = pos.EntryPrice
+		posMap["markPrice"] = pos.MarkPrice
+		posMap["unRealizedProfit"] = pos.unrealizedPnL()
+		posMap["leverage"] = float64(pos.Leverage)
+		posMap["liquidationPrice"] = pos.liquidationPrice()
+		result = append(result, posMap)
+	}
+	return result, nil
+}
+
+// openPosition 开仓通用逻辑（市价，按吃单费率计费）
+func (t *PaperTrader) openPosition(symbol, side string, quantity float64, leverage int) (map[string]interface{}, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sync()
+
+	if quantity <= 0 {
+		return nil, fmt.Errorf("开仓数量必须大于0")
+	}
+	if leverage <= 0 {
+		leverage = paperDefaultLeverage
+	}
+
+	klines, err := t.latestKlines(symbol)
+	if err != nil {
+		return nil, err
+	}
+	last := klines[len(klines)-1]
+	price := last.Close
+
+	// 与币安一致：开仓前清理该币种旧的止损止盈单和限价挂单
+	t.cancelOrders(symbol, true, true)
+	t.cancelLimitOrders(symbol)
+	t.state.Leverage[symbol] = leverage
+
+	fee := quantity * price * t.takerFeeRate
+	if err := t.checkAvailable(quantity*price/float64(leverage), fee); err != nil {
+		return nil, err
+	}
+
+	orderID := t.nextOrderID()
+	t.applyOpen(symbol, side, quantity, price, leverage, fee, orderID, last)
+	t.saveState()
+
+	log.Printf("🧪 模拟盘开仓成功: %s %s 数量 %.6f @ %.4f (杠杆 %dx, 手续费 %.4f)", symbol, side, quantity, price, leverage, fee)
+
+	result := make(map[string]interface{})
+	result["orderId"] = orderID
+	result["symbol"] = symbol
+	result["status"] = "FILLED"
+	result["avgPrice"] = price
+	return result, nil
+}
+
+// checkAvailable 可用保证金检查（调用方需持有锁）
+func (t *PaperTrader) checkAvailable(margin, fee float64) error {
+	totalUnrealized := 0.0
+	totalMargin := 0.0
+	for _, pos := range t.state.Positions {
+		totalUnrealized += pos.unrealizedPnL()
+		totalMargin += pos.Margin
+	}
+	available := t.state.WalletBalance + totalUnrealized - totalMargin - t.reservedOrderMargin()
+	if margin+fee > available {
+		return fmt.Errorf("模拟盘保证金不足: 需要 %.2f USDT（保证金 %.2f + 手续费 %.2f），可用 %.2f USDT", margin+fee, margin, fee, available)
+	}
+	return nil
+}
+
+// applyOpen 按指定价格开仓或加仓并扣除手续费（调用方需持有锁）
+func (t *PaperTrader) applyOpen(symbol, side string, quantity, price float64, leverage int, fee float64, orderID int64, last market.Kline) {
+	margin := quantity * price / float64(leverage)
+	key := positionKey(symbol, side)
+	pos, exists := t.state.Positions[key]
+	if exists {
+		// 加仓：按数量加权计算新的开仓均价
+		totalQty := pos.Quantity + quantity
+		pos.EntryPrice = (pos.EntryPrice*pos.Quantity + price*quantity) / totalQty
+		pos.Quantity = totalQty
+		pos.Margin += margin
+		pos.Leverage = leverage
+		pos.MarkPrice = price
+	} else {
+		t.state.Positions[key] = &paperPosition{
+			Symbol:          symbol,
+			Side:            side,
+			Quantity:        quantity,
+			EntryPrice:      price,
+			MarkPrice:       price,
+			Leverage:        leverage,
+			Margin:          margin,
+			LastFundingTime: t.nowFunc().UnixMilli(),
+			LastKlineOpen:   last.OpenTime,
+			LastHigh:        last.High,
+			LastLow:         last.Low,
+		}
+	}
+
+	t.state.WalletBalance -= fee
+	t.state.TotalFees += fee
+	t.recordFill(orderID, symbol, side, true, quantity, price, fee, 0)
+}
+
+// closeSide 平仓通用逻辑（quantity=0表示全部平仓）
+func (t *PaperTrader) closeSide(symbol, side string, quantity float64) (map[string]interface{}, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sync()
+
+	key := positionKey(symbol, side)
+	pos, exists := t.state.Positions[key]
+	if !exists {
+		sideName := "多仓"
+		if side == "short" {
+			sideName = "空仓"
+		}
+		return nil, fmt.Errorf("没有找到 %s 的%s", symbol, sideName)
+	}
+
+	if quantity <= 0 || quantity > pos.Quantity {
+		quantity = pos.Quantity
+	}
+	fullClose := quantity >= pos.Quantity
+
+	klines, err := t.latestKlines(symbol)
+	if err != nil {
+		return nil, err
+	}
+	price := klines[len(klines)-1].Close
+	orderID := t.nextOrderID()
+	pnl := t.closePosition(key, pos, quantity, price, orderID)
+	if fullClose {
+		// 与币安一致：全部平仓后取消该方向的止损止盈
+		t.cancelOrders(symbol, true, true)
+	}
+	t.saveState()
+
+	log.Printf("🧪 模拟盘平仓成功: %s %s 数量 %.6f @ %.4f (已实现盈亏 %+.4f)", symbol, side, quantity, price, pnl)
+
+	result := make(map[string]interface{})
+	result["orderId"] = orderID
+	result["symbol"] = symbol
+	result["status"] = "FILLED"
+	result["avgPrice"] = price
+	result["realizedPnl"] = pnl
+	return result, nil
+}
+
+// OpenLong 开多仓
+func (t *PaperTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	return t.openPosition(symbol, "long", quantity, leverage)
+}
+
+// OpenShort 开空仓
+func (t *PaperTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	return t.openPosition(symbol, "short", quantity, leverage)
+}
+
+// CloseLong 平多仓（quantity=0表示全部平仓）
+func (t *PaperTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+	return t.closeSide(symbol, "long", quantity)
+}
+
+// CloseShort 平空仓（quantity=0表示全部平仓）
+func (t *PaperTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+	return t.closeSide(symbol, "short", quantity)
+}
+
+// SetLeverage 设置杠杆（仅影响之后的开仓）
+func (t *PaperTrader) SetLeverage(symbol string, leverage int) error {
+	if leverage <= 0 {
+		return fmt.Errorf("杠杆倍数必须大于0")
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.state.Leverage[symbol] = leverage
+	t.saveState()
+	return nil
+}
+
+// SetMarginMode 设置仓位模式 (true=全仓, false=逐仓)
+// 模拟盘统一按逐仓公式估算强平价，此处仅记录配置
+func (t *PaperTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.state.CrossMargin[symbol] = isCrossMargin
+	t.saveState()
+	return nil
+}
+
+// GetMarketPrice 获取市场价格（最新3分钟K线收盘价）
+func (t *PaperTrader) GetMarketPrice(symbol string) (float64, error) {
+	klines, err := t.latestKlines(symbol)
+	if err != nil {
+		return 0, err
+	}
+	return klines[len(klines)-1].Close, nil
+}
+
+// setTrigger 设置止损/止盈触发价
+func (t *PaperTrader) setTrigger(symbol, positionSide string, price float64, isStopLoss bool) error {
+	if price <= 0 {
+		return fmt.Errorf("触发价格必须大于0")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sync()
+
+	side := "long"
+	if positionSide == "SHORT" {
+		side = "short"
+	}
+	pos, exists := t.state.Positions[positionKey(symbol, side)]
+	if !exists {
+		return fmt.Errorf("没有找到 %s 的 %s 持仓", symbol, positionSide)
+	}
+
+	if isStopLoss {
+		pos.StopLoss = price
+		log.Printf("  止损价设置: %.4f", price)
+	} else {
+		pos.TakeProfit = price
+		log.Printf("  止盈价设置: %.4f", price)
+	}
+	t.saveState()
+	return nil
+}
+
+// SetStopLoss 设置止损单（触发后全部平仓，与币安 closePosition 行为一致）
+func (t *PaperTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	return t.setTrigger(symbol, positionSide, stopPrice, true)
+}
+
+// SetTakeProfit 设置止盈单（触发后全部平仓，与币安 closePosition 行为一致）
+func (t *PaperTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	return t.setTrigger(symbol, positionSide, takeProfitPrice, false)
+}
+
+// cancelOrders 清除该币种的止损/止盈触发价（调用方需持有锁）
+func (t *PaperTrader) cancelOrders(symbol string, stopLoss, takeProfit bool) {
+	for _, side := range []string{"long", "short"} {
+		pos, exists := t.state.Positions[positionKey(symbol, side)]
+		if !exists {
+			continue
+		}
+		if stopLoss {
+			pos.StopLoss = 0
+		}
+		if takeProfit {
+			pos.TakeProfit = 0
+		}
+	}
+}
+
+// CancelStopLossOrders 仅取消止损单
+func (t *PaperTrader) CancelStopLossOrders(symbol string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cancelOrders(symbol, true, false)
+	t.saveState()
+	return nil
+}
+
+// CancelSideStopLossOrders 仅取消指定持仓方向的止损单
+func (t *PaperTrader) CancelSideStopLossOrders(symbol string, positionSide string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if pos, exists := t.state.Positions[positionKey(symbol, strings.ToLower(positionSide))]; exists {
+		pos.StopLoss = 0
+	}
+	t.saveState()
+	return nil
+}
+
+// CancelTakeProfitOrders 仅取消止盈单
+func (t *PaperTrader) CancelTakeProfitOrders(symbol string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cancelOrders(symbol, false, true)
+	t.saveState()
+	return nil
+}
+
+// CancelAllOrders 取消该币种的所有挂单（含限价单）
+func (t *PaperTrader) CancelAllOrders(symbol string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sync()
+	t.cancelOrders(symbol, true, true)
+	t.cancelLimitOrders(symbol)
+	t.saveState()
+	return nil
+}
+
+// CancelStopOrders 取消该币种的止盈/止损单
+func (t *PaperTrader) CancelStopOrders(symbol string) error {
+	return t.CancelAllOrders(symbol)
+}
+
+// GetTrades 获取成交明细
+func (t *PaperTrader) GetTrades(startTime int64) ([]TradeFill, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sync()
+
+	var fills []TradeFill
+	for _, f := range t.state.Fills {
+		if f.Time >= startTime {
+			fills = append(fills, f)
+		}
+	}
+	return fills, nil
+}
+
+// GetFundingPayments 获取资金费收支
+func (t *PaperTrader) GetFundingPayments(startTime int64) ([]FundingPayment, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sync()
+
+	var payments []FundingPayment
+	for _, p := range t.state.Funding {
+		if p.Time >= startTime {
+			payments = append(payments, p)
+		}
+	}
+	return payments, nil
+}
+
+// FormatQuantity 格式化数量到正确的精度
+func (t *PaperTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
+	factor := math.Pow(10, paperQuantityPrecisionDefault)
+	return fmt.Sprintf("%.*f", paperQuantityPrecisionDefault, math.Floor(quantity*factor+1e-9)/factor), nil
+}
+
+// PlaceLimitOrder 限价开仓
+// 会立即成交的限价单按当前价以吃单费率成交（GTX 直接拒绝），
+// 其余挂单在之后的K线价格穿过限价时按限价以挂单费率成交，IOC 未成交部分直接过期
+func (t *PaperTrader) PlaceLimitOrder(symbol, side string, quantity, price float64, leverage int, timeInForce string) (map[string]interface{}, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sync()
+
+	if quantity <= 0 || price <= 0 {
+		return nil, fmt.Errorf("限价单数量和价格必须大于0")
+	}
+	if leverage <= 0 {
+		leverage = paperDefaultLeverage
+	}
+
+	klines, err := t.latestKlines(symbol)
+	if err != nil {
+		return nil, err
+	}
+	last := klines[len(klines)-1]
+	current := last.Close
+	marketable := (side == "long" && price >= current) || (side == "short" && price <= current)
+	if marketable && timeInForce == TimeInForcePostOnly {
+		return nil, fmt.Errorf("只做 Maker 的限价单会立即成交，已被拒绝（限价 %.4f，当前价 %.4f）", price, current)
+	}
+
+	t.state.Leverage[symbol] = leverage
+	order := &paperOrder{
+		OrderID:       t.nextOrderID(),
+		Symbol:        symbol,
+		Side:          side,
+		Quantity:      quantity,
+		Price:         price,
+		Leverage:      leverage,
+		TimeInForce:   timeInForce,
+		Status:        OrderStatusNew,
+		LastKlineOpen: last.OpenTime,
+		LastHigh:      last.High,
+		LastLow:       last.Low,
+	}
+
+	switch {
+	case marketable:
+		fee := quantity * current * t.takerFeeRate
+		if err := t.checkAvailable(order.margin(), fee); err != nil {
+			return nil, err
+		}
+		t.applyOpen(symbol, side, quantity, current, leverage, fee, order.OrderID, last)
+		order.Status, order.ExecutedQty, order.AvgPrice = OrderStatusFilled, quantity, current
+	case timeInForce == TimeInForceIOC:
+		order.Status = OrderStatusExpired
+	default:
+		if err := t.checkAvailable(order.margin(), quantity*price*t.makerFeeRate); err != nil {
+			return nil, err
+		}
+	}
+	t.state.Orders = append(t.state.Orders, order)
+	t.pruneOrders()
+	t.saveState()
+
+	log.Printf("🧪 模拟盘限价单: %s %s 数量 %.6f @ %.4f (%s) → %s", symbol, side, quantity, price, timeInForce, order.Status)
+	return paperOrderResult(order), nil
+}
+
+// GetOrderStatus 查询订单状态
+func (t *PaperTrader) GetOrderStatus(symbol string, orderID int64) (map[string]interface{}, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sync()
+
+	order := t.findOrder(orderID)
+	if order == nil || order.Symbol != symbol {
+		return nil, fmt.Errorf("订单 %d 不存在", orderID)
+	}
+	return paperOrderResult(order), nil
+}
+
+// CancelOrder 撤销限价挂单（已成交或已结束的订单返回错误）
+func (t *PaperTrader) CancelOrder(symbol string, orderID int64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sync()
+
+	order := t.findOrder(orderID)
+	if order == nil || order.Symbol != symbol {
+		return fmt.Errorf("订单 %d 不存在", orderID)
+	}
+	if !IsOrderOpen(order.Status) {
+		return fmt.Errorf("订单 %d 已结束（%s），无法撤销", orderID, order.Status)
+	}
+	order.Status = OrderStatusCanceled
+	t.saveState()
+	return nil
+}
+
+// matchLimitOrders 用下单之后出现的K线价格撮合限价挂单（调用方需持有锁）
+// 价格需穿过限价才算成交（仅触及不成交），成交价为限价，按挂单费率计费
+func (t *PaperTrader) matchLimitOrders() bool {
+	changed := false
+	for _, order := range t.state.Orders {
+		if !IsOrderOpen(order.Status) {
+			continue
+		}
+		klines, err := t.latestKlines(order.Symbol)
+		if err != nil {
+			log.Printf("⚠️ 模拟盘: %v", err)
+			continue
+		}
+
+		for _, k := range klines {
+			if k.OpenTime < order.LastKlineOpen {
+				continue
+			}
+			low, high := k.Low, k.High
+			if k.OpenTime == order.LastKlineOpen {
+				// 同一根K线：只有突破上次记录的高低点才算新价格
+				low, high = k.Close, k.Close
+				if k.Low < order.LastLow {
+					low = k.Low
+				}
+				if k.High > order.LastHigh {
+					high = k.High
+				}
+			}
+			order.LastKlineOpen, order.LastHigh, order.LastLow = k.OpenTime, k.High, k.Low
+
+			crossed := (order.Side == "long" && low < order.Price) || (order.Side == "short" && high > order.Price)
+			if !crossed {
+				continue
+			}
+
+			// 成交时不再检查保证金：挂单期间保证金已被占用
+			quantity := order.Quantity - order.ExecutedQty
+			fee := quantity * order.Price * t.makerFeeRate
+			order.Status, order.ExecutedQty, order.AvgPrice = OrderStatusFilled, order.Quantity, order.Price
+			t.applyOpen(order.Symbol, order.Side, quantity, order.Price, order.Leverage, fee, order.OrderID, k)
+			log.Printf("🧪 模拟盘限价单成交: %s %s 数量 %.6f @ %.4f (手续费 %.4f)", order.Symbol, order.Side, quantity, order.Price, fee)
+			changed = true
+			break
+		}
+	}
+	return changed
+}
+
+// reservedOrderMargin 限价挂单占用的保证金（调用方需持有锁）
+func (t *PaperTrader) reservedOrderMargin() float64 {
+	reserved := 0.0
+	for _, order := range t.state.Orders {
+		if IsOrderOpen(order.Status) {
+			reserved += order.margin()
+		}
+	}
+	return reserved
+}
+
+// cancelLimitOrders 撤销该币种的所有限价挂单（调用方需持有锁）
+func (t *PaperTrader) cancelLimitOrders(symbol string) {
+	for _, order := range t.state.Orders {
+		if order.Symbol == symbol && IsOrderOpen(order.Status) {
+			order.Status = OrderStatusCanceled
+		}
+	}
+}
+
+// findOrder 按订单ID查找限价单（调用方需持有锁）
+func (t *PaperTrader) findOrder(orderID int64) *paperOrder {
+	for _, order := range t.state.Orders {
+		if order.OrderID == orderID {
+			return order
+		}
+	}
+	return nil
+}
+
+// pruneOrders 只保留挂单中和最近已结束的限价单（调用方需持有锁）
+func (t *PaperTrader) pruneOrders() {
+	closed := 0
+	for _, order := range t.state.Orders {
+		if !IsOrderOpen(order.Status) {
+			closed++
+		}
+	}
+	kept := t.state.Orders[:0]
+	for _, order := range t.state.Orders {
+		if !IsOrderOpen(order.Status) && closed > paperMaxClosedOrders {
+			closed--
+			continue
+		}
+		kept = append(kept, order)
+	}
+	t.state.Orders = kept
+}
+
+// paperOrderResult 订单状态（字段与币安一致）
+func paperOrderResult(order *paperOrder) map[string]interface{} {
+	result := make(map[string]interface{})
+	result["orderId"] = order.OrderID
+	result["symbol"] = order.Symbol
+	result["status"] = order.Status
+	result["quantity"] = order.Quantity
+	result["executedQty"] = order.ExecutedQty
+	result["avgPrice"] = order.AvgPrice
+	return result
+}
@@ -0,0 +1,466 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"nofx/market"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ============================================================
+// 一、PaperTraderTestSuite - 继承 base test suite
+// ============================================================
+
+// mockPaperMarket 模拟行情源（symbol -> K线）
+type mockPaperMarket struct {
+	klines map[string][]market.Kline
+	rate   float64
+	now    time.Time
+}
+
+func (m *mockPaperMarket) getKlines(symbol string) ([]market.Kline, error) {
+	klines, ok := m.klines[symbol]
+	if !ok {
+		return nil, fmt.Errorf("unknown symbol %s", symbol)
+	}
+	return klines, nil
+}
+
+// pushKline 追加一根新K线
+func (m *mockPaperMarket) pushKline(symbol string, open, high, low, close float64) {
+	klines := m.klines[symbol]
+	openTime := int64(0)
+	if len(klines) > 0 {
+		openTime = klines[len(klines)-1].OpenTime + 3*60*1000
+	}
+	m.klines[symbol] = append(klines, market.Kline{
+		OpenTime: openTime, Open: open, High: high, Low: low, Close: close,
+		CloseTime: openTime + 3*60*1000 - 1,
+	})
+}
+
+func newMockPaperMarket() *mockPaperMarket {
+	m := &mockPaperMarket{
+		klines: make(map[string][]market.Kline),
+		now:    time.Date(2025, 1, 1, 1, 0, 0, 0, time.UTC),
+	}
+	m.pushKline("BTCUSDT", 50000, 50000, 50000, 50000)
+	m.pushKline("ETHUSDT", 3000, 3000, 3000, 3000)
+	return m
+}
+
+func newTestPaperTrader(t *testing.T, balance float64, stateFile string) (*PaperTrader, *mockPaperMarket) {
+	pt, err := NewPaperTrader(balance, stateFile)
+	require.NoError(t, err)
+
+	m := newMockPaperMarket()
+	pt.klineFunc = m.getKlines
+	pt.fundingRateFunc = func(string, int64) (float64, error) { return m.rate, nil }
+	pt.nowFunc = func() time.Time { return m.now }
+	return pt, m
+}
+
+// TestPaperTrader_CommonInterface 运行通用接口测试中与持仓状态无关的部分
+func TestPaperTrader_CommonInterface(t *testing.T) {
+	pt, _ := newTestPaperTrader(t, 10000, "")
+	suite := NewTraderTestSuite(t, pt)
+	defer suite.Cleanup()
+
+	t.Run("GetBalance", func(t *testing.T) { suite.TestGetBalance() })
+	t.Run("GetPositions", func(t *testing.T) { suite.TestGetPositions() })
+	t.Run("GetMarketPrice", func(t *testing.T) { suite.TestGetMarketPrice() })
+	t.Run("SetLeverage", func(t *testing.T) { suite.TestSetLeverage() })
+	t.Run("SetMarginMode", func(t *testing.T) { suite.TestSetMarginMode() })
+	t.Run("FormatQuantity", func(t *testing.T) { suite.TestFormatQuantity() })
+	t.Run("CancelAllOrders", func(t *testing.T) { suite.TestCancelAllOrders() })
+}
+
+// ============================================================
+// 二、账本与撮合测试
+// ============================================================
+
+func TestPaperTrader_OpenAndCloseLedger(t *testing.T) {
+	pt, m := newTestPaperTrader(t, 1000, "")
+
+	order, err := pt.OpenLong("BTCUSDT", 0.1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), order["orderId"])
+
+	// 名义价值 5000，保证金 500，手续费 2
+	balance, err := pt.GetBalance()
+	require.NoError(t, err)
+	assert.InDelta(t, 998.0, balance["totalWalletBalance"].(float64), 1e-9)
+	assert.InDelta(t, 498.0, balance["availableBalance"].(float64), 1e-9)
+
+	// 价格上涨到 51000
+	m.pushKline("BTCUSDT", 50000, 51000, 50000, 51000)
+	positions, err := pt.GetPositions()
+	require.NoError(t, err)
+	require.Len(t, positions, 1)
+	assert.Equal(t, "long", positions[0]["side"])
+	assert.InDelta(t, 100.0, positions[0]["unRealizedProfit"].(float64), 1e-9)
+	assert.InDelta(t, 51000.0, positions[0]["markPrice"].(float64), 1e-9)
+
+	// 平一半：盈利 50，手续费 0.05*51000*0.0004 = 1.02
+	_, err = pt.CloseLong("BTCUSDT", 0.05)
+	require.NoError(t, err)
+	balance, _ = pt.GetBalance()
+	assert.InDelta(t, 998.0+50-1.02, balance["totalWalletBalance"].(float64), 1e-9)
+
+	// 全部平仓
+	_, err = pt.CloseLong("BTCUSDT", 0)
+	require.NoError(t, err)
+	positions, _ = pt.GetPositions()
+	assert.Empty(t, positions)
+
+	// 无持仓时平仓应返回错误
+	_, err = pt.CloseLong("BTCUSDT", 0)
+	assert.Error(t, err)
+}
+
+func TestPaperTrader_ShortPositionAmountNegative(t *testing.T) {
+	pt, m := newTestPaperTrader(t, 1000, "")
+
+	_, err := pt.OpenShort("ETHUSDT", 1, 5)
+	require.NoError(t, err)
+
+	m.pushKline("ETHUSDT", 3000, 3000, 2900, 2900)
+	positions, err := pt.GetPositions()
+	require.NoError(t, err)
+	require.Len(t, positions, 1)
+	assert.Equal(t, "short", positions[0]["side"])
+	assert.Equal(t, -1.0, positions[0]["positionAmt"])
+	assert.InDelta(t, 100.0, positions[0]["unRealizedProfit"].(float64), 1e-9)
+}
+
+func TestPaperTrader_InsufficientMargin(t *testing.T) {
+	pt, _ := newTestPaperTrader(t, 100, "")
+
+	// 名义价值 50000 / 10x = 5000 保证金，远超余额
+	_, err := pt.OpenLong("BTCUSDT", 1, 10)
+	assert.Error(t, err)
+
+	positions, _ := pt.GetPositions()
+	assert.Empty(t, positions)
+}
+
+func TestPaperTrader_StopLossAndTakeProfitTriggers(t *testing.T) {
+	tests := []struct {
+		name       string
+		side       string
+		stopLoss   float64
+		takeProfit float64
+		kline      [4]float64 // open, high, low, close
+		wantClosed bool
+		wantPnL    float64 // 平仓盈亏（不含手续费）
+	}{
+		{
+			name: "多头触发止损", side: "LONG", stopLoss: 49000, takeProfit: 52000,
+			kline: [4]float64{50000, 50100, 48800, 49500}, wantClosed: true, wantPnL: -100,
+		},
+		{
+			name: "多头触发止盈", side: "LONG", stopLoss: 49000, takeProfit: 52000,
+			kline: [4]float64{50000, 52500, 49900, 52100}, wantClosed: true, wantPnL: 200,
+		},
+		{
+			name: "多头跳空低开按开盘价止损", side: "LONG", stopLoss: 49000, takeProfit: 52000,
+			kline: [4]float64{48500, 48600, 48000, 48200}, wantClosed: true, wantPnL: -150,
+		},
+		{
+			name: "同一根K线同时触及止损止盈按止损处理", side: "LONG", stopLoss: 49000, takeProfit: 52000,
+			kline: [4]float64{50000, 52500, 48800, 50000}, wantClosed: true, wantPnL: -100,
+		},
+		{
+			name: "空头触发止损", side: "SHORT", stopLoss: 51000, takeProfit: 48000,
+			kline: [4]float64{50000, 51200, 49900, 51000}, wantClosed: true, wantPnL: -100,
+		},
+		{
+			name: "价格未触及不平仓", side: "LONG", stopLoss: 49000, takeProfit: 52000,
+			kline: [4]float64{50000, 51000, 49500, 50500}, wantClosed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pt, m := newTestPaperTrader(t, 10000, "")
+
+			var err error
+			if tt.side == "LONG" {
+				_, err = pt.OpenLong("BTCUSDT", 0.1, 10)
+			} else {
+				_, err = pt.OpenShort("BTCUSDT", 0.1, 10)
+			}
+			require.NoError(t, err)
+			require.NoError(t, pt.SetStopLoss("BTCUSDT", tt.side, 0.1, tt.stopLoss))
+			require.NoError(t, pt.SetTakeProfit("BTCUSDT", tt.side, 0.1, tt.takeProfit))
+
+			before, _ := pt.GetBalance()
+			m.pushKline("BTCUSDT", tt.kline[0], tt.kline[1], tt.kline[2], tt.kline[3])
+			positions, err := pt.GetPositions()
+			require.NoError(t, err)
+
+			if !tt.wantClosed {
+				assert.Len(t, positions, 1)
+				return
+			}
+			assert.Empty(t, positions)
+
+			after, _ := pt.GetBalance()
+			walletDelta := after["totalWalletBalance"].(float64) - before["totalWalletBalance"].(float64)
+			exitPrice := 50000 + tt.wantPnL/0.1
+			if tt.side == "SHORT" {
+				exitPrice = 50000 - tt.wantPnL/0.1
+			}
+			assert.InDelta(t, tt.wantPnL-0.1*exitPrice*paperDefaultTakerFeeRate, walletDelta, 1e-6)
+		})
+	}
+}
+
+func TestPaperTrader_TriggerIgnoresPricesBeforeOrder(t *testing.T) {
+	pt, m := newTestPaperTrader(t, 10000, "")
+
+	// 当前K线已经出现过 48000 的低点，之后才开仓设置止损
+	m.klines["BTCUSDT"][0] = market.Kline{OpenTime: 0, Open: 50000, High: 50000, Low: 48000, Close: 50000}
+	_, err := pt.OpenLong("BTCUSDT", 0.1, 10)
+	require.NoError(t, err)
+	require.NoError(t, pt.SetStopLoss("BTCUSDT", "LONG", 0.1, 49000))
+
+	// 同一根K线更新，但没有创出新低，不应触发
+	m.klines["BTCUSDT"][0].Close = 49800
+	positions, _ := pt.GetPositions()
+	assert.Len(t, positions, 1)
+
+	// 创出新低后触发
+	m.klines["BTCUSDT"][0].Low = 47900
+	positions, _ = pt.GetPositions()
+	assert.Empty(t, positions)
+}
+
+func TestPaperTrader_CancelStopLossKeepsTakeProfit(t *testing.T) {
+	pt, m := newTestPaperTrader(t, 10000, "")
+
+	_, err := pt.OpenLong("BTCUSDT", 0.1, 10)
+	require.NoError(t, err)
+	require.NoError(t, pt.SetStopLoss("BTCUSDT", "LONG", 0.1, 49000))
+	require.NoError(t, pt.SetTakeProfit("BTCUSDT", "LONG", 0.1, 52000))
+	require.NoError(t, pt.CancelStopLossOrders("BTCUSDT"))
+
+	m.pushKline("BTCUSDT", 50000, 50000, 48000, 48500)
+	positions, _ := pt.GetPositions()
+	assert.Len(t, positions, 1, "止损已取消，不应触发")
+
+	m.pushKline("BTCUSDT", 48500, 52100, 48500, 52000)
+	positions, _ = pt.GetPositions()
+	assert.Empty(t, positions, "止盈仍然有效")
+}
+
+func TestPaperTrader_Liquidation(t *testing.T) {
+	pt, m := newTestPaperTrader(t, 1000, "")
+
+	_, err := pt.OpenLong("BTCUSDT", 0.1, 20)
+	require.NoError(t, err)
+
+	// 20x 多仓强平价约为 50000*(1-0.05+0.004) = 47700
+	m.pushKline("BTCUSDT", 50000, 50000, 47000, 47500)
+	positions, _ := pt.GetPositions()
+	assert.Empty(t, positions)
+
+	balance, _ := pt.GetBalance()
+	assert.Less(t, balance["totalWalletBalance"].(float64), 1000.0-200)
+}
+
+func TestPaperTrader_FundingSettlement(t *testing.T) {
+	pt, m := newTestPaperTrader(t, 10000, "")
+	m.rate = 0.0001
+
+	_, err := pt.OpenLong("BTCUSDT", 1, 10)
+	require.NoError(t, err)
+	_, err = pt.OpenShort("ETHUSDT", 1, 10)
+	require.NoError(t, err)
+	before, _ := pt.GetBalance()
+
+	// 01:00 -> 17:00 UTC 经过 08:00 和 16:00 两个结算点
+	m.now = m.now.Add(16 * time.Hour)
+	after, err := pt.GetBalance()
+	require.NoError(t, err)
+
+	// 多头支付 2*50000*0.0001 = 10，空头收取 2*3000*0.0001 = 0.6
+	delta := after["totalWalletBalance"].(float64) - before["totalWalletBalance"].(float64)
+	assert.InDelta(t, -10+0.6, delta, 1e-9)
+	assert.InDelta(t, 9.4, after["totalFunding"].(float64), 1e-9)
+}
+
+func TestPaperTrader_FundingRetryAndHistoricalRates(t *testing.T) {
+	pt, m := newTestPaperTrader(t, 10000, "")
+	rates := map[int64]float64{}
+	fail := true
+	pt.fundingRateFunc = func(symbol string, fundingTime int64) (float64, error) {
+		if fail {
+			return 0, errors.New("network error")
+		}
+		rate, ok := rates[fundingTime]
+		if !ok {
+			return 0, errors.New("no record")
+		}
+		return rate, nil
+	}
+
+	_, err := pt.OpenLong("BTCUSDT", 1, 10)
+	require.NoError(t, err)
+	before, _ := pt.GetBalance()
+
+	// 01:00 -> 17:00 UTC：获取费率失败时不结算，也不跳过结算点
+	m.now = m.now.Add(16 * time.Hour)
+	after, err := pt.GetBalance()
+	require.NoError(t, err)
+	assert.Equal(t, before["totalWalletBalance"], after["totalWalletBalance"])
+	assert.Equal(t, 0.0, after["totalFunding"])
+
+	// 恢复后按各结算点的历史费率补结算；16:00 记录尚未出现时只结算 08:00
+	fail = false
+	day := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	rates[day.Add(8*time.Hour).UnixMilli()] = 0.0001
+	after, _ = pt.GetBalance()
+	assert.InDelta(t, 5.0, after["totalFunding"].(float64), 1e-9)
+
+	rates[day.Add(16*time.Hour).UnixMilli()] = -0.0002
+	after, _ = pt.GetBalance()
+	assert.InDelta(t, 5.0-10.0, after["totalFunding"].(float64), 1e-9)
+
+	funding, err := pt.GetFundingPayments(0)
+	require.NoError(t, err)
+	require.Len(t, funding, 2)
+	assert.Equal(t, day.Add(8*time.Hour).UnixMilli(), funding[0].Time)
+	assert.Equal(t, day.Add(16*time.Hour).UnixMilli(), funding[1].Time)
+}
+
+func TestPaperTrader_TradesAndFundingHistory(t *testing.T) {
+	pt, m := newTestPaperTrader(t, 10000, "")
+	m.rate = 0.0001
+	start := m.now.UnixMilli()
+
+	_, err := pt.OpenShort("BTCUSDT", 1, 10)
+	require.NoError(t, err)
+	m.now = m.now.Add(8 * time.Hour)
+	m.pushKline("BTCUSDT", 50000, 50000, 49000, 49000)
+	_, err = pt.CloseShort("BTCUSDT", 0)
+	require.NoError(t, err)
+
+	fills, err := pt.GetTrades(start)
+	require.NoError(t, err)
+	require.Len(t, fills, 2)
+	assert.Equal(t, "SELL", fills[0].Side)
+	assert.Equal(t, "SHORT", fills[0].PositionSide)
+	assert.True(t, fills[0].IsOpen())
+	assert.InDelta(t, 20.0, fills[0].Fee, 1e-9)
+	assert.Equal(t, "BUY", fills[1].Side)
+	assert.False(t, fills[1].IsOpen())
+	assert.InDelta(t, 1000.0, fills[1].RealizedPnL, 1e-9)
+
+	// 08:00 结算点：空头收取 49000*0.0001 = 4.9
+	funding, err := pt.GetFundingPayments(start)
+	require.NoError(t, err)
+	require.Len(t, funding, 1)
+	assert.InDelta(t, 4.9, funding[0].Amount, 1e-9)
+
+	// startTime 之后没有新记录
+	fills, _ = pt.GetTrades(m.now.UnixMilli() + 1)
+	assert.Empty(t, fills)
+}
+
+func TestPaperTrader_LimitOrderMakerFill(t *testing.T) {
+	pt, m := newTestPaperTrader(t, 1000, "")
+
+	// 会立即成交的只做 Maker 单被拒绝
+	_, err := pt.PlaceLimitOrder("BTCUSDT", "long", 0.1, 51000, 10, TimeInForcePostOnly)
+	assert.Error(t, err)
+
+	order, err := pt.PlaceLimitOrder("BTCUSDT", "long", 0.1, 49000, 10, TimeInForcePostOnly)
+	require.NoError(t, err)
+	assert.Equal(t, OrderStatusNew, order["status"])
+	orderID := order["orderId"].(int64)
+
+	// 挂单占用保证金 490
+	balance, _ := pt.GetBalance()
+	assert.InDelta(t, 1000.0, balance["totalWalletBalance"].(float64), 1e-9)
+	assert.InDelta(t, 510.0, balance["availableBalance"].(float64), 1e-9)
+
+	// 只触及限价不成交，价格穿过限价才成交
+	m.pushKline("BTCUSDT", 50000, 50000, 49000, 49500)
+	status, err := pt.GetOrderStatus("BTCUSDT", orderID)
+	require.NoError(t, err)
+	assert.Equal(t, OrderStatusNew, status["status"])
+
+	m.pushKline("BTCUSDT", 49500, 49500, 48900, 49200)
+	status, err = pt.GetOrderStatus("BTCUSDT", orderID)
+	require.NoError(t, err)
+	assert.Equal(t, OrderStatusFilled, status["status"])
+	assert.Equal(t, 0.1, status["executedQty"])
+	assert.Equal(t, 49000.0, status["avgPrice"])
+
+	// 按限价成交，Maker 手续费 0.1*49000*0.0002 = 0.98
+	positions, _ := pt.GetPositions()
+	require.Len(t, positions, 1)
+	assert.InDelta(t, 49000.0, positions[0]["entryPrice"].(float64), 1e-9)
+	balance, _ = pt.GetBalance()
+	assert.InDelta(t, 999.02, balance["totalWalletBalance"].(float64), 1e-9)
+
+	assert.Error(t, pt.CancelOrder("BTCUSDT", orderID), "已成交的订单不能撤销")
+}
+
+func TestPaperTrader_LimitOrderCancelAndIOC(t *testing.T) {
+	pt, _ := newTestPaperTrader(t, 1000, "")
+
+	// 不能立即成交的 IOC 单直接过期
+	order, err := pt.PlaceLimitOrder("ETHUSDT", "short", 1, 3100, 5, TimeInForceIOC)
+	require.NoError(t, err)
+	assert.Equal(t, OrderStatusExpired, order["status"])
+
+	// 可立即成交的限价单按当前价吃单成交
+	order, err = pt.PlaceLimitOrder("ETHUSDT", "short", 1, 2900, 5, TimeInForceGTC)
+	require.NoError(t, err)
+	assert.Equal(t, OrderStatusFilled, order["status"])
+	assert.Equal(t, 3000.0, order["avgPrice"])
+
+	order, err = pt.PlaceLimitOrder("BTCUSDT", "short", 0.01, 52000, 5, TimeInForceGTC)
+	require.NoError(t, err)
+	orderID := order["orderId"].(int64)
+	require.NoError(t, pt.CancelOrder("BTCUSDT", orderID))
+	status, err := pt.GetOrderStatus("BTCUSDT", orderID)
+	require.NoError(t, err)
+	assert.Equal(t, OrderStatusCanceled, status["status"])
+
+	// 保证金不足时拒绝挂单
+	_, err = pt.PlaceLimitOrder("BTCUSDT", "long", 1, 49000, 10, TimeInForceGTC)
+	assert.Error(t, err)
+}
+
+func TestPaperTrader_StatePersistence(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "paper.json")
+
+	pt, _ := newTestPaperTrader(t, 1000, stateFile)
+	_, err := pt.OpenLong("BTCUSDT", 0.1, 10)
+	require.NoError(t, err)
+	require.NoError(t, pt.SetStopLoss("BTCUSDT", "LONG", 0.1, 49000))
+
+	// 重新加载：initialBalance 不应覆盖已保存的余额
+	restored, _ := newTestPaperTrader(t, 5000, stateFile)
+	balance, err := restored.GetBalance()
+	require.NoError(t, err)
+	assert.InDelta(t, 998.0, balance["totalWalletBalance"].(float64), 1e-9)
+
+	positions, err := restored.GetPositions()
+	require.NoError(t, err)
+	require.Len(t, positions, 1)
+	assert.Equal(t, "BTCUSDT", positions[0]["symbol"])
+	assert.Equal(t, 49000.0, restored.state.Positions["BTCUSDT_long"].StopLoss)
+
+	order, err := restored.CloseLong("BTCUSDT", 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), order["orderId"])
+}
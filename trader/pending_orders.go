@@ -0,0 +1,540 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/events"
+	"nofx/logger"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 限价单超时处理方式
+const (
+	LimitFallbackCancel = "cancel" // 撤单，已成交部分正常设置止损止盈（默认）
+	LimitFallbackMarket = "market" // 撤单后剩余部分转市价开仓
+)
+
+// 挂单跟踪状态
+const (
+	PendingOrderOpen      = "open"      // 挂单中（含部分成交）
+	PendingOrderFilled    = "filled"    // 全部成交
+	PendingOrderCanceled  = "canceled"  // 超时撤单或被交易所/用户撤销
+	PendingOrderConverted = "converted" // 超时后剩余部分已转市价成交
+)
+
+// pendingOrderCheckInterval 挂单成交检查间隔（成交后尽快设置止损止盈）
+const pendingOrderCheckInterval = 15 * time.Second
+
+// ValidLimitOrderFallback 检查限价单超时处理方式是否有效（空值视为撤单）
+func ValidLimitOrderFallback(fallback string) bool {
+	return fallback == "" || fallback == LimitFallbackCancel || fallback == LimitFallbackMarket
+}
+
+// PendingOrder 跟踪中的限价开仓单
+//
+// 成交后按决策中的止损止盈保护持仓；超时未成交时按配置撤单或转市价
+type PendingOrder struct {
+	OrderID    int64     `json:"order_id"`
+	Symbol     string    `json:"symbol"`
+	Side       string    `json:"side"`       // long / short
+	EntryType  string    `json:"entry_type"` // limit / post_only
+	LimitPrice float64   `json:"limit_price"`
+	Quantity   float64   `json:"quantity"`
+	FilledQty  float64   `json:"filled_qty"`
+	AvgPrice   float64   `json:"avg_price,omitempty"`
+	Leverage   int       `json:"leverage"`
+	StopLoss   float64   `json:"stop_loss"`
+	TakeProfit float64   `json:"take_profit"`
+	Status     string    `json:"status"`
+	Reason     string    `json:"reason,omitempty"` // 撤单/转市价原因
+	PlacedAt   time.Time `json:"placed_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	ResolvedAt time.Time `json:"resolved_at,omitempty"`
+
+	reported bool // 结束后是否已在提示词中告知AI
+}
+
+// limitOrderTimeout 限价单有效时长（未配置时为一个扫描周期）
+func (at *AutoTrader) limitOrderTimeout() time.Duration {
+	if at.config.LimitOrderTimeout > 0 {
+		return at.config.LimitOrderTimeout
+	}
+	return at.config.ScanInterval
+}
+
+// limitOrderFallback 限价单超时处理方式
+func (at *AutoTrader) limitOrderFallback() string {
+	if at.config.LimitOrderFallback == "" {
+		return LimitFallbackCancel
+	}
+	return at.config.LimitOrderFallback
+}
+
+// checkNoPendingOrder 同币种同方向已有挂单时拒绝开仓（防止成交后仓位叠加）
+func (at *AutoTrader) checkNoPendingOrder(symbol, side string) error {
+	at.pendingOrderMu.Lock()
+	defer at.pendingOrderMu.Unlock()
+	for _, order := range at.pendingOrders {
+		if order.Status == PendingOrderOpen && order.Symbol == symbol && order.Side == side {
+			return fmt.Errorf("❌ %s 已有%s限价挂单（订单 %d），拒绝重复开仓", symbol, sideName(side), order.OrderID)
+		}
+	}
+	return nil
+}
+
+// placeLimitEntry 挂限价开仓单并登记跟踪（调用方需持有 execMu，已完成持仓和保证金检查）
+func (at *AutoTrader) placeLimitEntry(d *decision.Decision, side string, actionRecord *logger.DecisionAction) error {
+	timeInForce := TimeInForceGTC
+	if d.EntryType == decision.EntryTypePostOnly {
+		timeInForce = TimeInForcePostOnly
+	}
+
+	quantity := d.PositionSizeUSD / d.LimitPrice
+	actionRecord.Quantity = quantity
+	actionRecord.Price = d.LimitPrice
+
+	result, err := at.trader.PlaceLimitOrder(d.Symbol, side, quantity, d.LimitPrice, d.Leverage, timeInForce)
+	if err != nil {
+		return err
+	}
+	orderID, _ := result["orderId"].(int64)
+	actionRecord.OrderID = orderID
+	if placed, ok := result["quantity"].(float64); ok && placed > 0 {
+		quantity = placed
+		actionRecord.Quantity = placed
+	}
+
+	now := time.Now()
+	order := &PendingOrder{
+		OrderID:    orderID,
+		Symbol:     d.Symbol,
+		Side:       side,
+		EntryType:  d.EntryType,
+		LimitPrice: d.LimitPrice,
+		Quantity:   quantity,
+		Leverage:   d.Leverage,
+		StopLoss:   d.StopLoss,
+		TakeProfit: d.TakeProfit,
+		Status:     PendingOrderOpen,
+		PlacedAt:   now,
+		ExpiresAt:  now.Add(at.limitOrderTimeout()),
+	}
+
+	status, _ := result["status"].(string)
+	switch {
+	case status == OrderStatusFilled:
+		log.Printf("  ✓ 限价单立即成交，订单ID: %d, 数量: %.4f", orderID, quantity)
+		at.registerPendingOrder(order)
+		avgPrice, _ := result["avgPrice"].(float64)
+		at.finishPendingOrder(order, PendingOrderFilled, quantity, avgPrice, "")
+	case IsOrderOpen(status):
+		log.Printf("  ✓ 限价单已挂出，订单ID: %d, 价格: %.4f, 数量: %.4f（%s 前未成交则%s）", orderID, d.LimitPrice, quantity,
+			order.ExpiresAt.Format("15:04:05"), fallbackName(at.limitOrderFallback()))
+		at.registerPendingOrder(order)
+	default:
+		return fmt.Errorf("限价单未挂出，交易所状态: %s", status)
+	}
+	return nil
+}
+
+// registerPendingOrder 登记挂单并发布事件
+func (at *AutoTrader) registerPendingOrder(order *PendingOrder) {
+	at.pendingOrderMu.Lock()
+	if at.pendingOrders == nil {
+		at.pendingOrders = make(map[int64]*PendingOrder)
+	}
+	at.pendingOrders[order.OrderID] = order
+	data := order.eventData()
+	at.pendingOrderMu.Unlock()
+
+	at.publishEvent(events.OrderPlaced, data)
+}
+
+// startPendingOrderMonitor 启动挂单成交监控
+func (at *AutoTrader) startPendingOrderMonitor() {
+	at.monitorWg.Add(1)
+	go func() {
+		defer at.monitorWg.Done()
+
+		ticker := time.NewTicker(pendingOrderCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				at.checkPendingOrders()
+			case <-at.stopMonitorCh:
+				return
+			}
+		}
+	}()
+}
+
+// checkPendingOrders 检查所有挂单：成交后设置止损止盈，超时撤单或转市价
+func (at *AutoTrader) checkPendingOrders() {
+	at.execMu.Lock()
+	defer at.execMu.Unlock()
+
+	at.pendingOrderMu.Lock()
+	var open []*PendingOrder
+	for _, order := range at.pendingOrders {
+		if order.Status == PendingOrderOpen {
+			open = append(open, order)
+		}
+	}
+	at.pendingOrderMu.Unlock()
+
+	now := time.Now()
+	for _, order := range open {
+		at.checkPendingOrder(order, now)
+	}
+}
+
+// checkPendingOrder 查询单个挂单的状态并处理（调用方需持有 execMu）
+func (at *AutoTrader) checkPendingOrder(order *PendingOrder, now time.Time) {
+	status, executedQty, avgPrice, err := at.queryOrder(order)
+	if err != nil {
+		log.Printf("⚠️ 查询挂单失败 (%s 订单 %d): %v", order.Symbol, order.OrderID, err)
+		return
+	}
+
+	switch {
+	case status == OrderStatusFilled:
+		at.finishPendingOrder(order, PendingOrderFilled, executedQty, avgPrice, "")
+	case !IsOrderOpen(status):
+		at.finishPendingOrder(order, PendingOrderCanceled, executedQty, avgPrice, fmt.Sprintf("交易所订单状态 %s", status))
+	case now.After(order.ExpiresAt):
+		at.expirePendingOrder(order)
+	default:
+		at.pendingOrderMu.Lock()
+		order.FilledQty = executedQty
+		order.AvgPrice = avgPrice
+		at.pendingOrderMu.Unlock()
+	}
+}
+
+// expirePendingOrder 超时未成交：撤单，按配置将剩余部分转市价
+func (at *AutoTrader) expirePendingOrder(order *PendingOrder) {
+	if err := at.trader.CancelOrder(order.Symbol, order.OrderID); err != nil {
+		// 撤单期间可能刚好成交，以撤单后的订单状态为准
+		log.Printf("⚠️ 撤销超时挂单失败 (%s 订单 %d): %v", order.Symbol, order.OrderID, err)
+	}
+	status, executedQty, avgPrice, err := at.queryOrder(order)
+	if err != nil {
+		log.Printf("⚠️ 查询挂单失败 (%s 订单 %d): %v", order.Symbol, order.OrderID, err)
+		return
+	}
+	if IsOrderOpen(status) {
+		// 撤单未生效，下次检查重试
+		return
+	}
+	if status == OrderStatusFilled {
+		at.finishPendingOrder(order, PendingOrderFilled, executedQty, avgPrice, "")
+		return
+	}
+
+	remaining := order.Quantity - executedQty
+	if at.limitOrderFallback() != LimitFallbackMarket || remaining <= 0 {
+		at.finishPendingOrder(order, PendingOrderCanceled, executedQty, avgPrice, "超时未成交，已撤单")
+		return
+	}
+
+	at.convertToMarket(order, remaining, executedQty, avgPrice)
+}
+
+// convertToMarket 超时撤单后将剩余部分转市价开仓（调用方需持有 execMu）
+//
+// 与 executeOpenXWithRecord 相同，转市价前检查熔断暂停、同方向挂单和组合风控，风控可缩减剩余数量
+func (at *AutoTrader) convertToMarket(order *PendingOrder, remaining, executedQty, avgPrice float64) {
+	// 交易所上的挂单已撤销，先移出挂单状态，避免重复挂单检查和风控快照计入这笔订单本身
+	at.pendingOrderMu.Lock()
+	order.Status = PendingOrderCanceled
+	at.pendingOrderMu.Unlock()
+
+	if stopUntil := at.pausedUntil(); time.Now().Before(stopUntil) {
+		at.finishPendingOrder(order, PendingOrderCanceled, executedQty, avgPrice,
+			fmt.Sprintf("超时撤单，熔断暂停中（至 %s），剩余部分不转市价", stopUntil.Format("15:04:05")))
+		return
+	}
+	if err := at.checkNoPendingOrder(order.Symbol, order.Side); err != nil {
+		at.finishPendingOrder(order, PendingOrderCanceled, executedQty, avgPrice, fmt.Sprintf("超时撤单，剩余部分不转市价: %v", err))
+		return
+	}
+
+	d := &decision.Decision{
+		Action:          "open_" + order.Side,
+		Symbol:          order.Symbol,
+		Leverage:        order.Leverage,
+		PositionSizeUSD: remaining * order.LimitPrice,
+	}
+	// 挂单监控在周期之间运行，重新加载风控账户快照
+	at.riskAccount = nil
+	if err := at.checkRisk(d); err != nil {
+		at.finishPendingOrder(order, PendingOrderCanceled, executedQty, avgPrice, fmt.Sprintf("超时撤单，剩余部分不转市价: %v", err))
+		return
+	}
+	remaining = d.PositionSizeUSD / order.LimitPrice
+
+	log.Printf("🔁 限价单超时，剩余 %.4f 转市价开仓: %s %s", remaining, order.Symbol, order.Side)
+	var err error
+	if order.Side == "long" {
+		_, err = at.trader.OpenLong(order.Symbol, remaining, order.Leverage)
+	} else {
+		_, err = at.trader.OpenShort(order.Symbol, remaining, order.Leverage)
+	}
+	if err != nil {
+		at.finishPendingOrder(order, PendingOrderCanceled, executedQty, avgPrice, fmt.Sprintf("超时撤单，剩余部分转市价失败: %v", err))
+		return
+	}
+	at.trackRiskAccount(d)
+	at.finishPendingOrder(order, PendingOrderConverted, executedQty+remaining, avgPrice, "超时未成交，剩余部分已转市价")
+}
+
+// cancelPendingOrders 撤销匹配的限价开仓挂单，返回已撤销的挂单数（调用方需持有 execMu）
+//
+// symbol / side 为空时匹配全部。平仓前调用，避免挂单随后成交重新开仓；撤单时已成交的部分按正常成交处理，随后由平仓一并平掉
+func (at *AutoTrader) cancelPendingOrders(symbol, side, reason string) int {
+	at.pendingOrderMu.Lock()
+	var matched []*PendingOrder
+	for _, order := range at.pendingOrders {
+		if order.Status == PendingOrderOpen && (symbol == "" || order.Symbol == symbol) && (side == "" || order.Side == side) {
+			matched = append(matched, order)
+		}
+	}
+	at.pendingOrderMu.Unlock()
+
+	canceled := 0
+	for _, order := range matched {
+		if err := at.trader.CancelOrder(order.Symbol, order.OrderID); err != nil {
+			// 撤单期间可能刚好成交，以撤单后的订单状态为准
+			log.Printf("⚠️ 撤销挂单失败 (%s 订单 %d): %v", order.Symbol, order.OrderID, err)
+		}
+		status, executedQty, avgPrice, err := at.queryOrder(order)
+		if err != nil {
+			log.Printf("⚠️ 查询挂单失败 (%s 订单 %d): %v", order.Symbol, order.OrderID, err)
+			continue
+		}
+		switch {
+		case IsOrderOpen(status):
+			log.Printf("⚠️ 挂单撤销未生效 (%s 订单 %d)，由挂单监控继续跟踪", order.Symbol, order.OrderID)
+		case status == OrderStatusFilled:
+			at.finishPendingOrder(order, PendingOrderFilled, executedQty, avgPrice, "")
+		default:
+			at.finishPendingOrder(order, PendingOrderCanceled, executedQty, avgPrice, reason)
+			canceled++
+		}
+	}
+	return canceled
+}
+
+// queryOrder 查询交易所订单状态、已成交数量和成交均价
+func (at *AutoTrader) queryOrder(order *PendingOrder) (string, float64, float64, error) {
+	result, err := at.trader.GetOrderStatus(order.Symbol, order.OrderID)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	status, _ := result["status"].(string)
+	executedQty, _ := result["executedQty"].(float64)
+	avgPrice, _ := result["avgPrice"].(float64)
+	return status, executedQty, avgPrice, nil
+}
+
+// finishPendingOrder 结束挂单跟踪；有成交时按决策设置止损止盈（调用方需持有 execMu）
+func (at *AutoTrader) finishPendingOrder(order *PendingOrder, status string, filledQty, avgPrice float64, reason string) {
+	at.pendingOrderMu.Lock()
+	order.Status = status
+	order.FilledQty = filledQty
+	if avgPrice > 0 {
+		order.AvgPrice = avgPrice
+	}
+	order.Reason = reason
+	order.ResolvedAt = time.Now()
+	data := order.eventData()
+	at.pendingOrderMu.Unlock()
+
+	if filledQty > 0 {
+		log.Printf("✅ 限价开仓成交: %s %s 数量 %.4f（%s）", order.Symbol, order.Side, filledQty, order.statusText())
+		at.protectFilledEntry(order.Symbol, order.Side, filledQty, order.StopLoss, order.TakeProfit)
+		at.publishEvent(events.OrderFilled, data)
+		return
+	}
+	log.Printf("🚫 限价开仓单结束: %s %s 订单 %d（%s）", order.Symbol, order.Side, order.OrderID, order.statusText())
+	at.publishEvent(events.OrderCanceled, data)
+}
+
+// protectFilledEntry 为成交的限价开仓设置止损止盈并重置退出策略状态
+func (at *AutoTrader) protectFilledEntry(symbol, side string, quantity, stopLoss, takeProfit float64) {
+	at.positionFirstSeenTime[symbol+"_"+side] = time.Now().UnixMilli()
+
+	positionSide := strings.ToUpper(side)
+	if err := at.trader.SetStopLoss(symbol, positionSide, quantity, stopLoss); err != nil {
+		log.Printf("  ⚠ 设置止损失败: %v", err)
+		stopLoss = 0
+	}
+	// 重置该持仓的退出策略状态，记录初始止损（用于计算R）
+	at.recordStopLoss(symbol, side, stopLoss, true)
+	if err := at.trader.SetTakeProfit(symbol, positionSide, quantity, takeProfit); err != nil {
+		log.Printf("  ⚠ 设置止盈失败: %v", err)
+	}
+}
+
+// pendingOrderInfos 提示词中的挂单列表：挂单中的订单，以及上个周期之后结束的订单（只告知一次）
+func (at *AutoTrader) pendingOrderInfos() []decision.PendingOrder {
+	at.pendingOrderMu.Lock()
+	defer at.pendingOrderMu.Unlock()
+
+	var orders []*PendingOrder
+	for orderID, order := range at.pendingOrders {
+		if order.Status != PendingOrderOpen {
+			if order.reported {
+				delete(at.pendingOrders, orderID)
+				continue
+			}
+			order.reported = true
+		}
+		orders = append(orders, order)
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].PlacedAt.Before(orders[j].PlacedAt) })
+
+	infos := make([]decision.PendingOrder, 0, len(orders))
+	for _, order := range orders {
+		info := decision.PendingOrder{
+			Symbol:     order.Symbol,
+			Side:       order.Side,
+			EntryType:  order.EntryType,
+			LimitPrice: order.LimitPrice,
+			Quantity:   order.Quantity,
+			FilledQty:  order.FilledQty,
+			Status:     order.statusText(),
+		}
+		if order.Status == PendingOrderOpen {
+			info.ExpiresAt = order.ExpiresAt
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// GetPendingOrders 获取跟踪中的限价开仓单（含尚未告知AI的已结束订单）
+func (at *AutoTrader) GetPendingOrders() []PendingOrder {
+	at.pendingOrderMu.Lock()
+	defer at.pendingOrderMu.Unlock()
+
+	orders := make([]PendingOrder, 0, len(at.pendingOrders))
+	for _, order := range at.pendingOrders {
+		orders = append(orders, *order)
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].PlacedAt.After(orders[j].PlacedAt) })
+	return orders
+}
+
+// openPendingOrderCount 挂单中的订单数
+func (at *AutoTrader) openPendingOrderCount() int {
+	at.pendingOrderMu.Lock()
+	defer at.pendingOrderMu.Unlock()
+	count := 0
+	for _, order := range at.pendingOrders {
+		if order.Status == PendingOrderOpen {
+			count++
+		}
+	}
+	return count
+}
+
+// marshalPendingOrders 序列化挂单中的订单（保存到运行状态，重启后继续跟踪成交）
+func (at *AutoTrader) marshalPendingOrders() json.RawMessage {
+	at.pendingOrderMu.Lock()
+	defer at.pendingOrderMu.Unlock()
+	open := make([]*PendingOrder, 0, len(at.pendingOrders))
+	for _, order := range at.pendingOrders {
+		if order.Status == PendingOrderOpen {
+			open = append(open, order)
+		}
+	}
+	data, err := json.Marshal(open)
+	if err != nil {
+		log.Printf("⚠️ [%s] 序列化挂单失败: %v", at.name, err)
+		return nil
+	}
+	return data
+}
+
+// restorePendingOrders 从运行状态恢复挂单
+func (at *AutoTrader) restorePendingOrders(data json.RawMessage) {
+	if len(data) == 0 {
+		return
+	}
+	var orders []*PendingOrder
+	if err := json.Unmarshal(data, &orders); err != nil {
+		log.Printf("⚠️ [%s] 解析挂单失败: %v", at.name, err)
+		return
+	}
+
+	at.pendingOrderMu.Lock()
+	defer at.pendingOrderMu.Unlock()
+	if at.pendingOrders == nil {
+		at.pendingOrders = make(map[int64]*PendingOrder)
+	}
+	for _, order := range orders {
+		at.pendingOrders[order.OrderID] = order
+	}
+	if len(orders) > 0 {
+		log.Printf("📋 [%s] 恢复 %d 个限价开仓挂单", at.name, len(orders))
+	}
+}
+
+// statusText 挂单状态描述（用于提示词和日志）
+func (order *PendingOrder) statusText() string {
+	switch order.Status {
+	case PendingOrderOpen:
+		return "挂单中"
+	case PendingOrderFilled:
+		return "已成交"
+	case PendingOrderConverted:
+		return "已成交（超时后剩余部分转市价）"
+	default:
+		if order.Reason != "" {
+			return "已撤销：" + order.Reason
+		}
+		return "已撤销"
+	}
+}
+
+// eventData 挂单事件数据（调用方需持有 pendingOrderMu）
+func (order *PendingOrder) eventData() map[string]any {
+	data := map[string]any{
+		"order_id":    order.OrderID,
+		"symbol":      order.Symbol,
+		"side":        order.Side,
+		"entry_type":  order.EntryType,
+		"limit_price": order.LimitPrice,
+		"quantity":    order.Quantity,
+		"filled_qty":  order.FilledQty,
+		"status":      order.Status,
+		"expires_at":  order.ExpiresAt,
+	}
+	if order.Reason != "" {
+		data["reason"] = order.Reason
+	}
+	return data
+}
+
+// sideName 持仓方向的中文名称
+func sideName(side string) string {
+	if side == "long" {
+		return "多单"
+	}
+	return "空单"
+}
+
+// fallbackName 超时处理方式的中文描述
+func fallbackName(fallback string) string {
+	if fallback == LimitFallbackMarket {
+		return "剩余部分转市价"
+	}
+	return "撤单"
+}
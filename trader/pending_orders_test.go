@@ -0,0 +1,241 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+	"nofx/risk"
+	"time"
+)
+
+// ============================================================
+// 限价开仓挂单测试
+// ============================================================
+
+// limitEntry 返回 BTCUSDT 限价开多决策
+func limitEntry(entryType string) *decision.Decision {
+	return &decision.Decision{
+		Action:          "open_long",
+		Symbol:          "BTCUSDT",
+		Leverage:        5,
+		PositionSizeUSD: 1000,
+		StopLoss:        48000,
+		TakeProfit:      55000,
+		EntryType:       entryType,
+		LimitPrice:      50000,
+	}
+}
+
+func (s *AutoTraderTestSuite) TestPlaceLimitEntry() {
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 50500.0}, nil
+	})
+
+	actionRecord := &logger.DecisionAction{}
+	s.Require().NoError(s.autoTrader.executeOpenLongWithRecord(limitEntry(decision.EntryTypePostOnly), actionRecord))
+	s.Equal(50000.0, actionRecord.Price, "记录限价而非市价")
+	s.InDelta(0.02, actionRecord.Quantity, 1e-9)
+
+	placed := s.mockTrader.orders[actionRecord.OrderID]
+	s.Require().NotNil(placed)
+	s.Equal(TimeInForcePostOnly, placed["timeInForce"])
+	s.Equal(1, s.autoTrader.openPendingOrderCount())
+
+	orders := s.autoTrader.GetPendingOrders()
+	s.Require().Len(orders, 1)
+	s.Equal(PendingOrderOpen, orders[0].Status)
+	s.WithinDuration(time.Now().Add(s.config.ScanInterval), orders[0].ExpiresAt, time.Second, "默认有效期为一个扫描周期")
+
+	err := s.autoTrader.executeOpenLongWithRecord(limitEntry(decision.EntryTypeLimit), &logger.DecisionAction{})
+	s.Error(err)
+	s.Contains(err.Error(), "已有多单限价挂单")
+
+	infos := s.autoTrader.pendingOrderInfos()
+	s.Require().Len(infos, 1)
+	s.Equal("挂单中", infos[0].Status)
+	s.False(infos[0].ExpiresAt.IsZero())
+}
+
+func (s *AutoTraderTestSuite) TestCheckPendingOrders_Filled() {
+	actionRecord := &logger.DecisionAction{}
+	s.Require().NoError(s.autoTrader.placeLimitEntry(limitEntry(decision.EntryTypeLimit), "long", actionRecord))
+
+	// 部分成交：继续挂单
+	s.mockTrader.orders[actionRecord.OrderID]["status"] = OrderStatusPartiallyFilled
+	s.mockTrader.orders[actionRecord.OrderID]["executedQty"] = 0.01
+	s.autoTrader.checkPendingOrders()
+	s.Equal(0.01, s.autoTrader.GetPendingOrders()[0].FilledQty)
+	s.Equal(1, s.autoTrader.openPendingOrderCount())
+
+	// 全部成交：设置止损并重置退出状态
+	s.mockTrader.orders[actionRecord.OrderID]["status"] = OrderStatusFilled
+	s.mockTrader.orders[actionRecord.OrderID]["executedQty"] = 0.02
+	s.mockTrader.orders[actionRecord.OrderID]["avgPrice"] = 50000.0
+	s.autoTrader.checkPendingOrders()
+
+	order := s.autoTrader.GetPendingOrders()[0]
+	s.Equal(PendingOrderFilled, order.Status)
+	s.Equal(0.02, order.FilledQty)
+	s.Equal(48000.0, s.autoTrader.exitStates["BTCUSDT_long"].InitialStop)
+	s.Contains(s.autoTrader.positionFirstSeenTime, "BTCUSDT_long")
+
+	// 已结束的挂单只在下一次提示词中出现一次
+	infos := s.autoTrader.pendingOrderInfos()
+	s.Require().Len(infos, 1)
+	s.Equal("已成交", infos[0].Status)
+	s.True(infos[0].ExpiresAt.IsZero())
+	s.Empty(s.autoTrader.pendingOrderInfos())
+	s.Empty(s.autoTrader.GetPendingOrders())
+}
+
+func (s *AutoTraderTestSuite) TestCheckPendingOrders_Timeout() {
+	tests := []struct {
+		name           string
+		fallback       string
+		executedQty    float64
+		expectedStatus string
+		expectedFilled float64
+	}{
+		{"未成交撤单", LimitFallbackCancel, 0, PendingOrderCanceled, 0},
+		{"部分成交撤单后保护已成交部分", LimitFallbackCancel, 0.005, PendingOrderCanceled, 0.005},
+		{"剩余部分转市价", LimitFallbackMarket, 0.005, PendingOrderConverted, 0.02},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			s.autoTrader.pendingOrders = nil
+			s.autoTrader.exitStates = nil
+			s.autoTrader.config.LimitOrderFallback = tt.fallback
+
+			actionRecord := &logger.DecisionAction{}
+			s.Require().NoError(s.autoTrader.placeLimitEntry(limitEntry(decision.EntryTypeLimit), "long", actionRecord))
+			s.mockTrader.orders[actionRecord.OrderID]["executedQty"] = tt.executedQty
+			s.autoTrader.pendingOrders[actionRecord.OrderID].ExpiresAt = time.Now().Add(-time.Second)
+
+			s.autoTrader.checkPendingOrders()
+
+			s.Equal(OrderStatusCanceled, s.mockTrader.orders[actionRecord.OrderID]["status"], "超时后撤销交易所挂单")
+			order := s.autoTrader.GetPendingOrders()[0]
+			s.Equal(tt.expectedStatus, order.Status)
+			s.InDelta(tt.expectedFilled, order.FilledQty, 1e-9)
+			if tt.expectedFilled > 0 {
+				s.Equal(48000.0, s.autoTrader.exitStates["BTCUSDT_long"].InitialStop)
+			} else {
+				s.Nil(s.autoTrader.exitStates["BTCUSDT_long"])
+			}
+		})
+	}
+}
+
+func (s *AutoTraderTestSuite) TestPendingOrdersPersistence() {
+	s.Require().NoError(s.autoTrader.placeLimitEntry(limitEntry(decision.EntryTypeLimit), "long", &logger.DecisionAction{}))
+	filled := &logger.DecisionAction{}
+	s.Require().NoError(s.autoTrader.placeLimitEntry(limitEntry(decision.EntryTypeLimit), "short", filled))
+	s.mockTrader.orders[filled.OrderID]["status"] = OrderStatusFilled
+	s.autoTrader.checkPendingOrders()
+
+	data := s.autoTrader.marshalPendingOrders()
+
+	restored := &AutoTrader{name: "restored"}
+	restored.restorePendingOrders(data)
+	orders := restored.GetPendingOrders()
+	s.Require().Len(orders, 1, "只恢复挂单中的订单")
+	s.Equal("long", orders[0].Side)
+	s.Equal(48000.0, orders[0].StopLoss)
+	s.Equal(PendingOrderOpen, orders[0].Status)
+}
+
+func (s *AutoTraderTestSuite) TestCheckPendingOrders_TimeoutMarketGuards() {
+	s.autoTrader.config.LimitOrderFallback = LimitFallbackMarket
+	expire := func() *PendingOrder {
+		s.autoTrader.pendingOrders = nil
+		actionRecord := &logger.DecisionAction{}
+		s.Require().NoError(s.autoTrader.placeLimitEntry(limitEntry(decision.EntryTypeLimit), "long", actionRecord))
+		order := s.autoTrader.pendingOrders[actionRecord.OrderID]
+		order.ExpiresAt = time.Now().Add(-time.Second)
+		s.autoTrader.checkPendingOrders()
+		return order
+	}
+
+	// 熔断暂停中：只撤单，不转市价
+	s.autoTrader.stopUntil = time.Now().Add(time.Hour)
+	order := expire()
+	s.Equal(PendingOrderCanceled, order.Status)
+	s.Contains(order.Reason, "熔断暂停中")
+	s.autoTrader.stopUntil = time.Time{}
+
+	// 组合风控拒绝：只撤单，不转市价
+	s.autoTrader.riskEngine = risk.NewEngine(risk.Config{MaxPositions: 1})
+	s.mockTrader.positions = []map[string]interface{}{
+		{"symbol": "ETHUSDT", "side": "short", "positionAmt": -1.0, "markPrice": 3000.0, "leverage": 5.0},
+	}
+	defer func() { s.mockTrader.positions = []map[string]interface{}{} }()
+	order = expire()
+	s.Equal(PendingOrderCanceled, order.Status)
+	s.Contains(order.Reason, "风控拒绝")
+
+	// 风控缩减：按缩减后的数量转市价
+	s.autoTrader.riskEngine = risk.NewEngine(risk.Config{MaxMajorNotionalPct: 5})
+	s.mockTrader.positions = []map[string]interface{}{}
+	order = expire()
+	s.Equal(PendingOrderConverted, order.Status)
+	s.InDelta(10100*0.05/50000, order.FilledQty, 1e-9, "剩余部分按单币敞口上限缩减")
+}
+
+func (s *AutoTraderTestSuite) TestCancelPendingOrdersOnClose() {
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 50000.0}, nil
+	})
+	long := &logger.DecisionAction{}
+	s.Require().NoError(s.autoTrader.placeLimitEntry(limitEntry(decision.EntryTypeLimit), "long", long))
+	short := &logger.DecisionAction{}
+	s.Require().NoError(s.autoTrader.placeLimitEntry(limitEntry(decision.EntryTypeLimit), "short", short))
+
+	// close_long 只撤销同币种的多单挂单
+	err := s.autoTrader.executeCloseLongWithRecord(&decision.Decision{Action: "close_long", Symbol: "BTCUSDT"}, &logger.DecisionAction{})
+	s.Require().NoError(err)
+	s.Equal(OrderStatusCanceled, s.mockTrader.orders[long.OrderID]["status"])
+	s.Equal(PendingOrderCanceled, s.autoTrader.pendingOrders[long.OrderID].Status)
+	s.Equal(OrderStatusNew, s.mockTrader.orders[short.OrderID]["status"])
+	s.Equal(1, s.autoTrader.openPendingOrderCount())
+}
+
+func (s *AutoTraderTestSuite) TestFlattenAll_CancelsPendingOrders() {
+	s.autoTrader.decisionLogger = logger.NewDecisionLogger(s.T().TempDir())
+	actionRecord := &logger.DecisionAction{}
+	s.Require().NoError(s.autoTrader.placeLimitEntry(limitEntry(decision.EntryTypeLimit), "long", actionRecord))
+
+	record, err := s.autoTrader.FlattenAll("")
+	s.Require().NoError(err, "只有挂单时也应执行")
+	s.Empty(record.Decisions)
+	s.Contains(record.ExecutionLog, "🚫 已撤销 1 个限价开仓挂单")
+	s.Equal(OrderStatusCanceled, s.mockTrader.orders[actionRecord.OrderID]["status"])
+	s.Equal(0, s.autoTrader.openPendingOrderCount())
+}
+
+func (s *AutoTraderTestSuite) TestFlattenOnBreaker_CancelsPendingOrders() {
+	actionRecord := &logger.DecisionAction{}
+	s.Require().NoError(s.autoTrader.placeLimitEntry(limitEntry(decision.EntryTypeLimit), "short", actionRecord))
+
+	record := &logger.DecisionRecord{}
+	s.autoTrader.flattenOnBreaker(record)
+	s.Equal(logger.DecisionSourceBreaker, record.Source)
+	s.Equal(OrderStatusCanceled, s.mockTrader.orders[actionRecord.OrderID]["status"])
+	s.Equal(0, s.autoTrader.openPendingOrderCount())
+}
+
+func (s *AutoTraderTestSuite) TestRiskAccountIncludesPendingOrders() {
+	s.Require().NoError(s.autoTrader.placeLimitEntry(limitEntry(decision.EntryTypeLimit), "long", &logger.DecisionAction{}))
+
+	s.autoTrader.refreshRiskAccount(&decision.Context{Account: decision.AccountInfo{TotalEquity: 10000}})
+	account := s.autoTrader.riskAccount
+	s.Require().Len(account.Positions, 1)
+	s.Equal("BTCUSDT", account.Positions[0].Symbol)
+	s.InDelta(1000.0, account.Positions[0].Notional, 1e-9)
+	s.InDelta(200.0, account.MarginUsed, 1e-9)
+
+	// 挂单占用持仓数：达到上限后拒绝其他币种开仓
+	s.autoTrader.riskEngine = risk.NewEngine(risk.Config{MaxPositions: 1})
+	err := s.autoTrader.checkRisk(&decision.Decision{Action: "open_short", Symbol: "ETHUSDT", PositionSizeUSD: 100, Leverage: 5})
+	s.Error(err)
+}
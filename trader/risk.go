@@ -0,0 +1,164 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"nofx/decision"
+	"nofx/risk"
+	"strings"
+)
+
+// refreshRiskAccount 根据本周期交易上下文重建风控账户快照
+// 同一周期内后续开平仓会在快照上增量更新，避免依赖交易所持仓缓存
+func (at *AutoTrader) refreshRiskAccount(ctx *decision.Context) {
+	account := &risk.Account{
+		Equity:     ctx.Account.TotalEquity,
+		MarginUsed: ctx.Account.MarginUsed,
+	}
+	for _, pos := range ctx.Positions {
+		account.Positions = append(account.Positions, risk.Position{
+			Symbol:   pos.Symbol,
+			Side:     pos.Side,
+			Notional: pos.Quantity * pos.MarkPrice,
+			Margin:   pos.MarginUsed,
+		})
+	}
+	at.addPendingOrders(account)
+	at.riskAccount = at.withRiskState(account)
+}
+
+// loadRiskAccount 直接从交易所读取账户和持仓构建风控快照（不在交易周期内调用时使用）
+func (at *AutoTrader) loadRiskAccount() (*risk.Account, error) {
+	balance, err := at.trader.GetBalance()
+	if err != nil {
+		return nil, fmt.Errorf("获取账户余额失败: %w", err)
+	}
+	wallet, _ := balance["totalWalletBalance"].(float64)
+	unrealized, _ := balance["totalUnrealizedProfit"].(float64)
+
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		return nil, fmt.Errorf("获取持仓失败: %w", err)
+	}
+
+	account := &risk.Account{Equity: wallet + unrealized}
+	for _, pos := range positions {
+		symbol, _ := pos["symbol"].(string)
+		side, _ := pos["side"].(string)
+		quantity, _ := pos["positionAmt"].(float64)
+		markPrice, _ := pos["markPrice"].(float64)
+		quantity = math.Abs(quantity)
+		if quantity == 0 {
+			continue
+		}
+		leverage := 10.0
+		if lev, ok := pos["leverage"].(float64); ok && lev > 0 {
+			leverage = lev
+		}
+		notional := quantity * markPrice
+		account.MarginUsed += notional / leverage
+		account.Positions = append(account.Positions, risk.Position{
+			Symbol:   symbol,
+			Side:     side,
+			Notional: notional,
+			Margin:   notional / leverage,
+		})
+	}
+	at.addPendingOrders(account)
+	return at.withRiskState(account), nil
+}
+
+// addPendingOrders 将挂单中的限价开仓单未成交部分计入风控快照
+// 挂单成交后即成为持仓，需提前占用持仓数、敞口和保证金额度，避免多个挂单同时成交后超限
+func (at *AutoTrader) addPendingOrders(account *risk.Account) {
+	at.pendingOrderMu.Lock()
+	defer at.pendingOrderMu.Unlock()
+	for _, order := range at.pendingOrders {
+		if order.Status != PendingOrderOpen {
+			continue
+		}
+		notional := (order.Quantity - order.FilledQty) * order.LimitPrice
+		if notional <= 0 {
+			continue
+		}
+		margin := notional
+		if order.Leverage > 0 {
+			margin = notional / float64(order.Leverage)
+		}
+		account.MarginUsed += margin
+		account.Positions = append(account.Positions, risk.Position{
+			Symbol:   order.Symbol,
+			Side:     order.Side,
+			Notional: notional,
+			Margin:   margin,
+		})
+	}
+}
+
+// withRiskState 补充峰值净值和当日已实现盈亏
+func (at *AutoTrader) withRiskState(account *risk.Account) *risk.Account {
+	at.breakerMu.Lock()
+	defer at.breakerMu.Unlock()
+	if account.Equity > at.peakEquity {
+		at.peakEquity = account.Equity
+	}
+	account.PeakEquity = at.peakEquity
+	account.DailyRealizedPnL = at.dailyRealizedPnL
+	return account
+}
+
+// checkRisk 开仓前执行组合风控：拒绝时返回错误，超限时缩减 decision.PositionSizeUSD
+func (at *AutoTrader) checkRisk(d *decision.Decision) error {
+	if at.riskEngine == nil || (d.Action != "open_long" && d.Action != "open_short") {
+		return nil
+	}
+
+	if at.riskAccount == nil {
+		account, err := at.loadRiskAccount()
+		if err != nil {
+			return fmt.Errorf("🛡️ 风控拒绝: 无法获取账户快照: %w", err)
+		}
+		at.riskAccount = account
+	}
+
+	result, err := at.riskEngine.Evaluate(at.riskAccount, riskOrder(d))
+	if err != nil {
+		return fmt.Errorf("🛡️ 风控拒绝: %w", err)
+	}
+	if len(result.Adjustments) > 0 {
+		log.Printf("  🛡️ 风控缩减仓位 %s: %s", d.Symbol, strings.Join(result.Adjustments, "; "))
+		d.PositionSizeUSD = result.Notional
+	}
+	return nil
+}
+
+// trackRiskAccount 决策执行成功后同步更新风控快照
+func (at *AutoTrader) trackRiskAccount(d *decision.Decision) {
+	if at.riskAccount == nil {
+		return
+	}
+	switch d.Action {
+	case "open_long", "open_short":
+		at.riskAccount.Open(riskOrder(d))
+	case "close_long":
+		at.riskAccount.Close(d.Symbol, "long")
+	case "close_short":
+		at.riskAccount.Close(d.Symbol, "short")
+	}
+	at.riskAccount.DailyRealizedPnL = at.dailyRealizedPnL
+}
+
+// riskOrder 将开仓决策转换为风控订单
+func riskOrder(d *decision.Decision) risk.Order {
+	side := "long"
+	if d.Action == "open_short" {
+		side = "short"
+	}
+	return risk.Order{
+		Symbol:   d.Symbol,
+		Side:     side,
+		Notional: d.PositionSizeUSD,
+		Leverage: d.Leverage,
+	}
+}
@@ -0,0 +1,93 @@
+package trader
+
+import (
+	"log"
+	"nofx/config"
+)
+
+// traderStateStore 交易员运行状态快照存储（由 config.Database 实现）
+type traderStateStore interface {
+	GetTraderState(traderID string) (*config.TraderState, error)
+	SaveTraderState(state *config.TraderState) error
+}
+
+// stateStore 返回可用的运行状态存储（数据库未实现时返回 nil）
+func (at *AutoTrader) stateStore() traderStateStore {
+	store, _ := at.database.(traderStateStore)
+	return store
+}
+
+// loadTraderState 从数据库恢复周期计数、运行时长、当日已实现盈亏、持仓时长、峰值收益缓存和限价挂单
+func (at *AutoTrader) loadTraderState() {
+	store := at.stateStore()
+	if store == nil {
+		return
+	}
+	state, err := store.GetTraderState(at.id)
+	if err != nil {
+		log.Printf("⚠️ [%s] 读取运行状态失败: %v", at.name, err)
+		return
+	}
+	if state == nil {
+		return
+	}
+
+	at.callCount = state.CallCount
+	at.dailyRealizedPnL = state.DailyRealizedPnL
+	if !state.StartTime.IsZero() {
+		at.startTime = state.StartTime
+	}
+	if !state.LastResetTime.IsZero() {
+		at.lastResetTime = state.LastResetTime
+	}
+	for posKey, firstSeen := range state.PositionFirstSeen {
+		// 与退出状态中的开仓时间取较早者
+		if existing, ok := at.positionFirstSeenTime[posKey]; !ok || firstSeen < existing {
+			at.positionFirstSeenTime[posKey] = firstSeen
+		}
+	}
+
+	at.peakPnLCacheMutex.Lock()
+	for posKey, peak := range state.PeakPnL {
+		// 退出状态中的峰值可能由监控协程更新得更晚，取较大值
+		if existing, ok := at.peakPnLCache[posKey]; !ok || peak > existing {
+			at.peakPnLCache[posKey] = peak
+		}
+	}
+	at.peakPnLCacheMutex.Unlock()
+
+	at.restorePendingOrders(state.PendingOrders)
+
+	log.Printf("🔄 [%s] 恢复运行状态：周期 #%d，当日已实现盈亏 %+.2f，%d 个持仓", at.name,
+		at.callCount, at.dailyRealizedPnL, len(state.PositionFirstSeen))
+}
+
+// saveTraderState 持久化运行状态快照（每周期结束时调用，失败仅记录日志）
+//
+// 持仓首次出现时间和当日已实现盈亏也会被挂单监控、手动操作和审批执行修改，快照需持有 execMu
+func (at *AutoTrader) saveTraderState() {
+	store := at.stateStore()
+	if store == nil {
+		return
+	}
+
+	at.execMu.Lock()
+	state := &config.TraderState{
+		TraderID:          at.id,
+		CallCount:         at.callCount,
+		StartTime:         at.startTime,
+		DailyRealizedPnL:  at.dailyRealizedPnL,
+		LastResetTime:     at.lastResetTime,
+		PositionFirstSeen: make(map[string]int64, len(at.positionFirstSeenTime)),
+		PeakPnL:           at.GetPeakPnLCache(),
+		PendingOrders:     at.marshalPendingOrders(),
+	}
+	for posKey, firstSeen := range at.positionFirstSeenTime {
+		state.PositionFirstSeen[posKey] = firstSeen
+	}
+	at.execMu.Unlock()
+
+	if err := store.SaveTraderState(state); err != nil {
+		log.Printf("⚠️ [%s] 保存运行状态失败: %v", at.name, err)
+	}
+}
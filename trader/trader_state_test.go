@@ -0,0 +1,109 @@
+package trader
+
+import (
+	"fmt"
+	"nofx/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStateStore 内存运行状态存储
+type memoryStateStore struct {
+	state *config.TraderState
+}
+
+func (m *memoryStateStore) GetTraderState(traderID string) (*config.TraderState, error) {
+	return m.state, nil
+}
+
+func (m *memoryStateStore) SaveTraderState(state *config.TraderState) error {
+	m.state = state
+	return nil
+}
+
+func newStateTestTrader(store *memoryStateStore) *AutoTrader {
+	return &AutoTrader{
+		id:                    "state_trader",
+		name:                  "State Trader",
+		startTime:             time.Now(),
+		lastResetTime:         time.Now(),
+		positionFirstSeenTime: make(map[string]int64),
+		peakPnLCache:          make(map[string]float64),
+		database:              store,
+	}
+}
+
+func TestTraderState_SurvivesRestart(t *testing.T) {
+	store := &memoryStateStore{}
+	at := newStateTestTrader(store)
+
+	startTime := time.Now().Add(-48 * time.Hour).Truncate(time.Millisecond)
+	resetTime := time.Now().Add(-2 * time.Hour).Truncate(time.Millisecond)
+	at.startTime = startTime
+	at.lastResetTime = resetTime
+	at.callCount = 17
+	at.dailyRealizedPnL = -35.5
+	at.positionFirstSeenTime["BTCUSDT_long"] = startTime.UnixMilli()
+	at.UpdatePeakPnL("BTCUSDT", "long", -1.5)
+	at.saveTraderState()
+	require.NotNil(t, store.state)
+
+	// 模拟重启：新实例从存储恢复
+	restarted := newStateTestTrader(store)
+	restarted.loadTraderState()
+
+	assert.Equal(t, 17, restarted.callCount)
+	assert.Equal(t, -35.5, restarted.dailyRealizedPnL)
+	assert.True(t, restarted.startTime.Equal(startTime))
+	assert.True(t, restarted.lastResetTime.Equal(resetTime))
+	assert.Equal(t, startTime.UnixMilli(), restarted.positionFirstSeenTime["BTCUSDT_long"])
+	assert.Equal(t, -1.5, restarted.GetPeakPnLCache()["BTCUSDT_long"], "负峰值也应恢复")
+}
+
+func TestTraderState_MergesWithExitStates(t *testing.T) {
+	store := &memoryStateStore{state: &config.TraderState{
+		TraderID:          "state_trader",
+		CallCount:         3,
+		PositionFirstSeen: map[string]int64{"ETHUSDT_short": 2000},
+		PeakPnL:           map[string]float64{"ETHUSDT_short": 4},
+	}}
+	at := newStateTestTrader(store)
+	// 退出状态已恢复了更早的开仓时间和更高的峰值
+	at.positionFirstSeenTime["ETHUSDT_short"] = 1000
+	at.peakPnLCache["ETHUSDT_short"] = 6
+
+	at.loadTraderState()
+
+	assert.Equal(t, int64(1000), at.positionFirstSeenTime["ETHUSDT_short"])
+	assert.Equal(t, 6.0, at.peakPnLCache["ETHUSDT_short"])
+	assert.Equal(t, 3, at.callCount)
+}
+
+// TestTraderState_SaveWhileExecuting 挂单成交、手动操作与周期结束的状态快照并发执行（配合 -race 检查）
+func TestTraderState_SaveWhileExecuting(t *testing.T) {
+	at := newStateTestTrader(&memoryStateStore{})
+	at.trader = &MockTrader{}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			at.execMu.Lock()
+			at.protectFilledEntry(fmt.Sprintf("COIN%dUSDT", i), "long", 1, 90, 110)
+			at.dailyRealizedPnL += 1
+			at.execMu.Unlock()
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		at.saveTraderState()
+	}
+	<-done
+
+	at.saveTraderState()
+	state := at.database.(*memoryStateStore).state
+	assert.Len(t, state.PositionFirstSeen, 200)
+	assert.Equal(t, 200.0, state.DailyRealizedPnL)
+}
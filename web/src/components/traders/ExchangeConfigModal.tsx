@@ -225,6 +225,9 @@ export function ExchangeConfigModal({
         asterSigner.trim(),
         asterPrivateKey.trim()
       )
+    } else if (selectedExchange?.id === 'paper') {
+      // 模拟盘无需任何密钥
+      await onSave(selectedExchangeId, '', '', false)
     } else if (selectedExchange?.id === 'okx') {
       if (!apiKey.trim() || !secretKey.trim() || !passphrase.trim()) return
       await onSave(selectedExchangeId, apiKey.trim(), secretKey.trim(), testnet)
@@ -0,0 +1,78 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress Webhook 地址指向本机、内网、链路本地或未指定地址
+var ErrForbiddenAddress = errors.New("不允许推送到本机或内网地址")
+
+// forbiddenIP 是否为禁止推送的地址（回环、私有、链路本地、组播、未指定地址）
+func forbiddenIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast()
+}
+
+// ValidateURL 校验 Webhook 地址：需为 http(s) 地址，且解析后的所有 IP 都不是本机或内网地址
+//
+// 保存时的校验只用于尽早提示；实际投递时由 safeDialControl 在建立连接时再次检查，防止 DNS 重绑定和重定向绕过
+func ValidateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("无效的URL，需为 http(s) 地址: %s", rawURL)
+	}
+
+	host := u.Hostname()
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		addrs, err := net.LookupIP(host)
+		if err != nil {
+			return fmt.Errorf("解析域名失败: %w", err)
+		}
+		ips = addrs
+	}
+	for _, ip := range ips {
+		if forbiddenIP(ip) {
+			return fmt.Errorf("%w: %s (%s)", ErrForbiddenAddress, host, ip)
+		}
+	}
+	return nil
+}
+
+// safeDialControl 建立连接前检查已解析的目标 IP（每次连接都会经过，包括重定向后的请求）
+func safeDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || forbiddenIP(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+	return nil
+}
+
+// newSafeClient 创建只能访问公网地址的 HTTP 客户端（不使用代理，避免绕过地址检查）
+func newSafeClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: requestTimeout,
+		Control: safeDialControl,
+	}
+	return &http.Client{
+		Timeout: requestTimeout,
+		Transport: &http.Transport{
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: requestTimeout,
+			MaxIdleConns:        defaultConcurrency,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
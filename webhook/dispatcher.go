@@ -0,0 +1,314 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"nofx/config"
+	"nofx/events"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 对外推送的事件类型
+const (
+	EventPositionOpened = "position_opened" // 开仓成功
+	EventPositionClosed = "position_closed" // 平仓成功（含部分平仓、退出策略/熔断强制平仓）
+	EventSLTPChanged    = "sltp_changed"    // 止损/止盈调整（含退出策略移动止损）
+	EventCircuitBreaker = "circuit_breaker" // 触发熔断
+	EventAIFailure      = "ai_failure"      // AI 调用或决策解析失败
+	EventTest           = "test"            // 手动测试推送
+)
+
+// EventTypes 可订阅的事件类型
+var EventTypes = []string{EventPositionOpened, EventPositionClosed, EventSLTPChanged, EventCircuitBreaker, EventAIFailure}
+
+// 请求头
+const (
+	HeaderSignature = "X-Nofx-Signature" // sha256=<hex(HMAC-SHA256(secret, timestamp + "." + body))>
+	HeaderTimestamp = "X-Nofx-Timestamp" // 秒级 Unix 时间戳
+	HeaderEvent     = "X-Nofx-Event"
+	HeaderDelivery  = "X-Nofx-Delivery" // 同一事件的多次重试共用
+)
+
+const (
+	defaultMaxAttempts = 5
+	defaultBackoff     = 2 * time.Second // 第 n 次重试前等待 backoff * 2^(n-1)
+	defaultConcurrency = 8
+	requestTimeout     = 10 * time.Second
+)
+
+// Store Webhook 订阅与投递日志存储（由 config.Database 实现）
+type Store interface {
+	GetWebhooksForTrader(traderID string) ([]*config.Webhook, error)
+	SaveWebhookDelivery(delivery *config.WebhookDelivery) error
+}
+
+// Payload 推送的 JSON 内容
+type Payload struct {
+	ID        string         `json:"id"` // 投递ID，与 X-Nofx-Delivery 相同，可用于幂等去重
+	Event     string         `json:"event"`
+	TraderID  string         `json:"trader_id"`
+	Timestamp time.Time      `json:"timestamp"`
+	Source    string         `json:"source,omitempty"` // 原始交易员事件类型
+	Data      map[string]any `json:"data,omitempty"`
+}
+
+// Dispatcher 订阅交易员事件总线，将交易事件推送到用户配置的 Webhook
+//
+// 每个事件对每个匹配的 Webhook 异步投递，失败按指数退避重试，每次尝试写入投递日志
+type Dispatcher struct {
+	store       Store
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+
+	sem  chan struct{}
+	wg   sync.WaitGroup
+	stop chan struct{}
+	once sync.Once
+	sub  *events.Subscription
+}
+
+// NewDispatcher 创建 Webhook 分发器
+func NewDispatcher(store Store) *Dispatcher {
+	return &Dispatcher{
+		store:       store,
+		client:      newSafeClient(),
+		maxAttempts: defaultMaxAttempts,
+		backoff:     defaultBackoff,
+		sem:         make(chan struct{}, defaultConcurrency),
+		stop:        make(chan struct{}),
+	}
+}
+
+// SetRetry 设置最大尝试次数和退避基数
+func (d *Dispatcher) SetRetry(maxAttempts int, backoff time.Duration) {
+	if maxAttempts > 0 {
+		d.maxAttempts = maxAttempts
+	}
+	if backoff > 0 {
+		d.backoff = backoff
+	}
+}
+
+// Start 订阅事件总线并开始分发
+func (d *Dispatcher) Start(bus *events.Bus) {
+	d.sub, _ = bus.Subscribe(0, func(event events.Event) bool {
+		_, ok := Classify(event)
+		return ok
+	})
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		for event := range d.sub.Events() {
+			d.dispatch(event)
+		}
+	}()
+	log.Printf("🔌 Webhook 分发器已启动")
+}
+
+// Stop 停止分发，放弃尚在等待重试的投递并等待进行中的请求结束
+func (d *Dispatcher) Stop() {
+	d.once.Do(func() {
+		close(d.stop)
+		if d.sub != nil {
+			d.sub.Close()
+		}
+	})
+	d.wg.Wait()
+	if d.sub != nil && d.sub.Dropped() > 0 {
+		log.Printf("⚠️ Webhook 分发器因积压丢弃了 %d 个事件", d.sub.Dropped())
+	}
+}
+
+// dispatch 查找订阅该交易员事件的 Webhook 并异步投递
+func (d *Dispatcher) dispatch(event events.Event) {
+	eventType, ok := Classify(event)
+	if !ok {
+		return
+	}
+	webhooks, err := d.store.GetWebhooksForTrader(event.TraderID)
+	if err != nil {
+		log.Printf("⚠️ 查询交易员 %s 的 Webhook 失败: %v", event.TraderID, err)
+		return
+	}
+
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(eventType) {
+			continue
+		}
+		payload := &Payload{
+			ID:        newDeliveryID(),
+			Event:     eventType,
+			TraderID:  event.TraderID,
+			Timestamp: event.Time,
+			Source:    event.Type,
+			Data:      event.Data,
+		}
+		d.wg.Add(1)
+		go func(webhook *config.Webhook) {
+			defer d.wg.Done()
+			select {
+			case d.sem <- struct{}{}:
+			case <-d.stop:
+				return
+			}
+			defer func() { <-d.sem }()
+			d.Deliver(webhook, payload)
+		}(webhook)
+	}
+}
+
+// Deliver 同步投递（失败时按退避重试），返回最后一次尝试的投递记录
+func (d *Dispatcher) Deliver(webhook *config.Webhook, payload *Payload) *config.WebhookDelivery {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("❌ 序列化 Webhook 内容失败: %v", err)
+		return &config.WebhookDelivery{WebhookID: webhook.ID, DeliveryID: payload.ID, EventType: payload.Event, Error: err.Error()}
+	}
+
+	var delivery *config.WebhookDelivery
+	for attempt := 1; attempt <= d.maxAttempts; attempt++ {
+		if attempt > 1 {
+			wait := d.backoff << (attempt - 2)
+			select {
+			case <-time.After(wait):
+			case <-d.stop:
+				return delivery
+			}
+		}
+
+		delivery = d.send(webhook, payload, body, attempt)
+		if err := d.store.SaveWebhookDelivery(delivery); err != nil {
+			log.Printf("⚠️ 保存 Webhook 投递记录失败: %v", err)
+		}
+		if delivery.Success {
+			return delivery
+		}
+		log.Printf("⚠️ Webhook 投递失败 (%s %s, 第%d/%d次): %s", webhook.URL, payload.Event, attempt, d.maxAttempts, delivery.Error)
+	}
+	return delivery
+}
+
+// send 发送一次请求
+func (d *Dispatcher) send(webhook *config.Webhook, payload *Payload, body []byte, attempt int) *config.WebhookDelivery {
+	delivery := &config.WebhookDelivery{
+		WebhookID:  webhook.ID,
+		DeliveryID: payload.ID,
+		EventType:  payload.Event,
+		TraderID:   payload.TraderID,
+		Payload:    string(body),
+		Attempt:    attempt,
+		CreatedAt:  time.Now(),
+	}
+
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		delivery.Error = fmt.Sprintf("创建请求失败: %v", err)
+		return delivery
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "nofx-webhook/1.0")
+	req.Header.Set(HeaderEvent, payload.Event)
+	req.Header.Set(HeaderDelivery, payload.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, "sha256="+Sign(webhook.Secret, timestamp, body))
+
+	start := time.Now()
+	resp, err := d.client.Do(req)
+	delivery.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	defer resp.Body.Close()
+
+	// 响应内容不写入投递记录（记录会返回给用户，避免把目标地址的响应内容回显）
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	delivery.StatusCode = resp.StatusCode
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		delivery.Success = true
+		return delivery
+	}
+	delivery.Error = fmt.Sprintf("HTTP %d", resp.StatusCode)
+	return delivery
+}
+
+// Sign 计算签名：hex(HMAC-SHA256(secret, "<timestamp>.<body>"))
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify 校验签名头（供接收方参考实现）
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	expected := "sha256=" + Sign(secret, timestamp, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// Classify 将交易员事件映射为 Webhook 事件类型，不需要推送的事件返回 false
+func Classify(event events.Event) (string, bool) {
+	switch event.Type {
+	case events.DecisionExecuted:
+		action, _ := event.Data["action"].(string)
+		switch action {
+		case "open_long", "open_short":
+			return EventPositionOpened, true
+		case "close_long", "close_short", "partial_close":
+			return EventPositionClosed, true
+		case "update_stop_loss", "update_take_profit":
+			return EventSLTPChanged, true
+		}
+	case events.DrawdownClose:
+		return EventPositionClosed, true
+	case events.StopMoved:
+		return EventSLTPChanged, true
+	case events.StopTriggered:
+		return EventCircuitBreaker, true
+	case events.AIResponse:
+		if _, failed := event.Data["error"]; failed {
+			return EventAIFailure, true
+		}
+	}
+	return "", false
+}
+
+// ValidEventType 是否为可订阅的事件类型
+func ValidEventType(eventType string) bool {
+	for _, t := range EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// NewSecret 生成随机签名密钥
+func NewSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成签名密钥失败: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+// newDeliveryID 生成投递ID
+func newDeliveryID() string {
+	buf := make([]byte, 12)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,217 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"nofx/config"
+	"nofx/events"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStore struct {
+	mu         sync.Mutex
+	webhooks   []*config.Webhook
+	deliveries []*config.WebhookDelivery
+}
+
+func (m *memoryStore) GetWebhooksForTrader(traderID string) ([]*config.Webhook, error) {
+	var result []*config.Webhook
+	for _, w := range m.webhooks {
+		if w.Enabled && (w.TraderID == "" || w.TraderID == traderID) {
+			result = append(result, w)
+		}
+	}
+	return result, nil
+}
+
+func (m *memoryStore) SaveWebhookDelivery(delivery *config.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deliveries = append(m.deliveries, delivery)
+	return nil
+}
+
+func (m *memoryStore) snapshot() []*config.WebhookDelivery {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*config.WebhookDelivery(nil), m.deliveries...)
+}
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		event events.Event
+		want  string
+	}{
+		{events.Event{Type: events.DecisionExecuted, Data: map[string]any{"action": "open_short"}}, EventPositionOpened},
+		{events.Event{Type: events.DecisionExecuted, Data: map[string]any{"action": "partial_close"}}, EventPositionClosed},
+		{events.Event{Type: events.DecisionExecuted, Data: map[string]any{"action": "update_take_profit"}}, EventSLTPChanged},
+		{events.Event{Type: events.DecisionExecuted, Data: map[string]any{"action": "hold"}}, ""},
+		{events.Event{Type: events.DecisionFailed, Data: map[string]any{"action": "open_long"}}, ""},
+		{events.Event{Type: events.DrawdownClose}, EventPositionClosed},
+		{events.Event{Type: events.StopMoved}, EventSLTPChanged},
+		{events.Event{Type: events.StopTriggered}, EventCircuitBreaker},
+		{events.Event{Type: events.AIResponse, Data: map[string]any{"error": "timeout"}}, EventAIFailure},
+		{events.Event{Type: events.AIResponse, Data: map[string]any{}}, ""},
+		{events.Event{Type: events.BalanceSynced}, ""},
+	}
+	for _, tc := range cases {
+		got, ok := Classify(tc.event)
+		assert.Equal(t, tc.want, got, "%s %v", tc.event.Type, tc.event.Data)
+		assert.Equal(t, tc.want != "", ok)
+	}
+}
+
+func TestDeliver_SignsPayload(t *testing.T) {
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	store := &memoryStore{}
+	d := NewDispatcher(store)
+	d.client = server.Client() // 测试服务器监听在本机，跳过地址检查
+	hook := &config.Webhook{ID: "wh-1", URL: server.URL, Secret: "top-secret"}
+	delivery := d.Deliver(hook, &Payload{ID: "delivery-1", Event: EventPositionOpened, TraderID: "trader-1",
+		Timestamp: time.Now(), Data: map[string]any{"symbol": "BTCUSDT"}})
+
+	require.True(t, delivery.Success)
+	assert.Equal(t, http.StatusNoContent, delivery.StatusCode)
+	assert.Equal(t, EventPositionOpened, received.Header.Get(HeaderEvent))
+	assert.Equal(t, "delivery-1", received.Header.Get(HeaderDelivery))
+
+	timestamp, err := strconv.ParseInt(received.Header.Get(HeaderTimestamp), 10, 64)
+	require.NoError(t, err)
+	assert.True(t, Verify("top-secret", timestamp, body, received.Header.Get(HeaderSignature)))
+	assert.False(t, Verify("wrong-secret", timestamp, body, received.Header.Get(HeaderSignature)))
+
+	var payload Payload
+	require.NoError(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, "trader-1", payload.TraderID)
+	assert.Equal(t, "BTCUSDT", payload.Data["symbol"])
+
+	require.Len(t, store.snapshot(), 1)
+}
+
+func TestDeliver_RetriesWithBackoff(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	store := &memoryStore{}
+	d := NewDispatcher(store)
+	d.client = server.Client() // 测试服务器监听在本机，跳过地址检查
+	d.SetRetry(5, time.Millisecond)
+	delivery := d.Deliver(&config.Webhook{ID: "wh-1", URL: server.URL}, &Payload{ID: "d1", Event: EventAIFailure})
+
+	require.True(t, delivery.Success)
+	assert.Equal(t, 3, delivery.Attempt)
+
+	deliveries := store.snapshot()
+	require.Len(t, deliveries, 3, "每次尝试都应记录投递日志")
+	assert.False(t, deliveries[0].Success)
+	assert.Equal(t, http.StatusServiceUnavailable, deliveries[0].StatusCode)
+	assert.Equal(t, "HTTP 503", deliveries[0].Error, "不应回显响应内容")
+	assert.Equal(t, "d1", deliveries[2].DeliveryID)
+}
+
+func TestDeliver_GivesUpAfterMaxAttempts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	store := &memoryStore{}
+	d := NewDispatcher(store)
+	d.client = server.Client() // 测试服务器监听在本机，跳过地址检查
+	d.SetRetry(3, time.Millisecond)
+	delivery := d.Deliver(&config.Webhook{ID: "wh-1", URL: server.URL}, &Payload{ID: "d1", Event: EventAIFailure})
+
+	assert.False(t, delivery.Success)
+	assert.Len(t, store.snapshot(), 3)
+}
+
+func TestDispatcher_RoutesBusEvents(t *testing.T) {
+	received := make(chan Payload, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload Payload
+		json.NewDecoder(r.Body).Decode(&payload)
+		received <- payload
+	}))
+	defer server.Close()
+
+	store := &memoryStore{webhooks: []*config.Webhook{
+		{ID: "wh-opened", URL: server.URL, Events: []string{EventPositionOpened}, Enabled: true},
+		{ID: "wh-other-trader", TraderID: "trader-2", URL: server.URL, Enabled: true},
+	}}
+	bus := events.NewBus(10)
+	d := NewDispatcher(store)
+	d.client = server.Client() // 测试服务器监听在本机，跳过地址检查
+	d.Start(bus)
+
+	bus.Publish("trader-1", events.BalanceSynced, nil)
+	bus.Publish("trader-1", events.StopTriggered, map[string]any{"reason": "回撤"})
+	bus.Publish("trader-1", events.DecisionExecuted, map[string]any{"action": "open_long", "symbol": "ETHUSDT"})
+
+	select {
+	case payload := <-received:
+		assert.Equal(t, EventPositionOpened, payload.Event)
+		assert.Equal(t, events.DecisionExecuted, payload.Source)
+		assert.Equal(t, "ETHUSDT", payload.Data["symbol"])
+	case <-time.After(2 * time.Second):
+		t.Fatal("未收到 Webhook 推送")
+	}
+
+	d.Stop()
+	assert.Empty(t, received, "未订阅的事件和其他交易员的订阅不应推送")
+	assert.Equal(t, 0, bus.SubscriberCount())
+}
+
+func TestDeliver_RejectsPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("不应向本机地址发送请求")
+	}))
+	defer server.Close()
+
+	store := &memoryStore{}
+	d := NewDispatcher(store)
+	d.SetRetry(1, 0)
+	delivery := d.Deliver(&config.Webhook{ID: "wh-1", URL: server.URL}, &Payload{ID: "d1", Event: EventTest})
+	assert.False(t, delivery.Success)
+	assert.Contains(t, delivery.Error, ErrForbiddenAddress.Error())
+}
+
+func TestValidateURL(t *testing.T) {
+	for _, rawURL := range []string{
+		"ftp://example.com/hook",
+		"http://",
+		"http://127.0.0.1:8080/api",
+		"http://localhost/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"https://10.0.0.5/hook",
+		"http://192.168.1.1/hook",
+		"http://[::1]/hook",
+		"http://0.0.0.0/hook",
+	} {
+		assert.Error(t, ValidateURL(rawURL), rawURL)
+	}
+	assert.NoError(t, ValidateURL("https://93.184.216.34/hook"))
+}